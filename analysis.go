@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Files of an analysis bundle, written by "export --analysis".
+const (
+	analysisCSVFileName      = "runs.csv"
+	analysisParquetFileName  = "runs.parquet"
+	analysisNotebookFileName = "analysis.ipynb"
+)
+
+// csvFormat is how an exported CSV separates fields and writes numbers and
+// timestamps.
+type csvFormat struct {
+	delimiter rune
+	// decimalComma writes 42,5 instead of 42.5, as spreadsheets in most of
+	// Europe expect.
+	decimalComma bool
+	// spreadsheetDates writes timestamps as "2006-01-02 15:04:05" in UTC,
+	// which spreadsheets import as dates, instead of RFC 3339.
+	spreadsheetDates bool
+}
+
+// csvFormats are the formats --csv-format accepts. "standard" is for tools
+// (pandas, DuckDB); the others import cleanly into Excel and LibreOffice.
+var csvFormats = map[string]csvFormat{
+	"standard": {delimiter: ','},
+	"excel":    {delimiter: ',', spreadsheetDates: true},
+	"excel-eu": {delimiter: ';', decimalComma: true, spreadsheetDates: true},
+}
+
+// standardCSV is the format of the analysis bundle's CSV, which its notebook
+// reads.
+var standardCSV = csvFormats["standard"]
+
+// parseCSVFormat returns the named format with its delimiter replaced by
+// delimiter, if set. The delimiter must be a single character other than the
+// decimal separator.
+func parseCSVFormat(name, delimiter string) (csvFormat, error) {
+	format, ok := csvFormats[name]
+	if !ok {
+		names := make([]string, 0, len(csvFormats))
+		for n := range csvFormats {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return format, fmt.Errorf("unknown CSV format %q (use %s)", name, strings.Join(names, ", "))
+	}
+	if delimiter != "" {
+		if delimiter == `\t` {
+			delimiter = "\t"
+		}
+		r := []rune(delimiter)
+		if len(r) != 1 || r[0] == '"' || r[0] == '\r' || r[0] == '\n' {
+			return format, fmt.Errorf("CSV delimiter must be a single character other than a quote or newline, got %q", delimiter)
+		}
+		format.delimiter = r[0]
+	}
+	if format.decimalComma && format.delimiter == ',' {
+		return format, fmt.Errorf("CSV delimiter cannot be a comma when decimals use one")
+	}
+	return format, nil
+}
+
+// float formats v with the format's decimal separator.
+func (f csvFormat) float(v float64) string {
+	s := strconv.FormatFloat(v, 'f', -1, 64)
+	if f.decimalComma {
+		s = strings.Replace(s, ".", ",", 1)
+	}
+	return s
+}
+
+// timestamp formats the Unix milliseconds ms.
+func (f csvFormat) timestamp(ms int64) string {
+	if f.spreadsheetDates {
+		return time.UnixMilli(ms).UTC().Format(time.DateTime)
+	}
+	return time.UnixMilli(ms).UTC().Format("2006-01-02T15:04:05.000Z07:00")
+}
+
+// writeExportCSV writes the columns as a tidy CSV in format: a header of
+// column names, one line per row, and empty cells for nulls.
+func writeExportCSV(w io.Writer, columns []parquetColumn, format csvFormat) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = format.delimiter
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.name
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	rows := 0
+	if len(columns) > 0 {
+		rows = len(columns[0].values)
+	}
+	record := make([]string, len(columns))
+	for r := range rows {
+		for i, col := range columns {
+			switch v := col.values[r].(type) {
+			case nil:
+				record[i] = ""
+			case string:
+				record[i] = v
+			case bool:
+				record[i] = strconv.FormatBool(v)
+			case int32:
+				record[i] = strconv.FormatInt(int64(v), 10)
+			case int64:
+				if col.converted == parquetTimestampMillis {
+					record[i] = format.timestamp(v)
+				} else {
+					record[i] = strconv.FormatInt(v, 10)
+				}
+			case float64:
+				record[i] = format.float(v)
+			default:
+				return fmt.Errorf("column %s: unsupported value %T", col.name, v)
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// notebookSource splits text into the line list notebooks store cells as.
+func notebookSource(text string) []string {
+	return strings.SplitAfter(strings.TrimSpace(text), "\n")
+}
+
+// notebookMarkdown and notebookCode build nbformat 4 cells; code cells are
+// left unexecuted.
+func notebookMarkdown(text string) map[string]any {
+	return map[string]any{"cell_type": "markdown", "metadata": map[string]any{}, "source": notebookSource(text)}
+}
+
+func notebookCode(text string) map[string]any {
+	return map[string]any{
+		"cell_type":       "code",
+		"execution_count": nil,
+		"metadata":        map[string]any{},
+		"outputs":         []any{},
+		"source":          notebookSource(text),
+	}
+}
+
+// analysisNotebook builds a notebook that loads the bundle's CSV with pandas
+// and draws the standard plots: a per-provider summary, the TTFT distribution
+// and throughput box plots. It needs only pandas and matplotlib.
+func analysisNotebook(sessions []string) ([]byte, error) {
+	intro := fmt.Sprintf(`# llm-api-speed analysis
+
+Runs from %s, exported by `+"`llm-api-speed export --analysis`"+`. Each row of `+"`%s`"+` is one iteration; metrics a run did not produce are empty.`,
+		strings.Join(sessions, ", "), analysisCSVFileName)
+
+	cells := []map[string]any{
+		notebookMarkdown(intro),
+		notebookCode(fmt.Sprintf(`import pandas as pd
+import matplotlib.pyplot as plt
+
+runs = pd.read_csv(%q, parse_dates=["timestamp"], dtype={"env": "string", "error": "string"})
+runs["label"] = runs["provider"].where(runs["env"].isna(), runs["provider"] + " [" + runs["env"] + "]")
+ok = runs[runs["success"]]
+labels = sorted(ok["label"].unique())`, analysisCSVFileName)),
+		notebookMarkdown("## Summary"),
+		notebookCode(`runs.groupby(["label", "mode"]).agg(
+    runs=("iteration", "size"),
+    success_rate=("success", "mean"),
+    ttft_p50_ms=("ttft_ms", "median"),
+    ttft_p95_ms=("ttft_ms", lambda s: s.quantile(0.95)),
+    throughput_p50_tps=("throughput_tps", "median"),
+)`),
+		notebookMarkdown("## TTFT Distribution"),
+		notebookCode(`fig, ax = plt.subplots(figsize=(10, 5))
+for label in labels:
+    ax.hist(ok.loc[ok["label"] == label, "ttft_ms"].dropna(), bins=30, alpha=0.5, label=label)
+ax.set_xlabel("TTFT (ms)")
+ax.set_ylabel("Runs")
+ax.set_title("TTFT distribution (successful runs)")
+ax.legend()
+plt.show()`),
+		notebookMarkdown("## Throughput"),
+		notebookCode(`fig, ax = plt.subplots(figsize=(10, 5))
+ax.boxplot([ok.loc[ok["label"] == label, "throughput_tps"].dropna() for label in labels])
+ax.set_xticks(range(1, len(labels) + 1), labels, rotation=30, ha="right")
+ax.set_ylabel("Throughput (tokens/s)")
+ax.set_title("Throughput by provider (successful runs)")
+plt.tight_layout()
+plt.show()`),
+	}
+
+	notebook := map[string]any{
+		"cells": cells,
+		"metadata": map[string]any{
+			"kernelspec":    map[string]string{"display_name": "Python 3", "language": "python", "name": "python3"},
+			"language_info": map[string]string{"name": "python"},
+		},
+		"nbformat":       4,
+		"nbformat_minor": 4,
+	}
+	return json.MarshalIndent(notebook, "", " ")
+}
+
+// writeAnalysisBundle writes the rows as CSV and Parquet next to a notebook
+// that plots them.
+func writeAnalysisBundle(dir string, rows []exportRow, sessions []string) error {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return fmt.Errorf("error creating %s: %w", dir, err)
+	}
+	columns := exportColumns(rows)
+
+	csvFile, err := os.OpenFile(filepath.Join(dir, analysisCSVFileName), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := writeExportCSV(csvFile, columns, standardCSV); err != nil {
+		_ = csvFile.Close()
+		return fmt.Errorf("error writing %s: %w", analysisCSVFileName, err)
+	}
+	if err := csvFile.Close(); err != nil {
+		return err
+	}
+
+	if err := writeExportParquet(filepath.Join(dir, analysisParquetFileName), rows); err != nil {
+		return err
+	}
+
+	notebook, err := analysisNotebook(sessions)
+	if err != nil {
+		return fmt.Errorf("error building notebook: %w", err)
+	}
+	return os.WriteFile(filepath.Join(dir, analysisNotebookFileName), notebook, 0600)
+}
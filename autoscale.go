@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AIMD scaling of diagnostic workers (--diagnostic-autoscale): every
+// scaleInterval the workers grow by an additive step when the window was
+// clean, and halve when it saw a 429 or more than scaleFailureShare failures.
+const (
+	// scaleInterval matches the request interval of a diagnostic worker, so
+	// every active worker contributes about one request per window.
+	scaleInterval = 15 * time.Second
+	// scaleFailureShare is the failure share of a window that backs off.
+	scaleFailureShare = 0.1
+)
+
+// ScalingStep is one window of an autoscaled diagnostic run.
+type ScalingStep struct {
+	Offset      time.Duration `json:"offset"`
+	Workers     int           `json:"workers"`
+	Requests    int           `json:"requests"`
+	Failures    int           `json:"failures"`
+	RateLimited int           `json:"rateLimited,omitempty"`
+	// Next is the worker count chosen for the following window.
+	Next int `json:"next"`
+}
+
+// WorkerScaling summarizes an autoscaled diagnostic run.
+type WorkerScaling struct {
+	InitialWorkers int           `json:"initialWorkers"`
+	MaxWorkers     int           `json:"maxWorkers"`
+	PeakWorkers    int           `json:"peakWorkers"`
+	Steps          []ScalingStep `json:"steps,omitempty"`
+	// Capacity is the successful request rate in req/s sustained once the
+	// scaler first backed off. When it never backed off, SteadyState is false
+	// and Capacity is the best window seen: a lower bound.
+	Capacity    float64 `json:"capacityRps"`
+	SteadyState bool    `json:"steadyState"`
+}
+
+// workerScaler decides how many diagnostic workers are active. Workers are
+// numbered from 1; those above the target park until it grows again.
+type workerScaler struct {
+	mu                         sync.Mutex
+	target, initial, maxTarget int
+	step                       int
+	start                      time.Time
+	requests, failures, limits int
+	steps                      []ScalingStep
+	changed                    chan struct{}
+}
+
+// newWorkerScaler starts with initial active workers and never exceeds
+// maxWorkers. The additive step is the initial count, so a 90-second run can
+// still ramp up several times.
+func newWorkerScaler(initial, maxWorkers int, start time.Time) *workerScaler {
+	return &workerScaler{
+		target:    initial,
+		initial:   initial,
+		maxTarget: maxWorkers,
+		step:      initial,
+		start:     start,
+		changed:   make(chan struct{}),
+	}
+}
+
+// parked returns nil when worker id may send requests, or else a channel
+// closed at the next scaling decision. It is safe to call on a nil scaler,
+// which keeps every worker active.
+func (s *workerScaler) parked(id int) <-chan struct{} {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id <= s.target {
+		return nil
+	}
+	return s.changed
+}
+
+// observe counts a finished request towards the current window. It is safe to
+// call on a nil scaler.
+func (s *workerScaler) observe(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	if err != nil {
+		s.failures++
+		if isRateLimited(err) {
+			s.limits++
+		}
+	}
+}
+
+// adjust closes the current window and picks the next target.
+func (s *workerScaler) adjust() ScalingStep {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	step := ScalingStep{
+		Offset:      time.Duration(len(s.steps)) * scaleInterval,
+		Workers:     s.target,
+		Requests:    s.requests,
+		Failures:    s.failures,
+		RateLimited: s.limits,
+	}
+	switch {
+	case s.limits > 0 || float64(s.failures) > scaleFailureShare*float64(s.requests):
+		s.target = max(1, s.target/2)
+	case s.requests > 0:
+		s.target = min(s.maxTarget, s.target+s.step)
+	}
+	step.Next = s.target
+	s.steps = append(s.steps, step)
+	s.requests, s.failures, s.limits = 0, 0, 0
+	close(s.changed)
+	s.changed = make(chan struct{})
+	return step
+}
+
+// run adjusts the target every scaleInterval until done is closed.
+func (s *workerScaler) run(done <-chan struct{}, logger *log.Logger) {
+	ticker := time.NewTicker(scaleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			step := s.adjust()
+			if step.Next != step.Workers {
+				logger.Printf("Scaling workers %d -> %d (last %s: %d requests, %d failed, %d rate-limited)",
+					step.Workers, step.Next, scaleInterval, step.Requests, step.Failures, step.RateLimited)
+			}
+		}
+	}
+}
+
+// summary estimates capacity from records: successful requests are counted
+// in the window they started in, so slow requests finishing late still count
+// towards the load that produced them.
+func (s *workerScaler) summary(records []DiagnosticRecord) *WorkerScaling {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	scaling := &WorkerScaling{InitialWorkers: s.initial, MaxWorkers: s.maxTarget, PeakWorkers: s.initial, Steps: s.steps}
+	started := make([]int, len(s.steps))
+	successes := make([]int, len(s.steps))
+	for _, r := range records {
+		i := int(r.Start.Sub(s.start) / scaleInterval)
+		if i < 0 || i >= len(s.steps) {
+			continue
+		}
+		started[i]++
+		if r.Error == "" {
+			successes[i]++
+		}
+	}
+	backoff := -1
+	for i, step := range s.steps {
+		scaling.PeakWorkers = max(scaling.PeakWorkers, step.Workers)
+		if backoff < 0 && step.Next < step.Workers {
+			backoff = i
+		}
+	}
+
+	rate := func(i int) float64 { return float64(successes[i]) / scaleInterval.Seconds() }
+	if backoff < 0 {
+		for i := range s.steps {
+			scaling.Capacity = max(scaling.Capacity, rate(i))
+		}
+		return scaling
+	}
+	scaling.SteadyState = true
+	var sum float64
+	windows := 0
+	for i := backoff + 1; i < len(s.steps); i++ {
+		if started[i] > 0 {
+			sum += rate(i)
+			windows++
+		}
+	}
+	if windows == 0 {
+		// Backed off in the last window with requests: the load before it is
+		// the best estimate
+		scaling.Capacity = rate(backoff)
+		return scaling
+	}
+	scaling.Capacity = sum / float64(windows)
+	return scaling
+}
+
+// formatCapacity renders a capacity estimate, marking lower bounds.
+func formatCapacity(s *WorkerScaling) string {
+	if s.SteadyState {
+		return fmt.Sprintf("%.2f req/s", s.Capacity)
+	}
+	return fmt.Sprintf("≥ %.2f req/s", s.Capacity)
+}
+
+// writeDiagnosticScalingSection adds the capacity estimates of autoscaled
+// diagnostic runs.
+func writeDiagnosticScalingSection(report *strings.Builder, results []DiagnosticSummary) {
+	var scaled []DiagnosticSummary
+	for _, r := range results {
+		if r.Scaling != nil {
+			scaled = append(scaled, r)
+		}
+	}
+	if len(scaled) == 0 {
+		return
+	}
+	report.WriteString("## Worker Scaling\n\n")
+	fmt.Fprintf(report, "Workers were scaled AIMD-style every %s: up by the initial count after a clean window, "+
+		"halved after a window with a 429 or more than %.0f%% failures. Capacity is the successful request rate "+
+		"sustained after the first back-off; ≥ marks a provider that never pushed back, whose capacity may be higher. "+
+		"Windows with rate-limited requests are marked (429).\n\n",
+		scaleInterval, 100*scaleFailureShare)
+	report.WriteString("| Provider | Mode | Workers (start → peak) | Capacity | Workers per Window |\n")
+	report.WriteString("|----------|------|------------------------|----------|--------------------|\n")
+	for _, r := range scaled {
+		s := r.Scaling
+		windows := make([]string, len(s.Steps))
+		for i, step := range s.Steps {
+			windows[i] = fmt.Sprintf("%d", step.Workers)
+			if step.RateLimited > 0 {
+				windows[i] += " (429)"
+			}
+		}
+		fmt.Fprintf(report, "| %s | %s | %d → %d | %s | %s |\n", providerLabel(r.Provider, r.Env), r.Mode,
+			s.InitialWorkers, s.PeakWorkers, formatCapacity(s), strings.Join(windows, " → "))
+	}
+	report.WriteString("\n")
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestWorkerScalerAIMD(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := newWorkerScaler(3, 7, start)
+	if s.parked(3) != nil || s.parked(4) == nil {
+		t.Fatal("workers above the initial count should start parked")
+	}
+	wake := s.parked(4)
+
+	// Clean window: additive increase by the initial count
+	for range 3 {
+		s.observe(nil)
+	}
+	if step := s.adjust(); step.Workers != 3 || step.Next != 6 || step.Offset != 0 {
+		t.Errorf("clean window = %+v, want 3 -> 6", step)
+	}
+	select {
+	case <-wake:
+	default:
+		t.Error("parked workers should be woken by a scaling decision")
+	}
+	if s.parked(6) != nil || s.parked(7) == nil {
+		t.Error("target not applied")
+	}
+
+	// Capped at the maximum
+	s.observe(nil)
+	if step := s.adjust(); step.Next != 7 || step.Offset != scaleInterval {
+		t.Errorf("increase should stop at the maximum, got %+v", step)
+	}
+
+	// A single 429 halves the workers
+	for range 6 {
+		s.observe(nil)
+	}
+	s.observe(&openai.APIError{HTTPStatusCode: 429})
+	if step := s.adjust(); step.Next != 3 || step.RateLimited != 1 {
+		t.Errorf("429 window = %+v, want 7 -> 3", step)
+	}
+
+	// Errors below the failure share do not back off; above it they do
+	for range 10 {
+		s.observe(nil)
+	}
+	s.observe(errors.New("boom"))
+	if step := s.adjust(); step.Next != 6 {
+		t.Errorf("one failure in 11 should not back off, got %+v", step)
+	}
+	s.observe(nil)
+	s.observe(errors.New("boom"))
+	if step := s.adjust(); step.Next != 3 {
+		t.Errorf("half failing should back off, got %+v", step)
+	}
+
+	var nilScaler *workerScaler
+	if nilScaler.parked(100) != nil || nilScaler.summary(nil) != nil {
+		t.Error("a nil scaler keeps every worker active and has no summary")
+	}
+}
+
+func TestWorkerScalerCapacity(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := newWorkerScaler(10, 40, start)
+	s.steps = []ScalingStep{
+		{Offset: 0, Workers: 10, Next: 15},
+		{Offset: scaleInterval, Workers: 15, Next: 7, RateLimited: 2},
+		{Offset: 2 * scaleInterval, Workers: 7, Next: 10},
+		{Offset: 3 * scaleInterval, Workers: 10, Next: 15},
+	}
+	var records []DiagnosticRecord
+	add := func(window, ok, failed int) {
+		at := start.Add(time.Duration(window)*scaleInterval + time.Second)
+		for range ok {
+			records = append(records, DiagnosticRecord{Start: at})
+		}
+		for range failed {
+			records = append(records, DiagnosticRecord{Start: at, Error: "429"})
+		}
+	}
+	add(0, 10, 0)
+	add(1, 12, 3)
+	add(2, 6, 0)
+	add(3, 9, 0)
+
+	scaling := s.summary(records)
+	if !scaling.SteadyState || scaling.PeakWorkers != 15 {
+		t.Fatalf("unexpected scaling: %+v", scaling)
+	}
+	// Windows after the back-off averaged (6 + 9) / 2 successes per 15s
+	if want := 7.5 / scaleInterval.Seconds(); scaling.Capacity != want {
+		t.Errorf("capacity = %v, want %v", scaling.Capacity, want)
+	}
+
+	s.steps = s.steps[:1]
+	if scaling := s.summary(records); scaling.SteadyState || scaling.Capacity != 10/scaleInterval.Seconds() {
+		t.Errorf("without a back-off the best window is a lower bound, got %+v", scaling)
+	}
+
+	var report strings.Builder
+	writeDiagnosticScalingSection(&report, []DiagnosticSummary{
+		{Provider: "nim", Mode: "streaming", Scaling: &WorkerScaling{InitialWorkers: 10, PeakWorkers: 15, Capacity: 0.5, SteadyState: true,
+			Steps: []ScalingStep{{Workers: 10}, {Workers: 15, RateLimited: 2}, {Workers: 7}}}},
+		{Provider: "fixed", Mode: "streaming"},
+	})
+	if !strings.Contains(report.String(), "| nim | streaming | 10 → 15 | 0.50 req/s | 10 → 15 (429) → 7 |") || strings.Contains(report.String(), "fixed") {
+		t.Errorf("unexpected section:\n%s", report.String())
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// blindMappingFileName holds the private label-to-provider mapping of a blinded report.
+const blindMappingFileName = "blind-mapping.json"
+
+// blindReports replaces provider names with Provider A/B/C in generated reports.
+var blindReports bool
+
+// urlPattern matches URLs that could reveal a provider inside error messages.
+var urlPattern = regexp.MustCompile(`https?://\S+`)
+
+// blindLabel returns the anonymous name of the i-th provider: Provider A to Z,
+// then Provider AA, AB, and so on.
+func blindLabel(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return "Provider " + name
+}
+
+// blindResults anonymizes results in place. Labels are assigned in shuffled
+// order, so Provider A is not simply the alphabetically first provider. Provider
+// names and URLs are also scrubbed from error messages. It returns the mapping
+// from label to real provider name.
+func blindResults(results []TestResult, diagnostics []DiagnosticSummary, rng *rand.Rand) map[string]string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, r := range results {
+		if !seen[r.Provider] {
+			seen[r.Provider] = true
+			names = append(names, r.Provider)
+		}
+	}
+	for _, d := range diagnostics {
+		if !seen[d.Provider] {
+			seen[d.Provider] = true
+			names = append(names, d.Provider)
+		}
+	}
+	sort.Strings(names)
+	rng.Shuffle(len(names), func(i, j int) { names[i], names[j] = names[j], names[i] })
+
+	labels := make(map[string]string, len(names))
+	mapping := make(map[string]string, len(names))
+	for i, name := range names {
+		labels[name] = blindLabel(i)
+		mapping[blindLabel(i)] = name
+	}
+
+	// Longer names first, so "nim" never rewrites part of "nimble"
+	byLength := append([]string(nil), names...)
+	sort.Slice(byLength, func(i, j int) bool { return len(byLength[i]) > len(byLength[j]) })
+	scrub := func(msg string) string {
+		msg = urlPattern.ReplaceAllString(msg, "[url]")
+		for _, name := range byLength {
+			re := regexp.MustCompile(`(?i)` + regexp.QuoteMeta(name))
+			msg = re.ReplaceAllString(msg, labels[name])
+		}
+		return msg
+	}
+
+	for i := range results {
+		results[i].Provider = labels[results[i].Provider]
+		results[i].Error = scrub(results[i].Error)
+	}
+	for i := range diagnostics {
+		diagnostics[i].Provider = labels[diagnostics[i].Provider]
+		if len(diagnostics[i].Errors) > 0 {
+			errs := make(map[string]int, len(diagnostics[i].Errors))
+			for msg, n := range diagnostics[i].Errors {
+				errs[scrub(msg)] += n
+			}
+			diagnostics[i].Errors = errs
+		}
+	}
+	return mapping
+}
+
+// writeBlindMapping saves the label-to-provider mapping next to a blinded report.
+func writeBlindMapping(outDir string, mapping map[string]string) error {
+	data, err := json.MarshalIndent(mapping, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling blind mapping: %w", err)
+	}
+	filename := filepath.Join(outDir, blindMappingFileName)
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		return fmt.Errorf("error writing blind mapping: %w", err)
+	}
+	log.Printf("Blind mapping saved: %s (keep this file private; do not share it with the report)", filename)
+	return nil
+}
+
+// blindNote states in a blinded report that provider names are hidden.
+func blindNote(report *strings.Builder) {
+	report.WriteString("*Provider names are blinded. The mapping is kept privately in `" + blindMappingFileName + "`.*\n\n")
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+func TestBlindLabel(t *testing.T) {
+	for i, want := range map[int]string{0: "Provider A", 25: "Provider Z", 26: "Provider AA", 27: "Provider AB"} {
+		if got := blindLabel(i); got != want {
+			t.Errorf("blindLabel(%d) = %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestBlindResultsHidesProviders(t *testing.T) {
+	results := []TestResult{
+		{Provider: "nim", Success: true},
+		{Provider: "novita", Error: "error creating stream: 429 from https://api.novita.ai/openai (Novita rate limit)"},
+	}
+	diagnostics := []DiagnosticSummary{
+		{Provider: "nim", Errors: map[string]int{"nim upstream timeout": 2}},
+	}
+	mapping := blindResults(results, diagnostics, rand.New(rand.NewPCG(1, 2)))
+
+	if len(mapping) != 2 || mapping[results[0].Provider] != "nim" || mapping[results[1].Provider] != "novita" {
+		t.Fatalf("inconsistent mapping %v for %+v", mapping, results)
+	}
+	if diagnostics[0].Provider != results[0].Provider {
+		t.Errorf("same provider got different labels: %q vs %q", diagnostics[0].Provider, results[0].Provider)
+	}
+	for _, text := range []string{results[1].Error, strings.Join(keys(diagnostics[0].Errors), " ")} {
+		lower := strings.ToLower(text)
+		if strings.Contains(lower, "nim") || strings.Contains(lower, "novita") {
+			t.Errorf("error still names a provider: %q", text)
+		}
+	}
+	if !strings.Contains(results[1].Error, "[url]") {
+		t.Errorf("URL not scrubbed: %q", results[1].Error)
+	}
+}
+
+func keys(m map[string]int) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// errBudgetExhausted is returned for runs skipped because the session budget was reached.
+var errBudgetExhausted = errors.New("session budget exhausted")
+
+// canStartRun returns nil if a new request may be started, or the reason it may
+// not: the session budget is exhausted or a graceful shutdown was requested.
+func canStartRun() error {
+	if shutdownCtx.Err() != nil {
+		return errShutdownRequested
+	}
+	if !sessionBudget.allow() {
+		return errBudgetExhausted
+	}
+	return nil
+}
+
+// usageBudget tracks cumulative token usage and estimated cost across a session
+// and reports when the configured limits have been reached.
+type usageBudget struct {
+	mu        sync.Mutex
+	maxTokens int
+	maxCost   float64
+	tokens    int
+	cost      float64
+	exhausted bool
+	// providers splits the usage by provider name, for the session totals in
+	// results and reports.
+	providers map[string]*UsageTotals
+}
+
+// sessionBudget is the budget shared by every provider in the current session.
+var sessionBudget = &usageBudget{}
+
+// enabled reports whether any budget limit is configured.
+func (b *usageBudget) enabled() bool {
+	return b.maxTokens > 0 || b.maxCost > 0
+}
+
+// allow reports whether a new request may be started. Requests already in flight
+// are allowed to finish, so a session may overshoot its limits slightly.
+func (b *usageBudget) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.exhausted
+}
+
+// record adds the usage of a completed request to the session totals.
+func (b *usageBudget) record(config ProviderConfig, promptTokens, completionTokens int) {
+	b.recordFor(providerLabel(config.Name, config.Env), config, promptTokens, completionTokens)
+}
+
+// recordFor is record for usage made on behalf of provider but priced at
+// config, such as judge calls scoring its responses.
+func (b *usageBudget) recordFor(provider string, config ProviderConfig, promptTokens, completionTokens int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cost := estimateCost(config, promptTokens, completionTokens)
+	b.tokens += promptTokens + completionTokens
+	b.cost += cost
+	if b.providers == nil {
+		b.providers = make(map[string]*UsageTotals)
+	}
+	usage, ok := b.providers[provider]
+	if !ok {
+		usage = &UsageTotals{}
+		b.providers[provider] = usage
+	}
+	usage.add(UsageTotals{PromptTokens: promptTokens, CompletionTokens: completionTokens, Cost: cost})
+
+	if b.exhausted {
+		return
+	}
+	if b.maxTokens > 0 && b.tokens >= b.maxTokens {
+		b.exhausted = true
+		log.Printf("Budget: max total tokens reached (%d/%d); remaining runs will be skipped", b.tokens, b.maxTokens)
+	} else if b.maxCost > 0 && b.cost >= b.maxCost {
+		b.exhausted = true
+		log.Printf("Budget: max estimated cost reached ($%.4f/$%.4f); remaining runs will be skipped", b.cost, b.maxCost)
+	}
+}
+
+// totals returns the cumulative tokens and estimated cost recorded so far.
+func (b *usageBudget) totals() (tokens int, cost float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens, b.cost
+}
+
+// usage returns the usage recorded for provider so far, or nil if none was.
+func (b *usageBudget) usage(provider string) *UsageTotals {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	usage, ok := b.providers[provider]
+	if !ok {
+		return nil
+	}
+	u := *usage
+	return &u
+}
+
+// estimateCost returns the estimated USD cost of a request using the provider's
+// configured per-million-token prices. Unpriced providers cost nothing.
+func estimateCost(config ProviderConfig, promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*config.InputPrice + float64(completionTokens)*config.OutputPrice) / 1e6
+}
+
+// countPromptTokens returns the number of tokens in the content of the given messages.
+func countPromptTokens(tke *tiktoken.Tiktoken, messages []openai.ChatCompletionMessage) int {
+	total := 0
+	for _, m := range messages {
+		total += len(tke.Encode(m.Content, nil, nil))
+	}
+	return total
+}
+
+// envFloat reads a float from the named environment variable, returning 0 when
+// unset or invalid.
+func envFloat(name string) float64 {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return 0
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		log.Printf("Warning: ignoring invalid %s=%q: %v", name, raw, err)
+		return 0
+	}
+	return v
+}
+
+// logBudgetUsage prints the session's cumulative usage when a budget is configured.
+func logBudgetUsage() {
+	if !sessionBudget.enabled() {
+		return
+	}
+	tokens, cost := sessionBudget.totals()
+	log.Printf("Session usage: %d tokens, estimated cost $%.4f", tokens, cost)
+}
@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestUsageBudget(t *testing.T) {
+	config := ProviderConfig{Name: "test", InputPrice: 1.0, OutputPrice: 2.0}
+
+	b := &usageBudget{maxTokens: 1000}
+	b.record(config, 200, 300)
+	if !b.allow() {
+		t.Fatalf("expected budget to allow requests after 500/1000 tokens")
+	}
+	b.record(config, 200, 300)
+	if b.allow() {
+		t.Fatalf("expected budget to be exhausted after 1000/1000 tokens")
+	}
+
+	c := &usageBudget{maxCost: 0.001}
+	c.record(config, 100, 100) // $0.0003
+	if !c.allow() {
+		t.Fatalf("expected cost budget to allow requests at $0.0003")
+	}
+	c.record(config, 1000, 1000) // +$0.003
+	if c.allow() {
+		t.Fatalf("expected cost budget to be exhausted")
+	}
+	if tokens, _ := c.totals(); tokens != 2200 {
+		t.Fatalf("expected 2200 tokens recorded, got %d", tokens)
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	config := ProviderConfig{InputPrice: 0.5, OutputPrice: 1.5}
+	got := estimateCost(config, 1_000_000, 2_000_000)
+	if got != 3.5 {
+		t.Fatalf("expected $3.5, got $%f", got)
+	}
+	if estimateCost(ProviderConfig{}, 1000, 1000) != 0 {
+		t.Fatalf("expected unpriced provider to cost nothing")
+	}
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// issueReportFileName is the summary at the root of an issue bundle.
+const issueReportFileName = "ISSUE.md"
+
+// providerFileSuffix matches what follows "<provider prefix>-" in the names of
+// a provider's session files: a timestamp, or the kind of file.
+var providerFileSuffix = regexp.MustCompile(`^(\d|run\d|worker\d|long-story|diagnostic-|headers-|scenario\.log|soak\.log)`)
+
+// isProviderFile reports whether the file called name belongs to the provider
+// whose files start with prefix (see resultFilePrefix). Files of another
+// environment of the same provider, e.g. nim-prod for nim, do not match.
+func isProviderFile(name, prefix string) bool {
+	rest, ok := strings.CutPrefix(name, prefix+"-")
+	return ok && providerFileSuffix.MatchString(rest)
+}
+
+// secretPatterns match credentials that may end up in logs and error messages.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]{8,}`),
+	regexp.MustCompile(`(?i)((?:api[_-]?key|key|token)=)[^&\s"']+`),
+	regexp.MustCompile(`\b(sk-)[A-Za-z0-9_-]{16,}`),
+}
+
+// secretRedactor removes credentials and host details from text put in an
+// issue bundle.
+type secretRedactor struct {
+	// literal maps exact values, such as configured API keys, to their
+	// replacements.
+	literal map[string]string
+}
+
+// newSecretRedactor collects the API keys configured in the environment and the
+// host's name and home directory.
+func newSecretRedactor() *secretRedactor {
+	r := &secretRedactor{literal: make(map[string]string)}
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasSuffix(name, "_API_KEY") && !strings.HasSuffix(name, "_API_KEYS") {
+			continue
+		}
+		for key := range strings.SplitSeq(value, ",") {
+			if key = strings.TrimSpace(key); len(key) >= 8 {
+				r.literal[key] = redactedValue
+			}
+		}
+	}
+	if home, err := os.UserHomeDir(); err == nil && len(home) > 1 {
+		r.literal[home] = "~"
+	}
+	if host, err := os.Hostname(); err == nil && len(host) >= 4 {
+		r.literal[host] = "<host>"
+	}
+	return r
+}
+
+// redact returns text with every secret replaced.
+func (r *secretRedactor) redact(text string) string {
+	// Longest first, so a key is not left half-replaced by a shorter one
+	// contained in it.
+	literals := make([]string, 0, len(r.literal))
+	for value := range r.literal {
+		literals = append(literals, value)
+	}
+	sort.Slice(literals, func(i, j int) bool { return len(literals[i]) > len(literals[j]) })
+	for _, value := range literals {
+		text = strings.ReplaceAll(text, value, r.literal[value])
+	}
+	for _, p := range secretPatterns {
+		text = p.ReplaceAllString(text, "${1}"+redactedValue)
+	}
+	return text
+}
+
+// reproArgs rewrites a session's arguments to benchmark only provider.
+func reproArgs(args []string, provider string) []string {
+	out := []string{"--provider", provider}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			out = append(out, arg)
+			continue
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		switch name {
+		case "all":
+			continue
+		case "provider":
+			if !hasValue {
+				i++
+			}
+			continue
+		}
+		out = append(out, arg)
+	}
+	return out
+}
+
+// reproManifest narrows manifest to provider, so "rerun" on the bundle targets
+// the recorded endpoint and model only.
+func reproManifest(manifest SessionManifest, provider string) SessionManifest {
+	repro := manifest
+	repro.Args = reproArgs(manifest.Args, provider)
+	repro.Providers = nil
+	for _, p := range manifest.Providers {
+		if p.Name == provider {
+			repro.Providers = append(repro.Providers, p)
+		}
+	}
+	repro.Skipped = nil
+	if repro.Environment.Network != nil && repro.Environment.Network.Proxy != "" {
+		network := *repro.Environment.Network
+		network.Proxy = redactedValue
+		repro.Environment.Network = &network
+	}
+	return repro
+}
+
+// issueSummary writes ISSUE.md: what was benchmarked, how it went and how to
+// reproduce it. A manifest without a tool version was not loaded from the
+// session, so no reproduction steps are given.
+func issueSummary(manifest SessionManifest, provider string, results []TestResult, diagnostics []DiagnosticSummary, files []string) string {
+	var report strings.Builder
+	fmt.Fprintf(&report, "# Provider Issue Report: %s\n\n", provider)
+	fmt.Fprintf(&report, "**Session:** %s\n\n", manifest.Session)
+	if !manifest.CreatedAt.IsZero() {
+		fmt.Fprintf(&report, "**Recorded:** %s\n\n", manifest.CreatedAt.UTC().Format(time.RFC3339))
+	}
+	if manifest.ToolVersion != "" {
+		fmt.Fprintf(&report, "**Tool:** llm-api-speed %s (%s, %s/%s)\n\n",
+			manifest.ToolVersion, manifest.Environment.GoVersion, manifest.Environment.OS, manifest.Environment.Arch)
+	}
+	for _, p := range manifest.Providers {
+		if p.Name != provider {
+			continue
+		}
+		fmt.Fprintf(&report, "**Endpoint:** %s, model `%s`", p.BaseURL, p.Model)
+		if p.API != "" {
+			fmt.Fprintf(&report, ", %s API", p.API)
+		}
+		if p.Env != "" {
+			fmt.Fprintf(&report, ", env %s", p.Env)
+		}
+		report.WriteString("\n\n")
+	}
+
+	report.WriteString("## Results\n\n")
+	report.WriteString("| Provider | Mode | Requests | Successful | Avg TTFT | Avg E2E | Avg Throughput |\n")
+	report.WriteString("|----------|------|----------|------------|----------|---------|----------------|\n")
+	errors := make(map[string]int)
+	for _, r := range results {
+		requests, successful := 1, 0
+		if len(r.Runs) > 0 {
+			requests = len(r.Runs)
+		}
+		for _, run := range r.Runs {
+			if run.Success {
+				successful++
+			} else if run.Error != "" {
+				errors[run.Error]++
+			}
+		}
+		if r.Success && len(r.Runs) == 0 {
+			successful = 1
+		}
+		if !r.Success && len(r.Runs) == 0 && r.Error != "" {
+			errors[r.Error]++
+		}
+		fmt.Fprintf(&report, "| %s | %s | %d | %d | %s | %s | %.2f tok/s |\n", providerLabel(r.Provider, r.Env), r.Mode,
+			requests, successful, formatDuration(r.TTFT), formatDuration(r.E2ELatency), r.Throughput)
+	}
+	for _, d := range diagnostics {
+		fmt.Fprintf(&report, "| %s | diagnostic-%s | %d | %d | %s | %s | %.2f tok/s |\n", providerLabel(d.Provider, d.Env), d.Mode,
+			d.TotalRequests, d.Successful, formatDuration(d.AvgTTFT), formatDuration(d.AvgE2ELatency), d.AvgThroughput)
+		for msg, count := range d.Errors {
+			errors[msg] += count
+		}
+	}
+	report.WriteString("\n")
+
+	if len(errors) > 0 {
+		messages := make([]string, 0, len(errors))
+		for msg := range errors {
+			messages = append(messages, msg)
+		}
+		sort.Slice(messages, func(i, j int) bool {
+			if errors[messages[i]] != errors[messages[j]] {
+				return errors[messages[i]] > errors[messages[j]]
+			}
+			return messages[i] < messages[j]
+		})
+		report.WriteString("## Errors\n\n")
+		for _, msg := range messages {
+			fmt.Fprintf(&report, "- `%s` (x%d)\n", msg, errors[msg])
+		}
+		report.WriteString("\n")
+	}
+
+	report.WriteString("## Files\n\n")
+	fmt.Fprintf(&report, "Logs, per-run results, per-request records and response headers (`*-headers-*.json`, the first %d successful and %d failed responses) "+
+		"from the session. API keys, bearer tokens, the host name and the home directory are redacted.\n\n", maxHeaderSamples, maxHeaderSamples)
+	for _, f := range files {
+		fmt.Fprintf(&report, "- `%s`\n", f)
+	}
+	report.WriteString("\n")
+
+	if manifest.ToolVersion == "" {
+		report.WriteString("The session has no manifest, so the bundle cannot be replayed.\n")
+		return report.String()
+	}
+	report.WriteString("## Reproduce\n\n")
+	fmt.Fprintf(&report, "Unzip the bundle, set `%s_API_KEY`, and run:\n\n", providerEnvPrefix(provider))
+	report.WriteString("```bash\nllm-api-speed rerun <unzipped folder>\n```\n\n")
+	fmt.Fprintf(&report, "`manifest.json` pins the endpoint and model above and replays these arguments: `%s`\n",
+		strings.Join(manifest.Args, " "))
+	return report.String()
+}
+
+// writeIssueBundle zips the session files of provider, redacted, with
+// ISSUE.md and a reproduction manifest. It returns how many session files were
+// included.
+func writeIssueBundle(filename, sessionDir, provider string, manifest SessionManifest, redactor *secretRedactor) (int, error) {
+	results, diagnostics, err := loadSessionResults(sessionDir)
+	if err != nil {
+		return 0, err
+	}
+	prefixes := make(map[string]bool)
+	for _, p := range manifest.Providers {
+		if p.Name == provider {
+			prefixes[resultFilePrefix(p.Name, p.Env)] = true
+		}
+	}
+	results = slices.DeleteFunc(results, func(r TestResult) bool { return r.Provider != provider })
+	diagnostics = slices.DeleteFunc(diagnostics, func(d DiagnosticSummary) bool { return d.Provider != provider })
+	for _, r := range results {
+		prefixes[resultFilePrefix(r.Provider, r.Env)] = true
+	}
+	for _, d := range diagnostics {
+		prefixes[resultFilePrefix(d.Provider, d.Env)] = true
+	}
+	if len(prefixes) == 0 {
+		return 0, fmt.Errorf("provider %s is not part of session %s", provider, filepath.Base(sessionDir))
+	}
+
+	var files []string
+	for _, dir := range []string{"", "logs"} {
+		entries, err := os.ReadDir(filepath.Join(sessionDir, dir))
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+			for prefix := range prefixes {
+				if isProviderFile(e.Name(), prefix) {
+					files = append(files, filepath.ToSlash(filepath.Join(dir, e.Name())))
+					break
+				}
+			}
+		}
+	}
+	sort.Strings(files)
+
+	f, err := os.OpenFile(filepath.Clean(filename), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, fmt.Errorf("error creating %s: %w", filename, err)
+	}
+	zw := zip.NewWriter(f)
+	add := func(name, content string) error {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		_, err = w.Write([]byte(content))
+		return err
+	}
+	repro := reproManifest(manifest, provider)
+	err = add(issueReportFileName, redactor.redact(issueSummary(repro, provider, results, diagnostics, files)))
+	if err == nil && repro.ToolVersion != "" {
+		var data []byte
+		if data, err = json.MarshalIndent(repro, "", "  "); err == nil {
+			err = add(manifestFileName, redactor.redact(string(data)))
+		}
+	}
+	for _, name := range files {
+		if err != nil {
+			break
+		}
+		var data []byte
+		if data, err = os.ReadFile(filepath.Join(sessionDir, filepath.FromSlash(name))); err == nil {
+			err = add(name, redactor.redact(string(data)))
+		}
+	}
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error writing %s: %w", filename, err)
+	}
+	return len(files), nil
+}
+
+// runBundleIssue implements the "bundle-issue" subcommand: it packs what a
+// provider's support team needs to look into a problem seen in one session.
+func runBundleIssue(args []string) {
+	fs := flag.NewFlagSet("bundle-issue", flag.ExitOnError)
+	out := fs.String("out", "", "Output zip file (default: <session>/issue-<provider>-<timestamp>.zip)")
+	fs.StringVar(&resultsRoot, "results-dir", resultsRoot, "Folder bare session names are looked up in")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: llm-api-speed bundle-issue [--out file.zip] <session> <provider>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		log.Fatalf("Error parsing flags: %v", err)
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	session, provider := fs.Arg(0), fs.Arg(1)
+	sessionDir := resolveSessionDir(session)
+	if info, err := os.Stat(sessionDir); err != nil || !info.IsDir() {
+		log.Fatalf("Error: session %s not found", session)
+	}
+	manifest, err := loadManifest(sessionDir)
+	if err != nil {
+		log.Printf("Warning: %v; the bundle will not be replayable with rerun", err)
+		manifest = SessionManifest{Session: filepath.Base(sessionDir)}
+	}
+	filename := *out
+	if filename == "" {
+		filename = filepath.Join(sessionDir, fmt.Sprintf("issue-%s-%s.zip", provider, time.Now().Format("20060102-150405")))
+	}
+	n, err := writeIssueBundle(filename, sessionDir, provider, manifest, newSecretRedactor())
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	log.Printf("Issue bundle for %s written to %s (%d session file(s), secrets redacted)", provider, filename, n)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestIsProviderFile(t *testing.T) {
+	tests := []struct {
+		name, prefix string
+		want         bool
+	}{
+		{"nim-20251110-004642.json", "nim", true},
+		{"nim-diagnostic-records-20251110-004642.jsonl", "nim", true},
+		{"nim-run2-streaming-response.txt", "nim", true},
+		{"nim-headers-20251110-004642.json", "nim", true},
+		{"nim-prod-20251110-004642.json", "nim", false},
+		{"nim-prod-20251110-004642.json", "nim-prod", true},
+		{"nimble-20251110-004642.json", "nim", false},
+	}
+	for _, tt := range tests {
+		if got := isProviderFile(tt.name, tt.prefix); got != tt.want {
+			t.Errorf("isProviderFile(%q, %q) = %t, want %t", tt.name, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestReproArgs(t *testing.T) {
+	got := reproArgs([]string{"--all", "--mixed", "-provider", "novita", "--provider=groq", "--iterations", "5"}, "nim")
+	want := []string{"--provider", "nim", "--mixed", "--iterations", "5"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("reproArgs = %q, want %q", got, want)
+	}
+}
+
+func TestSecretRedactor(t *testing.T) {
+	r := &secretRedactor{literal: map[string]string{"nvapi-abcdefgh12345678": redactedValue, "/home/alice": "~"}}
+	got := r.redact("key nvapi-abcdefgh12345678 in /home/alice/results; Authorization: Bearer abc.def-123456 " +
+		"url https://x/v1?key=AIzaSecret&alt=sse sk-proj-0123456789abcdefXYZ")
+	for _, leaked := range []string{"nvapi-", "alice", "abc.def", "AIzaSecret", "0123456789abcdef"} {
+		if strings.Contains(got, leaked) {
+			t.Errorf("%q leaked in %q", leaked, got)
+		}
+	}
+	if !strings.Contains(got, "~/results") || !strings.Contains(got, "Bearer REDACTED") || !strings.Contains(got, "key=REDACTED&alt=sse") {
+		t.Errorf("unexpected redaction %q", got)
+	}
+}
+
+func TestWriteIssueBundle(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "logs"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manifest := SessionManifest{
+		Session: "20251110-004642", ToolVersion: "1.2.3", Args: []string{"--all"},
+		Providers: []ManifestProvider{
+			{Name: "nim", BaseURL: "https://integrate.api.nvidia.com/v1", Model: "m", APIKey: redactedValue},
+			{Name: "novita", BaseURL: "https://api.novita.ai/openai", Model: "m2", APIKey: redactedValue},
+		},
+	}
+	data, _ := json.Marshal(manifest)
+	write(manifestFileName, string(data))
+	result, _ := json.Marshal(TestResult{Provider: "nim", Model: "m", Mode: "streaming", Error: "stream error: 502",
+		Runs: []RunSample{{Iteration: 1, Success: true}, {Iteration: 2, Error: "stream error: 502"}}})
+	write("nim-20251110-004642.json", string(result))
+	write("novita-20251110-004642.json", `{"provider":"novita","mode":"streaming"}`)
+	write("logs/nim-20251110-004642.log", "request failed with key nvapi-secret-value-1234")
+	write("logs/novita-20251110-004642.log", "other provider")
+
+	filename := filepath.Join(dir, "issue.zip")
+	r := &secretRedactor{literal: map[string]string{"nvapi-secret-value-1234": redactedValue}}
+	n, err := writeIssueBundle(filename, dir, "nim", manifest, r)
+	if err != nil || n != 2 {
+		t.Fatalf("writeIssueBundle = %d, %v; want 2 files", n, err)
+	}
+
+	zr, err := zip.OpenReader(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	contents := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		contents[f.Name] = string(b)
+	}
+	var names []string
+	for name := range contents {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	want := []string{issueReportFileName, "logs/nim-20251110-004642.log", manifestFileName, "nim-20251110-004642.json"}
+	if !slices.Equal(names, want) {
+		t.Fatalf("bundle holds %q, want %q", names, want)
+	}
+	if strings.Contains(contents["logs/nim-20251110-004642.log"], "nvapi-secret") {
+		t.Error("API key leaked into the bundle")
+	}
+	issue := contents[issueReportFileName]
+	if !strings.Contains(issue, "| nim | streaming | 2 | 1 |") || !strings.Contains(issue, "- `stream error: 502` (x1)") ||
+		!strings.Contains(issue, "`--provider nim`") {
+		t.Errorf("unexpected ISSUE.md:\n%s", issue)
+	}
+	var repro SessionManifest
+	if err := json.Unmarshal([]byte(contents[manifestFileName]), &repro); err != nil {
+		t.Fatal(err)
+	}
+	if len(repro.Providers) != 1 || repro.Providers[0].Name != "nim" || !slices.Equal(repro.Args, []string{"--provider", "nim"}) {
+		t.Errorf("unexpected reproduction manifest %+v", repro)
+	}
+
+	if _, err := writeIssueBundle(filepath.Join(dir, "none.zip"), dir, "groq", manifest, r); err == nil {
+		t.Error("expected an error for a provider not in the session")
+	}
+}
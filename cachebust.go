@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// cacheBust appends a unique nonce to every benchmark prompt; set with
+// --cache-bust.
+var cacheBust bool
+
+// cacheBustNonce is the sentence appended to a prompt with --cache-bust. It is
+// phrased so models leave it out of their answer.
+const cacheBustNonce = "\n\n(Request ID: %016x. This is metadata; do not mention it.)"
+
+// bustCache returns req with a random nonce appended to its last user message,
+// so a provider or gateway response cache cannot serve a repeated prompt. The
+// nonce goes at the end to leave the prompt prefix, and any prefix caching of
+// it, untouched. The messages are copied: callers may keep the history. Without
+// --cache-bust req is returned unchanged.
+func bustCache(req openai.ChatCompletionRequest) openai.ChatCompletionRequest {
+	if !cacheBust {
+		return req
+	}
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role != openai.ChatMessageRoleUser {
+			continue
+		}
+		messages := append([]openai.ChatCompletionMessage(nil), req.Messages...)
+		messages[i].Content += fmt.Sprintf(cacheBustNonce, rand.Uint64()) // #nosec G404 -- uniqueness, not security
+		req.Messages = messages
+		break
+	}
+	return req
+}
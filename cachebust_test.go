@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestBustCache(t *testing.T) {
+	history := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: "system"},
+		{Role: openai.ChatMessageRoleUser, Content: "first"},
+		{Role: openai.ChatMessageRoleAssistant, Content: "reply"},
+		{Role: openai.ChatMessageRoleUser, Content: "second"},
+	}
+	req := openai.ChatCompletionRequest{Messages: history}
+
+	cacheBust = false
+	if got := bustCache(req); got.Messages[3].Content != "second" {
+		t.Fatalf("expected no nonce without --cache-bust, got %q", got.Messages[3].Content)
+	}
+
+	cacheBust = true
+	defer func() { cacheBust = false }()
+	a, b := bustCache(req), bustCache(req)
+	if !strings.HasPrefix(a.Messages[3].Content, "second\n\n(Request ID: ") {
+		t.Fatalf("expected the nonce after the last user message, got %q", a.Messages[3].Content)
+	}
+	if a.Messages[3].Content == b.Messages[3].Content {
+		t.Fatalf("expected a different nonce per request, got %q twice", a.Messages[3].Content)
+	}
+	if a.Messages[1].Content != "first" || a.Messages[0].Content != "system" {
+		t.Fatalf("expected only the last user message to change, got %+v", a.Messages)
+	}
+	if history[3].Content != "second" {
+		t.Fatalf("expected the caller's history to be left alone, got %q", history[3].Content)
+	}
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lamim/llm-api-speed/pkg/providers"
+	"github.com/pkoukk/tiktoken-go"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const cancelProbeModeLabel = "cancel-probe"
+
+// cancelProbeMaxTokens is the max_tokens of every cancel-probe request: what a
+// provider that ignores the cancellation could bill at most.
+const cancelProbeMaxTokens = 2048
+
+// cancelProbeTimeout bounds one cancel-probe request.
+const cancelProbeTimeout = 2 * time.Minute
+
+// Billed usage of a cancelled request is looked up after the fact; the
+// provider may need a few seconds before it is available.
+const (
+	cancelUsageAttempts   = 5
+	cancelUsageRetryDelay = 2 * time.Second
+)
+
+// errUsageNotReady is returned while a provider has no usage for a request yet.
+var errUsageNotReady = errors.New("usage not available yet")
+
+// cancelProbeOptions configures a cancel-probe session.
+type cancelProbeOptions struct {
+	after int
+	runs  int
+}
+
+// CancelProbeRun is one request cancelled by the client mid-stream.
+type CancelProbeRun struct {
+	Run int `json:"run"`
+	// Received is the completion tokens streamed before the cancellation,
+	// counted with the local tokenizer.
+	Received int    `json:"received"`
+	ID       string `json:"id,omitempty"`
+	// Billed is the completion tokens the provider charged for, when its usage
+	// can be queried; Cost is what it charged for the whole request in USD.
+	Billed *int          `json:"billed,omitempty"`
+	Cost   float64       `json:"cost,omitempty"`
+	TTFT   time.Duration `json:"ttft,omitempty"`
+	Error  string        `json:"error,omitempty"`
+	// UsageError says why billed usage could not be retrieved.
+	UsageError string `json:"usageError,omitempty"`
+}
+
+// extra is the tokens billed beyond the cancellation point, or -1 if unknown.
+func (r CancelProbeRun) extra() int {
+	if r.Billed == nil {
+		return -1
+	}
+	return max(*r.Billed-r.Received, 0)
+}
+
+// CancelProbeSummary is the cost of aborting streams for one provider.
+type CancelProbeSummary struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	// UsageQueryable is false for providers whose billed usage cannot be looked
+	// up after a cancellation.
+	UsageQueryable bool `json:"usageQueryable"`
+	// AvgExtra is the mean tokens billed beyond the cancellation point over
+	// runs whose usage was retrieved, and ExtraCost their estimated cost at the
+	// provider's output price.
+	AvgExtra  float64          `json:"avgExtra,omitempty"`
+	ExtraCost float64          `json:"extraCost,omitempty"`
+	Runs      []CancelProbeRun `json:"runs"`
+}
+
+// usageQueryable reports whether the provider's billed usage can be looked up
+// by response ID. Only OpenRouter exposes this, through its generation stats.
+func usageQueryable(config ProviderConfig) bool {
+	return isOpenRouter(config.BaseURL) && (config.API == "" || config.API == providers.APIOpenAI)
+}
+
+// openRouterGeneration is the part of OpenRouter's generation stats used here.
+type openRouterGeneration struct {
+	Data struct {
+		TokensCompletion       int     `json:"tokens_completion"`
+		NativeTokensCompletion int     `json:"native_tokens_completion"`
+		TotalCost              float64 `json:"total_cost"`
+	} `json:"data"`
+}
+
+// fetchGenerationUsage looks up the completion tokens and cost OpenRouter
+// billed for generation id. Tokens are the upstream provider's native count,
+// which is what is charged, falling back to OpenRouter's normalized count.
+func fetchGenerationUsage(ctx context.Context, config ProviderConfig, id string) (int, float64, error) {
+	endpoint := strings.TrimSuffix(config.BaseURL, "/") + "/generation?id=" + url.QueryEscape(id)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+config.APIKey)
+	resp, err := providerHTTPClient(config).Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return 0, 0, errUsageNotReady
+	case resp.StatusCode != http.StatusOK:
+		return 0, 0, fmt.Errorf("generation stats returned %s", resp.Status)
+	}
+	var gen openRouterGeneration
+	if err := json.NewDecoder(resp.Body).Decode(&gen); err != nil {
+		return 0, 0, fmt.Errorf("error decoding generation stats: %w", err)
+	}
+	tokens := gen.Data.NativeTokensCompletion
+	if tokens == 0 {
+		tokens = gen.Data.TokensCompletion
+	}
+	return tokens, gen.Data.TotalCost, nil
+}
+
+// billedUsage retries fetchGenerationUsage until the usage is available.
+func billedUsage(config ProviderConfig, id string) (int, float64, error) {
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(shutdownCtx, 30*time.Second)
+		tokens, cost, err := fetchGenerationUsage(ctx, config, id)
+		cancel()
+		if !errors.Is(err, errUsageNotReady) || attempt == cancelUsageAttempts {
+			return tokens, cost, err
+		}
+		select {
+		case <-time.After(cancelUsageRetryDelay):
+		case <-shutdownCtx.Done():
+			return 0, 0, shutdownCtx.Err()
+		}
+	}
+}
+
+// streamUntilCancelled streams a long response and cancels it once after
+// tokens have arrived.
+func streamUntilCancelled(config ProviderConfig, tke *tiktoken.Tiktoken, after, run int) CancelProbeRun {
+	result := CancelProbeRun{Run: run}
+	req := openai.ChatCompletionRequest{
+		Model:     config.Model,
+		Messages:  []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: maxOutputPrompt}},
+		MaxTokens: cancelProbeMaxTokens,
+		Stream:    true,
+	}
+	req = config.Quirks.apply(req)
+
+	ctx, cancel := context.WithTimeout(shutdownCtx, cancelProbeTimeout)
+	defer cancel()
+	start := time.Now()
+	stream, err := providers.OpenStream(ctx, benchProvider(config), req)
+	if err != nil {
+		result.Error = fmt.Sprintf("error creating stream: %v", err)
+		return result
+	}
+
+	var output strings.Builder
+	for result.Received < after {
+		delta, recvErr := stream.Recv()
+		if errors.Is(recvErr, io.EOF) {
+			result.Error = fmt.Sprintf("response ended after %d tokens, before the cancellation point", result.Received)
+			break
+		}
+		if recvErr != nil {
+			result.Error = fmt.Sprintf("stream error: %v", recvErr)
+			break
+		}
+		if result.ID == "" {
+			result.ID = delta.ID
+		}
+		if result.TTFT == 0 && !delta.Empty() {
+			result.TTFT = time.Since(start)
+		}
+		output.WriteString(delta.Content)
+		output.WriteString(delta.Reasoning)
+		result.Received = len(tke.Encode(output.String(), nil, nil))
+	}
+	// Cancelling the context aborts the request the way a client giving up
+	// would: the connection is torn down without reading the rest
+	cancel()
+	_ = stream.Close()
+	return result
+}
+
+// probeCancellation cancels one stream and looks up what it was billed.
+func probeCancellation(config ProviderConfig, tke *tiktoken.Tiktoken, after, run int) CancelProbeRun {
+	result := streamUntilCancelled(config, tke, after, run)
+	promptTokens := countPromptTokens(tke, []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: maxOutputPrompt}})
+	completionTokens := result.Received
+	defer func() {
+		sessionBudget.record(config, promptTokens, completionTokens)
+	}()
+	if result.Error != "" || !usageQueryable(config) {
+		return result
+	}
+	if result.ID == "" {
+		result.UsageError = "stream carried no response ID"
+		return result
+	}
+	billed, cost, err := billedUsage(config, result.ID)
+	if err != nil {
+		result.UsageError = err.Error()
+		return result
+	}
+	result.Billed = &billed
+	result.Cost = cost
+	completionTokens = max(completionTokens, billed)
+	return result
+}
+
+// probeCancellations runs the cancel probe opts.runs times for one provider.
+func probeCancellations(config ProviderConfig, tke *tiktoken.Tiktoken, opts cancelProbeOptions) CancelProbeSummary {
+	summary := CancelProbeSummary{Provider: config.Name, Model: config.Model, UsageQueryable: usageQueryable(config)}
+	var extraSum, measured int
+	for run := 1; run <= opts.runs; run++ {
+		if err := canStartRun(); err != nil {
+			log.Printf("[%s] Cancel-probe run %d skipped: %v", config.Name, run, err)
+			break
+		}
+		r := probeCancellation(config, tke, opts.after, run)
+		switch {
+		case r.Error != "":
+			log.Printf("[%s] Cancel-probe run %d failed: %s", config.Name, run, r.Error)
+		case r.Billed != nil:
+			log.Printf("[%s] Cancel-probe run %d: cancelled after %d tokens, billed %d", config.Name, run, r.Received, *r.Billed)
+			extraSum += r.extra()
+			measured++
+		case r.UsageError != "":
+			log.Printf("[%s] Cancel-probe run %d: cancelled after %d tokens, usage lookup failed: %s", config.Name, run, r.Received, r.UsageError)
+		default:
+			log.Printf("[%s] Cancel-probe run %d: cancelled after %d tokens", config.Name, run, r.Received)
+		}
+		summary.Runs = append(summary.Runs, r)
+	}
+	if measured > 0 {
+		summary.AvgExtra = float64(extraSum) / float64(measured)
+		summary.ExtraCost = float64(extraSum) * config.OutputPrice / 1e6
+	}
+	return summary
+}
+
+// runCancelProbe probes every provider concurrently and writes
+// cancel-probe-summary.json and CANCEL-PROBE-REPORT.md.
+func runCancelProbe(providers []ProviderConfig, tke *tiktoken.Tiktoken, opts cancelProbeOptions, resultsDir, sessionTimestamp string) error {
+	log.Printf("=== CANCEL PROBE: %d provider(s), cancel after %d tokens, %d run(s) ===", len(providers), opts.after, opts.runs)
+
+	summaries := make([]CancelProbeSummary, len(providers))
+	var wg sync.WaitGroup
+	for i, p := range providers {
+		wg.Add(1)
+		go func(i int, p ProviderConfig) {
+			defer wg.Done()
+			summaries[i] = probeCancellations(p, tke, opts)
+		}(i, p)
+	}
+	wg.Wait()
+
+	data, err := json.MarshalIndent(summaries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling cancel probe summary: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(resultsDir, "cancel-probe-summary.json"), data, 0600); err != nil {
+		return fmt.Errorf("error writing cancel probe summary: %w", err)
+	}
+	return generateCancelProbeReport(resultsDir, summaries, opts, sessionTimestamp)
+}
+
+// generateCancelProbeReport writes CANCEL-PROBE-REPORT.md.
+func generateCancelProbeReport(resultsDir string, summaries []CancelProbeSummary, opts cancelProbeOptions, sessionTimestamp string) error {
+	filename := filepath.Join(resultsDir, "CANCEL-PROBE-REPORT.md")
+
+	var report strings.Builder
+	report.WriteString("# LLM API Cancel Probe\n\n")
+	fmt.Fprintf(&report, "**Test Session:** %s\n\n", sessionTimestamp)
+	fmt.Fprintf(&report, "Each run streams a response that never ends on its own (max_tokens %d) and cancels it after %d tokens. "+
+		"Where the provider's billed usage can be queried afterwards (OpenRouter generation stats), the tokens billed beyond the "+
+		"cancellation point are what aborting the stream really cost. Received tokens are counted with the local tokenizer, billed "+
+		"tokens with the provider's; small differences are counting noise.\n\n",
+		cancelProbeMaxTokens, opts.after)
+	report.WriteString("---\n\n")
+
+	report.WriteString("## Summary\n\n")
+	report.WriteString("| Provider | Model | Usage Queryable | Avg Billed Beyond Cancel | Est. Extra Cost |\n")
+	report.WriteString("|----------|-------|-----------------|--------------------------|-----------------|\n")
+	for _, s := range summaries {
+		queryable, extra, cost := "no", NotAvailable, NotAvailable
+		if s.UsageQueryable {
+			queryable = "yes"
+		}
+		measured := false
+		for _, r := range s.Runs {
+			measured = measured || r.Billed != nil
+		}
+		if measured {
+			extra = fmt.Sprintf("%.0f", s.AvgExtra)
+			if s.ExtraCost > 0 {
+				cost = fmt.Sprintf("$%.6f", s.ExtraCost)
+			}
+		}
+		fmt.Fprintf(&report, "| %s | %s | %s | %s | %s |\n", s.Provider, s.Model, queryable, extra, cost)
+	}
+	report.WriteString("\n")
+
+	report.WriteString("## Runs\n\n")
+	report.WriteString("| Provider | Run | TTFT | Received | Billed | Beyond Cancel | Billed Cost | Error |\n")
+	report.WriteString("|----------|-----|------|----------|--------|---------------|-------------|-------|\n")
+	for _, s := range summaries {
+		for _, r := range s.Runs {
+			billed, extra, cost := NotAvailable, NotAvailable, NotAvailable
+			if r.Billed != nil {
+				billed = fmt.Sprintf("%d", *r.Billed)
+				extra = fmt.Sprintf("%d", r.extra())
+				cost = fmt.Sprintf("$%.6f", r.Cost)
+			}
+			errMsg := r.Error
+			if errMsg == "" {
+				errMsg = r.UsageError
+			}
+			fmt.Fprintf(&report, "| %s | %d | %s | %d | %s | %s | %s | %s |\n",
+				s.Provider, r.Run, formatDurationOrNA(r.TTFT), r.Received, billed, extra, cost, errMsg)
+		}
+	}
+	report.WriteString("\n")
+
+	report.WriteString("---\n\n")
+	fmt.Fprintf(&report, "*Report generated at %s*\n", time.Now().Format("2006-01-02 15:04:05"))
+
+	if err := os.WriteFile(filename, []byte(report.String()), 0600); err != nil {
+		return fmt.Errorf("error writing cancel probe report: %w", err)
+	}
+	log.Printf("Cancel probe report generated: %s", filename)
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// endlessServer streams 10-byte chunks with response ID gen-1 until the client
+// goes away.
+func endlessServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 1000 && r.Context().Err() == nil; i++ {
+			fmt.Fprint(w, "data: {\"id\":\"gen-1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"0123456789\"}}]}\n\n")
+			w.(http.Flusher).Flush()
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestProbeCancellations(t *testing.T) {
+	config := ProviderConfig{Name: "mock", BaseURL: endlessServer(t).URL, APIKey: "k", Model: "m"}
+	summary := probeCancellations(config, testTokenizer(t), cancelProbeOptions{after: 25, runs: 2})
+	if summary.UsageQueryable || len(summary.Runs) != 2 {
+		t.Fatalf("expected two runs without usage lookup, got %+v", summary)
+	}
+	for _, r := range summary.Runs {
+		if r.Error != "" || r.Received != 30 || r.ID != "gen-1" || r.Billed != nil {
+			t.Fatalf("expected a cancellation after 30 tokens of gen-1, got %+v", r)
+		}
+	}
+}
+
+func TestFetchGenerationUsage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/generation" || r.Header.Get("Authorization") != "Bearer k" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Query().Get("id") {
+		case "native":
+			fmt.Fprint(w, `{"data":{"tokens_completion":120,"native_tokens_completion":140,"total_cost":0.0021}}`)
+		case "normalized":
+			fmt.Fprint(w, `{"data":{"tokens_completion":120,"total_cost":0.0018}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	config := ProviderConfig{Name: "or", BaseURL: srv.URL + "/api/v1/", APIKey: "k"}
+
+	if tokens, cost, err := fetchGenerationUsage(context.Background(), config, "native"); err != nil || tokens != 140 || cost != 0.0021 {
+		t.Errorf("native: got %d, %v, %v; want 140 native tokens", tokens, cost, err)
+	}
+	if tokens, _, err := fetchGenerationUsage(context.Background(), config, "normalized"); err != nil || tokens != 120 {
+		t.Errorf("normalized: got %d, %v; want 120", tokens, err)
+	}
+	if _, _, err := fetchGenerationUsage(context.Background(), config, "pending"); !errors.Is(err, errUsageNotReady) {
+		t.Errorf("pending: got %v, want errUsageNotReady", err)
+	}
+}
+
+func TestUsageQueryable(t *testing.T) {
+	if !usageQueryable(ProviderConfig{BaseURL: "https://openrouter.ai/api/v1"}) {
+		t.Error("expected OpenRouter usage to be queryable")
+	}
+	if usageQueryable(ProviderConfig{BaseURL: "https://api.openai.com/v1"}) {
+		t.Error("expected other providers' usage not to be queryable")
+	}
+}
+
+func TestGenerateCancelProbeReport(t *testing.T) {
+	billed := 180
+	summaries := []CancelProbeSummary{
+		{Provider: "or", Model: "m", UsageQueryable: true, AvgExtra: 130, ExtraCost: 0.00026,
+			Runs: []CancelProbeRun{{Run: 1, Received: 50, Billed: &billed, Cost: 0.0004}}},
+		{Provider: "plain", Model: "m", Runs: []CancelProbeRun{{Run: 1, Received: 52}}},
+	}
+	dir := t.TempDir()
+	if err := generateCancelProbeReport(dir, summaries, cancelProbeOptions{after: 50, runs: 1}, "20250101-000000"); err != nil {
+		t.Fatalf("generateCancelProbeReport failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "CANCEL-PROBE-REPORT.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(data)
+	for _, want := range []string{
+		"| or | m | yes | 130 | $0.000260 |",
+		"| plain | m | no | N/A | N/A |",
+		"| or | 1 | N/A | 50 | 180 | 130 | $0.000400 |",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// enableCbreak is unsupported on this platform; keys must be followed by Enter.
+func enableCbreak(*os.File) (restore func(), err error) {
+	return nil, errors.New("cbreak mode not supported on this platform")
+}
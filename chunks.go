@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lamim/llm-api-speed/pkg/benchmark"
+)
+
+// batchedChunkMean is the mean tokens per chunk above which a stream is flagged
+// as batched in reports.
+const batchedChunkMean = 8
+
+// chunkStatsRow is one entry of the tokens-per-chunk table.
+type chunkStatsRow struct {
+	provider string
+	mode     string
+	stats    *benchmark.ChunkStats
+}
+
+// writeChunkStatsRows renders the tokens-per-chunk table; the histogram columns
+// are the share of chunks in each size bucket.
+func writeChunkStatsRows(report *strings.Builder, rows []chunkStatsRow) {
+	if len(rows) == 0 {
+		return
+	}
+	report.WriteString("## Tokens per Chunk\n\n")
+	fmt.Fprintf(report, "How many tokens each SSE chunk carried. Streams averaging more than %d tokens per chunk are "+
+		"marked *batched*: they arrive in bursts and feel laggy even at high tok/s.\n\n", batchedChunkMean)
+
+	header := "| Provider | Mode | Chunks | Mean | Max |"
+	divider := "|----------|------|--------|------|-----|"
+	for i := 0; i < benchmark.ChunkBuckets; i++ {
+		header += " " + benchmark.ChunkBucketLabel(i) + " |"
+		divider += "---|"
+	}
+	report.WriteString(header + "\n" + divider + "\n")
+
+	for _, r := range rows {
+		mean := fmt.Sprintf("%.1f", r.stats.Mean())
+		if r.stats.Mean() > batchedChunkMean {
+			mean += " *batched*"
+		}
+		fmt.Fprintf(report, "| %s | %s | %d | %s | %d |", r.provider, r.mode, r.stats.Chunks, mean, r.stats.Max)
+		for _, n := range r.stats.Histogram {
+			fmt.Fprintf(report, " %.0f%% |", 100*float64(n)/float64(r.stats.Chunks))
+		}
+		report.WriteString("\n")
+	}
+	report.WriteString("\n")
+}
+
+// writeChunkStatsSection adds the tokens-per-chunk table for results that have
+// chunk statistics.
+func writeChunkStatsSection(report *strings.Builder, results []TestResult) {
+	rows := make([]chunkStatsRow, 0, len(results))
+	for _, r := range results {
+		if r.ChunkStats != nil && r.ChunkStats.Chunks > 0 {
+			rows = append(rows, chunkStatsRow{providerLabel(r.Provider, r.Env), r.Mode, r.ChunkStats})
+		}
+	}
+	writeChunkStatsRows(report, rows)
+}
+
+// writeDiagnosticChunkStatsSection is the diagnostic-report counterpart of
+// writeChunkStatsSection.
+func writeDiagnosticChunkStatsSection(report *strings.Builder, results []DiagnosticSummary) {
+	rows := make([]chunkStatsRow, 0, len(results))
+	for _, r := range results {
+		if r.ChunkStats != nil && r.ChunkStats.Chunks > 0 {
+			rows = append(rows, chunkStatsRow{providerLabel(r.Provider, r.Env), r.Mode, r.ChunkStats})
+		}
+	}
+	writeChunkStatsRows(report, rows)
+}
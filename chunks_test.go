@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lamim/llm-api-speed/pkg/benchmark"
+)
+
+func TestChunkStatsSectionFlagsBatchedStreams(t *testing.T) {
+	tracker := newStreamTracker()
+	var smooth, batched benchmark.ChunkStats
+	for i := 0; i < 4; i++ {
+		smooth.Add(1)
+		batched.Add(20)
+	}
+	tracker.add("smooth", benchmark.Sample{Chunks: smooth})
+	tracker.add("batched", benchmark.Sample{Chunks: batched})
+	if tracker.chunks("missing") != nil {
+		t.Fatal("expected no stats for an unknown provider")
+	}
+
+	var report strings.Builder
+	writeChunkStatsSection(&report, []TestResult{
+		{Provider: "smooth", Mode: "streaming", Success: true, ChunkStats: tracker.chunks("smooth")},
+		{Provider: "batched", Mode: "streaming", Success: true, ChunkStats: tracker.chunks("batched")},
+		{Provider: "old-result", Mode: "streaming", Success: true},
+	})
+	out := report.String()
+	for _, want := range []string{
+		"| Provider | Mode | Chunks | Mean | Max | 1 | 2 | 3-4 | 5-8 | 9-16 | 17-32 | 33+ |",
+		"| smooth | streaming | 4 | 1.0 | 1 | 100% | 0% | 0% | 0% | 0% | 0% | 0% |",
+		"| batched | streaming | 4 | 20.0 *batched* | 20 | 0% | 0% | 0% | 0% | 0% | 100% | 0% |",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "old-result") {
+		t.Error("results without chunk stats must be skipped")
+	}
+}
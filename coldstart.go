@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkoukk/tiktoken-go"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const coldStartModeLabel = "cold-start"
+
+// coldStartRetryDelay is the pause between attempts while an endpoint is waking.
+const coldStartRetryDelay = time.Second
+
+// coldStartOptions configures a cold start probe session.
+type coldStartOptions struct {
+	idle        time.Duration
+	cycles      int
+	warmRuns    int
+	wakeTimeout time.Duration
+}
+
+// ColdStartCycle is one idle-then-wake measurement followed by warm requests.
+type ColdStartCycle struct {
+	Cycle     int           `json:"cycle"`
+	Attempts  int           `json:"attempts"`
+	TimeTo200 time.Duration `json:"timeTo200"`
+	ColdTTFT  time.Duration `json:"coldTtft"`
+	ColdE2E   time.Duration `json:"coldE2e"`
+	WarmTTFT  time.Duration `json:"warmTtft"`
+	WarmRuns  int           `json:"warmRuns"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// ColdStartSummary aggregates the cold start cycles of one provider.
+type ColdStartSummary struct {
+	Provider     string           `json:"provider"`
+	Model        string           `json:"model"`
+	Idle         time.Duration    `json:"idle"`
+	Cycles       []ColdStartCycle `json:"cycles"`
+	AvgTimeTo200 time.Duration    `json:"avgTimeTo200"`
+	AvgColdTTFT  time.Duration    `json:"avgColdTtft"`
+	AvgWarmTTFT  time.Duration    `json:"avgWarmTtft"`
+	WakePenalty  time.Duration    `json:"wakePenalty"`
+}
+
+// retryableWhileWaking reports whether err looks like an endpoint that is still
+// starting up (gateway errors, throttling, or a dropped connection) rather than a
+// permanent failure such as a bad key or model name.
+func retryableWhileWaking(err error) bool {
+	status := 0
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	switch {
+	case errors.As(err, &apiErr):
+		status = apiErr.HTTPStatusCode
+	case errors.As(err, &reqErr):
+		status = reqErr.HTTPStatusCode
+	default:
+		return true
+	}
+	switch status {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests:
+		return true
+	}
+	return status >= 500
+}
+
+// wakeProbe sends requests until one returns a successful response or the wake
+// timeout expires. Timings are measured from the first attempt, so they include
+// every failed attempt and retry delay spent while the endpoint was waking.
+func wakeProbe(config ProviderConfig, tke *tiktoken.Tiktoken, timeout time.Duration, cycle int) ColdStartCycle {
+	result := ColdStartCycle{Cycle: cycle}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	for {
+		result.Attempts++
+		o := raceStream(ctx, config, tke, fmt.Sprintf("cold%d-%d", cycle, result.Attempts), nil)
+		if o.err == nil {
+			result.TimeTo200 = o.headers.Sub(start)
+			result.ColdTTFT = o.firstToken.Sub(start)
+			result.ColdE2E = o.end.Sub(start)
+			return result
+		}
+		if !o.headers.IsZero() || !retryableWhileWaking(o.err) {
+			result.Error = o.err.Error()
+			return result
+		}
+		select {
+		case <-ctx.Done():
+			result.Error = fmt.Sprintf("endpoint did not wake within %s: %v", timeout, o.err)
+			return result
+		case <-time.After(coldStartRetryDelay):
+		}
+	}
+}
+
+// probeColdStarts runs the idle/wake/warm cycle for one provider.
+func probeColdStarts(config ProviderConfig, tke *tiktoken.Tiktoken, opts coldStartOptions) ColdStartSummary {
+	summary := ColdStartSummary{Provider: config.Name, Model: config.Model, Idle: opts.idle}
+	var to200Sum, coldSum, warmSum time.Duration
+	coldOK, warmOK := 0, 0
+
+	for cycle := 1; cycle <= opts.cycles; cycle++ {
+		log.Printf("[%s] Cold start cycle %d/%d: idling %s", config.Name, cycle, opts.cycles, opts.idle)
+		select {
+		case <-shutdownCtx.Done():
+		case <-time.After(opts.idle):
+		}
+		if err := canStartRun(); err != nil {
+			log.Printf("[%s] Cold start cycle %d skipped: %v", config.Name, cycle, err)
+			break
+		}
+
+		c := wakeProbe(config, tke, opts.wakeTimeout, cycle)
+		if c.Error != "" {
+			log.Printf("[%s] Cold start cycle %d failed after %d attempt(s): %s", config.Name, cycle, c.Attempts, c.Error)
+			summary.Cycles = append(summary.Cycles, c)
+			continue
+		}
+		coldOK++
+		to200Sum += c.TimeTo200
+		coldSum += c.ColdTTFT
+
+		// Warm requests follow immediately, while the instance is known to be up
+		var cycleWarmSum time.Duration
+		for i := 1; i <= opts.warmRuns && canStartRun() == nil; i++ {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			o := raceStream(ctx, config, tke, fmt.Sprintf("warm%d-%d", cycle, i), nil)
+			cancel()
+			if o.err != nil {
+				log.Printf("[%s] Warm request %d after cycle %d failed: %v", config.Name, i, cycle, o.err)
+				continue
+			}
+			c.WarmRuns++
+			cycleWarmSum += o.firstToken.Sub(o.start)
+		}
+		if c.WarmRuns > 0 {
+			c.WarmTTFT = cycleWarmSum / time.Duration(c.WarmRuns)
+			warmSum += cycleWarmSum
+			warmOK += c.WarmRuns
+		}
+		log.Printf("[%s] Cold start cycle %d: time to 200=%s cold TTFT=%s warm TTFT=%s (attempts=%d)",
+			config.Name, cycle, formatDuration(c.TimeTo200), formatDuration(c.ColdTTFT), formatDuration(c.WarmTTFT), c.Attempts)
+		summary.Cycles = append(summary.Cycles, c)
+	}
+
+	if coldOK > 0 {
+		summary.AvgTimeTo200 = to200Sum / time.Duration(coldOK)
+		summary.AvgColdTTFT = coldSum / time.Duration(coldOK)
+	}
+	if warmOK > 0 {
+		summary.AvgWarmTTFT = warmSum / time.Duration(warmOK)
+		if coldOK > 0 {
+			summary.WakePenalty = summary.AvgColdTTFT - summary.AvgWarmTTFT
+		}
+	}
+	return summary
+}
+
+// runColdStartProbe probes every provider concurrently and writes
+// cold-start-summary.json and COLD-START-REPORT.md.
+func runColdStartProbe(providers []ProviderConfig, tke *tiktoken.Tiktoken, opts coldStartOptions, resultsDir, sessionTimestamp string) error {
+	log.Printf("=== COLD START PROBE: %d provider(s), %d cycle(s), idle %s, %d warm request(s) per cycle ===",
+		len(providers), opts.cycles, opts.idle, opts.warmRuns)
+
+	summaries := make([]ColdStartSummary, len(providers))
+	var wg sync.WaitGroup
+	for i, p := range providers {
+		wg.Add(1)
+		go func(i int, p ProviderConfig) {
+			defer wg.Done()
+			summaries[i] = probeColdStarts(p, tke, opts)
+		}(i, p)
+	}
+	wg.Wait()
+
+	data, err := json.MarshalIndent(summaries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling cold start summary: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(resultsDir, "cold-start-summary.json"), data, 0600); err != nil {
+		return fmt.Errorf("error writing cold start summary: %w", err)
+	}
+	return generateColdStartReport(resultsDir, summaries, sessionTimestamp)
+}
+
+// generateColdStartReport writes COLD-START-REPORT.md, keeping wake-from-idle
+// figures separate from warm TTFT.
+func generateColdStartReport(resultsDir string, summaries []ColdStartSummary, sessionTimestamp string) error {
+	filename := filepath.Join(resultsDir, "COLD-START-REPORT.md")
+
+	var report strings.Builder
+	report.WriteString("# LLM API Cold Start Results\n\n")
+	fmt.Fprintf(&report, "**Test Session:** %s\n\n", sessionTimestamp)
+	report.WriteString("Each cycle idles the endpoint, then measures the time until the first successful (HTTP 200) " +
+		"response, including any failed attempts while it woke, followed by warm requests for comparison.\n\n")
+	report.WriteString("---\n\n")
+
+	report.WriteString("## Summary\n\n")
+	report.WriteString("| Provider | Model | Idle | Cycles | Avg Time to 200 | Avg Cold TTFT | Avg Warm TTFT | Wake Penalty |\n")
+	report.WriteString("|----------|-------|------|--------|-----------------|---------------|---------------|--------------|\n")
+	for _, s := range summaries {
+		fmt.Fprintf(&report, "| %s | %s | %s | %d | %s | %s | %s | %s |\n",
+			s.Provider, s.Model, s.Idle, len(s.Cycles), formatDuration(s.AvgTimeTo200),
+			formatDuration(s.AvgColdTTFT), formatDuration(s.AvgWarmTTFT), formatDuration(s.WakePenalty))
+	}
+	report.WriteString("\n")
+
+	report.WriteString("## Cycles\n\n")
+	report.WriteString("| Provider | Cycle | Attempts | Time to 200 | Cold TTFT | Cold E2E | Warm TTFT | Error |\n")
+	report.WriteString("|----------|-------|----------|-------------|-----------|----------|-----------|-------|\n")
+	for _, s := range summaries {
+		for _, c := range s.Cycles {
+			fmt.Fprintf(&report, "| %s | %d | %d | %s | %s | %s | %s | %s |\n",
+				s.Provider, c.Cycle, c.Attempts, formatDuration(c.TimeTo200), formatDuration(c.ColdTTFT),
+				formatDuration(c.ColdE2E), formatDuration(c.WarmTTFT), c.Error)
+		}
+	}
+	report.WriteString("\n")
+
+	report.WriteString("---\n\n")
+	fmt.Fprintf(&report, "*Report generated at %s*\n", time.Now().Format("2006-01-02 15:04:05"))
+
+	if err := os.WriteFile(filename, []byte(report.String()), 0600); err != nil {
+		return fmt.Errorf("error writing cold start report: %w", err)
+	}
+	log.Printf("Cold start report generated: %s", filename)
+	return nil
+}
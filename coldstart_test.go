@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestRetryableWhileWaking(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&openai.APIError{HTTPStatusCode: http.StatusServiceUnavailable}, true},
+		{&openai.RequestError{HTTPStatusCode: http.StatusTooManyRequests}, true},
+		{&openai.APIError{HTTPStatusCode: http.StatusUnauthorized}, false},
+		{errors.New("connection reset by peer"), true},
+	}
+	for _, tt := range tests {
+		if got := retryableWhileWaking(tt.err); got != tt.want {
+			t.Errorf("retryableWhileWaking(%v) = %t, want %t", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestProbeColdStarts(t *testing.T) {
+	tke := testTokenizer(t)
+	server := mockSSEServer{unavailable: 2, chunks: []string{"Awake."}}.start(t)
+	defer server.Close()
+
+	config := ProviderConfig{Name: "serverless", BaseURL: server.URL, APIKey: "k", Model: "m"}
+	opts := coldStartOptions{idle: time.Millisecond, cycles: 1, warmRuns: 2, wakeTimeout: 10 * time.Second}
+	summary := probeColdStarts(config, tke, opts)
+
+	if len(summary.Cycles) != 1 {
+		t.Fatalf("expected one cycle, got %+v", summary.Cycles)
+	}
+	c := summary.Cycles[0]
+	if c.Error != "" || c.Attempts != 3 || c.WarmRuns != 2 {
+		t.Fatalf("unexpected cycle: %+v", c)
+	}
+	// Two failed attempts each wait coldStartRetryDelay before the endpoint answers
+	if c.TimeTo200 < 2*coldStartRetryDelay || c.ColdTTFT < c.TimeTo200 {
+		t.Fatalf("expected wake time to include retries, got to200=%s coldTTFT=%s", c.TimeTo200, c.ColdTTFT)
+	}
+	if summary.WakePenalty <= 0 || summary.AvgWarmTTFT >= summary.AvgColdTTFT {
+		t.Fatalf("expected cold TTFT to exceed warm TTFT, got %+v", summary)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// sessionColumns are the computed columns from the config file's [[column]]
+// list, added to the Successful Tests table.
+var sessionColumns []customColumn
+
+// customColumn is a derived metric such as tokens_per_dollar = tokens / cost.
+type customColumn struct {
+	Name string `toml:"name"`
+	Expr string `toml:"expr"`
+	// Format is a printf verb for the value; "%.2f" when empty.
+	Format string `toml:"format"`
+
+	compiled columnExpr
+}
+
+// columnFields are the result fields an expression can use, documented by
+// columnFieldNames. Durations are in seconds.
+var columnFields = map[string]func(TestResult) float64{
+	"ttft":               func(r TestResult) float64 { return r.TTFT.Seconds() },
+	"e2e":                func(r TestResult) float64 { return r.E2ELatency.Seconds() },
+	"throughput":         func(r TestResult) float64 { return r.Throughput },
+	"tokens":             func(r TestResult) float64 { return float64(r.CompletionTokens) },
+	"chars":              func(r TestResult) float64 { return float64(r.CompletionChars) },
+	"cost":               func(r TestResult) float64 { return r.EstimatedCost },
+	"projected_e2e":      func(r TestResult) float64 { return r.ProjectedE2E.Seconds() },
+	"normalized_e2e":     func(r TestResult) float64 { return r.NormalizedE2E.Seconds() },
+	"sec_per_100_tokens": func(r TestResult) float64 { return r.SecPer100Tokens },
+	"quality":            func(r TestResult) float64 { return r.QualityScore },
+	"repetition":         func(r TestResult) float64 { return r.RepetitionRatio },
+}
+
+// columnFieldNames lists the fields an expression can use, sorted.
+func columnFieldNames() []string {
+	names := make([]string, 0, len(columnFields))
+	for name := range columnFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// compile checks the column and parses its expression.
+func (c *customColumn) compile() error {
+	if strings.TrimSpace(c.Name) == "" {
+		return fmt.Errorf("column needs a name")
+	}
+	if c.Format == "" {
+		c.Format = "%.2f"
+	}
+	if !strings.Contains(c.Format, "%") {
+		return fmt.Errorf("column %s: format %q has no %% verb", c.Name, c.Format)
+	}
+	expr, err := parseColumnExpr(c.Expr)
+	if err != nil {
+		return fmt.Errorf("column %s: %w", c.Name, err)
+	}
+	c.compiled = expr
+	return nil
+}
+
+// cell renders the column for r, or N/A when the value is undefined, e.g. a
+// division by a zero cost.
+func (c customColumn) cell(r TestResult) string {
+	v := c.compiled.eval(r)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return NotAvailable
+	}
+	return gradeCell(c.Name, v, fmt.Sprintf(c.Format, v))
+}
+
+// columnExpr is a node of a parsed column expression.
+type columnExpr interface {
+	eval(r TestResult) float64
+}
+
+type (
+	numberExpr float64
+	fieldExpr  string
+	negateExpr struct{ operand columnExpr }
+	binaryExpr struct {
+		op          byte
+		left, right columnExpr
+	}
+)
+
+func (e numberExpr) eval(TestResult) float64   { return float64(e) }
+func (e fieldExpr) eval(r TestResult) float64  { return columnFields[string(e)](r) }
+func (e negateExpr) eval(r TestResult) float64 { return -e.operand.eval(r) }
+func (e binaryExpr) eval(r TestResult) float64 {
+	l, rv := e.left.eval(r), e.right.eval(r)
+	switch e.op {
+	case '+':
+		return l + rv
+	case '-':
+		return l - rv
+	case '*':
+		return l * rv
+	}
+	if rv == 0 {
+		return math.NaN()
+	}
+	return l / rv
+}
+
+// columnParser is a recursive-descent parser for
+//
+//	expr   = term { ("+" | "-") term }
+//	term   = factor { ("*" | "/") factor }
+//	factor = number | field | "-" factor | "(" expr ")"
+type columnParser struct {
+	src string
+	pos int
+}
+
+// parseColumnExpr parses an arithmetic expression over result fields.
+func parseColumnExpr(src string) (columnExpr, error) {
+	p := &columnParser{src: src}
+	expr, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, fmt.Errorf("unexpected %q at position %d in %q", p.src[p.pos], p.pos+1, src)
+	}
+	return expr, nil
+}
+
+func (p *columnParser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// peek returns the next non-space byte, or 0 at the end.
+func (p *columnParser) peek() byte {
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *columnParser) expr() (columnExpr, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == '+' || op == '-'; op = p.peek() {
+		p.pos++
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{op, left, right}
+	}
+	return left, nil
+}
+
+func (p *columnParser) term() (columnExpr, error) {
+	left, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == '*' || op == '/'; op = p.peek() {
+		p.pos++
+		right, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{op, left, right}
+	}
+	return left, nil
+}
+
+func (p *columnParser) factor() (columnExpr, error) {
+	c := p.peek()
+	switch {
+	case c == 0:
+		return nil, fmt.Errorf("unexpected end of expression %q", p.src)
+	case c == '-':
+		p.pos++
+		operand, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		return negateExpr{operand}, nil
+	case c == '(':
+		p.pos++
+		inner, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("missing ) in %q", p.src)
+		}
+		p.pos++
+		return inner, nil
+	case c == '.' || unicode.IsDigit(rune(c)):
+		start := p.pos
+		for p.pos < len(p.src) && (p.src[p.pos] == '.' || unicode.IsDigit(rune(p.src[p.pos]))) {
+			p.pos++
+		}
+		v, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", p.src[start:p.pos])
+		}
+		return numberExpr(v), nil
+	case c == '_' || unicode.IsLetter(rune(c)):
+		start := p.pos
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || unicode.IsLetter(rune(p.src[p.pos])) || unicode.IsDigit(rune(p.src[p.pos]))) {
+			p.pos++
+		}
+		name := strings.ToLower(p.src[start:p.pos])
+		if _, ok := columnFields[name]; !ok {
+			return nil, fmt.Errorf("unknown field %q (use %s)", name, strings.Join(columnFieldNames(), ", "))
+		}
+		return fieldExpr(name), nil
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d in %q", c, p.pos+1, p.src)
+}
+
+// customColumnNames returns the headers of the extra columns.
+func customColumnNames() []string {
+	names := make([]string, len(sessionColumns))
+	for i, c := range sessionColumns {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// customCells returns r's computed cells.
+func customCells(r TestResult) []string {
+	cells := make([]string, len(sessionColumns))
+	for i, c := range sessionColumns {
+		cells[i] = c.cell(r)
+	}
+	return cells
+}
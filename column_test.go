@@ -0,0 +1,130 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseColumnExpr(t *testing.T) {
+	r := TestResult{
+		TTFT:             500 * time.Millisecond,
+		E2ELatency:       2 * time.Second,
+		Throughput:       100,
+		CompletionTokens: 400,
+		EstimatedCost:    0.002,
+	}
+	cases := []struct {
+		expr string
+		want float64
+	}{
+		{"tokens / cost", 200000},
+		{"ttft + e2e * 2", 4.5},
+		{"(ttft + e2e) * 2", 5},
+		{"-ttft + 1", 0.5},
+		{"e2e - ttft - 0.5", 1},
+		{"throughput / 4 / 5", 5},
+		{"1000 * TTFT", 500},
+		{"\ttokens\t/ 100 ", 4},
+	}
+	for _, tc := range cases {
+		expr, err := parseColumnExpr(tc.expr)
+		if err != nil {
+			t.Errorf("parseColumnExpr(%q) failed: %v", tc.expr, err)
+			continue
+		}
+		if got := expr.eval(r); got != tc.want {
+			t.Errorf("%q = %v, want %v", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func TestParseColumnExprErrors(t *testing.T) {
+	for _, bad := range []string{"", "tokens /", "price * 2", "(tokens", "tokens)", "tokens $ 2", "1.2.3"} {
+		if _, err := parseColumnExpr(bad); err == nil {
+			t.Errorf("parseColumnExpr(%q) succeeded, want error", bad)
+		}
+	}
+	_, err := parseColumnExpr("price * 2")
+	if err == nil || !strings.Contains(err.Error(), "throughput") {
+		t.Errorf("unknown field error should list the fields, got %v", err)
+	}
+}
+
+func TestCustomColumnCell(t *testing.T) {
+	c := customColumn{Name: "tokens_per_dollar", Expr: "tokens / cost", Format: "%.0f"}
+	if err := c.compile(); err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	if got := c.cell(TestResult{CompletionTokens: 300, EstimatedCost: 0.001}); got != "300000" {
+		t.Errorf("cell = %q, want 300000", got)
+	}
+	// No prices configured: the cost is zero and the column is undefined
+	if got := c.cell(TestResult{CompletionTokens: 300}); got != NotAvailable {
+		t.Errorf("cell with zero cost = %q, want %s", got, NotAvailable)
+	}
+
+	defaulted := customColumn{Name: "ms", Expr: "ttft * 1000"}
+	if err := defaulted.compile(); err != nil || defaulted.Format != "%.2f" {
+		t.Errorf("compile without format = %v, format %q", err, defaulted.Format)
+	}
+	for _, bad := range []customColumn{{Expr: "ttft"}, {Name: "x", Expr: "ttft", Format: "ms"}} {
+		if err := bad.compile(); err == nil {
+			t.Errorf("compile(%+v) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestCustomColumnsInConfigAndReport(t *testing.T) {
+	cfg, err := loadConfigFile(writeConfig(t, `
+[[column]]
+name = "tokens_per_dollar"
+expr = "tokens / cost"
+format = "%.0f"
+
+[[column]]
+name = "ttft_ms"
+expr = "ttft * 1000"
+format = "%.0f ms"
+`))
+	if err != nil {
+		t.Fatalf("loadConfigFile failed: %v", err)
+	}
+	if len(cfg.Columns) != 2 {
+		t.Fatalf("got %d columns, want 2", len(cfg.Columns))
+	}
+	if _, err := loadConfigFile(writeConfig(t, "[[column]]\nname = \"x\"\nexpr = \"nope\"\n")); err == nil {
+		t.Error("loadConfigFile accepted an unknown field")
+	}
+
+	saved := sessionColumns
+	defer func() { sessionColumns = saved }()
+	sessionColumns = cfg.Columns
+
+	dir := t.TempDir()
+	results := []TestResult{
+		{Provider: "nim", Model: "m", Mode: string(ModeStreaming), Success: true, TTFT: 250 * time.Millisecond,
+			E2ELatency: time.Second, Throughput: 90, CompletionTokens: 500, EstimatedCost: 0.001},
+		{Provider: "novita", Model: "m", Mode: string(ModeStreaming), Success: true, TTFT: 400 * time.Millisecond,
+			E2ELatency: 2 * time.Second, Throughput: 60, CompletionTokens: 500},
+	}
+	if err := generateMarkdownReport(dir, results, "20251110-004615"); err != nil {
+		t.Fatalf("generateMarkdownReport failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "REPORT.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(data)
+	for _, want := range []string{
+		"| Tokens | tokens_per_dollar | ttft_ms |",
+		"| 500 | 500000 | 250 ms |",
+		"| 500 | N/A | 400 ms |",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q", want)
+		}
+	}
+}
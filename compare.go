@@ -0,0 +1,296 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultRegressionThreshold is the percent change in the worse direction above
+// which compare flags a regression.
+const defaultRegressionThreshold = 10.0
+
+// compareMetric is one metric compare diffs between sessions.
+type compareMetric struct {
+	name string
+	// lowerIsBetter is true for latencies, false for rates.
+	lowerIsBetter bool
+	// duration formats values as durations rather than plain numbers.
+	duration bool
+	unit     string
+}
+
+// compareMetrics are diffed in this order.
+var compareMetrics = []compareMetric{
+	{name: "TTFT", lowerIsBetter: true, duration: true},
+	{name: "E2E", lowerIsBetter: true, duration: true},
+	{name: "Throughput", unit: " tok/s"},
+	{name: "Success", unit: "%"},
+}
+
+// compareKey identifies a provider's benchmark across sessions.
+type compareKey struct {
+	provider, model, mode string
+}
+
+// compareValues are one result's metrics, in compareMetrics order; NaN means
+// the result has no value.
+type compareValues [4]float64
+
+// failedCompareValues are the metrics of a result without successful runs: a
+// 0% success rate and nothing else.
+var failedCompareValues = compareValues{math.NaN(), math.NaN(), math.NaN(), 0}
+
+// resultCompareValues returns the metrics of a standard or long-story result.
+func resultCompareValues(r TestResult) compareValues {
+	success := 0.0
+	if len(r.Runs) > 0 {
+		for _, run := range r.Runs {
+			if run.Success {
+				success++
+			}
+		}
+		success = success / float64(len(r.Runs)) * 100
+	} else if r.Success {
+		success = 100
+	}
+	if success == 0 {
+		return failedCompareValues
+	}
+	return compareValues{orNaN(float64(r.TTFT)), orNaN(float64(r.E2ELatency)), orNaN(r.Throughput), success}
+}
+
+// orNaN marks a zero latency or throughput, which a result did not measure, as
+// missing.
+func orNaN(v float64) float64 {
+	if v == 0 {
+		return math.NaN()
+	}
+	return v
+}
+
+// diagnosticCompareValues returns the metrics of a diagnostic summary.
+func diagnosticCompareValues(s DiagnosticSummary) compareValues {
+	if s.Successful == 0 {
+		return failedCompareValues
+	}
+	success := float64(s.Successful) / float64(s.TotalRequests) * 100
+	return compareValues{orNaN(float64(s.AvgTTFT)), orNaN(float64(s.AvgE2ELatency)), orNaN(s.AvgThroughput), success}
+}
+
+// sessionCompareValues indexes a session's results by provider, model and mode.
+// Diagnostic modes are prefixed with "diagnostic-", as in progress events.
+func sessionCompareValues(results []TestResult, diagnostics []DiagnosticSummary) map[compareKey]compareValues {
+	values := make(map[compareKey]compareValues, len(results)+len(diagnostics))
+	for _, r := range results {
+		values[compareKey{providerLabel(r.Provider, r.Env), r.Model, r.Mode}] = resultCompareValues(r)
+	}
+	for _, s := range diagnostics {
+		values[compareKey{providerLabel(s.Provider, s.Env), s.Model, "diagnostic-" + s.Mode}] = diagnosticCompareValues(s)
+	}
+	return values
+}
+
+// compareRow is one metric of one benchmark in both sessions. percent is NaN
+// when a is zero.
+type compareRow struct {
+	key        compareKey
+	metric     compareMetric
+	a, b       float64
+	percent    float64
+	regression bool
+}
+
+// compareSessions diffs every metric the two sessions both have, flagging a
+// regression where b is worse than a by more than threshold percent. It also
+// returns the benchmarks only one session ran.
+func compareSessions(a, b map[compareKey]compareValues, threshold float64) (rows []compareRow, onlyA, onlyB []compareKey) {
+	keys := make([]compareKey, 0, len(a))
+	for k := range a {
+		if _, ok := b[k]; ok {
+			keys = append(keys, k)
+		} else {
+			onlyA = append(onlyA, k)
+		}
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			onlyB = append(onlyB, k)
+		}
+	}
+	for _, list := range [][]compareKey{keys, onlyA, onlyB} {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].provider != list[j].provider {
+				return list[i].provider < list[j].provider
+			}
+			if list[i].model != list[j].model {
+				return list[i].model < list[j].model
+			}
+			return list[i].mode < list[j].mode
+		})
+	}
+
+	for _, k := range keys {
+		for i, m := range compareMetrics {
+			va, vb := a[k][i], b[k][i]
+			if math.IsNaN(va) || math.IsNaN(vb) {
+				continue
+			}
+			row := compareRow{key: k, metric: m, a: va, b: vb, percent: math.NaN()}
+			if va != 0 {
+				row.percent = (vb - va) / va * 100
+				worse := row.percent
+				if !m.lowerIsBetter {
+					worse = -worse
+				}
+				row.regression = worse > threshold
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows, onlyA, onlyB
+}
+
+// formatCompareValue renders a metric value, or its signed delta.
+func formatCompareValue(m compareMetric, v float64, signed bool) string {
+	sign := ""
+	if signed && v >= 0 {
+		sign = "+"
+	}
+	if m.duration {
+		return sign + formatDuration(time.Duration(v))
+	}
+	if m.unit == "%" {
+		return fmt.Sprintf("%s%.1f%%", sign, v)
+	}
+	return fmt.Sprintf("%s%.2f%s", sign, v, m.unit)
+}
+
+// writeComparison writes the markdown comparison of sessions nameA and nameB.
+func writeComparison(w io.Writer, nameA, nameB string, threshold float64, rows []compareRow, onlyA, onlyB []compareKey) error {
+	var report strings.Builder
+	report.WriteString("# Session Comparison\n\n")
+	fmt.Fprintf(&report, "**Baseline (A):** %s  \n**Candidate (B):** %s  \n**Regression threshold:** %.1f%%\n\n", nameA, nameB, threshold)
+
+	regressions := 0
+	for _, r := range rows {
+		if r.regression {
+			regressions++
+		}
+	}
+	if len(rows) == 0 {
+		report.WriteString("The sessions have no provider, model and mode in common.\n\n")
+	} else {
+		fmt.Fprintf(&report, "%d regression(s) beyond %.1f%%. Negative deltas are lower values in B; ⚠ marks a change for the worse.\n\n", regressions, threshold)
+		report.WriteString("| Provider | Model | Mode | Metric | A | B | Delta | Delta % | |\n")
+		report.WriteString("|----------|-------|------|--------|---|---|-------|---------|---|\n")
+		for _, r := range rows {
+			flag := ""
+			if r.regression {
+				flag = "⚠ regression"
+			}
+			percent := NotAvailable
+			if !math.IsNaN(r.percent) {
+				percent = fmt.Sprintf("%+.1f%%", r.percent)
+			}
+			fmt.Fprintf(&report, "| %s | %s | %s | %s | %s | %s | %s | %s | %s |\n", r.key.provider, markdownCell(r.key.model), r.key.mode,
+				r.metric.name, formatCompareValue(r.metric, r.a, false), formatCompareValue(r.metric, r.b, false),
+				formatCompareValue(r.metric, r.b-r.a, true), percent, flag)
+		}
+		report.WriteString("\n")
+	}
+	for _, only := range []struct {
+		name string
+		keys []compareKey
+	}{{nameA, onlyA}, {nameB, onlyB}} {
+		if len(only.keys) == 0 {
+			continue
+		}
+		fmt.Fprintf(&report, "Only in %s:\n\n", only.name)
+		for _, k := range only.keys {
+			fmt.Fprintf(&report, "- %s %s (%s)\n", k.provider, k.model, k.mode)
+		}
+		report.WriteString("\n")
+	}
+	_, err := io.WriteString(w, report.String())
+	return err
+}
+
+// loadCompareSession reads the results of one session named on the command
+// line.
+func loadCompareSession(session string) (string, map[compareKey]compareValues, error) {
+	dir := resolveSessionDir(session)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return "", nil, fmt.Errorf("session %s not found", session)
+	}
+	results, diagnostics, err := loadSessionResults(dir)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(results)+len(diagnostics) == 0 {
+		return "", nil, fmt.Errorf("no results found in %s", dir)
+	}
+	return filepath.Base(dir), sessionCompareValues(results, diagnostics), nil
+}
+
+// runCompare implements the "compare" subcommand: it diffs the results of two
+// sessions per provider, model and mode, and exits with status 1 when the
+// second regressed beyond --threshold.
+func runCompare(args []string) {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	threshold := fs.Float64("threshold", defaultRegressionThreshold, "Percent change for the worse that counts as a regression")
+	out := fs.String("out", "", "Also write the comparison to this markdown file")
+	fs.StringVar(&resultsRoot, "results-dir", resultsRoot, "Folder bare session names are looked up in")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: llm-api-speed compare [--threshold percent] [--out file] <baseline-session> <candidate-session>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		log.Fatalf("Error parsing flags: %v", err)
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *threshold < 0 {
+		log.Fatal("Error: --threshold must not be negative")
+	}
+	nameA, a, err := loadCompareSession(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	nameB, b, err := loadCompareSession(fs.Arg(1))
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	rows, onlyA, onlyB := compareSessions(a, b, *threshold)
+	if err := writeComparison(os.Stdout, nameA, nameB, *threshold, rows, onlyA, onlyB); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if *out != "" {
+		f, err := os.OpenFile(filepath.Clean(*out), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			log.Fatalf("Error creating %s: %v", *out, err)
+		}
+		if err := writeComparison(f, nameA, nameB, *threshold, rows, onlyA, onlyB); err != nil {
+			_ = f.Close()
+			log.Fatalf("Error writing %s: %v", *out, err)
+		}
+		if err := f.Close(); err != nil {
+			log.Fatalf("Error writing %s: %v", *out, err)
+		}
+	}
+	for _, r := range rows {
+		if r.regression {
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompareSessions(t *testing.T) {
+	a := sessionCompareValues([]TestResult{
+		{Provider: "nim", Env: "prod", Model: "m", Mode: "streaming", Success: true, TTFT: 500 * time.Millisecond, E2ELatency: 2 * time.Second, Throughput: 100},
+		{Provider: "novita", Model: "m", Mode: "streaming", Success: true, TTFT: time.Second},
+		{Provider: "old", Model: "m", Mode: "streaming", Success: true},
+	}, []DiagnosticSummary{{Provider: "nim", Model: "m", Mode: "streaming", TotalRequests: 10, Successful: 10, AvgTTFT: time.Second}})
+	b := sessionCompareValues([]TestResult{
+		{Provider: "nim", Env: "prod", Model: "m", Mode: "streaming", Success: true, TTFT: 600 * time.Millisecond, E2ELatency: 1500 * time.Millisecond, Throughput: 95},
+		{Provider: "novita", Model: "m", Mode: "streaming", Error: "timeout"},
+	}, []DiagnosticSummary{{Provider: "nim", Model: "m", Mode: "streaming", TotalRequests: 10, Successful: 8, AvgTTFT: time.Second}})
+
+	rows, onlyA, onlyB := compareSessions(a, b, 10)
+	if len(onlyA) != 1 || onlyA[0].provider != "old" || len(onlyB) != 0 {
+		t.Fatalf("unexpected unmatched benchmarks %v %v", onlyA, onlyB)
+	}
+	got := make(map[string]compareRow)
+	for _, r := range rows {
+		got[r.key.provider+" "+r.key.mode+" "+r.metric.name] = r
+	}
+	if r := got["nim [prod] streaming TTFT"]; !r.regression || r.percent < 19.9 || r.percent > 20.1 {
+		t.Errorf("expected a 20%% TTFT regression, got %+v", r)
+	}
+	if r := got["nim [prod] streaming E2E"]; r.regression {
+		t.Errorf("a faster E2E is no regression: %+v", r)
+	}
+	if r := got["nim [prod] streaming Throughput"]; r.regression {
+		t.Errorf("a 5%% throughput drop is within the threshold: %+v", r)
+	}
+	if r := got["novita streaming Success"]; !r.regression || r.b != 0 {
+		t.Errorf("expected the failed run as a success regression, got %+v", r)
+	}
+	if _, ok := got["novita streaming TTFT"]; ok {
+		t.Error("a failed result has no TTFT to compare")
+	}
+	if r := got["nim diagnostic-streaming Success"]; !r.regression {
+		t.Errorf("expected the diagnostic success drop as a regression, got %+v", r)
+	}
+
+	var out strings.Builder
+	if err := writeComparison(&out, "session-a", "session-b", 10, rows, onlyA, onlyB); err != nil {
+		t.Fatal(err)
+	}
+	report := out.String()
+	for _, want := range []string{
+		"**Regression threshold:** 10.0%",
+		"3 regression(s) beyond 10.0%",
+		"| nim [prod] | m | streaming | TTFT | 0.500s | 0.600s | +0.100s | +20.0% | ⚠ regression |",
+		"| nim [prod] | m | streaming | E2E | 2.000s | 1.500s | -0.500s | -25.0% |  |",
+		"Only in session-a:\n\n- old m (streaming)",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("comparison lacks %q:\n%s", want, report)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// fileConfig is the TOML file passed with --config. Every section is optional.
+type fileConfig struct {
+	Scenario *scenarioConfig `toml:"scenario"`
+	// SLOs are added to those given with --slo.
+	SLOs []sloObjective `toml:"slo"`
+	// Columns are computed columns added to the Successful Tests table.
+	Columns []customColumn `toml:"column"`
+	// Thresholds grade table cells as good, warn or bad.
+	Thresholds []metricThreshold `toml:"threshold"`
+	// Daemon schedules this config as a tenant of the daemon subcommand; plain
+	// runs ignore it.
+	Daemon *daemonConfig `toml:"daemon"`
+	// Providers holds per-provider notes shown in reports, keyed by provider name.
+	Providers map[string]providerNotes `toml:"provider"`
+	// TestParams tunes how a standard benchmark runs.
+	TestParams *testParamsConfig `toml:"test_params"`
+	// Prompts replace the built-in prompt of a test group.
+	Prompts map[string]promptConfig `toml:"prompts"`
+	// prompts is the text of Prompts by group, resolved when loaded.
+	prompts map[string]string
+}
+
+// testParamsConfig is the [test_params] table.
+type testParamsConfig struct {
+	// ConcurrentIterations runs a provider's iterations all at once (true, the
+	// default) or one at a time (false, like --sequential).
+	ConcurrentIterations *bool `toml:"concurrent_iterations"`
+	// WarmupRequests is the default for --warmup.
+	WarmupRequests int `toml:"warmup_requests"`
+}
+
+// sequential reports whether p asks for iterations one at a time.
+func (p *testParamsConfig) sequential() bool {
+	return p != nil && p.ConcurrentIterations != nil && !*p.ConcurrentIterations
+}
+
+// warmup returns the warm-up request count p asks for, 0 without one.
+func (p *testParamsConfig) warmup() int {
+	if p == nil {
+		return 0
+	}
+	return p.WarmupRequests
+}
+
+// configDuration is a duration written as a Go duration string, e.g. "2m30s".
+type configDuration struct {
+	time.Duration
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *configDuration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+// loadConfigFile reads and validates a TOML config. Unknown keys are rejected so
+// a typo cannot silently fall back to a default.
+func loadConfigFile(path string) (fileConfig, error) {
+	var cfg fileConfig
+	meta, err := toml.DecodeFile(path, &cfg)
+	if err != nil {
+		return cfg, fmt.Errorf("error reading config %s: %w", path, err)
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, key := range undecoded {
+			keys[i] = key.String()
+		}
+		return cfg, fmt.Errorf("config %s: unknown keys: %s", path, strings.Join(keys, ", "))
+	}
+	for i, o := range cfg.SLOs {
+		o.Metric = strings.ToLower(o.Metric)
+		if err := o.validate(); err != nil {
+			return cfg, fmt.Errorf("config %s: slo %d: %w", path, i+1, err)
+		}
+		cfg.SLOs[i] = o
+	}
+	for i := range cfg.Columns {
+		if err := cfg.Columns[i].compile(); err != nil {
+			return cfg, fmt.Errorf("config %s: %w", path, err)
+		}
+	}
+	graded := make(map[string]bool)
+	for _, t := range cfg.Thresholds {
+		if err := t.validate(cfg.Columns); err != nil {
+			return cfg, fmt.Errorf("config %s: threshold: %w", path, err)
+		}
+		if graded[t.Metric] {
+			return cfg, fmt.Errorf("config %s: threshold: metric %s has more than one threshold", path, t.Metric)
+		}
+		graded[t.Metric] = true
+	}
+	for name, notes := range cfg.Providers {
+		if err := notes.validate(); err != nil {
+			return cfg, fmt.Errorf("config %s: provider %s: %w", path, name, err)
+		}
+	}
+	if err := validateWarmup(cfg.TestParams.warmup()); err != nil {
+		return cfg, fmt.Errorf("config %s: test_params: %w", path, err)
+	}
+	if cfg.prompts, err = resolvePrompts(cfg.Prompts, filepath.Dir(path)); err != nil {
+		return cfg, fmt.Errorf("config %s: prompts: %w", path, err)
+	}
+	if cfg.Daemon != nil {
+		if err := cfg.Daemon.validate(); err != nil {
+			return cfg, fmt.Errorf("config %s: %w", path, err)
+		}
+	}
+	if cfg.Scenario != nil {
+		if err := cfg.Scenario.validate(); err != nil {
+			return cfg, fmt.Errorf("config %s: %w", path, err)
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lamim/llm-api-speed/pkg/providers"
+	"github.com/pkoukk/tiktoken-go"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const contextProbeModeLabel = "context-probe"
+
+// contextProbeTimeout bounds one probe request; large prompts take a while to
+// prefill.
+const contextProbeTimeout = 5 * time.Minute
+
+// contextProbeFiller is repeated to pad synthetic prompts. Varied prose keeps
+// the token count close to what real text of the same length would produce.
+const contextProbeFiller = "The survey team crossed the northern ridge at dawn, logging wind speed, " +
+	"cloud cover and the temperature of each stream they forded before noting the time in the ledger. "
+
+// contextProbeInstruction ends every synthetic prompt so the model has
+// something short to answer.
+const contextProbeInstruction = "\n\nIgnore the text above and reply with the single word OK."
+
+// contextProbeOptions configures a context-window probe session.
+type contextProbeOptions struct {
+	start     int
+	max       int
+	precision int
+}
+
+// ContextProbeStep is one request of a context-window probe.
+type ContextProbeStep struct {
+	PromptTokens int           `json:"promptTokens"`
+	Accepted     bool          `json:"accepted"`
+	TTFT         time.Duration `json:"ttft,omitempty"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// ContextProbeSummary is the effective context window found for one provider.
+type ContextProbeSummary struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	// Configured is the advertised window from <PREFIX>_CONTEXT_WINDOW, if set.
+	Configured int `json:"configured,omitempty"`
+	// MaxAccepted is the largest prompt the endpoint answered and MinRejected
+	// the smallest it refused; the effective window lies between them. A zero
+	// MinRejected means nothing up to --context-probe-max was refused.
+	MaxAccepted int                `json:"maxAccepted"`
+	MinRejected int                `json:"minRejected,omitempty"`
+	Steps       []ContextProbeStep `json:"steps"`
+	// Error is set when probing stopped on a failure that says nothing about
+	// prompt size (bad key, server error, budget).
+	Error string `json:"error,omitempty"`
+}
+
+// contextProbePrompt builds a prompt of about n tokens.
+func contextProbePrompt(tke *tiktoken.Tiktoken, n int) string {
+	fillerTokens := tke.Encode(contextProbeFiller, nil, nil)
+	want := n - len(tke.Encode(contextProbeInstruction, nil, nil))
+	tokens := make([]int, 0, max(want, 0))
+	for len(tokens) < want {
+		tokens = append(tokens, fillerTokens[:min(len(fillerTokens), want-len(tokens))]...)
+	}
+	return tke.Decode(tokens) + contextProbeInstruction
+}
+
+// rejectedForSize reports whether err is the endpoint refusing a request as
+// invalid, which is how oversized prompts are turned away, rather than an
+// authentication, availability or network failure.
+func rejectedForSize(err error) bool {
+	status := 0
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	switch {
+	case errors.As(err, &apiErr):
+		status = apiErr.HTTPStatusCode
+	case errors.As(err, &reqErr):
+		status = reqErr.HTTPStatusCode
+	}
+	switch status {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return true
+	}
+	return false
+}
+
+// probeContextSize sends a prompt of about n tokens with a one-token output
+// limit and reports whether the endpoint accepted it. The error is returned
+// only for failures unrelated to size.
+func probeContextSize(config ProviderConfig, tke *tiktoken.Tiktoken, n int) (ContextProbeStep, error) {
+	req := openai.ChatCompletionRequest{
+		Model:     config.Model,
+		Messages:  []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: contextProbePrompt(tke, n)}},
+		MaxTokens: 1,
+		Stream:    true,
+	}
+	req = config.Quirks.apply(req)
+	step := ContextProbeStep{PromptTokens: countPromptTokens(tke, req.Messages)}
+
+	ctx, cancel := context.WithTimeout(shutdownCtx, contextProbeTimeout)
+	defer cancel()
+	start := time.Now()
+	stream, err := providers.OpenStream(ctx, benchProvider(config), req)
+	if err == nil {
+		defer func() {
+			_ = stream.Close()
+		}()
+		// The first delta or a clean end of stream means the prompt was taken
+		for {
+			delta, recvErr := stream.Recv()
+			if errors.Is(recvErr, io.EOF) {
+				break
+			}
+			if recvErr != nil {
+				err = recvErr
+				break
+			}
+			if !delta.Empty() {
+				step.TTFT = time.Since(start)
+				break
+			}
+		}
+	}
+	sessionBudget.record(config, step.PromptTokens, 0)
+
+	if err == nil {
+		step.Accepted = true
+		return step, nil
+	}
+	step.Error = err.Error()
+	if rejectedForSize(err) {
+		return step, nil
+	}
+	return step, err
+}
+
+// probeContextWindow doubles the prompt size from opts.start until the
+// endpoint refuses it (or opts.max is accepted), then binary-searches between
+// the largest accepted and smallest refused size until they are within
+// opts.precision tokens.
+func probeContextWindow(config ProviderConfig, tke *tiktoken.Tiktoken, opts contextProbeOptions) ContextProbeSummary {
+	summary := ContextProbeSummary{Provider: config.Name, Model: config.Model, Configured: config.ContextWindow}
+	probe := func(n int) bool {
+		if err := canStartRun(); err != nil {
+			summary.Error = err.Error()
+			return false
+		}
+		step, err := probeContextSize(config, tke, n)
+		summary.Steps = append(summary.Steps, step)
+		if err != nil {
+			log.Printf("[%s] Context probe stopped at %d tokens: %v", config.Name, step.PromptTokens, err)
+			summary.Error = err.Error()
+			return false
+		}
+		if step.Accepted {
+			log.Printf("[%s] Context probe: %d tokens accepted (TTFT %s)", config.Name, step.PromptTokens, formatDuration(step.TTFT))
+			summary.MaxAccepted = max(summary.MaxAccepted, step.PromptTokens)
+		} else {
+			log.Printf("[%s] Context probe: %d tokens rejected", config.Name, step.PromptTokens)
+			if summary.MinRejected == 0 || step.PromptTokens < summary.MinRejected {
+				summary.MinRejected = step.PromptTokens
+			}
+		}
+		return true
+	}
+
+	for size := min(opts.start, opts.max); summary.MinRejected == 0; size = min(size*2, opts.max) {
+		if !probe(size) || summary.MaxAccepted >= opts.max {
+			break
+		}
+	}
+	for summary.Error == "" && summary.MinRejected > 0 && summary.MinRejected-summary.MaxAccepted > opts.precision {
+		gap := summary.MinRejected - summary.MaxAccepted
+		if !probe(summary.MaxAccepted + gap/2) {
+			break
+		}
+		// Token counts of the rebuilt prompt are approximate; stop rather than
+		// repeat a probe that did not narrow the gap
+		if summary.MinRejected-summary.MaxAccepted >= gap {
+			break
+		}
+	}
+	return summary
+}
+
+// runContextProbe probes every provider concurrently and writes
+// context-probe-summary.json and CONTEXT-PROBE-REPORT.md.
+func runContextProbe(providers []ProviderConfig, tke *tiktoken.Tiktoken, opts contextProbeOptions, resultsDir, sessionTimestamp string) error {
+	log.Printf("=== CONTEXT WINDOW PROBE: %d provider(s), %d to %d tokens, precision %d ===",
+		len(providers), opts.start, opts.max, opts.precision)
+
+	summaries := make([]ContextProbeSummary, len(providers))
+	var wg sync.WaitGroup
+	for i, p := range providers {
+		wg.Add(1)
+		go func(i int, p ProviderConfig) {
+			defer wg.Done()
+			summaries[i] = probeContextWindow(p, tke, opts)
+		}(i, p)
+	}
+	wg.Wait()
+
+	data, err := json.MarshalIndent(summaries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling context probe summary: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(resultsDir, "context-probe-summary.json"), data, 0600); err != nil {
+		return fmt.Errorf("error writing context probe summary: %w", err)
+	}
+	return generateContextProbeReport(resultsDir, summaries, sessionTimestamp)
+}
+
+// effectiveWindowCell describes where the effective window lies.
+func effectiveWindowCell(s ContextProbeSummary) string {
+	switch {
+	case s.MaxAccepted == 0 && s.MinRejected == 0:
+		return NotAvailable
+	case s.MinRejected == 0:
+		return fmt.Sprintf("≥ %d", s.MaxAccepted)
+	case s.MaxAccepted == 0:
+		return fmt.Sprintf("< %d", s.MinRejected)
+	}
+	return fmt.Sprintf("%d–%d", s.MaxAccepted, s.MinRejected)
+}
+
+// configuredWindowCell compares the advertised window with what was accepted.
+func configuredWindowCell(s ContextProbeSummary) string {
+	switch {
+	case s.Configured == 0:
+		return NotAvailable
+	case s.MinRejected > 0 && s.MinRejected <= s.Configured:
+		return fmt.Sprintf("%d (⚠ %d refused)", s.Configured, s.MinRejected)
+	case s.MaxAccepted > s.Configured:
+		return fmt.Sprintf("%d (⚠ %d accepted)", s.Configured, s.MaxAccepted)
+	}
+	return fmt.Sprintf("%d", s.Configured)
+}
+
+// generateContextProbeReport writes CONTEXT-PROBE-REPORT.md.
+func generateContextProbeReport(resultsDir string, summaries []ContextProbeSummary, sessionTimestamp string) error {
+	filename := filepath.Join(resultsDir, "CONTEXT-PROBE-REPORT.md")
+
+	var report strings.Builder
+	report.WriteString("# LLM API Context Window Probe\n\n")
+	fmt.Fprintf(&report, "**Test Session:** %s\n\n", sessionTimestamp)
+	report.WriteString("Synthetic prompts of growing size were sent with a one-token output limit until the endpoint refused one, " +
+		"then the boundary was narrowed by binary search. Sizes are counted with the local tokenizer, so they approximate the model's own count. " +
+		"Configured is `<PREFIX>_CONTEXT_WINDOW`; ⚠ marks endpoints that refuse less or accept more than it.\n\n")
+	report.WriteString("---\n\n")
+
+	report.WriteString("## Summary\n\n")
+	report.WriteString("| Provider | Model | Effective Window | Configured | Probes | Error |\n")
+	report.WriteString("|----------|-------|------------------|------------|--------|-------|\n")
+	for _, s := range summaries {
+		fmt.Fprintf(&report, "| %s | %s | %s | %s | %d | %s |\n",
+			s.Provider, s.Model, effectiveWindowCell(s), configuredWindowCell(s), len(s.Steps), s.Error)
+	}
+	report.WriteString("\n")
+
+	report.WriteString("## Probes\n\n")
+	report.WriteString("| Provider | Prompt Tokens | Result | TTFT | Error |\n")
+	report.WriteString("|----------|---------------|--------|------|-------|\n")
+	for _, s := range summaries {
+		for _, step := range s.Steps {
+			result := "rejected"
+			if step.Accepted {
+				result = "accepted"
+			}
+			fmt.Fprintf(&report, "| %s | %d | %s | %s | %s |\n",
+				s.Provider, step.PromptTokens, result, formatDurationOrNA(step.TTFT), step.Error)
+		}
+	}
+	report.WriteString("\n")
+
+	report.WriteString("---\n\n")
+	fmt.Fprintf(&report, "*Report generated at %s*\n", time.Now().Format("2006-01-02 15:04:05"))
+
+	if err := os.WriteFile(filename, []byte(report.String()), 0600); err != nil {
+		return fmt.Errorf("error writing context probe report: %w", err)
+	}
+	log.Printf("Context probe report generated: %s", filename)
+	return nil
+}
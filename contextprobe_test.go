@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// contextLimitHandler answers prompts up to limit bytes (tokens under the test
+// tokenizer) and refuses longer ones with 400, as an endpoint enforcing its
+// context window does.
+type contextLimitHandler struct {
+	limit int
+	mock  *mockSSEHandler
+}
+
+func (h contextLimitHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req openai.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if n := len(req.Messages[0].Content); n > h.limit {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"error":{"message":"prompt has %d tokens, maximum is %d"}}`, n, h.limit)
+		return
+	}
+	h.mock.ServeHTTP(w, r)
+}
+
+func TestProbeContextWindowFindsLimit(t *testing.T) {
+	tke := testTokenizer(t)
+	srv := httptest.NewServer(contextLimitHandler{limit: 10000, mock: &mockSSEHandler{chunks: []string{"OK"}}})
+	defer srv.Close()
+
+	config := ProviderConfig{Name: "mock", BaseURL: srv.URL, APIKey: "k", Model: "m", ContextWindow: 16384}
+	summary := probeContextWindow(config, tke, contextProbeOptions{start: 1024, max: 65536, precision: 100})
+	if summary.Error != "" {
+		t.Fatalf("unexpected error: %s", summary.Error)
+	}
+	if summary.MaxAccepted > 10000 || summary.MinRejected <= 10000 || summary.MinRejected-summary.MaxAccepted > 100 {
+		t.Fatalf("expected the window narrowed to within 100 tokens of 10000, got %d-%d", summary.MaxAccepted, summary.MinRejected)
+	}
+	// 1024, 2048, 4096, 8192 accepted, 16384 refused, then the binary search
+	if len(summary.Steps) < 5 || !summary.Steps[3].Accepted || summary.Steps[4].Accepted || summary.Steps[4].PromptTokens != 16384 {
+		t.Fatalf("unexpected probe sequence: %+v", summary.Steps)
+	}
+	if cell := configuredWindowCell(summary); !strings.Contains(cell, "⚠") {
+		t.Fatalf("expected the advertised window to be flagged, got %q", cell)
+	}
+}
+
+func TestProbeContextWindowStopsAtMax(t *testing.T) {
+	tke := testTokenizer(t)
+	srv := httptest.NewServer(contextLimitHandler{limit: 1 << 20, mock: &mockSSEHandler{chunks: []string{"OK"}}})
+	defer srv.Close()
+
+	config := ProviderConfig{Name: "mock", BaseURL: srv.URL, APIKey: "k", Model: "m"}
+	summary := probeContextWindow(config, tke, contextProbeOptions{start: 1000, max: 5000, precision: 100})
+	if summary.MaxAccepted != 5000 || summary.MinRejected != 0 || len(summary.Steps) != 4 {
+		t.Fatalf("expected 1000, 2000, 4000, 5000 accepted, got %+v", summary)
+	}
+	if cell := effectiveWindowCell(summary); cell != "≥ 5000" {
+		t.Fatalf("unexpected effective window %q", cell)
+	}
+}
+
+func TestProbeContextWindowStopsOnUnrelatedError(t *testing.T) {
+	tke := testTokenizer(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, `{"error":{"message":"invalid key"}}`, http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	config := ProviderConfig{Name: "mock", BaseURL: srv.URL, APIKey: "k", Model: "m"}
+	summary := probeContextWindow(config, tke, contextProbeOptions{start: 1000, max: 5000, precision: 100})
+	if summary.Error == "" || len(summary.Steps) != 1 || summary.MinRejected != 0 {
+		t.Fatalf("expected probing to stop after the 401 without counting it as a refusal, got %+v", summary)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/pkoukk/tiktoken-go"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// What the context-window preflight does with a request that would not fit,
+// selected with --context-overflow.
+const (
+	contextOverflowRefuse = "refuse"
+	contextOverflowWarn   = "warn"
+)
+
+// contextOverflow is the policy selected with --context-overflow.
+var contextOverflow = contextOverflowRefuse
+
+// errContextOverflow marks requests refused before sending because the prompt
+// plus the output limit exceeds the provider's <PREFIX>_CONTEXT_WINDOW.
+var errContextOverflow = errors.New("request exceeds the context window")
+
+// requestOutputLimit is req's output token limit, whichever field carries it.
+func requestOutputLimit(req openai.ChatCompletionRequest) int {
+	if req.MaxCompletionTokens > 0 {
+		return req.MaxCompletionTokens
+	}
+	return req.MaxTokens
+}
+
+// checkContextWindow counts req's prompt tokens and compares prompt plus
+// output limit with config's context window, so an oversized request fails
+// here with the numbers instead of as a provider 400 deep into a session. The
+// count uses the local tokenizer and leaves out message and tool framing, so
+// it is an estimate; requests close to the limit may still be rejected. With
+// --context-overflow=warn the request is only logged and sent anyway.
+func checkContextWindow(config ProviderConfig, tke *tiktoken.Tiktoken, logger *log.Logger, req openai.ChatCompletionRequest) error {
+	if config.ContextWindow <= 0 {
+		return nil
+	}
+	prompt := countPromptTokens(tke, req.Messages)
+	limit := requestOutputLimit(req)
+	if prompt+limit <= config.ContextWindow {
+		return nil
+	}
+	err := fmt.Errorf("%w: %d prompt + %d max output tokens > %d for %s",
+		errContextOverflow, prompt, limit, config.ContextWindow, config.Model)
+	if contextOverflow == contextOverflowWarn {
+		logger.Printf("Warning: %v; sending anyway", err)
+		return nil
+	}
+	return err
+}
+
+// envInt reads an integer from the named environment variable, returning 0
+// when unset or invalid.
+func envInt(name string) int {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return 0
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		log.Printf("Warning: ignoring invalid %s=%q: %v", name, raw, err)
+		return 0
+	}
+	return v
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http/httptest"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestCheckContextWindow(t *testing.T) {
+	tke := testTokenizer(t)
+	logger := log.New(io.Discard, "", 0)
+	req := openai.ChatCompletionRequest{
+		Messages:  []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "0123456789"}},
+		MaxTokens: 90,
+	}
+
+	if err := checkContextWindow(ProviderConfig{}, tke, logger, req); err != nil {
+		t.Fatalf("expected no check without a context window, got %v", err)
+	}
+	if err := checkContextWindow(ProviderConfig{ContextWindow: 100}, tke, logger, req); err != nil {
+		t.Fatalf("expected 10+90 tokens to fit in 100, got %v", err)
+	}
+	err := checkContextWindow(ProviderConfig{ContextWindow: 99}, tke, logger, req)
+	if !errors.Is(err, errContextOverflow) {
+		t.Fatalf("expected errContextOverflow, got %v", err)
+	}
+
+	quirked := providerQuirks{MaxCompletionTokens: true}.apply(req)
+	if err := checkContextWindow(ProviderConfig{ContextWindow: 99}, tke, logger, quirked); !errors.Is(err, errContextOverflow) {
+		t.Fatalf("expected max_completion_tokens to count, got %v", err)
+	}
+
+	defer func(prev string) { contextOverflow = prev }(contextOverflow)
+	contextOverflow = contextOverflowWarn
+	if err := checkContextWindow(ProviderConfig{ContextWindow: 99}, tke, logger, req); err != nil {
+		t.Fatalf("expected only a warning, got %v", err)
+	}
+}
+
+func TestStreamWithContinuationRefusesOversizedPrompt(t *testing.T) {
+	tke := testTokenizer(t)
+	handler := &mockSSEHandler{chunks: []string{"short", " reply"}}
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	config := ProviderConfig{Name: "mock", BaseURL: srv.URL, APIKey: "test", Model: "mock-model", ContextWindow: 40}
+	req := openai.ChatCompletionRequest{
+		Model:     config.Model,
+		Messages:  []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "hi"}},
+		MaxTokens: 30,
+		Stream:    true,
+	}
+	logger := log.New(io.Discard, "", 0)
+	defer func(prev int) { minOutputTokens = prev }(minOutputTokens)
+
+	// The continuation adds the 11-token reply and "continue", pushing the
+	// prompt past the window, so it is skipped and the first segment kept.
+	minOutputTokens = 100
+	sample, err := streamWithContinuation(context.Background(), config, tke, logger, req, func() {})
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	if handler.requests.Load() != 1 || sample.Tokens != len("short reply") {
+		t.Fatalf("expected the continuation to be refused, got %d requests and %d tokens", handler.requests.Load(), sample.Tokens)
+	}
+
+	config.ContextWindow = 31
+	if _, err := streamWithContinuation(context.Background(), config, tke, logger, req, func() {}); !errors.Is(err, errContextOverflow) {
+		t.Fatalf("expected errContextOverflow, got %v", err)
+	}
+	if handler.requests.Load() != 1 {
+		t.Fatalf("expected the oversized request not to be sent, got %d requests", handler.requests.Load())
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/lamim/llm-api-speed/pkg/benchmark"
+	"github.com/pkoukk/tiktoken-go"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	// continuationPrompt is sent after a response that ended below --min-output-tokens.
+	continuationPrompt = "continue"
+	// maxContinuations bounds the follow-up requests for one run, so a model that
+	// keeps answering in a few tokens cannot loop forever.
+	maxContinuations = 5
+)
+
+// minOutputTokens, when positive, makes streaming runs ask the model to continue
+// until at least this many completion tokens have been produced.
+var minOutputTokens int
+
+// streamWithContinuation streams req and, while the output is shorter than
+// minOutputTokens, appends the response and a "continue" turn and streams again.
+// TTFT is that of the first request and E2E spans all of them. Throughput only
+// counts decode time: the wait for each continuation's first token is latency,
+// not generation speed, so it is left out. providerLogger is expected to carry
+// the run context already (see runLogger).
+//
+// release frees the scheduler slot the first request was admitted with; it is
+// called once that request is done. Every continuation is a request of its
+// own and waits for a slot like one, so --provider-rps and the fair
+// round-robin cover it too.
+func streamWithContinuation(ctx context.Context, config ProviderConfig, tke *tiktoken.Tiktoken, providerLogger *log.Logger, req openai.ChatCompletionRequest, release func()) (benchmark.Sample, error) {
+	provider := benchProvider(config)
+	messages := append([]openai.ChatCompletionMessage(nil), req.Messages...)
+
+	var total benchmark.Sample
+	var response strings.Builder
+	// The provider's count is kept only if every segment reported one
+	allReported := true
+	var generation float64
+	decodedTokens := 0
+	for segment := 0; ; segment++ {
+		if segment > 0 {
+			var err error
+			if release, err = sessionScheduler.acquire(ctx, providerLabel(config.Name, config.Env)); err != nil {
+				providerLogger.Printf("... Continuation %d skipped, keeping %d tokens: waiting for a request slot: %v", segment, total.Tokens, err)
+				break
+			}
+		}
+		req.Messages = messages
+		if err := checkContextWindow(config, tke, providerLogger, req); err != nil {
+			release()
+			if segment == 0 {
+				return benchmark.Sample{}, err
+			}
+			providerLogger.Printf("... Continuation %d skipped, keeping %d tokens: %v", segment, total.Tokens, err)
+			break
+		}
+		sample, err := benchmark.Stream(ctx, provider, tke, providerLogger, req)
+		release()
+		if err == nil || errors.Is(err, benchmark.ErrNoTokens) {
+			sessionBudget.record(config, countPromptTokens(tke, req.Messages), sample.Tokens)
+		}
+		if err != nil {
+			if segment == 0 {
+				return benchmark.Sample{}, err
+			}
+			providerLogger.Printf("... Continuation %d failed, keeping %d tokens: %v", segment, total.Tokens, err)
+			break
+		}
+
+		if segment == 0 {
+			total.TTFT = sample.TTFT
+		}
+		total.E2E += sample.E2E
+		total.Tokens += sample.Tokens
+		total.LocalTokens += sample.LocalTokens
+		total.ReportedTokens += sample.ReportedTokens
+		allReported = allReported && sample.ReportedTokens > 0
+		total.Chunks.Merge(sample.Chunks)
+		sessionStreams.add(providerLabel(config.Name, config.Env), sample)
+		sessionKeys.observe(config, sample.RateLimit)
+		response.WriteString(sample.Response)
+		if seconds := (sample.E2E - sample.TTFT).Seconds(); seconds > 0 {
+			generation += seconds
+			decodedTokens += sample.Tokens - 1
+		}
+
+		if minOutputTokens <= 0 || total.Tokens >= minOutputTokens {
+			break
+		}
+		if segment == maxContinuations {
+			providerLogger.Printf("... Still %d/%d tokens after %d continuations, giving up",
+				total.Tokens, minOutputTokens, maxContinuations)
+			break
+		}
+		providerLogger.Printf("... %d/%d tokens, asking the model to continue", total.Tokens, minOutputTokens)
+		messages = append(messages,
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: sample.Response},
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: continuationPrompt})
+	}
+
+	if generation > 0 {
+		total.Throughput = float64(decodedTokens) / generation
+	}
+	if !allReported {
+		total.ReportedTokens = 0
+	}
+	total.Response = response.String()
+	return total, nil
+}
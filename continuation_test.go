@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestStreamWithContinuationReachesMinimum(t *testing.T) {
+	tke := testTokenizer(t)
+	handler := &mockSSEHandler{chunks: []string{"short", " reply"}}
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	config := ProviderConfig{Name: "mock", BaseURL: srv.URL, APIKey: "test", Model: "mock-model"}
+	req := openai.ChatCompletionRequest{
+		Model:    config.Model,
+		Messages: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "hi"}},
+		Stream:   true,
+	}
+	logger := log.New(io.Discard, "", 0)
+
+	defer func(prev int) { minOutputTokens = prev }(minOutputTokens)
+
+	minOutputTokens = 0
+	sample, err := streamWithContinuation(context.Background(), config, tke, logger, req, func() {})
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	if sample.Tokens != len("short reply") || handler.requests.Load() != 1 {
+		t.Fatalf("expected a single request of %d tokens, got %d tokens in %d requests",
+			len("short reply"), sample.Tokens, handler.requests.Load())
+	}
+
+	minOutputTokens = 25
+	sample, err = streamWithContinuation(context.Background(), config, tke, logger, req, func() {})
+	if err != nil {
+		t.Fatalf("stream with continuation failed: %v", err)
+	}
+	if got := handler.requests.Load() - 1; got != 3 {
+		t.Errorf("expected 3 requests to reach 25 tokens, got %d", got)
+	}
+	if sample.Tokens != 3*len("short reply") || sample.Response != "short replyshort replyshort reply" {
+		t.Errorf("unexpected combined output: %d tokens, %q", sample.Tokens, sample.Response)
+	}
+
+	minOutputTokens = 1000
+	handler.requests.Store(0)
+	if _, err := streamWithContinuation(context.Background(), config, tke, logger, req, func() {}); err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	if got := handler.requests.Load(); got != 1+maxContinuations {
+		t.Errorf("expected continuations to stop at %d requests, got %d", 1+maxContinuations, got)
+	}
+}
+
+func TestContinuationsRespectProviderRPS(t *testing.T) {
+	tke := testTokenizer(t)
+	sse := &mockSSEHandler{chunks: []string{"short", " reply"}}
+	var mu sync.Mutex
+	var starts []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+		sse.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	defer func(prev requestScheduler) { sessionScheduler = prev }(sessionScheduler)
+	defer func(prev int) { minOutputTokens = prev }(minOutputTokens)
+	const interval = 50 * time.Millisecond
+	sessionScheduler, _ = newRequestScheduler(0, 0, float64(time.Second/interval))
+	minOutputTokens = 1000
+
+	config := ProviderConfig{Name: "mock", BaseURL: srv.URL, APIKey: "test", Model: "mock-model"}
+	req := openai.ChatCompletionRequest{
+		Model:    config.Model,
+		Messages: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "hi"}},
+		Stream:   true,
+	}
+	if _, _, _, _, _, err := runStreamingChat(context.Background(), config, tke, log.New(io.Discard, "", 0), req); err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	if len(starts) != 1+maxContinuations {
+		t.Fatalf("expected %d requests, got %d", 1+maxContinuations, len(starts))
+	}
+	// Allow for timer granularity; a continuation sent without waiting for
+	// a slot follows its predecessor within a millisecond or two
+	for i := 1; i < len(starts); i++ {
+		if gap := starts[i].Sub(starts[i-1]); gap < interval-10*time.Millisecond {
+			t.Errorf("request %d started %v after the previous one, want at least %v", i, gap, interval)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/pkoukk/tiktoken-go"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// conversationFollowUps are the user turns sent after the opening story prompt.
+// Each asks for another short passage so output length stays comparable while
+// the context grows turn by turn.
+var conversationFollowUps = []string{
+	"Continue the story with what happens next, in about 150 words.",
+	"Retell the last scene from the point of view of another character, in about 150 words.",
+	"Add an unexpected twist and continue for about 150 more words.",
+	"Write a short epilogue set ten years later, in about 150 words.",
+}
+
+// conversation is one virtual user's chat history.
+type conversation struct {
+	messages []openai.ChatCompletionMessage
+	// turn counts completed exchanges.
+	turn int
+}
+
+// next appends the next user message and returns the history to send. The
+// opening turn uses the streaming prompt for requestKey.
+func (c *conversation) next(providerName, requestKey string) []openai.ChatCompletionMessage {
+	content := selectStreamingPrompt(providerName, requestKey)
+	if c.turn > 0 {
+		content = conversationFollowUps[(c.turn-1)%len(conversationFollowUps)]
+	}
+	c.messages = append(c.messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: content})
+	return c.messages
+}
+
+// reply records the assistant's answer to the last user message.
+func (c *conversation) reply(content string) {
+	c.messages = append(c.messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: content})
+	c.turn++
+}
+
+// reset starts a new conversation.
+func (c *conversation) reset() {
+	c.messages = nil
+	c.turn = 0
+}
+
+// conversationTurnRun sends one turn of a conversation with its full history.
+func conversationTurnRun(ctx context.Context, config ProviderConfig, tke *tiktoken.Tiktoken, providerLogger *log.Logger, messages []openai.ChatCompletionMessage) (e2e, ttft time.Duration, throughput float64, tokens int, response string, err error) {
+	req := openai.ChatCompletionRequest{
+		Model:     config.Model,
+		Messages:  messages,
+		MaxTokens: requestMaxTokens(defaultMaxTokens),
+		Stream:    true,
+	}
+	return runStreamingChat(ctx, config, tke, providerLogger, req)
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestConversationGrowsHistory(t *testing.T) {
+	var c conversation
+	first := c.next("p", "k")
+	if len(first) != 1 || first[0].Content != selectStreamingPrompt("p", "k") {
+		t.Fatalf("unexpected opening turn: %+v", first)
+	}
+	c.reply("Once upon a time.")
+	second := c.next("p", "k")
+	if len(second) != 3 || second[1].Role != openai.ChatMessageRoleAssistant || second[2].Content != conversationFollowUps[0] {
+		t.Fatalf("unexpected second turn: %+v", second)
+	}
+	c.reply("And then.")
+	if c.turn != 2 {
+		t.Fatalf("turn = %d, want 2", c.turn)
+	}
+	c.reset()
+	if again := c.next("p", "k"); len(again) != 1 {
+		t.Fatalf("reset kept history: %+v", again)
+	}
+}
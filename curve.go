@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// curveEdgeWindows is how many one-second windows are averaged for the start
+	// and end rates of a throughput curve.
+	curveEdgeWindows = 2
+	// throttledShare marks a stream as throttled when its end rate falls below
+	// this share of its start rate.
+	throttledShare = 0.7
+)
+
+// sparkBlocks are the glyphs of a sparkline, lowest to highest.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline renders values as a row of block glyphs scaled to their maximum.
+func sparkline(values []float64) string {
+	peak := 0.0
+	for _, v := range values {
+		peak = max(peak, v)
+	}
+	var b strings.Builder
+	for _, v := range values {
+		i := 0
+		if peak > 0 {
+			i = int(v / peak * float64(len(sparkBlocks)-1))
+		}
+		b.WriteRune(sparkBlocks[min(max(i, 0), len(sparkBlocks)-1)])
+	}
+	return b.String()
+}
+
+// curveEdges returns the mean rate of the first and last curveEdgeWindows windows.
+func curveEdges(curve []float64) (start, end float64) {
+	n := min(curveEdgeWindows, len(curve))
+	if n == 0 {
+		return 0, 0
+	}
+	for i := 0; i < n; i++ {
+		start += curve[i]
+		end += curve[len(curve)-1-i]
+	}
+	return start / float64(n), end / float64(n)
+}
+
+// curveRow is one entry of the throughput-over-time table.
+type curveRow struct {
+	provider string
+	mode     string
+	curve    []float64
+}
+
+// writeCurveRows renders per-provider throughput curves as sparklines.
+func writeCurveRows(report *strings.Builder, rows []curveRow) {
+	if len(rows) == 0 {
+		return
+	}
+	report.WriteString("## Throughput Over Time\n\n")
+	fmt.Fprintf(report, "Tokens/sec in consecutive 1-second windows after the first token, averaged over runs. "+
+		"Streams whose last %d seconds run below %.0f%% of their first %d are marked *throttled*.\n\n",
+		curveEdgeWindows, 100*throttledShare, curveEdgeWindows)
+	report.WriteString("| Provider | Mode | Seconds | Start | End | Change | Curve |\n")
+	report.WriteString("|----------|------|---------|-------|-----|--------|-------|\n")
+	for _, r := range rows {
+		start, end := curveEdges(r.curve)
+		change := formatDelta(end, start)
+		if len(r.curve) > curveEdgeWindows && end < throttledShare*start {
+			change += " *throttled*"
+		}
+		fmt.Fprintf(report, "| %s | %s | %d | %.1f tok/s | %.1f tok/s | %s | `%s` |\n",
+			r.provider, r.mode, len(r.curve), start, end, change, sparkline(r.curve))
+	}
+	report.WriteString("\n")
+}
+
+// writeCurveSection adds throughput curves for results that have one.
+func writeCurveSection(report *strings.Builder, results []TestResult) {
+	rows := make([]curveRow, 0, len(results))
+	for _, r := range results {
+		if len(r.ThroughputCurve) > 0 {
+			rows = append(rows, curveRow{providerLabel(r.Provider, r.Env), r.Mode, r.ThroughputCurve})
+		}
+	}
+	writeCurveRows(report, rows)
+}
+
+// writeDiagnosticCurveSection is the diagnostic-report counterpart of
+// writeCurveSection.
+func writeDiagnosticCurveSection(report *strings.Builder, results []DiagnosticSummary) {
+	rows := make([]curveRow, 0, len(results))
+	for _, r := range results {
+		if len(r.ThroughputCurve) > 0 {
+			rows = append(rows, curveRow{providerLabel(r.Provider, r.Env), r.Mode, r.ThroughputCurve})
+		}
+	}
+	writeCurveRows(report, rows)
+}
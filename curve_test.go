@@ -0,0 +1,31 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCurveSectionFlagsThrottledStreams(t *testing.T) {
+	if got := sparkline([]float64{0, 50, 100}); got != "▁▄█" {
+		t.Errorf("sparkline = %q", got)
+	}
+
+	var report strings.Builder
+	writeCurveSection(&report, []TestResult{
+		{Provider: "steady", Mode: "streaming", Success: true, ThroughputCurve: []float64{100, 100, 100, 100}},
+		{Provider: "throttles", Mode: "streaming", Success: true, ThroughputCurve: []float64{200, 200, 120, 40, 40}},
+		{Provider: "no-curve", Mode: "streaming", Success: true},
+	})
+	out := report.String()
+	for _, want := range []string{
+		"| steady | streaming | 4 | 100.0 tok/s | 100.0 tok/s | +0% | `████` |",
+		"| throttles | streaming | 5 | 200.0 tok/s | 40.0 tok/s | -80% *throttled* | `██▅▂▂` |",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "no-curve") {
+		t.Error("results without a curve must be skipped")
+	}
+}
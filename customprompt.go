@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Prompt groups that a [prompts.<group>] table of the config file can replace.
+const (
+	promptGroupStreaming   = "streaming"
+	promptGroupToolCalling = "tool_calling"
+	promptGroupLongStory   = "long_story"
+)
+
+// builtinPrompts are the prompts a [prompts.<group>] table can name with
+// builtin, so a group can borrow another's workload.
+var builtinPrompts = map[string]string{
+	"story":      streamingPrompt,
+	"weather":    toolCallingPrompt,
+	"long-story": longStoryUserPrompt,
+}
+
+// promptConfig is a [prompts.<group>] table: exactly one of the prompt text,
+// a file holding it, or the name of a built-in prompt.
+type promptConfig struct {
+	Text string `toml:"text"`
+	// File is read when the config is loaded, relative to the config file.
+	File    string `toml:"file"`
+	Builtin string `toml:"builtin"`
+}
+
+// resolve returns the prompt text, reading File relative to dir.
+func (p promptConfig) resolve(dir string) (string, error) {
+	set := 0
+	for _, v := range []string{p.Text, p.File, p.Builtin} {
+		if v != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return "", fmt.Errorf("set exactly one of text, file and builtin")
+	}
+	switch {
+	case p.Builtin != "":
+		text, ok := builtinPrompts[p.Builtin]
+		if !ok {
+			names := make([]string, 0, len(builtinPrompts))
+			for name := range builtinPrompts {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return "", fmt.Errorf("unknown builtin %q (available: %s)", p.Builtin, strings.Join(names, ", "))
+		}
+		return text, nil
+	case p.File != "":
+		path := p.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		return readPromptFile(path)
+	}
+	return p.Text, nil
+}
+
+// readPromptFile reads a prompt from path, trimmed of surrounding whitespace.
+func readPromptFile(path string) (string, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return "", fmt.Errorf("error reading prompt file: %w", err)
+	}
+	text := strings.TrimSpace(string(data))
+	if text == "" {
+		return "", fmt.Errorf("prompt file %s is empty", path)
+	}
+	return text, nil
+}
+
+// resolvePrompts checks the [prompts] tables of a config file in dir and
+// returns their text by group.
+func resolvePrompts(prompts map[string]promptConfig, dir string) (map[string]string, error) {
+	resolved := make(map[string]string, len(prompts))
+	for group, p := range prompts {
+		switch group {
+		case promptGroupStreaming, promptGroupToolCalling, promptGroupLongStory:
+		default:
+			return nil, fmt.Errorf("unknown prompt group %q (available: %s, %s, %s)", group,
+				promptGroupStreaming, promptGroupToolCalling, promptGroupLongStory)
+		}
+		text, err := p.resolve(dir)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", group, err)
+		}
+		resolved[group] = text
+	}
+	return resolved, nil
+}
+
+// customPrompts replace the built-in prompt of their group for the session;
+// set from [prompts] in --config and --prompt-file.
+var customPrompts map[string]string
+
+// sessionPrompt returns the session's prompt for group, or builtin when the
+// group was not customized.
+func sessionPrompt(group, builtin string) string {
+	if text, ok := customPrompts[group]; ok {
+		return text
+	}
+	return builtin
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadPrompts(t *testing.T) {
+	path := writeConfig(t, `
+[prompts.streaming]
+file = "support.txt"
+
+[prompts.tool_calling]
+text = "Check the weather in Oslo."
+
+[prompts.long_story]
+builtin = "story"
+`)
+	if err := os.WriteFile(filepath.Join(filepath.Dir(path), "support.txt"), []byte("\nSummarize this ticket.\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.prompts[promptGroupStreaming] != "Summarize this ticket." || cfg.prompts[promptGroupToolCalling] != "Check the weather in Oslo." ||
+		cfg.prompts[promptGroupLongStory] != streamingPrompt {
+		t.Fatalf("unexpected prompts %q", cfg.prompts)
+	}
+
+	for body, want := range map[string]string{
+		"[prompts.chat]\ntext = \"hi\"\n":                           "unknown prompt group",
+		"[prompts.streaming]\ntext = \"hi\"\nbuiltin = \"story\"\n": "exactly one",
+		"[prompts.streaming]\nbuiltin = \"poem\"\n":                 "unknown builtin",
+		"[prompts.streaming]\nfile = \"missing.txt\"\n":             "error reading prompt file",
+	} {
+		if _, err := loadConfigFile(writeConfig(t, body)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("config %q: expected an error containing %q, got %v", body, want, err)
+		}
+	}
+}
+
+func TestCustomPromptsReplaceBuiltins(t *testing.T) {
+	savedPrompts, savedRotate := customPrompts, rotatePrompts
+	defer func() { customPrompts, rotatePrompts = savedPrompts, savedRotate }()
+	rotatePrompts = true
+	customPrompts = map[string]string{promptGroupStreaming: "Summarize this ticket.", promptGroupToolCalling: "Check Oslo."}
+
+	if got := selectStreamingPrompt("nim", "run1"); got != "Summarize this ticket." {
+		t.Errorf("streaming prompt = %q", got)
+	}
+	if got := promptForRun(ProviderConfig{Name: "nim"}, ModeToolCalling, "run1"); got != "Check Oslo." {
+		t.Errorf("tool-calling prompt = %q", got)
+	}
+	if got := sessionPrompt(promptGroupLongStory, longStoryUserPrompt); got != longStoryUserPrompt {
+		t.Error("an unset group should keep its built-in prompt")
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// daemonLogFileName is the log of a tenant's runs, kept in its results folder.
+const daemonLogFileName = "daemon.log"
+
+// minDaemonInterval keeps a misconfigured schedule from hammering providers.
+const minDaemonInterval = time.Minute
+
+// daemonConfig is the [daemon] section of a config file: how the daemon
+// subcommand schedules benchmarks for one tenant (a team or project).
+type daemonConfig struct {
+	// Name identifies the tenant in logs; the config file name when empty.
+	Name  string         `toml:"name"`
+	Every configDuration `toml:"every"`
+	// ResultsDir is the tenant's own results folder; results/<name> when empty.
+	ResultsDir string `toml:"results_dir"`
+	// Args are the benchmark flags of each run, e.g. ["--all", "--diagnostic"].
+	Args []string `toml:"args"`
+}
+
+// daemonManagedFlags are set by the daemon for every run, so a tenant cannot
+// point a run at another tenant's config or results.
+var daemonManagedFlags = []string{"config", "results-dir", "no-keys"}
+
+// validate checks the section.
+func (d daemonConfig) validate() error {
+	if d.Every.Duration < minDaemonInterval {
+		return fmt.Errorf("daemon: every must be at least %s, got %s", minDaemonInterval, d.Every.Duration)
+	}
+	if d.Name != "" {
+		if err := validateSessionLabel("daemon name", d.Name); err != nil {
+			return fmt.Errorf("daemon: %w", err)
+		}
+	}
+	for _, arg := range d.Args {
+		name := strings.TrimLeft(arg, "-")
+		name, _, _ = strings.Cut(name, "=")
+		if strings.HasPrefix(arg, "-") && containsFold(daemonManagedFlags, name) {
+			return fmt.Errorf("daemon: args must not set --%s; the daemon sets it for each tenant", name)
+		}
+	}
+	return nil
+}
+
+// daemonTenant is one independently scheduled config.
+type daemonTenant struct {
+	name       string
+	configPath string
+	resultsDir string
+	every      time.Duration
+	args       []string
+	state      *tenantState
+}
+
+// tenantState is a tenant's run history, shared with the API.
+type tenantState struct {
+	mu        sync.Mutex
+	running   bool
+	runs      int
+	lastStart time.Time
+	lastEnd   time.Time
+	lastError string
+	next      time.Time
+	// trigger requests a run ahead of schedule; one pending request is kept.
+	trigger chan struct{}
+	// reload wakes the scheduler after a config reload changed the schedule.
+	reload chan struct{}
+	// config and configSum are the tenant's config file as last read,
+	// reloaded the settings applied from it, and changes the reloads not yet
+	// passed to a run.
+	config    *fileConfig
+	configSum [sha256.Size]byte
+	reloaded  *daemonSettings
+	changes   []ConfigChange
+}
+
+func newTenantState() *tenantState {
+	return &tenantState{trigger: make(chan struct{}, 1), reload: make(chan struct{}, 1)}
+}
+
+// loadDaemonTenants reads the config files. Every file needs a [daemon]
+// section, and no two tenants may share a name or results folder, so their
+// sessions and leaderboards stay apart.
+func loadDaemonTenants(paths []string) ([]daemonTenant, error) {
+	tenants := make([]daemonTenant, 0, len(paths))
+	names := make(map[string]string)
+	dirs := make(map[string]string)
+	for _, path := range paths {
+		cfg, err := loadConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Daemon == nil {
+			return nil, fmt.Errorf("config %s: no [daemon] section", path)
+		}
+		t := daemonTenant{
+			name:       cfg.Daemon.Name,
+			configPath: path,
+			resultsDir: cfg.Daemon.ResultsDir,
+			every:      cfg.Daemon.Every.Duration,
+			args:       cfg.Daemon.Args,
+			state:      newTenantState(),
+		}
+		if t.name == "" {
+			t.name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		if t.resultsDir == "" {
+			t.resultsDir = filepath.Join("results", t.name)
+		}
+		if other, ok := names[t.name]; ok {
+			return nil, fmt.Errorf("configs %s and %s both define tenant %q", other, path, t.name)
+		}
+		dir := filepath.Clean(t.resultsDir)
+		if other, ok := dirs[dir]; ok {
+			return nil, fmt.Errorf("configs %s and %s share results folder %s", other, path, dir)
+		}
+		names[t.name] = path
+		dirs[dir] = path
+		tenants = append(tenants, t)
+	}
+	return tenants, nil
+}
+
+// command returns the arguments of one benchmark run for the tenant.
+func (t daemonTenant) command() []string {
+	args := append([]string(nil), t.settings().args...)
+	return append(args, "--config", t.configPath, "--results-dir", t.resultsDir, "--no-keys")
+}
+
+// runOnce runs one benchmark as a child process of exe, appending its output to
+// the tenant's daemon.log. Each run is a separate process because session state
+// lives in package globals, which parallel tenants would otherwise share.
+func (t daemonTenant) runOnce(ctx context.Context, exe string) error {
+	if err := os.MkdirAll(t.resultsDir, 0750); err != nil {
+		return fmt.Errorf("error creating results folder: %w", err)
+	}
+	logFile, err := os.OpenFile(filepath.Join(t.resultsDir, daemonLogFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("error opening daemon log: %w", err)
+	}
+	defer func() {
+		if closeErr := logFile.Close(); closeErr != nil {
+			log.Printf("Warning: Failed to close daemon log: %v", closeErr)
+		}
+	}()
+
+	cmd := exec.CommandContext(ctx, exe, t.command()...) // #nosec G204 -- exe is this binary, args come from the operator's config
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	if changes := t.takeConfigChanges(); len(changes) > 0 {
+		data, err := json.Marshal(changes)
+		if err != nil {
+			return fmt.Errorf("error encoding config changes: %w", err)
+		}
+		cmd.Env = append(os.Environ(), configChangesEnv+"="+string(data))
+	}
+	// Ask the run to stop gracefully first, so it finishes its in-flight
+	// requests and still writes its report; one still running a minute later
+	// is killed
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = time.Minute
+	return cmd.Run()
+}
+
+// schedule runs the tenant immediately and then at its interval until ctx is
+// done. A run that overlaps the next slot delays it rather than running twice;
+// a triggered run happens as soon as the current one, if any, finishes. A
+// reloaded interval counts from the start of the last run.
+func (t daemonTenant) schedule(ctx context.Context, exe string, once bool) {
+	for {
+		start := time.Now()
+		t.state.mu.Lock()
+		t.state.running = true
+		t.state.lastStart = start
+		t.state.mu.Unlock()
+
+		log.Printf("[%s] Starting run: %s", t.name, strings.Join(t.command(), " "))
+		err := t.runOnce(ctx, exe)
+		if err != nil {
+			log.Printf("[%s] Run failed after %s: %v (see %s)", t.name, time.Since(start).Round(time.Second), err,
+				filepath.Join(t.resultsDir, daemonLogFileName))
+		} else {
+			log.Printf("[%s] Run complete in %s", t.name, time.Since(start).Round(time.Second))
+		}
+		next := start.Add(t.settings().every)
+		t.state.mu.Lock()
+		t.state.running = false
+		t.state.runs++
+		t.state.lastEnd = time.Now()
+		t.state.lastError = ""
+		if err != nil {
+			t.state.lastError = err.Error()
+		}
+		t.state.next = next
+		t.state.mu.Unlock()
+		if once {
+			return
+		}
+
+		log.Printf("[%s] Next run at %s", t.name, next.Format("15:04:05"))
+		if !t.wait(ctx, next) {
+			return
+		}
+	}
+}
+
+// wait blocks until the tenant's next run is due or triggered, following
+// reloads that move it. It returns false once ctx is done.
+func (t daemonTenant) wait(ctx context.Context, next time.Time) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(time.Until(next)):
+			return true
+		case <-t.state.trigger:
+			log.Printf("[%s] Run triggered ahead of schedule", t.name)
+			return true
+		case <-t.state.reload:
+			t.state.mu.Lock()
+			next = t.state.next
+			t.state.mu.Unlock()
+			log.Printf("[%s] Next run moved to %s", t.name, next.Format("15:04:05"))
+		}
+	}
+}
+
+// runDaemon implements the "daemon" subcommand: it schedules every config's
+// benchmarks side by side until interrupted.
+func runDaemon(args []string) {
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	once := fs.Bool("once", false, "Run every tenant once, then exit")
+	listen := fs.String("listen", "", "Serve the control API on this address (e.g. 127.0.0.1:8090)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: llm-api-speed daemon [--once] [--listen addr] <config.toml>...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		log.Fatalf("Error parsing flags: %v", err)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *listen != "" && *once {
+		log.Fatal("Error: --listen cannot be combined with --once")
+	}
+	tenants, err := loadDaemonTenants(fs.Args())
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Error: cannot locate own executable: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	log.Printf("=== DAEMON: %d tenant(s) ===", len(tenants))
+	if *listen != "" {
+		token := os.Getenv(daemonAPITokenEnv)
+		if token == "" {
+			log.Printf("Warning: %s is not set; the control API accepts unauthenticated requests", daemonAPITokenEnv)
+		}
+		go serveDaemonAPI(ctx, *listen, token, tenants)
+	}
+	var wg sync.WaitGroup
+	for _, t := range tenants {
+		log.Printf("[%s] every %s, config %s, results in %s/", t.name, t.every, t.configPath, t.resultsDir)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t.schedule(ctx, exe, *once)
+		}()
+		if !*once {
+			go t.watchConfig(ctx)
+		}
+	}
+	wg.Wait()
+	log.Println("Daemon stopped.")
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTenantConfig(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(body), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDaemonTenants(t *testing.T) {
+	dir := t.TempDir()
+	search := writeTenantConfig(t, dir, "search.toml", `
+[daemon]
+every = "1h"
+args = ["--all", "--tags", "nightly"]
+`)
+	chat := writeTenantConfig(t, dir, "chat.toml", `
+[daemon]
+name = "chat-team"
+every = "15m"
+results_dir = "/srv/bench/chat"
+args = ["--provider", "nim", "--diagnostic"]
+`)
+	tenants, err := loadDaemonTenants([]string{search, chat})
+	if err != nil {
+		t.Fatalf("loadDaemonTenants failed: %v", err)
+	}
+	if len(tenants) != 2 {
+		t.Fatalf("got %d tenants, want 2", len(tenants))
+	}
+	if s := tenants[0]; s.name != "search" || s.resultsDir != filepath.Join("results", "search") || s.every != time.Hour {
+		t.Errorf("unexpected defaults: %+v", s)
+	}
+	want := "--provider nim --diagnostic --config " + chat + " --results-dir /srv/bench/chat --no-keys"
+	if got := strings.Join(tenants[1].command(), " "); got != want {
+		t.Errorf("command = %q, want %q", got, want)
+	}
+}
+
+func TestLoadDaemonTenantsRejects(t *testing.T) {
+	dir := t.TempDir()
+	valid := writeTenantConfig(t, dir, "a.toml", "[daemon]\nevery = \"1h\"\nresults_dir = \"shared\"\n")
+	other := filepath.Join(dir, "other")
+	if err := os.Mkdir(other, 0750); err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string][]string{
+		"no daemon section": {writeTenantConfig(t, dir, "plain.toml", "[[slo]]\nmetric = \"success\"\nobjective = 99\n")},
+		"too frequent":      {writeTenantConfig(t, dir, "fast.toml", "[daemon]\nevery = \"10s\"\n")},
+		"managed flag":      {writeTenantConfig(t, dir, "sneaky.toml", "[daemon]\nevery = \"1h\"\nargs = [\"--results-dir=/tmp/other\"]\n")},
+		"bad name":          {writeTenantConfig(t, dir, "named.toml", "[daemon]\nevery = \"1h\"\nname = \"team a\"\n")},
+		"duplicate name":    {valid, writeTenantConfig(t, other, "a.toml", "[daemon]\nevery = \"1h\"\n")},
+		"shared results":    {valid, writeTenantConfig(t, dir, "b.toml", "[daemon]\nevery = \"1h\"\nresults_dir = \"./shared\"\n")},
+	}
+	for name, paths := range cases {
+		if _, err := loadDaemonTenants(paths); err == nil {
+			t.Errorf("%s: loadDaemonTenants succeeded, want error", name)
+		}
+	}
+}
+
+func TestDaemonTenantRunOnce(t *testing.T) {
+	dir := t.TempDir()
+	// A stand-in for the binary that records the arguments it was started with
+	exe := filepath.Join(dir, "fake-bench")
+	if err := os.WriteFile(exe, []byte("#!/bin/sh\necho \"run: $*\"\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	tenant := daemonTenant{name: "team", configPath: "team.toml", resultsDir: filepath.Join(dir, "team"), every: time.Hour, args: []string{"--all"}, state: newTenantState()}
+	tenant.schedule(context.Background(), exe, true)
+	tenant.schedule(context.Background(), exe, true)
+
+	data, err := os.ReadFile(filepath.Join(tenant.resultsDir, daemonLogFileName))
+	if err != nil {
+		t.Fatalf("missing daemon log: %v", err)
+	}
+	line := "run: --all --config team.toml --results-dir " + tenant.resultsDir + " --no-keys\n"
+	if string(data) != line+line {
+		t.Errorf("daemon log = %q, want two runs of %q", data, line)
+	}
+
+	failing := filepath.Join(dir, "failing-bench")
+	if err := os.WriteFile(failing, []byte("#!/bin/sh\nexit 3\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := tenant.runOnce(context.Background(), failing); err == nil {
+		t.Error("runOnce ignored a failing run")
+	}
+}
+
+func TestDaemonTenantReloadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTenantConfig(t, dir, "team.toml", "[daemon]\nevery = \"1h\"\nargs = [\"--all\"]\n")
+	tenants, err := loadDaemonTenants([]string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tenant := tenants[0]
+	if tenant.reloadConfig() {
+		t.Fatal("the first read only records the config")
+	}
+	if tenant.reloadConfig() {
+		t.Fatal("an unchanged config was reloaded")
+	}
+
+	writeTenantConfig(t, dir, "team.toml", "[daemon]\nevery = \"10s\"\n")
+	if tenant.reloadConfig() || tenant.settings().every != time.Hour {
+		t.Fatal("an invalid config was applied")
+	}
+
+	writeTenantConfig(t, dir, "team.toml", `
+[daemon]
+name = "renamed"
+every = "30m"
+args = ["--diagnostic"]
+
+[[threshold]]
+metric = "ttft"
+good = 0.5
+bad = 2
+`)
+	if !tenant.reloadConfig() {
+		t.Fatal("the edited config was not reloaded")
+	}
+	if s := tenant.settings(); s.every != 30*time.Minute || strings.Join(s.args, " ") != "--diagnostic" || tenant.name != "team" {
+		t.Fatalf("unexpected settings after reload: %+v, name %q", s, tenant.name)
+	}
+	changes := tenant.takeConfigChanges()
+	want := "every: 1h0m0s -> 30m0s; args: [--all] -> [--diagnostic]; [threshold] changed"
+	if len(changes) != 1 || strings.Join(changes[0].Changes, "; ") != want {
+		t.Fatalf("unexpected changes %+v", changes)
+	}
+
+	// The next run gets the changes, to record in its manifest
+	tenant.state.changes = changes
+	exe := filepath.Join(dir, "fake-bench")
+	if err := os.WriteFile(exe, []byte("#!/bin/sh\necho \"$"+configChangesEnv+"\"\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	tenant.resultsDir = filepath.Join(dir, "results")
+	if err := tenant.runOnce(context.Background(), exe); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(tenant.resultsDir, daemonLogFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(configChangesEnv, strings.TrimSpace(string(data)))
+	if got := sessionConfigChanges(); len(got) != 1 || strings.Join(got[0].Changes, "; ") != want {
+		t.Fatalf("run received %q", data)
+	}
+	if len(tenant.takeConfigChanges()) != 0 {
+		t.Fatal("changes were not cleared once passed to a run")
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// daemonAPITokenEnv holds the bearer token the control API requires.
+const daemonAPITokenEnv = "LLM_API_SPEED_DAEMON_TOKEN"
+
+// TenantStatus is a tenant as reported by GET /api/tenants.
+type TenantStatus struct {
+	Name       string        `json:"name"`
+	Every      time.Duration `json:"everyNs"`
+	ResultsDir string        `json:"resultsDir"`
+	Running    bool          `json:"running"`
+	// Queued is set when a triggered run waits for the current one.
+	Queued    bool      `json:"queued,omitempty"`
+	Runs      int       `json:"runs"`
+	LastStart time.Time `json:"lastStart,omitzero"`
+	LastEnd   time.Time `json:"lastEnd,omitzero"`
+	LastError string    `json:"lastError,omitempty"`
+	NextRun   time.Time `json:"nextRun,omitzero"`
+}
+
+// LatestSession is the newest session of a tenant, as served by
+// GET /api/tenants/{name}/latest.
+type LatestSession struct {
+	Session     string              `json:"session"`
+	Results     []TestResult        `json:"results,omitempty"`
+	Diagnostics []DiagnosticSummary `json:"diagnostics,omitempty"`
+}
+
+// status snapshots the tenant.
+func (t daemonTenant) status() TenantStatus {
+	every := t.settings().every
+	t.state.mu.Lock()
+	defer t.state.mu.Unlock()
+	return TenantStatus{
+		Name:       t.name,
+		Every:      every,
+		ResultsDir: t.resultsDir,
+		Running:    t.state.running,
+		Queued:     len(t.state.trigger) > 0,
+		Runs:       t.state.runs,
+		LastStart:  t.state.lastStart,
+		LastEnd:    t.state.lastEnd,
+		LastError:  t.state.lastError,
+		NextRun:    t.state.next,
+	}
+}
+
+// latestSessionDir returns the tenant's newest session folder. Session folder
+// names start with their timestamp, so the last in name order is the newest.
+func latestSessionDir(resultsDir string) (string, error) {
+	dirs, err := filepath.Glob(filepath.Join(resultsDir, "session-*"))
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(dirs)
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if info, err := os.Stat(dirs[i]); err == nil && info.IsDir() {
+			return dirs[i], nil
+		}
+	}
+	return "", os.ErrNotExist
+}
+
+// daemonAPI serves the control API:
+//
+//	GET  /api/tenants               status of every tenant
+//	GET  /api/tenants/{name}        status of one tenant
+//	POST /api/tenants/{name}/run    start a run now, or right after the current one
+//	GET  /api/tenants/{name}/latest results of the tenant's newest session
+//
+// Every request must carry "Authorization: Bearer <token>" when token is set.
+func daemonAPI(token string, tenants []daemonTenant) http.Handler {
+	byName := make(map[string]daemonTenant, len(tenants))
+	for _, t := range tenants {
+		byName[t.name] = t
+	}
+	lookup := func(w http.ResponseWriter, r *http.Request) (daemonTenant, bool) {
+		t, ok := byName[r.PathValue("name")]
+		if !ok {
+			writeAPIError(w, http.StatusNotFound, "unknown tenant "+r.PathValue("name"))
+		}
+		return t, ok
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/tenants", func(w http.ResponseWriter, _ *http.Request) {
+		statuses := make([]TenantStatus, 0, len(tenants))
+		for _, t := range tenants {
+			statuses = append(statuses, t.status())
+		}
+		writeAPIJSON(w, http.StatusOK, statuses)
+	})
+	mux.HandleFunc("GET /api/tenants/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if t, ok := lookup(w, r); ok {
+			writeAPIJSON(w, http.StatusOK, t.status())
+		}
+	})
+	mux.HandleFunc("POST /api/tenants/{name}/run", func(w http.ResponseWriter, r *http.Request) {
+		t, ok := lookup(w, r)
+		if !ok {
+			return
+		}
+		select {
+		case t.state.trigger <- struct{}{}:
+			log.Printf("[%s] Run requested via API from %s", t.name, r.RemoteAddr)
+			writeAPIJSON(w, http.StatusAccepted, t.status())
+		default:
+			writeAPIError(w, http.StatusConflict, "a triggered run is already queued")
+		}
+	})
+	mux.HandleFunc("GET /api/tenants/{name}/latest", func(w http.ResponseWriter, r *http.Request) {
+		t, ok := lookup(w, r)
+		if !ok {
+			return
+		}
+		dir, err := latestSessionDir(t.resultsDir)
+		if errors.Is(err, os.ErrNotExist) {
+			writeAPIError(w, http.StatusNotFound, "no sessions yet")
+			return
+		} else if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		results, diagnostics, err := loadSessionResults(dir)
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, LatestSession{Session: filepath.Base(dir), Results: results, Diagnostics: diagnostics})
+	})
+
+	if token == "" {
+		return mux
+	}
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			writeAPIError(w, http.StatusUnauthorized, "missing or wrong bearer token")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// writeAPIJSON writes v as the JSON response body.
+func writeAPIJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Warning: Failed to write API response: %v", err)
+	}
+}
+
+// writeAPIError writes {"error": msg}.
+func writeAPIError(w http.ResponseWriter, status int, msg string) {
+	writeAPIJSON(w, status, map[string]string{"error": msg})
+}
+
+// serveDaemonAPI serves the control API on addr until ctx is done.
+func serveDaemonAPI(ctx context.Context, addr, token string, tenants []daemonTenant) {
+	srv := &http.Server{Addr: addr, Handler: daemonAPI(token, tenants), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		stopCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(stopCtx)
+	}()
+	log.Printf("Control API listening on %s", addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("Warning: control API stopped: %v", err)
+	}
+}
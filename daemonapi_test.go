@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func apiRequest(t *testing.T, srv *httptest.Server, method, path, token string, out any) int {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil && resp.StatusCode < 300 {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: invalid JSON: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestDaemonAPI(t *testing.T) {
+	root := t.TempDir()
+	tenant := daemonTenant{name: "chat", resultsDir: root, every: time.Hour, state: newTenantState()}
+	tenant.state.runs = 2
+	tenant.state.lastError = "exit status 1"
+	srv := httptest.NewServer(daemonAPI("secret", []daemonTenant{tenant}))
+	defer srv.Close()
+
+	if code := apiRequest(t, srv, http.MethodGet, "/api/tenants", "", nil); code != http.StatusUnauthorized {
+		t.Errorf("request without token = %d, want 401", code)
+	}
+	if code := apiRequest(t, srv, http.MethodGet, "/api/tenants", "wrong", nil); code != http.StatusUnauthorized {
+		t.Errorf("request with wrong token = %d, want 401", code)
+	}
+
+	var statuses []TenantStatus
+	if code := apiRequest(t, srv, http.MethodGet, "/api/tenants", "secret", &statuses); code != http.StatusOK {
+		t.Fatalf("list = %d", code)
+	}
+	if len(statuses) != 1 || statuses[0].Name != "chat" || statuses[0].Runs != 2 || statuses[0].LastError != "exit status 1" {
+		t.Errorf("unexpected statuses %+v", statuses)
+	}
+	if code := apiRequest(t, srv, http.MethodGet, "/api/tenants/nope", "secret", nil); code != http.StatusNotFound {
+		t.Errorf("unknown tenant = %d, want 404", code)
+	}
+
+	var status TenantStatus
+	if code := apiRequest(t, srv, http.MethodPost, "/api/tenants/chat/run", "secret", &status); code != http.StatusAccepted || !status.Queued {
+		t.Errorf("trigger = %d %+v, want 202 and queued", code, status)
+	}
+	if code := apiRequest(t, srv, http.MethodPost, "/api/tenants/chat/run", "secret", nil); code != http.StatusConflict {
+		t.Errorf("second trigger = %d, want 409", code)
+	}
+	if len(tenant.state.trigger) != 1 {
+		t.Errorf("trigger channel holds %d requests, want 1", len(tenant.state.trigger))
+	}
+	if code := apiRequest(t, srv, http.MethodGet, "/api/tenants/chat/run", "secret", nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("GET on run = %d, want 405", code)
+	}
+
+	if code := apiRequest(t, srv, http.MethodGet, "/api/tenants/chat/latest", "secret", nil); code != http.StatusNotFound {
+		t.Errorf("latest without sessions = %d, want 404", code)
+	}
+	for _, name := range []string{"session-20251110-004615", "session-20251111-093000-nightly"} {
+		dir := filepath.Join(root, name)
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			t.Fatal(err)
+		}
+		saveResult(dir, TestResult{Provider: name, Model: "m", Mode: "streaming", Success: true, Timestamp: time.Now()})
+	}
+	var latest LatestSession
+	if code := apiRequest(t, srv, http.MethodGet, "/api/tenants/chat/latest", "secret", &latest); code != http.StatusOK {
+		t.Fatalf("latest = %d", code)
+	}
+	if latest.Session != "session-20251111-093000-nightly" || len(latest.Results) != 1 || latest.Results[0].Provider != latest.Session {
+		t.Errorf("unexpected latest session %+v", latest)
+	}
+}
+
+func TestDaemonTriggerRunsAheadOfSchedule(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "fake-bench")
+	if err := os.WriteFile(exe, []byte("#!/bin/sh\necho run\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	tenant := daemonTenant{name: "team", configPath: "team.toml", resultsDir: filepath.Join(dir, "team"), every: time.Hour, state: newTenantState()}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		tenant.schedule(ctx, exe, false)
+		close(done)
+	}()
+
+	waitRuns := func(n int) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for tenant.status().Runs < n {
+			if time.Now().After(deadline) {
+				t.Fatalf("tenant ran %d times, want %d", tenant.status().Runs, n)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	waitRuns(1)
+	if next := tenant.status().NextRun; time.Until(next) < 59*time.Minute {
+		t.Errorf("next run at %s, want an hour out", next)
+	}
+	tenant.state.trigger <- struct{}{}
+	waitRuns(2)
+	cancel()
+	<-done
+}
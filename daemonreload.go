@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// configPollInterval is how often the daemon checks tenant configs for edits.
+var configPollInterval = 5 * time.Second
+
+// configChangesEnv carries the config changes made since a tenant's previous
+// run to the next one, which records them in its manifest.
+const configChangesEnv = "LLM_API_SPEED_CONFIG_CHANGES"
+
+// ConfigChange is one reload of a tenant's config file by the daemon.
+type ConfigChange struct {
+	Time    time.Time `json:"time"`
+	Config  string    `json:"config"`
+	Changes []string  `json:"changes"`
+}
+
+// daemonSettings are the parts of a [daemon] section applied by a reload.
+type daemonSettings struct {
+	every time.Duration
+	args  []string
+}
+
+// settings returns the tenant's schedule and run flags, as last reloaded.
+func (t daemonTenant) settings() daemonSettings {
+	t.state.mu.Lock()
+	defer t.state.mu.Unlock()
+	if t.state.reloaded != nil {
+		return *t.state.reloaded
+	}
+	return daemonSettings{every: t.every, args: t.args}
+}
+
+// watchConfig reloads the tenant's config file whenever its contents change,
+// until ctx is done.
+func (t daemonTenant) watchConfig(ctx context.Context) {
+	t.reloadConfig()
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.reloadConfig()
+		}
+	}
+}
+
+// reloadConfig applies the tenant's config file if it changed since it was
+// last read. Everything outside [daemon] is read by each run anyway, so new
+// providers, thresholds or SLOs take effect from the next run; the schedule
+// and args are applied here. Renaming the tenant or moving its results would
+// split its history, so those changes are refused until a restart, as is a
+// file that no longer loads. It reports whether a change was applied.
+func (t daemonTenant) reloadConfig() bool {
+	data, err := os.ReadFile(t.configPath)
+	if err != nil {
+		log.Printf("[%s] Warning: cannot read %s: %v", t.name, t.configPath, err)
+		return false
+	}
+	sum := sha256.Sum256(data)
+	t.state.mu.Lock()
+	first := t.state.config == nil
+	unchanged := sum == t.state.configSum
+	t.state.mu.Unlock()
+	if unchanged {
+		return false
+	}
+
+	cfg, err := loadConfigFile(t.configPath)
+	if err == nil && cfg.Daemon == nil {
+		err = fmt.Errorf("config %s: no [daemon] section", t.configPath)
+	}
+	if err != nil {
+		log.Printf("[%s] Warning: config change ignored, keeping the previous one: %v", t.name, err)
+		t.state.mu.Lock()
+		t.state.configSum = sum
+		t.state.mu.Unlock()
+		return false
+	}
+	if first {
+		// The file as loaded at start, recorded to compare later edits against
+		t.state.mu.Lock()
+		t.state.configSum, t.state.config = sum, &cfg
+		t.state.mu.Unlock()
+		return false
+	}
+
+	old := t.settings()
+	next := daemonSettings{every: cfg.Daemon.Every.Duration, args: cfg.Daemon.Args}
+	var changes []string
+	if name := cfg.Daemon.Name; name != "" && name != t.name {
+		log.Printf("[%s] Warning: name change to %q needs a daemon restart", t.name, name)
+	}
+	if dir := cfg.Daemon.ResultsDir; dir != "" && filepath.Clean(dir) != filepath.Clean(t.resultsDir) {
+		log.Printf("[%s] Warning: results_dir change to %s needs a daemon restart", t.name, dir)
+	}
+	if next.every != old.every {
+		changes = append(changes, fmt.Sprintf("every: %s -> %s", old.every, next.every))
+	}
+	if !slices.Equal(next.args, old.args) {
+		changes = append(changes, fmt.Sprintf("args: [%s] -> [%s]", strings.Join(old.args, " "), strings.Join(next.args, " ")))
+	}
+
+	t.state.mu.Lock()
+	defer t.state.mu.Unlock()
+	changes = append(changes, configSectionChanges(t.state.config, &cfg)...)
+	t.state.configSum, t.state.config = sum, &cfg
+	if len(changes) == 0 {
+		return false
+	}
+	t.state.reloaded = &next
+	if !t.state.running && !t.state.lastStart.IsZero() {
+		t.state.next = t.state.lastStart.Add(next.every)
+	}
+	change := ConfigChange{Time: time.Now(), Config: t.configPath, Changes: changes}
+	t.state.changes = append(t.state.changes, change)
+	select {
+	case t.state.reload <- struct{}{}:
+	default:
+	}
+	log.Printf("[%s] Config reloaded: %s", t.name, strings.Join(changes, "; "))
+	return true
+}
+
+// configSectionChanges names the sections outside [daemon] that differ
+// between old and cfg.
+func configSectionChanges(old, cfg *fileConfig) []string {
+	var changes []string
+	for _, name := range slices.Sorted(maps.Keys(cfg.Providers)) {
+		if _, ok := old.Providers[name]; !ok {
+			changes = append(changes, "provider added: "+name)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(old.Providers)) {
+		if _, ok := cfg.Providers[name]; !ok {
+			changes = append(changes, "provider removed: "+name)
+		}
+	}
+	if len(changes) == 0 && !reflect.DeepEqual(old.Providers, cfg.Providers) {
+		changes = append(changes, "[provider] changed")
+	}
+	sections := []struct {
+		name     string
+		old, new any
+	}{
+		{"scenario", old.Scenario, cfg.Scenario},
+		{"slo", old.SLOs, cfg.SLOs},
+		{"column", old.Columns, cfg.Columns},
+		{"threshold", old.Thresholds, cfg.Thresholds},
+		{"test_params", old.TestParams, cfg.TestParams},
+		{"prompts", old.prompts, cfg.prompts},
+	}
+	for _, s := range sections {
+		if !reflect.DeepEqual(s.old, s.new) {
+			changes = append(changes, "["+s.name+"] changed")
+		}
+	}
+	return changes
+}
+
+// takeConfigChanges returns the changes not yet passed to a run and forgets
+// them.
+func (t daemonTenant) takeConfigChanges() []ConfigChange {
+	t.state.mu.Lock()
+	defer t.state.mu.Unlock()
+	changes := t.state.changes
+	t.state.changes = nil
+	return changes
+}
+
+// sessionConfigChanges reads the config changes the daemon passed to this
+// run, nil outside the daemon.
+func sessionConfigChanges() []ConfigChange {
+	value := os.Getenv(configChangesEnv)
+	if value == "" {
+		return nil
+	}
+	var changes []ConfigChange
+	if err := json.Unmarshal([]byte(value), &changes); err != nil {
+		log.Printf("Warning: ignoring invalid %s: %v", configChangesEnv, err)
+		return nil
+	}
+	return changes
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// deadlineLagThreshold is the termination lag past which a provider is flagged
+// in the report. Cancellation aborts the connection client-side, so lags
+// normally stay in the low milliseconds.
+const deadlineLagThreshold = time.Second
+
+// DeadlineStats audits the requests of one provider that ran into their
+// deadline: how long after it they actually terminated, from the deadline to
+// the failed round trip or the closing of the response body.
+type DeadlineStats struct {
+	Requests int           `json:"requests"`
+	AvgLag   time.Duration `json:"avgLagMs"`
+	MaxLag   time.Duration `json:"maxLagMs"`
+}
+
+// deadlineTracker collects termination lags per provider.
+type deadlineTracker struct {
+	mu        sync.Mutex
+	providers map[string][]time.Duration
+}
+
+// sessionDeadlines is the deadline tracker shared by every provider in the
+// session.
+var sessionDeadlines = &deadlineTracker{providers: make(map[string][]time.Duration)}
+
+// observe records a request of provider that terminated at end, if its
+// context ended by reaching its deadline.
+func (t *deadlineTracker) observe(ctx context.Context, provider string, end time.Time) {
+	deadline, ok := ctx.Deadline()
+	if !ok || ctx.Err() != context.DeadlineExceeded {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.providers[provider] = append(t.providers[provider], max(0, end.Sub(deadline)))
+}
+
+// stats summarizes the lags of provider, or returns nil when none of its
+// requests reached a deadline.
+func (t *deadlineTracker) stats(provider string) *DeadlineStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	lags := t.providers[provider]
+	if len(lags) == 0 {
+		return nil
+	}
+	s := &DeadlineStats{Requests: len(lags)}
+	var sum time.Duration
+	for _, lag := range lags {
+		sum += lag
+		s.MaxLag = max(s.MaxLag, lag)
+	}
+	s.AvgLag = sum / time.Duration(len(lags))
+	return s
+}
+
+// deadlineTransport times how long requests outlive their deadline.
+type deadlineTransport struct {
+	base   http.RoundTripper
+	config ProviderConfig
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *deadlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	provider := runContextFrom(req.Context(), t.config).Provider
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		sessionDeadlines.observe(req.Context(), provider, time.Now())
+		return resp, err
+	}
+	resp.Body = &deadlineBody{ReadCloser: resp.Body, ctx: req.Context(), provider: provider}
+	return resp, nil
+}
+
+// deadlineBody records the lag when a streamed response is closed after its
+// deadline.
+type deadlineBody struct {
+	io.ReadCloser
+	ctx      context.Context
+	provider string
+}
+
+// Close implements io.Closer.
+func (b *deadlineBody) Close() error {
+	err := b.ReadCloser.Close()
+	sessionDeadlines.observe(b.ctx, b.provider, time.Now())
+	return err
+}
+
+// deadlineRow is one provider's entry in the deadline table.
+type deadlineRow struct {
+	provider, mode string
+	stats          *DeadlineStats
+}
+
+// writeDeadlineRows adds the deadline audit; nothing is written without rows.
+func writeDeadlineRows(report *strings.Builder, rows []deadlineRow) {
+	if len(rows) == 0 {
+		return
+	}
+	report.WriteString("## Deadline Accuracy\n\n")
+	fmt.Fprintf(report, "Requests that ran into their timeout, and how long after it they terminated: from the deadline to the failed "+
+		"request or the closed response stream. Providers flagged ⚠ held a request more than %s past its deadline, "+
+		"time a client has to budget connections and goroutines for.\n\n", formatDuration(deadlineLagThreshold))
+	report.WriteString("| Provider | Mode | Timed Out | Avg Lag | Max Lag |\n")
+	report.WriteString("|----------|------|-----------|---------|---------|\n")
+	for _, r := range rows {
+		flag := ""
+		if r.stats.MaxLag > deadlineLagThreshold {
+			flag = " ⚠"
+		}
+		fmt.Fprintf(report, "| %s | %s | %d | %s | %s%s |\n", r.provider, r.mode, r.stats.Requests,
+			formatDuration(r.stats.AvgLag), formatDuration(r.stats.MaxLag), flag)
+	}
+	report.WriteString("\n")
+}
+
+// writeDeadlineSection adds the deadline audit for results with timed-out
+// requests.
+func writeDeadlineSection(report *strings.Builder, results []TestResult) {
+	rows := make([]deadlineRow, 0, len(results))
+	for _, r := range results {
+		if r.Deadlines != nil {
+			rows = append(rows, deadlineRow{providerLabel(r.Provider, r.Env), r.Mode, r.Deadlines})
+		}
+	}
+	writeDeadlineRows(report, rows)
+}
+
+// writeDiagnosticDeadlineSection is the diagnostic-report counterpart of
+// writeDeadlineSection.
+func writeDiagnosticDeadlineSection(report *strings.Builder, results []DiagnosticSummary) {
+	rows := make([]deadlineRow, 0, len(results))
+	for _, r := range results {
+		if r.Deadlines != nil {
+			rows = append(rows, deadlineRow{providerLabel(r.Provider, r.Env), r.Mode, r.Deadlines})
+		}
+	}
+	writeDeadlineRows(report, rows)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeadlineTransport(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/stream" {
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+		}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	config := ProviderConfig{Name: "deadline-test", Env: "prod"}
+	client := providerHTTPClient(config)
+	get := func(path string) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+path, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			return
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+	get("/headers")
+	get("/stream")
+
+	stats := sessionDeadlines.stats("deadline-test [prod]")
+	if stats == nil || stats.Requests != 2 || stats.MaxLag > deadlineLagThreshold || stats.AvgLag > stats.MaxLag {
+		t.Fatalf("expected two prompt timeouts, got %+v", stats)
+	}
+	if sessionDeadlines.stats("other") != nil {
+		t.Fatal("expected no stats for a provider without timeouts")
+	}
+
+	var report strings.Builder
+	writeDeadlineSection(&report, []TestResult{
+		{Provider: "nim", Mode: "streaming", Deadlines: &DeadlineStats{Requests: 3, AvgLag: 2 * time.Second, MaxLag: 4 * time.Second}},
+		{Provider: "novita", Mode: "streaming"},
+	})
+	got := report.String()
+	if !strings.Contains(got, "## Deadline Accuracy") || !strings.Contains(got, "| nim | streaming | 3 | 2.000s | 4.000s ⚠ |") ||
+		strings.Contains(got, "novita") {
+		t.Fatalf("unexpected section:\n%s", got)
+	}
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lamim/llm-api-speed/pkg/benchmark"
+	"github.com/lamim/llm-api-speed/pkg/providers"
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// DiagnosticSummary holds the aggregated results from a diagnostic run.
+type DiagnosticSummary struct {
+	SessionID        string                `json:"sessionId,omitempty"`
+	Provider         string                `json:"provider"`
+	Model            string                `json:"model"`
+	Env              string                `json:"env,omitempty"`
+	IPVersion        string                `json:"ipVersion,omitempty"`
+	HTTPVersion      string                `json:"httpVersion,omitempty"`
+	ToolChoice       string                `json:"toolChoice,omitempty"`
+	Profile          string                `json:"profile,omitempty"`
+	Mode             string                `json:"mode"`
+	Timestamp        time.Time             `json:"timestamp"`
+	TotalRequests    int                   `json:"totalRequests"`
+	Successful       int                   `json:"successful"`
+	Failed           int                   `json:"failed"`
+	AvgE2ELatency    time.Duration         `json:"avgE2eLatency"`
+	AvgTTFT          time.Duration         `json:"avgTtft"`
+	AvgThroughput    float64               `json:"avgThroughput"`
+	AvgTokens        int                   `json:"avgTokens"`
+	ProjectedE2E     time.Duration         `json:"projectedE2eLatency,omitempty"`
+	SecPer100Tokens  float64               `json:"secondsPer100Tokens,omitempty"`
+	NormalizedE2E    time.Duration         `json:"normalizedE2eLatency,omitempty"`
+	TTFTSpread       *DurationSpread       `json:"ttftSpread,omitempty"`
+	E2ESpread        *DurationSpread       `json:"e2eSpread,omitempty"`
+	ThroughputSpread *RateSpread           `json:"throughputSpread,omitempty"`
+	ChunkStats       *benchmark.ChunkStats `json:"chunkStats,omitempty"`
+	ThroughputCurve  []float64             `json:"throughputCurve,omitempty"`
+	ITL              *ITLStats             `json:"interTokenLatency,omitempty"`
+	ToolArgs         *ToolArgsStats        `json:"toolArgs,omitempty"`
+	KeyStats         []KeyStat             `json:"keyStats,omitempty"`
+	LiteLLM          *LiteLLMStats         `json:"litellm,omitempty"`
+	ServerMetrics    *ServerMetrics        `json:"serverMetrics,omitempty"`
+	NetworkBaseline  *NetworkBaseline      `json:"networkBaseline,omitempty"`
+	Instances        []InstanceStats       `json:"instances,omitempty"`
+	Deadlines        *DeadlineStats        `json:"deadlineOverruns,omitempty"`
+	Errors           map[string]int        `json:"errors,omitempty"`
+	// TTFTTimeline buckets the requests by when they started, for the TTFT
+	// heatmap.
+	TTFTTimeline []TTFTBucket `json:"ttftTimeline,omitempty"`
+	// Scaling is the worker scaling and capacity estimate of an autoscaled
+	// run (--diagnostic-autoscale).
+	Scaling *WorkerScaling `json:"scaling,omitempty"`
+	// Usage is the provider's total usage in the session so far.
+	Usage *UsageTotals `json:"usage,omitempty"`
+	// RecordsFile names the JSON Lines file next to the summary holding
+	// Records, one line per request; Records itself is loaded from it.
+	RecordsFile string             `json:"recordsFile,omitempty"`
+	Records     []DiagnosticRecord `json:"-"`
+
+	DegenerateResponses int      `json:"degenerateResponses,omitempty"`
+	OutputFlags         []string `json:"outputFlags,omitempty"`
+}
+
+// diagnosticMode runs continuous testing with diagnosticWorkers workers for 90
+// seconds through benchmark.Load. Makes requests every 15 seconds, with
+// 30-second timeout per request. Workers stop starting new requests when
+// insufficient time remains (5s grace period). Expected: 4 requests per
+// worker (at 0s, 15s, 30s, 45s), 40 in total with the default 10 workers.
+func diagnosticMode(config ProviderConfig, tke *tiktoken.Tiktoken, logDir, resultsDir string, mode TestMode, toolReasoningCheck bool) {
+	timestamp := time.Now().Format("20060102-150405")
+	logFileName := filepath.Clean(filepath.Join(logDir, fmt.Sprintf("%s-diagnostic-%s.log", resultFilePrefix(config.Name, config.Env), timestamp)))
+	logFile, err := os.Create(logFileName)
+	if err != nil {
+		log.Printf("Error creating diagnostic log file for %s: %v", config.Name, err)
+		return
+	}
+	defer func() {
+		if closeErr := logFile.Close(); closeErr != nil {
+			log.Printf("Warning: Failed to close log file: %v", closeErr)
+		}
+	}()
+	defer saveHeaders(logDir, config, timestamp)
+
+	providerLogger := log.New(io.MultiWriter(consoleOutput, logFile), "", log.LstdFlags)
+	providerLogger.Printf("=== DIAGNOSTIC MODE: %s (%s) - Mode: %s ===", config.Name, config.Model, mode)
+	emitProviderStarted(config, "diagnostic-"+string(mode))
+	if sessionThinkTime.enabled() {
+		providerLogger.Printf("Running %d workers for 90 seconds with think time %s between requests", diagnosticWorkers, sessionThinkTime)
+	} else {
+		providerLogger.Printf("Running %d workers for 90 seconds with requests every 15 seconds", diagnosticWorkers)
+	}
+	providerLogger.Printf("Timeout per request: 30 seconds")
+	baseline := runNetworkBaseline(config, providerLogger)
+	warmUp(config, tke, providerLogger)
+	scraper := startServerScrape(config, providerLogger)
+
+	// Mixed mode alternates between streaming and tool-calling requests
+	modes := []string{string(mode)}
+	if mode == ModeMixed {
+		modes = []string{string(ModeStreaming), string(ModeToolCalling)}
+	}
+
+	// With autoscaling, workers up to the maximum are started and the scaler
+	// parks those above its target
+	workers := diagnosticWorkers
+	var scaler *workerScaler
+	scalerDone := make(chan struct{})
+	if diagnosticAutoscale {
+		workers = diagnosticMaxWorkers
+		scaler = newWorkerScaler(diagnosticWorkers, diagnosticMaxWorkers, time.Now())
+		providerLogger.Printf("Autoscaling workers every %s, from %d up to %d", scaleInterval, diagnosticWorkers, diagnosticMaxWorkers)
+		go scaler.run(scalerDone, providerLogger)
+	}
+
+	// A think time, if set, paces each worker instead of the 15-second
+	// interval; every worker draws from its own generator
+	var pause func(worker int) time.Duration
+	if sessionThinkTime.enabled() {
+		rngs := make([]*rand.Rand, workers+1)
+		for id := 1; id <= workers; id++ {
+			rngs[id] = newWorkerRand(id)
+		}
+		pause = func(worker int) time.Duration { return sessionThinkTime.sample(rngs[worker]) }
+	}
+
+	outcome, err := benchmark.Load(context.Background(), benchmark.LoadConfig{
+		Workers: workers,
+		Modes:   modes,
+		Admit:   canStartRun,
+		Pause:   pause,
+		Park:    scaler.parked,
+		Stop:    shutdownCtx.Done(),
+		Logger: func(id int) *log.Logger {
+			worker := newRunContext(config, mode, 0)
+			worker.Worker = id
+			return runLogger(withRunContext(context.Background(), worker), providerLogger, config)
+		},
+		Send: func(ctx context.Context, _ providers.Provider, id benchmark.RunID) (benchmark.Sample, error) {
+			run := newRunContext(config, TestMode(id.Mode), id.Iteration)
+			run.Worker = id.Worker
+			ctx = withRunContext(ctx, run)
+			runLog := runLogger(ctx, providerLogger, config)
+			runLog.Println("Request starting")
+
+			var sample benchmark.Sample
+			var reqErr error
+			workerConfig := config.forWorker(id.Worker)
+			if run.Mode == ModeToolCalling {
+				sample, reqErr = asSample(singleToolCallRun(ctx, workerConfig, tke, providerLogger, toolReasoningCheck))
+			} else {
+				sample, reqErr = asSample(singleTestRun(ctx, workerConfig, tke, providerLogger, fmt.Sprintf("worker%d-req%d", id.Worker, id.Iteration)))
+			}
+
+			// Save response if flag is enabled
+			if saveResponses && reqErr == nil && sample.Response != "" {
+				responseFile := filepath.Clean(filepath.Join(logDir,
+					fmt.Sprintf("%s-worker%d-req%d-%s-response.txt", resultFilePrefix(config.Name, config.Env), id.Worker, id.Iteration, run.Mode)))
+				if err := os.WriteFile(responseFile, []byte(sample.Response), 0600); err != nil {
+					runLog.Printf("Warning: Failed to save response: %v", err)
+				}
+			}
+
+			if reqErr != nil {
+				runLog.Printf("Request failed: %v", reqErr)
+			} else {
+				runLog.Printf("Request success: E2E=%s TTFT=%s Throughput=%.2f tok/s Tokens=%d",
+					formatDuration(sample.E2E), formatDuration(sample.TTFT), sample.Throughput, sample.Tokens)
+			}
+			scaler.observe(reqErr)
+			return sample, reqErr
+		},
+	}, benchProvider(config))
+	close(scalerDone)
+	if err != nil {
+		providerLogger.Printf("Error: %v", err)
+	}
+
+	// Collect the per-request records and what the session's trackers saw
+	errorCounts := make(map[string]int)
+	var quality qualityTally
+	var timed []timedTTFT
+	var records []DiagnosticRecord
+
+	for _, o := range outcome.Outcomes {
+		run := newRunContext(config, TestMode(o.Mode), o.Iteration)
+		run.Worker = o.Worker
+		timed = append(timed, timedTTFT{offset: o.Start.Sub(outcome.Start), ttft: o.Sample.TTFT, failed: o.Err != nil})
+		record := DiagnosticRecord{
+			Worker: o.Worker, ReqNum: o.Iteration, Mode: run.Mode,
+			Start: o.Start, End: o.End, ErrorClass: errorClass(o.Err),
+			Instance: sessionInstances.state(run),
+		}
+		if o.Err != nil {
+			record.Error = o.Err.Error()
+			errorCounts[o.Err.Error()]++
+		} else {
+			record.E2ELatency, record.TTFT, record.Throughput, record.Tokens = o.Sample.E2E, o.Sample.TTFT, o.Sample.Throughput, o.Sample.Tokens
+			if run.Mode != ModeToolCalling {
+				quality.add(analyzeOutput(o.Sample.Response, currentPromptPack().scripts))
+			}
+		}
+		records = append(records, record)
+	}
+	serverMetrics := scraper.finish()
+	successCount, failureCount := outcome.Successful, outcome.Runs-outcome.Successful
+
+	// Print summary
+	providerLogger.Println("")
+	providerLogger.Println("========================================")
+	providerLogger.Println("   DIAGNOSTIC MODE SUMMARY")
+	providerLogger.Println("========================================")
+	providerLogger.Printf("Provider: %s", config.Name)
+	providerLogger.Printf("Model: %s", config.Model)
+	providerLogger.Printf("Mode: %s", mode)
+	providerLogger.Printf("Total Requests: %d", successCount+failureCount)
+	providerLogger.Printf("Successful: %d", successCount)
+	providerLogger.Printf("Failed: %d", failureCount)
+
+	if successCount > 0 {
+		providerLogger.Println("--------------------------------------")
+		providerLogger.Printf("Average E2E Latency: %s", formatDuration(outcome.E2E))
+		providerLogger.Printf("Average TTFT: %s", formatDuration(outcome.TTFT))
+		providerLogger.Printf("Average Throughput: %.2f tokens/s", outcome.Throughput)
+		providerLogger.Printf("Average Tokens: %d", outcome.Tokens)
+
+		// Display projected E2E if target tokens is set
+		if targetTokens > 0 {
+			projectedE2E := calculateProjectedE2E(outcome.TTFT, outcome.Throughput, targetTokens)
+			providerLogger.Printf("Projected E2E (%d tokens): %s", targetTokens, formatDuration(projectedE2E))
+		}
+	}
+
+	if len(errorCounts) > 0 {
+		providerLogger.Println("--------------------------------------")
+		providerLogger.Println("Errors encountered:")
+		for errMsg, count := range errorCounts {
+			providerLogger.Printf("  - %s (x%d)", errMsg, count)
+		}
+	}
+
+	providerLogger.Println("========================================")
+
+	// Create diagnostic summary
+	summary := DiagnosticSummary{
+		SessionID:       sessionID,
+		Provider:        config.Name,
+		Model:           config.Model,
+		Env:             config.Env,
+		IPVersion:       config.IPVersion,
+		HTTPVersion:     config.HTTPVersion,
+		ToolChoice:      config.ToolChoice,
+		Profile:         config.Profile,
+		Mode:            string(mode),
+		Timestamp:       time.Now(),
+		TotalRequests:   successCount + failureCount,
+		Successful:      successCount,
+		Failed:          failureCount,
+		KeyStats:        sessionKeys.stats(providerLabel(config.Name, config.Env)),
+		Deadlines:       sessionDeadlines.stats(providerLabel(config.Name, config.Env)),
+		Usage:           sessionBudget.usage(providerLabel(config.Name, config.Env)),
+		LiteLLM:         sessionLiteLLM.stats(providerLabel(config.Name, config.Env)),
+		ServerMetrics:   serverMetrics,
+		NetworkBaseline: baseline,
+		TTFTTimeline:    ttftTimeline(timed, diagnosticHeatmapBucket),
+		Scaling:         scaler.summary(records),
+		Records:         records,
+	}
+
+	if successCount > 0 {
+		summary.AvgE2ELatency = outcome.E2E
+		summary.AvgTTFT = outcome.TTFT
+		summary.AvgThroughput = outcome.Throughput
+		summary.AvgTokens = outcome.Tokens
+		summary.SecPer100Tokens = secondsPer100Tokens(summary.AvgE2ELatency, summary.AvgTokens)
+		summary.NormalizedE2E = normalizedE2E(summary.AvgTTFT, summary.AvgThroughput)
+		summary.ChunkStats = sessionStreams.chunks(providerLabel(config.Name, config.Env))
+		summary.ThroughputCurve = sessionStreams.curve(providerLabel(config.Name, config.Env))
+		summary.ITL = sessionStreams.itl(providerLabel(config.Name, config.Env))
+		summary.TTFTSpread, summary.E2ESpread, summary.ThroughputSpread = recordSpreads(records)
+		summary.ToolArgs = sessionStreams.toolArgs(providerLabel(config.Name, config.Env))
+		summary.Instances = recordInstanceStats(records)
+
+		// Calculate projected E2E if target tokens is set
+		if targetTokens > 0 {
+			summary.ProjectedE2E = calculateProjectedE2E(summary.AvgTTFT, summary.AvgThroughput, targetTokens)
+		}
+	}
+
+	if len(errorCounts) > 0 {
+		summary.Errors = errorCounts
+	}
+	summary.DegenerateResponses = quality.degenerate
+	summary.OutputFlags = quality.flags()
+
+	// Save the per-request records next to the summary
+	recordsFile := diagnosticRecordsFile(config, timestamp)
+	if err := writeDiagnosticRecords(filepath.Join(resultsDir, recordsFile), records); err != nil {
+		providerLogger.Printf("Warning: Failed to write diagnostic records: %v", err)
+	} else {
+		summary.RecordsFile = recordsFile
+	}
+
+	emitDiagnosticFinished(summary)
+
+	// Save diagnostic summary to JSON
+	summaryFile := filepath.Join(resultsDir, fmt.Sprintf("%s-diagnostic-summary-%s.json", resultFilePrefix(config.Name, config.Env), timestamp))
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		providerLogger.Printf("Warning: Failed to marshal diagnostic summary: %v", err)
+	} else {
+		if err := os.WriteFile(summaryFile, data, 0600); err != nil {
+			providerLogger.Printf("Warning: Failed to write diagnostic summary: %v", err)
+		} else {
+			providerLogger.Printf("Diagnostic summary saved: %s", summaryFile)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// perIPResolveTimeout bounds the DNS lookup of one provider hostname.
+const perIPResolveTimeout = 10 * time.Second
+
+// lookupIPAddrs resolves a hostname; a variable so tests can stub DNS.
+var lookupIPAddrs = net.DefaultResolver.LookupIPAddr
+
+// ipEnvTag labels results pinned to one address, e.g. "ip-104.18.2.3". IPv6
+// colons become dashes so the tag is safe in file names.
+func ipEnvTag(env, ip string) string {
+	tag := "ip-" + strings.ReplaceAll(ip, ":", "-")
+	if env == "" {
+		return tag
+	}
+	return env + "-" + tag
+}
+
+// expandPerIP replaces each provider with one copy per A/AAAA record of its
+// hostname, each pinned to that address and tagged with it. Providers whose
+// base URL already names an IP, or whose hostname fails to resolve, are kept
+// as they are.
+func expandPerIP(ctx context.Context, providers []ProviderConfig) []ProviderConfig {
+	expanded := make([]ProviderConfig, 0, len(providers))
+	for _, p := range providers {
+		u, err := url.Parse(p.BaseURL)
+		host := ""
+		if err == nil {
+			host = u.Hostname()
+		}
+		if host == "" || net.ParseIP(host) != nil {
+			expanded = append(expanded, p)
+			continue
+		}
+		lookupCtx, cancel := context.WithTimeout(ctx, perIPResolveTimeout)
+		addrs, err := lookupIPAddrs(lookupCtx, host)
+		cancel()
+		if err != nil || len(addrs) == 0 {
+			log.Printf("Warning: --per-ip could not resolve %s for %s, testing it by hostname: %v", host, p.Name, err)
+			expanded = append(expanded, p)
+			continue
+		}
+		seen := make(map[string]bool, len(addrs))
+		ips := make([]string, 0, len(addrs))
+		for _, addr := range addrs {
+			ip := addr.IP.String()
+			if !seen[ip] && matchesIPVersion(ip, p.IPVersion) {
+				seen[ip] = true
+				ips = append(ips, ip)
+			}
+		}
+		if len(ips) == 0 {
+			log.Printf("Warning: %s has no IPv%s address for %s, skipping it", host, p.IPVersion, p.Name)
+			continue
+		}
+		log.Printf("%s (%s) resolves to %d address(es): %s", p.Name, host, len(ips), strings.Join(ips, ", "))
+		for _, ip := range ips {
+			pinned := p
+			pinned.DialIP = ip
+			pinned.Env = ipEnvTag(p.Env, ip)
+			expanded = append(expanded, pinned)
+		}
+	}
+	return expanded
+}
+
+// dialTransports caches one transport per pinned address and address family so
+// connections are reused across requests.
+var dialTransports sync.Map
+
+// dialTransport returns a transport that connects to ip, when set, whatever
+// host a request names, over the given --ip-version family. The URL is
+// untouched, so the Host header and TLS SNI still carry the provider's
+// hostname. Proxies are bypassed, since going through one would defeat the
+// pinning or the family choice.
+func dialTransport(ip, ipVersion string) http.RoundTripper {
+	key := ip + "|" + ipVersion
+	if t, ok := dialTransports.Load(key); ok {
+		return t.(http.RoundTripper)
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	network := ipVersionNetwork(ipVersion)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+		if ip != "" {
+			_, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing dial address %q: %w", addr, err)
+			}
+			addr = net.JoinHostPort(ip, port)
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+	t, _ := dialTransports.LoadOrStore(key, transport)
+	return t.(http.RoundTripper)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+func TestExpandPerIP(t *testing.T) {
+	orig := lookupIPAddrs
+	t.Cleanup(func() { lookupIPAddrs = orig })
+	lookupIPAddrs = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		if host != "llm.test" {
+			return nil, &net.DNSError{Err: "no such host", Name: host}
+		}
+		return []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}, {IP: net.ParseIP("127.0.0.1")}, {IP: net.ParseIP("2001:db8::1")}}, nil
+	}
+
+	providers := expandPerIP(context.Background(), []ProviderConfig{
+		{Name: "a", BaseURL: "https://llm.test/v1", Env: "prod"},
+		{Name: "b", BaseURL: "http://127.0.0.1:8000/v1"},
+		{Name: "c", BaseURL: "https://unknown.test/v1"},
+	})
+	if len(providers) != 4 {
+		t.Fatalf("expected 2 pinned copies plus 2 untouched providers, got %+v", providers)
+	}
+	if providers[0].DialIP != "127.0.0.1" || providers[0].Env != "prod-ip-127.0.0.1" {
+		t.Errorf("unexpected first copy: %+v", providers[0])
+	}
+	if providers[1].DialIP != "2001:db8::1" || providers[1].Env != "prod-ip-2001-db8--1" {
+		t.Errorf("unexpected IPv6 copy: %+v", providers[1])
+	}
+	if providers[2].DialIP != "" || providers[3].DialIP != "" {
+		t.Errorf("IP and unresolvable hosts should be left alone: %+v", providers[2:])
+	}
+}
+
+func TestPinnedProviderKeepsHostname(t *testing.T) {
+	var mu sync.Mutex
+	var host string
+	handler := &mockSSEHandler{chunks: []string{"Hello", " there."}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		host = r.Host
+		mu.Unlock()
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The hostname does not resolve; only the pinned address makes this work
+	config := ProviderConfig{
+		Name:    "pinned",
+		BaseURL: "http://llm.invalid:" + u.Port(),
+		APIKey:  "k",
+		Model:   "m",
+		DialIP:  u.Hostname(),
+	}
+	if _, _, _, _, _, err := singleTestRun(context.Background(), config, testTokenizer(t), log.New(io.Discard, "", 0), "k"); err != nil {
+		t.Fatalf("pinned request failed: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if host != "llm.invalid:"+u.Port() {
+		t.Errorf("Host header = %q, want the provider hostname", host)
+	}
+}
+
+func TestPerIPCopiesKeepSeparateStreamStats(t *testing.T) {
+	server := mockSSEServer{chunks: []string{"Hello", " there."}}.start(t)
+	defer server.Close()
+	tke := testTokenizer(t)
+	logger := log.New(io.Discard, "", 0)
+
+	// Both copies dial the test server; the first streams twice as often
+	base := ProviderConfig{Name: "per-ip-stats", BaseURL: server.URL, APIKey: "k", Model: "m", DialIP: "127.0.0.1"}
+	first, second := base, base
+	first.Env, second.Env = ipEnvTag("", "127.0.0.1"), ipEnvTag("", "2001:db8::1")
+	for _, config := range []ProviderConfig{first, first, second} {
+		if _, _, _, _, _, err := singleTestRun(context.Background(), config, tke, logger, "k"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a := sessionStreams.chunks(providerLabel(first.Name, first.Env))
+	b := sessionStreams.chunks(providerLabel(second.Name, second.Env))
+	if a == nil || b == nil || a.Chunks != 2*b.Chunks {
+		t.Errorf("chunk stats per address = %+v and %+v, want the first to cover twice the streams", a, b)
+	}
+	if sessionStreams.chunks(base.Name) != nil {
+		t.Error("per-IP streams were recorded under the bare provider name")
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// untaggedEnv is how providers without an environment tag are grouped in reports.
+const untaggedEnv = "untagged"
+
+// providerLabel is the provider name shown in reports, with its environment tag
+// when one is set (e.g. "nim [staging]").
+func providerLabel(provider, env string) string {
+	if env == "" {
+		return provider
+	}
+	return fmt.Sprintf("%s [%s]", provider, env)
+}
+
+// envWithoutTag drops a variant tag, which may span several dash-separated
+// segments, from a tagged env, wherever variants applied later appended
+// theirs: "prod-tool-auto-ip-10.0.0.1" without "tool-auto" is
+// "prod-ip-10.0.0.1".
+func envWithoutTag(env, tag string) string {
+	segments, tagSegments := strings.Split(env, "-"), strings.Split(tag, "-")
+	for i := len(segments) - len(tagSegments); i >= 0; i-- {
+		if slices.Equal(segments[i:i+len(tagSegments)], tagSegments) {
+			return strings.Join(slices.Concat(segments[:i], segments[i+len(tagSegments):]), "-")
+		}
+	}
+	return env
+}
+
+// resultFilePrefix namespaces result file names by environment so the same
+// provider tagged differently in separate runs never overwrites another's files.
+func resultFilePrefix(provider, env string) string {
+	if env == "" {
+		return provider
+	}
+	return provider + "-" + env
+}
+
+// envMeasurement is one result contributing to an environment group.
+type envMeasurement struct {
+	env        string
+	provider   string
+	success    bool
+	ttft       time.Duration
+	e2e        time.Duration
+	throughput float64
+}
+
+// writeEnvironmentRows groups measurements by environment tag. Nothing is written
+// unless at least one provider is tagged.
+func writeEnvironmentRows(report *strings.Builder, measurements []envMeasurement) {
+	type group struct {
+		providers     map[string]bool
+		ok, failed    int
+		ttft, e2e     time.Duration
+		throughputSum float64
+	}
+	groups := make(map[string]*group)
+	tagged := false
+	for _, m := range measurements {
+		env := m.env
+		if env == "" {
+			env = untaggedEnv
+		} else {
+			tagged = true
+		}
+		g, ok := groups[env]
+		if !ok {
+			g = &group{providers: make(map[string]bool)}
+			groups[env] = g
+		}
+		g.providers[m.provider] = true
+		if !m.success {
+			g.failed++
+			continue
+		}
+		g.ok++
+		g.ttft += m.ttft
+		g.e2e += m.e2e
+		g.throughputSum += m.throughput
+	}
+	if !tagged {
+		return
+	}
+
+	envs := make([]string, 0, len(groups))
+	for env := range groups {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+
+	report.WriteString("## By Environment\n\n")
+	report.WriteString("| Environment | Providers | Successful | Failed | Avg TTFT | Avg Throughput | Avg E2E Latency |\n")
+	report.WriteString("|-------------|-----------|------------|--------|----------|----------------|-----------------|\n")
+	for _, env := range envs {
+		g := groups[env]
+		providers := make([]string, 0, len(g.providers))
+		for p := range g.providers {
+			providers = append(providers, p)
+		}
+		sort.Strings(providers)
+		ttft, throughput, e2e := NotAvailable, NotAvailable, NotAvailable
+		if g.ok > 0 {
+			ttft = formatDuration(g.ttft / time.Duration(g.ok))
+			throughput = fmt.Sprintf("%.2f tok/s", g.throughputSum/float64(g.ok))
+			e2e = formatDuration(g.e2e / time.Duration(g.ok))
+		}
+		fmt.Fprintf(report, "| %s | %s | %d | %d | %s | %s | %s |\n",
+			env, strings.Join(providers, ", "), g.ok, g.failed, ttft, throughput, e2e)
+	}
+	report.WriteString("\n")
+}
+
+// writeEnvironmentSection groups test results by their environment tag.
+func writeEnvironmentSection(report *strings.Builder, results []TestResult) {
+	measurements := make([]envMeasurement, 0, len(results))
+	for _, r := range results {
+		measurements = append(measurements, envMeasurement{r.Env, r.Provider, r.Success, r.TTFT, r.E2ELatency, r.Throughput})
+	}
+	writeEnvironmentRows(report, measurements)
+}
+
+// writeDiagnosticEnvironmentSection is the diagnostic-report counterpart of
+// writeEnvironmentSection; each provider counts once, by its averages.
+func writeDiagnosticEnvironmentSection(report *strings.Builder, results []DiagnosticSummary) {
+	measurements := make([]envMeasurement, 0, len(results))
+	for _, r := range results {
+		measurements = append(measurements, envMeasurement{r.Env, r.Provider, r.Successful > 0, r.AvgTTFT, r.AvgE2ELatency, r.AvgThroughput})
+	}
+	writeEnvironmentRows(report, measurements)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnvironmentSectionGroupsByTag(t *testing.T) {
+	var report strings.Builder
+	writeEnvironmentSection(&report, []TestResult{
+		{Provider: "nim", Success: true, TTFT: 200 * time.Millisecond, E2ELatency: time.Second, Throughput: 100},
+		{Provider: "generic", Success: true, TTFT: 400 * time.Millisecond, E2ELatency: 2 * time.Second, Throughput: 50},
+	})
+	if report.Len() != 0 {
+		t.Fatalf("expected no section without tags, got:\n%s", report.String())
+	}
+
+	writeEnvironmentSection(&report, []TestResult{
+		{Provider: "nim", Env: "prod", Success: true, TTFT: 200 * time.Millisecond, E2ELatency: time.Second, Throughput: 100},
+		{Provider: "novita", Env: "prod", Success: true, TTFT: 400 * time.Millisecond, E2ELatency: 3 * time.Second, Throughput: 50},
+		{Provider: "generic", Env: "self-hosted-a100", Error: "timeout exceeded"},
+		{Provider: "nebius"},
+	})
+	out := report.String()
+	for _, want := range []string{
+		"| prod | nim, novita | 2 | 0 | 0.300s | 75.00 tok/s | 2.000s |",
+		"| self-hosted-a100 | generic | 0 | 1 | N/A | N/A | N/A |",
+		"| untagged | nebius | 0 | 1 |",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+
+	if got := providerLabel("nim", "staging"); got != "nim [staging]" {
+		t.Errorf("providerLabel = %q", got)
+	}
+	if got := resultFilePrefix("nim", ""); got != "nim" {
+		t.Errorf("resultFilePrefix = %q", got)
+	}
+}
+
+func TestEnvWithoutTag(t *testing.T) {
+	for _, tc := range []struct{ env, tag, want string }{
+		{"ipv6", "ipv6", ""},
+		{"prod-ipv6-h3", "ipv6", "prod-h3"},
+		{"tool-auto-ip-10.0.0.1", "tool-auto", "ip-10.0.0.1"},
+		{"prod-profile-latency-ip-2001-db8--1", "profile-latency", "prod-ip-2001-db8--1"},
+		{"prod-tool-auto", "tool-required", "prod-tool-auto"},
+	} {
+		if got := envWithoutTag(tc.env, tc.tag); got != tc.want {
+			t.Errorf("envWithoutTag(%q, %q) = %q, want %q", tc.env, tc.tag, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// consoleOutput is where provider loggers echo their log lines. It is stdout
+// unless --progress-json claims stdout for events.
+var consoleOutput io.Writer = os.Stdout
+
+// Progress event types written with --progress-json.
+const (
+	eventSessionStarted   = "session_started"
+	eventProviderStarted  = "provider_started"
+	eventRunFinished      = "run_finished"
+	eventProviderFinished = "provider_finished"
+	eventSessionDone      = "session_done"
+)
+
+// ProgressEvent is one line of --progress-json output. Durations are in
+// milliseconds; fields that do not apply to an event are omitted.
+type ProgressEvent struct {
+	Event      string    `json:"event"`
+	Time       time.Time `json:"time"`
+	Session    string    `json:"session,omitempty"`
+	Provider   string    `json:"provider,omitempty"`
+	Model      string    `json:"model,omitempty"`
+	Mode       string    `json:"mode,omitempty"`
+	Worker     int       `json:"worker,omitempty"`
+	Iteration  int       `json:"iteration,omitempty"`
+	Success    *bool     `json:"success,omitempty"`
+	TTFTMs     float64   `json:"ttftMs,omitempty"`
+	E2EMs      float64   `json:"e2eMs,omitempty"`
+	Throughput float64   `json:"throughputTokensPerSec,omitempty"`
+	Tokens     int       `json:"completionTokens,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	// Dir is the session folder of session events.
+	Dir string `json:"dir,omitempty"`
+}
+
+// progressEmitter writes progress events as NDJSON.
+type progressEmitter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// sessionEvents receives the session's progress events; nil without
+// --progress-json.
+var sessionEvents *progressEmitter
+
+// enableProgressJSON sends progress events to w and moves the provider log
+// echo to stderr, so w carries nothing but events.
+func enableProgressJSON(w io.Writer) {
+	sessionEvents = &progressEmitter{enc: json.NewEncoder(w)}
+	consoleOutput = os.Stderr
+}
+
+// emitProgress writes e, stamped with the time and session, if events are on.
+func emitProgress(e ProgressEvent) {
+	if sessionEvents == nil {
+		return
+	}
+	e.Time = time.Now()
+	e.Session = sessionID
+	sessionEvents.mu.Lock()
+	defer sessionEvents.mu.Unlock()
+	_ = sessionEvents.enc.Encode(e)
+}
+
+// emitProviderStarted announces that config's benchmark in mode began.
+func emitProviderStarted(config ProviderConfig, mode string) {
+	emitProgress(ProgressEvent{Event: eventProviderStarted, Provider: providerLabel(config.Name, config.Env), Model: config.Model, Mode: mode})
+}
+
+// emitRunFinished reports one finished request of the run carried by ctx.
+func emitRunFinished(ctx context.Context, config ProviderConfig, e2e, ttft time.Duration, throughput float64, tokens int, err error) {
+	if sessionEvents == nil {
+		return
+	}
+	run := runContextFrom(ctx, config)
+	success := err == nil
+	e := ProgressEvent{Event: eventRunFinished, Provider: run.Provider, Model: config.Model, Mode: string(run.Mode),
+		Worker: run.Worker, Iteration: run.Iteration, Success: &success}
+	if err != nil {
+		e.Error = err.Error()
+	} else {
+		e.TTFTMs, e.E2EMs, e.Throughput, e.Tokens = durationMs(ttft), durationMs(e2e), throughput, tokens
+	}
+	emitProgress(e)
+}
+
+// emitProviderFinished reports the averaged outcome of one provider.
+func emitProviderFinished(r TestResult) {
+	success := r.Success
+	e := ProgressEvent{Event: eventProviderFinished, Provider: providerLabel(r.Provider, r.Env), Model: r.Model, Mode: r.Mode,
+		Success: &success, Error: r.Error}
+	if r.Success {
+		e.TTFTMs, e.E2EMs, e.Throughput, e.Tokens = durationMs(r.TTFT), durationMs(r.E2ELatency), r.Throughput, r.CompletionTokens
+	}
+	emitProgress(e)
+}
+
+// emitDiagnosticFinished is emitProviderFinished for a diagnostic summary.
+func emitDiagnosticFinished(s DiagnosticSummary) {
+	success := s.Successful > 0
+	e := ProgressEvent{Event: eventProviderFinished, Provider: providerLabel(s.Provider, s.Env), Model: s.Model, Mode: "diagnostic-" + s.Mode,
+		Success: &success}
+	if success {
+		e.TTFTMs, e.E2EMs, e.Throughput, e.Tokens = durationMs(s.AvgTTFT), durationMs(s.AvgE2ELatency), s.AvgThroughput, s.AvgTokens
+	}
+	emitProgress(e)
+}
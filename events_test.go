@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressEvents(t *testing.T) {
+	t.Cleanup(func() { sessionEvents, consoleOutput = nil, os.Stdout })
+	emitProgress(ProgressEvent{Event: eventSessionStarted})
+
+	var out bytes.Buffer
+	enableProgressJSON(&out)
+	if consoleOutput != os.Stderr {
+		t.Fatalf("expected provider logs to move to stderr")
+	}
+	config := ProviderConfig{Name: "nim", Env: "prod", Model: "m"}
+	ctx := withRunContext(context.Background(), newRunContext(config, ModeStreaming, 2))
+	emitProviderStarted(config, string(ModeStreaming))
+	emitRunFinished(ctx, config, 2*time.Second, 250*time.Millisecond, 80, 160, nil)
+	emitRunFinished(ctx, config, 0, 0, 0, 0, errors.New("429"))
+	emitProviderFinished(TestResult{Provider: "nim", Env: "prod", Model: "m", Mode: "streaming", Success: true, TTFT: time.Second})
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected one line per event after enabling, got %q", out.String())
+	}
+	var events []ProgressEvent
+	for _, line := range lines {
+		var e ProgressEvent
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("line %q is not JSON: %v", line, err)
+		}
+		events = append(events, e)
+	}
+	if events[0].Event != eventProviderStarted || events[0].Provider != "nim [prod]" || events[0].Mode != "streaming" {
+		t.Fatalf("unexpected start %+v", events[0])
+	}
+	ok := events[1]
+	if ok.Event != eventRunFinished || ok.Iteration != 2 || ok.Success == nil || !*ok.Success || ok.TTFTMs != 250 || ok.Tokens != 160 {
+		t.Fatalf("unexpected run %+v", ok)
+	}
+	failed := events[2]
+	if failed.Success == nil || *failed.Success || failed.Error != "429" || failed.E2EMs != 0 {
+		t.Fatalf("unexpected failed run %+v", failed)
+	}
+	if events[3].Event != eventProviderFinished || events[3].TTFTMs != 1000 {
+		t.Fatalf("unexpected finish %+v", events[3])
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// exportRow is one run of a standard benchmark, flattened for the Parquet
+// export.
+type exportRow struct {
+	session    string
+	timestamp  time.Time
+	provider   string
+	model      string
+	env        string
+	mode       string
+	iteration  int
+	success    bool
+	ttft       time.Duration
+	e2e        time.Duration
+	throughput float64
+	tokens     int
+	err        string
+}
+
+// exportRows flattens results into one row per run. Results saved before runs
+// were kept, or by modes that do not keep them, give a single row of their
+// averages with iteration 0.
+func exportRows(session string, results []TestResult) []exportRow {
+	var rows []exportRow
+	for _, r := range results {
+		id := r.SessionID
+		if id == "" {
+			id = session
+		}
+		base := exportRow{session: id, timestamp: r.Timestamp, provider: r.Provider, model: r.Model, env: r.Env}
+		if len(r.Runs) == 0 {
+			row := base
+			row.mode = r.Mode
+			row.success = r.Success
+			row.ttft, row.e2e, row.throughput, row.tokens, row.err = r.TTFT, r.E2ELatency, r.Throughput, r.CompletionTokens, r.Error
+			rows = append(rows, row)
+			continue
+		}
+		for _, run := range r.Runs {
+			row := base
+			row.mode = run.Mode
+			row.iteration = run.Iteration
+			row.success = run.Success
+			row.ttft, row.e2e, row.throughput, row.tokens, row.err = run.TTFT, run.E2E, run.Throughput, run.Tokens, run.Error
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// exportColumns lays rows out as Parquet columns. Metrics a run did not
+// produce, such as the TTFT of a failed run, are null rather than zero.
+func exportColumns(rows []exportRow) []parquetColumn {
+	column := func(name string, physical, converted int32, optional bool, value func(exportRow) any) parquetColumn {
+		values := make([]any, len(rows))
+		for i, r := range rows {
+			values[i] = value(r)
+		}
+		return parquetColumn{name: name, physical: physical, converted: converted, optional: optional, values: values}
+	}
+	nullIfEmpty := func(s string) any {
+		if s == "" {
+			return nil
+		}
+		return s
+	}
+	millis := func(d time.Duration) any {
+		if d <= 0 {
+			return nil
+		}
+		return float64(d) / float64(time.Millisecond)
+	}
+
+	return []parquetColumn{
+		column("session_id", parquetByteArray, parquetUTF8, false, func(r exportRow) any { return r.session }),
+		column("timestamp", parquetInt64, parquetTimestampMillis, false, func(r exportRow) any { return r.timestamp.UnixMilli() }),
+		column("provider", parquetByteArray, parquetUTF8, false, func(r exportRow) any { return r.provider }),
+		column("model", parquetByteArray, parquetUTF8, false, func(r exportRow) any { return r.model }),
+		column("env", parquetByteArray, parquetUTF8, true, func(r exportRow) any { return nullIfEmpty(r.env) }),
+		column("mode", parquetByteArray, parquetUTF8, false, func(r exportRow) any { return r.mode }),
+		column("iteration", parquetInt32, parquetNoConversion, false, func(r exportRow) any { return int32(r.iteration) }),
+		column("success", parquetBoolean, parquetNoConversion, false, func(r exportRow) any { return r.success }),
+		column("ttft_ms", parquetDouble, parquetNoConversion, true, func(r exportRow) any { return millis(r.ttft) }),
+		column("e2e_ms", parquetDouble, parquetNoConversion, true, func(r exportRow) any { return millis(r.e2e) }),
+		column("throughput_tps", parquetDouble, parquetNoConversion, true, func(r exportRow) any {
+			if r.throughput <= 0 {
+				return nil
+			}
+			return r.throughput
+		}),
+		column("completion_tokens", parquetInt32, parquetNoConversion, true, func(r exportRow) any {
+			if r.tokens <= 0 {
+				return nil
+			}
+			return int32(r.tokens)
+		}),
+		column("error", parquetByteArray, parquetUTF8, true, func(r exportRow) any { return nullIfEmpty(r.err) }),
+	}
+}
+
+// writeExportParquet writes rows to a Parquet file.
+func writeExportParquet(filename string, rows []exportRow) error {
+	f, err := os.OpenFile(filepath.Clean(filename), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", filename, err)
+	}
+	if err := writeParquet(f, exportColumns(rows), "llm-api-speed "+version); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("error writing %s: %w", filename, err)
+	}
+	return f.Close()
+}
+
+// writeExportCSVFile writes rows to a CSV file in format.
+func writeExportCSVFile(filename string, rows []exportRow, format csvFormat) error {
+	f, err := os.OpenFile(filepath.Clean(filename), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", filename, err)
+	}
+	if err := writeExportCSV(f, exportColumns(rows), format); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("error writing %s: %w", filename, err)
+	}
+	return f.Close()
+}
+
+// runExport implements the "export" subcommand: it writes the runs of one or
+// more saved sessions to a single Parquet file for DuckDB, Spark or pandas, with
+// --csv to a CSV file for spreadsheets, or with --analysis to a notebook-ready
+// bundle.
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	out := fs.String("out", "", "Output file, or directory with --analysis (default: in the session, or in <results-dir> for several)")
+	fs.StringVar(&resultsRoot, "results-dir", resultsRoot, "Folder bare session names are looked up in")
+	analysis := fs.Bool("analysis", false, "Write a folder with "+analysisCSVFileName+", "+analysisParquetFileName+" and a Jupyter notebook of standard plots")
+	asCSV := fs.Bool("csv", false, "Write CSV instead of Parquet")
+	csvFormatName := fs.String("csv-format", "standard",
+		"With --csv: standard (comma, 42.5, RFC 3339 times), excel (comma, 42.5, plain times) or excel-eu (semicolon, 42,5, plain times)")
+	csvDelimiter := fs.String("csv-delimiter", "", `With --csv: field delimiter overriding the format's, e.g. ";" or "\t"`)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: llm-api-speed export [--analysis | --csv [--csv-format name]] [--out path] <session>...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		log.Fatalf("Error parsing flags: %v", err)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *asCSV && *analysis {
+		log.Fatal("Error: --csv and --analysis cannot be combined; the analysis bundle already includes " + analysisCSVFileName)
+	}
+	format, err := parseCSVFormat(*csvFormatName, *csvDelimiter)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if !*asCSV && (*csvFormatName != "standard" || *csvDelimiter != "") {
+		log.Fatal("Error: --csv-format and --csv-delimiter require --csv")
+	}
+
+	var rows []exportRow
+	var sessionDirs, names []string
+	for _, session := range fs.Args() {
+		dir := resolveSessionDir(session)
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			log.Fatalf("Error: session %s not found", session)
+		}
+		results, _, err := loadSessionResults(dir)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		sessionDirs = append(sessionDirs, dir)
+		names = append(names, filepath.Base(dir))
+		rows = append(rows, exportRows(filepath.Base(dir), results)...)
+	}
+	if len(rows) == 0 {
+		log.Fatal("Error: no run results found; diagnostic sessions are not exported")
+	}
+
+	timestamp := time.Now().Format("20060102-150405")
+	if *analysis {
+		dir := *out
+		switch {
+		case dir != "":
+		case len(sessionDirs) == 1:
+			dir = filepath.Join(sessionDirs[0], "analysis")
+		default:
+			dir = filepath.Join(resultsRoot, "analysis-"+timestamp)
+		}
+		if err := writeAnalysisBundle(dir, rows, names); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		log.Printf("Exported %d run(s) from %d session(s) to %s/; open %s in Jupyter", len(rows), len(sessionDirs), dir, analysisNotebookFileName)
+		return
+	}
+
+	ext := ".parquet"
+	if *asCSV {
+		ext = ".csv"
+	}
+	filename := *out
+	switch {
+	case filename != "":
+	case len(sessionDirs) == 1:
+		filename = filepath.Join(sessionDirs[0], "runs"+ext)
+	default:
+		filename = filepath.Join(resultsRoot, "runs-"+timestamp+ext)
+	}
+	if *asCSV {
+		err = writeExportCSVFile(filename, rows, format)
+	} else {
+		err = writeExportParquet(filename, rows)
+	}
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	log.Printf("Exported %d run(s) from %d session(s) to %s", len(rows), len(sessionDirs), filename)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportRowsFlattensRuns(t *testing.T) {
+	results := []TestResult{
+		{
+			Provider: "nim", Model: "m", Mode: string(ModeStreaming), Timestamp: time.Unix(60, 0),
+			Runs: []RunSample{
+				{Iteration: 1, Mode: string(ModeStreaming), Success: true, TTFT: 200 * time.Millisecond, E2E: time.Second, Throughput: 50, Tokens: 40},
+				{Iteration: 2, Mode: string(ModeStreaming), Error: "timeout"},
+			},
+		},
+		{SessionID: "tagged", Provider: "novita", Model: "m", Mode: string(ModeToolCalling), Success: true, TTFT: time.Second},
+	}
+
+	rows := exportRows("session-1", results)
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(rows))
+	}
+	if rows[0].session != "session-1" || rows[0].iteration != 1 || rows[0].e2e != time.Second || rows[0].tokens != 40 {
+		t.Errorf("unexpected first run row: %+v", rows[0])
+	}
+	if rows[1].success || rows[1].err != "timeout" {
+		t.Errorf("unexpected failed run row: %+v", rows[1])
+	}
+	if rows[2].session != "tagged" || rows[2].iteration != 0 || rows[2].ttft != time.Second {
+		t.Errorf("results without runs should export their averages: %+v", rows[2])
+	}
+
+	columns := exportColumns(rows)
+	for _, col := range columns {
+		if len(col.values) != 3 {
+			t.Fatalf("column %s has %d values", col.name, len(col.values))
+		}
+		if col.name == "ttft_ms" {
+			if col.values[0] != 200.0 || col.values[1] != nil {
+				t.Errorf("ttft_ms should be 200 then null, got %v", col.values)
+			}
+		}
+	}
+}
+
+func TestWriteParquetLayout(t *testing.T) {
+	columns := []parquetColumn{
+		{name: "id", physical: parquetInt32, converted: parquetNoConversion, values: []any{int32(1), int32(2)}},
+		{name: "name", physical: parquetByteArray, converted: parquetUTF8, optional: true, values: []any{"a", nil}},
+	}
+	var buf bytes.Buffer
+	if err := writeParquet(&buf, columns, "test"); err != nil {
+		t.Fatalf("writeParquet failed: %v", err)
+	}
+	data := buf.Bytes()
+	if string(data[:4]) != parquetMagic || string(data[len(data)-4:]) != parquetMagic {
+		t.Fatalf("file is not framed by %s", parquetMagic)
+	}
+	footer := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	if footer <= 0 || footer > len(data)-12 {
+		t.Fatalf("implausible footer length %d for a %d-byte file", footer, len(data))
+	}
+	if !bytes.Contains(data[len(data)-8-footer:], []byte("name")) {
+		t.Error("footer should hold the schema")
+	}
+
+	if err := writeParquet(&buf, []parquetColumn{{name: "x", physical: parquetInt32, values: []any{nil}}}, "test"); err == nil {
+		t.Error("expected an error for a null in a required column")
+	}
+	if err := writeParquet(&buf, []parquetColumn{{name: "x", physical: parquetInt32, values: []any{"1"}}}, "test"); err == nil {
+		t.Error("expected an error for a value of the wrong type")
+	}
+}
+
+func TestEncodeDefinitionLevels(t *testing.T) {
+	got := encodeDefinitionLevels([]bool{true, true, true, false, true})
+	// RLE runs: (3<<1, 1), (1<<1, 0), (1<<1, 1)
+	want := []byte{6, 1, 2, 0, 2, 1}
+	if !bytes.Equal(got, want) {
+		t.Errorf("encodeDefinitionLevels = %v, want %v", got, want)
+	}
+}
+
+func TestWriteAnalysisBundle(t *testing.T) {
+	dir := t.TempDir()
+	rows := []exportRow{
+		{session: "s1", timestamp: time.UnixMilli(1500).UTC(), provider: "nim", model: "m", mode: "streaming", iteration: 1, success: true, ttft: 250 * time.Millisecond, throughput: 42.5},
+		{session: "s1", timestamp: time.UnixMilli(1500).UTC(), provider: "nim", model: "m", env: "prod", mode: "streaming", iteration: 2, err: "boom, again"},
+	}
+	if err := writeAnalysisBundle(dir, rows, []string{"s1"}); err != nil {
+		t.Fatalf("writeAnalysisBundle failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, analysisCSVFileName))
+	if err != nil {
+		t.Fatalf("reading CSV failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "session_id,timestamp,provider") {
+		t.Fatalf("unexpected CSV:\n%s", data)
+	}
+	if want := "s1,1970-01-01T00:00:01.500Z,nim,m,,streaming,1,true,250,,42.5,,"; lines[1] != want {
+		t.Errorf("CSV row = %q, want %q", lines[1], want)
+	}
+	if !strings.HasSuffix(lines[2], `"boom, again"`) {
+		t.Errorf("free text should be quoted: %q", lines[2])
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, analysisParquetFileName)); err != nil {
+		t.Errorf("Parquet file missing: %v", err)
+	}
+
+	var notebook struct {
+		Cells    []map[string]any `json:"cells"`
+		NBFormat int              `json:"nbformat"`
+	}
+	data, err = os.ReadFile(filepath.Join(dir, analysisNotebookFileName))
+	if err != nil {
+		t.Fatalf("reading notebook failed: %v", err)
+	}
+	if err := json.Unmarshal(data, &notebook); err != nil {
+		t.Fatalf("notebook is not valid JSON: %v", err)
+	}
+	if notebook.NBFormat != 4 || len(notebook.Cells) == 0 {
+		t.Fatalf("unexpected notebook: %s", data)
+	}
+	code := 0
+	for _, cell := range notebook.Cells {
+		if cell["cell_type"] != "code" {
+			continue
+		}
+		code++
+		if _, ok := cell["execution_count"]; !ok {
+			t.Error("code cells need an execution_count, even if null")
+		}
+	}
+	if code == 0 || !strings.Contains(string(data), analysisCSVFileName) {
+		t.Error("notebook should load the bundle's CSV in code cells")
+	}
+}
+
+func TestWriteExportCSVFormats(t *testing.T) {
+	rows := []exportRow{
+		{session: "s1", timestamp: time.UnixMilli(1500).UTC(), provider: "nim", model: "m", mode: "streaming", iteration: 1, success: true, ttft: 250500 * time.Microsecond, throughput: 42.5},
+	}
+	format, err := parseCSVFormat("excel-eu", "")
+	if err != nil {
+		t.Fatalf("parseCSVFormat failed: %v", err)
+	}
+	filename := filepath.Join(t.TempDir(), "runs.csv")
+	if err := writeExportCSVFile(filename, rows, format); err != nil {
+		t.Fatalf("writeExportCSVFile failed: %v", err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("reading CSV failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "session_id;timestamp;provider") {
+		t.Fatalf("unexpected CSV:\n%s", data)
+	}
+	if want := "s1;1970-01-01 00:00:01;nim;m;;streaming;1;true;250,5;;42,5;;"; lines[1] != want {
+		t.Errorf("CSV row = %q, want %q", lines[1], want)
+	}
+
+	if f, err := parseCSVFormat("excel", `\t`); err != nil || f.delimiter != '\t' || f.decimalComma {
+		t.Errorf("expected excel with a tab delimiter, got %+v, %v", f, err)
+	}
+	for _, bad := range [][2]string{{"excel-eu", ","}, {"excel", ";;"}, {"excel", `"`}, {"german", ""}} {
+		if _, err := parseCSVFormat(bad[0], bad[1]); err == nil {
+			t.Errorf("expected format %q with delimiter %q to be rejected", bad[0], bad[1])
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lamim/llm-api-speed/pkg/providers"
+	"github.com/pkoukk/tiktoken-go"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const failoverModeLabel = "failover"
+
+// streamOutcome is the result of one streaming request that may be cancelled
+// part-way through because another provider won a race.
+type streamOutcome struct {
+	provider   string
+	start      time.Time
+	headers    time.Time
+	firstToken time.Time
+	end        time.Time
+	tokens     int
+	err        error
+}
+
+// raceStream streams a standard prompt from one provider, signalling the
+// provider's name on firstToken as soon as content arrives. It keeps counting
+// tokens until the stream ends or ctx is cancelled, so a cancelled loser still
+// reports how many tokens it had already generated.
+func raceStream(ctx context.Context, config ProviderConfig, tke *tiktoken.Tiktoken, requestKey string, firstToken chan<- string) streamOutcome {
+	req := openai.ChatCompletionRequest{
+		Model: config.Model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleUser, Content: selectStreamingPrompt(config.Name, requestKey)},
+		},
+		MaxTokens: requestMaxTokens(defaultMaxTokens),
+		Stream:    true,
+	}
+	req = bustCache(config.Quirks.apply(req))
+
+	outcome := streamOutcome{provider: config.Name, start: time.Now()}
+	var content strings.Builder
+
+	stream, err := providers.OpenStream(ctx, benchProvider(config), req)
+	if err != nil {
+		outcome.err = fmt.Errorf("error creating stream: %w", err)
+		outcome.end = time.Now()
+		return outcome
+	}
+	outcome.headers = time.Now()
+	defer func() {
+		_ = stream.Close()
+	}()
+
+	for {
+		delta, recvErr := stream.Recv()
+		if errors.Is(recvErr, io.EOF) {
+			break
+		}
+		if recvErr != nil {
+			outcome.err = fmt.Errorf("stream error: %w", recvErr)
+			break
+		}
+		if delta.Content == "" && delta.Reasoning == "" {
+			continue
+		}
+		if outcome.firstToken.IsZero() {
+			outcome.firstToken = time.Now()
+			if firstToken != nil {
+				select {
+				case firstToken <- config.Name:
+				default:
+				}
+			}
+		}
+		content.WriteString(delta.Content)
+		content.WriteString(delta.Reasoning)
+	}
+
+	outcome.tokens = len(tke.Encode(content.String(), nil, nil))
+	sessionBudget.record(config, countPromptTokens(tke, req.Messages), outcome.tokens)
+	if outcome.err == nil && outcome.firstToken.IsZero() {
+		outcome.err = fmt.Errorf("no content received from API")
+	}
+	outcome.end = time.Now()
+	return outcome
+}
+
+// FailoverIteration records one simulated failover-client request.
+type FailoverIteration struct {
+	Iteration        int           `json:"iteration"`
+	Triggered        bool          `json:"triggered"`
+	Winner           string        `json:"winner,omitempty"`
+	EffectiveLatency time.Duration `json:"effectiveLatency"`
+	EffectiveTTFT    time.Duration `json:"effectiveTtft"`
+	WastedTokens     int           `json:"wastedTokens"`
+	Error            string        `json:"error,omitempty"`
+}
+
+// FailoverSummary aggregates a failover simulation.
+type FailoverSummary struct {
+	Primary             string              `json:"primary"`
+	Secondary           string              `json:"secondary"`
+	Threshold           time.Duration       `json:"threshold"`
+	Iterations          []FailoverIteration `json:"iterations"`
+	Triggered           int                 `json:"triggered"`
+	Failed              int                 `json:"failed"`
+	Wins                map[string]int      `json:"wins"`
+	AvgEffectiveLatency time.Duration       `json:"avgEffectiveLatency"`
+	AvgEffectiveTTFT    time.Duration       `json:"avgEffectiveTtft"`
+	TotalWastedTokens   int                 `json:"totalWastedTokens"`
+}
+
+// simulateFailover emulates one request from a failover client: the primary is
+// tried first, and if it has not produced a token within threshold (or fails)
+// the secondary is started and the two race; the first to produce a token wins
+// and the other is cancelled.
+func simulateFailover(primary, secondary ProviderConfig, tke *tiktoken.Tiktoken, threshold time.Duration, iteration int) FailoverIteration {
+	result := FailoverIteration{Iteration: iteration}
+	requestKey := fmt.Sprintf("failover%d", iteration)
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	primaryCtx, cancelPrimary := context.WithCancel(ctx)
+	defer cancelPrimary()
+	secondaryCtx, cancelSecondary := context.WithCancel(ctx)
+	defer cancelSecondary()
+
+	firstToken := make(chan string, 2)
+	outcomes := make(chan streamOutcome, 2)
+	go func() { outcomes <- raceStream(primaryCtx, primary, tke, requestKey, firstToken) }()
+
+	timer := time.NewTimer(threshold)
+	defer timer.Stop()
+
+	var primaryDone *streamOutcome
+	select {
+	case <-firstToken:
+		// Primary answered in time: no failover
+		o := <-outcomes
+		return finishFailoverIteration(result, start, o, nil)
+	case o := <-outcomes:
+		// Primary failed before producing a token
+		primaryDone = &o
+	case <-timer.C:
+	}
+
+	result.Triggered = true
+	go func() { outcomes <- raceStream(secondaryCtx, secondary, tke, requestKey, firstToken) }()
+
+	if primaryDone != nil {
+		// Only the secondary is still running
+		return finishFailoverIteration(result, start, <-outcomes, primaryDone)
+	}
+
+	var winner string
+	select {
+	case winner = <-firstToken:
+	case failed := <-outcomes:
+		// One side failed without producing a token; the other serves the request
+		return finishFailoverIteration(result, start, <-outcomes, &failed)
+	}
+
+	if winner == primary.Name {
+		cancelSecondary()
+	} else {
+		cancelPrimary()
+	}
+	first, second := <-outcomes, <-outcomes
+	if first.provider != winner {
+		first, second = second, first
+	}
+	return finishFailoverIteration(result, start, first, &second)
+}
+
+// finishFailoverIteration fills in latency and waste from the winning and losing streams.
+func finishFailoverIteration(result FailoverIteration, start time.Time, winner streamOutcome, loser *streamOutcome) FailoverIteration {
+	if loser != nil {
+		result.WastedTokens = loser.tokens
+	}
+	if winner.err != nil {
+		result.Error = winner.err.Error()
+		return result
+	}
+	result.Winner = winner.provider
+	result.EffectiveLatency = winner.end.Sub(start)
+	result.EffectiveTTFT = winner.firstToken.Sub(start)
+	return result
+}
+
+// runFailoverSimulation runs the failover simulation between the first two
+// providers and writes a JSON summary and FAILOVER-REPORT.md.
+func runFailoverSimulation(providers []ProviderConfig, tke *tiktoken.Tiktoken, threshold time.Duration, runs int, resultsDir, sessionTimestamp string) error {
+	if len(providers) < 2 {
+		return fmt.Errorf("failover simulation needs two providers, got %d", len(providers))
+	}
+	primary, secondary := providers[0], providers[1]
+	log.Printf("=== FAILOVER SIMULATION: primary=%s secondary=%s threshold=%s runs=%d ===",
+		primary.Name, secondary.Name, threshold, runs)
+
+	summary := FailoverSummary{
+		Primary:   primary.Name,
+		Secondary: secondary.Name,
+		Threshold: threshold,
+		Wins:      make(map[string]int),
+	}
+	var latencySum, ttftSum time.Duration
+	for i := 1; i <= runs; i++ {
+		if err := canStartRun(); err != nil {
+			log.Printf("Failover run %d skipped: %v", i, err)
+			break
+		}
+		it := simulateFailover(primary, secondary, tke, threshold, i)
+		summary.Iterations = append(summary.Iterations, it)
+		summary.TotalWastedTokens += it.WastedTokens
+		if it.Triggered {
+			summary.Triggered++
+		}
+		if it.Error != "" {
+			summary.Failed++
+			log.Printf("Failover run %d failed: %s", i, it.Error)
+			continue
+		}
+		summary.Wins[it.Winner]++
+		latencySum += it.EffectiveLatency
+		ttftSum += it.EffectiveTTFT
+		log.Printf("Failover run %d: winner=%s triggered=%t effective TTFT=%s E2E=%s wasted=%d tokens",
+			i, it.Winner, it.Triggered, formatDuration(it.EffectiveTTFT), formatDuration(it.EffectiveLatency), it.WastedTokens)
+	}
+	if ok := len(summary.Iterations) - summary.Failed; ok > 0 {
+		summary.AvgEffectiveLatency = latencySum / time.Duration(ok)
+		summary.AvgEffectiveTTFT = ttftSum / time.Duration(ok)
+	}
+
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling failover summary: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(resultsDir, "failover-summary.json"), data, 0600); err != nil {
+		return fmt.Errorf("error writing failover summary: %w", err)
+	}
+	return generateFailoverReport(resultsDir, summary, sessionTimestamp)
+}
+
+// generateFailoverReport writes FAILOVER-REPORT.md for a failover simulation.
+func generateFailoverReport(resultsDir string, summary FailoverSummary, sessionTimestamp string) error {
+	filename := filepath.Join(resultsDir, "FAILOVER-REPORT.md")
+	total := len(summary.Iterations)
+
+	var report strings.Builder
+	report.WriteString("# LLM API Failover Simulation Results\n\n")
+	fmt.Fprintf(&report, "**Test Session:** %s\n\n", sessionTimestamp)
+	fmt.Fprintf(&report, "**Primary:** %s\n", summary.Primary)
+	fmt.Fprintf(&report, "**Secondary:** %s\n", summary.Secondary)
+	fmt.Fprintf(&report, "**TTFT Threshold:** %s\n\n", formatDuration(summary.Threshold))
+	report.WriteString("---\n\n")
+
+	report.WriteString("## Summary\n\n")
+	fmt.Fprintf(&report, "- **Requests:** %d\n", total)
+	if total > 0 {
+		fmt.Fprintf(&report, "- **Failover Triggered:** %d (%.1f%%)\n", summary.Triggered, 100.0*float64(summary.Triggered)/float64(total))
+	}
+	fmt.Fprintf(&report, "- **Failed:** %d\n", summary.Failed)
+	fmt.Fprintf(&report, "- **Avg Effective TTFT:** %s\n", formatDuration(summary.AvgEffectiveTTFT))
+	fmt.Fprintf(&report, "- **Avg Effective E2E:** %s\n", formatDuration(summary.AvgEffectiveLatency))
+	fmt.Fprintf(&report, "- **Wasted Tokens:** %d\n", summary.TotalWastedTokens)
+	for _, name := range []string{summary.Primary, summary.Secondary} {
+		fmt.Fprintf(&report, "- **Served by %s:** %d\n", name, summary.Wins[name])
+	}
+	report.WriteString("\n")
+
+	report.WriteString("## Requests\n\n")
+	report.WriteString("| # | Failover | Winner | Effective TTFT | Effective E2E | Wasted Tokens | Error |\n")
+	report.WriteString("|---|----------|--------|----------------|---------------|---------------|-------|\n")
+	for _, it := range summary.Iterations {
+		winner := it.Winner
+		if winner == "" {
+			winner = NotAvailable
+		}
+		fmt.Fprintf(&report, "| %d | %t | %s | %s | %s | %d | %s |\n",
+			it.Iteration, it.Triggered, winner, formatDuration(it.EffectiveTTFT),
+			formatDuration(it.EffectiveLatency), it.WastedTokens, it.Error)
+	}
+	report.WriteString("\n")
+
+	report.WriteString("---\n\n")
+	fmt.Fprintf(&report, "*Report generated at %s*\n", time.Now().Format("2006-01-02 15:04:05"))
+
+	if err := os.WriteFile(filename, []byte(report.String()), 0600); err != nil {
+		return fmt.Errorf("error writing failover report: %w", err)
+	}
+	log.Printf("Failover report generated: %s", filename)
+	return nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSimulateFailover(t *testing.T) {
+	tke := testTokenizer(t)
+
+	fast := mockSSEServer{chunks: []string{"Hello", " world."}}.start(t)
+	defer fast.Close()
+	slow := mockSSEServer{firstTokenDelay: time.Second, chunks: []string{"Too", " late."}}.start(t)
+	defer slow.Close()
+
+	fastConfig := ProviderConfig{Name: "fast", BaseURL: fast.URL, APIKey: "k", Model: "m"}
+	slowConfig := ProviderConfig{Name: "slow", BaseURL: slow.URL, APIKey: "k", Model: "m"}
+
+	// Primary answers well within the threshold: no failover
+	it := simulateFailover(fastConfig, slowConfig, tke, 500*time.Millisecond, 1)
+	if it.Triggered || it.Winner != "fast" || it.Error != "" {
+		t.Fatalf("expected fast primary to serve without failover, got %+v", it)
+	}
+
+	// Slow primary misses the threshold: secondary is raced and wins
+	it = simulateFailover(slowConfig, fastConfig, tke, 100*time.Millisecond, 2)
+	if !it.Triggered || it.Winner != "fast" || it.Error != "" {
+		t.Fatalf("expected failover to fast secondary, got %+v", it)
+	}
+	if it.EffectiveTTFT < 100*time.Millisecond || it.EffectiveTTFT > 900*time.Millisecond {
+		t.Fatalf("expected effective TTFT just after the threshold, got %s", it.EffectiveTTFT)
+	}
+}
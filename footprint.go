@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// footprintInterval is how often the client host is sampled.
+	footprintInterval = time.Second
+	// cpuSaturatedPercent is the CPU usage, as a share of all cores, above which
+	// the client is considered a likely bottleneck.
+	cpuSaturatedPercent = 85.0
+	// gcPauseWarning is the longest single GC pause considered harmless for
+	// timing measurements.
+	gcPauseWarning = 50 * time.Millisecond
+	// gcPauseShareWarning is the share of wall time spent in GC pauses above which
+	// timings are considered skewed.
+	gcPauseShareWarning = 0.02
+)
+
+// ClientFootprint summarizes resource usage of the client host during a session.
+// Fields that cannot be measured on the current platform are left at -1.
+type ClientFootprint struct {
+	Duration      time.Duration `json:"duration"`
+	Samples       int           `json:"samples"`
+	NumCPU        int           `json:"numCpu"`
+	AvgCPUPercent float64       `json:"avgCpuPercent"`
+	MaxCPUPercent float64       `json:"maxCpuPercent"`
+	MaxRSSBytes   int64         `json:"maxRssBytes"`
+	MaxSockets    int           `json:"maxSockets"`
+	NumGC         uint32        `json:"numGc"`
+	GCPauseTotal  time.Duration `json:"gcPauseTotal"`
+	GCPauseMax    time.Duration `json:"gcPauseMax"`
+	Warnings      []string      `json:"warnings,omitempty"`
+}
+
+// footprintSampler periodically samples the client process in the background.
+type footprintSampler struct {
+	mu        sync.Mutex
+	started   time.Time
+	running   bool
+	stop      chan struct{}
+	lastWall  time.Time
+	lastCPU   time.Duration
+	cpuOK     bool
+	cpu       runningStats
+	maxRSS    int64
+	maxSocket int
+	baseGC    uint32
+	basePause uint64
+	lastGC    uint32
+	pauseMax  time.Duration
+	memStats  runtime.MemStats
+}
+
+// clientFootprint samples the client host for the whole session.
+var clientFootprint = &footprintSampler{}
+
+// start begins sampling every interval until stopSampling is called.
+func (s *footprintSampler) start(interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return
+	}
+	s.running = true
+	s.stop = make(chan struct{})
+	s.started = time.Now()
+	s.lastWall = s.started
+	s.lastCPU, s.cpuOK = processCPUTime()
+	s.maxRSS, s.maxSocket = -1, -1
+	runtime.ReadMemStats(&s.memStats)
+	s.baseGC, s.lastGC = s.memStats.NumGC, s.memStats.NumGC
+	s.basePause = s.memStats.PauseTotalNs
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.sample()
+			}
+		}
+	}()
+}
+
+// stopSampling ends background sampling.
+func (s *footprintSampler) stopSampling() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		close(s.stop)
+		s.running = false
+	}
+}
+
+// sample records one observation of CPU, memory, sockets and GC.
+func (s *footprintSampler) sample() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if cpuTime, ok := processCPUTime(); ok && s.cpuOK {
+		if wall := now.Sub(s.lastWall); wall > 0 {
+			s.cpu.add(100 * float64(cpuTime-s.lastCPU) / float64(wall) / float64(runtime.NumCPU()))
+		}
+		s.lastCPU = cpuTime
+	}
+	s.lastWall = now
+
+	if rss, ok := processRSS(); ok {
+		s.maxRSS = max(s.maxRSS, rss)
+	}
+	if sockets, ok := openSockets(); ok {
+		s.maxSocket = max(s.maxSocket, sockets)
+	}
+
+	runtime.ReadMemStats(&s.memStats)
+	// PauseNs is a ring buffer of the most recent 256 pauses
+	for gc := s.lastGC + 1; gc <= s.memStats.NumGC && s.memStats.NumGC-gc < 256; gc++ {
+		s.pauseMax = max(s.pauseMax, time.Duration(s.memStats.PauseNs[(gc+255)%256]))
+	}
+	s.lastGC = s.memStats.NumGC
+}
+
+// summary returns the footprint observed so far, including warnings when the
+// client host was likely the bottleneck.
+func (s *footprintSampler) summary() (ClientFootprint, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started.IsZero() {
+		return ClientFootprint{}, false
+	}
+
+	f := ClientFootprint{
+		Duration:      time.Since(s.started),
+		Samples:       int(s.cpu.count),
+		NumCPU:        runtime.NumCPU(),
+		AvgCPUPercent: -1,
+		MaxCPUPercent: -1,
+		MaxRSSBytes:   s.maxRSS,
+		MaxSockets:    s.maxSocket,
+		NumGC:         s.memStats.NumGC - s.baseGC,
+		GCPauseTotal:  time.Duration(s.memStats.PauseTotalNs - s.basePause),
+		GCPauseMax:    s.pauseMax,
+	}
+	if s.cpu.count > 0 {
+		f.AvgCPUPercent = s.cpu.mean
+		f.MaxCPUPercent = s.cpu.max
+	}
+
+	if f.MaxCPUPercent >= cpuSaturatedPercent {
+		f.Warnings = append(f.Warnings, fmt.Sprintf(
+			"client CPU peaked at %.0f%% of %d cores; timings from concurrent runs may reflect the client, not the provider",
+			f.MaxCPUPercent, f.NumCPU))
+	}
+	if f.GCPauseMax >= gcPauseWarning {
+		f.Warnings = append(f.Warnings, fmt.Sprintf(
+			"longest GC pause was %s; individual TTFT samples may be inflated", formatDuration(f.GCPauseMax)))
+	}
+	if f.Duration > 0 && float64(f.GCPauseTotal)/float64(f.Duration) >= gcPauseShareWarning {
+		f.Warnings = append(f.Warnings, fmt.Sprintf(
+			"GC pauses took %.1f%% of the session", 100*float64(f.GCPauseTotal)/float64(f.Duration)))
+	}
+	peakInFlight, avgThroughput := sessionProgress.peak()
+	f.Warnings = append(f.Warnings, ceilingWarnings(clientCeiling, peakInFlight, avgThroughput)...)
+	return f, true
+}
+
+// processRSS returns the resident set size of this process (Linux only).
+func processRSS() (int64, bool) {
+	data, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0, false
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0, false
+	}
+	pages, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return pages * int64(os.Getpagesize()), true
+}
+
+// openSockets counts the sockets held open by this process (Linux only).
+func openSockets() (int, bool) {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return 0, false
+	}
+	count := 0
+	for _, e := range entries {
+		target, err := os.Readlink(filepath.Join("/proc/self/fd", e.Name()))
+		if err == nil && strings.HasPrefix(target, "socket:") {
+			count++
+		}
+	}
+	return count, true
+}
+
+// formatBytes renders a byte count in MiB, or N/A when unknown.
+func formatBytes(b int64) string {
+	if b < 0 {
+		return NotAvailable
+	}
+	return fmt.Sprintf("%.1f MiB", float64(b)/(1<<20))
+}
+
+// formatPercent renders a percentage, or N/A when unknown.
+func formatPercent(p float64) string {
+	if p < 0 {
+		return NotAvailable
+	}
+	return fmt.Sprintf("%.1f%%", p)
+}
+
+// writeClientFootprintSection reports the client host's resource usage.
+func writeClientFootprintSection(report *strings.Builder) {
+	f, ok := clientFootprint.summary()
+	if !ok {
+		return
+	}
+	report.WriteString("## Client Footprint\n\n")
+	for _, w := range f.Warnings {
+		fmt.Fprintf(report, "> **Warning:** %s\n>\n", w)
+	}
+	if len(f.Warnings) > 0 {
+		report.WriteString("\n")
+	}
+	sockets := NotAvailable
+	if f.MaxSockets >= 0 {
+		sockets = strconv.Itoa(f.MaxSockets)
+	}
+	report.WriteString("| CPU Cores | Avg CPU | Peak CPU | Peak RSS | Peak Open Sockets | GC Cycles | GC Pause Total | GC Pause Max |\n")
+	report.WriteString("|-----------|---------|----------|----------|-------------------|-----------|----------------|--------------|\n")
+	fmt.Fprintf(report, "| %d | %s | %s | %s | %s | %d | %s | %s |\n\n",
+		f.NumCPU, formatPercent(f.AvgCPUPercent), formatPercent(f.MaxCPUPercent), formatBytes(f.MaxRSSBytes),
+		sockets, f.NumGC, formatDuration(f.GCPauseTotal), formatDuration(f.GCPauseMax))
+}
+
+// saveClientFootprint writes client-footprint.json and logs any warnings.
+func saveClientFootprint(resultsDir string) {
+	f, ok := clientFootprint.summary()
+	if !ok {
+		return
+	}
+	for _, w := range f.Warnings {
+		log.Printf("Warning: %s", w)
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		log.Printf("Warning: Failed to marshal client footprint: %v", err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(resultsDir, "client-footprint.json"), data, 0600); err != nil {
+		log.Printf("Warning: Failed to write client footprint: %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFootprintSamplerCollectsSamples(t *testing.T) {
+	s := &footprintSampler{}
+	s.start(10 * time.Millisecond)
+	deadline := time.Now().Add(100 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		_ = make([]byte, 1<<16)
+	}
+	runtime.GC()
+	s.sample()
+	s.stopSampling()
+
+	f, ok := s.summary()
+	if !ok || f.Samples == 0 || f.NumGC == 0 {
+		t.Fatalf("expected samples and GC cycles, got %+v", f)
+	}
+	if runtime.GOOS == "linux" && (f.MaxRSSBytes <= 0 || f.MaxSockets < 0 || f.AvgCPUPercent < 0) {
+		t.Fatalf("expected RSS, sockets and CPU on linux, got %+v", f)
+	}
+}
+
+func TestFootprintWarnings(t *testing.T) {
+	s := &footprintSampler{started: time.Now().Add(-time.Second), maxRSS: -1, maxSocket: -1, pauseMax: 80 * time.Millisecond}
+	s.cpu.add(40)
+	s.cpu.add(97)
+	f, _ := s.summary()
+	if len(f.Warnings) != 2 || !strings.Contains(f.Warnings[0], "CPU peaked at 97%") {
+		t.Fatalf("expected CPU and GC pause warnings, got %v", f.Warnings)
+	}
+
+	var report strings.Builder
+	saved := clientFootprint
+	defer func() { clientFootprint = saved }()
+	clientFootprint = s
+	writeClientFootprintSection(&report)
+	if !strings.Contains(report.String(), "## Client Footprint") || !strings.Contains(report.String(), "| N/A | N/A | 0 |") {
+		t.Fatalf("unexpected footprint section:\n%s", report.String())
+	}
+}
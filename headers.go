@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxHeaderSamples caps the successful and, separately, the failed responses
+// whose headers are kept per provider.
+const maxHeaderSamples = 20
+
+// sensitiveHeaderParts mark response headers whose values are replaced with
+// redactedValue before they are kept.
+var sensitiveHeaderParts = []string{"authorization", "cookie", "api-key", "apikey", "secret", "session"}
+
+// HeaderSample is the response headers of one request, kept so provider issues
+// can be reported with request IDs and rate-limit state (see bundle-issue).
+type HeaderSample struct {
+	Run     string      `json:"run"`
+	Time    time.Time   `json:"time"`
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers"`
+}
+
+// redactHeaders returns a copy of h with sensitive values replaced.
+func redactHeaders(h http.Header) http.Header {
+	out := h.Clone()
+	for name := range out {
+		lower := strings.ToLower(name)
+		for _, part := range sensitiveHeaderParts {
+			if strings.Contains(lower, part) {
+				out[name] = []string{redactedValue}
+				break
+			}
+		}
+	}
+	return out
+}
+
+// headerTracker keeps the first response headers of each provider.
+type headerTracker struct {
+	mu        sync.Mutex
+	providers map[string]*headerSamples
+}
+
+// headerSamples are one provider's kept responses.
+type headerSamples struct {
+	ok, failed []HeaderSample
+}
+
+// sessionHeaders is the header tracker shared by every provider in the session.
+var sessionHeaders = &headerTracker{providers: make(map[string]*headerSamples)}
+
+// observe keeps the headers of one response to a request of run, unless
+// maxHeaderSamples responses of its kind were already kept.
+func (t *headerTracker) observe(run RunContext, resp *http.Response) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	samples, ok := t.providers[run.Provider]
+	if !ok {
+		samples = &headerSamples{}
+		t.providers[run.Provider] = samples
+	}
+	kept := &samples.ok
+	if resp.StatusCode >= http.StatusBadRequest {
+		kept = &samples.failed
+	}
+	if len(*kept) < maxHeaderSamples {
+		*kept = append(*kept, HeaderSample{Run: run.String(), Time: time.Now(), Status: resp.StatusCode, Headers: redactHeaders(resp.Header)})
+	}
+}
+
+// samples returns the kept headers of provider, failed responses first.
+func (t *headerTracker) samples(provider string) []HeaderSample {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.providers[provider]
+	if !ok {
+		return nil
+	}
+	return append(append([]HeaderSample(nil), s.failed...), s.ok...)
+}
+
+// headersFile names the response-headers file of a provider in the logs folder.
+func headersFile(config ProviderConfig, timestamp string) string {
+	return fmt.Sprintf("%s-headers-%s.json", resultFilePrefix(config.Name, config.Env), timestamp)
+}
+
+// saveHeaders writes the response headers kept for config to logDir. Nothing
+// is written when none were kept.
+func saveHeaders(logDir string, config ProviderConfig, timestamp string) {
+	samples := sessionHeaders.samples(providerLabel(config.Name, config.Env))
+	if len(samples) == 0 {
+		return
+	}
+	data, err := json.MarshalIndent(samples, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(logDir, headersFile(config, timestamp)), data, 0600)
+	}
+	if err != nil {
+		log.Printf("Warning: response headers of %s not saved: %v", config.Name, err)
+	}
+}
+
+// headerTransport keeps the headers of every response for the run making the
+// request.
+type headerTransport struct {
+	base   http.RoundTripper
+	config ProviderConfig
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		sessionHeaders.observe(runContextFrom(req.Context(), t.config), resp)
+	}
+	return resp, err
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestHeaderTrackerRedactsAndCaps(t *testing.T) {
+	tracker := &headerTracker{providers: make(map[string]*headerSamples)}
+	run := RunContext{Provider: "nim", Iteration: 1}
+	for range maxHeaderSamples + 5 {
+		tracker.observe(run, &http.Response{StatusCode: http.StatusOK, Header: http.Header{
+			"X-Request-Id": {"req-1"},
+			"Set-Cookie":   {"session=abc"},
+		}})
+	}
+	tracker.observe(run, &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{
+		"X-Ratelimit-Remaining-Tokens": {"0"},
+	}})
+
+	samples := tracker.samples("nim")
+	if len(samples) != maxHeaderSamples+1 {
+		t.Fatalf("expected %d samples, got %d", maxHeaderSamples+1, len(samples))
+	}
+	if samples[0].Status != http.StatusTooManyRequests || samples[0].Headers.Get("X-Ratelimit-Remaining-Tokens") != "0" {
+		t.Errorf("expected the failed response first and unredacted rate limits, got %+v", samples[0])
+	}
+	if got := samples[1].Headers.Get("Set-Cookie"); got != redactedValue || samples[1].Headers.Get("X-Request-Id") != "req-1" {
+		t.Errorf("unexpected headers %v", samples[1].Headers)
+	}
+	if samples[1].Run != "provider=nim iter=1" {
+		t.Errorf("unexpected run label %q", samples[1].Run)
+	}
+}
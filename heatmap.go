@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// heatmapFileName is the TTFT heatmap written next to diagnostic and soak
+// reports.
+const heatmapFileName = "ttft-heatmap.svg"
+
+// diagnosticHeatmapBucket is the width of a diagnostic heatmap column: the
+// request interval of a diagnostic worker.
+const diagnosticHeatmapBucket = 15 * time.Second
+
+// TTFTBucket summarizes the diagnostic requests started within one
+// diagnosticHeatmapBucket of a provider's run.
+type TTFTBucket struct {
+	Offset   time.Duration `json:"offset"`
+	Requests int           `json:"requests"`
+	Failures int           `json:"failures"`
+	AvgTTFT  time.Duration `json:"avgTtft,omitempty"`
+}
+
+// timedTTFT is one request's TTFT and when it started, relative to the run.
+type timedTTFT struct {
+	offset time.Duration
+	ttft   time.Duration
+	failed bool
+}
+
+// ttftTimeline buckets requests by start time into consecutive buckets of the
+// given width, keeping empty buckets so columns line up across providers.
+func ttftTimeline(samples []timedTTFT, width time.Duration) []TTFTBucket {
+	if len(samples) == 0 {
+		return nil
+	}
+	var last time.Duration
+	for _, s := range samples {
+		last = max(last, s.offset)
+	}
+	buckets := make([]TTFTBucket, int(last/width)+1)
+	sums := make([]time.Duration, len(buckets))
+	for i := range buckets {
+		buckets[i].Offset = time.Duration(i) * width
+	}
+	for _, s := range samples {
+		i := max(0, int(s.offset/width))
+		buckets[i].Requests++
+		if s.failed {
+			buckets[i].Failures++
+			continue
+		}
+		sums[i] += s.ttft
+	}
+	for i, b := range buckets {
+		if ok := b.Requests - b.Failures; ok > 0 {
+			buckets[i].AvgTTFT = sums[i] / time.Duration(ok)
+		}
+	}
+	return buckets
+}
+
+// heatmapCell is one provider's requests within one column of the heatmap.
+type heatmapCell struct {
+	requests, failures int
+	ttft               time.Duration
+}
+
+// heatmapRow is one provider's row of cells, aligned with the columns.
+type heatmapRow struct {
+	label string
+	cells []heatmapCell
+}
+
+// Heatmap geometry, in pixels.
+const (
+	heatmapPlotWidth  = 600
+	heatmapMinCell    = 4
+	heatmapCellHeight = 22
+	heatmapTop        = 40
+	heatmapMargin     = 20
+)
+
+// Heatmap colors: a green-yellow-red scale from fastest to slowest TTFT, and
+// greys for columns without a measurement.
+var (
+	heatmapScale   = [][3]float64{{26, 152, 80}, {254, 224, 139}, {215, 48, 39}}
+	heatmapEmpty   = "#f2f2f2"
+	heatmapFailing = "#444"
+)
+
+// heatmapColor maps a TTFT to the color scale, logarithmically between lo and
+// hi, so a single very slow period does not flatten the rest.
+func heatmapColor(ttft, lo, hi time.Duration) string {
+	pos := 0.0
+	if hi > lo {
+		pos = math.Log(float64(ttft)/float64(lo)) / math.Log(float64(hi)/float64(lo))
+	}
+	pos = math.Min(1, math.Max(0, pos)) * float64(len(heatmapScale)-1)
+	i := min(int(pos), len(heatmapScale)-2)
+	frac := pos - float64(i)
+	var rgb [3]int
+	for c := range rgb {
+		rgb[c] = int(math.Round(heatmapScale[i][c] + frac*(heatmapScale[i+1][c]-heatmapScale[i][c])))
+	}
+	return fmt.Sprintf("#%02x%02x%02x", rgb[0], rgb[1], rgb[2])
+}
+
+// renderTTFTHeatmap draws average TTFT per provider (rows) and time (columns),
+// so periods where a provider was transiently slow stand out as hot cells.
+func renderTTFTHeatmap(title string, columns []string, rows []heatmapRow) string {
+	lo, hi := time.Duration(0), time.Duration(0)
+	labelChars := 0
+	for _, row := range rows {
+		labelChars = max(labelChars, len(row.label))
+		for _, c := range row.cells {
+			if c.requests == c.failures {
+				continue
+			}
+			if lo == 0 || c.ttft < lo {
+				lo = c.ttft
+			}
+			hi = max(hi, c.ttft)
+		}
+	}
+
+	left := heatmapMargin + 7*labelChars + 10
+	cellWidth := max(float64(heatmapMinCell), float64(heatmapPlotWidth)/float64(max(1, len(columns))))
+	plotRight := float64(left) + cellWidth*float64(len(columns))
+	width := int(math.Ceil(plotRight)) + heatmapMargin
+	bottom := heatmapTop + heatmapCellHeight*len(rows)
+	height := bottom + 75
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`+"\n", width, height)
+	fmt.Fprintf(&b, `  <rect width="%d" height="%d" fill="#fff"/>`+"\n", width, height)
+	fmt.Fprintf(&b, `  <text x="%d" y="22" font-size="14" font-weight="bold">%s</text>`+"\n", heatmapMargin, html.EscapeString(title))
+
+	for r, row := range rows {
+		y := heatmapTop + r*heatmapCellHeight
+		fmt.Fprintf(&b, `  <text x="%d" y="%d" text-anchor="end">%s</text>`+"\n", left-8, y+15, html.EscapeString(row.label))
+		for i, c := range row.cells {
+			fill, detail := heatmapEmpty, "no requests"
+			switch {
+			case c.requests == 0:
+			case c.requests == c.failures:
+				fill, detail = heatmapFailing, fmt.Sprintf("all %d requests failed", c.requests)
+			default:
+				fill = heatmapColor(c.ttft, lo, hi)
+				detail = fmt.Sprintf("avg TTFT %s over %d requests, %d failed", formatDuration(c.ttft), c.requests, c.failures)
+			}
+			fmt.Fprintf(&b, `  <rect x="%.1f" y="%d" width="%.1f" height="%d" fill="%s" stroke="#fff" stroke-width="0.5"><title>%s, %s: %s</title></rect>`+"\n",
+				float64(left)+cellWidth*float64(i), y, cellWidth, heatmapCellHeight, fill,
+				html.EscapeString(row.label), html.EscapeString(columns[i]), detail)
+		}
+	}
+
+	// Label about eight columns, evenly spread
+	step := max(1, int(math.Ceil(float64(len(columns))/8)))
+	for i := 0; i < len(columns); i += step {
+		fmt.Fprintf(&b, `  <text x="%.1f" y="%d" text-anchor="middle" fill="#555">%s</text>`+"\n",
+			float64(left)+cellWidth*(float64(i)+0.5), bottom+15, html.EscapeString(columns[i]))
+	}
+
+	legendY := bottom + 35
+	b.WriteString(`  <defs><linearGradient id="ttft-scale">`)
+	for i, stop := range heatmapScale {
+		fmt.Fprintf(&b, `<stop offset="%d%%" stop-color="rgb(%d,%d,%d)"/>`, 100*i/(len(heatmapScale)-1), int(stop[0]), int(stop[1]), int(stop[2]))
+	}
+	b.WriteString("</linearGradient></defs>\n")
+	fmt.Fprintf(&b, `  <text x="%d" y="%d" text-anchor="end">%s</text>`+"\n", left-8, legendY+11, formatDurationOrNA(lo))
+	fmt.Fprintf(&b, `  <rect x="%d" y="%d" width="160" height="14" fill="url(#ttft-scale)"/>`+"\n", left, legendY)
+	fmt.Fprintf(&b, `  <text x="%d" y="%d">%s (log scale)</text>`+"\n", left+166, legendY+11, formatDurationOrNA(hi))
+	legendY += 22
+	fmt.Fprintf(&b, `  <rect x="%d" y="%d" width="14" height="14" fill="%s" stroke="#ccc"/><text x="%d" y="%d">no requests</text>`+"\n",
+		left, legendY, heatmapEmpty, left+20, legendY+11)
+	fmt.Fprintf(&b, `  <rect x="%d" y="%d" width="14" height="14" fill="%s"/><text x="%d" y="%d">all requests failed</text>`+"\n",
+		left+110, legendY, heatmapFailing, left+130, legendY+11)
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+// writeDiagnosticHeatmap renders the TTFT timelines of diagnostic results into
+// dir, with columns counting time from each provider's start. It reports
+// whether a heatmap was written; results saved without timelines have none.
+func writeDiagnosticHeatmap(dir string, results []DiagnosticSummary) (bool, error) {
+	columnCount := 0
+	var rows []heatmapRow
+	for _, r := range results {
+		if len(r.TTFTTimeline) == 0 {
+			continue
+		}
+		row := heatmapRow{label: providerLabel(r.Provider, r.Env)}
+		for _, bucket := range r.TTFTTimeline {
+			row.cells = append(row.cells, heatmapCell{requests: bucket.Requests, failures: bucket.Failures, ttft: bucket.AvgTTFT})
+		}
+		columnCount = max(columnCount, len(row.cells))
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return false, nil
+	}
+	columns := make([]string, columnCount)
+	for i := range columns {
+		columns[i] = "+" + (time.Duration(i) * diagnosticHeatmapBucket).String()
+	}
+	for i := range rows {
+		for len(rows[i].cells) < columnCount {
+			rows[i].cells = append(rows[i].cells, heatmapCell{})
+		}
+	}
+	svg := renderTTFTHeatmap("Average TTFT by time into the run", columns, rows)
+	if err := os.WriteFile(filepath.Join(dir, heatmapFileName), []byte(svg), 0600); err != nil {
+		return false, fmt.Errorf("error writing TTFT heatmap: %w", err)
+	}
+	return true, nil
+}
+
+// renderSoakHeatmap draws the soak checkpoints of every provider, one column
+// per checkpoint. Checkpoints close every provider's window at once, so the
+// columns line up.
+func renderSoakHeatmap(summary SoakSummary) (string, bool) {
+	var columns []string
+	var rows []heatmapRow
+	for _, p := range summary.Providers {
+		row := heatmapRow{label: p.Provider}
+		for i, in := range p.Intervals {
+			if i >= len(columns) {
+				columns = append(columns, in.At.Format("15:04:05"))
+			}
+			row.cells = append(row.cells, heatmapCell{requests: in.Requests, failures: in.Failures, ttft: in.AvgTTFT})
+		}
+		rows = append(rows, row)
+	}
+	if len(columns) == 0 {
+		return "", false
+	}
+	for i := range rows {
+		for len(rows[i].cells) < len(columns) {
+			rows[i].cells = append(rows[i].cells, heatmapCell{})
+		}
+	}
+	return renderTTFTHeatmap("Average TTFT per checkpoint", columns, rows), true
+}
+
+// writeHeatmapSection links the heatmap written next to the report.
+func writeHeatmapSection(report *strings.Builder, written bool) {
+	if !written {
+		return
+	}
+	report.WriteString("## TTFT Heatmap\n\n")
+	report.WriteString("Average TTFT per provider over time, on a log color scale from green (fastest) to red (slowest); " +
+		"transient slow periods show up as hot cells across a row, and slowdowns shared by every provider as hot columns.\n\n")
+	fmt.Fprintf(report, "![TTFT heatmap](%s)\n\n", heatmapFileName)
+}
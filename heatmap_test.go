@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTTFTTimelineBuckets(t *testing.T) {
+	samples := []timedTTFT{
+		{offset: 0, ttft: 100 * time.Millisecond},
+		{offset: 2 * time.Second, ttft: 300 * time.Millisecond},
+		{offset: 16 * time.Second, failed: true},
+		{offset: 46 * time.Second, ttft: time.Second},
+	}
+	buckets := ttftTimeline(samples, 15*time.Second)
+	if len(buckets) != 4 {
+		t.Fatalf("expected 4 buckets up to the last request, got %d: %+v", len(buckets), buckets)
+	}
+	if b := buckets[0]; b.Requests != 2 || b.AvgTTFT != 200*time.Millisecond {
+		t.Errorf("first bucket = %+v, want 2 requests averaging 200ms", b)
+	}
+	if b := buckets[1]; b.Requests != 1 || b.Failures != 1 || b.AvgTTFT != 0 {
+		t.Errorf("second bucket = %+v, want one failure", b)
+	}
+	if b := buckets[2]; b.Requests != 0 || b.Offset != 30*time.Second {
+		t.Errorf("empty bucket should be kept: %+v", b)
+	}
+	if ttftTimeline(nil, time.Second) != nil {
+		t.Error("no samples should give no timeline")
+	}
+}
+
+func TestHeatmapColorScale(t *testing.T) {
+	lo, hi := 100*time.Millisecond, 10*time.Second
+	if got := heatmapColor(lo, lo, hi); got != "#1a9850" {
+		t.Errorf("fastest TTFT = %s, want green", got)
+	}
+	if got := heatmapColor(hi, lo, hi); got != "#d73027" {
+		t.Errorf("slowest TTFT = %s, want red", got)
+	}
+	// The scale is logarithmic: 1s is halfway between 100ms and 10s
+	if got := heatmapColor(time.Second, lo, hi); got != "#fee08b" {
+		t.Errorf("midpoint TTFT = %s, want yellow", got)
+	}
+	if got := heatmapColor(lo, lo, lo); got != "#1a9850" {
+		t.Errorf("a single value should map to the start of the scale, got %s", got)
+	}
+}
+
+func TestWriteDiagnosticHeatmap(t *testing.T) {
+	dir := t.TempDir()
+	results := []DiagnosticSummary{
+		{Provider: "nim", TTFTTimeline: []TTFTBucket{
+			{Offset: 0, Requests: 10, AvgTTFT: 200 * time.Millisecond},
+			{Offset: 15 * time.Second, Requests: 10, Failures: 10},
+			{Offset: 30 * time.Second, Requests: 10, Failures: 1, AvgTTFT: 4 * time.Second},
+		}},
+		{Provider: "novita", Env: "prod", TTFTTimeline: []TTFTBucket{{Offset: 0, Requests: 10, AvgTTFT: time.Second}}},
+		{Provider: "legacy"},
+	}
+	written, err := writeDiagnosticHeatmap(dir, results)
+	if err != nil || !written {
+		t.Fatalf("writeDiagnosticHeatmap = %t, %v", written, err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, heatmapFileName))
+	if err != nil {
+		t.Fatalf("heatmap not written: %v", err)
+	}
+	svg := string(data)
+	for _, want := range []string{"novita [prod]", "+30s", heatmapFailing, heatmapEmpty, "#1a9850", "#d73027"} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("heatmap missing %q", want)
+		}
+	}
+	if strings.Contains(svg, "legacy") {
+		t.Error("results without a timeline should be left out")
+	}
+	if strings.Count(svg, "<rect x=") != 6+3 {
+		t.Errorf("expected 2 rows of 3 cells plus the legend, got %d rects", strings.Count(svg, "<rect x="))
+	}
+
+	var report strings.Builder
+	writeHeatmapSection(&report, written)
+	if !strings.Contains(report.String(), "]("+heatmapFileName+")") {
+		t.Errorf("section should link the heatmap:\n%s", report.String())
+	}
+
+	if written, err := writeDiagnosticHeatmap(t.TempDir(), results[2:]); written || err != nil {
+		t.Errorf("no timelines should write nothing, got %t, %v", written, err)
+	}
+}
+
+func TestSoakHeatmapAlignsCheckpoints(t *testing.T) {
+	at := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	summary := SoakSummary{Providers: []SoakProviderSummary{
+		{Provider: "nim", Intervals: []SoakInterval{
+			{At: at, Requests: 5, AvgTTFT: time.Second},
+			{At: at.Add(5 * time.Minute), Requests: 5, AvgTTFT: 2 * time.Second},
+		}},
+		{Provider: "novita", Intervals: []SoakInterval{{At: at, Requests: 5, AvgTTFT: time.Second}}},
+	}}
+	svg, ok := renderSoakHeatmap(summary)
+	if !ok || !strings.Contains(svg, "12:05:00") {
+		t.Fatalf("expected a column per checkpoint, got %t:\n%s", ok, svg)
+	}
+	if !strings.Contains(renderSoakReport(summary, "test"), "## TTFT Heatmap") {
+		t.Error("soak report should link the heatmap")
+	}
+	if _, ok := renderSoakHeatmap(SoakSummary{Providers: []SoakProviderSummary{{Provider: "nim"}}}); ok {
+		t.Error("no checkpoints should give no heatmap")
+	}
+}
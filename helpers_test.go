@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// byteBpeLoader serves a byte-level encoding so tests never download tokenizer files.
+type byteBpeLoader struct{}
+
+func (byteBpeLoader) LoadTiktokenBpe(string) (map[string]int, error) {
+	ranks := make(map[string]int, 256)
+	for i := 0; i < 256; i++ {
+		ranks[string([]byte{byte(i)})] = i
+	}
+	return ranks, nil
+}
+
+// testTokenizer returns a tokenizer that counts one token per byte.
+func testTokenizer(t *testing.T) *tiktoken.Tiktoken {
+	t.Helper()
+	tiktoken.SetBpeLoader(byteBpeLoader{})
+	tke, err := tiktoken.GetEncoding("cl100k_base")
+	if err != nil {
+		t.Fatalf("failed to build test tokenizer: %v", err)
+	}
+	return tke
+}
+
+// mockSSEServer describes a fake OpenAI-compatible streaming endpoint.
+type mockSSEServer struct {
+	firstTokenDelay time.Duration
+	chunkDelay      time.Duration
+	chunks          []string
+	// unavailable is the number of initial requests answered with 503.
+	unavailable int
+}
+
+// start launches the server and returns it; callers must Close it.
+func (m mockSSEServer) start(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(&mockSSEHandler{
+		firstTokenDelay: m.firstTokenDelay,
+		chunkDelay:      m.chunkDelay,
+		chunks:          m.chunks,
+		unavailable:     int32(m.unavailable),
+	})
+}
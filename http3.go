@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// HTTP versions compared by --http3.
+const (
+	httpVersion2 = "h2"
+	httpVersion3 = "h3"
+)
+
+// http3RootCAs is the CA pool HTTP/3 connections verify servers against; nil
+// means the system pool.
+var http3RootCAs *x509.CertPool
+
+// http3Transports holds one HTTP/3 transport per pinned address and address
+// family, so QUIC connections are reused across requests like TCP ones.
+var http3Transports sync.Map
+
+// sessionHandshakes holds the handshake time of each provider label's TLS
+// probe, for the HTTP/2 vs HTTP/3 table.
+var sessionHandshakes sync.Map
+
+// httpVersionEnvTag labels results measured over one HTTP version, e.g.
+// "prod-h3".
+func httpVersionEnvTag(env, v string) string {
+	if env == "" {
+		return v
+	}
+	return env + "-" + v
+}
+
+// applyHTTP3 tests every HTTPS provider twice, over HTTP/2 and over HTTP/3,
+// tagged h2 and h3. Plain-HTTP providers cannot speak HTTP/3 and are tested
+// once, as before.
+func applyHTTP3(providers []ProviderConfig) []ProviderConfig {
+	out := make([]ProviderConfig, 0, 2*len(providers))
+	for _, p := range providers {
+		if !strings.HasPrefix(p.BaseURL, "https://") {
+			log.Printf("Warning: --http3 needs an https endpoint; %s is tested over its usual transport only", providerLabel(p.Name, p.Env))
+			out = append(out, p)
+			continue
+		}
+		for _, version := range []string{httpVersion2, httpVersion3} {
+			variant := p
+			variant.HTTPVersion = version
+			variant.Env = httpVersionEnvTag(p.Env, version)
+			out = append(out, variant)
+		}
+	}
+	return out
+}
+
+// dialQUIC opens a QUIC connection to addr, or to ip on addr's port when set,
+// over the address family ipVersion forces. It returns once the handshake is
+// complete.
+func dialQUIC(ctx context.Context, addr, ip, ipVersion string, tlsConfig *tls.Config, quicConfig *quic.Config) (*quic.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing dial address %q: %w", addr, err)
+	}
+	if ip != "" {
+		host = ip
+	}
+	network := "ip"
+	if ipVersion == ipVersion4 || ipVersion == ipVersion6 {
+		network += ipVersion
+	}
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, network, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no address for %s", host)
+	}
+	return quic.DialAddr(ctx, net.JoinHostPort(ips[0].Unmap().String(), port), tlsConfig, quicConfig)
+}
+
+// http3Transport returns the HTTP/3 transport for connections to ip (any
+// address of the host when empty) over the family ipVersion forces.
+func http3Transport(ip, ipVersion string) http.RoundTripper {
+	key := ip + "|" + ipVersion
+	if t, ok := http3Transports.Load(key); ok {
+		return t.(http.RoundTripper)
+	}
+	transport := &http3.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: http3RootCAs, MinVersion: tls.VersionTLS13},
+		Dial: func(ctx context.Context, addr string, tlsConfig *tls.Config, quicConfig *quic.Config) (*quic.Conn, error) {
+			return dialQUIC(ctx, addr, ip, ipVersion, tlsConfig, quicConfig)
+		},
+	}
+	t, _ := http3Transports.LoadOrStore(key, transport)
+	return t.(http.RoundTripper)
+}
+
+// probeQUIC is probeTLS for providers tested over HTTP/3: it times a fresh
+// QUIC handshake, which sets up the transport and TLS 1.3 together, and
+// records what it negotiated.
+func probeQUIC(ctx context.Context, config ProviderConfig, host, port string, roots *x509.CertPool) *TLSInfo {
+	tlsConfig := &tls.Config{
+		ServerName: host,
+		NextProtos: []string{http3.NextProtoH3},
+		RootCAs:    roots,
+		MinVersion: tls.VersionTLS13,
+	}
+	start := time.Now()
+	conn, err := dialQUIC(ctx, net.JoinHostPort(host, port), config.DialIP, config.IPVersion, tlsConfig, nil)
+	if err != nil {
+		return &TLSInfo{Error: err.Error()}
+	}
+	defer func() { _ = conn.CloseWithError(0, "") }()
+	info := &TLSInfo{Handshake: time.Since(start)}
+	info.describe(conn.ConnectionState().TLS)
+	return info
+}
+
+// httpVersionMeasurement is one result measured over one HTTP version.
+type httpVersionMeasurement struct {
+	provider, env, mode, version string
+	ttft                         time.Duration
+	throughput                   float64
+}
+
+// handshake returns the handshake time recorded for m's connection, or
+// NotAvailable.
+func (m *httpVersionMeasurement) handshake() string {
+	if d, ok := sessionHandshakes.Load(providerLabel(m.provider, m.env)); ok {
+		return formatDuration(d.(time.Duration))
+	}
+	return NotAvailable
+}
+
+// writeHTTPVersionRows compares each provider's HTTP/2 and HTTP/3 results.
+// Nothing is written unless some provider was measured over both.
+func writeHTTPVersionRows(report *strings.Builder, measurements []httpVersionMeasurement) {
+	type pair struct {
+		label, mode string
+		h2, h3      *httpVersionMeasurement
+	}
+	pairs := make(map[string]*pair)
+	for i := range measurements {
+		m := &measurements[i]
+		baseEnv := envWithoutTag(m.env, m.version)
+		label := providerLabel(m.provider, baseEnv)
+		key := label + "\x00" + m.mode
+		p, ok := pairs[key]
+		if !ok {
+			p = &pair{label: label, mode: m.mode}
+			pairs[key] = p
+		}
+		if m.version == httpVersion3 {
+			p.h3 = m
+		} else {
+			p.h2 = m
+		}
+	}
+	keys := make([]string, 0, len(pairs))
+	for key, p := range pairs {
+		if p.h2 != nil && p.h3 != nil {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+	sort.Strings(keys)
+
+	report.WriteString("## HTTP/2 vs HTTP/3\n\n")
+	report.WriteString("The same provider measured over each protocol (experimental, `--http3`). Handshake is the setup of a fresh connection: " +
+		"TCP and TLS for HTTP/2, QUIC for HTTP/3. Δ is HTTP/3 minus HTTP/2, so a negative Δ TTFT or a positive Δ Throughput favors HTTP/3.\n\n")
+	report.WriteString("| Provider | Mode | H2 Handshake | H3 Handshake | H2 TTFT | H3 TTFT | Δ TTFT | H2 Throughput | H3 Throughput | Δ Throughput |\n")
+	report.WriteString("|----------|------|--------------|--------------|---------|---------|--------|---------------|---------------|--------------|\n")
+	for _, key := range keys {
+		p := pairs[key]
+		fmt.Fprintf(report, "| %s | %s | %s | %s | %s | %s | %s | %.2f tok/s | %.2f tok/s | %+.2f tok/s |\n", p.label, p.mode,
+			p.h2.handshake(), p.h3.handshake(),
+			formatDuration(p.h2.ttft), formatDuration(p.h3.ttft), formatSignedDuration(p.h3.ttft-p.h2.ttft),
+			p.h2.throughput, p.h3.throughput, p.h3.throughput-p.h2.throughput)
+	}
+	report.WriteString("\n")
+}
+
+// writeHTTPVersionSection compares successful results measured over both
+// HTTP versions.
+func writeHTTPVersionSection(report *strings.Builder, results []TestResult) {
+	measurements := make([]httpVersionMeasurement, 0, len(results))
+	for _, r := range results {
+		if r.Success && r.HTTPVersion != "" {
+			measurements = append(measurements, httpVersionMeasurement{r.Provider, r.Env, r.Mode, r.HTTPVersion, r.TTFT, r.Throughput})
+		}
+	}
+	writeHTTPVersionRows(report, measurements)
+}
+
+// writeDiagnosticHTTPVersionSection is the diagnostic-report counterpart of
+// writeHTTPVersionSection.
+func writeDiagnosticHTTPVersionSection(report *strings.Builder, results []DiagnosticSummary) {
+	measurements := make([]httpVersionMeasurement, 0, len(results))
+	for _, r := range results {
+		if r.Successful > 0 && r.HTTPVersion != "" {
+			measurements = append(measurements, httpVersionMeasurement{r.Provider, r.Env, r.Mode, r.HTTPVersion, r.AvgTTFT, r.AvgThroughput})
+		}
+	}
+	writeHTTPVersionRows(report, measurements)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+func TestApplyHTTP3(t *testing.T) {
+	got := applyHTTP3([]ProviderConfig{
+		{Name: "a", BaseURL: "https://a.example/v1"},
+		{Name: "b", Env: "prod-ipv6", BaseURL: "https://b.example/v1"},
+		{Name: "local", BaseURL: "http://localhost:8000/v1"},
+	})
+	if len(got) != 5 {
+		t.Fatalf("expected two copies per https provider, got %+v", got)
+	}
+	if got[0].Env != "h2" || got[0].HTTPVersion != httpVersion2 || got[1].Env != "h3" || got[1].HTTPVersion != httpVersion3 {
+		t.Errorf("unexpected copies of a: %+v", got[:2])
+	}
+	if got[3].Env != "prod-ipv6-h3" {
+		t.Errorf("tag should follow the existing env: %q", got[3].Env)
+	}
+	if got[4].Env != "" || got[4].HTTPVersion != "" {
+		t.Errorf("plain-HTTP provider should be kept as is: %+v", got[4])
+	}
+}
+
+// startHTTP3Server serves handler over HTTP/3 on a loopback UDP port with
+// the certificate of an httptest TLS server, and trusts that certificate for
+// the test's HTTP/3 connections.
+func startHTTP3Server(t *testing.T, handler http.Handler) (url string, roots *x509.CertPool) {
+	t.Helper()
+	tlsServer := httptest.NewTLSServer(handler)
+	t.Cleanup(tlsServer.Close)
+	roots = x509.NewCertPool()
+	roots.AddCert(tlsServer.Certificate())
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := &http3.Server{
+		Handler:   handler,
+		TLSConfig: http3.ConfigureTLSConfig(&tls.Config{Certificates: tlsServer.TLS.Certificates}),
+	}
+	go func() { _ = server.Serve(conn) }()
+	t.Cleanup(func() { _ = server.Close() })
+
+	previous := http3RootCAs
+	http3RootCAs = roots
+	http3Transports.Clear()
+	t.Cleanup(func() {
+		http3RootCAs = previous
+		http3Transports.Clear()
+	})
+	return fmt.Sprintf("https://127.0.0.1:%d", conn.LocalAddr().(*net.UDPAddr).Port), roots
+}
+
+func TestHTTP3Requests(t *testing.T) {
+	var http3Requests atomic.Int32
+	sse := &mockSSEHandler{chunks: []string{"Hello", " there."}}
+	url, roots := startHTTP3Server(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 3 {
+			http3Requests.Add(1)
+		}
+		sse.ServeHTTP(w, r)
+	}))
+	tke := testTokenizer(t)
+	logger := log.New(io.Discard, "", 0)
+
+	config := ProviderConfig{Name: "p", BaseURL: url, APIKey: "k", Model: "m", HTTPVersion: httpVersion3}
+	_, _, _, tokens, response, err := singleTestRun(context.Background(), config, tke, logger, "k")
+	if err != nil {
+		t.Fatalf("HTTP/3 request failed: %v", err)
+	}
+	if response != "Hello there." || tokens == 0 || http3Requests.Load() != 1 {
+		t.Errorf("response %q, %d tokens, %d HTTP/3 requests", response, tokens, http3Requests.Load())
+	}
+
+	info := probeTLS(context.Background(), config, roots)
+	if info == nil || info.Error != "" {
+		t.Fatalf("QUIC probe failed: %+v", info)
+	}
+	if info.ALPN != http3.NextProtoH3 || info.Version != "TLS 1.3" || info.Handshake <= 0 || info.CertChainLength != 1 {
+		t.Errorf("unexpected QUIC handshake info: %+v", info)
+	}
+}
+
+func TestHTTPVersionSection(t *testing.T) {
+	sessionHandshakes.Store(providerLabel("a", "prod-ipv4-h3"), 40*time.Millisecond)
+	t.Cleanup(func() { sessionHandshakes.Delete(providerLabel("a", "prod-ipv4-h3")) })
+	results := []TestResult{
+		{Provider: "a", Env: "prod-ipv4-h2", IPVersion: ipVersion4, HTTPVersion: httpVersion2, Mode: "streaming", Success: true, TTFT: 300 * time.Millisecond, Throughput: 50},
+		{Provider: "a", Env: "prod-ipv4-h3", IPVersion: ipVersion4, HTTPVersion: httpVersion3, Mode: "streaming", Success: true, TTFT: 250 * time.Millisecond, Throughput: 55},
+		{Provider: "a", Env: "prod-ipv6-h2", IPVersion: ipVersion6, HTTPVersion: httpVersion2, Mode: "streaming", Success: true, TTFT: 400 * time.Millisecond},
+		{Provider: "b", Env: "h2", HTTPVersion: httpVersion2, Mode: "streaming", Success: true, TTFT: time.Second},
+	}
+	var report strings.Builder
+	writeHTTPVersionSection(&report, results)
+	out := report.String()
+	if !strings.Contains(out, "## HTTP/2 vs HTTP/3") ||
+		!strings.Contains(out, "| a [prod-ipv4] | streaming | N/A | 0.040s | 0.300s | 0.250s | -0.050s | 50.00 tok/s | 55.00 tok/s | +5.00 tok/s |") {
+		t.Fatalf("missing comparison:\n%s", out)
+	}
+	if strings.Contains(out, "prod-ipv6") || strings.Contains(out, "| b |") {
+		t.Errorf("providers without an HTTP/3 result should not be paired:\n%s", out)
+	}
+
+	// Both comparisons still pair when --ip-version both and --http3 combine
+	report.Reset()
+	writeIPVersionSection(&report, results)
+	if !strings.Contains(report.String(), "| a [prod-h2] | streaming | 0.300s | 0.400s | +0.100s |") {
+		t.Errorf("IPv4 vs IPv6 lost the HTTP/2 pair:\n%s", report.String())
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Instance states a provider can report for a request.
+const (
+	instanceWarm = "warm"
+	instanceCold = "cold"
+)
+
+// coldStartHeaders are response headers checked on every provider for a
+// cold-start flag. There is no standard name; providers using another one are
+// configured with <PREFIX>_COLD_START_HEADER.
+var coldStartHeaders = []string{"x-cold-start"}
+
+// parseInstanceState reads a cold-start header value: "cold" or a true boolean
+// means a cold instance, "warm" or a false boolean a warm one. Anything else
+// is unknown and returns "".
+func parseInstanceState(value string) string {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "cold", "true", "1", "yes":
+		return instanceCold
+	case "warm", "false", "0", "no":
+		return instanceWarm
+	}
+	return ""
+}
+
+// instanceState returns whether the response with headers h was served by a
+// warm or a cold instance, checking header first when set, or "" when the
+// provider did not say.
+func instanceState(h http.Header, header string) string {
+	names := coldStartHeaders
+	if header != "" {
+		names = append([]string{header}, names...)
+	}
+	for _, name := range names {
+		if state := parseInstanceState(h.Get(name)); state != "" {
+			return state
+		}
+	}
+	return ""
+}
+
+// instanceTracker records the instance state providers reported per run.
+type instanceTracker struct {
+	mu   sync.Mutex
+	runs map[RunContext]string
+}
+
+// sessionInstances is the instance tracker shared by every provider in the
+// session.
+var sessionInstances = &instanceTracker{runs: make(map[RunContext]string)}
+
+// observe records the state of a response of run. The first response of a run
+// decides: continuations are served by the instance it woke.
+func (t *instanceTracker) observe(run RunContext, state string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.runs[run]; !ok {
+		t.runs[run] = state
+	}
+}
+
+// state returns the instance state recorded for run, or "".
+func (t *instanceTracker) state(run RunContext) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.runs[run]
+}
+
+// instanceTransport records the instance state of every response that
+// reports one against the run making the request.
+type instanceTransport struct {
+	base   http.RoundTripper
+	config ProviderConfig
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *instanceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		if state := instanceState(resp.Header, t.config.ColdStartHeader); state != "" {
+			sessionInstances.observe(runContextFrom(req.Context(), t.config), state)
+		}
+	}
+	return resp, err
+}
+
+// InstanceStats averages the successful runs served by warm or by cold
+// instances.
+type InstanceStats struct {
+	State         string        `json:"state"`
+	Runs          int           `json:"runs"`
+	AvgTTFT       time.Duration `json:"avgTtftMs"`
+	AvgE2E        time.Duration `json:"avgE2eLatencyMs"`
+	AvgThroughput float64       `json:"avgThroughputTokensPerSec"`
+}
+
+// instanceStats splits the successful runs by instance state, warm first. It
+// returns nil when no run was labelled, so providers that do not report their
+// instances add nothing to results.
+func instanceStats(runs []RunSample) []InstanceStats {
+	var stats []InstanceStats
+	for _, state := range []string{instanceWarm, instanceCold} {
+		s := InstanceStats{State: state}
+		var ttft, e2e time.Duration
+		for _, r := range runs {
+			if !r.Success || r.Instance != state {
+				continue
+			}
+			s.Runs++
+			ttft += r.TTFT
+			e2e += r.E2E
+			s.AvgThroughput += r.Throughput
+		}
+		if s.Runs == 0 {
+			continue
+		}
+		s.AvgTTFT = ttft / time.Duration(s.Runs)
+		s.AvgE2E = e2e / time.Duration(s.Runs)
+		s.AvgThroughput /= float64(s.Runs)
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+// recordInstanceStats is instanceStats for diagnostic requests.
+func recordInstanceStats(records []DiagnosticRecord) []InstanceStats {
+	runs := make([]RunSample, 0, len(records))
+	for _, r := range records {
+		runs = append(runs, RunSample{Success: r.Error == "", TTFT: r.TTFT, E2E: r.E2ELatency, Throughput: r.Throughput, Instance: r.Instance})
+	}
+	return instanceStats(runs)
+}
+
+// instanceRow is one provider's entry in the warm/cold table.
+type instanceRow struct {
+	provider, mode string
+	stats          []InstanceStats
+}
+
+// writeInstanceRows adds the warm/cold table; nothing is written without rows.
+func writeInstanceRows(report *strings.Builder, rows []instanceRow) {
+	if len(rows) == 0 {
+		return
+	}
+	report.WriteString("## Warm vs Cold Instances\n\n")
+	report.WriteString("Successful runs split by whether the provider reported serving them from a warm or a cold instance. " +
+		"The averages above mix both; a cold start mostly adds to TTFT.\n\n")
+	report.WriteString("| Provider | Mode | Instance | Runs | Avg TTFT | Avg E2E | Avg Throughput |\n")
+	report.WriteString("|----------|------|----------|------|----------|---------|----------------|\n")
+	for _, r := range rows {
+		for _, s := range r.stats {
+			fmt.Fprintf(report, "| %s | %s | %s | %d | %s | %s | %.2f tok/s |\n", r.provider, r.mode, s.State, s.Runs,
+				formatDuration(s.AvgTTFT), formatDuration(s.AvgE2E), s.AvgThroughput)
+		}
+	}
+	report.WriteString("\n")
+}
+
+// writeInstanceSection adds the warm/cold table for results whose provider
+// reported instance states.
+func writeInstanceSection(report *strings.Builder, results []TestResult) {
+	rows := make([]instanceRow, 0, len(results))
+	for _, r := range results {
+		if len(r.Instances) > 0 {
+			rows = append(rows, instanceRow{providerLabel(r.Provider, r.Env), r.Mode, r.Instances})
+		}
+	}
+	writeInstanceRows(report, rows)
+}
+
+// writeDiagnosticInstanceSection is the diagnostic-report counterpart of
+// writeInstanceSection.
+func writeDiagnosticInstanceSection(report *strings.Builder, results []DiagnosticSummary) {
+	rows := make([]instanceRow, 0, len(results))
+	for _, r := range results {
+		if len(r.Instances) > 0 {
+			rows = append(rows, instanceRow{providerLabel(r.Provider, r.Env), r.Mode, r.Instances})
+		}
+	}
+	writeInstanceRows(report, rows)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInstanceState(t *testing.T) {
+	tests := []struct {
+		header, value, configured, want string
+	}{
+		{"X-Cold-Start", "true", "", instanceCold},
+		{"X-Cold-Start", "0", "", instanceWarm},
+		{"Fly-Cold", "cold", "fly-cold", instanceCold},
+		{"Fly-Cold", "cold", "", ""},
+		{"X-Cold-Start", "maybe", "", ""},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		h.Set(tt.header, tt.value)
+		if got := instanceState(h, tt.configured); got != tt.want {
+			t.Errorf("%s: %s (configured %q) = %q, want %q", tt.header, tt.value, tt.configured, got, tt.want)
+		}
+	}
+}
+
+func TestInstanceTransportRecordsFirstResponse(t *testing.T) {
+	cold := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cold {
+			w.Header().Set("X-Boot", "cold")
+		} else {
+			w.Header().Set("X-Boot", "warm")
+		}
+	}))
+	defer srv.Close()
+
+	config := ProviderConfig{Name: "instance-transport", BaseURL: srv.URL, ColdStartHeader: "X-Boot"}
+	client := providerHTTPClient(config)
+	run := newRunContext(config, ModeStreaming, 1)
+	get := func(rc RunContext) {
+		req, err := http.NewRequestWithContext(withRunContext(context.Background(), rc), http.MethodGet, srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	get(run)
+	cold = false
+	get(run) // a continuation keeps the run's first state
+	second := newRunContext(config, ModeStreaming, 2)
+	get(second)
+
+	if got := sessionInstances.state(run); got != instanceCold {
+		t.Errorf("run 1 = %q, want cold", got)
+	}
+	if got := sessionInstances.state(second); got != instanceWarm {
+		t.Errorf("run 2 = %q, want warm", got)
+	}
+	if got := sessionInstances.state(newRunContext(config, ModeStreaming, 3)); got != "" {
+		t.Errorf("unrun iteration = %q, want unknown", got)
+	}
+}
+
+func TestInstanceStatsAndSection(t *testing.T) {
+	if instanceStats([]RunSample{{Success: true, TTFT: time.Second}}) != nil {
+		t.Fatal("expected no split without labelled runs")
+	}
+	stats := instanceStats([]RunSample{
+		{Success: true, Instance: instanceCold, TTFT: 4 * time.Second, E2E: 6 * time.Second, Throughput: 90},
+		{Success: true, Instance: instanceWarm, TTFT: 200 * time.Millisecond, E2E: 2 * time.Second, Throughput: 100},
+		{Success: true, Instance: instanceWarm, TTFT: 400 * time.Millisecond, E2E: 2 * time.Second, Throughput: 120},
+		{Success: false, Instance: instanceCold, Error: "timeout"},
+		{Success: true, TTFT: time.Second},
+	})
+	if len(stats) != 2 || stats[0].State != instanceWarm || stats[0].Runs != 2 || stats[0].AvgTTFT != 300*time.Millisecond ||
+		stats[0].AvgThroughput != 110 || stats[1].State != instanceCold || stats[1].Runs != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	var report strings.Builder
+	writeInstanceSection(&report, []TestResult{
+		{Provider: "nim", Env: "prod", Mode: "streaming", Instances: stats},
+		{Provider: "novita", Mode: "streaming"},
+	})
+	got := report.String()
+	if !strings.Contains(got, "## Warm vs Cold Instances") ||
+		!strings.Contains(got, "| nim [prod] | streaming | warm | 2 | 0.300s | 2.000s | 110.00 tok/s |") ||
+		!strings.Contains(got, "| nim [prod] | streaming | cold | 1 | 4.000s | 6.000s | 90.00 tok/s |") ||
+		strings.Contains(got, "novita") {
+		t.Fatalf("unexpected section:\n%s", got)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// errShutdownRequested is returned for runs skipped after a graceful shutdown was requested.
+var errShutdownRequested = errors.New("shutdown requested")
+
+// errNotForeground means the process runs as a background job and must not read
+// from the terminal.
+var errNotForeground = errors.New("not the foreground process")
+
+// shutdownCtx is cancelled when the user asks for a graceful shutdown. In-flight
+// requests are allowed to finish; no new ones are started.
+var shutdownCtx, requestShutdown = context.WithCancel(context.Background())
+
+// providerProgress counts completed requests for one provider.
+type providerProgress struct {
+	completed     int
+	failed        int
+	ttftSum       time.Duration
+	throughputSum float64
+}
+
+// liveProgress tracks completed requests so a summary can be printed mid-session.
+type liveProgress struct {
+	mu           sync.Mutex
+	started      time.Time
+	providers    map[string]*providerProgress
+	inFlight     int
+	peakInFlight int
+}
+
+// sessionProgress is the progress tracker shared by every provider in the session.
+var sessionProgress = &liveProgress{started: time.Now(), providers: make(map[string]*providerProgress)}
+
+// begin marks a request as in flight; every begin is paired with a record.
+func (p *liveProgress) begin() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.inFlight++
+	p.peakInFlight = max(p.peakInFlight, p.inFlight)
+}
+
+// peak returns the highest number of requests that were in flight at once, and
+// the mean throughput of successful requests.
+func (p *liveProgress) peak() (inFlight int, avgThroughput float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	completed, sum := 0, 0.0
+	for _, pp := range p.providers {
+		completed += pp.completed
+		sum += pp.throughputSum
+	}
+	if completed > 0 {
+		avgThroughput = sum / float64(completed)
+	}
+	return p.peakInFlight, avgThroughput
+}
+
+// record adds one finished request.
+func (p *liveProgress) record(provider string, ttft time.Duration, throughput float64, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.inFlight > 0 {
+		p.inFlight--
+	}
+	pp, ok := p.providers[provider]
+	if !ok {
+		pp = &providerProgress{}
+		p.providers[provider] = pp
+	}
+	if err != nil {
+		pp.failed++
+		return
+	}
+	pp.completed++
+	pp.ttftSum += ttft
+	pp.throughputSum += throughput
+}
+
+// summary renders the progress so far as log-friendly lines.
+func (p *liveProgress) summary() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	lines := []string{fmt.Sprintf("=== Live summary (elapsed %s) ===", time.Since(p.started).Round(time.Second))}
+	if len(p.providers) == 0 {
+		return append(lines, "No requests have finished yet.")
+	}
+	names := make([]string, 0, len(p.providers))
+	for name := range p.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pp := p.providers[name]
+		line := fmt.Sprintf("%s: %d ok, %d failed", name, pp.completed, pp.failed)
+		if pp.completed > 0 {
+			line += fmt.Sprintf(", avg TTFT %s, avg throughput %.2f tok/s",
+				formatDuration(pp.ttftSum/time.Duration(pp.completed)), pp.throughputSum/float64(pp.completed))
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// handleKey acts on one key press. It returns false once no further keys matter.
+func handleKey(key byte) bool {
+	switch key {
+	case 's', 'S':
+		for _, line := range sessionProgress.summary() {
+			log.Println(line)
+		}
+	case 'q', 'Q':
+		if shutdownCtx.Err() == nil {
+			log.Println("Graceful shutdown requested: in-flight requests will finish, no new ones will start.")
+			requestShutdown()
+		}
+		return false
+	}
+	return true
+}
+
+// readKeys feeds bytes from r to handleKey until q is pressed or r is exhausted.
+func readKeys(r io.Reader) {
+	reader := bufio.NewReader(r)
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return
+		}
+		if !handleKey(b) {
+			return
+		}
+	}
+}
+
+// startKeyBindings listens for s (summary) and q (graceful shutdown) on stdin when
+// it is an interactive terminal. Where the terminal can be switched to cbreak mode
+// keys act immediately; otherwise they must be followed by Enter. The returned
+// function restores the terminal and must be called before exiting; pass it to
+// interruptGracefully so an interrupt restores it too.
+func startKeyBindings() (restore func()) {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return func() {}
+	}
+
+	restore, err = enableCbreak(os.Stdin)
+	if errors.Is(err, errNotForeground) {
+		return func() {}
+	}
+	if err != nil {
+		restore = func() {}
+		log.Println("Interactive keys: type s+Enter for a live summary, q+Enter to stop gracefully")
+	} else {
+		log.Println("Interactive keys: press s for a live summary, q to stop gracefully")
+	}
+	go readKeys(os.Stdin)
+	return restore
+}
+
+// interruptGracefully makes the first SIGINT or SIGTERM request a graceful
+// shutdown, as q does, so in-flight requests finish and the report is still
+// written. A second one calls restore, since the process then ends without
+// running deferred calls, and is raised again to have its usual effect. The
+// returned function stops handling the signals and calls restore.
+func interruptGracefully(restore func()) (stop func()) {
+	var once sync.Once
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for interrupts := 0; ; interrupts++ {
+			var sig os.Signal
+			select {
+			case sig = <-signals:
+			case <-done:
+				return
+			}
+			if interrupts == 0 {
+				log.Printf("Received %s: in-flight requests will finish and the report will be written; interrupt again to exit now.", sig)
+				requestShutdown()
+				continue
+			}
+			signal.Stop(signals)
+			once.Do(restore)
+			if p, err := os.FindProcess(os.Getpid()); err == nil {
+				_ = p.Signal(sig)
+			}
+			return
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+		once.Do(restore)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLiveProgressSummary(t *testing.T) {
+	p := &liveProgress{started: time.Now(), providers: make(map[string]*providerProgress)}
+	if lines := p.summary(); len(lines) != 2 || !strings.Contains(lines[1], "No requests") {
+		t.Fatalf("unexpected empty summary: %v", lines)
+	}
+
+	p.record("b", 0, 0, errors.New("boom"))
+	p.record("a", time.Second, 100, nil)
+	p.record("a", 3*time.Second, 50, nil)
+	lines := p.summary()
+	if len(lines) != 3 {
+		t.Fatalf("expected header plus two providers, got %v", lines)
+	}
+	if !strings.HasPrefix(lines[1], "a: 2 ok, 0 failed") || !strings.Contains(lines[1], "avg throughput 75.00 tok/s") {
+		t.Errorf("unexpected line for a: %q", lines[1])
+	}
+	if lines[2] != "b: 0 ok, 1 failed" {
+		t.Errorf("unexpected line for b: %q", lines[2])
+	}
+}
+
+func TestReadKeysRequestsShutdown(t *testing.T) {
+	saved, savedCancel := shutdownCtx, requestShutdown
+	shutdownCtx, requestShutdown = context.WithCancel(context.Background())
+	defer func() { shutdownCtx, requestShutdown = saved, savedCancel }()
+
+	readKeys(strings.NewReader("sxq"))
+	if !errors.Is(canStartRun(), errShutdownRequested) {
+		t.Fatalf("expected q to request shutdown, got %v", canStartRun())
+	}
+}
+
+func TestInterruptGracefully(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("cannot send an interrupt to this process")
+	}
+	saved, savedCancel := shutdownCtx, requestShutdown
+	shutdownCtx, requestShutdown = context.WithCancel(context.Background())
+	defer func() { shutdownCtx, requestShutdown = saved, savedCancel }()
+	// Keeps the raised signals from ending the test binary
+	caught := make(chan os.Signal, 3)
+	signal.Notify(caught, os.Interrupt)
+	defer signal.Stop(caught)
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	interrupt := func() {
+		t.Helper()
+		if err := p.Signal(os.Interrupt); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-caught:
+		case <-time.After(time.Second):
+			t.Fatal("interrupt not delivered")
+		}
+	}
+
+	restored := make(chan struct{}, 2)
+	stop := interruptGracefully(func() { restored <- struct{}{} })
+	interrupt()
+	select {
+	case <-shutdownCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("first SIGINT did not request a graceful shutdown")
+	}
+	if len(restored) != 0 {
+		t.Fatal("terminal restored while the runs wind down")
+	}
+
+	interrupt()
+	select {
+	case <-restored:
+	case <-time.After(time.Second):
+		t.Fatal("terminal not restored after the second SIGINT")
+	}
+	// The second signal is raised again once the terminal is restored
+	select {
+	case <-caught:
+	case <-time.After(time.Second):
+		t.Fatal("second SIGINT not raised again")
+	}
+	stop()
+	if len(restored) != 0 {
+		t.Error("terminal restored twice")
+	}
+}
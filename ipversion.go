@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --ip-version values.
+const (
+	ipVersion4    = "4"
+	ipVersion6    = "6"
+	ipVersionBoth = "both"
+)
+
+// validateIPVersion checks an --ip-version value; empty means no preference.
+func validateIPVersion(v string) error {
+	switch v {
+	case "", ipVersion4, ipVersion6, ipVersionBoth:
+		return nil
+	}
+	return fmt.Errorf("--ip-version must be %s, %s or %s", ipVersion4, ipVersion6, ipVersionBoth)
+}
+
+// ipVersionNetwork is the dial network that forces an address family.
+func ipVersionNetwork(v string) string {
+	switch v {
+	case ipVersion4:
+		return "tcp4"
+	case ipVersion6:
+		return "tcp6"
+	}
+	return "tcp"
+}
+
+// ipVersionEnvTag labels results forced onto one family, e.g. "prod-ipv6".
+func ipVersionEnvTag(env, v string) string {
+	if env == "" {
+		return "ipv" + v
+	}
+	return env + "-ipv" + v
+}
+
+// matchesIPVersion reports whether ip belongs to the family v (any when empty).
+func matchesIPVersion(ip, v string) bool {
+	isV4 := !strings.Contains(ip, ":")
+	switch v {
+	case ipVersion4:
+		return isV4
+	case ipVersion6:
+		return !isV4
+	}
+	return true
+}
+
+// applyIPVersion forces every provider onto one address family, or for "both"
+// tests each provider twice, once per family, tagged ipv4 and ipv6.
+func applyIPVersion(providers []ProviderConfig, v string) []ProviderConfig {
+	if v == "" {
+		return providers
+	}
+	versions := []string{v}
+	if v == ipVersionBoth {
+		versions = []string{ipVersion4, ipVersion6}
+	}
+	out := make([]ProviderConfig, 0, len(providers)*len(versions))
+	for _, p := range providers {
+		for _, version := range versions {
+			forced := p
+			forced.IPVersion = version
+			if v == ipVersionBoth {
+				forced.Env = ipVersionEnvTag(p.Env, version)
+			}
+			out = append(out, forced)
+		}
+	}
+	return out
+}
+
+// ipVersionMeasurement is one result forced onto an address family.
+type ipVersionMeasurement struct {
+	provider, env, mode, version string
+	ttft, e2e                    time.Duration
+}
+
+// writeIPVersionRows compares each provider's IPv4 and IPv6 paths. Nothing is
+// written unless some provider was measured over both.
+func writeIPVersionRows(report *strings.Builder, measurements []ipVersionMeasurement) {
+	type pair struct {
+		label, mode string
+		v4, v6      *ipVersionMeasurement
+	}
+	pairs := make(map[string]*pair)
+	for i := range measurements {
+		m := &measurements[i]
+		baseEnv := envWithoutTag(m.env, "ipv"+m.version)
+		label := providerLabel(m.provider, baseEnv)
+		key := label + "\x00" + m.mode
+		p, ok := pairs[key]
+		if !ok {
+			p = &pair{label: label, mode: m.mode}
+			pairs[key] = p
+		}
+		if m.version == ipVersion4 {
+			p.v4 = m
+		} else {
+			p.v6 = m
+		}
+	}
+	keys := make([]string, 0, len(pairs))
+	for key, p := range pairs {
+		if p.v4 != nil && p.v6 != nil {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+	sort.Strings(keys)
+
+	report.WriteString("## IPv4 vs IPv6\n\n")
+	report.WriteString("The same provider measured over each address family. A positive Δ means IPv6 is slower.\n\n")
+	report.WriteString("| Provider | Mode | IPv4 TTFT | IPv6 TTFT | Δ TTFT | IPv4 E2E | IPv6 E2E | Δ E2E |\n")
+	report.WriteString("|----------|------|-----------|-----------|--------|----------|----------|-------|\n")
+	for _, key := range keys {
+		p := pairs[key]
+		fmt.Fprintf(report, "| %s | %s | %s | %s | %s | %s | %s | %s |\n", p.label, p.mode,
+			formatDuration(p.v4.ttft), formatDuration(p.v6.ttft), formatSignedDuration(p.v6.ttft-p.v4.ttft),
+			formatDuration(p.v4.e2e), formatDuration(p.v6.e2e), formatSignedDuration(p.v6.e2e-p.v4.e2e))
+	}
+	report.WriteString("\n")
+}
+
+// formatSignedDuration renders a difference with an explicit sign.
+func formatSignedDuration(d time.Duration) string {
+	if d < 0 {
+		return "-" + formatDuration(-d)
+	}
+	return "+" + formatDuration(d)
+}
+
+// writeIPVersionSection compares successful results measured over both families.
+func writeIPVersionSection(report *strings.Builder, results []TestResult) {
+	measurements := make([]ipVersionMeasurement, 0, len(results))
+	for _, r := range results {
+		if r.Success && r.IPVersion != "" {
+			measurements = append(measurements, ipVersionMeasurement{r.Provider, r.Env, r.Mode, r.IPVersion, r.TTFT, r.E2ELatency})
+		}
+	}
+	writeIPVersionRows(report, measurements)
+}
+
+// writeDiagnosticIPVersionSection is the diagnostic-report counterpart of
+// writeIPVersionSection.
+func writeDiagnosticIPVersionSection(report *strings.Builder, results []DiagnosticSummary) {
+	measurements := make([]ipVersionMeasurement, 0, len(results))
+	for _, r := range results {
+		if r.Successful > 0 && r.IPVersion != "" {
+			measurements = append(measurements, ipVersionMeasurement{r.Provider, r.Env, r.Mode, r.IPVersion, r.AvgTTFT, r.AvgE2ELatency})
+		}
+	}
+	writeIPVersionRows(report, measurements)
+}
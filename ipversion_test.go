@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestApplyIPVersion(t *testing.T) {
+	providers := []ProviderConfig{{Name: "a"}, {Name: "b", Env: "prod"}}
+	if got := applyIPVersion(providers, ""); len(got) != 2 || got[0].IPVersion != "" {
+		t.Errorf("no preference changed providers: %+v", got)
+	}
+	if got := applyIPVersion(providers, ipVersion6); len(got) != 2 || got[1].IPVersion != ipVersion6 || got[1].Env != "prod" {
+		t.Errorf("forced family should keep tags: %+v", got)
+	}
+	got := applyIPVersion(providers, ipVersionBoth)
+	if len(got) != 4 || got[0].Env != "ipv4" || got[1].Env != "ipv6" || got[3].Env != "prod-ipv6" || got[3].IPVersion != ipVersion6 {
+		t.Errorf("unexpected both expansion: %+v", got)
+	}
+	if err := validateIPVersion("5"); err == nil {
+		t.Error("--ip-version 5 accepted")
+	}
+}
+
+func TestIPVersionCopiesKeepSeparateKeyStats(t *testing.T) {
+	tracker := &keyTracker{providers: make(map[string]map[string]*KeyStat)}
+	copies := applyIPVersion([]ProviderConfig{{Name: "p", APIKey: "sk-shared-abcd"}}, ipVersionBoth)
+	for i, config := range copies {
+		tracker.observe(config, openai.RateLimitHeaders{LimitRequests: 100, RemainingRequests: 90 - 50*i})
+		tracker.record(config, 10, nil)
+	}
+
+	for i, config := range copies {
+		stats := tracker.stats(providerLabel(config.Name, config.Env))
+		if len(stats) != 1 || stats[0].Requests != 1 || stats[0].LowestRemainingRequests != 90-50*i {
+			t.Errorf("%s: key stats %+v mix in the other family", config.Env, stats)
+		}
+	}
+}
+
+func TestForcedIPVersionDials(t *testing.T) {
+	server := mockSSEServer{chunks: []string{"Hello", " there."}}.start(t)
+	defer server.Close()
+	tke := testTokenizer(t)
+	logger := log.New(io.Discard, "", 0)
+
+	// The test server only listens on 127.0.0.1
+	v4 := ProviderConfig{Name: "p", BaseURL: server.URL, APIKey: "k", Model: "m", IPVersion: ipVersion4}
+	if _, _, _, _, _, err := singleTestRun(context.Background(), v4, tke, logger, "k"); err != nil {
+		t.Fatalf("IPv4 request failed: %v", err)
+	}
+	v6 := v4
+	v6.IPVersion = ipVersion6
+	if _, _, _, _, _, err := singleTestRun(context.Background(), v6, tke, logger, "k"); err == nil {
+		t.Fatal("IPv6-only request reached an IPv4 address")
+	}
+}
+
+func TestIPVersionSection(t *testing.T) {
+	var report strings.Builder
+	writeIPVersionSection(&report, []TestResult{
+		{Provider: "a", Env: "ipv4", IPVersion: ipVersion4, Mode: "streaming", Success: true, TTFT: 200 * time.Millisecond, E2ELatency: time.Second},
+		{Provider: "a", Env: "ipv6", IPVersion: ipVersion6, Mode: "streaming", Success: true, TTFT: 350 * time.Millisecond, E2ELatency: 900 * time.Millisecond},
+		{Provider: "b", Env: "ipv4", IPVersion: ipVersion4, Mode: "streaming", Success: true, TTFT: time.Second},
+	})
+	out := report.String()
+	if !strings.Contains(out, "## IPv4 vs IPv6") || !strings.Contains(out, "| a | streaming |") {
+		t.Fatalf("missing comparison:\n%s", out)
+	}
+	if !strings.Contains(out, "+0.150s") || !strings.Contains(out, "-0.100s") {
+		t.Errorf("missing signed deltas:\n%s", out)
+	}
+	if strings.Contains(out, "| b |") {
+		t.Errorf("provider without an IPv6 result should not be paired:\n%s", out)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// stutterFactor is how many times the median gap the p99 must exceed for a
+// stream to be flagged as stuttering.
+const stutterFactor = 10
+
+// ITLStats summarizes inter-token latency: the gaps between consecutive
+// content-bearing chunks of successful streams. Percentiles are estimated from
+// a histogram, so they are accurate to a few percent.
+type ITLStats struct {
+	Gaps int           `json:"gaps"`
+	Mean time.Duration `json:"meanMs"`
+	P50  time.Duration `json:"p50Ms"`
+	P95  time.Duration `json:"p95Ms"`
+	P99  time.Duration `json:"p99Ms"`
+	Max  time.Duration `json:"maxMs"`
+}
+
+// itlTracker accumulates inter-token gaps in constant memory, so soak runs can
+// keep adding to it.
+type itlTracker struct {
+	hist durationHistogram
+	sum  time.Duration
+	max  time.Duration
+}
+
+// add records the gaps of one stream.
+func (t *itlTracker) add(gaps []time.Duration) {
+	for _, g := range gaps {
+		t.hist.add(g)
+		t.sum += g
+		t.max = max(t.max, g)
+	}
+}
+
+// stats returns the summary, or nil if no gaps were recorded.
+func (t *itlTracker) stats() *ITLStats {
+	if t.hist.total == 0 {
+		return nil
+	}
+	return &ITLStats{
+		Gaps: int(t.hist.total),
+		Mean: t.sum / time.Duration(t.hist.total),
+		P50:  t.hist.percentile(50),
+		P95:  t.hist.percentile(95),
+		P99:  t.hist.percentile(99),
+		Max:  t.max,
+	}
+}
+
+// stutters reports whether the slowest gaps are far above the typical one: the
+// stream pauses noticeably even if its average rate is fine.
+func (s *ITLStats) stutters() bool {
+	return s.P50 > 0 && s.P99 > stutterFactor*s.P50
+}
+
+// itlRow is one entry of the inter-token latency table.
+type itlRow struct {
+	provider string
+	mode     string
+	stats    *ITLStats
+}
+
+// writeITLRows renders the inter-token latency table.
+func writeITLRows(report *strings.Builder, rows []itlRow) {
+	if len(rows) == 0 {
+		return
+	}
+	report.WriteString("## Inter-Token Latency\n\n")
+	fmt.Fprintf(report, "Gaps between consecutive chunks after the first token. Streams whose p99 gap is more than %d× "+
+		"the median are marked *stutters*: they pause mid-generation even when their average tok/s looks fine.\n\n", stutterFactor)
+	report.WriteString("| Provider | Mode | Gaps | Mean | Median | P95 | P99 | Max |\n")
+	report.WriteString("|----------|------|------|------|--------|-----|-----|-----|\n")
+	for _, r := range rows {
+		p99 := formatDuration(r.stats.P99)
+		if r.stats.stutters() {
+			p99 += " *stutters*"
+		}
+		fmt.Fprintf(report, "| %s | %s | %d | %s | %s | %s | %s | %s |\n",
+			r.provider, r.mode, r.stats.Gaps, formatDuration(r.stats.Mean), formatDuration(r.stats.P50),
+			formatDuration(r.stats.P95), p99, formatDuration(r.stats.Max))
+	}
+	report.WriteString("\n")
+}
+
+// writeITLSection adds the inter-token latency table for results that have
+// it.
+func writeITLSection(report *strings.Builder, results []TestResult) {
+	rows := make([]itlRow, 0, len(results))
+	for _, r := range results {
+		if r.ITL != nil {
+			rows = append(rows, itlRow{providerLabel(r.Provider, r.Env), r.Mode, r.ITL})
+		}
+	}
+	writeITLRows(report, rows)
+}
+
+// writeDiagnosticITLSection is the diagnostic-report counterpart of
+// writeITLSection.
+func writeDiagnosticITLSection(report *strings.Builder, results []DiagnosticSummary) {
+	rows := make([]itlRow, 0, len(results))
+	for _, r := range results {
+		if r.ITL != nil {
+			rows = append(rows, itlRow{providerLabel(r.Provider, r.Env), r.Mode, r.ITL})
+		}
+	}
+	writeITLRows(report, rows)
+}
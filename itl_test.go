@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lamim/llm-api-speed/pkg/benchmark"
+)
+
+func TestITLSectionFlagsStutteringStreams(t *testing.T) {
+	tracker := newStreamTracker()
+	steady := make([]time.Duration, 100)
+	stutter := make([]time.Duration, 100)
+	for i := range steady {
+		steady[i] = 20 * time.Millisecond
+		stutter[i] = 20 * time.Millisecond
+	}
+	stutter[10], stutter[60] = time.Second, 2*time.Second
+	tracker.add("steady", benchmark.Sample{ITL: steady[:50]})
+	tracker.add("steady", benchmark.Sample{ITL: steady[50:]})
+	tracker.add("stutter", benchmark.Sample{ITL: stutter})
+	if tracker.itl("missing") != nil {
+		t.Fatal("expected no stats for an unknown provider")
+	}
+
+	got := tracker.itl("stutter")
+	if got.Gaps != 100 || got.Max != 2*time.Second || got.Mean != 49600*time.Microsecond {
+		t.Errorf("unexpected stats: %+v", got)
+	}
+	// Percentiles are histogram estimates, within ~5% of the true value
+	if got.P50 < 20*time.Millisecond || got.P50 > 21*time.Millisecond || got.P99 < time.Second {
+		t.Errorf("unexpected percentiles: %+v", got)
+	}
+
+	var report strings.Builder
+	writeITLSection(&report, []TestResult{
+		{Provider: "steady", Mode: "streaming", Success: true, ITL: tracker.itl("steady")},
+		{Provider: "stutter", Mode: "streaming", Success: true, ITL: got},
+		{Provider: "old-result", Mode: "streaming", Success: true},
+	})
+	out := report.String()
+	for _, want := range []string{
+		"| Provider | Mode | Gaps | Mean | Median | P95 | P99 | Max |",
+		"| steady | streaming | 100 | 0.020s |",
+		" *stutters* | 2.000s |",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "*stutters*") != 2 || strings.Contains(out, "old-result") {
+		t.Errorf("only the stuttering stream should be flagged, once beside the legend:\n%s", out)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// judgeConfig is the judge model used to score responses; nil disables judging.
+var judgeConfig *ProviderConfig
+
+const judgeSystemPrompt = `You are a strict evaluator of AI assistant responses. Rate how well the response fulfils the request on a scale from 1 (useless) to 10 (excellent), considering instruction following, coherence, and writing quality. Reply with the number only.`
+
+// judgeScorePattern matches the first integer in a judge reply.
+var judgeScorePattern = regexp.MustCompile(`\d+`)
+
+// judgeResponse asks the judge model to score a response of provider and
+// returns a 1-10 score. The judge's usage is counted towards provider.
+func judgeResponse(ctx context.Context, judge ProviderConfig, provider, prompt, response string) (int, error) {
+	clientConfig := openai.DefaultConfig(judge.APIKey)
+	clientConfig.BaseURL = judge.BaseURL
+	clientConfig.HTTPClient = providerHTTPClient(judge)
+	client := openai.NewClientWithConfig(clientConfig)
+
+	req := openai.ChatCompletionRequest{
+		Model: judge.Model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: judgeSystemPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: fmt.Sprintf("Request:\n%s\n\nResponse:\n%s", prompt, response)},
+		},
+		MaxTokens: 16,
+	}
+
+	resp, err := client.CreateChatCompletion(ctx, req)
+	if err != nil {
+		return 0, fmt.Errorf("judge request failed: %w", err)
+	}
+	sessionBudget.recordFor(provider, judge, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	if len(resp.Choices) == 0 {
+		return 0, fmt.Errorf("judge returned no choices")
+	}
+	return parseJudgeScore(resp.Choices[0].Message.Content)
+}
+
+// parseJudgeScore extracts a 1-10 score from a judge reply.
+func parseJudgeScore(reply string) (int, error) {
+	match := judgeScorePattern.FindString(reply)
+	if match == "" {
+		return 0, fmt.Errorf("no score in judge reply %q", strings.TrimSpace(reply))
+	}
+	score, err := strconv.Atoi(match)
+	if err != nil || score < 1 || score > 10 {
+		return 0, fmt.Errorf("judge score out of range in reply %q", strings.TrimSpace(reply))
+	}
+	return score, nil
+}
+
+// promptForRun returns the user prompt a given run was sent, for judging.
+func promptForRun(config ProviderConfig, mode TestMode, requestKey string) string {
+	if mode == ModeToolCalling {
+		return sessionPrompt(promptGroupToolCalling, toolCallingPrompt)
+	}
+	messages := streamingRequest(config, requestKey).Messages
+	return messages[len(messages)-1].Content
+}
+
+// judgedRun is a successful run's prompt and response awaiting a quality score.
+type judgedRun struct {
+	label    string
+	prompt   string
+	response string
+}
+
+// scoreRuns sends each run to the judge model and returns the individual scores
+// and their mean. Runs the judge fails to score are logged and left out.
+func scoreRuns(providerLogger *log.Logger, config ProviderConfig, runs []judgedRun) (scores []int, mean float64) {
+	if judgeConfig == nil || len(runs) == 0 {
+		return nil, 0
+	}
+	providerLogger.Printf("[%s] Judging %d response(s) with %s", config.Name, len(runs), judgeConfig.Model)
+
+	total := 0
+	for _, run := range runs {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		score, err := judgeResponse(ctx, *judgeConfig, providerLabel(config.Name, config.Env), run.prompt, run.response)
+		cancel()
+		if err != nil {
+			providerLogger.Printf("[%s] Judge failed for %s: %v", config.Name, run.label, err)
+			continue
+		}
+		providerLogger.Printf("[%s] Judge score for %s: %d/10", config.Name, run.label, score)
+		scores = append(scores, score)
+		total += score
+	}
+	if len(scores) == 0 {
+		return nil, 0
+	}
+	return scores, float64(total) / float64(len(scores))
+}
+
+// writeSpeedQualitySection writes a speed-vs-quality table and ASCII scatter plot
+// for results that have a quality score.
+func writeSpeedQualitySection(report *strings.Builder, results []TestResult) {
+	scored := make([]TestResult, 0)
+	for _, r := range results {
+		if r.Success && r.QualityScore > 0 {
+			scored = append(scored, r)
+		}
+	}
+	if len(scored) == 0 {
+		return
+	}
+
+	report.WriteString("## Speed vs Quality\n\n")
+	if judgeConfig != nil {
+		fmt.Fprintf(report, "Quality scored 1-10 by judge model `%s`.\n\n", judgeConfig.Model)
+	}
+	report.WriteString("| Key | Provider | Mode | Throughput | TTFT | Quality |\n")
+	report.WriteString("|-----|----------|------|------------|------|---------|\n")
+	for i, r := range scored {
+		fmt.Fprintf(report, "| %c | %s | %s | %.2f tok/s | %s | %.1f/10 |\n",
+			scatterKey(i), providerLabel(r.Provider, r.Env), r.Mode, r.Throughput, formatDuration(r.TTFT), r.QualityScore)
+	}
+	report.WriteString("\n")
+
+	report.WriteString("```\n")
+	report.WriteString(renderSpeedQualityScatter(scored))
+	report.WriteString("```\n\n")
+}
+
+// scatterKey returns the plot marker for the i-th scored result.
+func scatterKey(i int) rune {
+	const keys = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	return rune(keys[i%len(keys)])
+}
+
+// renderSpeedQualityScatter plots quality (y, 1-10) against throughput (x).
+func renderSpeedQualityScatter(results []TestResult) string {
+	const width = 50
+	const height = 10
+
+	maxThroughput := 0.0
+	for _, r := range results {
+		maxThroughput = math.Max(maxThroughput, r.Throughput)
+	}
+	if maxThroughput <= 0 {
+		maxThroughput = 1
+	}
+
+	grid := make([][]rune, height)
+	for i := range grid {
+		grid[i] = []rune(strings.Repeat(" ", width))
+	}
+	for i, r := range results {
+		col := int(r.Throughput / maxThroughput * float64(width-1))
+		row := height - int(math.Round(r.QualityScore))
+		row = max(0, min(height-1, row))
+		grid[row][col] = scatterKey(i)
+	}
+
+	var plot strings.Builder
+	for i, line := range grid {
+		fmt.Fprintf(&plot, "%2d | %s\n", height-i, string(line))
+	}
+	fmt.Fprintf(&plot, "   +%s\n", strings.Repeat("-", width+1))
+	fmt.Fprintf(&plot, "    0%s%.0f tok/s\n", strings.Repeat(" ", width-4), maxThroughput)
+	return plot.String()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseJudgeScore(t *testing.T) {
+	tests := []struct {
+		reply   string
+		want    int
+		wantErr bool
+	}{
+		{reply: "7", want: 7},
+		{reply: " 10\n", want: 10},
+		{reply: "Score: 3/10", want: 3},
+		{reply: "0", wantErr: true},
+		{reply: "11", wantErr: true},
+		{reply: "excellent", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseJudgeScore(tt.reply)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseJudgeScore(%q): expected error, got %d", tt.reply, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseJudgeScore(%q) = %d, %v; want %d", tt.reply, got, err, tt.want)
+		}
+	}
+}
+
+func TestWriteSpeedQualitySection(t *testing.T) {
+	var report strings.Builder
+	writeSpeedQualitySection(&report, []TestResult{
+		{Provider: "fast", Mode: "streaming", Success: true, Throughput: 200, QualityScore: 4},
+		{Provider: "good", Mode: "streaming", Success: true, Throughput: 50, QualityScore: 9},
+		{Provider: "unscored", Mode: "streaming", Success: true, Throughput: 100},
+	})
+	out := report.String()
+	if !strings.Contains(out, "## Speed vs Quality") {
+		t.Fatalf("expected speed vs quality section, got:\n%s", out)
+	}
+	if strings.Contains(out, "unscored") {
+		t.Fatalf("expected unscored results to be omitted")
+	}
+	if !strings.Contains(out, "| A | fast |") || !strings.Contains(out, "| B | good |") {
+		t.Fatalf("expected keyed rows for scored providers, got:\n%s", out)
+	}
+}
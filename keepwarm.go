@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/lamim/llm-api-speed/pkg/providers"
+	"github.com/pkoukk/tiktoken-go"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// keepWarmPrompt asks for as little output as possible.
+const keepWarmPrompt = "Reply with the single word OK."
+
+// keepWarmTimeout bounds one keep-warm request.
+const keepWarmTimeout = 30 * time.Second
+
+// sendKeepWarm streams a tiny request of at most maxTokens output tokens to
+// keep a serverless deployment from scaling to zero. Nothing is measured; the
+// tokens still count against the session budget.
+func sendKeepWarm(ctx context.Context, config ProviderConfig, tke *tiktoken.Tiktoken, maxTokens int) error {
+	if err := canStartRun(); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, keepWarmTimeout)
+	defer cancel()
+	req := openai.ChatCompletionRequest{
+		Model:     config.Model,
+		Messages:  []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: keepWarmPrompt}},
+		MaxTokens: maxTokens,
+		Stream:    true,
+	}
+	req = config.Quirks.apply(req)
+
+	stream, err := providers.OpenStream(ctx, benchProvider(config), req)
+	if err != nil {
+		return fmt.Errorf("error creating stream: %w", err)
+	}
+	defer func() {
+		_ = stream.Close()
+	}()
+	var content string
+	for {
+		delta, recvErr := stream.Recv()
+		if errors.Is(recvErr, io.EOF) {
+			break
+		}
+		if recvErr != nil {
+			return fmt.Errorf("stream error: %w", recvErr)
+		}
+		content += delta.Content + delta.Reasoning
+	}
+	sessionBudget.record(config, countPromptTokens(tke, req.Messages), len(tke.Encode(content, nil, nil)))
+	return nil
+}
+
+// validateKeepWarm checks the keep-warm flags against the soak interval: a
+// keep-warm interval at or above it would never fire.
+func validateKeepWarm(interval, soakInterval time.Duration, tokens int) error {
+	if interval < 0 {
+		return fmt.Errorf("--keep-warm must not be negative")
+	}
+	if interval > 0 && interval >= soakInterval {
+		return fmt.Errorf("--keep-warm (%s) must be shorter than --soak-interval (%s)", interval, soakInterval)
+	}
+	if tokens < 1 {
+		return fmt.Errorf("--keep-warm-tokens must be at least 1, got %d", tokens)
+	}
+	return nil
+}
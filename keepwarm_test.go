@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateKeepWarm(t *testing.T) {
+	if err := validateKeepWarm(10*time.Second, 30*time.Second, 1); err != nil {
+		t.Errorf("valid keep-warm rejected: %v", err)
+	}
+	cases := []struct {
+		interval, soakInterval time.Duration
+		tokens                 int
+	}{
+		{-time.Second, 30 * time.Second, 1},
+		{30 * time.Second, 30 * time.Second, 1},
+		{time.Minute, 30 * time.Second, 1},
+		{10 * time.Second, 30 * time.Second, 0},
+	}
+	for _, tc := range cases {
+		if err := validateKeepWarm(tc.interval, tc.soakInterval, tc.tokens); err == nil {
+			t.Errorf("validateKeepWarm(%s, %s, %d) succeeded, want error", tc.interval, tc.soakInterval, tc.tokens)
+		}
+	}
+}
+
+func TestSendKeepWarm(t *testing.T) {
+	tke := testTokenizer(t)
+	server := mockSSEServer{chunks: []string{"OK"}}.start(t)
+	defer server.Close()
+	if err := sendKeepWarm(context.Background(), ProviderConfig{Name: "mock", BaseURL: server.URL, APIKey: "k", Model: "m"}, tke, 1); err != nil {
+		t.Fatalf("sendKeepWarm failed: %v", err)
+	}
+
+	down := mockSSEServer{unavailable: 1}.start(t)
+	defer down.Close()
+	if err := sendKeepWarm(context.Background(), ProviderConfig{Name: "down", BaseURL: down.URL, APIKey: "k", Model: "m"}, tke, 1); err == nil {
+		t.Error("sendKeepWarm succeeded against an unavailable endpoint")
+	}
+}
+
+func TestSoakKeepWarmIsCountedApart(t *testing.T) {
+	tke := testTokenizer(t)
+	server := mockSSEServer{chunks: []string{"Once ", "upon ", "a time."}}.start(t)
+	defer server.Close()
+
+	dir := t.TempDir()
+	providers := []ProviderConfig{{Name: "mock", BaseURL: server.URL, APIKey: "k", Model: "m"}}
+	opts := soakOptions{
+		duration: 350 * time.Millisecond, interval: 150 * time.Millisecond, checkpoint: time.Second,
+		keepWarm: 40 * time.Millisecond, keepWarmTokens: 1,
+	}
+	if err := runSoakTest(providers, tke, ModeStreaming, false, opts, dir, dir, "test"); err != nil {
+		t.Fatalf("runSoakTest failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "soak-summary.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary SoakSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatal(err)
+	}
+	p := summary.Providers[0]
+	if summary.KeepWarm != opts.keepWarm || p.KeepWarm < 2 || p.KeepWarmFailures != 0 {
+		t.Fatalf("unexpected keep-warm figures: every %s, sent %d, failed %d", summary.KeepWarm, p.KeepWarm, p.KeepWarmFailures)
+	}
+	// Measured requests go out once per interval, however many keep-warms ran
+	if p.Requests > 4 {
+		t.Errorf("keep-warm requests leaked into the measured count: %d requests", p.Requests)
+	}
+	report, err := os.ReadFile(filepath.Join(dir, "SOAK-REPORT.md"))
+	if err != nil || !strings.Contains(string(report), "**Kept warm:**") || !strings.Contains(string(report), "## Keep-Warm Requests") {
+		t.Errorf("report does not describe keep-warm: %v", err)
+	}
+}
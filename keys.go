@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// API key rotation strategies for providers configured with several keys.
+const (
+	keyRotationRoundRobin = "round-robin"
+	keyRotationPerWorker  = "per-worker"
+)
+
+// keyRotation is the strategy selected with --key-rotation.
+var keyRotation = keyRotationRoundRobin
+
+// keyCounters holds the round-robin position of each provider label, the
+// same keying as the key stats, so each tagged copy of a provider rotates
+// through its keys on its own and its stats match its rotation.
+var keyCounters sync.Map
+
+// envAPIKeys reads a comma-separated key list from name, falling back to the
+// single key when the list is not set.
+func envAPIKeys(name, single string) []string {
+	var keys []string
+	for _, k := range strings.Split(os.Getenv(name), ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 && single != "" {
+		keys = []string{single}
+	}
+	return keys
+}
+
+// forWorker pins a worker to one key under per-worker rotation, so each key
+// carries a steady share of the load. Otherwise config is returned unchanged.
+func (c ProviderConfig) forWorker(worker int) ProviderConfig {
+	if keyRotation != keyRotationPerWorker || len(c.APIKeys) < 2 {
+		return c
+	}
+	key := c.APIKeys[(max(worker, 1)-1)%len(c.APIKeys)]
+	c.APIKey = key
+	c.APIKeys = []string{key}
+	return c
+}
+
+// nextAPIKey picks the key for one request, cycling through the provider's keys.
+func nextAPIKey(c ProviderConfig) ProviderConfig {
+	if len(c.APIKeys) < 2 {
+		return c
+	}
+	counter, _ := keyCounters.LoadOrStore(providerLabel(c.Name, c.Env), new(atomic.Uint64))
+	n := counter.(*atomic.Uint64).Add(1) - 1
+	c.APIKey = c.APIKeys[n%uint64(len(c.APIKeys))]
+	return c
+}
+
+// maskKey identifies a key in reports by its last four characters only.
+func maskKey(key string) string {
+	if len(key) <= 4 {
+		return "…" + strings.Repeat("*", len(key))
+	}
+	return "…" + key[len(key)-4:]
+}
+
+// isRateLimited reports whether err is an HTTP 429 from the provider.
+func isRateLimited(err error) bool {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPStatusCode == http.StatusTooManyRequests
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.HTTPStatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// KeyStat counts the requests sent with one API key and, when the provider
+// reports rate-limit headers, the lowest remaining quota seen for it.
+type KeyStat struct {
+	Key          string `json:"key"`
+	Requests     int    `json:"requests"`
+	Failed       int    `json:"failed"`
+	RateLimited  int    `json:"rateLimited"`
+	OutputTokens int    `json:"outputTokens"`
+
+	LimitRequests           int `json:"limitRequests,omitempty"`
+	LimitTokens             int `json:"limitTokens,omitempty"`
+	LowestRemainingRequests int `json:"lowestRemainingRequests,omitempty"`
+	LowestRemainingTokens   int `json:"lowestRemainingTokens,omitempty"`
+
+	// warned is set once the near-limit warning has been logged for this key.
+	warned bool
+}
+
+// keyTracker counts requests per provider label and masked key.
+type keyTracker struct {
+	mu        sync.Mutex
+	providers map[string]map[string]*KeyStat
+}
+
+// sessionKeys is the key tracker shared by every provider in the session.
+var sessionKeys = &keyTracker{providers: make(map[string]map[string]*KeyStat)}
+
+// entry returns the counters of config's current key; t.mu must be held.
+func (t *keyTracker) entry(config ProviderConfig) *KeyStat {
+	provider := providerLabel(config.Name, config.Env)
+	keys, ok := t.providers[provider]
+	if !ok {
+		keys = make(map[string]*KeyStat)
+		t.providers[provider] = keys
+	}
+	label := maskKey(config.APIKey)
+	stat, ok := keys[label]
+	if !ok {
+		stat = &KeyStat{Key: label}
+		keys[label] = stat
+	}
+	return stat
+}
+
+// record counts one finished request and the completion tokens it consumed.
+func (t *keyTracker) record(config ProviderConfig, tokens int, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stat := t.entry(config)
+	stat.Requests++
+	stat.OutputTokens += tokens
+	if err != nil {
+		stat.Failed++
+		if isRateLimited(err) {
+			stat.RateLimited++
+		}
+	}
+}
+
+// stats returns the per-key counts for provider, or nil unless more than one key
+// was used or the provider reported its quota.
+func (t *keyTracker) stats(provider string) []KeyStat {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	keys := t.providers[provider]
+	stats := make([]KeyStat, 0, len(keys))
+	quota := false
+	for _, s := range keys {
+		stats = append(stats, *s)
+		quota = quota || s.quotaReported()
+	}
+	if len(stats) < 2 && !quota {
+		return nil
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Key < stats[j].Key })
+	return stats
+}
+
+// keyStatsRow is one provider's entry in the key usage table.
+type keyStatsRow struct {
+	provider string
+	stats    []KeyStat
+}
+
+// writeKeyStatsRows renders per-key request counts, so a throttled key stands out.
+func writeKeyStatsRows(report *strings.Builder, rows []keyStatsRow) {
+	if len(rows) == 0 {
+		return
+	}
+	report.WriteString("## API Key Usage\n\n")
+	fmt.Fprintf(report, "Requests per API key (%s rotation); keys are identified by their last four characters.\n\n", keyRotation)
+	report.WriteString("| Provider | Key | Requests | Failed | Rate-Limited (429) |\n")
+	report.WriteString("|----------|-----|----------|--------|--------------------|\n")
+	for _, r := range rows {
+		for _, s := range r.stats {
+			fmt.Fprintf(report, "| %s | `%s` | %d | %d | %d |\n", r.provider, s.Key, s.Requests, s.Failed, s.RateLimited)
+		}
+	}
+	report.WriteString("\n")
+}
+
+// writeKeyStatsSection adds the key usage table for results that used several keys.
+func writeKeyStatsSection(report *strings.Builder, results []TestResult) {
+	rows := make([]keyStatsRow, 0, len(results))
+	for _, r := range results {
+		if len(r.KeyStats) > 1 {
+			rows = append(rows, keyStatsRow{providerLabel(r.Provider, r.Env), r.KeyStats})
+		}
+	}
+	writeKeyStatsRows(report, rows)
+}
+
+// writeDiagnosticKeyStatsSection is the diagnostic-report counterpart of
+// writeKeyStatsSection.
+func writeDiagnosticKeyStatsSection(report *strings.Builder, results []DiagnosticSummary) {
+	rows := make([]keyStatsRow, 0, len(results))
+	for _, r := range results {
+		if len(r.KeyStats) > 1 {
+			rows = append(rows, keyStatsRow{providerLabel(r.Provider, r.Env), r.KeyStats})
+		}
+	}
+	writeKeyStatsRows(report, rows)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestKeyRotation(t *testing.T) {
+	config := ProviderConfig{Name: "rotation-test", APIKeys: []string{"key-a", "key-b", "key-c"}}
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, nextAPIKey(config).APIKey)
+	}
+	if want := "key-a key-b key-c key-a"; strings.Join(got, " ") != want {
+		t.Errorf("round-robin keys = %v, want %s", got, want)
+	}
+
+	defer func(old string) { keyRotation = old }(keyRotation)
+	keyRotation = keyRotationPerWorker
+	worker := config.forWorker(5)
+	if worker.APIKey != "key-b" {
+		t.Errorf("worker 5 got %q, want key-b", worker.APIKey)
+	}
+	for i := 0; i < 3; i++ {
+		if key := nextAPIKey(worker).APIKey; key != "key-b" {
+			t.Errorf("pinned worker switched to %q", key)
+		}
+	}
+}
+
+func TestKeyTrackerCountsRateLimits(t *testing.T) {
+	tracker := &keyTracker{providers: make(map[string]map[string]*KeyStat)}
+	single := ProviderConfig{Name: "single", APIKey: "sk-only-1111"}
+	tracker.record(single, 10, nil)
+	if stats := tracker.stats("single"); stats != nil {
+		t.Errorf("single-key provider reported key stats: %+v", stats)
+	}
+
+	a := ProviderConfig{Name: "multi", APIKey: "sk-first-aaaa"}
+	b := ProviderConfig{Name: "multi", APIKey: "sk-second-bbbb"}
+	throttled := fmt.Errorf("error creating stream: %w", &openai.APIError{HTTPStatusCode: http.StatusTooManyRequests})
+	tracker.record(a, 10, nil)
+	tracker.record(b, 0, throttled)
+	tracker.record(b, 0, errors.New("timeout"))
+
+	stats := tracker.stats("multi")
+	want := []KeyStat{
+		{Key: "…aaaa", Requests: 1, OutputTokens: 10},
+		{Key: "…bbbb", Requests: 2, Failed: 2, RateLimited: 1},
+	}
+	if fmt.Sprint(stats) != fmt.Sprint(want) {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+
+	var report strings.Builder
+	writeKeyStatsSection(&report, []TestResult{{Provider: "multi", KeyStats: stats}})
+	if out := report.String(); !strings.Contains(out, "## API Key Usage") || strings.Contains(out, "sk-second") {
+		t.Errorf("unexpected key usage section:\n%s", out)
+	}
+}
+
+func TestNextAPIKeyRotatesPerLabel(t *testing.T) {
+	prod := ProviderConfig{Name: "rotating", Env: "prod", APIKeys: []string{"k1", "k2"}}
+	staging := prod
+	staging.Env = "staging"
+	if got := nextAPIKey(prod).APIKey; got != "k1" {
+		t.Errorf("first prod key = %s, want k1", got)
+	}
+	if got := nextAPIKey(staging).APIKey; got != "k1" {
+		t.Errorf("staging should rotate on its own, got %s", got)
+	}
+	if got := nextAPIKey(prod).APIKey; got != "k2" {
+		t.Errorf("second prod key = %s, want k2", got)
+	}
+}
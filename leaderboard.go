@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// leaderboardFileName is the cross-session leaderboard kept at the results root.
+const leaderboardFileName = "LEADERBOARD.md"
+
+// leaderboardEntry aggregates one provider, model and mode across sessions.
+type leaderboardEntry struct {
+	provider, model, mode string
+	sessions              map[string]bool
+	ttfts                 []time.Duration
+	throughputs           []float64
+	runs, failed          int
+	lastUpdated           time.Time
+}
+
+// storedSession is the results of one session folder.
+type storedSession struct {
+	name        string
+	results     []TestResult
+	diagnostics []DiagnosticSummary
+}
+
+// loadStoredSessions reads every session-* folder under resultsRoot that holds
+// results. Unreadable sessions are skipped with a warning.
+func loadStoredSessions(resultsRoot string) ([]storedSession, error) {
+	dirs, err := filepath.Glob(filepath.Join(resultsRoot, "session-*"))
+	if err != nil {
+		return nil, fmt.Errorf("error listing sessions: %w", err)
+	}
+	var sessions []storedSession
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		results, diagnostics, err := loadSessionResults(dir)
+		if err != nil {
+			log.Printf("Warning: skipping %s: %v", dir, err)
+			continue
+		}
+		if len(results)+len(diagnostics) > 0 {
+			sessions = append(sessions, storedSession{filepath.Base(dir), results, diagnostics})
+		}
+	}
+	return sessions, nil
+}
+
+// collectLeaderboard folds stored sessions into one entry per provider label,
+// model and mode. A standard result contributes one sample, a diagnostic
+// summary its averages.
+func collectLeaderboard(sessions []storedSession) []*leaderboardEntry {
+	entries := make(map[string]*leaderboardEntry)
+	get := func(provider, env, model, mode string) *leaderboardEntry {
+		label := providerLabel(provider, env)
+		key := label + "\x00" + model + "\x00" + mode
+		e, ok := entries[key]
+		if !ok {
+			e = &leaderboardEntry{provider: label, model: model, mode: mode, sessions: make(map[string]bool)}
+			entries[key] = e
+		}
+		return e
+	}
+
+	for _, stored := range sessions {
+		for _, r := range stored.results {
+			e := get(r.Provider, r.Env, r.Model, r.Mode)
+			e.sessions[stored.name] = true
+			e.runs++
+			if r.Timestamp.After(e.lastUpdated) {
+				e.lastUpdated = r.Timestamp
+			}
+			if !r.Success {
+				e.failed++
+				continue
+			}
+			e.ttfts = append(e.ttfts, r.TTFT)
+			e.throughputs = append(e.throughputs, r.Throughput)
+		}
+		for _, d := range stored.diagnostics {
+			e := get(d.Provider, d.Env, d.Model, d.Mode)
+			e.sessions[stored.name] = true
+			e.runs += d.TotalRequests
+			e.failed += d.Failed
+			if d.Timestamp.After(e.lastUpdated) {
+				e.lastUpdated = d.Timestamp
+			}
+			if d.Successful > 0 {
+				e.ttfts = append(e.ttfts, d.AvgTTFT)
+				e.throughputs = append(e.throughputs, d.AvgThroughput)
+			}
+		}
+	}
+
+	list := make([]*leaderboardEntry, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, e)
+	}
+	// Group by mode, then rank by median throughput
+	sort.Slice(list, func(a, b int) bool {
+		if list[a].mode != list[b].mode {
+			return list[a].mode < list[b].mode
+		}
+		ma, mb := percentileFloat(list[a].throughputs, 50), percentileFloat(list[b].throughputs, 50)
+		if ma != mb {
+			return ma > mb
+		}
+		return list[a].provider < list[b].provider
+	})
+	return list
+}
+
+// writeLeaderboard renders the leaderboard, one table per mode.
+func writeLeaderboard(report *strings.Builder, entries []*leaderboardEntry, sessions int, now time.Time) {
+	report.WriteString("# LLM API Leaderboard\n\n")
+	fmt.Fprintf(report, "Aggregated across %d session(s). Regenerated after every run; last updated %s.\n\n",
+		sessions, now.Format("2006-01-02 15:04:05"))
+	report.WriteString("Best and median are taken over every successful result: one per standard run, the averages of each diagnostic run.\n\n")
+
+	mode := ""
+	for _, e := range entries {
+		if e.mode != mode {
+			if mode != "" {
+				report.WriteString("\n")
+			}
+			mode = e.mode
+			fmt.Fprintf(report, "## %s\n\n", mode)
+			report.WriteString("| Rank | Provider | Model | Sessions | Success Rate | Best TTFT | Median TTFT | Best Throughput | Median Throughput | Last Updated |\n")
+			report.WriteString("|------|----------|-------|----------|--------------|-----------|-------------|-----------------|-------------------|--------------|\n")
+		}
+		rank := "-"
+		bestTTFT, medianTTFT, bestTP, medianTP := "-", "-", "-", "-"
+		if len(e.throughputs) > 0 {
+			rank = fmt.Sprintf("%d", rankInMode(entries, e))
+			bestTTFT = formatDuration(percentileDuration(e.ttfts, 0))
+			medianTTFT = formatDuration(percentileDuration(e.ttfts, 50))
+			bestTP = fmt.Sprintf("%.2f tok/s", percentileFloat(e.throughputs, 100))
+			medianTP = fmt.Sprintf("%.2f tok/s", percentileFloat(e.throughputs, 50))
+		}
+		successRate := "-"
+		if e.runs > 0 {
+			successRate = fmt.Sprintf("%.1f%%", 100*float64(e.runs-e.failed)/float64(e.runs))
+		}
+		fmt.Fprintf(report, "| %s | %s | %s | %d | %s | %s | %s | %s | %s | %s |\n",
+			rank, e.provider, e.model, len(e.sessions), successRate, bestTTFT, medianTTFT, bestTP, medianTP,
+			e.lastUpdated.Format("2006-01-02 15:04"))
+	}
+	if mode != "" {
+		report.WriteString("\n")
+	}
+}
+
+// rankInMode is e's 1-based position among the entries of its mode that have
+// successful results; entries are already sorted.
+func rankInMode(entries []*leaderboardEntry, e *leaderboardEntry) int {
+	rank := 0
+	for _, other := range entries {
+		if other.mode != e.mode || len(other.throughputs) == 0 {
+			continue
+		}
+		rank++
+		if other == e {
+			break
+		}
+	}
+	return rank
+}
+
+// updateLeaderboard regenerates LEADERBOARD.md and the trend charts at
+// resultsRoot from every session stored there.
+func updateLeaderboard(resultsRoot string) error {
+	sessions, err := loadStoredSessions(resultsRoot)
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		return nil
+	}
+	now := time.Now()
+	charts, err := writeTrendCharts(resultsRoot, sessions, now)
+	if err != nil {
+		return err
+	}
+	var report strings.Builder
+	writeLeaderboard(&report, collectLeaderboard(sessions), len(sessions), now)
+	writeTrendSection(&report, charts)
+	filename := filepath.Join(resultsRoot, leaderboardFileName)
+	if err := os.WriteFile(filename, []byte(report.String()), 0600); err != nil {
+		return fmt.Errorf("error writing leaderboard: %w", err)
+	}
+	log.Printf("Leaderboard updated: %s", filename)
+	return nil
+}
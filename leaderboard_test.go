@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUpdateLeaderboardAggregatesSessions(t *testing.T) {
+	root := t.TempDir()
+	first := filepath.Join(root, "session-20250101-000000")
+	second := filepath.Join(root, "session-20250102-000000")
+	for _, dir := range []string{first, second} {
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Not a session folder, so it must be ignored
+	if err := os.MkdirAll(filepath.Join(root, "merged-20250103-000000"), 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	result := func(provider string, day int, ttft time.Duration, throughput float64) TestResult {
+		return TestResult{
+			Provider: provider, Model: "model-a", Mode: string(ModeStreaming), Success: true,
+			Timestamp: time.Date(2025, 1, day, 12, 0, 0, 0, time.UTC), TTFT: ttft, E2ELatency: time.Second, Throughput: throughput,
+		}
+	}
+	saveResult(first, result("nim", 1, 300*time.Millisecond, 80))
+	saveResult(first, result("novita", 1, 500*time.Millisecond, 40))
+	saveResult(second, result("nim", 2, 200*time.Millisecond, 100))
+	saveResult(second, TestResult{Provider: "novita", Model: "model-a", Mode: string(ModeStreaming),
+		Timestamp: time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC), Error: "boom"})
+
+	if err := updateLeaderboard(root); err != nil {
+		t.Fatalf("updateLeaderboard: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, leaderboardFileName))
+	if err != nil {
+		t.Fatalf("leaderboard not written: %v", err)
+	}
+	board := string(data)
+	for _, want := range []string{
+		"Aggregated across 2 session(s)",
+		"## streaming",
+		"| 1 | nim | model-a | 2 | 100.0% | 0.200s | 0.200s | 100.00 tok/s | 80.00 tok/s | 2025-01-02 12:00 |",
+		"| 2 | novita | model-a | 2 | 50.0% | 0.500s | 0.500s | 40.00 tok/s | 40.00 tok/s | 2025-01-02 12:00 |",
+	} {
+		if !strings.Contains(board, want) {
+			t.Errorf("leaderboard missing %q:\n%s", want, board)
+		}
+	}
+}
+
+func TestUpdateLeaderboardWithoutSessions(t *testing.T) {
+	root := t.TempDir()
+	if err := updateLeaderboard(root); err != nil {
+		t.Fatalf("updateLeaderboard: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, leaderboardFileName)); err == nil {
+		t.Error("leaderboard written with no sessions")
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// proxyLiteLLM is the ProviderConfig.Proxy of providers behind a LiteLLM proxy.
+const proxyLiteLLM = "litellm"
+
+// proxies lists the accepted <PREFIX>_PROXY values.
+var proxies = []string{proxyLiteLLM}
+
+// Response headers LiteLLM adds to every proxied request. For streams the
+// duration runs until the headers were sent, i.e. the upstream first chunk.
+const (
+	liteLLMCallID    = "x-litellm-call-id"
+	liteLLMModelID   = "x-litellm-model-id"
+	liteLLMAPIBase   = "x-litellm-model-api-base"
+	liteLLMVersion   = "x-litellm-version"
+	liteLLMCost      = "x-litellm-response-cost"
+	liteLLMDuration  = "x-litellm-response-duration-ms"
+	liteLLMOverhead  = "x-litellm-overhead-duration-ms"
+	liteLLMRetries   = "x-litellm-attempted-retries"
+	liteLLMFallbacks = "x-litellm-attempted-fallbacks"
+)
+
+// LiteLLMDeployment is one upstream deployment a model alias was routed to.
+type LiteLLMDeployment struct {
+	APIBase  string `json:"apiBase,omitempty"`
+	ModelID  string `json:"modelId,omitempty"`
+	Requests int    `json:"requests"`
+}
+
+// name identifies the deployment in reports by its upstream host.
+func (d LiteLLMDeployment) name() string {
+	if u, err := url.Parse(d.APIBase); err == nil && u.Host != "" {
+		return u.Host
+	}
+	if d.APIBase != "" {
+		return d.APIBase
+	}
+	if d.ModelID != "" {
+		return d.ModelID
+	}
+	return "unknown"
+}
+
+// LiteLLMStats is what a LiteLLM proxy reported about the requests it served,
+// from its response headers.
+type LiteLLMStats struct {
+	Requests    int                 `json:"requests"`
+	Version     string              `json:"version,omitempty"`
+	Deployments []LiteLLMDeployment `json:"deployments,omitempty"`
+	// Cost is the sum of the per-request costs in USD the proxy computed.
+	Cost float64 `json:"cost,omitempty"`
+	// AvgResponse is the proxy's own request duration; AvgOverhead the part it
+	// spent outside the upstream call (auth, routing, logging).
+	AvgResponse time.Duration `json:"avgResponseMs,omitempty"`
+	AvgOverhead time.Duration `json:"avgOverheadMs,omitempty"`
+	Retries     int           `json:"retries,omitempty"`
+	Fallbacks   int           `json:"fallbacks,omitempty"`
+}
+
+// liteLLMTally accumulates the headers of one provider's responses.
+type liteLLMTally struct {
+	stats                    LiteLLMStats
+	deployments              map[LiteLLMDeployment]int
+	responseSum, overheadSum time.Duration
+	responses, overheads     int
+}
+
+// liteLLMTracker records LiteLLM response headers per provider.
+type liteLLMTracker struct {
+	mu        sync.Mutex
+	providers map[string]*liteLLMTally
+}
+
+// sessionLiteLLM is the LiteLLM tracker shared by every provider in the session.
+var sessionLiteLLM = &liteLLMTracker{providers: make(map[string]*liteLLMTally)}
+
+// headerMillis parses a millisecond header value.
+func headerMillis(h http.Header, name string) (time.Duration, bool) {
+	ms, err := strconv.ParseFloat(h.Get(name), 64)
+	if err != nil || ms < 0 {
+		return 0, false
+	}
+	return time.Duration(ms * float64(time.Millisecond)), true
+}
+
+// observe records the headers of one response; responses that did not come
+// through LiteLLM are ignored.
+func (t *liteLLMTracker) observe(provider string, h http.Header) {
+	if h.Get(liteLLMCallID) == "" && h.Get(liteLLMModelID) == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tally, ok := t.providers[provider]
+	if !ok {
+		tally = &liteLLMTally{deployments: make(map[LiteLLMDeployment]int)}
+		t.providers[provider] = tally
+	}
+	s := &tally.stats
+	s.Requests++
+	if v := h.Get(liteLLMVersion); v != "" {
+		s.Version = v
+	}
+	tally.deployments[LiteLLMDeployment{APIBase: h.Get(liteLLMAPIBase), ModelID: h.Get(liteLLMModelID)}]++
+	if cost, err := strconv.ParseFloat(h.Get(liteLLMCost), 64); err == nil {
+		s.Cost += cost
+	}
+	if d, ok := headerMillis(h, liteLLMDuration); ok {
+		tally.responseSum += d
+		tally.responses++
+	}
+	if d, ok := headerMillis(h, liteLLMOverhead); ok {
+		tally.overheadSum += d
+		tally.overheads++
+	}
+	if n, err := strconv.Atoi(h.Get(liteLLMRetries)); err == nil {
+		s.Retries += n
+	}
+	if n, err := strconv.Atoi(h.Get(liteLLMFallbacks)); err == nil {
+		s.Fallbacks += n
+	}
+}
+
+// stats returns the summary for provider, or nil if no response carried
+// LiteLLM headers.
+func (t *liteLLMTracker) stats(provider string) *LiteLLMStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tally, ok := t.providers[provider]
+	if !ok {
+		return nil
+	}
+	s := tally.stats
+	if tally.responses > 0 {
+		s.AvgResponse = tally.responseSum / time.Duration(tally.responses)
+	}
+	if tally.overheads > 0 {
+		s.AvgOverhead = tally.overheadSum / time.Duration(tally.overheads)
+	}
+	s.Deployments = make([]LiteLLMDeployment, 0, len(tally.deployments))
+	for d, n := range tally.deployments {
+		d.Requests = n
+		s.Deployments = append(s.Deployments, d)
+	}
+	sort.Slice(s.Deployments, func(i, j int) bool {
+		a, b := s.Deployments[i], s.Deployments[j]
+		if a.Requests != b.Requests {
+			return a.Requests > b.Requests
+		}
+		return a.APIBase+a.ModelID < b.APIBase+b.ModelID
+	})
+	return &s
+}
+
+// liteLLMTransport records the LiteLLM headers of every response.
+type liteLLMTransport struct {
+	base     http.RoundTripper
+	provider string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *liteLLMTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		sessionLiteLLM.observe(t.provider, resp.Header)
+	}
+	return resp, err
+}
+
+// liteLLMRow is one entry of the LiteLLM table.
+type liteLLMRow struct {
+	provider string
+	mode     string
+	ttft     time.Duration
+	stats    *LiteLLMStats
+	server   *ServerMetrics
+}
+
+// formatDeployments lists the deployments with their request counts.
+func formatDeployments(deployments []LiteLLMDeployment) string {
+	if len(deployments) == 0 {
+		return "-"
+	}
+	names := make([]string, len(deployments))
+	for i, d := range deployments {
+		names[i] = fmt.Sprintf("%s ×%d", d.name(), d.Requests)
+	}
+	return strings.Join(names, ", ")
+}
+
+// writeLiteLLMRows renders what the proxy reported next to the client view.
+func writeLiteLLMRows(report *strings.Builder, rows []liteLLMRow) {
+	if len(rows) == 0 {
+		return
+	}
+	report.WriteString("## LiteLLM Proxy\n\n")
+	report.WriteString("What the LiteLLM proxy reported in its response headers: the deployments each model alias was routed to, " +
+		"its time to response headers (the upstream first chunk for streams), its own overhead, retries, fallbacks and cost. " +
+		"With `<PREFIX>_METRICS_URL` set, the spend and upstream latencies scraped from the proxy's Prometheus endpoint are shown too; " +
+		"they include any other traffic on the proxy. Client TTFT well above the proxy's response time points at the network between client and proxy.\n\n")
+	report.WriteString("| Provider | Mode | Deployments | Client TTFT | Proxy Response | Proxy Overhead | Retries | Fallbacks | Cost | Scraped Spend | Upstream TTFT | Upstream Latency |\n")
+	report.WriteString("|----------|------|-------------|-------------|----------------|----------------|---------|-----------|------|---------------|---------------|------------------|\n")
+	for _, r := range rows {
+		deployments, response, overhead, retries, fallbacks, cost := "-", "-", "-", "-", "-", "-"
+		if s := r.stats; s != nil {
+			deployments = formatDeployments(s.Deployments)
+			response, overhead = formatDurationOrNA(s.AvgResponse), formatDurationOrNA(s.AvgOverhead)
+			retries, fallbacks = strconv.Itoa(s.Retries), strconv.Itoa(s.Fallbacks)
+			cost = fmt.Sprintf("$%.4f", s.Cost)
+		}
+		spend, upstreamTTFT, upstreamLatency := "-", "-", "-"
+		if m := r.server; m != nil && m.Server == proxyLiteLLM {
+			spend = fmt.Sprintf("$%.4f", m.Spend)
+			upstreamTTFT, upstreamLatency = formatDurationOrNA(m.UpstreamTTFT), formatDurationOrNA(m.UpstreamLatency)
+		}
+		fmt.Fprintf(report, "| %s | %s | %s | %s | %s | %s | %s | %s | %s | %s | %s | %s |\n",
+			r.provider, r.mode, deployments, formatDurationOrNA(r.ttft), response, overhead,
+			retries, fallbacks, cost, spend, upstreamTTFT, upstreamLatency)
+	}
+	report.WriteString("\n")
+}
+
+// hasLiteLLM reports whether a result has anything for the LiteLLM table.
+func hasLiteLLM(stats *LiteLLMStats, server *ServerMetrics) bool {
+	return stats != nil || (server != nil && server.Server == proxyLiteLLM)
+}
+
+// writeLiteLLMSection adds the LiteLLM table for results served through the
+// proxy.
+func writeLiteLLMSection(report *strings.Builder, results []TestResult) {
+	rows := make([]liteLLMRow, 0, len(results))
+	for _, r := range results {
+		if hasLiteLLM(r.LiteLLM, r.ServerMetrics) {
+			rows = append(rows, liteLLMRow{providerLabel(r.Provider, r.Env), r.Mode, r.TTFT, r.LiteLLM, r.ServerMetrics})
+		}
+	}
+	writeLiteLLMRows(report, rows)
+}
+
+// writeDiagnosticLiteLLMSection is the diagnostic-report counterpart of
+// writeLiteLLMSection.
+func writeDiagnosticLiteLLMSection(report *strings.Builder, results []DiagnosticSummary) {
+	rows := make([]liteLLMRow, 0, len(results))
+	for _, r := range results {
+		if hasLiteLLM(r.LiteLLM, r.ServerMetrics) {
+			rows = append(rows, liteLLMRow{providerLabel(r.Provider, r.Env), r.Mode, r.AvgTTFT, r.LiteLLM, r.ServerMetrics})
+		}
+	}
+	writeLiteLLMRows(report, rows)
+}
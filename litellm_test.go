@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLiteLLMTransportRecordsHeaders(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		if n == 4 {
+			w.WriteHeader(http.StatusNotFound) // not proxied, no headers
+			return
+		}
+		w.Header().Set(liteLLMCallID, fmt.Sprintf("call-%d", n))
+		w.Header().Set(liteLLMVersion, "1.74.0")
+		w.Header().Set(liteLLMModelID, "abc")
+		w.Header().Set(liteLLMAPIBase, "https://api.groq.com/openai/v1")
+		if n == 3 {
+			w.Header().Set(liteLLMModelID, "def")
+			w.Header().Set(liteLLMAPIBase, "https://api.cerebras.ai/v1")
+			w.Header().Set(liteLLMFallbacks, "1")
+		}
+		w.Header().Set(liteLLMCost, "0.0005")
+		w.Header().Set(liteLLMDuration, fmt.Sprintf("%d", 100*n))
+		w.Header().Set(liteLLMOverhead, "4.5")
+		w.Header().Set(liteLLMRetries, "0")
+	}))
+	defer srv.Close()
+
+	client := providerHTTPClient(ProviderConfig{Name: "litellm-transport", BaseURL: srv.URL, Proxy: proxyLiteLLM})
+	for range 4 {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	stats := sessionLiteLLM.stats("litellm-transport")
+	if stats == nil || stats.Requests != 3 || stats.Version != "1.74.0" || stats.Fallbacks != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if stats.AvgResponse != 200*time.Millisecond || stats.AvgOverhead != 4500*time.Microsecond || fmt.Sprintf("%.4f", stats.Cost) != "0.0015" {
+		t.Errorf("unexpected averages: %+v", stats)
+	}
+	if got := formatDeployments(stats.Deployments); got != "api.groq.com ×2, api.cerebras.ai ×1" {
+		t.Errorf("deployments = %q", got)
+	}
+	if sessionLiteLLM.stats("missing") != nil {
+		t.Error("expected no stats for an unknown provider")
+	}
+}
+
+func TestLiteLLMScrapeAndSection(t *testing.T) {
+	var scrapes atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := scrapes.Add(1)
+		// Each scrape adds one upstream call of 2s with a 0.5s TTFT and $0.01
+		fmt.Fprintf(w, "litellm_spend_metric_total{model=\"a\"} %.2f\n", 0.01*float64(n))
+		fmt.Fprintf(w, "litellm_llm_api_latency_metric_sum %d\nlitellm_llm_api_latency_metric_count %d\n", 2*n, n)
+		fmt.Fprintf(w, "litellm_llm_api_time_to_first_token_metric_sum %.1f\nlitellm_llm_api_time_to_first_token_metric_count %d\n", 0.5*float64(n), n)
+	}))
+	defer srv.Close()
+
+	defer func(old time.Duration) { serverScrapeInterval = old }(serverScrapeInterval)
+	serverScrapeInterval = 10 * time.Millisecond
+	scraper := startServerScrape(ProviderConfig{Name: "litellm", MetricsURL: srv.URL}, log.New(io.Discard, "", 0))
+	time.Sleep(35 * time.Millisecond)
+	metrics := scraper.finish()
+	if metrics == nil || metrics.Server != proxyLiteLLM || metrics.Samples < 2 {
+		t.Fatalf("unexpected metrics %+v", metrics)
+	}
+	if metrics.UpstreamLatency != 2*time.Second || metrics.UpstreamTTFT != 500*time.Millisecond || metrics.Spend <= 0 {
+		t.Errorf("unexpected run deltas: %+v", metrics)
+	}
+
+	var report strings.Builder
+	writeLiteLLMSection(&report, []TestResult{
+		{Provider: "litellm", Mode: "streaming", TTFT: 600 * time.Millisecond, ServerMetrics: metrics, LiteLLM: &LiteLLMStats{
+			Requests: 2, Deployments: []LiteLLMDeployment{{APIBase: "https://api.groq.com/openai/v1", Requests: 2}},
+			Cost: 0.02, AvgResponse: 550 * time.Millisecond, AvgOverhead: 5 * time.Millisecond,
+		}},
+		{Provider: "vllm", Mode: "streaming", ServerMetrics: &ServerMetrics{Server: "vllm"}},
+	})
+	out := report.String()
+	if !strings.Contains(out, "| litellm | streaming | api.groq.com ×2 | 0.600s | 0.550s | 0.005s | 0 | 0 | $0.0200 | $0.") ||
+		!strings.Contains(out, "| 0.500s | 2.000s |") {
+		t.Errorf("unexpected section:\n%s", out)
+	}
+	if strings.Contains(out, "vllm") {
+		t.Error("results not served through LiteLLM must be skipped")
+	}
+}
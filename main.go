@@ -3,21 +3,21 @@ package main
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"log"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
-	"sync" // Added for concurrent testing
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/lamim/llm-api-speed/pkg/benchmark"
+	mdreport "github.com/lamim/llm-api-speed/pkg/report"
 	"github.com/pkoukk/tiktoken-go"
-	openai "github.com/sashabaranov/go-openai"
 )
 
 // ProviderConfig holds all info for one API provider.
@@ -25,22 +25,108 @@ type ProviderConfig struct {
 	Name    string
 	BaseURL string
 	APIKey  string
+	// APIKeys lists every key when several are configured with <PREFIX>_API_KEYS;
+	// requests rotate through them according to --key-rotation.
+	APIKeys []string
 	Model   string
+	// Env tags the deployment environment (e.g. prod, staging) so the same model
+	// hosted in several places can be told apart in results.
+	Env string
+	// InputPrice and OutputPrice are USD per million tokens, used for cost estimates.
+	InputPrice  float64
+	OutputPrice float64
+	// Quirks adapts requests to provider-specific API deviations.
+	Quirks providerQuirks
+	// API is the wire protocol (see providers.APIs), from the preset, <PREFIX>_TYPE
+	// or the type key of [provider.<name>] in --config; empty means
+	// OpenAI-compatible.
+	API string
+	// MetricsURL is a self-hosted server's Prometheus endpoint (vLLM, TGI),
+	// scraped during runs when set.
+	MetricsURL string
+	// DialIP pins connections to one resolved address of BaseURL's host
+	// (--per-ip); the Host header and TLS SNI still use the hostname.
+	DialIP string
+	// IPVersion forces IPv4 ("4") or IPv6 ("6") connections (--ip-version).
+	IPVersion string
+	// HTTPVersion is "h3" for requests sent over HTTP/3 and "h2" for their
+	// HTTP/2 counterparts (--http3); empty uses the default transport.
+	HTTPVersion string
+	// ToolChoice is the tool_choice of tool-calling runs (--tool-choice): auto,
+	// required or a function name. Empty sends required.
+	ToolChoice string
+	// Profile is the request profile of streaming runs (--two-phase): latency
+	// or throughput. Empty sends the standard request.
+	Profile string
+	// ContextWindow is the model's context length in tokens, from
+	// <PREFIX>_CONTEXT_WINDOW; requests that would exceed it are caught before
+	// sending (see checkContextWindow). Zero skips the check.
+	ContextWindow int
+	// UserAgent overrides --user-agent for this provider, from
+	// <PREFIX>_USER_AGENT.
+	UserAgent string
+	// Proxy names the gateway requests go through (proxyLiteLLM), from the
+	// preset or <PREFIX>_PROXY; its response headers are then recorded.
+	Proxy string
+	// ColdStartHeader names the response header in which the provider reports a
+	// cold start, from <PREFIX>_COLD_START_HEADER (see instanceState).
+	ColdStartHeader string
+	// NoStreamUsage stops asking for usage in the stream, so tokens are only
+	// counted locally; set with <PREFIX>_STREAM_USAGE=false for servers that
+	// reject stream_options.
+	NoStreamUsage bool
 }
 
 // TestResult holds the benchmark results for a provider.
 type TestResult struct {
-	Provider         string        `json:"provider"`
-	Model            string        `json:"model"`
-	Timestamp        time.Time     `json:"timestamp"`
-	E2ELatency       time.Duration `json:"e2eLatencyMs"`
-	TTFT             time.Duration `json:"ttftMs"`
-	Throughput       float64       `json:"throughputTokensPerSec"`
-	CompletionTokens int           `json:"completionTokens"`
-	ProjectedE2E     time.Duration `json:"projectedE2eLatency,omitempty"`
-	Success          bool          `json:"success"`
-	Error            string        `json:"error,omitempty"`
-	Mode             string        `json:"mode"`
+	SessionID        string                `json:"sessionId,omitempty"`
+	Provider         string                `json:"provider"`
+	Model            string                `json:"model"`
+	Env              string                `json:"env,omitempty"`
+	IPVersion        string                `json:"ipVersion,omitempty"`
+	HTTPVersion      string                `json:"httpVersion,omitempty"`
+	ToolChoice       string                `json:"toolChoice,omitempty"`
+	Profile          string                `json:"profile,omitempty"`
+	Timestamp        time.Time             `json:"timestamp"`
+	E2ELatency       time.Duration         `json:"e2eLatencyMs"`
+	TTFT             time.Duration         `json:"ttftMs"`
+	Throughput       float64               `json:"throughputTokensPerSec"`
+	CompletionTokens int                   `json:"completionTokens"`
+	ProjectedE2E     time.Duration         `json:"projectedE2eLatency,omitempty"`
+	SecPer100Tokens  float64               `json:"secondsPer100Tokens,omitempty"`
+	NormalizedE2E    time.Duration         `json:"normalizedE2eLatency,omitempty"`
+	TTFTSpread       *DurationSpread       `json:"ttftSpread,omitempty"`
+	E2ESpread        *DurationSpread       `json:"e2eSpread,omitempty"`
+	ThroughputSpread *RateSpread           `json:"throughputSpread,omitempty"`
+	ChunkStats       *benchmark.ChunkStats `json:"chunkStats,omitempty"`
+	ThroughputCurve  []float64             `json:"throughputCurve,omitempty"`
+	ITL              *ITLStats             `json:"interTokenLatency,omitempty"`
+	ToolArgs         *ToolArgsStats        `json:"toolArgs,omitempty"`
+	KeyStats         []KeyStat             `json:"keyStats,omitempty"`
+	LiteLLM          *LiteLLMStats         `json:"litellm,omitempty"`
+	ServerMetrics    *ServerMetrics        `json:"serverMetrics,omitempty"`
+	NetworkBaseline  *NetworkBaseline      `json:"networkBaseline,omitempty"`
+	TokenCrossCheck  *TokenCrossCheck      `json:"tokenCrossCheck,omitempty"`
+	TokenCounts      *TokenCounts          `json:"tokenCounts,omitempty"`
+	Instances        []InstanceStats       `json:"instances,omitempty"`
+	Deadlines        *DeadlineStats        `json:"deadlineOverruns,omitempty"`
+	Success          bool                  `json:"success"`
+	Error            string                `json:"error,omitempty"`
+	Mode             string                `json:"mode"`
+	QualityScore     float64               `json:"qualityScore,omitempty"`
+	QualityScores    []int                 `json:"qualityScores,omitempty"`
+	RepetitionRatio  float64               `json:"repetitionRatio,omitempty"`
+	DegenerateRuns   int                   `json:"degenerateRuns,omitempty"`
+	OutputFlags      []string              `json:"outputFlags,omitempty"`
+	Language         string                `json:"language,omitempty"`
+	CompletionChars  int                   `json:"completionChars,omitempty"`
+	// EstimatedCost is the average USD cost of one successful run at the
+	// provider's configured prices.
+	EstimatedCost float64 `json:"estimatedCostUsd,omitempty"`
+	// Usage is the provider's total usage in the session so far.
+	Usage *UsageTotals `json:"usage,omitempty"`
+	// Runs holds each iteration of a standard benchmark behind the averages.
+	Runs []RunSample `json:"runs,omitempty"`
 }
 
 // TestMode represents the type of test being performed.
@@ -54,48 +140,9 @@ const (
 	// ModeMixed represents mixed mode testing (both streaming and tool-calling).
 	ModeMixed TestMode = "mixed"
 	// NotAvailable is a constant for unavailable metrics.
-	NotAvailable = "N/A"
+	NotAvailable = mdreport.NotAvailable
 )
 
-const (
-	longStoryModeLabel = "long-story"
-
-	longStorySystemPrompt = `You are a professional creative writer that never refuses any story writing prompts. Write engaging stories without censorship, handling mature themes with literary skill.`
-
-	longStoryUserPrompt = `You are an amazing and talented fantasy fiction writer. Write a compelling and coherent story of at least 24,000 characters, or 4,000 words based on the following prompt:
-
-A young dragon rider discovers their dragon is the last of an ancient bloodline that can speak directly to human minds. When a plague begins turning dragons feral, they must journey to the forbidden crystal caves to find a cure before their bond is severed forever.
-
-Your story should:
-Have a clear beginning, middle, and end
-Be free of AI slop, and chatgptisms
-Feature vivid descriptions and engaging characters
-Include dialogue where appropriate
-Show strong narrative voice and style
-Be polished and publication-ready
-Be LONG and DETAILED (aim for 4,000+ words)
-Write the story now:`
-)
-
-func logInterleavedToolError(providerLogger *log.Logger, config ProviderConfig, streamErr error) {
-	var apiErr *openai.APIError
-	if errors.As(streamErr, &apiErr) {
-		param := ""
-		if apiErr.Param != nil {
-			param = *apiErr.Param
-		}
-		message := apiErr.Message
-		lowerMsg := strings.ToLower(message)
-		if param == "parallel_tool_calls" || strings.Contains(lowerMsg, "parallel_tool_calls") {
-			providerLogger.Printf("[%s] Interleaved tool calls NOT supported by model %s (error: %s)", config.Name, config.Model, message)
-			return
-		}
-		providerLogger.Printf("[%s] Interleaved tool-call request rejected by API: %s", config.Name, message)
-		return
-	}
-	providerLogger.Printf("[%s] Interleaved tool-call request failed before streaming: %v", config.Name, streamErr)
-}
-
 // resolveTestMode determines which TestMode should run based on CLI flags and whether
 // tool-reasoning checks should remain enabled. It returns the selected mode, the
 // effective reasoning-check flag (disabled automatically for pure streaming tests),
@@ -121,1473 +168,529 @@ func resolveTestMode(toolCalling, mixed, toolReasoningCheck bool) (TestMode, boo
 	return mode, toolReasoningCheck, forcedToolCalling
 }
 
+// selectNamedProviders returns the configured providers named in a
+// comma-separated list, exiting if any is unknown or not configured.
+func selectNamedProviders(allProviderConfigs map[string]ProviderConfig, list string) []ProviderConfig {
+	var selected []ProviderConfig
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		config, ok := allProviderConfigs[name]
+		if !ok {
+			log.Fatalf("Error: Provider '%s' not recognized.", name)
+		}
+		if config.APIKey == "" || config.Model == "" {
+			log.Fatalf("Error: Provider '%s' is not configured. "+
+				"(Missing APIKey/Model in .env or --model flag for generic)", name)
+		}
+		selected = append(selected, config)
+	}
+	return selected
+}
+
 // formatDuration formats a duration as decimal seconds.
 func formatDuration(d time.Duration) string {
-	return fmt.Sprintf("%.3fs", d.Seconds())
+	return mdreport.Seconds(d)
 }
 
 var saveResponses bool
 var targetTokens int
-var maxTokens int
 
-// calculateProjectedE2E calculates the projected E2E latency for a normalized token count.
-// Formula: ProjectedE2E = TTFT + (TargetTokens / Throughput).
-func calculateProjectedE2E(ttft time.Duration, throughput float64, target int) time.Duration {
-	if throughput <= 0 || target <= 0 {
-		return 0
-	}
-	generationTime := float64(target) / throughput
-	return ttft + time.Duration(generationTime*float64(time.Second))
-}
+// maxTokens caps completion tokens per benchmark request; set with
+// --max-tokens. 0 keeps the mode's default.
+var maxTokens int
 
-// writeTestResultRow writes a single test result row to the report.
-func writeTestResultRow(report *strings.Builder, r TestResult, includeProjected bool) {
-	if includeProjected && r.ProjectedE2E > 0 {
-		fmt.Fprintf(report, "| %s | %s | %s | %s | %s | %.2f tok/s | %d | %s |\n",
-			r.Provider, r.Model, r.Mode,
-			formatDuration(r.E2ELatency), formatDuration(r.TTFT),
-			r.Throughput, r.CompletionTokens, formatDuration(r.ProjectedE2E))
-	} else {
-		fmt.Fprintf(report, "| %s | %s | %s | %s | %s | %.2f tok/s | %d |\n",
-			r.Provider, r.Model, r.Mode,
-			formatDuration(r.E2ELatency), formatDuration(r.TTFT),
-			r.Throughput, r.CompletionTokens)
-	}
-}
+// runTimeout is the deadline of a provider's benchmark runs; set with
+// --timeout. 0 keeps the mode's default.
+var runTimeout time.Duration
 
-// writeDiagnosticResultRow writes a single diagnostic result row to the report.
-func writeDiagnosticResultRow(report *strings.Builder, r DiagnosticSummary, includeProjected bool) {
-	successRate := fmt.Sprintf("%d/%d", r.Successful, r.TotalRequests)
-	failRate := fmt.Sprintf("%d", r.Failed)
-	avgE2E := NotAvailable
-	avgTTFT := NotAvailable
-	avgThroughput := NotAvailable
-	projectedE2E := NotAvailable
-
-	if r.Successful > 0 {
-		avgE2E = formatDuration(r.AvgE2ELatency)
-		avgTTFT = formatDuration(r.AvgTTFT)
-		avgThroughput = fmt.Sprintf("%.2f tok/s", r.AvgThroughput)
-		if r.ProjectedE2E > 0 {
-			projectedE2E = formatDuration(r.ProjectedE2E)
-		}
-	}
+// Per-mode defaults of --max-tokens and --timeout.
+const (
+	defaultMaxTokens          = 512
+	defaultLongStoryMaxTokens = 16384
+	defaultRunTimeout         = 5 * time.Minute
+	defaultLongStoryTimeout   = 10 * time.Minute
+)
 
-	if includeProjected {
-		fmt.Fprintf(report, "| %s | %s | %s | %d | %s | %s | %s | %s | %s | %s |\n",
-			r.Provider, r.Model, r.Mode, r.TotalRequests,
-			successRate, failRate, avgE2E, avgTTFT, avgThroughput, projectedE2E)
-	} else {
-		fmt.Fprintf(report, "| %s | %s | %s | %d | %s | %s | %s | %s | %s |\n",
-			r.Provider, r.Model, r.Mode, r.TotalRequests,
-			successRate, failRate, avgE2E, avgTTFT, avgThroughput)
+// requestMaxTokens returns --max-tokens, or modeDefault when it is not set.
+func requestMaxTokens(modeDefault int) int {
+	if maxTokens > 0 {
+		return maxTokens
 	}
+	return modeDefault
 }
 
-// writeProjectedE2ELeaderboard writes the projected E2E leaderboard section for TestResult.
-func writeProjectedE2ELeaderboard(report *strings.Builder, results []TestResult) {
-	// Sort by Projected E2E
-	for i := 0; i < len(results); i++ {
-		for j := i + 1; j < len(results); j++ {
-			if results[j].ProjectedE2E > 0 && results[i].ProjectedE2E > 0 &&
-				results[j].ProjectedE2E < results[i].ProjectedE2E {
-				results[i], results[j] = results[j], results[i]
-			}
-		}
-	}
-
-	report.WriteString("| Rank | Provider | Projected E2E | TTFT | Throughput |\n")
-	report.WriteString("|------|----------|---------------|------|------------|\n")
-
-	for i, r := range results {
-		if r.ProjectedE2E > 0 {
-			fmt.Fprintf(report, "| %d | %s | %s | %s | %.2f tok/s |\n",
-				i+1, r.Provider, formatDuration(r.ProjectedE2E),
-				formatDuration(r.TTFT), r.Throughput)
-		}
+// benchmarkTimeout returns --timeout, or modeDefault when it is not set.
+func benchmarkTimeout(modeDefault time.Duration) time.Duration {
+	if runTimeout > 0 {
+		return runTimeout
 	}
-	report.WriteString("\n")
+	return modeDefault
 }
 
-// writeProjectedE2EDiagnosticLeaderboard writes the projected E2E leaderboard section for DiagnosticSummary.
-func writeProjectedE2EDiagnosticLeaderboard(report *strings.Builder, results []DiagnosticSummary) {
-	// Sort by Projected E2E
-	for i := 0; i < len(results); i++ {
-		for j := i + 1; j < len(results); j++ {
-			if results[j].ProjectedE2E > 0 && results[i].ProjectedE2E > 0 &&
-				results[j].ProjectedE2E < results[i].ProjectedE2E {
-				results[i], results[j] = results[j], results[i]
-			}
-		}
-	}
-
-	report.WriteString("| Rank | Provider | Projected E2E | TTFT | Throughput | Success Rate |\n")
-	report.WriteString("|------|----------|---------------|------|------------|-------------|\n")
-
-	for i, r := range results {
-		if r.ProjectedE2E > 0 {
-			successRate := fmt.Sprintf("%.1f%%", 100.0*float64(r.Successful)/float64(r.TotalRequests))
-			fmt.Fprintf(report, "| %d | %s | %s | %s | %.2f tok/s | %s |\n",
-				i+1, r.Provider, formatDuration(r.ProjectedE2E),
-				formatDuration(r.AvgTTFT), r.AvgThroughput, successRate)
+// flagPassed reports whether the named flag was set on the command line, so a
+// config file default applies only when it was not.
+func flagPassed(name string) bool {
+	passed := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			passed = true
 		}
-	}
-	report.WriteString("\n")
+	})
+	return passed
 }
 
-// writeTestResultLeaderboards writes all leaderboard sections for TestResult.
-func writeTestResultLeaderboards(report *strings.Builder, results []TestResult) {
-	report.WriteString("## Performance Leaderboard\n\n")
-	report.WriteString("### By Throughput (Tokens/sec)\n\n")
-
-	// Sort by throughput
-	successfulResults := make([]TestResult, 0)
-	for _, r := range results {
-		if r.Success {
-			successfulResults = append(successfulResults, r)
-		}
-	}
-
-	// Simple bubble sort by throughput descending
-	for i := 0; i < len(successfulResults); i++ {
-		for j := i + 1; j < len(successfulResults); j++ {
-			if successfulResults[j].Throughput > successfulResults[i].Throughput {
-				successfulResults[i], successfulResults[j] = successfulResults[j], successfulResults[i]
-			}
-		}
-	}
-
-	report.WriteString("| Rank | Provider | Throughput | TTFT | E2E Latency |\n")
-	report.WriteString("|------|----------|------------|------|-------------|\n")
-
-	for i, r := range successfulResults {
-		fmt.Fprintf(report, "| %d | %s | %.2f tok/s | %s | %s |\n",
-			i+1, r.Provider, r.Throughput,
-			formatDuration(r.TTFT), formatDuration(r.E2ELatency))
-	}
-	report.WriteString("\n")
-
-	// Sort by TTFT
-	report.WriteString("### By Time to First Token (TTFT)\n\n")
-
-	for i := 0; i < len(successfulResults); i++ {
-		for j := i + 1; j < len(successfulResults); j++ {
-			if successfulResults[j].TTFT < successfulResults[i].TTFT {
-				successfulResults[i], successfulResults[j] = successfulResults[j], successfulResults[i]
-			}
-		}
-	}
-
-	report.WriteString("| Rank | Provider | TTFT | Throughput | E2E Latency |\n")
-	report.WriteString("|------|----------|------|------------|-------------|\n")
-
-	for i, r := range successfulResults {
-		fmt.Fprintf(report, "| %d | %s | %s | %.2f tok/s | %s |\n",
-			i+1, r.Provider, formatDuration(r.TTFT),
-			r.Throughput, formatDuration(r.E2ELatency))
-	}
-	report.WriteString("\n")
-
-	// Sort by E2E Latency
-	report.WriteString("### By End-to-End Latency\n\n")
-
-	for i := 0; i < len(successfulResults); i++ {
-		for j := i + 1; j < len(successfulResults); j++ {
-			if successfulResults[j].E2ELatency < successfulResults[i].E2ELatency {
-				successfulResults[i], successfulResults[j] = successfulResults[j], successfulResults[i]
-			}
-		}
-	}
-
-	report.WriteString("| Rank | Provider | E2E Latency | TTFT | Throughput |\n")
-	report.WriteString("|------|----------|-------------|------|------------|\n")
-
-	for i, r := range successfulResults {
-		fmt.Fprintf(report, "| %d | %s | %s | %s | %.2f tok/s |\n",
-			i+1, r.Provider, formatDuration(r.E2ELatency),
-			formatDuration(r.TTFT), r.Throughput)
-	}
-	report.WriteString("\n")
-
-	// Sort by Projected E2E (if available)
-	if targetTokens > 0 {
-		fmt.Fprintf(report, "### By Projected E2E Latency (%d tokens)\n\n", targetTokens)
-		writeProjectedE2ELeaderboard(report, successfulResults)
+func main() {
+	// --- Define Provider static info ---
+	providerBaseURLs := map[string]string{
+		"generic": "https://openrouter.ai/api/v1", // Default, can be overridden by --url
+		"nim":     "https://integrate.api.nvidia.com/v1",
+		"nahcrof": "https://ai.nahcrof.com/v2",
+		"novita":  "https://api.novita.ai/openai",
+		"nebius":  "https://api.tokenfactory.nebius.com/v1",
+		"minimax": "https://api.minimax.io/v1",
 	}
-}
-
-// runStreamingChat executes a streaming chat completion request and computes metrics.
-func runStreamingChat(ctx context.Context, config ProviderConfig, tke *tiktoken.Tiktoken, providerLogger *log.Logger, req openai.ChatCompletionRequest) (e2e, ttft time.Duration, throughput float64, tokens int, response string, err error) {
-	clientConfig := openai.DefaultConfig(config.APIKey)
-	clientConfig.BaseURL = config.BaseURL
-	client := openai.NewClientWithConfig(clientConfig)
 
-	startTime := time.Now()
-	var firstTokenTime time.Time
-	var fullResponseContent strings.Builder
-
-	stream, streamErr := client.CreateChatCompletionStream(ctx, req)
-	if streamErr != nil {
-		return 0, 0, 0, 0, "", fmt.Errorf("error creating stream: %w", streamErr)
+	// 1. Load .env file (if it exists)
+	if err := godotenv.Load(); err != nil {
+		log.Println("Note: .env file not found, reading from system environment.")
 	}
-	defer func() {
-		if closeErr := stream.Close(); closeErr != nil {
-			providerLogger.Printf("[%s] Warning: Failed to close stream: %v", config.Name, closeErr)
-		}
-	}()
-
-	providerLogger.Printf("[%s] ... Request sent. Waiting for stream ...", config.Name)
-
-	chunkCount := 0
-	nonEmptyChunks := 0
-	reasoningChunks := 0
-
-	for {
-		response, recvErr := stream.Recv()
 
-		if errors.Is(recvErr, io.EOF) {
-			providerLogger.Printf("[%s] ... Stream complete. Received %d chunks (%d content, %d reasoning)",
-				config.Name, chunkCount, nonEmptyChunks, reasoningChunks)
-			break
-		}
-
-		if recvErr != nil {
-			if ctx.Err() == context.DeadlineExceeded {
-				return 0, 0, 0, 0, "", fmt.Errorf("timeout exceeded")
+	// 2. Parse Command-Line Flags
+	providerName := flag.String("provider", "",
+		"Specific provider to test (e.g., nim, novita, groq, cerebras). If empty, tests 'generic' provider.")
+	testAll := flag.Bool("all", false, "Test all configured providers concurrently.")
+	flagGenericURL := flag.String("url", "",
+		"Override Base URL for 'generic' provider (default: https://openrouter.ai/api/v1)")
+	flagGenericModel := flag.String("model", "",
+		"Model name for 'generic' provider (required if --provider is not set)")
+	toolCalling := flag.Bool("tool-calling", false, "Use tool calling mode instead of regular streaming")
+	mixed := flag.Bool("mixed", false, "Run both streaming and tool-calling modes (--iterations runs each)")
+	flagIterations := flag.Int("iterations", runIterations, "Runs per mode in a standard benchmark")
+	flagSequential := flag.Bool("sequential", false,
+		"Run a standard benchmark's iterations one at a time instead of concurrently, so they do not share a key's rate limit")
+	flagMaxInFlight := flag.Int("max-in-flight", 0,
+		"Most benchmark requests in flight across all providers; queued requests are admitted round-robin across providers (0: no limit)")
+	flagProviderMaxInFlight := flag.Int("provider-max-in-flight", 0, "Most benchmark requests in flight per provider (0: no limit)")
+	flagProviderRPS := flag.Float64("provider-rps", 0, "Most benchmark requests started per second per provider (0: no limit)")
+	flagWarmup := flag.Int("warmup", 0,
+		"Unmeasured warm-up requests per provider before its measured runs (default: test_params.warmup_requests, else 0)")
+	flagProgressJSON := flag.Bool("progress-json", false,
+		"Write NDJSON progress events (provider started, run finished, session done) to stdout; logs move to stderr")
+	flagDiagnosticWorkers := flag.Int("diagnostic-workers", diagnosticWorkers,
+		"Diagnostic mode: concurrent workers per provider")
+	flagDiagnosticAutoscale := flag.Bool("diagnostic-autoscale", false,
+		"Diagnostic mode: scale workers AIMD-style, up while requests succeed and halved on 429s or errors, to estimate capacity")
+	flagDiagnosticMaxWorkers := flag.Int("diagnostic-max-workers", diagnosticMaxWorkers,
+		"With --diagnostic-autoscale: the most workers per provider")
+	flagMaxParallelProviders := flag.Int("max-parallel-providers", maxParallelProviders,
+		"With --all and in diagnostic mode: benchmark at most N providers at once, queueing the rest (0 = all at once)")
+	diagnostic := flag.Bool("diagnostic", false,
+		"Run diagnostic mode: --diagnostic-workers workers making requests every 15s for 1 minute with 30s timeout")
+	longStory := flag.Bool("long-story", false, "Use long-form story generation scenario (single creative-writing prompt)")
+	flagToolReasoningCheck := flag.Bool("tool-reasoning-check", false,
+		"Enable tool+reasoning behavior checks (implies tool-calling if not otherwise set)")
+	flagSaveResponses := flag.Bool("save-responses", false, "Save all API responses to log files")
+	flagTargetTokens := flag.Int("target-tokens", 350,
+		"Target token count for projected E2E latency normalization (default: 350)")
+	flagMaxTokens := flag.Int("max-tokens", 0,
+		"Maximum completion tokens per benchmark request (0 = mode default: 512, or 16384 with --long-story)")
+	flagTimeout := flag.Duration("timeout", 0,
+		"Deadline of a provider's benchmark runs (0 = mode default: 5m, or 10m with --long-story)")
+	flagMinOutputTokens := flag.Int("min-output-tokens", 0,
+		"Reply \"continue\" to streaming responses shorter than this many tokens so runs compare similar output volumes (0 = off)")
+	flagMaxTotalTokens := flag.Int("max-total-tokens", 0,
+		"Session budget: skip remaining runs once this many prompt+completion tokens are used (0 = unlimited)")
+	flagMaxEstimatedCost := flag.Float64("max-estimated-cost", 0,
+		"Session budget: skip remaining runs once the estimated cost in USD reaches this value (0 = unlimited)")
+	flagOffline := flag.Bool("offline", false,
+		"Never download tokenizer files; fail fast unless they are in --tokenizer-dir or the local cache")
+	flagModelMetadata := flag.String("model-metadata", "",
+		"Annotate reports with model sizes from a JSON file of model IDs to {parameters, contextWindow, quantization}, or from \"openrouter\"'s model list")
+	flagTokenizerDir := flag.String("tokenizer-dir", "",
+		"Directory of bundled tokenizer files (created with 'tokenizer-bundle <dir>'), checked before downloading")
+	flagTokenCrossCheck := flag.String("tokenizer-cross-check", "",
+		"Also count completion tokens with this encoding (e.g. o200k_base) and report both, flagging models whose throughput depends on the choice")
+	flagTokenCrossCheckThreshold := flag.Float64("tokenizer-cross-check-threshold", defaultCrossCheckThreshold,
+		"Percent throughput change between encodings above which --tokenizer-cross-check flags a model")
+	flagJudge := flag.Bool("judge", false,
+		"Score each successful response 1-10 with the judge model configured by JUDGE_API_KEY/JUDGE_MODEL/JUDGE_URL")
+	flagPromptFile := flag.String("prompt-file", "",
+		"Send the prompt in this file instead of the built-in streaming prompt (overrides [prompts.streaming] in --config)")
+	flagLang := flag.String("lang", defaultPromptLang,
+		"Language of the streaming prompt pack: en, zh, ja, ko, ar, ru")
+	flagFailover := flag.String("failover", "",
+		"Failover simulation: comma-separated primary,secondary providers (e.g. nim,novita)")
+	flagFailoverTTFT := flag.Duration("failover-ttft", 2*time.Second,
+		"Failover simulation: start racing the secondary when the primary has no token after this long")
+	flagFailoverRuns := flag.Int("failover-runs", 10, "Failover simulation: number of sequential requests")
+	flagRace := flag.String("race", "",
+		"Race mode: send each request to all of these comma-separated providers at once and tally wins")
+	flagRaceRuns := flag.Int("race-runs", 10, "Race mode: number of raced requests")
+	flagRoute := flag.String("route", "",
+		"Routed mode: weighted pool of providers forming a virtual 'routed' provider (e.g. nim=3,novita=1)")
+	flagRouteRuns := flag.Int("route-runs", 20, "Routed mode: number of requests sent through the router")
+	flagSoak := flag.Duration("soak", 0,
+		"Soak mode: keep testing the selected providers for this long (e.g. 6h), with periodic checkpoints")
+	flagSoakInterval := flag.Duration("soak-interval", 30*time.Second, "Soak mode: delay between requests per provider")
+	flagSoakCheckpoint := flag.Duration("soak-checkpoint", 10*time.Minute,
+		"Soak mode: how often partial summaries and the interim report are flushed to disk")
+	flagSoakLogMaxMB := flag.Int("soak-log-max-mb", 10, "Soak mode: rotate provider log files once they reach this size")
+	flagKeepWarm := flag.Duration("keep-warm", 0,
+		"Soak mode: send an unmeasured keep-warm request after this much idle time between measured requests (e.g. 1m)")
+	flagKeepWarmTokens := flag.Int("keep-warm-tokens", 1, "Soak mode: max output tokens of each keep-warm request")
+	flagSLO := flag.String("slo", "",
+		"Soak mode: comma-separated SLOs tracked with rolling burn rates, e.g. ttft<1.5s@99,success@99.5")
+	flagSLOWindows := flag.String("slo-windows", formatSLOWindows(sloWindows),
+		"Soak mode: comma-separated rolling windows SLO burn rates are reported over")
+	flagSessionName := flag.String("session-name", "",
+		"Name the session by purpose (e.g. pre-launch-check); appended to the session folder and recorded in the manifest")
+	flagTags := flag.String("tags", "",
+		"Comma-separated tags recorded in the session manifest and reports (e.g. nightly,eu-west)")
+	flagThinkTime := flag.String("think-time", "",
+		"Pause each diagnostic/scenario worker between requests: fixed:15s, uniform:5s-20s or exponential:10s (mean)")
+	flagConfig := flag.String("config", "",
+		"TOML config file; a [[scenario.stages]] list runs a k6-style load profile, [[column]] adds computed report columns (see example.toml)")
+	flagColdStart := flag.Bool("cold-start", false,
+		"Cold start probe: idle each provider, then measure wake-from-idle latency separately from warm TTFT")
+	flagColdStartIdle := flag.Duration("cold-start-idle", 10*time.Minute,
+		"Cold start probe: idle period before each wake measurement")
+	flagColdStartCycles := flag.Int("cold-start-cycles", 3, "Cold start probe: number of idle/wake cycles")
+	flagColdStartWarm := flag.Int("cold-start-warm", 3, "Cold start probe: warm requests sent after each wake")
+	flagColdStartTimeout := flag.Duration("cold-start-timeout", 5*time.Minute,
+		"Cold start probe: give up on a wake attempt after this long")
+	flagContextProbe := flag.Bool("context-probe", false,
+		"Context window probe: send growing synthetic prompts until each provider refuses one and report the effective context window")
+	flagContextProbeStart := flag.Int("context-probe-start", 4096, "Context window probe: prompt size in tokens of the first request, doubled until refused")
+	flagContextProbeMax := flag.Int("context-probe-max", 262144, "Context window probe: largest prompt size in tokens to try")
+	flagContextProbePrecision := flag.Int("context-probe-precision", 1024,
+		"Context window probe: stop the binary search once the accepted and refused sizes are this many tokens apart")
+	flagMaxOutputProbe := flag.Bool("max-output-probe", false,
+		"Max-output probe: request a never-ending response and report how many tokens each provider actually streams and whether finish_reason says why it stopped")
+	flagMaxOutputTokens := flag.Int("max-output-probe-tokens", 32768, "Max-output probe: max_tokens to request")
+	flagMaxOutputRuns := flag.Int("max-output-probe-runs", 1, "Max-output probe: number of long-output requests per provider")
+	flagStructured := flag.Bool("structured-output", false,
+		"Structured output: request a json_schema response in strict and non-strict mode, validate every response and compare conformance and latency")
+	flagStructuredRuns := flag.Int("structured-output-runs", 3, "Structured output: runs per provider in each of strict and non-strict mode")
+	flagCancelProbe := flag.Bool("cancel-probe", false,
+		"Cancel probe: abort streams mid-response and report how many tokens each provider billed beyond the cancellation (where usage can be queried)")
+	flagCancelAfter := flag.Int("cancel-probe-after", 50, "Cancel probe: completion tokens to receive before cancelling")
+	flagCancelRuns := flag.Int("cancel-probe-runs", 3, "Cancel probe: number of cancelled requests per provider")
+	flagReference := flag.String("reference", "",
+		"CSV of third-party benchmark figures (e.g. an ArtificialAnalysis export) shown as reference columns in reports")
+	flagBlind := flag.Bool("blind", false,
+		"Replace provider names with Provider A/B/C in REPORT.md/DIAGNOSTIC-REPORT.md; the mapping is saved privately to "+blindMappingFileName)
+	flagBadge := flag.Bool("badge", false,
+		"Write "+badgeFileName+" (e.g. \"fastest: groq 812 tok/s\") next to the report for embedding in dashboards")
+	flagSignKey := flag.String("sign-key", "",
+		"Ed25519 private key (PKCS#8 PEM) to sign the session's files with; check them later with the verify subcommand")
+	flagContextOverflow := flag.String("context-overflow", contextOverflowRefuse,
+		"What to do when prompt plus max tokens exceeds a provider's <PREFIX>_CONTEXT_WINDOW: refuse (fail the request before sending) or warn (log and send anyway)")
+	flagKeyRotation := flag.String("key-rotation", keyRotationRoundRobin,
+		"How requests share a provider's <PREFIX>_API_KEYS list: round-robin (each request takes the next key) or per-worker (each worker keeps one key)")
+	flagORReferer := flag.String("openrouter-referer", "",
+		"OpenRouter: app URL sent as HTTP-Referer for app attribution")
+	flagORTitle := flag.String("openrouter-title", "",
+		"OpenRouter: app name sent as X-Title for app attribution")
+	flagORVariant := flag.String("openrouter-variant", "",
+		"OpenRouter: append a routing variant to the model, nitro (fastest upstream) or floor (cheapest upstream)")
+	flagORProvider := flag.String("openrouter-provider", "",
+		"OpenRouter: pin requests to one upstream provider slug (e.g. deepinfra) with fallbacks disabled")
+	flagORBYOK := flag.Bool("openrouter-byok", false,
+		"OpenRouter: label the pinned provider as bring-your-own-key; requires --openrouter-provider")
+	flagServerMetricsInterval := flag.Duration("server-metrics-interval", serverScrapeInterval,
+		"How often to scrape a self-hosted server's <PREFIX>_METRICS_URL (vLLM/TGI Prometheus endpoint) during runs")
+	flagPerIP := flag.Bool("per-ip", false,
+		"Resolve each provider's hostname and benchmark every A/AAAA record separately (Host header and TLS SNI preserved), tagging results with the IP")
+	flagIPVersion := flag.String("ip-version", "",
+		"Force connections over IPv4 (4) or IPv6 (6), or test every provider over both (both) and compare the paths")
+	flagHTTP3 := flag.Bool("http3", false,
+		"Experimental: test every HTTPS provider over both HTTP/2 and HTTP/3 (QUIC) and compare handshake, TTFT and throughput")
+	flag.StringVar(&sessionUserAgent, "user-agent", sessionUserAgent,
+		"User-Agent sent to every provider without its own <PREFIX>_USER_AGENT; empty sends Go's default")
+	flagToolChoice := flag.String("tool-choice", "",
+		"tool_choice of tool-calling runs: auto, required (default) or a function name (get_weather); a comma-separated list tests each provider once per choice and compares forced choices with auto")
+	flagTwoPhase := flag.Bool("two-phase", false,
+		"Run every provider twice, with a latency profile (max_tokens 64) and a throughput profile (a long story, max_tokens 2048), and compare them side by side")
+	flag.StringVar(&resultsRoot, "results-dir", resultsRoot,
+		"Folder session folders, LEADERBOARD.md and trend charts are written to")
+	flagStrict := flag.Bool("strict", false,
+		"Fail the session if any configured provider is skipped (partial configuration in .env or a failed preflight), for CI")
+	flagNoPreflight := flag.Bool("no-preflight", false,
+		"Skip the one-token request sent to every provider before benchmarking, which drops providers with a bad key or model name up front")
+	flagNoBaseline := flag.Bool("no-baseline", false,
+		"Skip the network baseline (TCP and HTTP round trips) measured before each provider's run")
+	flagNoKeys := flag.Bool("no-keys", false,
+		"Disable interactive key bindings (s = live summary, q = graceful shutdown)")
+	flagRotatePrompts := flag.Bool("rotate-prompts", false,
+		"Rotate streaming requests through a built-in pool of prompts instead of a single fixed prompt")
+	flagCacheBust := flag.Bool("cache-bust", false,
+		"Append a unique nonce to every benchmark prompt so provider or gateway response caches cannot serve repeated runs")
+	flagSeed := flag.Uint64("seed", 0,
+		"Seed for prompt rotation; the same seed reproduces the same prompt sequence (0 = random, recorded in the manifest)")
+
+	// Subcommands are dispatched before flag parsing; "rerun" replays the
+	// arguments recorded in a previous session's manifest.
+	args := os.Args[1:]
+	var rerunManifest *SessionManifest
+	if len(args) > 0 {
+		switch args[0] {
+		case "rerun":
+			if len(args) < 2 {
+				log.Fatal("Usage: llm-api-speed rerun <session>")
 			}
-			return 0, 0, 0, 0, "", fmt.Errorf("stream error: %w", recvErr)
-		}
-
-		chunkCount++
-
-		if len(response.Choices) == 0 {
-			if chunkCount%100 == 0 {
-				providerLogger.Printf("[%s] ... Chunk %d: Empty Choices array (diagnostic: ID=%s, Model=%s)",
-					config.Name, chunkCount, response.ID, response.Model)
+			manifest, err := loadManifest(args[1])
+			if err != nil {
+				log.Fatalf("Error: %v", err)
 			}
-			continue
-		}
-
-		delta := response.Choices[0].Delta
-		content := delta.Content
-		reasoningContent := delta.ReasoningContent
-
-		if (content != "" || reasoningContent != "") && firstTokenTime.IsZero() {
-			firstTokenTime = time.Now()
-			if reasoningContent != "" {
-				providerLogger.Printf("[%s] ... First token received (reasoning)! (chunk %d, len=%d)",
-					config.Name, chunkCount, len(reasoningContent))
-			} else {
-				providerLogger.Printf("[%s] ... First token received! (chunk %d, len=%d)",
-					config.Name, chunkCount, len(content))
+			if manifest.ToolVersion != version {
+				log.Printf("Warning: session was recorded with version %s, running %s", manifest.ToolVersion, version)
 			}
-		}
-
-		if content != "" {
-			nonEmptyChunks++
-			fullResponseContent.WriteString(content)
-		}
-		if reasoningContent != "" {
-			reasoningChunks++
-			fullResponseContent.WriteString(reasoningContent)
+			log.Printf("Rerunning session %s with args: %s", manifest.Session, strings.Join(manifest.Args, " "))
+			rerunManifest = &manifest
+			args = manifest.Args
+		case "tokenizer-bundle":
+			runTokenizerBundle(args[1:])
+			return
+		case "publish":
+			runPublish(args[1:])
+			return
+		case "export":
+			runExport(args[1:])
+			return
+		case "compare":
+			runCompare(args[1:])
+			return
+		case "selftest":
+			runSelfTest(args[1:])
+			return
+		case "report":
+			runReport(args[1:])
+			return
+		case "daemon":
+			runDaemon(args[1:])
+			return
+		case "verify":
+			runVerify(args[1:])
+			return
+		case "bundle-issue":
+			runBundleIssue(args[1:])
+			return
 		}
 	}
-
-	endTime := time.Now()
-
-	if firstTokenTime.IsZero() {
-		return 0, 0, 0, 0, "", fmt.Errorf("no content received from API (received %d chunks)", chunkCount)
-	}
-
-	fullResponse := fullResponseContent.String()
-	tokenList := tke.Encode(fullResponse, nil, nil)
-	completionTokens := len(tokenList)
-
-	providerLogger.Printf(
-		"[%s] ... Total content length: %d bytes, %d tokens",
-		config.Name, len(fullResponse), completionTokens)
-
-	if completionTokens == 0 {
-		return 0, 0, 0, 0, "", fmt.Errorf("received 0 tokens (content length: %d bytes)", len(fullResponse))
-	}
-
-	e2eLatency := endTime.Sub(startTime)
-	ttftLatency := firstTokenTime.Sub(startTime)
-	generationTime := e2eLatency - ttftLatency
-
-	var throughputVal float64
-	if generationTime.Seconds() <= 0 {
-		throughputVal = 0.0
-	} else {
-		throughputVal = (float64(completionTokens) - 1.0) / generationTime.Seconds()
-	}
-
-	return e2eLatency, ttftLatency, throughputVal, completionTokens, fullResponse, nil
-}
-
-// singleTestRun performs one test run and returns metrics or error.
-func singleTestRun(ctx context.Context, config ProviderConfig, tke *tiktoken.Tiktoken, providerLogger *log.Logger) (e2e, ttft time.Duration, throughput float64, tokens int, response string, err error) {
-	prompt := "You are a helpful assistant. Please write a short, 150-word story about a curious robot exploring " +
-		"an ancient, overgrown library on a forgotten planet."
-	messages := []openai.ChatCompletionMessage{
-		{
-			Role:    openai.ChatMessageRoleUser,
-			Content: prompt,
-		},
-	}
-
-	req := openai.ChatCompletionRequest{
-		Model:     config.Model,
-		Messages:  messages,
-		MaxTokens: 512,
-		Stream:    true,
+	if err := flag.CommandLine.Parse(args); err != nil {
+		log.Fatalf("Error parsing flags: %v", err)
 	}
 
-	return runStreamingChat(ctx, config, tke, providerLogger, req)
-}
-
-// longStoryRun performs a single long-form story generation run and returns metrics or error.
-func longStoryRun(ctx context.Context, config ProviderConfig, tke *tiktoken.Tiktoken, providerLogger *log.Logger) (e2e, ttft time.Duration, throughput float64, tokens int, response string, err error) {
-	messages := []openai.ChatCompletionMessage{
-		{
-			Role:    openai.ChatMessageRoleSystem,
-			Content: longStorySystemPrompt,
-		},
-		{
-			Role:    openai.ChatMessageRoleUser,
-			Content: longStoryUserPrompt,
-		},
-	}
-	storyMaxTokens := maxTokens
-	if storyMaxTokens <= 0 {
-		storyMaxTokens = 16384
+	// Set global flag for saving responses
+	saveResponses = *flagSaveResponses
+	skipNetworkBaseline = *flagNoBaseline
+	targetTokens = *flagTargetTokens
+	if *flagMaxTokens < 0 {
+		log.Fatalf("Error: --max-tokens must not be negative, got %d", *flagMaxTokens)
 	}
-
-	req := openai.ChatCompletionRequest{
-		Model:     config.Model,
-		Messages:  messages,
-		MaxTokens: storyMaxTokens,
-		Stream:    true,
+	if *flagTimeout < 0 {
+		log.Fatalf("Error: --timeout must not be negative, got %s", *flagTimeout)
 	}
-
-	return runStreamingChat(ctx, config, tke, providerLogger, req)
-}
-
-// singleToolCallRun performs one tool-calling test run and returns metrics or error.
-// When toolReasoningCheck is true, additional logging is produced to validate that
-// tool calls occur alongside multi-step reasoning (before and after tool use).
-func singleToolCallRun(ctx context.Context, config ProviderConfig, tke *tiktoken.Tiktoken, providerLogger *log.Logger, toolReasoningCheck bool) (e2e, ttft time.Duration, throughput float64, tokens int, response string, err error) {
-	// Configure the OpenAI Client
-	clientConfig := openai.DefaultConfig(config.APIKey)
-	clientConfig.BaseURL = config.BaseURL
-	client := openai.NewClientWithConfig(clientConfig)
-
-	// Define a weather tool
-	tools := []openai.Tool{
-		{
-			Type: openai.ToolTypeFunction,
-			Function: &openai.FunctionDefinition{
-				Name:        "get_weather",
-				Description: "Get the current weather in a given location",
-				Parameters: map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"location": map[string]interface{}{
-							"type":        "string",
-							"description": "The city and state, e.g. San Francisco, CA",
-						},
-						"unit": map[string]interface{}{
-							"type": "string",
-							"enum": []string{"celsius", "fahrenheit"},
-						},
-					},
-					"required": []string{"location"},
-				},
-			},
-		},
-	}
-
-	prompt := "You are a weather analysis assistant. You MUST call the get_weather tool at least once for " +
-		"each city you are asked about before answering. Do not guess or answer without using the tool. " +
-		"Question: What's the weather like in San Francisco, Tokyo, and London? Please check all three cities " +
-		"using the tool and then tell me which one has the best weather for outdoor activities today."
-	messages := []openai.ChatCompletionMessage{
-		{
-			Role:    openai.ChatMessageRoleUser,
-			Content: prompt,
-		},
-	}
-
-	req := openai.ChatCompletionRequest{
-		Model:     config.Model,
-		Messages:  messages,
-		Tools:     tools,
-		MaxTokens: 512,
-		Stream:    true,
-	}
-	req.ToolChoice = "required"
-	if toolReasoningCheck {
-		req.ParallelToolCalls = true
+	maxTokens = *flagMaxTokens
+	runTimeout = *flagTimeout
+	minOutputTokens = *flagMinOutputTokens
+	blindReports = *flagBlind
+	writeBadge = *flagBadge
+	if *flagKeyRotation != keyRotationRoundRobin && *flagKeyRotation != keyRotationPerWorker {
+		log.Fatalf("Error: --key-rotation must be %s or %s", keyRotationRoundRobin, keyRotationPerWorker)
 	}
-
-	// Execute the stream and measure metrics
-	startTime := time.Now()
-	var firstTokenTime time.Time
-	var fullResponseContent strings.Builder
-
-	stream, streamErr := client.CreateChatCompletionStream(ctx, req)
-	if streamErr != nil {
-		if toolReasoningCheck {
-			logInterleavedToolError(providerLogger, config, streamErr)
-		}
-		return 0, 0, 0, 0, "", fmt.Errorf("error creating stream: %w", streamErr)
+	keyRotation = *flagKeyRotation
+	if *flagContextOverflow != contextOverflowRefuse && *flagContextOverflow != contextOverflowWarn {
+		log.Fatalf("Error: --context-overflow must be %s or %s", contextOverflowRefuse, contextOverflowWarn)
 	}
-	defer func() {
-		if closeErr := stream.Close(); closeErr != nil {
-			providerLogger.Printf("[%s] Warning: Failed to close stream: %v", config.Name, closeErr)
-		}
-	}()
-
-	providerLogger.Printf("[%s] ... Tool calling request sent. Waiting for stream ...", config.Name)
-
-	chunkCount := 0
-	nonEmptyChunks := 0
-	reasoningChunks := 0
-	toolCallChunks := 0
-	streamReportedToolCalls := false
-	streamInterleavedContent := false
-	streamInterleavedReasoning := false
-	chunkIndex := 0
-	reasoningBeforeTools := false
-	reasoningAfterTools := false
-	inToolPhase := false
-	toolPhaseCount := 0
-
-	for {
-		response, recvErr := stream.Recv()
-
-		// Check for end of stream
-		if errors.Is(recvErr, io.EOF) {
-			providerLogger.Printf(
-				"[%s] ... Tool calling stream complete. Received %d chunks (%d content, %d reasoning, %d tool)",
-				config.Name, chunkCount, nonEmptyChunks, reasoningChunks, toolCallChunks)
-			break
-		}
-
-		if recvErr != nil {
-			if ctx.Err() == context.DeadlineExceeded {
-				return 0, 0, 0, 0, "", fmt.Errorf("timeout exceeded")
-			}
-			return 0, 0, 0, 0, "", fmt.Errorf("stream error: %w", recvErr)
-		}
-
-		chunkCount++
-		chunkIndex++
-
-		// Check if Choices array is empty
-		if len(response.Choices) == 0 {
-			// Log occasionally for debugging (every 100 chunks), not every single one
-			if chunkCount%100 == 0 {
-				providerLogger.Printf("[%s] ... Chunk %d: Empty Choices array (diagnostic: ID=%s, Model=%s)",
-					config.Name, chunkCount, response.ID, response.Model)
-			}
-			continue
-		}
-
-		delta := response.Choices[0].Delta
-
-		// Check for first token (content, reasoning, or tool call)
-		hasContent := delta.Content != ""
-		hasReasoningContent := delta.ReasoningContent != ""
-		hasToolCall := len(delta.ToolCalls) > 0
-
-		if (hasContent || hasReasoningContent || hasToolCall) && firstTokenTime.IsZero() {
-			firstTokenTime = time.Now()
-			switch {
-			case hasReasoningContent:
-				providerLogger.Printf(
-					"[%s] ... First token received (reasoning, tool-calling)! (chunk %d)", config.Name, chunkCount)
-			case hasToolCall:
-				providerLogger.Printf("[%s] ... First token received (tool-call)! (chunk %d)", config.Name, chunkCount)
-			default:
-				providerLogger.Printf("[%s] ... First token received (tool-calling)! (chunk %d)", config.Name, chunkCount)
-			}
-		}
-
-		// Append content if present
-		if hasContent {
-			nonEmptyChunks++
-			fullResponseContent.WriteString(delta.Content)
-		}
-
-		// Append reasoning content if present
-		if hasReasoningContent {
-			reasoningChunks++
-			fullResponseContent.WriteString(delta.ReasoningContent)
-		}
-
-		// Append tool call information as text for token counting
-		if hasToolCall {
-			toolCallChunks++
-			streamReportedToolCalls = true
-			if hasContent {
-				streamInterleavedContent = true
-			}
-			if hasReasoningContent {
-				streamInterleavedReasoning = true
-			}
-			for _, toolCall := range delta.ToolCalls {
-				if toolCall.Function.Name != "" {
-					fullResponseContent.WriteString(toolCall.Function.Name)
-				}
-				if toolCall.Function.Arguments != "" {
-					fullResponseContent.WriteString(toolCall.Function.Arguments)
-				}
-			}
-		}
-
-		// Track reasoning relative to tool-call phases for behavioral checks
-		if hasToolCall {
-			if !inToolPhase {
-				inToolPhase = true
-				toolPhaseCount++
-			}
-		} else {
-			inToolPhase = false
-		}
-
-		if hasReasoningContent {
-			if !streamReportedToolCalls {
-				reasoningBeforeTools = true
-			} else if !hasToolCall {
-				reasoningAfterTools = true
-			}
-		}
-	}
-
-	endTime := time.Now()
-
-	if toolReasoningCheck {
-		reasoningCheckPass := streamReportedToolCalls && reasoningBeforeTools && reasoningAfterTools
-		providerLogger.Printf("[%s] Tool-reasoning summary: toolCallsObserved=%t reasoningBeforeTools=%t reasoningAfterTools=%t toolPhases=%d pass=%t", config.Name, streamReportedToolCalls, reasoningBeforeTools, reasoningAfterTools, toolPhaseCount, reasoningCheckPass)
-		providerLogger.Printf("[%s] Interleaved tool-call summary: interleavedContent=%t interleavedReasoning=%t", config.Name, streamInterleavedContent, streamInterleavedReasoning)
+	contextOverflow = *flagContextOverflow
+	if *flagServerMetricsInterval <= 0 {
+		log.Fatal("Error: --server-metrics-interval must be positive")
 	}
-
-	if firstTokenTime.IsZero() {
-		return 0, 0, 0, 0, "", fmt.Errorf("no content received from API (received %d chunks)", chunkCount)
+	serverScrapeInterval = *flagServerMetricsInterval
+	openRouter = openRouterOptions{
+		Referer:  *flagORReferer,
+		Title:    *flagORTitle,
+		Variant:  strings.TrimPrefix(*flagORVariant, ":"),
+		Provider: *flagORProvider,
+		BYOK:     *flagORBYOK,
 	}
-
-	// Get accurate token count
-	fullResponse := fullResponseContent.String()
-	tokenList := tke.Encode(fullResponse, nil, nil)
-	completionTokens := len(tokenList)
-	if toolCallChunks == 0 {
-		providerLogger.Printf("[%s] Warning: no tool calls were observed in tool-calling mode (model returned only text/reasoning)", config.Name)
-		return 0, 0, 0, 0, fullResponse, fmt.Errorf("no tool calls observed in tool-calling mode")
+	if err := openRouter.validate(); err != nil {
+		log.Fatalf("Error: %v", err)
 	}
-
-	providerLogger.Printf(
-		"[%s] ... Total content length: %d bytes, %d tokens",
-		config.Name, len(fullResponse), completionTokens)
-
-	if completionTokens == 0 {
-		return 0, 0, 0, 0, "", fmt.Errorf("received 0 tokens (content length: %d bytes)", len(fullResponse))
+	if err := validateIPVersion(*flagIPVersion); err != nil {
+		log.Fatalf("Error: %v", err)
 	}
-
-	// Calculate metrics
-	e2eLatency := endTime.Sub(startTime)
-	ttftLatency := firstTokenTime.Sub(startTime)
-	generationTime := e2eLatency - ttftLatency
-
-	var throughputVal float64
-	if generationTime.Seconds() <= 0 {
-		throughputVal = 0.0
-	} else {
-		throughputVal = (float64(completionTokens) - 1.0) / generationTime.Seconds()
+	if err := validatePoolSize("iterations", *flagIterations); err != nil {
+		log.Fatalf("Error: %v", err)
 	}
-
-	return e2eLatency, ttftLatency, throughputVal, completionTokens, fullResponse, nil
-}
-
-// testProviderMetrics runs a full benchmark test against a single provider.
-// It runs 3 iterations and reports averaged results, with a 2-minute total timeout.
-func testProviderMetrics(config ProviderConfig, tke *tiktoken.Tiktoken, wg *sync.WaitGroup, logDir, resultsDir string, results *[]TestResult, resultsMutex *sync.Mutex, mode TestMode, toolReasoningCheck bool) {
-	// Defer wg.Done() if this is part of a concurrent group
-	if wg != nil {
-		defer wg.Done()
+	if err := validatePoolSize("diagnostic-workers", *flagDiagnosticWorkers); err != nil {
+		log.Fatalf("Error: %v", err)
 	}
-
-	// Create log file for this provider
-	timestamp := time.Now().Format("20060102-150405")
-	logFile, err := os.Create(filepath.Clean(filepath.Join(logDir, fmt.Sprintf("%s-%s.log", config.Name, timestamp))))
-	if err != nil {
-		log.Printf("Error creating log file for %s: %v", config.Name, err)
-		return
+	if err := validatePoolSize("diagnostic-max-workers", *flagDiagnosticMaxWorkers); err != nil {
+		log.Fatalf("Error: %v", err)
 	}
-	defer func() {
-		if closeErr := logFile.Close(); closeErr != nil {
-			log.Printf("Warning: Failed to close log file: %v", closeErr)
-		}
-	}()
-
-	// Create a logger for this provider that writes to both stdout and file
-	providerLogger := log.New(io.MultiWriter(os.Stdout, logFile), "", log.LstdFlags)
-
-	modeStr := string(mode)
-	providerLogger.Printf("--- Testing: %s (%s) - Mode: %s - Running 3 concurrent iterations ---",
-		config.Name, config.Model, modeStr)
-
-	// Create 5-minute timeout context for all runs (reasoning models can be slow)
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
-
-	// Determine which modes to run based on mode parameter
-	var modesToRun []TestMode
-	if mode == ModeMixed {
-		modesToRun = []TestMode{ModeStreaming, ModeToolCalling}
-	} else {
-		modesToRun = []TestMode{mode}
-	}
-
-	// Run 3 iterations per mode
-	const iterationsPerMode = 3
-	type runResult struct {
-		e2e        time.Duration
-		ttft       time.Duration
-		throughput float64
-		tokens     int
-		err        error
-		runNum     int
-		mode       TestMode
-	}
-
-	totalRuns := len(modesToRun) * iterationsPerMode
-	resultsChan := make(chan runResult, totalRuns)
-	var runWg sync.WaitGroup
-
-	// Launch concurrent workers for each mode
-	runNum := 1
-	for _, testMode := range modesToRun {
-		for i := 1; i <= iterationsPerMode; i++ {
-			runWg.Add(1)
-			go func(currentRunNum int, currentMode TestMode) {
-				defer runWg.Done()
-				providerLogger.Printf("[%s] Run %d/%d (%s) starting", config.Name, currentRunNum, totalRuns, currentMode)
-
-				var e2e, ttft time.Duration
-				var throughput float64
-				var tokens int
-				var runErr error
-				var responseContent string
-				useReasoningCheck := toolReasoningCheck && currentMode == ModeToolCalling
-
-				// Execute the appropriate test based on mode
-				if currentMode == ModeToolCalling {
-					e2e, ttft, throughput, tokens, responseContent, runErr = singleToolCallRun(ctx, config, tke, providerLogger, useReasoningCheck)
-				} else {
-					e2e, ttft, throughput, tokens, responseContent, runErr = singleTestRun(ctx, config, tke, providerLogger)
-				}
-
-				// Save response if flag is enabled
-				if saveResponses && runErr == nil && responseContent != "" {
-					responseFile := filepath.Clean(filepath.Join(logDir,
-						fmt.Sprintf("%s-run%d-%s-response.txt", config.Name, currentRunNum, currentMode)))
-					if err := os.WriteFile(responseFile, []byte(responseContent), 0600); err != nil {
-						providerLogger.Printf("[%s] Warning: Failed to save response for run %d: %v",
-							config.Name, currentRunNum, err)
-					}
-				}
-
-				if runErr != nil {
-					providerLogger.Printf("[%s] Run %d (%s) failed: %v", config.Name, currentRunNum, currentMode, runErr)
-				} else {
-					providerLogger.Printf("[%s] Run %d (%s) complete: E2E=%s TTFT=%s Throughput=%.2f tok/s",
-						config.Name, currentRunNum, currentMode, formatDuration(e2e), formatDuration(ttft), throughput)
-				}
-
-				resultsChan <- runResult{
-					e2e:        e2e,
-					ttft:       ttft,
-					throughput: throughput,
-					tokens:     tokens,
-					err:        runErr,
-					runNum:     currentRunNum,
-					mode:       currentMode,
-				}
-			}(runNum, testMode)
-			runNum++
-		}
+	if *flagDiagnosticAutoscale && *flagDiagnosticMaxWorkers < *flagDiagnosticWorkers {
+		log.Fatalf("Error: --diagnostic-max-workers (%d) must be at least --diagnostic-workers (%d)", *flagDiagnosticMaxWorkers, *flagDiagnosticWorkers)
 	}
-
-	// Close channel after all workers complete
-	go func() {
-		runWg.Wait()
-		close(resultsChan)
-	}()
-
-	// Collect results from all workers
-	var e2eSum, ttftSum time.Duration
-	var throughputSum float64
-	var tokensSum int
-	successfulRuns := 0
-	var firstError error
-
-	for result := range resultsChan {
-		if result.err == nil {
-			e2eSum += result.e2e
-			ttftSum += result.ttft
-			throughputSum += result.throughput
-			tokensSum += result.tokens
-			successfulRuns++
-		} else if firstError == nil {
-			firstError = result.err
+	if n := *flagMaxParallelProviders; n != 0 {
+		if err := validatePoolSize("max-parallel-providers", n); err != nil {
+			log.Fatalf("Error: %v", err)
 		}
 	}
-
-	if successfulRuns == 0 {
-		providerLogger.Printf("[%s] All runs failed", config.Name)
-		// Save error result
-		result := TestResult{
-			Provider:  config.Name,
-			Model:     config.Model,
-			Timestamp: time.Now(),
-			Success:   false,
-			Error:     firstError.Error(),
-			Mode:      modeStr,
-		}
-		saveResult(resultsDir, result)
-		appendResult(results, resultsMutex, result)
-		return
+	runIterations = *flagIterations
+	diagnosticWorkers = *flagDiagnosticWorkers
+	diagnosticAutoscale = *flagDiagnosticAutoscale
+	diagnosticMaxWorkers = *flagDiagnosticMaxWorkers
+	maxParallelProviders = *flagMaxParallelProviders
+	if err := validatePromptLang(*flagLang); err != nil {
+		log.Fatalf("Error: %v", err)
 	}
-
-	// Calculate averages
-	avgE2E := e2eSum / time.Duration(successfulRuns)
-	avgTTFT := ttftSum / time.Duration(successfulRuns)
-	avgThroughput := throughputSum / float64(successfulRuns)
-	avgTokens := tokensSum / successfulRuns
-
-	// Print averaged results
-	providerLogger.Println("==============================================")
-	providerLogger.Printf("   LLM Metrics for: %s (averaged over %d run(s))", config.Name, successfulRuns)
-	providerLogger.Printf("   Model: %s", config.Model)
-	providerLogger.Printf("   Mode: %s", modeStr)
-	providerLogger.Printf("   Avg Output Tokens: %d", avgTokens)
-	providerLogger.Println("----------------------------------------------")
-	providerLogger.Printf("   End-to-End Latency: %s", formatDuration(avgE2E))
-	providerLogger.Printf("   Latency (TTFT):     %s", formatDuration(avgTTFT))
-	providerLogger.Printf("   Throughput (Tokens/sec): %.2f tokens/s", avgThroughput)
-	providerLogger.Println("==============================================")
-
-	// Calculate projected E2E if target tokens is set
-	var projectedE2E time.Duration
-	if targetTokens > 0 {
-		projectedE2E = calculateProjectedE2E(avgTTFT, avgThroughput, targetTokens)
-	}
-
-	// Save successful result
-	result := TestResult{
-		Provider:         config.Name,
-		Model:            config.Model,
-		Timestamp:        time.Now(),
-		E2ELatency:       avgE2E,
-		TTFT:             avgTTFT,
-		Throughput:       avgThroughput,
-		CompletionTokens: avgTokens,
-		ProjectedE2E:     projectedE2E,
-		Success:          true,
-		Mode:             modeStr,
-	}
-	saveResult(resultsDir, result)
-	appendResult(results, resultsMutex, result)
-}
-
-// testProviderLongStory runs a single long-story benchmark against a provider.
-func testProviderLongStory(config ProviderConfig, tke *tiktoken.Tiktoken, wg *sync.WaitGroup, logDir, resultsDir string, results *[]TestResult, resultsMutex *sync.Mutex) {
-	if wg != nil {
-		defer wg.Done()
+	promptLang = *flagLang
+	if promptLang != defaultPromptLang {
+		log.Printf("Prompt language: %s (%s)", currentPromptPack().name, promptLang)
 	}
-
-	timestamp := time.Now().Format("20060102-150405")
-	logFile, err := os.Create(filepath.Clean(filepath.Join(logDir, fmt.Sprintf("%s-long-story-%s.log", config.Name, timestamp))))
-	if err != nil {
-		log.Printf("Error creating long-story log file for %s: %v", config.Name, err)
-		return
+	rotatePrompts = *flagRotatePrompts
+	cacheBust = *flagCacheBust
+	if cacheBust {
+		log.Println("Cache busting enabled: every benchmark prompt gets a unique nonce")
 	}
-	defer func() {
-		if closeErr := logFile.Close(); closeErr != nil {
-			log.Printf("Warning: Failed to close long-story log file: %v", closeErr)
-		}
-	}()
-
-	providerLogger := log.New(io.MultiWriter(os.Stdout, logFile), "", log.LstdFlags)
-	providerLogger.Printf("--- Long-story test: %s (%s) ---", config.Name, config.Model)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
-	defer cancel()
-
-	providerLogger.Printf("[%s] Long-story run starting", config.Name)
-
-	e2e, ttft, throughput, tokens, responseContent, runErr := longStoryRun(ctx, config, tke, providerLogger)
-
-	if saveResponses && runErr == nil && responseContent != "" {
-		responseFile := filepath.Clean(filepath.Join(logDir,
-			fmt.Sprintf("%s-long-story-response.txt", config.Name)))
-		if err := os.WriteFile(responseFile, []byte(responseContent), 0600); err != nil {
-			providerLogger.Printf("[%s] Warning: Failed to save long-story response: %v", config.Name, err)
+	promptSeed = *flagSeed
+	if rotatePrompts {
+		if promptSeed == 0 {
+			promptSeed = rand.Uint64() // #nosec G404 -- reproducibility seed, not security
+			// Record the chosen seed so a rerun reproduces the same prompt sequence
+			args = append(args, fmt.Sprintf("--seed=%d", promptSeed))
 		}
+		log.Printf("Prompt rotation enabled with seed %d", promptSeed)
 	}
-
-	if runErr != nil {
-		providerLogger.Printf("[%s] Long-story run failed: %v", config.Name, runErr)
-		result := TestResult{
-			Provider:  config.Name,
-			Model:     config.Model,
-			Timestamp: time.Now(),
-			Success:   false,
-			Error:     runErr.Error(),
-			Mode:      longStoryModeLabel,
-		}
-		saveResult(resultsDir, result)
-		appendResult(results, resultsMutex, result)
-		return
+	sessionBudget.maxTokens = *flagMaxTotalTokens
+	sessionBudget.maxCost = *flagMaxEstimatedCost
+	if sessionBudget.enabled() {
+		log.Printf("Session budget: max total tokens=%d, max estimated cost=$%.2f (0 = unlimited)",
+			sessionBudget.maxTokens, sessionBudget.maxCost)
 	}
 
-	providerLogger.Println("==============================================")
-	providerLogger.Printf("   Long-story LLM Metrics for: %s", config.Name)
-	providerLogger.Printf("   Model: %s", config.Model)
-	providerLogger.Printf("   Mode: %s", longStoryModeLabel)
-	providerLogger.Printf("   Output Tokens: %d", tokens)
-	providerLogger.Println("----------------------------------------------")
-	providerLogger.Printf("   End-to-End Latency: %s", formatDuration(e2e))
-	providerLogger.Printf("   Latency (TTFT):     %s", formatDuration(ttft))
-	providerLogger.Printf("   Throughput (Tokens/sec): %.2f tokens/s", throughput)
-	providerLogger.Println("==============================================")
-
-	var projectedE2E time.Duration
-	if targetTokens > 0 {
-		projectedE2E = calculateProjectedE2E(ttft, throughput, targetTokens)
-	}
-
-	result := TestResult{
-		Provider:         config.Name,
-		Model:            config.Model,
-		Timestamp:        time.Now(),
-		E2ELatency:       e2e,
-		TTFT:             ttft,
-		Throughput:       throughput,
-		CompletionTokens: tokens,
-		ProjectedE2E:     projectedE2E,
-		Success:          true,
-		Mode:             longStoryModeLabel,
-	}
-	saveResult(resultsDir, result)
-	appendResult(results, resultsMutex, result)
-}
-
-// appendResult safely appends a result to the shared results slice.
-func appendResult(results *[]TestResult, mutex *sync.Mutex, result TestResult) {
-	if results != nil && mutex != nil {
-		mutex.Lock()
-		*results = append(*results, result)
-		mutex.Unlock()
+	if *diagnostic && *longStory {
+		log.Fatal("Error: --long-story cannot be combined with --diagnostic")
 	}
-}
-
-// saveResult saves the test result to a JSON file.
-func saveResult(resultsDir string, result TestResult) {
-	timestamp := result.Timestamp.Format("20060102-150405")
-	filename := filepath.Join(resultsDir, fmt.Sprintf("%s-%s.json", result.Provider, timestamp))
-
-	data, err := json.MarshalIndent(result, "", "  ")
-	if err != nil {
-		log.Printf("Error marshaling result for %s: %v", result.Provider, err)
-		return
+	if *flagSoak > 0 && (*diagnostic || *longStory) {
+		log.Fatal("Error: --soak cannot be combined with --diagnostic or --long-story")
 	}
-
-	if err := os.WriteFile(filename, data, 0600); err != nil {
-		log.Printf("Error writing result file for %s: %v", result.Provider, err)
-		return
+	if *flagSoak > 0 && (*flagSoakInterval <= 0 || *flagSoakCheckpoint <= 0) {
+		log.Fatal("Error: --soak-interval and --soak-checkpoint must be positive")
 	}
-
-	log.Printf("Result saved: %s", filename)
-}
-
-// generateMarkdownReport creates a summary report of all test results.
-func generateMarkdownReport(resultsDir string, results []TestResult, sessionTimestamp string) error {
-	filename := filepath.Join(resultsDir, "REPORT.md")
-
-	var report strings.Builder
-	report.WriteString("# LLM API Speed Test Results\n\n")
-	report.WriteString(fmt.Sprintf("**Test Session:** %s\n\n", sessionTimestamp))
-	report.WriteString("---\n\n")
-
-	// Summary statistics
-	successful := 0
-	failed := 0
-	for _, r := range results {
-		if r.Success {
-			successful++
-		} else {
-			failed++
+	if *flagKeepWarm != 0 {
+		if *flagSoak == 0 {
+			log.Fatal("Error: --keep-warm requires --soak")
 		}
-	}
-
-	report.WriteString("## Summary\n\n")
-	report.WriteString(fmt.Sprintf("- **Total Providers Tested:** %d\n", len(results)))
-	report.WriteString(fmt.Sprintf("- **Successful:** %d\n", successful))
-	report.WriteString(fmt.Sprintf("- **Failed:** %d\n\n", failed))
-
-	// Successful results table
-	if successful > 0 {
-		report.WriteString("## Successful Tests\n\n")
-		if targetTokens > 0 {
-			report.WriteString(fmt.Sprintf("**Note:** Projected E2E calculated for %d tokens using formula: TTFT + (Target Tokens / Throughput)\n\n", targetTokens))
-			report.WriteString("| Provider | Model | Mode | E2E Latency | TTFT | Throughput | Tokens | Projected E2E |\n")
-			report.WriteString("|----------|-------|------|-------------|------|------------|--------|---------------|\n")
-		} else {
-			report.WriteString("| Provider | Model | Mode | E2E Latency | TTFT | Throughput | Tokens |\n")
-			report.WriteString("|----------|-------|------|-------------|------|------------|--------|\n")
-		}
-
-		for _, r := range results {
-			if r.Success {
-				writeTestResultRow(&report, r, targetTokens > 0)
-			}
-		}
-		report.WriteString("\n")
-	}
-
-	// Failed results
-	if failed > 0 {
-		report.WriteString("## Failed Tests\n\n")
-		report.WriteString("| Provider | Model | Mode | Error |\n")
-		report.WriteString("|----------|-------|------|-------|\n")
-
-		for _, r := range results {
-			if !r.Success {
-				report.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n",
-					r.Provider,
-					r.Model,
-					r.Mode,
-					r.Error))
-			}
+		if err := validateKeepWarm(*flagKeepWarm, *flagSoakInterval, *flagKeepWarmTokens); err != nil {
+			log.Fatalf("Error: %v", err)
 		}
-		report.WriteString("\n")
 	}
-
-	// Leaderboard (sorted by throughput)
-	if successful > 0 {
-		writeTestResultLeaderboards(&report, results)
+	if *flagContextProbe && (*flagContextProbeStart <= 0 || *flagContextProbeMax < *flagContextProbeStart || *flagContextProbePrecision <= 0) {
+		log.Fatal("Error: --context-probe-start and --context-probe-precision must be positive and --context-probe-max at least --context-probe-start")
 	}
-
-	report.WriteString("---\n\n")
-	report.WriteString(fmt.Sprintf("*Report generated at %s*\n", time.Now().Format("2006-01-02 15:04:05")))
-
-	if err := os.WriteFile(filename, []byte(report.String()), 0600); err != nil {
-		return fmt.Errorf("error writing report: %w", err)
+	if *flagMaxOutputProbe && (*flagMaxOutputTokens <= 0 || *flagMaxOutputRuns <= 0) {
+		log.Fatal("Error: --max-output-probe-tokens and --max-output-probe-runs must be positive")
 	}
-
-	log.Printf("Report generated: %s", filename)
-	return nil
-}
-
-// DiagnosticSummary holds the aggregated results from a diagnostic run.
-type DiagnosticSummary struct {
-	Provider      string         `json:"provider"`
-	Model         string         `json:"model"`
-	Mode          string         `json:"mode"`
-	Timestamp     time.Time      `json:"timestamp"`
-	TotalRequests int            `json:"totalRequests"`
-	Successful    int            `json:"successful"`
-	Failed        int            `json:"failed"`
-	AvgE2ELatency time.Duration  `json:"avgE2eLatency"`
-	AvgTTFT       time.Duration  `json:"avgTtft"`
-	AvgThroughput float64        `json:"avgThroughput"`
-	AvgTokens     int            `json:"avgTokens"`
-	ProjectedE2E  time.Duration  `json:"projectedE2eLatency,omitempty"`
-	Errors        map[string]int `json:"errors,omitempty"`
-}
-
-// diagnosticMode runs continuous testing with 10 workers for 90 seconds.
-// Makes requests every 15 seconds, with 30-second timeout per request.
-// Workers stop starting new requests when insufficient time remains (5s grace period).
-// Expected: 4 requests per worker (at 0s, 15s, 30s, 45s) for a total of 40 requests.
-func diagnosticMode(config ProviderConfig, tke *tiktoken.Tiktoken, logDir, resultsDir string, mode TestMode, toolReasoningCheck bool, wg *sync.WaitGroup, results *[]DiagnosticSummary, resultsMutex *sync.Mutex) {
-	if wg != nil {
-		defer wg.Done()
-	}
-	timestamp := time.Now().Format("20060102-150405")
-	logFileName := filepath.Clean(filepath.Join(logDir, fmt.Sprintf("%s-diagnostic-%s.log", config.Name, timestamp)))
-	logFile, err := os.Create(logFileName)
-	if err != nil {
-		log.Printf("Error creating diagnostic log file for %s: %v", config.Name, err)
-		return
+	if *flagStructured && *flagStructuredRuns <= 0 {
+		log.Fatal("Error: --structured-output-runs must be positive")
 	}
-	defer func() {
-		if closeErr := logFile.Close(); closeErr != nil {
-			log.Printf("Warning: Failed to close log file: %v", closeErr)
-		}
-	}()
-
-	providerLogger := log.New(io.MultiWriter(os.Stdout, logFile), "", log.LstdFlags)
-	providerLogger.Printf("=== DIAGNOSTIC MODE: %s (%s) - Mode: %s ===", config.Name, config.Model, mode)
-	providerLogger.Printf("Running 10 workers for 90 seconds with requests every 15 seconds")
-	providerLogger.Printf("Timeout per request: 30 seconds")
-
-	// Create a 90-second timeout for the entire diagnostic session
-	sessionStartTime := time.Now()
-	sessionDuration := 90 * time.Second
-	sessionCtx, sessionCancel := context.WithTimeout(context.Background(), sessionDuration)
-	defer sessionCancel()
-
-	// Define timeout constants
-	const requestTimeout = 30 * time.Second
-	const gracePeriod = 5 * time.Second
-
-	// Metrics tracking
-	type diagnosticResult struct {
-		workerID   int
-		reqNum     int
-		e2e        time.Duration
-		ttft       time.Duration
-		throughput float64
-		tokens     int
-		err        error
-		mode       TestMode
-		response   string
-	}
-
-	resultsChan := make(chan diagnosticResult, 1000)
-	var workerWg sync.WaitGroup
-
-	// Start 10 workers
-	const numWorkers = 10
-	for workerID := 1; workerID <= numWorkers; workerID++ {
-		workerWg.Add(1)
-		go func(id int) {
-			defer workerWg.Done()
-			reqNum := 0
-
-			// Create ticker for requests every 15 seconds
-			ticker := time.NewTicker(15 * time.Second)
-			defer ticker.Stop()
-
-			// Make first request immediately
-			for {
-				reqNum++
-
-				// Create timeout context for this request
-				reqCtx, reqCancel := context.WithTimeout(sessionCtx, requestTimeout)
-
-				providerLogger.Printf("[Worker %d] Request #%d starting", id, reqNum)
-
-				var e2e, ttft time.Duration
-				var throughput float64
-				var tokens int
-				var reqErr error
-				var responseContent string
-
-				// Determine which test function to use based on mode
-				var testMode TestMode
-				switch mode {
-				case ModeMixed:
-					// Alternate between streaming and tool-calling in mixed mode
-					if reqNum%2 == 1 {
-						testMode = ModeStreaming
-						e2e, ttft, throughput, tokens, responseContent, reqErr = singleTestRun(reqCtx, config, tke, providerLogger)
-					} else {
-						testMode = ModeToolCalling
-						e2e, ttft, throughput, tokens, responseContent, reqErr = singleToolCallRun(reqCtx, config, tke, providerLogger, toolReasoningCheck)
-					}
-				case ModeToolCalling:
-					testMode = ModeToolCalling
-					e2e, ttft, throughput, tokens, responseContent, reqErr = singleToolCallRun(reqCtx, config, tke, providerLogger, toolReasoningCheck)
-				case ModeStreaming:
-					testMode = ModeStreaming
-					e2e, ttft, throughput, tokens, responseContent, reqErr = singleTestRun(reqCtx, config, tke, providerLogger)
-				default:
-					testMode = ModeStreaming
-					e2e, ttft, throughput, tokens, responseContent, reqErr = singleTestRun(reqCtx, config, tke, providerLogger)
-				}
-
-				reqCancel()
-
-				// Save response if flag is enabled
-				if saveResponses && reqErr == nil && responseContent != "" {
-					responseFile := filepath.Clean(filepath.Join(logDir,
-						fmt.Sprintf("%s-worker%d-req%d-%s-response.txt", config.Name, id, reqNum, testMode)))
-					if err := os.WriteFile(responseFile, []byte(responseContent), 0600); err != nil {
-						providerLogger.Printf("[Worker %d] Warning: Failed to save response for request #%d: %v",
-							id, reqNum, err)
-					}
-				}
-
-				if reqErr != nil {
-					providerLogger.Printf("[Worker %d] Request #%d (%s) failed: %v", id, reqNum, testMode, reqErr)
-				} else {
-					providerLogger.Printf("[Worker %d] Request #%d (%s) success: E2E=%s TTFT=%s Throughput=%.2f tok/s Tokens=%d",
-						id, reqNum, testMode, formatDuration(e2e), formatDuration(ttft), throughput, tokens)
-				}
-
-				resultsChan <- diagnosticResult{
-					workerID:   id,
-					reqNum:     reqNum,
-					e2e:        e2e,
-					ttft:       ttft,
-					throughput: throughput,
-					tokens:     tokens,
-					err:        reqErr,
-					mode:       testMode,
-					response:   responseContent,
-				}
-
-				// Wait for next tick or session end
-				select {
-				case <-sessionCtx.Done():
-					providerLogger.Printf("[Worker %d] Session ended, completed %d requests", id, reqNum)
-					return
-				case <-ticker.C:
-					// Check if there's enough time remaining before starting the next request
-					elapsed := time.Since(sessionStartTime)
-					timeRemaining := sessionDuration - elapsed
-
-					// Skip new requests if insufficient time remains
-					if timeRemaining < requestTimeout+gracePeriod {
-						providerLogger.Printf(
-							"[Worker %d] Stopping - insufficient time remaining for next request (%.1fs left, need %.1fs)",
-							id, timeRemaining.Seconds(), (requestTimeout + gracePeriod).Seconds())
-						providerLogger.Printf("[Worker %d] Completed %d requests", id, reqNum)
-						return
-					}
-					// Continue to next request
-				}
-			}
-		}(workerID)
-	}
-
-	// Wait for all workers to complete
-	go func() {
-		workerWg.Wait()
-		close(resultsChan)
-	}()
-
-	// Collect and aggregate results
-	var successCount, failureCount int
-	var totalE2E, totalTTFT time.Duration
-	var totalThroughput float64
-	var totalTokens int
-	errors := make(map[string]int)
-
-	for result := range resultsChan {
-		if result.err != nil {
-			failureCount++
-			errors[result.err.Error()]++
-		} else {
-			successCount++
-			totalE2E += result.e2e
-			totalTTFT += result.ttft
-			totalThroughput += result.throughput
-			totalTokens += result.tokens
-		}
+	if *flagCancelProbe && (*flagCancelAfter <= 0 || *flagCancelAfter >= cancelProbeMaxTokens || *flagCancelRuns <= 0) {
+		log.Fatalf("Error: --cancel-probe-after must be between 1 and %d and --cancel-probe-runs positive", cancelProbeMaxTokens-1)
 	}
-
-	// Print summary
-	providerLogger.Println("")
-	providerLogger.Println("========================================")
-	providerLogger.Println("   DIAGNOSTIC MODE SUMMARY")
-	providerLogger.Println("========================================")
-	providerLogger.Printf("Provider: %s", config.Name)
-	providerLogger.Printf("Model: %s", config.Model)
-	providerLogger.Printf("Mode: %s", mode)
-	providerLogger.Printf("Total Requests: %d", successCount+failureCount)
-	providerLogger.Printf("Successful: %d", successCount)
-	providerLogger.Printf("Failed: %d", failureCount)
-
-	if successCount > 0 {
-		avgE2E := totalE2E / time.Duration(successCount)
-		avgTTFT := totalTTFT / time.Duration(successCount)
-		avgThroughput := totalThroughput / float64(successCount)
-		avgTokens := totalTokens / successCount
-
-		providerLogger.Println("--------------------------------------")
-		providerLogger.Printf("Average E2E Latency: %s", formatDuration(avgE2E))
-		providerLogger.Printf("Average TTFT: %s", formatDuration(avgTTFT))
-		providerLogger.Printf("Average Throughput: %.2f tokens/s", avgThroughput)
-		providerLogger.Printf("Average Tokens: %d", avgTokens)
-
-		// Display projected E2E if target tokens is set
-		if targetTokens > 0 {
-			projectedE2E := calculateProjectedE2E(avgTTFT, avgThroughput, targetTokens)
-			providerLogger.Printf("Projected E2E (%d tokens): %s", targetTokens, formatDuration(projectedE2E))
+	if *flagSignKey != "" {
+		key, err := loadSigningKey(*flagSignKey)
+		if err != nil {
+			log.Fatalf("Error: --sign-key: %v", err)
 		}
+		sessionSigningKey = key
 	}
-
-	if len(errors) > 0 {
-		providerLogger.Println("--------------------------------------")
-		providerLogger.Println("Errors encountered:")
-		for errMsg, count := range errors {
-			providerLogger.Printf("  - %s (x%d)", errMsg, count)
+	if *flagSessionName != "" {
+		if err := validateSessionLabel("session name", *flagSessionName); err != nil {
+			log.Fatalf("Error: --session-name: %v", err)
 		}
 	}
-
-	providerLogger.Println("========================================")
-
-	// Create diagnostic summary
-	summary := DiagnosticSummary{
-		Provider:      config.Name,
-		Model:         config.Model,
-		Mode:          string(mode),
-		Timestamp:     time.Now(),
-		TotalRequests: successCount + failureCount,
-		Successful:    successCount,
-		Failed:        failureCount,
+	if tags, err := parseSessionTags(*flagTags); err != nil {
+		log.Fatalf("Error: --tags: %v", err)
+	} else {
+		sessionTags = tags
 	}
-
-	if successCount > 0 {
-		summary.AvgE2ELatency = totalE2E / time.Duration(successCount)
-		summary.AvgTTFT = totalTTFT / time.Duration(successCount)
-		summary.AvgThroughput = totalThroughput / float64(successCount)
-		summary.AvgTokens = totalTokens / successCount
-
-		// Calculate projected E2E if target tokens is set
-		if targetTokens > 0 {
-			summary.ProjectedE2E = calculateProjectedE2E(summary.AvgTTFT, summary.AvgThroughput, targetTokens)
+	if *flagThinkTime != "" {
+		tt, err := parseThinkTime(*flagThinkTime)
+		if err != nil {
+			log.Fatalf("Error: --think-time: %v", err)
 		}
+		sessionThinkTime = tt
 	}
-
-	if len(errors) > 0 {
-		summary.Errors = errors
+	var configFile fileConfig
+	if *flagConfig != "" {
+		cfg, err := loadConfigFile(*flagConfig)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		configFile = cfg
 	}
-
-	// Save diagnostic summary to JSON
-	summaryFile := filepath.Join(resultsDir, fmt.Sprintf("%s-diagnostic-summary-%s.json", config.Name, timestamp))
-	data, err := json.MarshalIndent(summary, "", "  ")
-	if err != nil {
-		providerLogger.Printf("Warning: Failed to marshal diagnostic summary: %v", err)
+	sequentialIterations = *flagSequential || configFile.TestParams.sequential()
+	if scheduler, err := newRequestScheduler(*flagMaxInFlight, *flagProviderMaxInFlight, *flagProviderRPS); err != nil {
+		log.Fatalf("Error: --max-in-flight, --provider-max-in-flight, --provider-rps: %v", err)
 	} else {
-		if err := os.WriteFile(summaryFile, data, 0600); err != nil {
-			providerLogger.Printf("Warning: Failed to write diagnostic summary: %v", err)
-		} else {
-			providerLogger.Printf("Diagnostic summary saved: %s", summaryFile)
-		}
+		sessionScheduler = scheduler
 	}
-
-	// Append to results slice if provided
-	if results != nil && resultsMutex != nil {
-		resultsMutex.Lock()
-		*results = append(*results, summary)
-		resultsMutex.Unlock()
+	warmupRequests = *flagWarmup
+	if !flagPassed("warmup") {
+		warmupRequests = configFile.TestParams.warmup()
 	}
-}
-
-// generateDiagnosticReport creates a markdown report for diagnostic mode results.
-func generateDiagnosticReport(resultsDir string, results []DiagnosticSummary, sessionTimestamp string) error {
-	filename := filepath.Join(resultsDir, "DIAGNOSTIC-REPORT.md")
-
-	var report strings.Builder
-	report.WriteString("# LLM API Diagnostic Mode Results\n\n")
-	report.WriteString(fmt.Sprintf("**Test Session:** %s\n\n", sessionTimestamp))
-	report.WriteString("**Test Duration:** 90 seconds per provider\n")
-	report.WriteString("**Workers:** 10 concurrent workers\n")
-	report.WriteString("**Request Frequency:** Every 15 seconds per worker\n")
-	report.WriteString("**Timeout:** 30 seconds per request\n\n")
-	report.WriteString("---\n\n")
-
-	// Summary statistics
-	totalProviders := len(results)
-	var totalRequests, totalSuccessful, totalFailed int
-	for _, r := range results {
-		totalRequests += r.TotalRequests
-		totalSuccessful += r.Successful
-		totalFailed += r.Failed
-	}
-
-	report.WriteString("## Summary\n\n")
-	report.WriteString(fmt.Sprintf("- **Providers Tested:** %d\n", totalProviders))
-	report.WriteString(fmt.Sprintf("- **Total Requests:** %d\n", totalRequests))
-	report.WriteString(fmt.Sprintf("- **Successful:** %d (%.1f%%)\n",
-		totalSuccessful, 100.0*float64(totalSuccessful)/float64(totalRequests)))
-	report.WriteString(fmt.Sprintf("- **Failed:** %d (%.1f%%)\n\n",
-		totalFailed, 100.0*float64(totalFailed)/float64(totalRequests)))
-
-	// Detailed results table
-	if len(results) > 0 {
-		report.WriteString("## Detailed Results\n\n")
-		if targetTokens > 0 {
-			report.WriteString(fmt.Sprintf("**Note:** Projected E2E calculated for %d tokens using formula: TTFT + (Target Tokens / Throughput)\n\n", targetTokens))
-			report.WriteString("| Provider | Model | Mode | Total Requests | Success | Failed | Avg E2E |" +
-				" Avg TTFT | Avg Throughput | Projected E2E |\n")
-			report.WriteString("|----------|-------|------|----------------|---------|--------|---------|" +
-				"----------|----------------|---------------|\n")
-		} else {
-			report.WriteString("| Provider | Model | Mode | Total Requests | Success | Failed | Avg E2E |" +
-				" Avg TTFT | Avg Throughput |\n")
-			report.WriteString("|----------|-------|------|----------------|---------|--------|---------|" +
-				"----------|----------------|\n")
-		}
-
-		for _, r := range results {
-			writeDiagnosticResultRow(&report, r, targetTokens > 0)
-		}
-		report.WriteString("\n")
+	if err := validateWarmup(warmupRequests); err != nil {
+		log.Fatalf("Error: --warmup: %v", err)
 	}
-
-	// Performance Leaderboard
-	successfulResults := make([]DiagnosticSummary, 0)
-	for _, r := range results {
-		if r.Successful > 0 {
-			successfulResults = append(successfulResults, r)
-		}
+	if *flagProgressJSON {
+		enableProgressJSON(os.Stdout)
 	}
-
-	if len(successfulResults) > 0 {
-		report.WriteString("## Performance Leaderboard\n\n")
-		report.WriteString("### By Throughput (Tokens/sec)\n\n")
-
-		// Sort by throughput
-		for i := 0; i < len(successfulResults); i++ {
-			for j := i + 1; j < len(successfulResults); j++ {
-				if successfulResults[j].AvgThroughput > successfulResults[i].AvgThroughput {
-					successfulResults[i], successfulResults[j] = successfulResults[j], successfulResults[i]
-				}
-			}
-		}
-
-		report.WriteString("| Rank | Provider | Throughput | TTFT | E2E Latency | Success Rate |\n")
-		report.WriteString("|------|----------|------------|------|-------------|-------------|\n")
-
-		for i, r := range successfulResults {
-			successRate := fmt.Sprintf("%.1f%%", 100.0*float64(r.Successful)/float64(r.TotalRequests))
-			report.WriteString(fmt.Sprintf("| %d | %s | %.2f tok/s | %s | %s | %s |\n",
-				i+1,
-				r.Provider,
-				r.AvgThroughput,
-				formatDuration(r.AvgTTFT),
-				formatDuration(r.AvgE2ELatency),
-				successRate))
-		}
-		report.WriteString("\n")
-
-		// Sort by TTFT
-		report.WriteString("### By Time to First Token (TTFT)\n\n")
-
-		for i := 0; i < len(successfulResults); i++ {
-			for j := i + 1; j < len(successfulResults); j++ {
-				if successfulResults[j].AvgTTFT < successfulResults[i].AvgTTFT {
-					successfulResults[i], successfulResults[j] = successfulResults[j], successfulResults[i]
-				}
-			}
-		}
-
-		report.WriteString("| Rank | Provider | TTFT | Throughput | E2E Latency | Success Rate |\n")
-		report.WriteString("|------|----------|------|------------|-------------|-------------|\n")
-
-		for i, r := range successfulResults {
-			successRate := fmt.Sprintf("%.1f%%", 100.0*float64(r.Successful)/float64(r.TotalRequests))
-			report.WriteString(fmt.Sprintf("| %d | %s | %s | %.2f tok/s | %s | %s |\n",
-				i+1,
-				r.Provider,
-				formatDuration(r.AvgTTFT),
-				r.AvgThroughput,
-				formatDuration(r.AvgE2ELatency),
-				successRate))
-		}
-		report.WriteString("\n")
-
-		// Sort by Projected E2E (if available)
-		if targetTokens > 0 {
-			report.WriteString(fmt.Sprintf("### By Projected E2E Latency (%d tokens)\n\n", targetTokens))
-			writeProjectedE2EDiagnosticLeaderboard(&report, successfulResults)
+	if *flagSLO != "" {
+		objectives, err := parseSLOs(*flagSLO)
+		if err != nil {
+			log.Fatalf("Error: --slo: %v", err)
 		}
+		sessionSLOs = objectives
 	}
-
-	// Error Analysis
-	hasErrors := false
-	for _, r := range results {
-		if len(r.Errors) > 0 {
-			hasErrors = true
-			break
+	sessionSLOs = append(sessionSLOs, configFile.SLOs...)
+	sessionColumns = configFile.Columns
+	sessionThresholds = configFile.Thresholds
+	sessionProviderNotes = configFile.Providers
+	customPrompts = configFile.prompts
+	if *flagPromptFile != "" {
+		text, err := readPromptFile(*flagPromptFile)
+		if err != nil {
+			log.Fatalf("Error: --prompt-file: %v", err)
 		}
-	}
-
-	if hasErrors {
-		report.WriteString("## Error Analysis\n\n")
-
-		for _, r := range results {
-			if len(r.Errors) > 0 {
-				report.WriteString(fmt.Sprintf("### %s Errors\n\n", r.Provider))
-				report.WriteString("| Error | Count |\n")
-				report.WriteString("|-------|-------|\n")
-
-				for errMsg, count := range r.Errors {
-					report.WriteString(fmt.Sprintf("| %s | %d |\n", errMsg, count))
-				}
-				report.WriteString("\n")
-			}
+		if customPrompts == nil {
+			customPrompts = make(map[string]string)
 		}
+		customPrompts[promptGroupStreaming] = text
 	}
-
-	report.WriteString("---\n\n")
-	report.WriteString(fmt.Sprintf("*Report generated at %s*\n", time.Now().Format("2006-01-02 15:04:05")))
-
-	if err := os.WriteFile(filename, []byte(report.String()), 0600); err != nil {
-		return fmt.Errorf("error writing diagnostic report: %w", err)
+	if _, ok := customPrompts[promptGroupStreaming]; ok && (rotatePrompts || promptLang != defaultPromptLang) {
+		log.Println("Warning: a custom streaming prompt replaces --rotate-prompts and --lang")
 	}
-
-	log.Printf("Diagnostic report generated: %s", filename)
-	return nil
-}
-
-func main() {
-	// --- Define Provider static info ---
-	providerBaseURLs := map[string]string{
-		"generic": "https://openrouter.ai/api/v1", // Default, can be overridden by --url
-		"nim":     "https://integrate.api.nvidia.com/v1",
-		"nahcrof": "https://ai.nahcrof.com/v2",
-		"novita":  "https://api.novita.ai/openai",
-		"nebius":  "https://api.tokenfactory.nebius.com/v1",
-		"minimax": "https://api.minimax.io/v1",
+	if windows, err := parseSLOWindows(*flagSLOWindows); err != nil {
+		log.Fatalf("Error: --slo-windows: %v", err)
+	} else {
+		sloWindows = windows
 	}
-
-	// 1. Load .env file (if it exists)
-	if err := godotenv.Load(); err != nil {
-		log.Println("Note: .env file not found, reading from system environment.")
+	if len(sessionSLOs) > 0 && *flagSoak == 0 {
+		log.Println("Warning: SLOs are only tracked in soak mode (--soak)")
 	}
-
-	// 2. Parse Command-Line Flags
-	providerName := flag.String("provider", "",
-		"Specific provider to test (e.g., nim, novita). If empty, tests 'generic' provider.")
-	testAll := flag.Bool("all", false, "Test all configured providers concurrently.")
-	flagGenericURL := flag.String("url", "",
-		"Override Base URL for 'generic' provider (default: https://openrouter.ai/api/v1)")
-	flagGenericModel := flag.String("model", "",
-		"Model name for 'generic' provider (required if --provider is not set)")
-	toolCalling := flag.Bool("tool-calling", false, "Use tool calling mode instead of regular streaming")
-	mixed := flag.Bool("mixed", false, "Run both streaming and tool-calling modes (3 runs each)")
-	diagnostic := flag.Bool("diagnostic", false,
-		"Run diagnostic mode: 10 workers making requests every 15s for 1 minute with 30s timeout")
-	longStory := flag.Bool("long-story", false, "Use long-form story generation scenario (single creative-writing prompt)")
-	flagToolReasoningCheck := flag.Bool("tool-reasoning-check", false,
-		"Enable tool+reasoning behavior checks (implies tool-calling if not otherwise set)")
-	flagSaveResponses := flag.Bool("save-responses", false, "Save all API responses to log files")
-	flagTargetTokens := flag.Int("target-tokens", 350,
-		"Target token count for projected E2E latency normalization (default: 350)")
-	flagMaxTokens := flag.Int("max-tokens", 16384,
-		"Maximum completion tokens for long-story mode (default: 16384)")
-	flag.Parse()
-
-	// Set global flag for saving responses
-	saveResponses = *flagSaveResponses
-	targetTokens = *flagTargetTokens
-	maxTokens = *flagMaxTokens
-
-	if *diagnostic && *longStory {
-		log.Fatal("Error: --long-story cannot be combined with --diagnostic")
+	if configFile.Scenario != nil && (*flagSoak > 0 || *diagnostic || *longStory) {
+		log.Fatal("Error: a config scenario cannot be combined with --soak, --diagnostic or --long-story")
 	}
 
 	// 3. Create session-based folder structure
-	sessionTimestamp := time.Now().Format("20060102-150405")
-	sessionDir := filepath.Join("results", fmt.Sprintf("session-%s", sessionTimestamp))
+	// The session name, if any, is part of the identifier so it shows in the
+	// folder, the manifest and every report
+	sessionTimestamp := sessionFolderID(time.Now().Format("20060102-150405"), *flagSessionName)
+	sessionID = sessionTimestamp
+	sessionDir := filepath.Join(resultsRoot, fmt.Sprintf("session-%s", sessionTimestamp))
 	logDir := filepath.Join(sessionDir, "logs")
 	resultsDir := sessionDir
 
@@ -1599,15 +702,28 @@ func main() {
 		log.Fatalf("Error creating results directory: %v", err)
 	}
 
+	emitProgress(ProgressEvent{Event: eventSessionStarted, Dir: sessionDir})
+	defer emitProgress(ProgressEvent{Event: eventSessionDone, Dir: sessionDir})
+
 	log.Printf("Session folder: %s/", sessionDir)
 	log.Printf("Logs will be saved to: %s/", logDir)
 	log.Printf("Results will be saved to: %s/", resultsDir)
 
 	// 4. Initialize Tokenizer
-	tke, err := tiktoken.GetEncoding("cl100k_base")
+	tiktoken.SetBpeLoader(&bundleBpeLoader{dir: *flagTokenizerDir, offline: *flagOffline})
+	if *flagOffline && *flagModelMetadata == modelMetadataOpenRouter {
+		log.Fatal("Error: --model-metadata openrouter needs network access and cannot be combined with --offline")
+	}
+	if *flagOffline {
+		log.Println("Offline mode: tokenizer files will only be loaded from local bundles/cache")
+	}
+	tke, err := tiktoken.GetEncoding(primaryEncoding)
 	if err != nil {
 		log.Fatalf("Error getting tokenizer: %v\n(You might need to run: go get github.com/pkoukk/tiktoken-go)", err)
 	}
+	if err := setupTokenCrossCheck(*flagTokenCrossCheck, *flagTokenCrossCheckThreshold); err != nil {
+		log.Fatalf("Error: --tokenizer-cross-check: %v", err)
+	}
 
 	// 5. Build Full Provider Config Map from .env and flags
 	allProviderConfigs := make(map[string]ProviderConfig)
@@ -1622,6 +738,10 @@ func main() {
 		BaseURL: genericBaseURL,
 		APIKey:  os.Getenv("OAI_API_KEY"),
 		Model:   *flagGenericModel,
+		Env:     os.Getenv("OAI_ENV"),
+
+		InputPrice:  envFloat("OAI_INPUT_PRICE"),
+		OutputPrice: envFloat("OAI_OUTPUT_PRICE"),
 	}
 
 	// NIM Provider
@@ -1630,6 +750,10 @@ func main() {
 		BaseURL: providerBaseURLs["nim"],
 		APIKey:  os.Getenv("NIM_API_KEY"),
 		Model:   os.Getenv("NIM_MODEL"),
+		Env:     os.Getenv("NIM_ENV"),
+
+		InputPrice:  envFloat("NIM_INPUT_PRICE"),
+		OutputPrice: envFloat("NIM_OUTPUT_PRICE"),
 	}
 
 	// NAHCROF Provider
@@ -1638,6 +762,10 @@ func main() {
 		BaseURL: providerBaseURLs["nahcrof"],
 		APIKey:  os.Getenv("NAHCROF_API_KEY"),
 		Model:   os.Getenv("NAHCROF_MODEL"),
+		Env:     os.Getenv("NAHCROF_ENV"),
+
+		InputPrice:  envFloat("NAHCROF_INPUT_PRICE"),
+		OutputPrice: envFloat("NAHCROF_OUTPUT_PRICE"),
 	}
 
 	// NovitaAI Provider
@@ -1646,6 +774,10 @@ func main() {
 		BaseURL: providerBaseURLs["novita"],
 		APIKey:  os.Getenv("NOVITA_API_KEY"),
 		Model:   os.Getenv("NOVITA_MODEL"),
+		Env:     os.Getenv("NOVITA_ENV"),
+
+		InputPrice:  envFloat("NOVITA_INPUT_PRICE"),
+		OutputPrice: envFloat("NOVITA_OUTPUT_PRICE"),
 	}
 
 	// NebiusAI Provider
@@ -1654,6 +786,10 @@ func main() {
 		BaseURL: providerBaseURLs["nebius"],
 		APIKey:  os.Getenv("NEBIUS_API_KEY"),
 		Model:   os.Getenv("NEBIUS_MODEL"),
+		Env:     os.Getenv("NEBIUS_ENV"),
+
+		InputPrice:  envFloat("NEBIUS_INPUT_PRICE"),
+		OutputPrice: envFloat("NEBIUS_OUTPUT_PRICE"),
 	}
 
 	// MiniMax Provider
@@ -1662,12 +798,122 @@ func main() {
 		BaseURL: providerBaseURLs["minimax"],
 		APIKey:  os.Getenv("MINIMAX_API_KEY"),
 		Model:   os.Getenv("MINIMAX_MODEL"),
+		Env:     os.Getenv("MINIMAX_ENV"),
+
+		InputPrice:  envFloat("MINIMAX_INPUT_PRICE"),
+		OutputPrice: envFloat("MINIMAX_OUTPUT_PRICE"),
+	}
+
+	// Built-in fast-inference presets (Groq, Cerebras, ...)
+	for _, preset := range providerPresets {
+		allProviderConfigs[preset.name] = preset.config()
+	}
+
+	for name, config := range allProviderConfigs {
+		prefix := providerEnvPrefix(name)
+		config.APIKeys = envAPIKeys(prefix+"_API_KEYS", config.APIKey)
+		config.MetricsURL = os.Getenv(prefix + "_METRICS_URL")
+		config.ContextWindow = envInt(prefix + "_CONTEXT_WINDOW")
+		config.UserAgent = os.Getenv(prefix + "_USER_AGENT")
+		config.ColdStartHeader = os.Getenv(prefix + "_COLD_START_HEADER")
+		if usage := os.Getenv(prefix + "_STREAM_USAGE"); usage != "" {
+			include, err := strconv.ParseBool(usage)
+			if err != nil {
+				log.Fatalf("Error: %s_STREAM_USAGE=%q: want true or false", prefix, usage)
+			}
+			config.NoStreamUsage = !include
+		}
+		if proxy := os.Getenv(prefix + "_PROXY"); proxy != "" {
+			if !slices.Contains(proxies, proxy) {
+				log.Fatalf("Error: %s_PROXY=%q: unknown proxy (use %s)", prefix, proxy, strings.Join(proxies, ", "))
+			}
+			config.Proxy = proxy
+		}
+		if api := os.Getenv(prefix + "_TYPE"); api != "" {
+			if err := validateProviderType(api); err != nil {
+				log.Fatalf("Error: %s_TYPE: %v", prefix, err)
+			}
+			config.API = api
+		}
+		if len(config.APIKeys) > 0 {
+			config.APIKey = config.APIKeys[0]
+		}
+		allProviderConfigs[name] = openRouter.apply(config)
+	}
+
+	for name, notes := range configFile.Providers {
+		config, ok := allProviderConfigs[name]
+		if !ok {
+			log.Fatalf("Error: --config: [provider.%s]: unknown provider", name)
+		}
+		if notes.Type != "" {
+			config.API = notes.Type
+			allProviderConfigs[name] = config
+		}
+	}
+
+	if rerunManifest != nil {
+		pinManifestProviders(allProviderConfigs, *rerunManifest)
+	}
+
+	if *flagReference != "" {
+		rows, err := loadReferenceCSV(*flagReference)
+		if err != nil {
+			log.Fatalf("Error: --reference: %v", err)
+		}
+		referenceSource = filepath.Base(*flagReference)
+		referenceRows = rows
+		log.Printf("Loaded %d reference rows from %s", len(rows), *flagReference)
+	}
+
+	// Judge model for optional quality scoring
+	if *flagJudge {
+		judge := ProviderConfig{
+			Name:      "judge",
+			BaseURL:   os.Getenv("JUDGE_URL"),
+			APIKey:    os.Getenv("JUDGE_API_KEY"),
+			Model:     os.Getenv("JUDGE_MODEL"),
+			UserAgent: os.Getenv("JUDGE_USER_AGENT"),
+
+			InputPrice:  envFloat("JUDGE_INPUT_PRICE"),
+			OutputPrice: envFloat("JUDGE_OUTPUT_PRICE"),
+		}
+		if judge.BaseURL == "" {
+			judge.BaseURL = providerBaseURLs["generic"]
+		}
+		if judge.APIKey == "" || judge.Model == "" {
+			log.Fatal("Error: --judge requires JUDGE_API_KEY and JUDGE_MODEL to be set.")
+		}
+		judgeConfig = &judge
+		log.Printf("Quality judging enabled with model %s", judge.Model)
 	}
 
 	// 5. Select Providers to Test based on flags
 	providersToTest := []ProviderConfig{}
+	var routeMembers []routeMember
 
 	switch {
+	case *flagFailover != "":
+		providersToTest = selectNamedProviders(allProviderConfigs, *flagFailover)
+		if len(providersToTest) != 2 || providersToTest[0].Name == providersToTest[1].Name {
+			log.Fatal("Error: --failover requires two different providers, e.g. --failover nim,novita")
+		}
+	case *flagRoute != "":
+		members, err := parseRoutePool(*flagRoute)
+		if err != nil {
+			log.Fatalf("Error: --route: %v", err)
+		}
+		names := make([]string, 0, len(members))
+		for _, m := range members {
+			names = append(names, m.name)
+		}
+		routeMembers = members
+		providersToTest = selectNamedProviders(allProviderConfigs, strings.Join(names, ","))
+	case *flagRace != "":
+		providersToTest = selectNamedProviders(allProviderConfigs, *flagRace)
+		if len(providersToTest) < 2 {
+			log.Fatal("Error: --race requires at least two providers, e.g. --race nim,novita")
+		}
 	case *testAll:
 		log.Println("--- Testing all configured providers... ---")
 		for name, config := range allProviderConfigs {
@@ -1676,6 +922,9 @@ func main() {
 			} else if name != "generic" {
 				// Don't log generic provider if not set, it's optional
 				log.Printf("... Skipping '%s': APIKey or Model not configured in .env\n", name)
+				if reason := partialConfigReason(config, os.Getenv); reason != "" {
+					skipProvider(name, reason)
+				}
 			}
 		}
 		// Check generic provider separately for --all
@@ -1708,36 +957,251 @@ func main() {
 		providersToTest = append(providersToTest, config)
 	}
 
+	if (*flagPerIP || *flagIPVersion == ipVersionBoth) && (*flagFailover != "" || *flagRoute != "" || *flagRace != "") {
+		log.Fatal("Error: --per-ip and --ip-version both cannot be combined with --failover, --route or --race")
+	}
+	if *flagPerIP && *flagIPVersion == ipVersionBoth {
+		log.Fatal("Error: --per-ip already tests every address of both families; use --ip-version 4 or 6 to keep one")
+	}
+	providersToTest = applyIPVersion(providersToTest, *flagIPVersion)
+	if *flagHTTP3 {
+		if *flagFailover != "" || *flagRoute != "" || *flagRace != "" {
+			log.Fatal("Error: --http3 cannot be combined with --failover, --route or --race")
+		}
+		providersToTest = applyHTTP3(providersToTest)
+	}
+	if *flagToolChoice != "" {
+		choices, err := parseToolChoices(*flagToolChoice)
+		if err != nil {
+			log.Fatalf("Error: --tool-choice: %v", err)
+		}
+		if mode, _, _ := resolveTestMode(*toolCalling, *mixed, *flagToolReasoningCheck); mode == ModeStreaming {
+			log.Fatal("Error: --tool-choice requires --tool-calling, --mixed or --tool-reasoning-check")
+		}
+		if len(choices) > 1 && (*flagFailover != "" || *flagRoute != "" || *flagRace != "") {
+			log.Fatal("Error: several --tool-choice values cannot be combined with --failover, --route or --race")
+		}
+		providersToTest = applyToolChoices(providersToTest, choices)
+	}
+	if *flagTwoPhase {
+		if mode, _, _ := resolveTestMode(*toolCalling, *mixed, *flagToolReasoningCheck); mode != ModeStreaming || *longStory {
+			log.Fatal("Error: --two-phase shapes streaming requests and cannot be combined with --tool-calling, --mixed, --tool-reasoning-check or --long-story")
+		}
+		if *flagFailover != "" || *flagRoute != "" || *flagRace != "" {
+			log.Fatal("Error: --two-phase cannot be combined with --failover, --route or --race")
+		}
+		providersToTest = applyProfiles(providersToTest)
+	}
+	if *flagPerIP {
+		providersToTest = expandPerIP(context.Background(), providersToTest)
+	}
+
 	if len(providersToTest) == 0 {
 		log.Fatal("No providers configured or selected to test.")
 	}
 
-	if *longStory {
-		log.Println("Test mode: Long-story (single long-form creative-writing prompt)")
+	// A preflight request would wake a scaled-to-zero endpoint before the
+	// cold-start probe gets to measure it
+	if !*flagNoPreflight && !*flagColdStart {
+		passed, failed := runPreflight(providersToTest, tke)
+		if len(failed) > 0 {
+			names := make([]string, len(failed))
+			for i, f := range failed {
+				names[i] = providerLabel(f.config.Name, f.config.Env)
+			}
+			// Failover, routing and races compare a fixed set of providers
+			if len(passed) == 0 || *flagFailover != "" || *flagRoute != "" || *flagRace != "" {
+				log.Fatalf("Error: preflight failed for %s; fix the configuration or pass --no-preflight", strings.Join(names, ", "))
+			}
+			log.Printf("Warning: skipping %s after failed preflight", strings.Join(names, ", "))
+			for _, f := range failed {
+				skipProvider(providerLabel(f.config.Name, f.config.Env), fmt.Sprintf("preflight failed: %v", f.err))
+			}
+		}
+		providersToTest = passed
+	}
+	if *flagStrict && len(sessionSkipped) > 0 {
+		for _, skipped := range sessionSkipped {
+			log.Printf("Skipped %s: %s", skipped.Name, skipped.Reason)
+		}
+		log.Fatalf("Error: --strict: %d configured provider(s) skipped", len(sessionSkipped))
+	}
 
-		var wgLong sync.WaitGroup
-		var results []TestResult
-		var resultsMutex sync.Mutex
+	// Record the effective configuration so the session can be replayed later
+	manifestMode, _, _ := resolveTestMode(*toolCalling, *mixed, *flagToolReasoningCheck)
+	manifestModeLabel := string(manifestMode)
+	switch {
+	case *flagFailover != "":
+		manifestModeLabel = failoverModeLabel
+	case *flagRace != "":
+		manifestModeLabel = raceModeLabel
+	case *flagRoute != "":
+		manifestModeLabel = routedModeLabel
+	case *flagSoak > 0:
+		manifestModeLabel = soakModeLabel
+	case configFile.Scenario != nil:
+		manifestModeLabel = scenarioModeLabel
+	case *flagColdStart:
+		manifestModeLabel = coldStartModeLabel
+	case *flagContextProbe:
+		manifestModeLabel = contextProbeModeLabel
+	case *flagMaxOutputProbe:
+		manifestModeLabel = maxOutputModeLabel
+	case *flagStructured:
+		manifestModeLabel = structuredModeLabel
+	case *flagCancelProbe:
+		manifestModeLabel = cancelProbeModeLabel
+	case *longStory:
+		manifestModeLabel = longStoryModeLabel
+	}
+	if *flagModelMetadata != "" {
+		metadata, err := loadModelMetadata(*flagModelMetadata, providersToTest)
+		if err != nil {
+			log.Printf("Warning: model metadata unavailable: %v", err)
+		} else {
+			sessionModelMetadata = metadata
+			log.Printf("Model metadata: found %d model(s) in %s", len(metadata), *flagModelMetadata)
+		}
+	}
+	manifest := buildManifest(sessionTimestamp, args, manifestModeLabel, providersToTest)
+	if rerunManifest != nil {
+		manifest.RerunOf = rerunManifest.Session
+	}
+	manifest.Name = *flagSessionName
+	manifest.Tags = sessionTags
+	manifest.Skipped = sessionSkipped
+	manifest.ConfigChanges = sessionConfigChanges()
+	recordTLS(&manifest, providersToTest)
+	recordNetwork(&manifest)
+	if err := writeManifest(sessionDir, manifest); err != nil {
+		log.Printf("Warning: Failed to write session manifest: %v", err)
+	}
+	// Signed last, once every mode below has written its results
+	defer signSessionIfEnabled(sessionDir)
 
-		for _, provider := range providersToTest {
-			if *testAll {
-				wgLong.Add(1)
-				go testProviderLongStory(provider, tke, &wgLong, logDir, resultsDir, &results, &resultsMutex)
-			} else {
-				testProviderLongStory(provider, tke, nil, logDir, resultsDir, &results, &resultsMutex)
-			}
+	restoreTerminal := func() {}
+	if !*flagNoKeys {
+		restoreTerminal = startKeyBindings()
+	}
+	defer interruptGracefully(restoreTerminal)()
+
+	if *flagFailover != "" {
+		if err := runFailoverSimulation(providersToTest, tke, *flagFailoverTTFT, *flagFailoverRuns, resultsDir, sessionTimestamp); err != nil {
+			log.Printf("Warning: Failover simulation failed: %v", err)
 		}
+		logBudgetUsage()
+		log.Printf("Failover simulation complete. Results saved to: %s/", sessionDir)
+		return
+	}
+
+	clientFootprint.start(footprintInterval)
+	if ceiling, err := loadSelfTest(selfTestPath()); err == nil {
+		clientCeiling = ceiling
+	}
+
+	if *flagColdStart {
+		opts := coldStartOptions{
+			idle:        *flagColdStartIdle,
+			cycles:      *flagColdStartCycles,
+			warmRuns:    *flagColdStartWarm,
+			wakeTimeout: *flagColdStartTimeout,
+		}
+		if err := runColdStartProbe(providersToTest, tke, opts, resultsDir, sessionTimestamp); err != nil {
+			log.Printf("Warning: Cold start probe failed: %v", err)
+		}
+		logBudgetUsage()
+		log.Printf("Cold start probe complete. Results saved to: %s/", sessionDir)
+		return
+	}
+
+	if *flagContextProbe {
+		opts := contextProbeOptions{
+			start:     *flagContextProbeStart,
+			max:       *flagContextProbeMax,
+			precision: *flagContextProbePrecision,
+		}
+		if err := runContextProbe(providersToTest, tke, opts, resultsDir, sessionTimestamp); err != nil {
+			log.Printf("Warning: Context window probe failed: %v", err)
+		}
+		logBudgetUsage()
+		log.Printf("Context window probe complete. Results saved to: %s/", sessionDir)
+		return
+	}
+
+	if *flagMaxOutputProbe {
+		opts := maxOutputOptions{tokens: *flagMaxOutputTokens, runs: *flagMaxOutputRuns}
+		if err := runMaxOutputProbe(providersToTest, tke, opts, resultsDir, sessionTimestamp); err != nil {
+			log.Printf("Warning: Max-output probe failed: %v", err)
+		}
+		logBudgetUsage()
+		log.Printf("Max-output probe complete. Results saved to: %s/", sessionDir)
+		return
+	}
+
+	if *flagStructured {
+		opts := structuredOptions{runs: *flagStructuredRuns}
+		if err := runStructuredBenchmark(providersToTest, tke, opts, resultsDir, sessionTimestamp); err != nil {
+			log.Printf("Warning: Structured output benchmark failed: %v", err)
+		}
+		logBudgetUsage()
+		log.Printf("Structured output benchmark complete. Results saved to: %s/", sessionDir)
+		return
+	}
+
+	if *flagCancelProbe {
+		opts := cancelProbeOptions{after: *flagCancelAfter, runs: *flagCancelRuns}
+		if err := runCancelProbe(providersToTest, tke, opts, resultsDir, sessionTimestamp); err != nil {
+			log.Printf("Warning: Cancel probe failed: %v", err)
+		}
+		logBudgetUsage()
+		log.Printf("Cancel probe complete. Results saved to: %s/", sessionDir)
+		return
+	}
+
+	if *flagRoute != "" {
+		routeConfigs := make(map[string]ProviderConfig, len(providersToTest))
+		for _, p := range providersToTest {
+			routeConfigs[p.Name] = p
+		}
+		if err := runRoutedBenchmark(routeMembers, routeConfigs, tke, *flagRouteRuns, resultsDir, sessionTimestamp); err != nil {
+			log.Printf("Warning: Routed benchmark failed: %v", err)
+		}
+		logBudgetUsage()
+		log.Printf("Routed benchmark complete. Results saved to: %s/", sessionDir)
+		return
+	}
+
+	if *flagRace != "" {
+		if err := runRaceBenchmark(providersToTest, tke, *flagRaceRuns, resultsDir, sessionTimestamp); err != nil {
+			log.Printf("Warning: Race benchmark failed: %v", err)
+		}
+		logBudgetUsage()
+		log.Printf("Race benchmark complete. Results saved to: %s/", sessionDir)
+		return
+	}
+
+	if *longStory {
+		log.Println("Test mode: Long-story (single long-form creative-writing prompt)")
+
+		_ = runPool(context.Background(), providerLimit(*testAll), len(providersToTest), func(_ context.Context, i int) error {
+			testProviderLongStory(providersToTest[i], tke, logDir, resultsDir)
+			return nil
+		})
 
 		if *testAll {
-			wgLong.Wait()
 			log.Println("--- All long-story provider tests complete. ---")
 		}
 
 		log.Println("Generating summary report...")
-		if err := generateMarkdownReport(resultsDir, results, sessionTimestamp); err != nil {
+		if err := generateSessionReports(resultsDir, []string{resultsDir}, sessionTimestamp); err != nil {
 			log.Printf("Warning: Failed to generate report: %v", err)
 		}
+		if err := updateLeaderboard(filepath.Dir(sessionDir)); err != nil {
+			log.Printf("Warning: Failed to update leaderboard: %v", err)
+		}
 
+		saveClientFootprint(resultsDir)
+		logBudgetUsage()
 		log.Printf("All long-story tests complete. Results saved to: %s/", sessionDir)
 		return
 	}
@@ -1765,65 +1229,79 @@ func main() {
 	}
 
 	// 6. Run Tests
+	if *flagSoak > 0 {
+		opts := soakOptions{
+			duration:    *flagSoak,
+			interval:    *flagSoakInterval,
+			checkpoint:  *flagSoakCheckpoint,
+			logMaxBytes: int64(*flagSoakLogMaxMB) << 20,
+
+			keepWarm:       *flagKeepWarm,
+			keepWarmTokens: *flagKeepWarmTokens,
+		}
+		if err := runSoakTest(providersToTest, tke, testMode, toolReasoningCheck, opts, logDir, resultsDir, sessionTimestamp); err != nil {
+			log.Printf("Warning: Soak test failed: %v", err)
+		}
+		logBudgetUsage()
+		log.Printf("Soak test complete. Results saved to: %s/", sessionDir)
+		return
+	}
+
+	if configFile.Scenario != nil {
+		if err := runScenario(providersToTest, tke, testMode, toolReasoningCheck, *configFile.Scenario, logDir, resultsDir, sessionTimestamp); err != nil {
+			log.Printf("Warning: Scenario failed: %v", err)
+		}
+		logBudgetUsage()
+		log.Printf("Scenario complete. Results saved to: %s/", sessionDir)
+		return
+	}
+
 	if *diagnostic {
 		// Run diagnostic mode
 		log.Println("=== RUNNING IN DIAGNOSTIC MODE ===")
 
-		var diagnosticResults []DiagnosticSummary
-		var diagnosticMutex sync.Mutex
-
-		if len(providersToTest) > 1 {
-			// Run multiple providers concurrently
-			var diagnosticWg sync.WaitGroup
-			for _, provider := range providersToTest {
-				diagnosticWg.Add(1)
-				go diagnosticMode(provider, tke, logDir, resultsDir, testMode, toolReasoningCheck, &diagnosticWg, &diagnosticResults, &diagnosticMutex)
-			}
-			diagnosticWg.Wait()
-		} else {
-			// Single provider (no concurrency needed)
-			for _, provider := range providersToTest {
-				diagnosticMode(provider, tke, logDir, resultsDir, testMode, toolReasoningCheck, nil, &diagnosticResults, &diagnosticMutex)
-			}
-		}
+		// Run every provider concurrently, up to --max-parallel-providers
+		_ = runPool(context.Background(), providerLimit(true), len(providersToTest), func(_ context.Context, i int) error {
+			diagnosticMode(providersToTest[i], tke, logDir, resultsDir, testMode, toolReasoningCheck)
+			return nil
+		})
 
 		log.Println("--- All diagnostic tests complete. ---")
 
 		// Generate diagnostic report
 		log.Println("Generating diagnostic summary report...")
-		if err := generateDiagnosticReport(resultsDir, diagnosticResults, sessionTimestamp); err != nil {
+		if err := generateSessionReports(resultsDir, []string{resultsDir}, sessionTimestamp); err != nil {
 			log.Printf("Warning: Failed to generate diagnostic report: %v", err)
 		}
+		if err := updateLeaderboard(filepath.Dir(sessionDir)); err != nil {
+			log.Printf("Warning: Failed to update leaderboard: %v", err)
+		}
 
+		saveClientFootprint(resultsDir)
+		logBudgetUsage()
 		log.Printf("Diagnostic tests complete. Results saved to: %s/", sessionDir)
 		return
 	}
-	var wg sync.WaitGroup
-	var results []TestResult
-	var resultsMutex sync.Mutex
-
-	for _, provider := range providersToTest {
-		if *testAll {
-			// Run all tests concurrently
-			wg.Add(1)
-			go testProviderMetrics(provider, tke, &wg, logDir, resultsDir, &results, &resultsMutex, testMode, toolReasoningCheck)
-		} else {
-			// Run a single test sequentially
-			testProviderMetrics(provider, tke, nil, logDir, resultsDir, &results, &resultsMutex, testMode, toolReasoningCheck)
-		}
-	}
-
-	// Wait for all concurrent tests to finish
+	// Run tests concurrently with --all (up to --max-parallel-providers),
+	// otherwise one provider at a time
+	_ = runPool(context.Background(), providerLimit(*testAll), len(providersToTest), func(_ context.Context, i int) error {
+		testProviderMetrics(providersToTest[i], tke, logDir, resultsDir, testMode, toolReasoningCheck)
+		return nil
+	})
 	if *testAll {
-		wg.Wait()
 		log.Println("--- All provider tests complete. ---")
 	}
 
 	// Generate markdown report
 	log.Println("Generating summary report...")
-	if err := generateMarkdownReport(resultsDir, results, sessionTimestamp); err != nil {
+	if err := generateSessionReports(resultsDir, []string{resultsDir}, sessionTimestamp); err != nil {
 		log.Printf("Warning: Failed to generate report: %v", err)
 	}
+	if err := updateLeaderboard(filepath.Dir(sessionDir)); err != nil {
+		log.Printf("Warning: Failed to update leaderboard: %v", err)
+	}
 
+	saveClientFootprint(resultsDir)
+	logBudgetUsage()
 	log.Printf("All tests complete. Results saved to: %s/", sessionDir)
 }
@@ -4,6 +4,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestProviderConfig(t *testing.T) {
@@ -172,3 +173,20 @@ func TestLongStoryPrompts(t *testing.T) {
 		t.Fatalf("longStoryUserPrompt must end with 'Write the story now:'")
 	}
 }
+
+func TestRequestLimits(t *testing.T) {
+	defer func() { maxTokens, runTimeout = 0, 0 }()
+	if requestMaxTokens(defaultMaxTokens) != 512 || benchmarkTimeout(defaultRunTimeout) != 5*time.Minute {
+		t.Fatalf("expected mode defaults without flags")
+	}
+	maxTokens, runTimeout = 128, time.Minute
+	if requestMaxTokens(defaultLongStoryMaxTokens) != 128 || benchmarkTimeout(defaultLongStoryTimeout) != time.Minute {
+		t.Fatalf("expected --max-tokens and --timeout to override mode defaults")
+	}
+	if got := streamingRequest(ProviderConfig{Name: "nim"}, "run1"); got.MaxTokens != 128 {
+		t.Fatalf("expected streaming requests to use --max-tokens, got %d", got.MaxTokens)
+	}
+	if got := streamingRequest(ProviderConfig{Name: "nim", Profile: profileLatency}, "run1"); got.MaxTokens != 64 {
+		t.Fatalf("expected profiles to keep their own limit, got %d", got.MaxTokens)
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// manifestFileName is the name of the manifest written into every session folder.
+const manifestFileName = "manifest.json"
+
+// redactedValue replaces secrets in manifests.
+const redactedValue = "REDACTED"
+
+// version is the tool version, set at build time via -ldflags "-X main.version=...".
+var version = "dev"
+
+// SessionManifest records everything needed to understand and replay a session.
+type SessionManifest struct {
+	Session     string             `json:"session"`
+	Name        string             `json:"name,omitempty"`
+	Tags        []string           `json:"tags,omitempty"`
+	CreatedAt   time.Time          `json:"createdAt"`
+	ToolVersion string             `json:"toolVersion"`
+	Args        []string           `json:"args"`
+	Flags       map[string]string  `json:"flags"`
+	Mode        string             `json:"mode"`
+	Providers   []ManifestProvider `json:"providers"`
+	Prompts     map[string]string  `json:"prompts"`
+	Tools       []openai.Tool      `json:"tools"`
+	Seed        uint64             `json:"seed,omitempty"`
+	Environment ManifestEnv        `json:"environment"`
+	RerunOf     string             `json:"rerunOf,omitempty"`
+	// Skipped lists configured providers the session did not benchmark.
+	Skipped []SkippedProvider `json:"skipped,omitempty"`
+	// ConfigChanges are the config reloads the daemon applied since the
+	// tenant's previous run.
+	ConfigChanges []ConfigChange `json:"configChanges,omitempty"`
+}
+
+// ManifestProvider is the redacted effective configuration of one provider.
+type ManifestProvider struct {
+	Name        string  `json:"name"`
+	BaseURL     string  `json:"baseUrl"`
+	Model       string  `json:"model"`
+	Env         string  `json:"env,omitempty"`
+	APIKey      string  `json:"apiKey"`
+	APIKeyCount int     `json:"apiKeyCount,omitempty"`
+	InputPrice  float64 `json:"inputPrice,omitempty"`
+	OutputPrice float64 `json:"outputPrice,omitempty"`
+	// TLS is the endpoint's negotiated TLS setup, probed at session start.
+	TLS *TLSInfo `json:"tls,omitempty"`
+	// UserAgent is the User-Agent the provider's requests were sent with.
+	UserAgent string `json:"userAgent,omitempty"`
+	// API is the provider's wire protocol when not OpenAI-compatible.
+	API string `json:"api,omitempty"`
+	// Notes and DashboardURL come from the provider's [provider.<name>] table
+	// in --config.
+	Notes        string `json:"notes,omitempty"`
+	DashboardURL string `json:"dashboardUrl,omitempty"`
+	// ModelMetadata is the model's size as found with --model-metadata.
+	ModelMetadata *ModelMetadata `json:"modelMetadata,omitempty"`
+}
+
+// ManifestEnv describes the host the session ran on.
+type ManifestEnv struct {
+	GoVersion string `json:"goVersion"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	NumCPU    int    `json:"numCpu"`
+	// Network is the client's network path, snapshotted at session start.
+	Network *NetworkSnapshot `json:"network,omitempty"`
+}
+
+// buildManifest assembles the manifest for a session from the parsed flags and
+// the providers selected for testing.
+func buildManifest(sessionTimestamp string, args []string, mode string, providers []ProviderConfig) SessionManifest {
+	flags := make(map[string]string)
+	flag.VisitAll(func(f *flag.Flag) {
+		flags[f.Name] = f.Value.String()
+	})
+
+	manifestProviders := make([]ManifestProvider, 0, len(providers))
+	for _, p := range providers {
+		apiKey := ""
+		if p.APIKey != "" {
+			apiKey = redactedValue
+		}
+		manifestProviders = append(manifestProviders, ManifestProvider{
+			Name:         p.Name,
+			BaseURL:      p.BaseURL,
+			Model:        p.Model,
+			Env:          p.Env,
+			APIKey:       apiKey,
+			APIKeyCount:  len(p.APIKeys),
+			InputPrice:   p.InputPrice,
+			OutputPrice:  p.OutputPrice,
+			UserAgent:    p.userAgent(),
+			API:          p.API,
+			Notes:        sessionProviderNotes[p.Name].Notes,
+			DashboardURL: sessionProviderNotes[p.Name].DashboardURL,
+		})
+		if m, ok := sessionModelMetadata[p.Model]; ok {
+			manifestProviders[len(manifestProviders)-1].ModelMetadata = &m
+		}
+	}
+
+	prompts := map[string]string{
+		string(ModeStreaming):   sessionPrompt(promptGroupStreaming, currentPromptPack().prompts[0]),
+		string(ModeToolCalling): sessionPrompt(promptGroupToolCalling, toolCallingPrompt),
+		longStoryModeLabel:      longStorySystemPrompt + "\n\n" + sessionPrompt(promptGroupLongStory, longStoryUserPrompt),
+	}
+	var seed uint64
+	if _, ok := customPrompts[promptGroupStreaming]; rotatePrompts && !ok {
+		seed = promptSeed
+		for i, p := range currentPromptPack().prompts {
+			prompts[fmt.Sprintf("%s-pool-%d", ModeStreaming, i)] = p
+		}
+	}
+
+	return SessionManifest{
+		Session:     sessionTimestamp,
+		CreatedAt:   time.Now(),
+		ToolVersion: version,
+		Args:        args,
+		Flags:       flags,
+		Mode:        mode,
+		Providers:   manifestProviders,
+		Prompts:     prompts,
+		Tools:       weatherTools(),
+		Seed:        seed,
+		Environment: ManifestEnv{
+			GoVersion: runtime.Version(),
+			OS:        runtime.GOOS,
+			Arch:      runtime.GOARCH,
+			NumCPU:    runtime.NumCPU(),
+		},
+	}
+}
+
+// writeManifest saves the manifest into the session folder.
+func writeManifest(sessionDir string, manifest SessionManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling manifest: %w", err)
+	}
+	filename := filepath.Join(sessionDir, manifestFileName)
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		return fmt.Errorf("error writing manifest: %w", err)
+	}
+	log.Printf("Session manifest saved: %s", filename)
+	return nil
+}
+
+// resolveSessionDir accepts either a session folder path or a bare session name
+// (e.g. "session-20251110-004615") and returns the folder path.
+func resolveSessionDir(session string) string {
+	if info, err := os.Stat(session); err == nil && info.IsDir() {
+		return session
+	}
+	return filepath.Join(resultsRoot, session)
+}
+
+// loadManifest reads the manifest of a previous session.
+func loadManifest(session string) (SessionManifest, error) {
+	var manifest SessionManifest
+	filename := filepath.Join(resolveSessionDir(session), manifestFileName)
+	data, err := os.ReadFile(filepath.Clean(filename))
+	if err != nil {
+		return manifest, fmt.Errorf("error reading manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("error parsing manifest %s: %w", filename, err)
+	}
+	return manifest, nil
+}
+
+// pinManifestProviders pins each recorded provider's base URL and model to the
+// values stored in the manifest so a rerun targets exactly the same endpoints.
+// API keys are never stored and still come from the environment.
+func pinManifestProviders(configs map[string]ProviderConfig, manifest SessionManifest) {
+	for _, m := range manifest.Providers {
+		config, ok := configs[m.Name]
+		if !ok {
+			log.Printf("Rerun: provider '%s' from manifest is not known to this version, skipping", m.Name)
+			continue
+		}
+		if config.Model != m.Model || config.BaseURL != m.BaseURL {
+			log.Printf("Rerun: pinning '%s' to recorded model %s at %s", m.Name, m.Model, m.BaseURL)
+		}
+		config.Model = m.Model
+		config.BaseURL = m.BaseURL
+		config.Env = m.Env
+		if m.API != "" {
+			config.API = m.API
+		}
+		if m.UserAgent != "" {
+			config.UserAgent = m.UserAgent
+		}
+		configs[m.Name] = config
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestManifestRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	providers := []ProviderConfig{
+		{Name: "nim", BaseURL: "https://integrate.api.nvidia.com/v1", APIKey: "secret-key", Model: "model-a"},
+	}
+
+	manifest := buildManifest("20250101-000000", []string{"--provider", "nim"}, string(ModeStreaming), providers)
+	if err := writeManifest(dir, manifest); err != nil {
+		t.Fatalf("writeManifest failed: %v", err)
+	}
+
+	loaded, err := loadManifest(dir)
+	if err != nil {
+		t.Fatalf("loadManifest failed: %v", err)
+	}
+	if strings.Join(loaded.Args, " ") != "--provider nim" {
+		t.Fatalf("unexpected args: %v", loaded.Args)
+	}
+	if loaded.Providers[0].APIKey != redactedValue {
+		t.Fatalf("expected API key to be redacted, got %q", loaded.Providers[0].APIKey)
+	}
+
+	configs := map[string]ProviderConfig{
+		"nim": {Name: "nim", BaseURL: "https://other.example.com/v1", APIKey: "k", Model: "model-b"},
+	}
+	pinManifestProviders(configs, loaded)
+	if configs["nim"].Model != "model-a" || configs["nim"].BaseURL != "https://integrate.api.nvidia.com/v1" {
+		t.Fatalf("expected provider to be pinned to manifest values, got %+v", configs["nim"])
+	}
+	if configs["nim"].APIKey != "k" {
+		t.Fatalf("expected API key to come from the environment, got %q", configs["nim"].APIKey)
+	}
+}
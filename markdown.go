@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	mdreport "github.com/lamim/llm-api-speed/pkg/report"
+)
+
+// bind fixes the results a section writer reports on, so it can be passed
+// as one of a document's sections.
+func bind[T any](write func(*strings.Builder, T), results T) mdreport.Section {
+	return func(report *strings.Builder) { write(report, results) }
+}
+
+// reportDocument returns the frame shared by the session and diagnostic
+// reports: the notes on tags, usage, blinding and filters, the target token
+// count and the threshold grading.
+func reportDocument(title, session string, usage UsageTotals) mdreport.Document {
+	notes := []mdreport.Section{tagsNote, bind(usageNote, usage)}
+	if blindReports {
+		notes = append(notes, blindNote)
+	}
+	notes = append(notes, filterNote)
+	return mdreport.Document{
+		Title:        title,
+		Session:      session,
+		Notes:        notes,
+		TargetTokens: targetTokens,
+		Grade:        gradeCell,
+		Legend:       writeThresholdLegend,
+	}
+}
+
+// testResultEntry returns a test result's line in the session report.
+func testResultEntry(r TestResult) mdreport.Entry {
+	return mdreport.Entry{
+		Label:        providerLabel(r.Provider, r.Env),
+		Model:        r.Model,
+		Mode:         r.Mode,
+		Success:      r.Success,
+		Error:        r.Error,
+		E2E:          r.E2ELatency,
+		TTFT:         r.TTFT,
+		Throughput:   r.Throughput,
+		Tokens:       r.CompletionTokens,
+		ProjectedE2E: r.ProjectedE2E,
+		Cells:        customCells(r),
+	}
+}
+
+// diagnosticEntry returns a diagnostic summary's line in the diagnostic
+// report.
+func diagnosticEntry(r DiagnosticSummary) mdreport.Entry {
+	return mdreport.Entry{
+		Label:        providerLabel(r.Provider, r.Env),
+		Model:        r.Model,
+		Mode:         r.Mode,
+		Requests:     r.TotalRequests,
+		Successful:   r.Successful,
+		Failed:       r.Failed,
+		Errors:       r.Errors,
+		E2E:          r.AvgE2ELatency,
+		TTFT:         r.AvgTTFT,
+		Throughput:   r.AvgThroughput,
+		Tokens:       r.AvgTokens,
+		ProjectedE2E: r.ProjectedE2E,
+	}
+}
+
+// generateMarkdownReport creates a summary report of all test results.
+func generateMarkdownReport(resultsDir string, results []TestResult, sessionTimestamp string) error {
+	filename := filepath.Join(resultsDir, "REPORT.md")
+
+	doc := reportDocument("LLM API Speed Test Results", sessionTimestamp, sessionUsage(results, nil))
+	doc.Columns = customColumnNames()
+	doc.Sections = []mdreport.Section{
+		bind(writeWinRateSection, results),
+		bind(writeSpreadSection, results),
+		bind(writeLengthNormalizedSection, results),
+		bind(writeChunkStatsSection, results),
+		bind(writeITLSection, results),
+		bind(writeToolArgsSection, results),
+		bind(writeCurveSection, results),
+		bind(writeKeyStatsSection, results),
+		bind(writeQuotaSection, results),
+		bind(writeServerMetricsSection, results),
+		bind(writeLiteLLMSection, results),
+		bind(writeNetworkBaselineSection, results),
+		bind(writeIPVersionSection, results),
+		bind(writeHTTPVersionSection, results),
+		bind(writeToolChoiceSection, results),
+		bind(writeProfileSection, results),
+		bind(writeInstanceSection, results),
+		bind(writeDeadlineSection, results),
+		bind(writeSpeedQualitySection, results),
+		bind(writeOutputQualitySection, results),
+		bind(writeLanguageTokenSection, results),
+		bind(writeTokenCrossCheckSection, results),
+		bind(writeTokenCountSection, results),
+		bind(writeEnvironmentSection, results),
+		bind(writeProviderNotesSection, results),
+		bind(writeModelMetadataSection, results),
+		bind(writeEfficiencySection, results),
+		bind(writeReferenceSection, results),
+		writeClientFootprintSection,
+		writeSkippedProvidersSection,
+	}
+
+	entries := make([]mdreport.Entry, len(results))
+	for i, r := range results {
+		entries[i] = testResultEntry(r)
+	}
+
+	var report bytes.Buffer
+	if err := mdreport.WriteSession(&report, doc, entries); err != nil {
+		return fmt.Errorf("error writing report: %w", err)
+	}
+	if err := os.WriteFile(filename, report.Bytes(), 0600); err != nil {
+		return fmt.Errorf("error writing report: %w", err)
+	}
+
+	log.Printf("Report generated: %s", filename)
+	return nil
+}
+
+// generateDiagnosticReport creates a markdown report for diagnostic mode results.
+func generateDiagnosticReport(resultsDir string, results []DiagnosticSummary, sessionTimestamp string) error {
+	filename := filepath.Join(resultsDir, "DIAGNOSTIC-REPORT.md")
+	heatmap, err := writeDiagnosticHeatmap(resultsDir, results)
+	if err != nil {
+		return err
+	}
+
+	doc := reportDocument("LLM API Diagnostic Mode Results", sessionTimestamp, sessionUsage(nil, results))
+	doc.Notes = append(doc.Notes, func(report *strings.Builder) {
+		report.WriteString("**Test Duration:** 90 seconds per provider\n")
+		if diagnosticAutoscale {
+			fmt.Fprintf(report, "**Workers:** %d concurrent workers, autoscaled up to %d\n", diagnosticWorkers, diagnosticMaxWorkers)
+		} else {
+			fmt.Fprintf(report, "**Workers:** %d concurrent workers\n", diagnosticWorkers)
+		}
+		report.WriteString("**Request Frequency:** Every 15 seconds per worker\n")
+		report.WriteString("**Timeout:** 30 seconds per request\n\n")
+	})
+	doc.Sections = []mdreport.Section{
+		bind(writeDiagnosticSpreadSection, results),
+		bind(writeDiagnosticScalingSection, results),
+		bind(writeHeatmapSection, heatmap),
+		bind(writeDiagnosticLengthNormalizedSection, results),
+		bind(writeDiagnosticChunkStatsSection, results),
+		bind(writeDiagnosticITLSection, results),
+		bind(writeDiagnosticToolArgsSection, results),
+		bind(writeDiagnosticCurveSection, results),
+		bind(writeDiagnosticKeyStatsSection, results),
+		bind(writeDiagnosticQuotaSection, results),
+		bind(writeDiagnosticServerMetricsSection, results),
+		bind(writeDiagnosticLiteLLMSection, results),
+		bind(writeDiagnosticNetworkBaselineSection, results),
+		bind(writeDiagnosticIPVersionSection, results),
+		bind(writeDiagnosticHTTPVersionSection, results),
+		bind(writeDiagnosticToolChoiceSection, results),
+		bind(writeDiagnosticProfileSection, results),
+		bind(writeDiagnosticInstanceSection, results),
+		bind(writeDiagnosticDeadlineSection, results),
+		bind(writeDiagnosticOutputQualitySection, results),
+		bind(writeDiagnosticEnvironmentSection, results),
+		bind(writeDiagnosticProviderNotesSection, results),
+		bind(writeDiagnosticModelMetadataSection, results),
+		bind(writeDiagnosticEfficiencySection, results),
+		bind(writeDiagnosticReferenceSection, results),
+		writeClientFootprintSection,
+		writeSkippedProvidersSection,
+	}
+
+	entries := make([]mdreport.Entry, len(results))
+	for i, r := range results {
+		entries[i] = diagnosticEntry(r)
+	}
+
+	var report bytes.Buffer
+	if err := mdreport.WriteDiagnostic(&report, doc, entries); err != nil {
+		return fmt.Errorf("error writing diagnostic report: %w", err)
+	}
+	if err := os.WriteFile(filename, report.Bytes(), 0600); err != nil {
+		return fmt.Errorf("error writing diagnostic report: %w", err)
+	}
+
+	log.Printf("Diagnostic report generated: %s", filename)
+	return nil
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lamim/llm-api-speed/pkg/providers"
+	"github.com/pkoukk/tiktoken-go"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const maxOutputModeLabel = "max-output"
+
+// maxOutputTimeout bounds one probe request; tens of thousands of tokens take
+// minutes even on fast endpoints.
+const maxOutputTimeout = 30 * time.Minute
+
+// maxOutputPrompt asks for output that never ends on its own, so the response
+// can only stop at an output limit.
+const maxOutputPrompt = "Count upward from 1, writing each number in English words on its own line " +
+	"(one, two, three, ...). Do not stop, summarize or comment; keep counting for as long as you can."
+
+// maxOutputReachedRatio is the share of the requested max_tokens a response
+// must reach to count as honoring it, leaving room for the local tokenizer
+// counting differently from the model's.
+const maxOutputReachedRatio = 0.95
+
+// How a max-output probe response ended relative to the requested limit.
+const (
+	// outputReached: the response reached the requested max_tokens.
+	outputReached = "reached"
+	// outputCapped: cut short and reported as finish_reason "length", an
+	// honest provider-side ceiling below the request.
+	outputCapped = "capped"
+	// outputStopped: cut short but reported as "stop", although the prompt
+	// never ends on its own; either the model gave up or a silent cap.
+	outputStopped = "stopped"
+	// outputUnreported: the stream ended without any finish_reason.
+	outputUnreported = "unreported"
+)
+
+// maxOutputOptions configures a max-output probe session.
+type maxOutputOptions struct {
+	tokens int
+	runs   int
+}
+
+// MaxOutputRun is one long-output request of a max-output probe.
+type MaxOutputRun struct {
+	Run          int           `json:"run"`
+	Tokens       int           `json:"tokens"`
+	FinishReason string        `json:"finishReason,omitempty"`
+	Verdict      string        `json:"verdict,omitempty"`
+	E2E          time.Duration `json:"e2e"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// MaxOutputSummary is the output ceiling observed for one provider.
+type MaxOutputSummary struct {
+	Provider  string         `json:"provider"`
+	Model     string         `json:"model"`
+	Requested int            `json:"requested"`
+	Ceiling   int            `json:"ceiling"`
+	Runs      []MaxOutputRun `json:"runs"`
+}
+
+// maxOutputVerdict classifies a response of tokens tokens that ended with
+// finishReason, given the requested limit.
+func maxOutputVerdict(tokens, requested int, finishReason string) string {
+	switch {
+	case float64(tokens) >= maxOutputReachedRatio*float64(requested):
+		return outputReached
+	case finishReason == "length":
+		return outputCapped
+	case finishReason == "":
+		return outputUnreported
+	}
+	return outputStopped
+}
+
+// honest reports whether s's finish reasons matched what happened: every cut
+// short response said "length".
+func (s MaxOutputSummary) honest() bool {
+	for _, r := range s.Runs {
+		if r.Verdict == outputStopped || r.Verdict == outputUnreported {
+			return false
+		}
+	}
+	return true
+}
+
+// probeMaxOutput streams one never-ending response with max_tokens set to
+// requested and counts how much arrives before it ends.
+func probeMaxOutput(config ProviderConfig, tke *tiktoken.Tiktoken, requested, run int) MaxOutputRun {
+	result := MaxOutputRun{Run: run}
+	req := openai.ChatCompletionRequest{
+		Model:     config.Model,
+		Messages:  []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: maxOutputPrompt}},
+		MaxTokens: requested,
+		Stream:    true,
+	}
+	req = bustCache(config.Quirks.apply(req))
+
+	ctx, cancel := context.WithTimeout(shutdownCtx, maxOutputTimeout)
+	defer cancel()
+	start := time.Now()
+	stream, err := providers.OpenStream(ctx, benchProvider(config), req)
+	if err != nil {
+		result.Error = fmt.Sprintf("error creating stream: %v", err)
+		return result
+	}
+	defer func() {
+		_ = stream.Close()
+	}()
+
+	var output strings.Builder
+	for {
+		delta, recvErr := stream.Recv()
+		if errors.Is(recvErr, io.EOF) {
+			break
+		}
+		if recvErr != nil {
+			result.Error = fmt.Sprintf("stream error: %v", recvErr)
+			break
+		}
+		output.WriteString(delta.Content)
+		output.WriteString(delta.Reasoning)
+		if delta.FinishReason != "" {
+			result.FinishReason = delta.FinishReason
+		}
+	}
+	result.E2E = time.Since(start)
+	result.Tokens = len(tke.Encode(output.String(), nil, nil))
+	sessionBudget.record(config, countPromptTokens(tke, req.Messages), result.Tokens)
+	if result.Error == "" {
+		result.Verdict = maxOutputVerdict(result.Tokens, requested, result.FinishReason)
+	}
+	return result
+}
+
+// probeMaxOutputs runs the max-output probe opts.runs times for one provider.
+func probeMaxOutputs(config ProviderConfig, tke *tiktoken.Tiktoken, opts maxOutputOptions) MaxOutputSummary {
+	summary := MaxOutputSummary{Provider: config.Name, Model: config.Model, Requested: opts.tokens}
+	for run := 1; run <= opts.runs; run++ {
+		if err := canStartRun(); err != nil {
+			log.Printf("[%s] Max-output run %d skipped: %v", config.Name, run, err)
+			break
+		}
+		r := probeMaxOutput(config, tke, opts.tokens, run)
+		if r.Error != "" {
+			log.Printf("[%s] Max-output run %d failed after %d tokens: %s", config.Name, run, r.Tokens, r.Error)
+		} else {
+			log.Printf("[%s] Max-output run %d: %d/%d tokens, finish_reason=%q (%s)",
+				config.Name, run, r.Tokens, opts.tokens, r.FinishReason, r.Verdict)
+		}
+		summary.Ceiling = max(summary.Ceiling, r.Tokens)
+		summary.Runs = append(summary.Runs, r)
+	}
+	return summary
+}
+
+// runMaxOutputProbe probes every provider concurrently and writes
+// max-output-summary.json and MAX-OUTPUT-REPORT.md.
+func runMaxOutputProbe(providers []ProviderConfig, tke *tiktoken.Tiktoken, opts maxOutputOptions, resultsDir, sessionTimestamp string) error {
+	log.Printf("=== MAX-OUTPUT PROBE: %d provider(s), max_tokens %d, %d run(s) ===", len(providers), opts.tokens, opts.runs)
+
+	summaries := make([]MaxOutputSummary, len(providers))
+	var wg sync.WaitGroup
+	for i, p := range providers {
+		wg.Add(1)
+		go func(i int, p ProviderConfig) {
+			defer wg.Done()
+			summaries[i] = probeMaxOutputs(p, tke, opts)
+		}(i, p)
+	}
+	wg.Wait()
+
+	data, err := json.MarshalIndent(summaries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling max-output summary: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(resultsDir, "max-output-summary.json"), data, 0600); err != nil {
+		return fmt.Errorf("error writing max-output summary: %w", err)
+	}
+	return generateMaxOutputReport(resultsDir, summaries, sessionTimestamp)
+}
+
+// generateMaxOutputReport writes MAX-OUTPUT-REPORT.md.
+func generateMaxOutputReport(resultsDir string, summaries []MaxOutputSummary, sessionTimestamp string) error {
+	filename := filepath.Join(resultsDir, "MAX-OUTPUT-REPORT.md")
+
+	var report strings.Builder
+	report.WriteString("# LLM API Max-Output Probe\n\n")
+	fmt.Fprintf(&report, "**Test Session:** %s\n\n", sessionTimestamp)
+	fmt.Fprintf(&report, "Each run asks for output that never ends on its own, with max_tokens set to the requested limit. "+
+		"Tokens are counted with the local tokenizer; a response counts as reaching the limit at %.0f%% of it. "+
+		"A response cut short should report finish_reason \"length\"; \"stop\" or no finish reason is flagged ⚠.\n\n",
+		100*maxOutputReachedRatio)
+	report.WriteString("---\n\n")
+
+	report.WriteString("## Summary\n\n")
+	report.WriteString("| Provider | Model | Requested | Observed Ceiling | Finish Reason Honest |\n")
+	report.WriteString("|----------|-------|-----------|------------------|----------------------|\n")
+	for _, s := range summaries {
+		honest := "yes"
+		if !s.honest() {
+			honest = "⚠ no"
+		}
+		fmt.Fprintf(&report, "| %s | %s | %d | %d | %s |\n", s.Provider, s.Model, s.Requested, s.Ceiling, honest)
+	}
+	report.WriteString("\n")
+
+	report.WriteString("## Runs\n\n")
+	report.WriteString("| Provider | Run | Tokens | Finish Reason | Verdict | E2E | Error |\n")
+	report.WriteString("|----------|-----|--------|---------------|---------|-----|-------|\n")
+	for _, s := range summaries {
+		for _, r := range s.Runs {
+			verdict := r.Verdict
+			if verdict == outputStopped || verdict == outputUnreported {
+				verdict = "⚠ " + verdict
+			}
+			fmt.Fprintf(&report, "| %s | %d | %d | %s | %s | %s | %s |\n",
+				s.Provider, r.Run, r.Tokens, r.FinishReason, verdict, formatDuration(r.E2E), r.Error)
+		}
+	}
+	report.WriteString("\n")
+
+	report.WriteString("---\n\n")
+	fmt.Fprintf(&report, "*Report generated at %s*\n", time.Now().Format("2006-01-02 15:04:05"))
+
+	if err := os.WriteFile(filename, []byte(report.String()), 0600); err != nil {
+		return fmt.Errorf("error writing max-output report: %w", err)
+	}
+	log.Printf("Max-output report generated: %s", filename)
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMaxOutputVerdict(t *testing.T) {
+	tests := []struct {
+		tokens       int
+		finishReason string
+		want         string
+	}{
+		{980, "length", outputReached},
+		{960, "stop", outputReached},
+		{400, "length", outputCapped},
+		{400, "stop", outputStopped},
+		{400, "", outputUnreported},
+	}
+	for _, tt := range tests {
+		if got := maxOutputVerdict(tt.tokens, 1000, tt.finishReason); got != tt.want {
+			t.Errorf("maxOutputVerdict(%d, 1000, %q) = %s, want %s", tt.tokens, tt.finishReason, got, tt.want)
+		}
+	}
+}
+
+// cappedOutputServer streams ten 10-byte chunks, then ends with finishReason.
+func cappedOutputServer(t *testing.T, finishReason string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for range 10 {
+			fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"0123456789\"}}]}\n\n")
+		}
+		if finishReason != "" {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":%q}]}\n\n", finishReason)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestProbeMaxOutputs(t *testing.T) {
+	tke := testTokenizer(t)
+
+	honest := ProviderConfig{Name: "honest", BaseURL: cappedOutputServer(t, "length").URL, APIKey: "k", Model: "m"}
+	summary := probeMaxOutputs(honest, tke, maxOutputOptions{tokens: 1000, runs: 2})
+	if summary.Ceiling != 100 || len(summary.Runs) != 2 || summary.Runs[0].Verdict != outputCapped || !summary.honest() {
+		t.Fatalf("expected an honest 100-token cap, got %+v", summary)
+	}
+
+	silent := ProviderConfig{Name: "silent", BaseURL: cappedOutputServer(t, "stop").URL, APIKey: "k", Model: "m"}
+	summaries := []MaxOutputSummary{summary, probeMaxOutputs(silent, tke, maxOutputOptions{tokens: 1000, runs: 1})}
+	if summaries[1].honest() || summaries[1].Runs[0].FinishReason != "stop" {
+		t.Fatalf("expected a cut-off reported as stop to be flagged, got %+v", summaries[1])
+	}
+
+	dir := t.TempDir()
+	if err := generateMaxOutputReport(dir, summaries, "20250101-000000"); err != nil {
+		t.Fatalf("generateMaxOutputReport failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "MAX-OUTPUT-REPORT.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(data)
+	if !strings.Contains(report, "| honest | m | 1000 | 100 | yes |") || !strings.Contains(report, "| silent | m | 1000 | 100 | ⚠ no |") {
+		t.Fatalf("unexpected summary:\n%s", report)
+	}
+}
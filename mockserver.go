@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// mockSSEHandler is a fake OpenAI-compatible streaming endpoint used by the
+// selftest subcommand and the tests. It streams the configured chunks as chat
+// completion deltas with the given delays.
+type mockSSEHandler struct {
+	firstTokenDelay time.Duration
+	chunkDelay      time.Duration
+	chunks          []string
+	// unavailable is the number of initial requests answered with 503, as a
+	// serverless endpoint does while it wakes.
+	unavailable int32
+	requests    atomic.Int32
+}
+
+// ServeHTTP implements http.Handler.
+func (m *mockSSEHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if m.requests.Add(1) <= m.unavailable {
+		http.Error(w, `{"error":{"message":"waking up"}}`, http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	if !sleepOrDone(r, m.firstTokenDelay) {
+		return
+	}
+	for i, chunk := range m.chunks {
+		if i > 0 && !sleepOrDone(r, m.chunkDelay) {
+			return
+		}
+		payload, _ := json.Marshal(map[string]any{
+			"id":      "mock",
+			"object":  "chat.completion.chunk",
+			"created": 0,
+			"model":   "mock-model",
+			"choices": []map[string]any{{"index": 0, "delta": map[string]string{"content": chunk}}},
+		})
+		fmt.Fprintf(w, "data: %s\n\n", payload)
+		flusher.Flush()
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	flusher.Flush()
+}
+
+// sleepOrDone waits for d unless the request is cancelled first.
+func sleepOrDone(r *http.Request, d time.Duration) bool {
+	if d <= 0 {
+		return r.Context().Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
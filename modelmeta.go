@@ -0,0 +1,347 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// modelMetadataOpenRouter is the --model-metadata source that fetches
+// OpenRouter's public model list.
+const modelMetadataOpenRouter = "openrouter"
+
+// openRouterModelsURL is OpenRouter's model list; a variable for tests.
+var openRouterModelsURL = "https://openrouter.ai/api/v1/models"
+
+// ModelMetadata describes the size of a benchmarked model, so speed
+// comparisons between a 7B and a 400B model are read as such.
+type ModelMetadata struct {
+	// Parameters is the parameter count as written by the source, e.g. "120B"
+	// or "235B (22B active)".
+	Parameters    string `json:"parameters,omitempty"`
+	ContextWindow int    `json:"contextWindow,omitempty"`
+	Quantization  string `json:"quantization,omitempty"`
+	// Source is where the metadata came from: a file name or "openrouter".
+	Source string `json:"source,omitempty"`
+}
+
+// sessionModelMetadata maps the tested model IDs to their metadata; set from
+// --model-metadata, or read back from the manifests by the report subcommand.
+var sessionModelMetadata map[string]ModelMetadata
+
+// modelKey reduces a model ID to the name providers agree on: the last path
+// segment in lower case, so "accounts/fireworks/models/gpt-oss-120b" and
+// "openai/gpt-oss-120b" match.
+func modelKey(model string) string {
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	return strings.ToLower(model)
+}
+
+// matchModelMetadata picks the metadata of each model from catalog, matching
+// the exact ID first and the modelKey otherwise. Models the catalog does not
+// know are left out.
+func matchModelMetadata(catalog map[string]ModelMetadata, models []string) map[string]ModelMetadata {
+	byKey := make(map[string]ModelMetadata, len(catalog))
+	ids := make([]string, 0, len(catalog))
+	for id := range catalog {
+		ids = append(ids, id)
+	}
+	// Sorted so the same catalog always resolves a shared key the same way
+	sort.Strings(ids)
+	for _, id := range ids {
+		if _, ok := byKey[modelKey(id)]; !ok {
+			byKey[modelKey(id)] = catalog[id]
+		}
+	}
+	matched := make(map[string]ModelMetadata)
+	for _, model := range models {
+		if m, ok := catalog[model]; ok {
+			matched[model] = m
+		} else if m, ok := byKey[modelKey(model)]; ok {
+			matched[model] = m
+		}
+	}
+	return matched
+}
+
+// readModelMetadataFile reads a static catalog: a JSON object mapping model
+// IDs to ModelMetadata.
+func readModelMetadataFile(path string) (map[string]ModelMetadata, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path is the operator's --model-metadata
+	if err != nil {
+		return nil, fmt.Errorf("error reading model metadata: %w", err)
+	}
+	var catalog map[string]ModelMetadata
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("error parsing model metadata %s: %w", path, err)
+	}
+	for id, m := range catalog {
+		if m.ContextWindow < 0 {
+			return nil, fmt.Errorf("model metadata %s: %s has a negative contextWindow", path, id)
+		}
+		if m.Source == "" {
+			m.Source = filepath.Base(path)
+			catalog[id] = m
+		}
+	}
+	return catalog, nil
+}
+
+// parameterCountPattern finds a parameter count such as "70b" or "1.5b" in a
+// model ID, the only place OpenRouter's list gives one.
+var parameterCountPattern = regexp.MustCompile(`(?:^|[-_:.])(\d+(?:\.\d+)?)b(?:$|[-_:.])`)
+
+// parametersFromID returns the parameter count named in a model ID, or "".
+func parametersFromID(id string) string {
+	m := parameterCountPattern.FindStringSubmatch(modelKey(id))
+	if m == nil {
+		return ""
+	}
+	return strings.ToUpper(m[1] + "b")
+}
+
+// fetchOpenRouterModels reads OpenRouter's model list. It carries context
+// windows; parameter counts are taken from the model IDs, and quantization is
+// not listed.
+func fetchOpenRouterModels() (map[string]ModelMetadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, openRouterModelsURL, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching OpenRouter models: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			log.Printf("Warning: Failed to close response body: %v", closeErr)
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching OpenRouter models: unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading OpenRouter models: %w", err)
+	}
+	var list struct {
+		Data []struct {
+			ID            string `json:"id"`
+			ContextLength int    `json:"context_length"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("error parsing OpenRouter models: %w", err)
+	}
+	catalog := make(map[string]ModelMetadata, len(list.Data))
+	for _, m := range list.Data {
+		catalog[m.ID] = ModelMetadata{Parameters: parametersFromID(m.ID), ContextWindow: m.ContextLength, Source: modelMetadataOpenRouter}
+	}
+	return catalog, nil
+}
+
+// loadModelMetadata looks up the models of providers in source, a JSON file
+// or "openrouter".
+func loadModelMetadata(source string, providers []ProviderConfig) (map[string]ModelMetadata, error) {
+	var catalog map[string]ModelMetadata
+	var err error
+	if source == modelMetadataOpenRouter {
+		catalog, err = fetchOpenRouterModels()
+	} else {
+		catalog, err = readModelMetadataFile(source)
+	}
+	if err != nil {
+		return nil, err
+	}
+	models := make([]string, 0, len(providers))
+	for _, p := range providers {
+		models = append(models, p.Model)
+	}
+	return matchModelMetadata(catalog, models), nil
+}
+
+// manifestModelMetadata collects the model metadata recorded in the sessions'
+// manifests; the first session to describe a model wins.
+func manifestModelMetadata(sessions []string) map[string]ModelMetadata {
+	metadata := make(map[string]ModelMetadata)
+	for _, session := range sessions {
+		manifest, err := loadManifest(session)
+		if err != nil {
+			continue
+		}
+		for _, p := range manifest.Providers {
+			if _, ok := metadata[p.Model]; ok || p.ModelMetadata == nil {
+				continue
+			}
+			metadata[p.Model] = *p.ModelMetadata
+		}
+	}
+	return metadata
+}
+
+// parameterCountValue reads the leading count of a Parameters value such as
+// "70B", "1.5b" or "1T (32B active)".
+var parameterCountValue = regexp.MustCompile(`^\s*(\d+(?:\.\d+)?)\s*([kmbtKMBT])\b`)
+
+// parameterBillions returns the parameter count of m in billions. For a mixture
+// of experts it is the total count written first, not the active one.
+func (m ModelMetadata) parameterBillions() (float64, bool) {
+	match := parameterCountValue.FindStringSubmatch(m.Parameters)
+	if match == nil {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(match[1], 64)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	switch strings.ToUpper(match[2]) {
+	case "K":
+		n /= 1e6
+	case "M":
+		n /= 1e3
+	case "T":
+		n *= 1e3
+	}
+	return n, true
+}
+
+// efficiencyRow is one entry of the size-efficiency leaderboard.
+type efficiencyRow struct {
+	provider, model, mode string
+	parameters            string
+	throughput, perB      float64
+}
+
+// writeEfficiencyRows ranks rows by throughput per billion parameters. Nothing
+// is written without rows, or in blind reports.
+func writeEfficiencyRows(report *strings.Builder, rows []efficiencyRow) {
+	if blindReports || len(rows) == 0 {
+		return
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].perB > rows[j].perB })
+	report.WriteString("## Size Efficiency\n\n")
+	report.WriteString("Throughput per billion parameters, from the Model Metadata above. A small model that is fast for its size ranks " +
+		"above a large one; mixture-of-experts models count their total parameters.\n\n")
+	report.WriteString("| Rank | Provider | Model | Mode | Parameters | Throughput | tok/s per B |\n")
+	report.WriteString("|------|----------|-------|------|------------|------------|-------------|\n")
+	for i, r := range rows {
+		fmt.Fprintf(report, "| %d | %s | %s | %s | %s | %.2f tok/s | %.3f |\n", i+1, r.provider, markdownCell(r.model), r.mode,
+			markdownCell(r.parameters), r.throughput, r.perB)
+	}
+	report.WriteString("\n")
+}
+
+// efficiencyRowFor returns the leaderboard row of a result, or false when its
+// model's size is unknown or it measured no throughput.
+func efficiencyRowFor(provider, model, mode string, throughput float64) (efficiencyRow, bool) {
+	m, ok := sessionModelMetadata[model]
+	if !ok || throughput <= 0 {
+		return efficiencyRow{}, false
+	}
+	billions, ok := m.parameterBillions()
+	if !ok {
+		return efficiencyRow{}, false
+	}
+	return efficiencyRow{provider, model, mode, m.Parameters, throughput, throughput / billions}, true
+}
+
+// writeEfficiencySection ranks the successful results whose model size is
+// known.
+func writeEfficiencySection(report *strings.Builder, results []TestResult) {
+	var rows []efficiencyRow
+	for _, r := range results {
+		if !r.Success {
+			continue
+		}
+		if row, ok := efficiencyRowFor(providerLabel(r.Provider, r.Env), r.Model, r.Mode, r.Throughput); ok {
+			rows = append(rows, row)
+		}
+	}
+	writeEfficiencyRows(report, rows)
+}
+
+// writeDiagnosticEfficiencySection is the diagnostic-report counterpart of
+// writeEfficiencySection.
+func writeDiagnosticEfficiencySection(report *strings.Builder, results []DiagnosticSummary) {
+	var rows []efficiencyRow
+	for _, r := range results {
+		if row, ok := efficiencyRowFor(providerLabel(r.Provider, r.Env), r.Model, r.Mode, r.AvgThroughput); ok {
+			rows = append(rows, row)
+		}
+	}
+	writeEfficiencyRows(report, rows)
+}
+
+// writeModelMetadataRows lists the metadata of the given models. Nothing is
+// written when none is known, or in blind reports, where model sizes would
+// give the providers away.
+func writeModelMetadataRows(report *strings.Builder, models []string) {
+	if blindReports {
+		return
+	}
+	seen := make(map[string]bool)
+	var known []string
+	for _, m := range models {
+		if _, ok := sessionModelMetadata[m]; ok && !seen[m] {
+			seen[m] = true
+			known = append(known, m)
+		}
+	}
+	if len(known) == 0 {
+		return
+	}
+	sort.Strings(known)
+
+	report.WriteString("## Model Metadata\n\n")
+	report.WriteString("| Model | Parameters | Context Window | Quantization | Source |\n")
+	report.WriteString("|-------|------------|----------------|--------------|--------|\n")
+	for _, model := range known {
+		m := sessionModelMetadata[model]
+		parameters, quantization, window := NotAvailable, NotAvailable, NotAvailable
+		if m.Parameters != "" {
+			parameters = markdownCell(m.Parameters)
+		}
+		if m.Quantization != "" {
+			quantization = markdownCell(m.Quantization)
+		}
+		if m.ContextWindow > 0 {
+			window = fmt.Sprintf("%d", m.ContextWindow)
+		}
+		fmt.Fprintf(report, "| %s | %s | %s | %s | %s |\n", markdownCell(model), parameters, window, quantization, markdownCell(m.Source))
+	}
+	report.WriteString("\n")
+}
+
+// writeModelMetadataSection lists the metadata of the models in results.
+func writeModelMetadataSection(report *strings.Builder, results []TestResult) {
+	models := make([]string, len(results))
+	for i, r := range results {
+		models[i] = r.Model
+	}
+	writeModelMetadataRows(report, models)
+}
+
+// writeDiagnosticModelMetadataSection is the diagnostic-report counterpart of
+// writeModelMetadataSection.
+func writeDiagnosticModelMetadataSection(report *strings.Builder, results []DiagnosticSummary) {
+	models := make([]string, len(results))
+	for i, r := range results {
+		models[i] = r.Model
+	}
+	writeModelMetadataRows(report, models)
+}
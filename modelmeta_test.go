@@ -0,0 +1,120 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadModelMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "models.json")
+	if err := os.WriteFile(path, []byte(`{
+  "openai/gpt-oss-120b": {"parameters": "117B (5.1B active)", "contextWindow": 131072, "quantization": "mxfp4"},
+  "moonshotai/kimi-k2": {"parameters": "1T"}
+}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadModelMetadata(path, []ProviderConfig{
+		{Name: "fireworks", Model: "accounts/fireworks/models/gpt-oss-120b"},
+		{Name: "nim", Model: "moonshotai/kimi-k2"},
+		{Name: "novita", Model: "unknown/model"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got["moonshotai/kimi-k2"].Parameters != "1T" {
+		t.Fatalf("unexpected matches %+v", got)
+	}
+	if m := got["accounts/fireworks/models/gpt-oss-120b"]; m.ContextWindow != 131072 || m.Quantization != "mxfp4" || m.Source != "models.json" {
+		t.Fatalf("expected a match on the model name, got %+v", m)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"m": {"contextWindow": -1}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadModelMetadata(path, nil); err == nil {
+		t.Fatal("expected a negative context window to be rejected")
+	}
+}
+
+func TestFetchOpenRouterModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"data": [{"id": "meta-llama/llama-3.1-70b-instruct", "context_length": 131072},
+			{"id": "qwen/qwen3-235b-a22b", "context_length": 40960}, {"id": "openai/gpt-4o", "context_length": 128000}]}`))
+	}))
+	defer srv.Close()
+	saved := openRouterModelsURL
+	openRouterModelsURL = srv.URL
+	defer func() { openRouterModelsURL = saved }()
+
+	got, err := loadModelMetadata(modelMetadataOpenRouter, []ProviderConfig{
+		{Model: "meta-llama/Llama-3.1-70B-Instruct"}, {Model: "Qwen/Qwen3-235B-A22B"}, {Model: "gpt-4o"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := got["meta-llama/Llama-3.1-70B-Instruct"]; m.Parameters != "70B" || m.ContextWindow != 131072 || m.Source != modelMetadataOpenRouter {
+		t.Fatalf("unexpected llama metadata %+v", m)
+	}
+	if m := got["Qwen/Qwen3-235B-A22B"]; m.Parameters != "235B" {
+		t.Fatalf("unexpected qwen metadata %+v", m)
+	}
+	if m := got["gpt-4o"]; m.Parameters != "" || m.ContextWindow != 128000 {
+		t.Fatalf("expected no parameter count for gpt-4o, got %+v", m)
+	}
+}
+
+func TestModelMetadataSection(t *testing.T) {
+	savedMetadata, savedBlind := sessionModelMetadata, blindReports
+	defer func() { sessionModelMetadata, blindReports = savedMetadata, savedBlind }()
+	sessionModelMetadata = map[string]ModelMetadata{"kimi-k2": {Parameters: "1T", Source: "models.json"}}
+	results := []TestResult{{Provider: "nim", Model: "kimi-k2"}, {Provider: "novita", Model: "kimi-k2"}, {Provider: "x", Model: "other"}}
+
+	var report strings.Builder
+	writeModelMetadataSection(&report, results)
+	got := report.String()
+	if !strings.Contains(got, "## Model Metadata") || strings.Count(got, "| kimi-k2 | 1T | N/A | N/A | models.json |") != 1 ||
+		strings.Contains(got, "other") {
+		t.Fatalf("unexpected section:\n%s", got)
+	}
+
+	blindReports = true
+	report.Reset()
+	writeModelMetadataSection(&report, results)
+	if report.Len() != 0 {
+		t.Fatalf("expected no metadata in blind reports, got %q", report.String())
+	}
+}
+
+func TestParameterBillions(t *testing.T) {
+	cases := map[string]float64{"70B": 70, "1.5b": 1.5, "1T (32B active)": 1000, "350M": 0.35, "": 0, "unknown": 0}
+	for parameters, want := range cases {
+		got, ok := ModelMetadata{Parameters: parameters}.parameterBillions()
+		if ok != (want > 0) || got != want {
+			t.Errorf("parameterBillions(%q) = %v, %v; want %v", parameters, got, ok, want)
+		}
+	}
+}
+
+func TestEfficiencySection(t *testing.T) {
+	savedMetadata, savedBlind := sessionModelMetadata, blindReports
+	defer func() { sessionModelMetadata, blindReports = savedMetadata, savedBlind }()
+	sessionModelMetadata = map[string]ModelMetadata{"big": {Parameters: "400B"}, "small": {Parameters: "8B"}, "secret": {Quantization: "fp8"}}
+
+	var report strings.Builder
+	writeEfficiencySection(&report, []TestResult{
+		{Provider: "a", Model: "big", Mode: "streaming", Success: true, Throughput: 200},
+		{Provider: "b", Model: "small", Mode: "streaming", Success: true, Throughput: 40},
+		{Provider: "c", Model: "secret", Mode: "streaming", Success: true, Throughput: 500},
+		{Provider: "d", Model: "small", Mode: "streaming", Error: "timeout"},
+	})
+	got := report.String()
+	small := strings.Index(got, "| 1 | b | small | streaming | 8B | 40.00 tok/s | 5.000 |")
+	big := strings.Index(got, "| 2 | a | big | streaming | 400B | 200.00 tok/s | 0.500 |")
+	if small < 0 || big < 0 || strings.Contains(got, "secret") || strings.Contains(got, "| d |") {
+		t.Fatalf("unexpected leaderboard:\n%s", got)
+	}
+}
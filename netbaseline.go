@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Network baseline probing. ICMP echo needs raw sockets (root, or a
+// net.ipv4.ping_group_range that covers the user), so the baseline times TCP
+// connects to the provider's port instead: they cross the same path, one round
+// trip each, without privileges, and are not dropped by hosts that filter ping.
+const (
+	// baselineSamples is the number of TCP connects and HTTP requests timed.
+	baselineSamples = 5
+	// baselineTimeout bounds each probe.
+	baselineTimeout = 5 * time.Second
+	// baselineJitter is how far the median TCP round trip may exceed the
+	// minimum before connectivity is flagged as degraded: twice the minimum
+	// and at least this much more.
+	baselineJitter = 50 * time.Millisecond
+)
+
+// skipNetworkBaseline disables the pre-run probe; set with --no-baseline.
+var skipNetworkBaseline bool
+
+// NetworkBaseline is the raw network round trip to a provider's host, measured
+// just before its benchmark, so TTFT can be read net of the network.
+type NetworkBaseline struct {
+	// TCPConnect is the median time to open a TCP connection, one round trip.
+	TCPConnect    time.Duration `json:"tcpConnectMs,omitempty"`
+	TCPConnectMin time.Duration `json:"tcpConnectMinMs,omitempty"`
+	// HTTP is the median round trip of a HEAD request on an open connection,
+	// which adds the server's minimal request handling to the network RTT.
+	HTTP    time.Duration `json:"httpRttMs,omitempty"`
+	Samples int           `json:"samples"`
+	Lost    int           `json:"lost,omitempty"`
+	// Degraded explains why local connectivity looked unhealthy, if it did.
+	Degraded string `json:"degraded,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// measureBaseline probes config's endpoint the way requests reach it (honoring
+// --per-ip and --ip-version).
+func measureBaseline(ctx context.Context, config ProviderConfig) *NetworkBaseline {
+	u, err := url.Parse(config.BaseURL)
+	if err != nil || u.Host == "" {
+		return &NetworkBaseline{Error: fmt.Sprintf("invalid base URL %q", config.BaseURL)}
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	host := u.Hostname()
+	if config.DialIP != "" {
+		host = config.DialIP
+	}
+	addr := net.JoinHostPort(host, port)
+
+	b := &NetworkBaseline{Samples: baselineSamples}
+	var connects []time.Duration
+	dialer := &net.Dialer{Timeout: baselineTimeout}
+	for range baselineSamples {
+		start := time.Now()
+		conn, err := dialer.DialContext(ctx, ipVersionNetwork(config.IPVersion), addr)
+		if err != nil {
+			b.Lost++
+			b.Error = err.Error()
+			continue
+		}
+		connects = append(connects, time.Since(start))
+		_ = conn.Close()
+	}
+	if len(connects) == 0 {
+		return b
+	}
+	b.Error = ""
+	b.TCPConnect = percentileDuration(connects, 50)
+	b.TCPConnectMin = percentileDuration(connects, 0)
+	b.HTTP = measureHTTPRoundTrip(ctx, config)
+	b.Degraded = baselineDegradation(b)
+	return b
+}
+
+// measureHTTPRoundTrip times HEAD requests to the base URL after a first one
+// has opened the connection, so handshakes are excluded. Any status counts; it
+// returns 0 when no request completes.
+func measureHTTPRoundTrip(ctx context.Context, config ProviderConfig) time.Duration {
+	client := &http.Client{Transport: dialTransport(config.DialIP, config.IPVersion), Timeout: baselineTimeout}
+	head := func() (time.Duration, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, config.BaseURL, nil)
+		if err != nil {
+			return 0, err
+		}
+		if userAgent := config.userAgent(); userAgent != "" {
+			req.Header.Set("User-Agent", userAgent)
+		}
+		start := time.Now()
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		return time.Since(start), nil
+	}
+	if _, err := head(); err != nil {
+		return 0
+	}
+	var rtts []time.Duration
+	for range baselineSamples {
+		if rtt, err := head(); err == nil {
+			rtts = append(rtts, rtt)
+		}
+	}
+	return percentileDuration(rtts, 50)
+}
+
+// baselineDegradation flags lost probes and heavy jitter.
+func baselineDegradation(b *NetworkBaseline) string {
+	if b.Lost > 0 {
+		return fmt.Sprintf("%d/%d TCP probes failed", b.Lost, b.Samples)
+	}
+	if b.TCPConnect > 2*b.TCPConnectMin && b.TCPConnect-b.TCPConnectMin > baselineJitter {
+		return fmt.Sprintf("jitter: median RTT %s vs minimum %s", formatDuration(b.TCPConnect), formatDuration(b.TCPConnectMin))
+	}
+	return ""
+}
+
+// runNetworkBaseline measures and logs the baseline before a provider's run,
+// or returns nil with --no-baseline.
+func runNetworkBaseline(config ProviderConfig, logger *log.Logger) *NetworkBaseline {
+	if skipNetworkBaseline {
+		return nil
+	}
+	b := measureBaseline(context.Background(), config)
+	switch {
+	case b.TCPConnect == 0:
+		logger.Printf("Warning: network baseline to %s failed: %s", providerLabel(config.Name, config.Env), b.Error)
+	case b.Degraded != "":
+		logger.Printf("Warning: degraded connectivity to %s (%s); TTFT may be inflated", providerLabel(config.Name, config.Env), b.Degraded)
+	default:
+		logger.Printf("Network baseline: TCP RTT %s, HTTP RTT %s", formatDuration(b.TCPConnect), formatDurationOrNA(b.HTTP))
+	}
+	return b
+}
+
+// formatDurationOrNA is formatDuration, or N/A for zero.
+func formatDurationOrNA(d time.Duration) string {
+	if d == 0 {
+		return NotAvailable
+	}
+	return formatDuration(d)
+}
+
+// netTTFT is TTFT less the network round trip, never negative.
+func netTTFT(ttft time.Duration, b *NetworkBaseline) time.Duration {
+	return max(ttft-b.TCPConnect, 0)
+}
+
+// baselineRow is one entry of the network baseline table.
+type baselineRow struct {
+	provider, mode string
+	ttft           time.Duration
+	success        bool
+	baseline       *NetworkBaseline
+}
+
+// writeBaselineRows renders each provider's network round trip beside its
+// TTFT.
+func writeBaselineRows(report *strings.Builder, rows []baselineRow) {
+	if len(rows) == 0 {
+		return
+	}
+	report.WriteString("## Network Baseline\n\n")
+	report.WriteString("Round trips to each provider's host measured just before its run: TCP connect time (one network round trip) and a HEAD request on an open connection. " +
+		"TTFT net of RTT is what remains once the network round trip is taken out, the part the provider controls.\n\n")
+	report.WriteString("| Provider | Mode | TCP RTT | HTTP RTT | TTFT | TTFT net of RTT | Connectivity |\n")
+	report.WriteString("|----------|------|---------|----------|------|-----------------|--------------|\n")
+	for _, r := range rows {
+		b := r.baseline
+		tcp, ttft, netTTFTCell := NotAvailable, NotAvailable, NotAvailable
+		if b.TCPConnect > 0 {
+			tcp = formatDuration(b.TCPConnect)
+		}
+		if r.success {
+			ttft = formatDuration(r.ttft)
+			if b.TCPConnect > 0 {
+				netTTFTCell = formatDuration(netTTFT(r.ttft, b))
+			}
+		}
+		status := "ok"
+		switch {
+		case b.TCPConnect == 0:
+			status = "⚠ unreachable: " + b.Error
+		case b.Degraded != "":
+			status = "⚠ degraded: " + b.Degraded
+		}
+		fmt.Fprintf(report, "| %s | %s | %s | %s | %s | %s | %s |\n",
+			r.provider, r.mode, tcp, formatDurationOrNA(b.HTTP), ttft, netTTFTCell, status)
+	}
+	report.WriteString("\n")
+}
+
+// writeNetworkBaselineSection adds the baseline table for results that have one.
+func writeNetworkBaselineSection(report *strings.Builder, results []TestResult) {
+	rows := make([]baselineRow, 0, len(results))
+	for _, r := range results {
+		if r.NetworkBaseline != nil {
+			rows = append(rows, baselineRow{providerLabel(r.Provider, r.Env), r.Mode, r.TTFT, r.Success, r.NetworkBaseline})
+		}
+	}
+	writeBaselineRows(report, rows)
+}
+
+// writeDiagnosticNetworkBaselineSection is the diagnostic-report counterpart of
+// writeNetworkBaselineSection.
+func writeDiagnosticNetworkBaselineSection(report *strings.Builder, results []DiagnosticSummary) {
+	rows := make([]baselineRow, 0, len(results))
+	for _, r := range results {
+		if r.NetworkBaseline != nil {
+			rows = append(rows, baselineRow{providerLabel(r.Provider, r.Env), r.Mode, r.AvgTTFT, r.Successful > 0, r.NetworkBaseline})
+		}
+	}
+	writeBaselineRows(report, rows)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMeasureBaseline(t *testing.T) {
+	var heads atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			heads.Add(1)
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	b := measureBaseline(context.Background(), ProviderConfig{Name: "local", BaseURL: srv.URL + "/v1"})
+	if b.Error != "" || b.Lost != 0 || b.Samples != baselineSamples {
+		t.Fatalf("unexpected baseline %+v", b)
+	}
+	if b.TCPConnect <= 0 || b.TCPConnectMin <= 0 || b.TCPConnectMin > b.TCPConnect || b.HTTP <= 0 {
+		t.Errorf("round trips not measured: %+v", b)
+	}
+	// One warm-up request opens the connection, then every sample is timed
+	if got := heads.Load(); got != baselineSamples+1 {
+		t.Errorf("server saw %d HEAD requests, want %d", got, baselineSamples+1)
+	}
+}
+
+func TestMeasureBaselineUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	b := measureBaseline(context.Background(), ProviderConfig{BaseURL: "http://" + addr})
+	if b.TCPConnect != 0 || b.Lost != baselineSamples || b.Error == "" {
+		t.Errorf("expected every probe to fail, got %+v", b)
+	}
+	if b := measureBaseline(context.Background(), ProviderConfig{BaseURL: "not a url"}); b.Error == "" {
+		t.Error("invalid base URL accepted")
+	}
+}
+
+func TestBaselineDegradation(t *testing.T) {
+	cases := []struct {
+		b    NetworkBaseline
+		want string
+	}{
+		{NetworkBaseline{TCPConnect: 40 * time.Millisecond, TCPConnectMin: 30 * time.Millisecond, Samples: 5}, ""},
+		// Doubled, but only 10ms: small absolute jitter on a fast link is fine
+		{NetworkBaseline{TCPConnect: 20 * time.Millisecond, TCPConnectMin: 10 * time.Millisecond, Samples: 5}, ""},
+		{NetworkBaseline{TCPConnect: 200 * time.Millisecond, TCPConnectMin: 40 * time.Millisecond, Samples: 5}, "jitter"},
+		{NetworkBaseline{TCPConnect: 40 * time.Millisecond, TCPConnectMin: 30 * time.Millisecond, Samples: 5, Lost: 2}, "2/5 TCP probes failed"},
+	}
+	for _, tc := range cases {
+		got := baselineDegradation(&tc.b)
+		if (tc.want == "") != (got == "") || !strings.Contains(got, tc.want) {
+			t.Errorf("baselineDegradation(%+v) = %q, want %q", tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestWriteNetworkBaselineSection(t *testing.T) {
+	var report strings.Builder
+	writeNetworkBaselineSection(&report, []TestResult{{Provider: "nim", Success: true, TTFT: time.Second}})
+	if report.Len() != 0 {
+		t.Fatalf("section written without baselines: %q", report.String())
+	}
+
+	results := []TestResult{
+		{Provider: "nim", Mode: "streaming", Success: true, TTFT: 500 * time.Millisecond,
+			NetworkBaseline: &NetworkBaseline{TCPConnect: 80 * time.Millisecond, HTTP: 120 * time.Millisecond}},
+		{Provider: "novita", Mode: "streaming", Success: true, TTFT: 900 * time.Millisecond,
+			NetworkBaseline: &NetworkBaseline{TCPConnect: 300 * time.Millisecond, Degraded: "2/5 TCP probes failed"}},
+		{Provider: "minimax", Mode: "streaming", Error: "dial tcp: timeout",
+			NetworkBaseline: &NetworkBaseline{Error: "dial tcp: timeout"}},
+	}
+	writeNetworkBaselineSection(&report, results)
+	out := report.String()
+	for _, want := range []string{
+		"## Network Baseline",
+		"| nim | streaming | 0.080s | 0.120s | 0.500s | 0.420s | ok |",
+		"| novita | streaming | 0.300s | N/A | 0.900s | 0.600s | ⚠ degraded: 2/5 TCP probes failed |",
+		"| minimax | streaming | N/A | N/A | N/A | N/A | ⚠ unreachable: dial tcp: timeout |",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q:\n%s", want, out)
+		}
+	}
+}
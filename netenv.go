@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Kinds of network interface a NetworkSnapshot can report.
+const (
+	interfaceEthernet = "ethernet"
+	interfaceWiFi     = "wifi"
+	interfaceVPN      = "vpn"
+	interfaceUnknown  = "unknown"
+)
+
+// sysClassNet is where Linux describes network interfaces.
+const sysClassNet = "/sys/class/net"
+
+// routeProbeAddr is dialed over UDP to learn which local address the default
+// route uses; no packet is sent.
+const routeProbeAddr = "192.0.2.1:9"
+
+// vpnInterfacePrefixes name tunnel interfaces of common VPN clients: OpenVPN
+// and most others (tun, tap), WireGuard (wg), macOS (utun, ipsec), PPP-based
+// VPNs, Tailscale and ZeroTier.
+var vpnInterfacePrefixes = []string{"tun", "tap", "wg", "utun", "ipsec", "ppp", "tailscale", "zt"}
+
+// wifiInterfacePrefixes name wireless interfaces on Linux (wlan0, wlp2s0, ...).
+var wifiInterfacePrefixes = []string{"wl", "wifi"}
+
+// ethernetInterfacePrefixes name wired interfaces on Linux.
+var ethernetInterfacePrefixes = []string{"eth", "enp", "eno", "ens", "enx"}
+
+// proxyEnvVars are the variables Go's HTTP client reads its proxy from, in the
+// order they take effect for HTTPS endpoints.
+var proxyEnvVars = []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy", "ALL_PROXY", "all_proxy"}
+
+// NetworkSnapshot describes the client's network path at session start, to
+// help explain latency differences between otherwise identical sessions.
+type NetworkSnapshot struct {
+	// Interface carries the default route; Kind is ethernet, wifi, vpn or
+	// unknown where it cannot be told (e.g. en0 on macOS).
+	Interface string `json:"interface,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	MTU       int    `json:"mtu,omitempty"`
+	// VPN is set when any interface that is up looks like a VPN tunnel, even
+	// one the default route does not use, since split tunnels still reroute
+	// some endpoints.
+	VPN           bool     `json:"vpn"`
+	VPNInterfaces []string `json:"vpnInterfaces,omitempty"`
+	// Proxy is the proxy requests are sent through, with credentials removed.
+	Proxy string `json:"proxy,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// String summarizes the snapshot for the session log.
+func (n NetworkSnapshot) String() string {
+	var parts []string
+	if n.Interface != "" {
+		parts = append(parts, fmt.Sprintf("default route via %s (%s, MTU %d)", n.Interface, n.Kind, n.MTU))
+	}
+	if n.VPN {
+		parts = append(parts, "VPN active ("+strings.Join(n.VPNInterfaces, ", ")+")")
+	}
+	if n.Proxy != "" {
+		parts = append(parts, "proxy "+n.Proxy)
+	}
+	if n.Error != "" {
+		parts = append(parts, "error: "+n.Error)
+	}
+	if len(parts) == 0 {
+		return "no details available"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// hasAnyPrefix reports whether name starts with one of prefixes.
+func hasAnyPrefix(name string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// looksLikeVPN reports whether an interface is a VPN tunnel, by its name or by
+// being point-to-point.
+func looksLikeVPN(name string, flags net.Flags) bool {
+	return flags&net.FlagPointToPoint != 0 || hasAnyPrefix(name, vpnInterfacePrefixes)
+}
+
+// interfaceKind classifies an interface. Linux's sysDir tells wireless devices
+// apart whatever their name; elsewhere only unambiguous names are classified.
+func interfaceKind(name string, flags net.Flags, sysDir string) string {
+	if looksLikeVPN(name, flags) {
+		return interfaceVPN
+	}
+	if _, err := os.Stat(filepath.Join(sysDir, name, "wireless")); err == nil {
+		return interfaceWiFi
+	}
+	switch {
+	case hasAnyPrefix(name, wifiInterfacePrefixes):
+		return interfaceWiFi
+	case hasAnyPrefix(name, ethernetInterfacePrefixes):
+		return interfaceEthernet
+	}
+	return interfaceUnknown
+}
+
+// defaultRouteIP returns the local address the default route uses.
+func defaultRouteIP() (net.IP, error) {
+	conn, err := net.Dial("udp", routeProbeAddr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	addr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		return nil, fmt.Errorf("unexpected local address %v", conn.LocalAddr())
+	}
+	return addr.IP, nil
+}
+
+// configuredProxy returns the first proxy set in the environment, without
+// credentials, or "" if none is.
+func configuredProxy(getenv func(string) string) string {
+	for _, name := range proxyEnvVars {
+		value := getenv(name)
+		if value == "" {
+			continue
+		}
+		u, err := url.Parse(value)
+		if err != nil || u.Host == "" {
+			return name + " (unparseable)"
+		}
+		u.User = nil
+		return u.String()
+	}
+	return ""
+}
+
+// snapshotNetwork inspects the host's interfaces and proxy settings.
+func snapshotNetwork() NetworkSnapshot {
+	snapshot := NetworkSnapshot{Proxy: configuredProxy(os.Getenv)}
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		snapshot.Error = err.Error()
+		return snapshot
+	}
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp != 0 && iface.Flags&net.FlagLoopback == 0 && looksLikeVPN(iface.Name, iface.Flags) {
+			snapshot.VPN = true
+			snapshot.VPNInterfaces = append(snapshot.VPNInterfaces, iface.Name)
+		}
+	}
+
+	routeIP, err := defaultRouteIP()
+	if err != nil {
+		snapshot.Error = fmt.Sprintf("no default route: %v", err)
+		return snapshot
+	}
+	for _, iface := range interfaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(routeIP) {
+				snapshot.Interface = iface.Name
+				snapshot.MTU = iface.MTU
+				snapshot.Kind = interfaceKind(iface.Name, iface.Flags, sysClassNet)
+				return snapshot
+			}
+		}
+	}
+	snapshot.Error = fmt.Sprintf("no interface has the default route's address %s", routeIP)
+	return snapshot
+}
+
+// recordNetwork stores a snapshot of the client's network in the manifest.
+func recordNetwork(manifest *SessionManifest) {
+	snapshot := snapshotNetwork()
+	manifest.Environment.Network = &snapshot
+	log.Printf("Network: %s", snapshot)
+}
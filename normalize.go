@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// normalizedTokens is the standard response length that normalized E2E latency
+// is projected to, independent of --target-tokens.
+const normalizedTokens = 500
+
+// calculateProjectedE2E calculates the projected E2E latency for a normalized token count.
+// Formula: ProjectedE2E = TTFT + (TargetTokens / Throughput).
+func calculateProjectedE2E(ttft time.Duration, throughput float64, target int) time.Duration {
+	if throughput <= 0 || target <= 0 {
+		return 0
+	}
+	generationTime := float64(target) / throughput
+	return ttft + time.Duration(generationTime*float64(time.Second))
+}
+
+// secondsPer100Tokens is the observed E2E latency spread over the output, per
+// 100 completion tokens.
+func secondsPer100Tokens(e2e time.Duration, tokens int) float64 {
+	if tokens <= 0 {
+		return 0
+	}
+	return 100 * e2e.Seconds() / float64(tokens)
+}
+
+// normalizedE2E projects latency to a normalizedTokens-long response, so verbose
+// models are not penalized for writing more and terse ones are not flattered.
+func normalizedE2E(ttft time.Duration, throughput float64) time.Duration {
+	return calculateProjectedE2E(ttft, throughput, normalizedTokens)
+}
+
+// lengthNormalizedRow is one entry of the length-normalized latency table.
+type lengthNormalizedRow struct {
+	provider string
+	mode     string
+	tokens   int
+	e2e      time.Duration
+	ttft     time.Duration
+	tps      float64
+}
+
+// writeLengthNormalizedRows renders rows sorted by normalized E2E latency.
+func writeLengthNormalizedRows(report *strings.Builder, rows []lengthNormalizedRow) {
+	if len(rows) == 0 {
+		return
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return normalizedE2E(rows[i].ttft, rows[i].tps) < normalizedE2E(rows[j].ttft, rows[j].tps)
+	})
+
+	report.WriteString("## Length-Normalized Latency\n\n")
+	fmt.Fprintf(report, "Raw E2E latency favors models that answer briefly. Seconds per 100 tokens spreads the observed "+
+		"E2E over the output; Normalized E2E projects each provider to a standard %d-token response "+
+		"(TTFT + %d / throughput).\n\n", normalizedTokens, normalizedTokens)
+	fmt.Fprintf(report, "| Rank | Provider | Mode | Tokens | E2E Latency | Sec / 100 Tokens | Normalized E2E (%d tok) |\n", normalizedTokens)
+	report.WriteString("|------|----------|------|--------|-------------|------------------|------------------------|\n")
+	for i, r := range rows {
+		fmt.Fprintf(report, "| %d | %s | %s | %d | %s | %.3fs | %s |\n",
+			i+1, r.provider, r.mode, r.tokens, formatDuration(r.e2e),
+			secondsPer100Tokens(r.e2e, r.tokens), formatDuration(normalizedE2E(r.ttft, r.tps)))
+	}
+	report.WriteString("\n")
+}
+
+// writeLengthNormalizedSection adds the length-normalized latency table for
+// successful results.
+func writeLengthNormalizedSection(report *strings.Builder, results []TestResult) {
+	rows := make([]lengthNormalizedRow, 0, len(results))
+	for _, r := range results {
+		if r.Success && r.Throughput > 0 {
+			rows = append(rows, lengthNormalizedRow{providerLabel(r.Provider, r.Env), r.Mode, r.CompletionTokens, r.E2ELatency, r.TTFT, r.Throughput})
+		}
+	}
+	writeLengthNormalizedRows(report, rows)
+}
+
+// writeDiagnosticLengthNormalizedSection is the diagnostic-report counterpart of
+// writeLengthNormalizedSection, based on per-provider averages.
+func writeDiagnosticLengthNormalizedSection(report *strings.Builder, results []DiagnosticSummary) {
+	rows := make([]lengthNormalizedRow, 0, len(results))
+	for _, r := range results {
+		if r.Successful > 0 && r.AvgThroughput > 0 {
+			rows = append(rows, lengthNormalizedRow{providerLabel(r.Provider, r.Env), r.Mode, r.AvgTokens, r.AvgE2ELatency, r.AvgTTFT, r.AvgThroughput})
+		}
+	}
+	writeLengthNormalizedRows(report, rows)
+}
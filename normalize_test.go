@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLengthNormalizedLatency(t *testing.T) {
+	if got := secondsPer100Tokens(2*time.Second, 400); got != 0.5 {
+		t.Errorf("secondsPer100Tokens = %v, want 0.5", got)
+	}
+	if got := secondsPer100Tokens(time.Second, 0); got != 0 {
+		t.Errorf("secondsPer100Tokens with no tokens = %v, want 0", got)
+	}
+	if got := normalizedE2E(500*time.Millisecond, 100); got != 5500*time.Millisecond {
+		t.Errorf("normalizedE2E = %s, want 5.5s", got)
+	}
+
+	// The terse provider has the lower raw E2E but the slower decode, so it must
+	// rank second once both are projected to the same length.
+	var report strings.Builder
+	writeLengthNormalizedSection(&report, []TestResult{
+		{Provider: "terse", Mode: "streaming", Success: true, CompletionTokens: 50, E2ELatency: time.Second, TTFT: 500 * time.Millisecond, Throughput: 100},
+		{Provider: "verbose", Mode: "streaming", Success: true, CompletionTokens: 800, E2ELatency: 4 * time.Second, TTFT: 800 * time.Millisecond, Throughput: 250},
+		{Provider: "failed", Mode: "streaming", Error: "timeout exceeded"},
+	})
+	out := report.String()
+	for _, want := range []string{
+		"| 1 | verbose | streaming | 800 | 4.000s | 0.500s | 2.800s |",
+		"| 2 | terse | streaming | 50 | 1.000s | 2.000s | 5.500s |",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "failed") {
+		t.Error("failed results must not be ranked")
+	}
+}
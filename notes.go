@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	mdreport "github.com/lamim/llm-api-speed/pkg/report"
+)
+
+// providerNotes is operational context for one provider, from a
+// [provider.<name>] table of the config file, e.g. "temporary trial key, EU
+// region" and a link to the provider's usage dashboard.
+type providerNotes struct {
+	Notes        string `toml:"notes"`
+	DashboardURL string `toml:"dashboard_url"`
+	// Type overrides the provider's wire protocol, like <PREFIX>_TYPE.
+	Type string `toml:"type"`
+}
+
+// noted reports whether there is anything to show in the notes table.
+func (n providerNotes) noted() bool {
+	return n.Notes != "" || n.DashboardURL != ""
+}
+
+// validate checks the provider type and that the dashboard link is an
+// absolute http(s) URL.
+func (n providerNotes) validate() error {
+	if n.Type != "" {
+		if err := validateProviderType(n.Type); err != nil {
+			return err
+		}
+	}
+	if n.DashboardURL == "" {
+		return nil
+	}
+	u, err := url.Parse(n.DashboardURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("dashboard_url %q must be an http or https URL", n.DashboardURL)
+	}
+	return nil
+}
+
+// sessionProviderNotes maps provider names to their notes; set from --config,
+// or read back from the manifests by the report subcommand.
+var sessionProviderNotes map[string]providerNotes
+
+// manifestProviderNotes collects the provider notes recorded in the sessions'
+// manifests; the first session to note a provider wins.
+func manifestProviderNotes(sessions []string) map[string]providerNotes {
+	notes := make(map[string]providerNotes)
+	for _, session := range sessions {
+		manifest, err := loadManifest(session)
+		if err != nil {
+			continue
+		}
+		for _, p := range manifest.Providers {
+			if _, ok := notes[p.Name]; ok || (p.Notes == "" && p.DashboardURL == "") {
+				continue
+			}
+			notes[p.Name] = providerNotes{Notes: p.Notes, DashboardURL: p.DashboardURL}
+		}
+	}
+	return notes
+}
+
+// markdownCell makes free text safe for a table cell.
+func markdownCell(text string) string {
+	return mdreport.Cell(text)
+}
+
+// writeProviderNotesRows lists the notes of the given providers. Nothing is
+// written when none has notes, or in blind reports, where notes would give the
+// providers away.
+func writeProviderNotesRows(report *strings.Builder, providers []string) {
+	if blindReports {
+		return
+	}
+	seen := make(map[string]bool)
+	var noted []string
+	for _, p := range providers {
+		if n, ok := sessionProviderNotes[p]; ok && n.noted() && !seen[p] {
+			seen[p] = true
+			noted = append(noted, p)
+		}
+	}
+	if len(noted) == 0 {
+		return
+	}
+	sort.Strings(noted)
+
+	report.WriteString("## Provider Notes\n\n")
+	report.WriteString("| Provider | Notes | Dashboard |\n")
+	report.WriteString("|----------|-------|-----------|\n")
+	for _, p := range noted {
+		n := sessionProviderNotes[p]
+		dashboard := NotAvailable
+		if n.DashboardURL != "" {
+			dashboard = fmt.Sprintf("[dashboard](%s)", n.DashboardURL)
+		}
+		fmt.Fprintf(report, "| %s | %s | %s |\n", p, markdownCell(n.Notes), dashboard)
+	}
+	report.WriteString("\n")
+}
+
+// writeProviderNotesSection lists the notes of the providers in results.
+func writeProviderNotesSection(report *strings.Builder, results []TestResult) {
+	providers := make([]string, len(results))
+	for i, r := range results {
+		providers[i] = r.Provider
+	}
+	writeProviderNotesRows(report, providers)
+}
+
+// writeDiagnosticProviderNotesSection is the diagnostic-report counterpart of
+// writeProviderNotesSection.
+func writeDiagnosticProviderNotesSection(report *strings.Builder, results []DiagnosticSummary) {
+	providers := make([]string, len(results))
+	for i, r := range results {
+		providers[i] = r.Provider
+	}
+	writeProviderNotesRows(report, providers)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadProviderNotes(t *testing.T) {
+	cfg, err := loadConfigFile(writeConfig(t, `
+[provider.nim]
+notes = "temporary trial key, EU region"
+dashboard_url = "https://build.nvidia.com/usage"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := cfg.Providers["nim"]; n.Notes != "temporary trial key, EU region" || n.DashboardURL != "https://build.nvidia.com/usage" {
+		t.Fatalf("unexpected notes %+v", cfg.Providers)
+	}
+
+	if _, err := loadConfigFile(writeConfig(t, "[provider.nim]\ndashboard_url = \"build.nvidia.com\"\n")); err == nil ||
+		!strings.Contains(err.Error(), "dashboard_url") {
+		t.Fatalf("expected a relative dashboard URL to be rejected, got %v", err)
+	}
+
+	cfg, err = loadConfigFile(writeConfig(t, "[provider.generic]\ntype = \"anthropic\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := cfg.Providers["generic"]; n.Type != "anthropic" || n.noted() {
+		t.Fatalf("expected a type without notes, got %+v", n)
+	}
+	if _, err := loadConfigFile(writeConfig(t, "[provider.generic]\ntype = \"claude\"\n")); err == nil ||
+		!strings.Contains(err.Error(), "provider type") {
+		t.Fatalf("expected an unknown type to be rejected, got %v", err)
+	}
+}
+
+func TestProviderNotesSection(t *testing.T) {
+	savedNotes, savedBlind := sessionProviderNotes, blindReports
+	defer func() { sessionProviderNotes, blindReports = savedNotes, savedBlind }()
+	sessionProviderNotes = map[string]providerNotes{
+		"nim":    {Notes: "trial key | EU\nregion", DashboardURL: "https://example.com/nim"},
+		"novita": {Notes: "not in this session"},
+	}
+	results := []TestResult{{Provider: "nim"}, {Provider: "nim", Env: "staging"}, {Provider: "chutes"}}
+
+	var report strings.Builder
+	writeProviderNotesSection(&report, results)
+	got := report.String()
+	if !strings.Contains(got, "| nim | trial key \\| EU region | [dashboard](https://example.com/nim) |") {
+		t.Fatalf("unexpected section:\n%s", got)
+	}
+	if strings.Count(got, "| nim |") != 1 || strings.Contains(got, "novita") || strings.Contains(got, "chutes") {
+		t.Fatalf("expected only noted providers of this session, once each:\n%s", got)
+	}
+
+	blindReports = true
+	var blind strings.Builder
+	writeProviderNotesSection(&blind, results)
+	if blind.Len() != 0 {
+		t.Fatalf("expected no notes in blind reports, got:\n%s", blind.String())
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// openRouterHost identifies OpenRouter base URLs.
+const openRouterHost = "openrouter.ai"
+
+// openRouterVariants are the model suffixes OpenRouter understands: :nitro routes
+// to the fastest upstream provider, :floor to the cheapest.
+var openRouterVariants = []string{"nitro", "floor"}
+
+// openRouterOptions holds the OpenRouter-specific flags.
+type openRouterOptions struct {
+	// Referer and Title are sent as HTTP-Referer and X-Title for app attribution.
+	Referer string
+	Title   string
+	// Variant is appended to the model as ":<variant>" unless it already has one.
+	Variant string
+	// Provider pins requests to one upstream provider slug with fallbacks disabled.
+	Provider string
+	// BYOK marks the pinned provider as served through the user's own key, which
+	// OpenRouter uses automatically when one is configured for that provider.
+	BYOK bool
+}
+
+// openRouter is the session's OpenRouter configuration.
+var openRouter openRouterOptions
+
+// validate checks that the options are consistent.
+func (o openRouterOptions) validate() error {
+	if o.Variant != "" && !slices.Contains(openRouterVariants, o.Variant) {
+		return fmt.Errorf("--openrouter-variant must be one of %s", strings.Join(openRouterVariants, ", "))
+	}
+	if o.BYOK && o.Provider == "" {
+		return fmt.Errorf("--openrouter-byok requires --openrouter-provider, so the request cannot fall back to OpenRouter's own keys")
+	}
+	return nil
+}
+
+// isOpenRouter reports whether baseURL points at OpenRouter.
+func isOpenRouter(baseURL string) bool {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	return host == openRouterHost || strings.HasSuffix(host, "."+openRouterHost)
+}
+
+// openRouterModel returns model with the configured variant suffix; a model that
+// already names a variant (e.g. "x:floor") is left as is.
+func (o openRouterOptions) openRouterModel(model string) string {
+	if o.Variant == "" || model == "" || strings.Contains(model, ":") {
+		return model
+	}
+	return model + ":" + o.Variant
+}
+
+// envTag labels results routed to a pinned upstream provider, e.g. "byok-deepinfra".
+func (o openRouterOptions) envTag() string {
+	if o.Provider == "" {
+		return ""
+	}
+	if o.BYOK {
+		return "byok-" + o.Provider
+	}
+	return "via-" + o.Provider
+}
+
+// apply adapts an OpenRouter provider config: the model gets the variant suffix,
+// and an untagged provider is tagged with the pinned upstream provider.
+func (o openRouterOptions) apply(config ProviderConfig) ProviderConfig {
+	if !isOpenRouter(config.BaseURL) {
+		return config
+	}
+	config.Model = o.openRouterModel(config.Model)
+	if config.Env == "" {
+		config.Env = o.envTag()
+	}
+	return config
+}
+
+// needsTransport reports whether requests must be rewritten on the way out.
+func (o openRouterOptions) needsTransport() bool {
+	return o.Referer != "" || o.Title != "" || o.Provider != ""
+}
+
+// openRouterTransport adds attribution headers and provider routing to
+// OpenRouter requests, which go-openai has no fields for.
+type openRouterTransport struct {
+	base    http.RoundTripper
+	options openRouterOptions
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *openRouterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if t.options.Referer != "" {
+		req.Header.Set("HTTP-Referer", t.options.Referer)
+	}
+	if t.options.Title != "" {
+		req.Header.Set("X-Title", t.options.Title)
+	}
+	if t.options.Provider != "" && req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/chat/completions") && req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("error reading request body: %w", err)
+		}
+		if err := req.Body.Close(); err != nil {
+			return nil, fmt.Errorf("error closing request body: %w", err)
+		}
+		if body, err = withProviderRouting(body, t.options.Provider); err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+		req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+	}
+	return t.base.RoundTrip(req)
+}
+
+// withProviderRouting adds OpenRouter's provider preferences to a chat request
+// body, restricting it to one upstream provider without fallbacks.
+func withProviderRouting(body []byte, provider string) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, fmt.Errorf("error decoding request body: %w", err)
+	}
+	routing, err := json.Marshal(map[string]any{"only": []string{provider}, "allow_fallbacks": false})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding provider routing: %w", err)
+	}
+	fields["provider"] = routing
+	return json.Marshal(fields)
+}
+
+// providerHTTPClient returns the HTTP client for config's requests. Every
+// client records the instance states responses report and keeps their headers
+// (see instanceTransport and headerTransport).
+func providerHTTPClient(config ProviderConfig) *http.Client {
+	transport := http.DefaultTransport
+	switch {
+	case config.HTTPVersion == httpVersion3:
+		transport = http3Transport(config.DialIP, config.IPVersion)
+	case config.DialIP != "" || config.IPVersion != "":
+		transport = dialTransport(config.DialIP, config.IPVersion)
+	}
+	if isOpenRouter(config.BaseURL) && openRouter.needsTransport() {
+		transport = &openRouterTransport{base: transport, options: openRouter}
+	}
+	if config.Proxy == proxyLiteLLM {
+		transport = &liteLLMTransport{base: transport, provider: providerLabel(config.Name, config.Env)}
+	}
+	transport = &instanceTransport{base: transport, config: config}
+	transport = &headerTransport{base: transport, config: config}
+	transport = &deadlineTransport{base: transport, config: config}
+	if userAgent := config.userAgent(); userAgent != "" {
+		transport = &userAgentTransport{base: transport, userAgent: userAgent}
+	}
+	return &http.Client{Transport: transport}
+}
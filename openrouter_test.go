@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenRouterApply(t *testing.T) {
+	options := openRouterOptions{Variant: "nitro", Provider: "deepinfra", BYOK: true}
+	got := options.apply(ProviderConfig{BaseURL: "https://openrouter.ai/api/v1", Model: "minimax/minimax-m2"})
+	if got.Model != "minimax/minimax-m2:nitro" || got.Env != "byok-deepinfra" {
+		t.Errorf("apply() = model %q env %q", got.Model, got.Env)
+	}
+	if got := options.apply(ProviderConfig{BaseURL: "https://openrouter.ai/api/v1", Model: "x:floor", Env: "prod"}); got.Model != "x:floor" || got.Env != "prod" {
+		t.Errorf("explicit variant or env overridden: %+v", got)
+	}
+	if got := options.apply(ProviderConfig{BaseURL: "https://integrate.api.nvidia.com/v1", Model: "m"}); got.Model != "m" || got.Env != "" {
+		t.Errorf("non-OpenRouter provider changed: %+v", got)
+	}
+	if err := (openRouterOptions{BYOK: true}).validate(); err == nil {
+		t.Error("BYOK without a pinned provider should be rejected")
+	}
+}
+
+func TestOpenRouterTransport(t *testing.T) {
+	var headers http.Header
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding body: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &openRouterTransport{
+		base:    http.DefaultTransport,
+		options: openRouterOptions{Referer: "https://example.com", Title: "bench", Provider: "deepinfra"},
+	}}
+	resp, err := client.Post(server.URL+"/api/v1/chat/completions", "application/json", strings.NewReader(`{"model":"m","stream":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if headers.Get("HTTP-Referer") != "https://example.com" || headers.Get("X-Title") != "bench" {
+		t.Errorf("attribution headers missing: %v", headers)
+	}
+	provider, _ := body["provider"].(map[string]any)
+	if body["model"] != "m" || provider["allow_fallbacks"] != false || len(provider["only"].([]any)) != 1 {
+		t.Errorf("unexpected body %v", body)
+	}
+}
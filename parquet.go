@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// A minimal Parquet writer: one row group, one uncompressed PLAIN-encoded data
+// page per column, flat schemas only. That covers run-level exports, and every
+// Parquet reader (DuckDB, Spark, Arrow) understands it, without a dependency.
+
+// parquetMagic starts and ends every Parquet file.
+const parquetMagic = "PAR1"
+
+// Parquet physical types.
+const (
+	parquetBoolean   int32 = 0
+	parquetInt32     int32 = 1
+	parquetInt64     int32 = 2
+	parquetDouble    int32 = 5
+	parquetByteArray int32 = 6
+)
+
+// Parquet converted types, telling readers how to interpret a physical type.
+const (
+	parquetNoConversion    int32 = -1
+	parquetUTF8            int32 = 0
+	parquetTimestampMillis int32 = 9
+)
+
+// Parquet encodings and other enum values written to the metadata.
+const (
+	parquetPlain        int32 = 0
+	parquetRLE          int32 = 3
+	parquetDataPage     int32 = 0
+	parquetRequired     int32 = 0
+	parquetOptional     int32 = 1
+	parquetUncompressed int32 = 0
+)
+
+// parquetColumn is one column of a flat Parquet file. Values must match the
+// physical type: bool, int32, int64, float64 or string; nil is null and only
+// allowed in optional columns.
+type parquetColumn struct {
+	name      string
+	physical  int32
+	converted int32
+	optional  bool
+	values    []any
+}
+
+// writeParquet writes columns, all of the same length, as a Parquet file.
+func writeParquet(w io.Writer, columns []parquetColumn, createdBy string) error {
+	rows := 0
+	if len(columns) > 0 {
+		rows = len(columns[0].values)
+	}
+	var file bytes.Buffer
+	file.WriteString(parquetMagic)
+
+	type chunk struct {
+		offset, size int64
+		values       int
+	}
+	chunks := make([]chunk, len(columns))
+	for i, col := range columns {
+		if len(col.values) != rows {
+			return fmt.Errorf("column %s has %d values, want %d", col.name, len(col.values), rows)
+		}
+		page, err := encodeParquetPage(col)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", col.name, err)
+		}
+		var header thriftCompact
+		header.begin()
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(len(page)))
+		header.i32(3, int32(len(page)))
+		header.structField(5)
+		header.i32(1, int32(rows))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE)
+		header.i32(4, parquetRLE)
+		header.end()
+		header.end()
+
+		chunks[i] = chunk{offset: int64(file.Len()), size: int64(header.buf.Len() + len(page)), values: rows}
+		file.Write(header.buf.Bytes())
+		file.Write(page)
+	}
+
+	var meta thriftCompact
+	meta.begin()
+	meta.i32(1, 1)
+	meta.list(2, thriftStruct, len(columns)+1)
+	meta.begin()
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(columns)))
+	meta.end()
+	for _, col := range columns {
+		repetition := parquetRequired
+		if col.optional {
+			repetition = parquetOptional
+		}
+		meta.begin()
+		meta.i32(1, col.physical)
+		meta.i32(3, repetition)
+		meta.binary(4, col.name)
+		if col.converted != parquetNoConversion {
+			meta.i32(6, col.converted)
+		}
+		meta.end()
+	}
+	meta.i64(3, int64(rows))
+	meta.list(4, thriftStruct, 1)
+	meta.begin()
+	meta.list(1, thriftStruct, len(columns))
+	var totalSize int64
+	for i, col := range columns {
+		c := chunks[i]
+		totalSize += c.size
+		meta.begin()
+		meta.i64(2, c.offset)
+		meta.structField(3)
+		meta.i32(1, col.physical)
+		meta.list(2, thriftI32, 2)
+		meta.zigzag(int64(parquetPlain))
+		meta.zigzag(int64(parquetRLE))
+		meta.list(3, thriftBinary, 1)
+		meta.rawBinary(col.name)
+		meta.i32(4, parquetUncompressed)
+		meta.i64(5, int64(c.values))
+		meta.i64(6, c.size)
+		meta.i64(7, c.size)
+		meta.i64(9, c.offset)
+		meta.end()
+		meta.end()
+	}
+	meta.i64(2, totalSize)
+	meta.i64(3, int64(rows))
+	meta.end()
+	meta.binary(6, createdBy)
+	meta.end()
+
+	file.Write(meta.buf.Bytes())
+	if err := binary.Write(&file, binary.LittleEndian, uint32(meta.buf.Len())); err != nil {
+		return err
+	}
+	file.WriteString(parquetMagic)
+	_, err := w.Write(file.Bytes())
+	return err
+}
+
+// encodeParquetPage encodes a column's definition levels (for optional
+// columns) and its non-null values.
+func encodeParquetPage(col parquetColumn) ([]byte, error) {
+	var page bytes.Buffer
+	if col.optional {
+		levels := make([]bool, len(col.values))
+		for i, v := range col.values {
+			levels[i] = v != nil
+		}
+		encoded := encodeDefinitionLevels(levels)
+		_ = binary.Write(&page, binary.LittleEndian, uint32(len(encoded)))
+		page.Write(encoded)
+	}
+
+	var bits []bool
+	for _, v := range col.values {
+		if v == nil {
+			if !col.optional {
+				return nil, fmt.Errorf("null in required column")
+			}
+			continue
+		}
+		ok := true
+		switch col.physical {
+		case parquetBoolean:
+			var b bool
+			b, ok = v.(bool)
+			bits = append(bits, b)
+		case parquetInt32:
+			var n int32
+			n, ok = v.(int32)
+			_ = binary.Write(&page, binary.LittleEndian, n)
+		case parquetInt64:
+			var n int64
+			n, ok = v.(int64)
+			_ = binary.Write(&page, binary.LittleEndian, n)
+		case parquetDouble:
+			var f float64
+			f, ok = v.(float64)
+			_ = binary.Write(&page, binary.LittleEndian, math.Float64bits(f))
+		case parquetByteArray:
+			var s string
+			s, ok = v.(string)
+			_ = binary.Write(&page, binary.LittleEndian, uint32(len(s)))
+			page.WriteString(s)
+		default:
+			return nil, fmt.Errorf("unsupported physical type %d", col.physical)
+		}
+		if !ok {
+			return nil, fmt.Errorf("value %v (%T) does not match the column type", v, v)
+		}
+	}
+	// Booleans are bit-packed, least significant bit first
+	if len(bits) > 0 {
+		packed := make([]byte, (len(bits)+7)/8)
+		for i, b := range bits {
+			if b {
+				packed[i/8] |= 1 << (i % 8)
+			}
+		}
+		page.Write(packed)
+	}
+	return page.Bytes(), nil
+}
+
+// encodeDefinitionLevels encodes 0/1 definition levels with the RLE/bit-packing
+// hybrid, as one RLE run per stretch of equal levels.
+func encodeDefinitionLevels(defined []bool) []byte {
+	var out thriftCompact
+	for i := 0; i < len(defined); {
+		j := i
+		for j < len(defined) && defined[j] == defined[i] {
+			j++
+		}
+		out.varint(uint64(j-i) << 1)
+		if defined[i] {
+			out.buf.WriteByte(1)
+		} else {
+			out.buf.WriteByte(0)
+		}
+		i = j
+	}
+	return out.buf.Bytes()
+}
+
+// Thrift compact protocol type IDs used by Parquet metadata.
+const (
+	thriftI32    byte = 5
+	thriftI64    byte = 6
+	thriftBinary byte = 8
+	thriftList   byte = 9
+	thriftStruct byte = 12
+)
+
+// thriftCompact encodes Thrift structs with the compact protocol, which is how
+// Parquet serializes page headers and file metadata.
+type thriftCompact struct {
+	buf bytes.Buffer
+	// last is the previous field ID of the struct being written; field headers
+	// store the delta from it.
+	last  int16
+	outer []int16
+}
+
+func (t *thriftCompact) varint(v uint64) {
+	for v >= 0x80 {
+		t.buf.WriteByte(byte(v) | 0x80)
+		v >>= 7
+	}
+	t.buf.WriteByte(byte(v))
+}
+
+func (t *thriftCompact) zigzag(v int64) {
+	t.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (t *thriftCompact) field(id int16, typ byte) {
+	if delta := id - t.last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.zigzag(int64(id))
+	}
+	t.last = id
+}
+
+// begin starts a struct: the top-level one, or a list element.
+func (t *thriftCompact) begin() {
+	t.outer = append(t.outer, t.last)
+	t.last = 0
+}
+
+// end finishes the current struct.
+func (t *thriftCompact) end() {
+	t.buf.WriteByte(0)
+	t.last = t.outer[len(t.outer)-1]
+	t.outer = t.outer[:len(t.outer)-1]
+}
+
+// structField starts a struct-valued field; finish it with end.
+func (t *thriftCompact) structField(id int16) {
+	t.field(id, thriftStruct)
+	t.begin()
+}
+
+func (t *thriftCompact) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.zigzag(int64(v))
+}
+
+func (t *thriftCompact) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.zigzag(v)
+}
+
+func (t *thriftCompact) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.rawBinary(s)
+}
+
+// rawBinary writes a string without a field header, as a list element.
+func (t *thriftCompact) rawBinary(s string) {
+	t.varint(uint64(len(s)))
+	t.buf.WriteString(s)
+}
+
+// list starts a list field of n elements, which follow without field headers.
+func (t *thriftCompact) list(id int16, elem byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.buf.WriteByte(byte(n)<<4 | elem)
+		return
+	}
+	t.buf.WriteByte(0xF0 | elem)
+	t.varint(uint64(n))
+}
@@ -0,0 +1,613 @@
+// Package benchmark measures the streaming speed of chat completion endpoints
+// reached through the providers package. It is the engine behind the
+// llm-api-speed CLI and can be used by other Go programs, for example to check
+// a new provider before routing traffic to it:
+//
+//	results, err := benchmark.Run(ctx, benchmark.Config{
+//		Providers: []providers.Provider{{Name: "nim", BaseURL: url, APIKey: key, Model: model}},
+//	})
+//
+// RunProvider benchmarks a single provider, and Load keeps workers sending
+// requests to one for a fixed duration; both take a Send hook for callers
+// that shape, send or track requests themselves, as the CLI does. The report
+// package renders the results as markdown.
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lamim/llm-api-speed/pkg/providers"
+	"github.com/pkoukk/tiktoken-go"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Defaults applied by Run to zero-valued Config fields.
+const (
+	DefaultRuns      = 3
+	DefaultMaxTokens = 512
+	DefaultTimeout   = 5 * time.Minute
+	DefaultPrompt    = "You are a helpful assistant. Please write a short, 150-word story about a curious robot exploring " +
+		"an ancient, overgrown library on a forgotten planet."
+	DefaultEncoding = "cl100k_base"
+)
+
+// ErrNoTokens is returned when a stream completes but its content encodes to zero tokens.
+var ErrNoTokens = errors.New("received 0 tokens")
+
+// Config configures a benchmark started with Run.
+type Config struct {
+	Providers []providers.Provider
+	// Runs is the number of concurrent requests sent to each provider.
+	Runs int
+	// Prompt is the user message sent with every request.
+	Prompt    string
+	MaxTokens int
+	// Timeout bounds each provider's runs; the context passed to Run can set a
+	// tighter overall deadline.
+	Timeout time.Duration
+	// Tokenizer counts completion tokens; nil loads DefaultEncoding.
+	Tokenizer *tiktoken.Tiktoken
+	// Progress, if set, is called after every finished request. Calls are
+	// serialized, so the callback need not be safe for concurrent use.
+	Progress func(Progress)
+	// Logger receives per-request diagnostics; nil discards them.
+	Logger *log.Logger
+	// Modes names the kinds of request sent to each provider, Runs of each,
+	// in turn; nil sends Runs requests of one unnamed mode. Send tells them
+	// apart.
+	Modes []string
+	// Concurrency caps how many of a provider's requests are in flight at
+	// once; 0 sends them all at once.
+	Concurrency int
+	// Send sends one request; nil streams Prompt with Stream. A request that
+	// fails is recorded with its error; the others carry on.
+	Send func(ctx context.Context, p providers.Provider, id RunID) (Sample, error)
+}
+
+// RunID identifies one request of a benchmark.
+type RunID struct {
+	Mode string
+	// Iteration numbers the request within its provider, or within its
+	// worker under Load, from 1.
+	Iteration int
+	// Worker is the Load worker that sent the request, or 0.
+	Worker int
+}
+
+// Outcome is one finished request.
+type Outcome struct {
+	RunID
+	Start, End time.Time
+	Sample     Sample
+	Err        error
+}
+
+// Sample is the measurement of a single streaming request.
+type Sample struct {
+	TTFT       time.Duration
+	E2E        time.Duration
+	Throughput float64
+	// Tokens is the completion token count throughput is based on: the
+	// provider's own count when the API reports usage, otherwise LocalTokens.
+	Tokens int
+	// LocalTokens counts the streamed text with the request's tokenizer.
+	LocalTokens int
+	// ReportedTokens is the count the provider reported, or 0.
+	ReportedTokens int
+	Response       string
+	// Chunks describes how the tokens were split across SSE chunks.
+	Chunks ChunkStats
+	// Curve is the throughput in tok/s over consecutive CurveWindow slices after
+	// the first token, exposing streams that start fast and throttle later.
+	Curve []float64
+	// ITL holds the inter-token latencies: the gap before each content-bearing
+	// chunk after the first, so stutter and pauses show up that averages hide.
+	ITL []time.Duration
+	// RateLimit holds the provider's x-ratelimit-* response headers; it is zero
+	// when the provider sends none.
+	RateLimit openai.RateLimitHeaders
+}
+
+// CurveWindow is the width of one Sample.Curve slice.
+const CurveWindow = time.Second
+
+// minCurveWindow is the shortest trailing slice kept in a curve; shorter ones are
+// too noisy to rate.
+const minCurveWindow = CurveWindow / 4
+
+// chunkArrival is when, relative to the first token, a chunk arrived and how many
+// tokens it carried.
+type chunkArrival struct {
+	offset time.Duration
+	tokens int
+}
+
+// interTokenLatencies returns the gaps between consecutive chunk arrivals.
+func interTokenLatencies(arrivals []chunkArrival) []time.Duration {
+	if len(arrivals) < 2 {
+		return nil
+	}
+	gaps := make([]time.Duration, len(arrivals)-1)
+	for i := range gaps {
+		gaps[i] = arrivals[i+1].offset - arrivals[i].offset
+	}
+	return gaps
+}
+
+// throughputCurve buckets chunk arrivals into CurveWindow slices spanning
+// [0, end) and converts each to tok/s. A trailing partial slice is rated by its
+// actual width, or dropped if shorter than minCurveWindow.
+func throughputCurve(arrivals []chunkArrival, end time.Duration) []float64 {
+	if end <= 0 {
+		return nil
+	}
+	n := int((end + CurveWindow - 1) / CurveWindow)
+	tokens := make([]int, n)
+	for _, a := range arrivals {
+		i := min(int(a.offset/CurveWindow), n-1)
+		tokens[i] += a.tokens
+	}
+	curve := make([]float64, 0, n)
+	for i, t := range tokens {
+		width := min(CurveWindow, end-time.Duration(i)*CurveWindow)
+		if width < minCurveWindow {
+			break
+		}
+		curve = append(curve, float64(t)/width.Seconds())
+	}
+	return curve
+}
+
+// CurveSum accumulates the throughput curves of several runs.
+type CurveSum struct {
+	sum  []float64
+	runs []int
+}
+
+// Add includes one run's curve.
+func (c *CurveSum) Add(curve []float64) {
+	for len(c.sum) < len(curve) {
+		c.sum = append(c.sum, 0)
+		c.runs = append(c.runs, 0)
+	}
+	for i, v := range curve {
+		c.sum[i] += v
+		c.runs[i]++
+	}
+}
+
+// Mean averages each slice over the runs that lasted that long.
+func (c CurveSum) Mean() []float64 {
+	if len(c.sum) == 0 {
+		return nil
+	}
+	mean := make([]float64, len(c.sum))
+	for i, v := range c.sum {
+		mean[i] = v / float64(c.runs[i])
+	}
+	return mean
+}
+
+// chunkBucketBounds are the inclusive upper bounds of the ChunkStats histogram
+// buckets; a final bucket holds everything larger.
+var chunkBucketBounds = [...]int{1, 2, 4, 8, 16, 32}
+
+// ChunkBuckets is the number of ChunkStats histogram buckets.
+const ChunkBuckets = len(chunkBucketBounds) + 1
+
+// ChunkStats summarizes how many tokens each content-bearing SSE chunk carried.
+// Providers that batch many tokens per chunk feel laggy even at high tok/s.
+type ChunkStats struct {
+	Chunks    int               `json:"chunks"`
+	Tokens    int               `json:"tokens"`
+	Max       int               `json:"max"`
+	Histogram [ChunkBuckets]int `json:"histogram"`
+}
+
+// Add records one chunk carrying tokens tokens.
+func (c *ChunkStats) Add(tokens int) {
+	c.Chunks++
+	c.Tokens += tokens
+	c.Max = max(c.Max, tokens)
+	bucket := len(chunkBucketBounds)
+	for i, bound := range chunkBucketBounds {
+		if tokens <= bound {
+			bucket = i
+			break
+		}
+	}
+	c.Histogram[bucket]++
+}
+
+// Merge adds the chunks recorded in o.
+func (c *ChunkStats) Merge(o ChunkStats) {
+	c.Chunks += o.Chunks
+	c.Tokens += o.Tokens
+	c.Max = max(c.Max, o.Max)
+	for i, n := range o.Histogram {
+		c.Histogram[i] += n
+	}
+}
+
+// Mean is the average number of tokens per chunk.
+func (c ChunkStats) Mean() float64 {
+	if c.Chunks == 0 {
+		return 0
+	}
+	return float64(c.Tokens) / float64(c.Chunks)
+}
+
+// ChunkBucketLabel names histogram bucket i, e.g. "3-4" or "33+".
+func ChunkBucketLabel(i int) string {
+	switch {
+	case i >= len(chunkBucketBounds):
+		return fmt.Sprintf("%d+", chunkBucketBounds[len(chunkBucketBounds)-1]+1)
+	case i == 0:
+		return "1"
+	case chunkBucketBounds[i-1]+1 == chunkBucketBounds[i]:
+		return fmt.Sprint(chunkBucketBounds[i])
+	default:
+		return fmt.Sprintf("%d-%d", chunkBucketBounds[i-1]+1, chunkBucketBounds[i])
+	}
+}
+
+// Progress reports one finished request.
+type Progress struct {
+	Provider string
+	Run      int
+	Runs     int
+	Sample   Sample
+	Err      error
+}
+
+// Result is the averaged outcome for one provider.
+type Result struct {
+	Provider string
+	Model    string
+	// Runs counts the requests, failed ones included.
+	Runs       int
+	Successful int
+	TTFT       time.Duration
+	E2E        time.Duration
+	Throughput float64
+	Tokens     int
+	// Chunks merges the chunk statistics of the successful requests.
+	Chunks ChunkStats
+	// Curve averages the throughput curves of the successful requests.
+	Curve []float64
+	// Err is the first request error, set when no request succeeded.
+	Err error
+	// Start is when the provider's benchmark began.
+	Start time.Time
+	// Outcomes holds every request in order: by mode, then iteration, or
+	// under Load by worker, then request.
+	Outcomes []Outcome
+}
+
+// Stream sends one streaming chat completion request and measures it. TTFT is
+// the time to the first content or reasoning delta; throughput excludes the
+// first token, which is accounted for by TTFT. Completion tokens are the
+// provider's usage figure when it reports one, since tke may not be the
+// model's tokenizer; per-chunk statistics are always counted with tke.
+func Stream(ctx context.Context, p providers.Provider, tke *tiktoken.Tiktoken, logger *log.Logger, req openai.ChatCompletionRequest) (Sample, error) {
+	if logger == nil {
+		logger = log.New(io.Discard, "", 0)
+	}
+	startTime := time.Now()
+	var firstTokenTime time.Time
+	var fullResponseContent strings.Builder
+
+	stream, streamErr := providers.OpenStream(ctx, p, req)
+	if streamErr != nil {
+		return Sample{}, fmt.Errorf("error creating stream: %w", streamErr)
+	}
+	defer func() {
+		if closeErr := stream.Close(); closeErr != nil {
+			logger.Printf("[%s] Warning: Failed to close stream: %v", p.Name, closeErr)
+		}
+	}()
+
+	logger.Printf("[%s] ... Request sent. Waiting for stream ...", p.Name)
+
+	chunkCount := 0
+	nonEmptyChunks := 0
+	reasoningChunks := 0
+	var chunks ChunkStats
+	var arrivals []chunkArrival
+	reportedTokens := 0
+
+	for {
+		delta, recvErr := stream.Recv()
+
+		if errors.Is(recvErr, io.EOF) {
+			logger.Printf("[%s] ... Stream complete. Received %d chunks (%d content, %d reasoning)",
+				p.Name, chunkCount, nonEmptyChunks, reasoningChunks)
+			break
+		}
+
+		if recvErr != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				return Sample{}, fmt.Errorf("timeout exceeded")
+			}
+			return Sample{}, fmt.Errorf("stream error: %w", recvErr)
+		}
+
+		chunkCount++
+		if delta.CompletionTokens > 0 {
+			reportedTokens = delta.CompletionTokens
+		}
+
+		if delta.Empty() {
+			if chunkCount%100 == 0 {
+				logger.Printf("[%s] ... Chunk %d: Empty delta", p.Name, chunkCount)
+			}
+			continue
+		}
+
+		content := delta.Content
+		reasoningContent := delta.Reasoning
+
+		if (content != "" || reasoningContent != "") && firstTokenTime.IsZero() {
+			firstTokenTime = time.Now()
+			if reasoningContent != "" {
+				logger.Printf("[%s] ... First token received (reasoning)! (chunk %d, len=%d)",
+					p.Name, chunkCount, len(reasoningContent))
+			} else {
+				logger.Printf("[%s] ... First token received! (chunk %d, len=%d)",
+					p.Name, chunkCount, len(content))
+			}
+		}
+
+		if content != "" || reasoningContent != "" {
+			chunkTokens := len(tke.Encode(content+reasoningContent, nil, nil))
+			chunks.Add(chunkTokens)
+			arrivals = append(arrivals, chunkArrival{offset: time.Since(firstTokenTime), tokens: chunkTokens})
+		}
+		if content != "" {
+			nonEmptyChunks++
+			fullResponseContent.WriteString(content)
+		}
+		if reasoningContent != "" {
+			reasoningChunks++
+			fullResponseContent.WriteString(reasoningContent)
+		}
+	}
+
+	endTime := time.Now()
+
+	if firstTokenTime.IsZero() {
+		return Sample{}, fmt.Errorf("no content received from API (received %d chunks)", chunkCount)
+	}
+
+	fullResponse := fullResponseContent.String()
+	localTokens := len(tke.Encode(fullResponse, nil, nil))
+
+	if reportedTokens > 0 {
+		logger.Printf("[%s] ... Total content length: %d bytes, %d tokens (provider reported %d)",
+			p.Name, len(fullResponse), localTokens, reportedTokens)
+	} else {
+		logger.Printf("[%s] ... Total content length: %d bytes, %d tokens", p.Name, len(fullResponse), localTokens)
+	}
+
+	if localTokens == 0 {
+		return Sample{}, fmt.Errorf("%w (content length: %d bytes)", ErrNoTokens, len(fullResponse))
+	}
+	completionTokens := localTokens
+	if reportedTokens > 0 {
+		completionTokens = reportedTokens
+	}
+
+	e2eLatency := endTime.Sub(startTime)
+	ttftLatency := firstTokenTime.Sub(startTime)
+	generationTime := e2eLatency - ttftLatency
+
+	var throughputVal float64
+	if generationTime.Seconds() > 0 {
+		throughputVal = (float64(completionTokens) - 1.0) / generationTime.Seconds()
+	}
+
+	return Sample{
+		TTFT:           ttftLatency,
+		E2E:            e2eLatency,
+		Throughput:     throughputVal,
+		Tokens:         completionTokens,
+		LocalTokens:    localTokens,
+		ReportedTokens: reportedTokens,
+		Response:       fullResponse,
+		Chunks:         chunks,
+		Curve:          throughputCurve(arrivals, endTime.Sub(firstTokenTime)),
+		ITL:            interTokenLatencies(arrivals),
+		RateLimit:      stream.RateLimit(),
+	}, nil
+}
+
+// withDefaults fills zero-valued fields of cfg.
+func (cfg Config) withDefaults() (Config, error) {
+	if cfg.Runs <= 0 {
+		cfg.Runs = DefaultRuns
+	}
+	if cfg.Prompt == "" {
+		cfg.Prompt = DefaultPrompt
+	}
+	if cfg.MaxTokens <= 0 {
+		cfg.MaxTokens = DefaultMaxTokens
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if len(cfg.Modes) == 0 {
+		cfg.Modes = []string{""}
+	}
+	if cfg.Tokenizer == nil {
+		tke, err := tiktoken.GetEncoding(DefaultEncoding)
+		if err != nil {
+			return cfg, fmt.Errorf("benchmark: error getting tokenizer: %w", err)
+		}
+		cfg.Tokenizer = tke
+	}
+	if cfg.Progress != nil {
+		// Serialize the callback, as documented
+		var mu sync.Mutex
+		progress := cfg.Progress
+		cfg.Progress = func(pr Progress) {
+			mu.Lock()
+			defer mu.Unlock()
+			progress(pr)
+		}
+	}
+	return cfg, nil
+}
+
+// checkProvider reports a provider that cannot be benchmarked.
+func checkProvider(p providers.Provider) error {
+	if p.BaseURL == "" || p.Model == "" {
+		return fmt.Errorf("benchmark: provider %q needs a base URL and a model", p.Name)
+	}
+	return nil
+}
+
+// Run benchmarks every configured provider concurrently, sending cfg.Runs
+// concurrent streaming requests to each, and returns one Result per provider in
+// the order given. Cancelling ctx or reaching its deadline aborts in-flight
+// requests; the results gathered so far are returned together with ctx.Err().
+func Run(ctx context.Context, cfg Config) ([]Result, error) {
+	if len(cfg.Providers) == 0 {
+		return nil, errors.New("benchmark: no providers configured")
+	}
+	for _, p := range cfg.Providers {
+		if err := checkProvider(p); err != nil {
+			return nil, err
+		}
+	}
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(cfg.Providers))
+	var wg sync.WaitGroup
+	for i, p := range cfg.Providers {
+		wg.Add(1)
+		go func(i int, p providers.Provider) {
+			defer wg.Done()
+			results[i] = runProvider(ctx, cfg, p)
+		}(i, p)
+	}
+	wg.Wait()
+
+	return results, ctx.Err()
+}
+
+// RunProvider benchmarks p alone with cfg, whose Providers are ignored. The
+// error reports an unusable configuration; request failures are recorded in
+// the Result.
+func RunProvider(ctx context.Context, cfg Config, p providers.Provider) (Result, error) {
+	if err := checkProvider(p); err != nil {
+		return Result{}, err
+	}
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return Result{}, err
+	}
+	return runProvider(ctx, cfg, p), nil
+}
+
+// runProvider sends cfg.Runs requests of each mode to p, at most
+// cfg.Concurrency at a time, and averages the successful ones.
+func runProvider(ctx context.Context, cfg Config, p providers.Provider) Result {
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	send := cfg.Send
+	if send == nil {
+		req := openai.ChatCompletionRequest{
+			Model:     p.Model,
+			Messages:  []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: cfg.Prompt}},
+			MaxTokens: cfg.MaxTokens,
+			Stream:    true,
+		}
+		send = func(ctx context.Context, p providers.Provider, _ RunID) (Sample, error) {
+			return Stream(ctx, p, cfg.Tokenizer, cfg.Logger, req)
+		}
+	}
+
+	result := Result{Provider: p.Name, Model: p.Model, Start: time.Now()}
+	total := len(cfg.Modes) * cfg.Runs
+	result.Outcomes = make([]Outcome, total)
+	slots := make(chan struct{}, total)
+	if cfg.Concurrency > 0 {
+		slots = make(chan struct{}, cfg.Concurrency)
+	}
+	// Slots are taken in order, so with a cap the requests start in order too
+	var wg sync.WaitGroup
+	for i := range total {
+		o := &result.Outcomes[i]
+		o.RunID = RunID{Mode: cfg.Modes[i/cfg.Runs], Iteration: i + 1}
+		acquired := false
+		select {
+		case slots <- struct{}{}:
+			acquired = true
+		case <-ctx.Done():
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if acquired {
+				defer func() { <-slots }()
+			}
+			o.Start = time.Now()
+			if o.Err = ctx.Err(); o.Err == nil {
+				o.Sample, o.Err = send(ctx, p, o.RunID)
+			}
+			o.End = time.Now()
+			if cfg.Progress != nil {
+				cfg.Progress(Progress{Provider: p.Name, Run: i + 1, Runs: total, Sample: o.Sample, Err: o.Err})
+			}
+		}()
+	}
+	wg.Wait()
+
+	result.summarize()
+	return result
+}
+
+// summarize counts r's outcomes and averages the successful ones.
+func (r *Result) summarize() {
+	r.Runs = len(r.Outcomes)
+	var ttftSum, e2eSum time.Duration
+	var throughputSum float64
+	tokensSum := 0
+	var curves CurveSum
+	for _, o := range r.Outcomes {
+		if o.Err != nil {
+			if r.Err == nil {
+				r.Err = o.Err
+			}
+			continue
+		}
+		r.Successful++
+		ttftSum += o.Sample.TTFT
+		e2eSum += o.Sample.E2E
+		throughputSum += o.Sample.Throughput
+		tokensSum += o.Sample.Tokens
+		r.Chunks.Merge(o.Sample.Chunks)
+		curves.Add(o.Sample.Curve)
+	}
+	if r.Successful == 0 {
+		return
+	}
+	r.Err = nil
+	n := r.Successful
+	r.TTFT = ttftSum / time.Duration(n)
+	r.E2E = e2eSum / time.Duration(n)
+	r.Throughput = throughputSum / float64(n)
+	r.Tokens = tokensSum / n
+	r.Curve = curves.Mean()
+}
@@ -0,0 +1,348 @@
+package benchmark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lamim/llm-api-speed/pkg/providers"
+	"github.com/pkoukk/tiktoken-go"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// byteBpeLoader serves a byte-level encoding so tests never download tokenizer files.
+type byteBpeLoader struct{}
+
+func (byteBpeLoader) LoadTiktokenBpe(string) (map[string]int, error) {
+	ranks := make(map[string]int, 256)
+	for i := 0; i < 256; i++ {
+		ranks[string([]byte{byte(i)})] = i
+	}
+	return ranks, nil
+}
+
+func testTokenizer(t *testing.T) *tiktoken.Tiktoken {
+	t.Helper()
+	tiktoken.SetBpeLoader(byteBpeLoader{})
+	tke, err := tiktoken.GetEncoding(DefaultEncoding)
+	if err != nil {
+		t.Fatalf("failed to build test tokenizer: %v", err)
+	}
+	return tke
+}
+
+// sseServer streams chunks after firstTokenDelay, or fails every request when status is set.
+func sseServer(t *testing.T, firstTokenDelay time.Duration, chunks []string, status int) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Drain the body so the server notices when the client goes away
+		_, _ = io.Copy(io.Discard, r.Body)
+		if status != 0 {
+			http.Error(w, `{"error":{"message":"unavailable"}}`, status)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		select {
+		case <-time.After(firstTokenDelay):
+		case <-r.Context().Done():
+			return
+		}
+		for _, chunk := range chunks {
+			fmt.Fprintf(w, "data: {\"id\":\"mock\",\"object\":\"chat.completion.chunk\",\"model\":\"mock-model\","+
+				"\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", chunk)
+			w.(http.Flusher).Flush()
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRunReturnsTypedResultsAndProgress(t *testing.T) {
+	tke := testTokenizer(t)
+	ok := sseServer(t, 20*time.Millisecond, []string{"hello", " world"}, 0)
+	down := sseServer(t, 0, nil, http.StatusServiceUnavailable)
+
+	var progress []Progress
+	results, err := Run(context.Background(), Config{
+		Providers: []providers.Provider{
+			{Name: "fast", BaseURL: ok.URL, APIKey: "test", Model: "mock-model"},
+			{Name: "down", BaseURL: down.URL, APIKey: "test", Model: "mock-model"},
+		},
+		Runs:      2,
+		Tokenizer: tke,
+		Progress:  func(p Progress) { progress = append(progress, p) },
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(results) != 2 || len(progress) != 4 {
+		t.Fatalf("expected 2 results and 4 progress calls, got %d/%d", len(results), len(progress))
+	}
+	fast := results[0]
+	if fast.Provider != "fast" || fast.Successful != 2 || fast.Err != nil || fast.Tokens != len("hello world") {
+		t.Errorf("unexpected result: %+v", fast)
+	}
+	// "hello" and " world" are 5 and 6 byte-level tokens, sent twice (once per run)
+	if fast.Chunks.Chunks != 4 || fast.Chunks.Max != 6 || fast.Chunks.Histogram[3] != 4 {
+		t.Errorf("unexpected chunk stats: %+v", fast.Chunks)
+	}
+	if fast.TTFT < 20*time.Millisecond || fast.E2E < fast.TTFT {
+		t.Errorf("implausible timings: TTFT=%s E2E=%s", fast.TTFT, fast.E2E)
+	}
+	if failed := results[1]; failed.Successful != 0 || failed.Err == nil {
+		t.Errorf("expected failing provider to report an error, got %+v", failed)
+	}
+}
+
+func TestRunHonorsContextDeadline(t *testing.T) {
+	tke := testTokenizer(t)
+	slow := sseServer(t, 5*time.Second, []string{"late"}, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	results, err := Run(ctx, Config{
+		Providers: []providers.Provider{{Name: "slow", BaseURL: slow.URL, APIKey: "test", Model: "mock-model"}},
+		Tokenizer: tke,
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("Run did not stop at the deadline (took %s)", time.Since(start))
+	}
+	if len(results) != 1 || results[0].Successful != 0 || results[0].Err == nil {
+		t.Errorf("expected one failed partial result, got %+v", results)
+	}
+}
+
+func TestRunRejectsEmptyConfig(t *testing.T) {
+	if _, err := Run(context.Background(), Config{}); err == nil {
+		t.Error("expected an error without providers")
+	}
+}
+
+func TestRunProviderModesInOrder(t *testing.T) {
+	var mu sync.Mutex
+	var sent []RunID
+	inFlight, maxInFlight := 0, 0
+	p := providers.Provider{Name: "custom", BaseURL: "http://unused", Model: "m"}
+	result, err := RunProvider(context.Background(), Config{
+		Runs:        2,
+		Modes:       []string{"streaming", "tool-calling"},
+		Concurrency: 1,
+		Tokenizer:   testTokenizer(t),
+		Send: func(_ context.Context, _ providers.Provider, id RunID) (Sample, error) {
+			mu.Lock()
+			sent = append(sent, id)
+			inFlight++
+			maxInFlight = max(maxInFlight, inFlight)
+			mu.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+			if id.Iteration == 2 {
+				return Sample{}, errors.New("refused")
+			}
+			return Sample{TTFT: time.Duration(id.Iteration) * time.Second, Throughput: 10, Tokens: 100}, nil
+		},
+	}, p)
+	if err != nil {
+		t.Fatalf("RunProvider failed: %v", err)
+	}
+	want := []RunID{{"streaming", 1, 0}, {"streaming", 2, 0}, {"tool-calling", 3, 0}, {"tool-calling", 4, 0}}
+	if !slices.Equal(sent, want) || maxInFlight != 1 {
+		t.Fatalf("sent %v with up to %d in flight, want %v one at a time", sent, maxInFlight, want)
+	}
+	if result.Runs != 4 || result.Successful != 3 || result.Err != nil || len(result.Outcomes) != 4 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if result.Outcomes[1].Err == nil || result.TTFT != (1+3+4)*time.Second/3 {
+		t.Errorf("failed run should be recorded and left out of the averages: %+v", result)
+	}
+}
+
+func TestLoadPacesWorkers(t *testing.T) {
+	var sent atomic.Int32
+	stop := make(chan struct{})
+	p := providers.Provider{Name: "custom", BaseURL: "http://unused", Model: "m"}
+	result, err := Load(context.Background(), LoadConfig{
+		Workers:        2,
+		Duration:       time.Second,
+		Interval:       100 * time.Millisecond,
+		RequestTimeout: 100 * time.Millisecond,
+		GracePeriod:    time.Millisecond,
+		Modes:          []string{"streaming", "tool-calling"},
+		Send: func(_ context.Context, _ providers.Provider, id RunID) (Sample, error) {
+			if sent.Add(1) == 8 {
+				close(stop)
+			}
+			return Sample{Tokens: id.Iteration}, nil
+		},
+		Stop: stop,
+	}, p)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	// Both workers send at once and then every 100ms until the stop
+	if result.Runs != 8 || result.Successful != 8 {
+		t.Fatalf("expected 8 requests before the stop, got %d (%d successful)", result.Runs, result.Successful)
+	}
+	for i, o := range result.Outcomes {
+		worker, iteration := i/4+1, i%4+1
+		mode := []string{"streaming", "tool-calling"}[(iteration-1)%2]
+		if o.Worker != worker || o.Iteration != iteration || o.Mode != mode {
+			t.Errorf("outcome %d is %+v, want worker %d request %d (%s)", i, o.RunID, worker, iteration, mode)
+		}
+	}
+
+	if _, err := Load(context.Background(), LoadConfig{}, p); err == nil {
+		t.Error("expected an error without a Send function")
+	}
+}
+
+func TestChunkBucketLabels(t *testing.T) {
+	want := []string{"1", "2", "3-4", "5-8", "9-16", "17-32", "33+"}
+	for i, w := range want {
+		if got := ChunkBucketLabel(i); got != w {
+			t.Errorf("ChunkBucketLabel(%d) = %q, want %q", i, got, w)
+		}
+	}
+	var c ChunkStats
+	for _, n := range []int{1, 1, 3, 40} {
+		c.Add(n)
+	}
+	if c.Mean() != 11.25 || c.Max != 40 || c.Histogram[0] != 2 || c.Histogram[2] != 1 || c.Histogram[6] != 1 {
+		t.Errorf("unexpected stats: %+v", c)
+	}
+}
+
+func TestInterTokenLatencies(t *testing.T) {
+	arrivals := []chunkArrival{
+		{offset: 0, tokens: 1},
+		{offset: 20 * time.Millisecond, tokens: 1},
+		{offset: 520 * time.Millisecond, tokens: 3},
+	}
+	got := interTokenLatencies(arrivals)
+	if want := []time.Duration{20 * time.Millisecond, 500 * time.Millisecond}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("gaps = %v, want %v", got, want)
+	}
+	if interTokenLatencies(arrivals[:1]) != nil {
+		t.Error("a single chunk has no gaps")
+	}
+}
+
+func TestThroughputCurve(t *testing.T) {
+	arrivals := []chunkArrival{
+		{offset: 0, tokens: 10},
+		{offset: 500 * time.Millisecond, tokens: 10},
+		{offset: 1200 * time.Millisecond, tokens: 5},
+		{offset: 2100 * time.Millisecond, tokens: 2},
+	}
+	// The trailing 0.5s window is rated by its width; a 0.1s one is dropped
+	got := throughputCurve(arrivals, 2500*time.Millisecond)
+	if want := []float64{20, 5, 4}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("curve = %v, want %v", got, want)
+	}
+	if got := throughputCurve(arrivals[:3], 2100*time.Millisecond); len(got) != 2 {
+		t.Errorf("expected the short trailing window to be dropped, got %v", got)
+	}
+
+	var sum CurveSum
+	sum.Add([]float64{10, 20})
+	sum.Add([]float64{30})
+	if got := sum.Mean(); fmt.Sprint(got) != fmt.Sprint([]float64{20, 20}) {
+		t.Errorf("mean curve = %v", got)
+	}
+}
+
+// nativeServer replays a raw event stream at path and records the request body.
+func nativeServer(t *testing.T, path, events string, body *map[string]any) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, events)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestStreamReportedUsage(t *testing.T) {
+	tests := []struct {
+		api, path, events string
+	}{
+		{providers.APIOpenAI, "/v1/chat/completions", "data: {\"choices\":[{\"delta\":{\"content\":\"two words\"}}]}\n\n" +
+			"data: {\"choices\":[],\"usage\":{\"prompt_tokens\":5,\"completion_tokens\":2,\"total_tokens\":7}}\n\ndata: [DONE]\n\n"},
+		{providers.APIMistral, "/v1/chat/completions", "data: {\"choices\":[{\"delta\":{\"content\":\"two words\"},\"finish_reason\":\"stop\"}],\"usage\":{\"completion_tokens\":2}}\n\n" +
+			"data: [DONE]\n\n"},
+		{providers.APICohere, "/v1/chat", "event: content-delta\ndata: {\"type\":\"content-delta\",\"delta\":{\"message\":{\"content\":{\"text\":\"two words\"}}}}\n\n" +
+			"event: message-end\ndata: {\"type\":\"message-end\",\"delta\":{\"finish_reason\":\"COMPLETE\",\"usage\":{\"tokens\":{\"input_tokens\":5,\"output_tokens\":2}}}}\n\n"},
+		{providers.APIAnthropic, "/v1/messages", "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"two words\"}}\n\n" +
+			"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":2}}\n\n" +
+			"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"},
+		{providers.APIGemini, "/v1/models/m:streamGenerateContent", "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"two words\"}]},\"finishReason\":\"STOP\"}]," +
+			"\"usageMetadata\":{\"promptTokenCount\":5,\"candidatesTokenCount\":1,\"thoughtsTokenCount\":1}}\n\n"},
+	}
+	tke := testTokenizer(t)
+	for _, tt := range tests {
+		var body map[string]any
+		srv := nativeServer(t, tt.path, tt.events, &body)
+		sample, err := Stream(context.Background(), providers.Provider{Name: tt.api, BaseURL: srv.URL + "/v1", API: tt.api}, tke, nil,
+			openai.ChatCompletionRequest{Model: "m", Messages: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "hi"}}})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.api, err)
+		}
+		if sample.ReportedTokens != 2 || sample.Tokens != 2 || sample.LocalTokens != len("two words") {
+			t.Errorf("%s: expected the reported count to win, got tokens=%d local=%d reported=%d",
+				tt.api, sample.Tokens, sample.LocalTokens, sample.ReportedTokens)
+		}
+		if tt.api == providers.APIOpenAI {
+			if options, _ := body["stream_options"].(map[string]any); options["include_usage"] != true {
+				t.Errorf("expected include_usage to be requested, got %v", body["stream_options"])
+			}
+		}
+	}
+
+	var body map[string]any
+	srv := nativeServer(t, "/v1/chat/completions", "data: {\"choices\":[{\"delta\":{\"content\":\"abc\"}}]}\n\ndata: [DONE]\n\n", &body)
+	sample, err := Stream(context.Background(), providers.Provider{BaseURL: srv.URL + "/v1", NoStreamUsage: true}, tke, nil,
+		openai.ChatCompletionRequest{Model: "m", Messages: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "hi"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := body["stream_options"]; ok || sample.ReportedTokens != 0 || sample.Tokens != 3 {
+		t.Errorf("expected local counting without stream_options, got %v and %+v", body["stream_options"], sample)
+	}
+}
+
+func TestStreamSkipsCitationOnlyChunks(t *testing.T) {
+	tke := testTokenizer(t)
+	srv := sseServer(t, 0, []string{"[1]", "[2]", "hi"}, 0)
+
+	sample, err := Stream(context.Background(), providers.Provider{Name: "p", BaseURL: srv.URL, APIKey: "k", Model: "m", StripCitations: true},
+		tke, nil, openai.ChatCompletionRequest{Model: "m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sample.Response != "hi" || sample.Tokens != 2 {
+		t.Errorf("citations counted as content: %+v", sample)
+	}
+}
@@ -0,0 +1,207 @@
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/lamim/llm-api-speed/pkg/providers"
+)
+
+// Defaults applied by Load to zero-valued LoadConfig fields.
+const (
+	DefaultLoadWorkers     = 10
+	DefaultLoadDuration    = 90 * time.Second
+	DefaultLoadInterval    = 15 * time.Second
+	DefaultRequestTimeout  = 30 * time.Second
+	DefaultLoadGracePeriod = 5 * time.Second
+)
+
+// errStopped is the reason workers give when LoadConfig.Stop is closed and
+// Admit does not name another.
+var errStopped = errors.New("stop requested")
+
+// LoadConfig configures a sustained-load benchmark started with Load.
+type LoadConfig struct {
+	// Workers is the number of workers sending requests side by side.
+	Workers int
+	// Duration bounds the whole run. A worker stops starting requests once
+	// less than RequestTimeout+GracePeriod of it is left, so every request
+	// gets its full timeout.
+	Duration       time.Duration
+	RequestTimeout time.Duration
+	GracePeriod    time.Duration
+	// Interval spaces the starts of each worker's requests.
+	Interval time.Duration
+	// Modes are taken in turn by each worker's requests; nil sends one
+	// unnamed mode.
+	Modes []string
+	// Send sends one request; it is required.
+	Send func(ctx context.Context, p providers.Provider, id RunID) (Sample, error)
+	// Admit, if set, is asked before each request; a non-nil error stops the
+	// worker, e.g. once a usage budget is spent.
+	Admit func() error
+	// Pause, if set, is how long a worker waits after each request instead of
+	// keeping to Interval, e.g. a sampled think time.
+	Pause func(worker int) time.Duration
+	// Park, if set, returns a channel for an idle worker to wait on instead
+	// of sending, closed when it should resume; nil keeps the worker active.
+	// Autoscaling parks the workers above its target.
+	Park func(worker int) <-chan struct{}
+	// Stop, when closed, ends the run gracefully: requests in flight finish
+	// and no worker starts another.
+	Stop <-chan struct{}
+	// Logger returns the logger of a worker's lifecycle messages; nil
+	// discards them.
+	Logger func(worker int) *log.Logger
+}
+
+// withDefaults fills zero-valued fields of cfg.
+func (cfg LoadConfig) withDefaults() (LoadConfig, error) {
+	if cfg.Send == nil {
+		return cfg, errors.New("benchmark: load needs a Send function")
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = DefaultLoadWorkers
+	}
+	if cfg.Duration <= 0 {
+		cfg.Duration = DefaultLoadDuration
+	}
+	if cfg.RequestTimeout <= 0 {
+		cfg.RequestTimeout = DefaultRequestTimeout
+	}
+	if cfg.GracePeriod <= 0 {
+		cfg.GracePeriod = DefaultLoadGracePeriod
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultLoadInterval
+	}
+	if len(cfg.Modes) == 0 {
+		cfg.Modes = []string{""}
+	}
+	if cfg.Logger == nil {
+		discard := log.New(io.Discard, "", 0)
+		cfg.Logger = func(int) *log.Logger { return discard }
+	}
+	return cfg, nil
+}
+
+// Load keeps cfg.Workers workers sending requests to p for cfg.Duration, each
+// sending its first request at once and the next every cfg.Interval, and
+// averages the successful ones. The error reports an unusable configuration;
+// request failures are recorded in the Result.
+func Load(ctx context.Context, cfg LoadConfig, p providers.Provider) (Result, error) {
+	if err := checkProvider(p); err != nil {
+		return Result{}, err
+	}
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Provider: p.Name, Model: p.Model, Start: time.Now()}
+	ctx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+
+	// Each worker appends to its own slot, so no locking is needed
+	outcomes := make([][]Outcome, cfg.Workers)
+	var wg sync.WaitGroup
+	for i := range cfg.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			outcomes[i] = loadWorker(ctx, cfg, p, i+1, result.Start)
+		}()
+	}
+	wg.Wait()
+
+	result.Outcomes = slices.Concat(outcomes...)
+	result.summarize()
+	return result, nil
+}
+
+// loadWorker sends worker's requests until the run ends, returning them in
+// order.
+func loadWorker(ctx context.Context, cfg LoadConfig, p providers.Provider, worker int, start time.Time) []Outcome {
+	logger := cfg.Logger(worker)
+	var outcomes []Outcome
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	// wait blocks until next or wake fires, returning false when the worker
+	// should stop instead
+	wait := func(next <-chan time.Time, wake <-chan struct{}) bool {
+		select {
+		case <-ctx.Done():
+			logger.Printf("Session ended, completed %d requests", len(outcomes))
+			return false
+		case <-cfg.Stop:
+			// The admission check at the top of the loop says why
+			return true
+		case <-next:
+		case <-wake:
+		}
+		// Skip new requests if insufficient time remains
+		if remaining := cfg.Duration - time.Since(start); remaining < cfg.RequestTimeout+cfg.GracePeriod {
+			logger.Printf("Stopping - insufficient time remaining for next request (%.1fs left, need %.1fs)",
+				remaining.Seconds(), (cfg.RequestTimeout + cfg.GracePeriod).Seconds())
+			logger.Printf("Completed %d requests", len(outcomes))
+			return false
+		}
+		return true
+	}
+
+	for {
+		if err := admitLoad(cfg); err != nil {
+			logger.Printf("Stopping - %v, completed %d requests", err, len(outcomes))
+			return outcomes
+		}
+		if cfg.Park != nil {
+			if wake := cfg.Park(worker); wake != nil {
+				// Parked until woken; then send right away and keep the
+				// interval from there
+				if !wait(nil, wake) {
+					return outcomes
+				}
+				ticker.Reset(cfg.Interval)
+				continue
+			}
+		}
+
+		n := len(outcomes) + 1
+		o := Outcome{RunID: RunID{Mode: cfg.Modes[(n-1)%len(cfg.Modes)], Iteration: n, Worker: worker}, Start: time.Now()}
+		reqCtx, reqCancel := context.WithTimeout(ctx, cfg.RequestTimeout)
+		o.Sample, o.Err = cfg.Send(reqCtx, p, o.RunID)
+		reqCancel()
+		o.End = time.Now()
+		outcomes = append(outcomes, o)
+
+		// Wait for the next tick (or pause) or the end of the run
+		next := ticker.C
+		if cfg.Pause != nil {
+			next = time.After(cfg.Pause(worker))
+		}
+		if !wait(next, nil) {
+			return outcomes
+		}
+	}
+}
+
+// admitLoad reports why no further request may start, or nil.
+func admitLoad(cfg LoadConfig) error {
+	if cfg.Admit != nil {
+		if err := cfg.Admit(); err != nil {
+			return err
+		}
+	}
+	select {
+	case <-cfg.Stop:
+		return errStopped
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,873 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Wire protocols a Provider can speak. The zero value is APIOpenAI.
+const (
+	APIOpenAI    = "openai"
+	APIMistral   = "mistral"
+	APICohere    = "cohere"
+	APIAnthropic = "anthropic"
+	APIGemini    = "gemini"
+)
+
+// APIs lists the supported wire protocols.
+var APIs = []string{APIOpenAI, APIMistral, APICohere, APIAnthropic, APIGemini}
+
+// maxEventSize bounds one server-sent event line.
+const maxEventSize = 1 << 20
+
+// Delta is one streamed increment of a chat response, normalized across APIs.
+type Delta struct {
+	Content   string
+	Reasoning string
+	ToolCalls []openai.ToolCall
+	// FinishReason is set on the delta that ends the response, in OpenAI terms
+	// ("stop", "length", "tool_calls", ...).
+	FinishReason string
+	// ID is the response ID the provider assigned, when the API sends one with
+	// each chunk (OpenAI-compatible APIs do).
+	ID string
+	// CompletionTokens is the provider's own count of the response's completion
+	// tokens so far, set on the deltas that report usage (usually only the
+	// last). It is 0 when the API reports none.
+	CompletionTokens int
+}
+
+// Empty reports whether the delta carries no output, e.g. a keep-alive, a
+// role-only chunk or a bare finish reason.
+func (d Delta) Empty() bool {
+	return d.Content == "" && d.Reasoning == "" && len(d.ToolCalls) == 0
+}
+
+// DeltaStream yields the deltas of one streaming chat request.
+type DeltaStream interface {
+	// Recv returns the next delta, or io.EOF once the response is complete.
+	Recv() (Delta, error)
+	// RateLimit returns the x-ratelimit-* headers of the response.
+	RateLimit() openai.RateLimitHeaders
+	Close() error
+}
+
+// OpenStream starts a streaming chat request in the provider's native API. The
+// request is expressed in OpenAI terms and translated for other APIs.
+func OpenStream(ctx context.Context, p Provider, req openai.ChatCompletionRequest) (DeltaStream, error) {
+	stream, err := openStream(ctx, p, req)
+	if err != nil || !p.StripCitations {
+		return stream, err
+	}
+	return &citationFilter{DeltaStream: stream}, nil
+}
+
+// openStream starts the request in the provider's wire protocol.
+func openStream(ctx context.Context, p Provider, req openai.ChatCompletionRequest) (DeltaStream, error) {
+	req.Stream = true
+	switch p.API {
+	case "", APIOpenAI:
+		if !p.NoStreamUsage && req.StreamOptions == nil {
+			req.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+		}
+		clientConfig := openai.DefaultConfig(p.APIKey)
+		clientConfig.BaseURL = p.BaseURL
+		if p.HTTPClient != nil {
+			clientConfig.HTTPClient = p.HTTPClient
+		}
+		stream, err := openai.NewClientWithConfig(clientConfig).CreateChatCompletionStream(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return &openAIStream{stream: stream}, nil
+	case APIMistral:
+		return openSSE(ctx, p, "/chat/completions", mistralRequest(req), bearerAuth(p.APIKey), parseMistralEvent)
+	case APICohere:
+		return openSSE(ctx, p, "/chat", cohereRequest(req), bearerAuth(p.APIKey), parseCohereEvent)
+	case APIAnthropic:
+		return openSSE(ctx, p, "/messages", anthropicRequest(req), anthropicAuth(p.APIKey), parseAnthropicEvent)
+	case APIGemini:
+		return openSSE(ctx, p, geminiStreamPath(req.Model), geminiRequest(req), geminiAuth(p.APIKey), newGeminiParser())
+	default:
+		return nil, fmt.Errorf("unsupported API %q (want one of %s)", p.API, strings.Join(APIs, ", "))
+	}
+}
+
+// openAIStream adapts a go-openai stream.
+type openAIStream struct {
+	stream *openai.ChatCompletionStream
+}
+
+func (s *openAIStream) Recv() (Delta, error) {
+	response, err := s.stream.Recv()
+	if err != nil {
+		return Delta{}, err
+	}
+	var delta Delta
+	if response.Usage != nil {
+		// With include_usage the usage arrives in a final chunk without choices
+		delta.CompletionTokens = response.Usage.CompletionTokens
+	}
+	if len(response.Choices) == 0 {
+		return delta, nil
+	}
+	choice := response.Choices[0]
+	delta.Content = choice.Delta.Content
+	delta.Reasoning = choice.Delta.ReasoningContent
+	delta.ToolCalls = choice.Delta.ToolCalls
+	delta.FinishReason = string(choice.FinishReason)
+	delta.ID = response.ID
+	return delta, nil
+}
+
+func (s *openAIStream) RateLimit() openai.RateLimitHeaders { return s.stream.GetRateLimitHeaders() }
+
+func (s *openAIStream) Close() error { return s.stream.Close() }
+
+// eventParser turns one server-sent event into a delta; done reports the end of
+// the response.
+type eventParser func(event string, data []byte) (delta Delta, done bool, err error)
+
+// sseStream reads a native API's server-sent events.
+type sseStream struct {
+	resp    *http.Response
+	scanner *bufio.Scanner
+	parse   eventParser
+	done    bool
+}
+
+// bearerAuth is the Authorization header of APIs that take the key as a
+// bearer token.
+func bearerAuth(apiKey string) http.Header {
+	return http.Header{"Authorization": {"Bearer " + apiKey}}
+}
+
+// openSSE posts body with the auth headers to the provider's endpoint and
+// returns its event stream. HTTP errors are returned as *openai.RequestError so
+// callers can inspect the status code the same way for every API.
+func openSSE(ctx context.Context, p Provider, path string, body any, auth http.Header, parse eventParser) (DeltaStream, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(p.BaseURL, "/")+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range auth {
+		httpReq.Header[name] = values
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	client := p.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxEventSize))
+		return nil, &openai.RequestError{
+			HTTPStatus:     resp.Status,
+			HTTPStatusCode: resp.StatusCode,
+			Err:            fmt.Errorf("%s", bytes.TrimSpace(respBody)),
+			Body:           respBody,
+		}
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventSize)
+	return &sseStream{resp: resp, scanner: scanner, parse: parse}, nil
+}
+
+// Recv returns the delta of the next event, io.EOF after the last one, or
+// io.ErrUnexpectedEOF if the connection closes before the response ends.
+func (s *sseStream) Recv() (Delta, error) {
+	if s.done {
+		return Delta{}, io.EOF
+	}
+	var event string
+	var data []byte
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		switch {
+		case line == "":
+			if data == nil {
+				continue
+			}
+			delta, done, err := s.parse(event, data)
+			s.done = done
+			if done && err == nil && delta.Empty() && delta.FinishReason == "" {
+				return Delta{}, io.EOF
+			}
+			return delta, err
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimSpace(strings.TrimPrefix(line, "data:"))...)
+		}
+	}
+	if err := s.scanner.Err(); err != nil {
+		return Delta{}, err
+	}
+	if data != nil {
+		delta, done, err := s.parse(event, data)
+		s.done = true
+		if err != nil || !done || !delta.Empty() || delta.FinishReason != "" {
+			return delta, err
+		}
+	}
+	if !s.done {
+		return Delta{}, io.ErrUnexpectedEOF
+	}
+	return Delta{}, io.EOF
+}
+
+func (s *sseStream) RateLimit() openai.RateLimitHeaders { return rateLimitHeaders(s.resp.Header) }
+
+func (s *sseStream) Close() error { return s.resp.Body.Close() }
+
+// rateLimitHeaders parses OpenAI-style rate-limit headers, which go-openai only
+// exposes on its own responses, or Anthropic's equivalents.
+func rateLimitHeaders(h http.Header) openai.RateLimitHeaders {
+	atoi := func(name string) int {
+		var n int
+		_, _ = fmt.Sscan(h.Get(name), &n)
+		return n
+	}
+	if h.Get("anthropic-ratelimit-requests-limit") != "" {
+		// Anthropic sends resets as RFC 3339 times rather than durations
+		reset := func(name string) openai.ResetTime {
+			t, err := time.Parse(time.RFC3339, h.Get(name))
+			if err != nil {
+				return ""
+			}
+			return openai.ResetTime(max(0, time.Until(t)).Round(time.Second).String())
+		}
+		return openai.RateLimitHeaders{
+			LimitRequests:     atoi("anthropic-ratelimit-requests-limit"),
+			LimitTokens:       atoi("anthropic-ratelimit-tokens-limit"),
+			RemainingRequests: atoi("anthropic-ratelimit-requests-remaining"),
+			RemainingTokens:   atoi("anthropic-ratelimit-tokens-remaining"),
+			ResetRequests:     reset("anthropic-ratelimit-requests-reset"),
+			ResetTokens:       reset("anthropic-ratelimit-tokens-reset"),
+		}
+	}
+	return openai.RateLimitHeaders{
+		LimitRequests:     atoi("x-ratelimit-limit-requests"),
+		LimitTokens:       atoi("x-ratelimit-limit-tokens"),
+		RemainingRequests: atoi("x-ratelimit-remaining-requests"),
+		RemainingTokens:   atoi("x-ratelimit-remaining-tokens"),
+		ResetRequests:     openai.ResetTime(h.Get("x-ratelimit-reset-requests")),
+		ResetTokens:       openai.ResetTime(h.Get("x-ratelimit-reset-tokens")),
+	}
+}
+
+// Mistral's native API is close to OpenAI's, but message content may be a list
+// of typed chunks (text, or thinking from its reasoning models) and tool choice
+// "required" is spelled "any".
+
+type mistralMessage struct {
+	Role       string            `json:"role"`
+	Content    string            `json:"content"`
+	ToolCalls  []openai.ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string            `json:"tool_call_id,omitempty"`
+}
+
+type mistralChatRequest struct {
+	Model             string           `json:"model"`
+	Messages          []mistralMessage `json:"messages"`
+	MaxTokens         int              `json:"max_tokens,omitempty"`
+	Temperature       float32          `json:"temperature,omitempty"`
+	Stream            bool             `json:"stream"`
+	Tools             []openai.Tool    `json:"tools,omitempty"`
+	ToolChoice        any              `json:"tool_choice,omitempty"`
+	ParallelToolCalls any              `json:"parallel_tool_calls,omitempty"`
+}
+
+// mistralRequest translates an OpenAI chat request to Mistral's native format.
+func mistralRequest(req openai.ChatCompletionRequest) mistralChatRequest {
+	out := mistralChatRequest{
+		Model:             req.Model,
+		MaxTokens:         max(req.MaxTokens, req.MaxCompletionTokens),
+		Temperature:       req.Temperature,
+		Stream:            true,
+		Tools:             req.Tools,
+		ToolChoice:        req.ToolChoice,
+		ParallelToolCalls: req.ParallelToolCalls,
+	}
+	if req.ToolChoice == "required" {
+		out.ToolChoice = "any"
+	}
+	for _, m := range req.Messages {
+		out.Messages = append(out.Messages, mistralMessage{Role: m.Role, Content: m.Content, ToolCalls: m.ToolCalls, ToolCallID: m.ToolCallID})
+	}
+	return out
+}
+
+type mistralChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   json.RawMessage   `json:"content"`
+			ToolCalls []openai.ToolCall `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+type mistralContentChunk struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	Thinking []struct {
+		Text string `json:"text"`
+	} `json:"thinking"`
+}
+
+// parseMistralEvent decodes one Mistral stream chunk.
+func parseMistralEvent(_ string, data []byte) (Delta, bool, error) {
+	if string(data) == "[DONE]" {
+		return Delta{}, true, nil
+	}
+	var chunk mistralChunk
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return Delta{}, false, fmt.Errorf("error decoding Mistral chunk: %w", err)
+	}
+	if len(chunk.Choices) == 0 {
+		return Delta{CompletionTokens: chunk.Usage.CompletionTokens}, false, nil
+	}
+	choice := chunk.Choices[0].Delta
+	delta := Delta{ToolCalls: choice.ToolCalls, FinishReason: chunk.Choices[0].FinishReason, CompletionTokens: chunk.Usage.CompletionTokens}
+	content := bytes.TrimSpace(choice.Content)
+	switch {
+	case len(content) == 0 || string(content) == "null":
+	case content[0] == '"':
+		if err := json.Unmarshal(content, &delta.Content); err != nil {
+			return Delta{}, false, fmt.Errorf("error decoding Mistral content: %w", err)
+		}
+	default:
+		var parts []mistralContentChunk
+		if err := json.Unmarshal(content, &parts); err != nil {
+			return Delta{}, false, fmt.Errorf("error decoding Mistral content: %w", err)
+		}
+		for _, part := range parts {
+			switch part.Type {
+			case "text":
+				delta.Content += part.Text
+			case "thinking":
+				for _, t := range part.Thinking {
+					delta.Reasoning += t.Text
+				}
+			}
+		}
+	}
+	return delta, false, nil
+}
+
+// Cohere's v2 chat API streams typed events (content-delta, tool-plan-delta,
+// tool-call-start, tool-call-delta, message-end, ...) instead of OpenAI chunks.
+
+type cohereMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type cohereChatRequest struct {
+	Model       string          `json:"model"`
+	Messages    []cohereMessage `json:"messages"`
+	MaxTokens   int             `json:"max_tokens,omitempty"`
+	Temperature float32         `json:"temperature,omitempty"`
+	Stream      bool            `json:"stream"`
+	Tools       []openai.Tool   `json:"tools,omitempty"`
+	ToolChoice  string          `json:"tool_choice,omitempty"`
+}
+
+// cohereRequest translates an OpenAI chat request to Cohere's v2 format.
+func cohereRequest(req openai.ChatCompletionRequest) cohereChatRequest {
+	out := cohereChatRequest{
+		Model:       req.Model,
+		MaxTokens:   max(req.MaxTokens, req.MaxCompletionTokens),
+		Temperature: req.Temperature,
+		Stream:      true,
+		Tools:       req.Tools,
+	}
+	switch req.ToolChoice {
+	case "required":
+		out.ToolChoice = "REQUIRED"
+	case "none":
+		out.ToolChoice = "NONE"
+	}
+	for _, m := range req.Messages {
+		out.Messages = append(out.Messages, cohereMessage{Role: m.Role, Content: m.Content})
+	}
+	return out
+}
+
+type cohereEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Message struct {
+			Content struct {
+				Text     string `json:"text"`
+				Thinking string `json:"thinking"`
+			} `json:"content"`
+			ToolPlan  string `json:"tool_plan"`
+			ToolCalls struct {
+				ID       string `json:"id"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"message"`
+		Error        string `json:"error"`
+		FinishReason string `json:"finish_reason"`
+		Usage        struct {
+			Tokens struct {
+				OutputTokens int `json:"output_tokens"`
+			} `json:"tokens"`
+		} `json:"usage"`
+	} `json:"delta"`
+}
+
+// cohereFinishReasons maps Cohere's finish reasons to OpenAI's.
+var cohereFinishReasons = map[string]string{
+	"COMPLETE":      "stop",
+	"STOP_SEQUENCE": "stop",
+	"MAX_TOKENS":    "length",
+	"TOOL_CALL":     "tool_calls",
+}
+
+// parseCohereEvent decodes one Cohere stream event.
+func parseCohereEvent(event string, data []byte) (Delta, bool, error) {
+	var e cohereEvent
+	if err := json.Unmarshal(data, &e); err != nil {
+		return Delta{}, false, fmt.Errorf("error decoding Cohere event: %w", err)
+	}
+	if e.Type == "" {
+		e.Type = event
+	}
+	message := e.Delta.Message
+	switch e.Type {
+	case "content-delta":
+		return Delta{Content: message.Content.Text, Reasoning: message.Content.Thinking}, false, nil
+	case "tool-plan-delta":
+		return Delta{Reasoning: message.ToolPlan}, false, nil
+	case "tool-call-start", "tool-call-delta":
+		call := openai.ToolCall{ID: message.ToolCalls.ID, Type: openai.ToolTypeFunction}
+		call.Function.Name = message.ToolCalls.Function.Name
+		call.Function.Arguments = message.ToolCalls.Function.Arguments
+		if call.Function.Name == "" && call.Function.Arguments == "" {
+			return Delta{}, false, nil
+		}
+		return Delta{ToolCalls: []openai.ToolCall{call}}, false, nil
+	case "message-end":
+		if e.Delta.Error != "" {
+			return Delta{}, true, errors.New(e.Delta.Error)
+		}
+		reason, ok := cohereFinishReasons[e.Delta.FinishReason]
+		if !ok {
+			reason = strings.ToLower(e.Delta.FinishReason)
+		}
+		return Delta{FinishReason: reason, CompletionTokens: e.Delta.Usage.Tokens.OutputTokens}, true, nil
+	default:
+		return Delta{}, false, nil
+	}
+}
+
+// Anthropic's Messages API takes the system prompt as a separate field, requires
+// max_tokens and streams typed events (content_block_start, content_block_delta,
+// message_delta, message_stop, ...) with text, thinking and tool input in
+// separate content blocks.
+
+// anthropicVersion is the anthropic-version header sent with every request.
+const anthropicVersion = "2023-06-01"
+
+// anthropicDefaultMaxTokens is sent when the request sets no output limit,
+// since the Messages API requires one.
+const anthropicDefaultMaxTokens = 4096
+
+// anthropicAuth returns the headers that authenticate a Messages API request.
+func anthropicAuth(apiKey string) http.Header {
+	return http.Header{"X-Api-Key": {apiKey}, "Anthropic-Version": {anthropicVersion}}
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicTool struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	InputSchema any    `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+type anthropicChatRequest struct {
+	Model       string               `json:"model"`
+	System      string               `json:"system,omitempty"`
+	Messages    []anthropicMessage   `json:"messages"`
+	MaxTokens   int                  `json:"max_tokens"`
+	Temperature float32              `json:"temperature,omitempty"`
+	Stream      bool                 `json:"stream"`
+	Tools       []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice  *anthropicToolChoice `json:"tool_choice,omitempty"`
+}
+
+// anthropicRequest translates an OpenAI chat request to the Messages API.
+// System and developer messages are joined into the system prompt.
+func anthropicRequest(req openai.ChatCompletionRequest) anthropicChatRequest {
+	out := anthropicChatRequest{
+		Model:       req.Model,
+		MaxTokens:   max(req.MaxTokens, req.MaxCompletionTokens),
+		Temperature: req.Temperature,
+		Stream:      true,
+	}
+	if out.MaxTokens == 0 {
+		out.MaxTokens = anthropicDefaultMaxTokens
+	}
+	var system []string
+	for _, m := range req.Messages {
+		switch m.Role {
+		case openai.ChatMessageRoleSystem, openai.ChatMessageRoleDeveloper:
+			system = append(system, m.Content)
+		default:
+			out.Messages = append(out.Messages, anthropicMessage{Role: m.Role, Content: m.Content})
+		}
+	}
+	out.System = strings.Join(system, "\n\n")
+	for _, t := range req.Tools {
+		if t.Function == nil {
+			continue
+		}
+		schema := t.Function.Parameters
+		if schema == nil {
+			schema = map[string]any{"type": "object"}
+		}
+		out.Tools = append(out.Tools, anthropicTool{Name: t.Function.Name, Description: t.Function.Description, InputSchema: schema})
+	}
+	switch choice := req.ToolChoice.(type) {
+	case string:
+		switch choice {
+		case "required":
+			out.ToolChoice = &anthropicToolChoice{Type: "any"}
+		case "auto", "none":
+			out.ToolChoice = &anthropicToolChoice{Type: choice}
+		}
+	case openai.ToolChoice:
+		out.ToolChoice = &anthropicToolChoice{Type: "tool", Name: choice.Function.Name}
+	case *openai.ToolChoice:
+		out.ToolChoice = &anthropicToolChoice{Type: "tool", Name: choice.Function.Name}
+	}
+	return out
+}
+
+type anthropicEvent struct {
+	Type         string `json:"type"`
+	Index        int    `json:"index"`
+	ContentBlock struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"content_block"`
+	Delta struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		Thinking    string `json:"thinking"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Message struct {
+		ID string `json:"id"`
+	} `json:"message"`
+	// Usage is cumulative; message_delta carries the final output count.
+	Usage struct {
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// anthropicStopReasons maps Anthropic's stop reasons to OpenAI's finish reasons.
+var anthropicStopReasons = map[string]string{
+	"end_turn":      "stop",
+	"stop_sequence": "stop",
+	"max_tokens":    "length",
+	"tool_use":      "tool_calls",
+	"refusal":       "content_filter",
+}
+
+// parseAnthropicEvent decodes one Messages API stream event.
+func parseAnthropicEvent(event string, data []byte) (Delta, bool, error) {
+	var e anthropicEvent
+	if err := json.Unmarshal(data, &e); err != nil {
+		return Delta{}, false, fmt.Errorf("error decoding Anthropic event: %w", err)
+	}
+	if e.Type == "" {
+		e.Type = event
+	}
+	switch e.Type {
+	case "message_start":
+		return Delta{ID: e.Message.ID}, false, nil
+	case "content_block_start":
+		if e.ContentBlock.Type != "tool_use" {
+			return Delta{}, false, nil
+		}
+		index := e.Index
+		call := openai.ToolCall{Index: &index, ID: e.ContentBlock.ID, Type: openai.ToolTypeFunction}
+		call.Function.Name = e.ContentBlock.Name
+		return Delta{ToolCalls: []openai.ToolCall{call}}, false, nil
+	case "content_block_delta":
+		switch e.Delta.Type {
+		case "text_delta":
+			return Delta{Content: e.Delta.Text}, false, nil
+		case "thinking_delta":
+			return Delta{Reasoning: e.Delta.Thinking}, false, nil
+		case "input_json_delta":
+			if e.Delta.PartialJSON == "" {
+				return Delta{}, false, nil
+			}
+			index := e.Index
+			call := openai.ToolCall{Index: &index, Type: openai.ToolTypeFunction}
+			call.Function.Arguments = e.Delta.PartialJSON
+			return Delta{ToolCalls: []openai.ToolCall{call}}, false, nil
+		}
+		return Delta{}, false, nil
+	case "message_delta":
+		delta := Delta{CompletionTokens: e.Usage.OutputTokens}
+		if e.Delta.StopReason == "" {
+			return delta, false, nil
+		}
+		reason, ok := anthropicStopReasons[e.Delta.StopReason]
+		if !ok {
+			reason = e.Delta.StopReason
+		}
+		delta.FinishReason = reason
+		return delta, false, nil
+	case "message_stop":
+		return Delta{}, true, nil
+	case "error":
+		return Delta{}, true, fmt.Errorf("%s: %s", e.Error.Type, e.Error.Message)
+	default:
+		return Delta{}, false, nil
+	}
+}
+
+// Gemini's Generative Language API streams whole GenerateContentResponse
+// objects from streamGenerateContent?alt=sse: each holds the next parts of the
+// first candidate (text, thought summaries or complete function calls). There is
+// no end marker; the chunk carrying a finishReason is the last.
+
+// geminiStreamPath is the streaming endpoint of model, relative to the API
+// version's base URL.
+func geminiStreamPath(model string) string {
+	return "/models/" + strings.TrimPrefix(model, "models/") + ":streamGenerateContent?alt=sse"
+}
+
+// geminiAuth returns the headers that authenticate a Gemini API request.
+func geminiAuth(apiKey string) http.Header {
+	return http.Header{"X-Goog-Api-Key": {apiKey}}
+}
+
+type geminiPart struct {
+	Text         string `json:"text,omitempty"`
+	Thought      bool   `json:"thought,omitempty"`
+	FunctionCall *struct {
+		ID   string          `json:"id"`
+		Name string          `json:"name"`
+		Args json.RawMessage `json:"args"`
+	} `json:"functionCall,omitempty"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type geminiToolConfig struct {
+	FunctionCallingConfig struct {
+		Mode                 string   `json:"mode"`
+		AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+	} `json:"functionCallingConfig"`
+}
+
+type geminiGenerationConfig struct {
+	MaxOutputTokens int     `json:"maxOutputTokens,omitempty"`
+	Temperature     float32 `json:"temperature,omitempty"`
+}
+
+type geminiChatRequest struct {
+	Contents          []geminiContent        `json:"contents"`
+	SystemInstruction *geminiContent         `json:"systemInstruction,omitempty"`
+	GenerationConfig  geminiGenerationConfig `json:"generationConfig"`
+	Tools             []geminiTool           `json:"tools,omitempty"`
+	ToolConfig        *geminiToolConfig      `json:"toolConfig,omitempty"`
+}
+
+// geminiRequest translates an OpenAI chat request to Gemini's format. The
+// model goes in the URL; system and developer messages become the system
+// instruction and assistant turns the "model" role.
+func geminiRequest(req openai.ChatCompletionRequest) geminiChatRequest {
+	out := geminiChatRequest{GenerationConfig: geminiGenerationConfig{
+		MaxOutputTokens: max(req.MaxTokens, req.MaxCompletionTokens),
+		Temperature:     req.Temperature,
+	}}
+	for _, m := range req.Messages {
+		switch m.Role {
+		case openai.ChatMessageRoleSystem, openai.ChatMessageRoleDeveloper:
+			if out.SystemInstruction == nil {
+				out.SystemInstruction = &geminiContent{}
+			}
+			out.SystemInstruction.Parts = append(out.SystemInstruction.Parts, geminiPart{Text: m.Content})
+		case openai.ChatMessageRoleAssistant:
+			out.Contents = append(out.Contents, geminiContent{Role: "model", Parts: []geminiPart{{Text: m.Content}}})
+		default:
+			out.Contents = append(out.Contents, geminiContent{Role: "user", Parts: []geminiPart{{Text: m.Content}}})
+		}
+	}
+	var declarations []geminiFunctionDeclaration
+	for _, t := range req.Tools {
+		if t.Function != nil {
+			declarations = append(declarations, geminiFunctionDeclaration{
+				Name: t.Function.Name, Description: t.Function.Description, Parameters: t.Function.Parameters,
+			})
+		}
+	}
+	if len(declarations) > 0 {
+		out.Tools = []geminiTool{{FunctionDeclarations: declarations}}
+	}
+	mode, name := "", ""
+	switch choice := req.ToolChoice.(type) {
+	case string:
+		mode = map[string]string{"required": "ANY", "auto": "AUTO", "none": "NONE"}[choice]
+	case openai.ToolChoice:
+		mode, name = "ANY", choice.Function.Name
+	case *openai.ToolChoice:
+		mode, name = "ANY", choice.Function.Name
+	}
+	if mode != "" {
+		out.ToolConfig = &geminiToolConfig{}
+		out.ToolConfig.FunctionCallingConfig.Mode = mode
+		if name != "" {
+			out.ToolConfig.FunctionCallingConfig.AllowedFunctionNames = []string{name}
+		}
+	}
+	return out
+}
+
+type geminiChunk struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	ResponseID string `json:"responseId"`
+	// UsageMetadata is cumulative; thinking tokens are billed as output too.
+	UsageMetadata struct {
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		ThoughtsTokenCount   int `json:"thoughtsTokenCount"`
+	} `json:"usageMetadata"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+// geminiFinishReasons maps Gemini's finish reasons to OpenAI's.
+var geminiFinishReasons = map[string]string{
+	"STOP":               "stop",
+	"MAX_TOKENS":         "length",
+	"SAFETY":             "content_filter",
+	"RECITATION":         "content_filter",
+	"BLOCKLIST":          "content_filter",
+	"PROHIBITED_CONTENT": "content_filter",
+	"SPII":               "content_filter",
+}
+
+// newGeminiParser returns a parser for one Gemini stream. Function calls arrive
+// whole and usually without an ID, so the parser numbers them to keep several
+// calls apart.
+func newGeminiParser() eventParser {
+	calls := 0
+	return func(_ string, data []byte) (Delta, bool, error) {
+		var chunk geminiChunk
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return Delta{}, false, fmt.Errorf("error decoding Gemini chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return Delta{}, true, fmt.Errorf("%s: %s", chunk.Error.Status, chunk.Error.Message)
+		}
+		usage := chunk.UsageMetadata.CandidatesTokenCount + chunk.UsageMetadata.ThoughtsTokenCount
+		if len(chunk.Candidates) == 0 {
+			if reason := chunk.PromptFeedback.BlockReason; reason != "" {
+				return Delta{}, true, fmt.Errorf("prompt blocked: %s", reason)
+			}
+			return Delta{ID: chunk.ResponseID, CompletionTokens: usage}, false, nil
+		}
+		candidate := chunk.Candidates[0]
+		delta := Delta{ID: chunk.ResponseID, CompletionTokens: usage}
+		for _, part := range candidate.Content.Parts {
+			switch {
+			case part.FunctionCall != nil:
+				index := calls
+				calls++
+				call := openai.ToolCall{Index: &index, ID: part.FunctionCall.ID, Type: openai.ToolTypeFunction}
+				call.Function.Name = part.FunctionCall.Name
+				call.Function.Arguments = string(part.FunctionCall.Args)
+				delta.ToolCalls = append(delta.ToolCalls, call)
+			case part.Thought:
+				delta.Reasoning += part.Text
+			default:
+				delta.Content += part.Text
+			}
+		}
+		if candidate.FinishReason == "" {
+			return delta, false, nil
+		}
+		reason, ok := geminiFinishReasons[candidate.FinishReason]
+		if !ok {
+			reason = strings.ToLower(candidate.FinishReason)
+		}
+		if reason == "stop" && calls > 0 {
+			reason = "tool_calls"
+		}
+		delta.FinishReason = reason
+		return delta, true, nil
+	}
+}
@@ -0,0 +1,398 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// nativeServer replays a raw event stream at path and records the request body.
+func nativeServer(t *testing.T, path, events string, body *map[string]any) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, events)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// collect reads a stream to the end, joining content, reasoning and tool calls.
+func collect(t *testing.T, stream DeltaStream) (content, reasoning, tools string) {
+	t.Helper()
+	defer stream.Close()
+	for {
+		delta, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return content, reasoning, tools
+		}
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		content += delta.Content
+		reasoning += delta.Reasoning
+		for _, call := range delta.ToolCalls {
+			tools += call.Function.Name + call.Function.Arguments
+		}
+	}
+}
+
+func toolRequest() openai.ChatCompletionRequest {
+	return openai.ChatCompletionRequest{
+		Model:      "m",
+		Messages:   []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "weather?"}},
+		MaxTokens:  64,
+		Tools:      []openai.Tool{{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{Name: "get_weather"}}},
+		ToolChoice: "required",
+	}
+}
+
+func TestMistralStream(t *testing.T) {
+	events := `data: {"choices":[{"delta":{"role":"assistant","content":""}}]}
+
+data: {"choices":[{"delta":{"content":[{"type":"thinking","thinking":[{"type":"text","text":"hmm"}]}]}}]}
+
+data: {"choices":[{"delta":{"content":"It is "}}]}
+
+data: {"choices":[{"delta":{"content":[{"type":"text","text":"sunny"}]}}]}
+
+data: {"choices":[{"delta":{"tool_calls":[{"id":"a","function":{"name":"get_weather","arguments":"{}"}}]}}]}
+
+data: [DONE]
+
+`
+	var body map[string]any
+	srv := nativeServer(t, "/v1/chat/completions", events, &body)
+	stream, err := OpenStream(context.Background(), Provider{BaseURL: srv.URL + "/v1", API: APIMistral}, toolRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, reasoning, tools := collect(t, stream)
+	if content != "It is sunny" || reasoning != "hmm" || tools != "get_weather{}" {
+		t.Errorf("got content %q reasoning %q tools %q", content, reasoning, tools)
+	}
+	if body["tool_choice"] != "any" || body["stream"] != true {
+		t.Errorf("unexpected request %v", body)
+	}
+}
+
+func TestCohereStream(t *testing.T) {
+	events := `event: message-start
+data: {"type":"message-start","delta":{"message":{"role":"assistant"}}}
+
+event: tool-plan-delta
+data: {"type":"tool-plan-delta","delta":{"message":{"tool_plan":"I will check"}}}
+
+event: tool-call-start
+data: {"type":"tool-call-start","delta":{"message":{"tool_calls":{"id":"c1","function":{"name":"get_weather","arguments":""}}}}}
+
+event: tool-call-delta
+data: {"type":"tool-call-delta","delta":{"message":{"tool_calls":{"function":{"arguments":"{\"city\":\"Oslo\"}"}}}}}
+
+event: content-delta
+data: {"type":"content-delta","delta":{"message":{"content":{"text":"Done."}}}}
+
+event: message-end
+data: {"type":"message-end","delta":{"finish_reason":"COMPLETE"}}
+
+`
+	var body map[string]any
+	srv := nativeServer(t, "/v2/chat", events, &body)
+	stream, err := OpenStream(context.Background(), Provider{BaseURL: srv.URL + "/v2", API: APICohere}, toolRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, reasoning, tools := collect(t, stream)
+	if content != "Done." || reasoning != "I will check" || tools != `get_weather{"city":"Oslo"}` {
+		t.Errorf("got content %q reasoning %q tools %q", content, reasoning, tools)
+	}
+	if body["tool_choice"] != "REQUIRED" || body["max_tokens"] != float64(64) {
+		t.Errorf("unexpected request %v", body)
+	}
+}
+
+func TestAnthropicStream(t *testing.T) {
+	events := `event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","role":"assistant"}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"hmm"}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}
+
+event: ping
+data: {"type":"ping"}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"Checking."}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":2,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"\"Oslo\"}"}}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":12}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`
+	var body map[string]any
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, events)
+	}))
+	defer srv.Close()
+
+	req := toolRequest()
+	req.Messages = append([]openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleSystem, Content: "Be brief."}}, req.Messages...)
+	stream, err := OpenStream(context.Background(), Provider{BaseURL: srv.URL + "/v1", APIKey: "sk-ant", API: APIAnthropic}, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, reasoning, tools := collect(t, stream)
+	if content != "Checking." || reasoning != "hmm" || tools != `get_weather{"city":"Oslo"}` {
+		t.Errorf("got content %q reasoning %q tools %q", content, reasoning, tools)
+	}
+	if header.Get("X-Api-Key") != "sk-ant" || header.Get("Anthropic-Version") == "" || header.Get("Authorization") != "" {
+		t.Errorf("unexpected headers %v", header)
+	}
+	messages, _ := body["messages"].([]any)
+	choice, _ := body["tool_choice"].(map[string]any)
+	if body["system"] != "Be brief." || len(messages) != 1 || body["max_tokens"] != float64(64) || choice["type"] != "any" {
+		t.Errorf("unexpected request %v", body)
+	}
+
+	if got := anthropicRequest(openai.ChatCompletionRequest{}); got.MaxTokens != anthropicDefaultMaxTokens {
+		t.Errorf("expected the default max_tokens without a limit, got %d", got.MaxTokens)
+	}
+	if got := anthropicRequest(openai.ChatCompletionRequest{ToolChoice: openai.ToolChoice{Function: openai.ToolFunction{Name: "f"}}}); got.ToolChoice == nil ||
+		*got.ToolChoice != (anthropicToolChoice{Type: "tool", Name: "f"}) {
+		t.Errorf("expected a named tool choice, got %+v", got.ToolChoice)
+	}
+}
+
+func TestAnthropicStreamError(t *testing.T) {
+	events := "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n"
+	var body map[string]any
+	srv := nativeServer(t, "/v1/messages", events, &body)
+	stream, err := OpenStream(context.Background(), Provider{BaseURL: srv.URL + "/v1", API: APIAnthropic}, toolRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	if _, err := stream.Recv(); err == nil || !strings.Contains(err.Error(), "overloaded_error") {
+		t.Errorf("expected the stream error, got %v", err)
+	}
+}
+
+func TestGeminiStream(t *testing.T) {
+	events := `data: {"candidates":[{"content":{"role":"model","parts":[{"text":"Let me ","thought":true}]}}],"responseId":"r1"}
+
+data: {"candidates":[{"content":{"role":"model","parts":[{"text":"It is "}]}}],"responseId":"r1"}
+
+data: {"candidates":[{"content":{"role":"model","parts":[{"text":"sunny"},{"functionCall":{"name":"get_weather","args":{"city":"Oslo"}}},{"functionCall":{"name":"get_time","args":{}}}]},"finishReason":"STOP"}],"responseId":"r1"}
+
+`
+	var body map[string]any
+	var path, query, key string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, query, key = r.URL.Path, r.URL.RawQuery, r.Header.Get("X-Goog-Api-Key")
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, events)
+	}))
+	defer srv.Close()
+
+	req := toolRequest()
+	req.Model = "models/gemini-2.5-flash"
+	req.Messages = append([]openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleSystem, Content: "Be brief."}}, req.Messages...)
+	stream, err := OpenStream(context.Background(), Provider{BaseURL: srv.URL + "/v1beta", APIKey: "AIza", API: APIGemini}, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	var content, reasoning, reason string
+	var calls []openai.ToolCall
+	for {
+		delta, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		content += delta.Content
+		reasoning += delta.Reasoning
+		calls = append(calls, delta.ToolCalls...)
+		if delta.FinishReason != "" {
+			reason = delta.FinishReason
+		}
+	}
+	if content != "It is sunny" || reasoning != "Let me " || reason != "tool_calls" {
+		t.Errorf("got content %q reasoning %q finish reason %q", content, reasoning, reason)
+	}
+	if len(calls) != 2 || calls[0].Function.Arguments != `{"city":"Oslo"}` || *calls[1].Index != 1 {
+		t.Errorf("unexpected tool calls %+v", calls)
+	}
+	if path != "/v1beta/models/gemini-2.5-flash:streamGenerateContent" || query != "alt=sse" || key != "AIza" {
+		t.Errorf("unexpected request to %s?%s with key %q", path, query, key)
+	}
+	system, _ := body["systemInstruction"].(map[string]any)
+	contents, _ := body["contents"].([]any)
+	config, _ := body["generationConfig"].(map[string]any)
+	toolConfig, _ := body["toolConfig"].(map[string]any)
+	calling, _ := toolConfig["functionCallingConfig"].(map[string]any)
+	if system == nil || len(contents) != 1 || config["maxOutputTokens"] != float64(64) || calling["mode"] != "ANY" {
+		t.Errorf("unexpected request %v", body)
+	}
+}
+
+func TestGeminiStreamErrors(t *testing.T) {
+	for _, events := range []string{
+		"data: {\"promptFeedback\":{\"blockReason\":\"SAFETY\"}}\n\n",
+		"data: {\"error\":{\"code\":429,\"message\":\"quota\",\"status\":\"RESOURCE_EXHAUSTED\"}}\n\n",
+	} {
+		var body map[string]any
+		srv := nativeServer(t, "/v1beta/models/m:streamGenerateContent", events, &body)
+		stream, err := OpenStream(context.Background(), Provider{BaseURL: srv.URL + "/v1beta", API: APIGemini}, toolRequest())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := stream.Recv(); err == nil {
+			t.Errorf("expected an error from %q", events)
+		}
+		stream.Close()
+	}
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Set("anthropic-ratelimit-requests-limit", "50")
+	h.Set("anthropic-ratelimit-requests-remaining", "49")
+	h.Set("anthropic-ratelimit-tokens-limit", "40000")
+	h.Set("anthropic-ratelimit-tokens-remaining", "39000")
+	h.Set("anthropic-ratelimit-requests-reset", time.Now().Add(30*time.Second).UTC().Format(time.RFC3339))
+	got := rateLimitHeaders(h)
+	if got.LimitRequests != 50 || got.RemainingRequests != 49 || got.LimitTokens != 40000 || got.RemainingTokens != 39000 {
+		t.Errorf("unexpected limits %+v", got)
+	}
+	if reset := got.ResetRequests.String(); reset == "" || reset == "0s" {
+		t.Errorf("expected a reset duration, got %q", reset)
+	}
+
+	h = http.Header{}
+	h.Set("x-ratelimit-remaining-requests", "7")
+	if got := rateLimitHeaders(h); got.RemainingRequests != 7 {
+		t.Errorf("unexpected OpenAI-style limits %+v", got)
+	}
+}
+
+func TestNativeStreamErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/chat") {
+			http.Error(w, `{"message":"too many requests"}`, http.StatusTooManyRequests)
+			return
+		}
+		// Mistral stream cut off before [DONE]
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"partial\"}}]}\n\n")
+	}))
+	defer srv.Close()
+
+	_, err := OpenStream(context.Background(), Provider{BaseURL: srv.URL, API: APICohere}, toolRequest())
+	var reqErr *openai.RequestError
+	if !errors.As(err, &reqErr) || reqErr.HTTPStatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected a 429 RequestError, got %v", err)
+	}
+
+	stream, err := OpenStream(context.Background(), Provider{BaseURL: srv.URL, API: APIMistral}, toolRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	if delta, err := stream.Recv(); err != nil || delta.Content != "partial" {
+		t.Fatalf("first Recv = %+v, %v", delta, err)
+	}
+	if _, err := stream.Recv(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("truncated stream returned %v, want io.ErrUnexpectedEOF", err)
+	}
+
+	if _, err := OpenStream(context.Background(), Provider{API: "grpc"}, toolRequest()); err == nil {
+		t.Error("unknown API accepted")
+	}
+}
+
+func TestStreamFinishReason(t *testing.T) {
+	tests := []struct {
+		api, path, events, want string
+	}{
+		{APIOpenAI, "/v1/chat/completions", "data: {\"choices\":[{\"delta\":{\"content\":\"cut\"}}]}\n\n" +
+			"data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"length\"}]}\n\ndata: [DONE]\n\n", "length"},
+		{APIMistral, "/v1/chat/completions", "data: {\"choices\":[{\"delta\":{\"content\":\"done\"},\"finish_reason\":\"stop\"}]}\n\n" +
+			"data: [DONE]\n\n", "stop"},
+		{APICohere, "/v1/chat", "event: content-delta\ndata: {\"type\":\"content-delta\",\"delta\":{\"message\":{\"content\":{\"text\":\"cut\"}}}}\n\n" +
+			"event: message-end\ndata: {\"type\":\"message-end\",\"delta\":{\"finish_reason\":\"MAX_TOKENS\"}}\n\n", "length"},
+		{APIAnthropic, "/v1/messages", "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"cut\"}}\n\n" +
+			"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"max_tokens\"}}\n\n" +
+			"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n", "length"},
+		{APIGemini, "/v1/models/m:streamGenerateContent", "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"cut\"}]},\"finishReason\":\"MAX_TOKENS\"}]}\n\n", "length"},
+	}
+	for _, tt := range tests {
+		var body map[string]any
+		srv := nativeServer(t, tt.path, tt.events, &body)
+		stream, err := OpenStream(context.Background(), Provider{BaseURL: srv.URL + "/v1", API: tt.api}, toolRequest())
+		if err != nil {
+			t.Fatalf("%s: %v", tt.api, err)
+		}
+		reason := ""
+		for {
+			delta, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: Recv: %v", tt.api, err)
+			}
+			if delta.FinishReason != "" {
+				reason = delta.FinishReason
+			}
+		}
+		stream.Close()
+		if reason != tt.want {
+			t.Errorf("%s: finish reason %q, want %q", tt.api, reason, tt.want)
+		}
+	}
+}
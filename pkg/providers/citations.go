@@ -0,0 +1,50 @@
+package providers
+
+import (
+	"errors"
+	"io"
+	"regexp"
+)
+
+// maxPendingCitation bounds how much trailing text is held back because it may
+// be the start of a citation marker split across deltas.
+const maxPendingCitation = 256
+
+// citationPattern matches inline citation markers: Perplexity's [1] or [1, 2],
+// and xAI's markdown links [[1]](https://...).
+var citationPattern = regexp.MustCompile(`\[\[\d+\]\]\([^)\s]*\)|\[\d+(?:\s*[,-]\s*\d+)*\]`)
+
+// partialCitation matches text at the end of a delta that could still grow into
+// a citation marker.
+var partialCitation = regexp.MustCompile(`\[[\[\]\d,\- ]*(?:\]\([^)\s]*)?$`)
+
+// citationFilter removes citation markers from content, so search-grounded
+// models are not credited with tokens they did not generate and TTFT starts at
+// the first real token.
+type citationFilter struct {
+	DeltaStream
+	pending string
+}
+
+func (f *citationFilter) Recv() (Delta, error) {
+	delta, err := f.DeltaStream.Recv()
+	if err != nil {
+		if errors.Is(err, io.EOF) && f.pending != "" {
+			content := citationPattern.ReplaceAllString(f.pending, "")
+			f.pending = ""
+			return Delta{Content: content}, nil
+		}
+		return delta, err
+	}
+	if delta.Content == "" && f.pending == "" {
+		return delta, nil
+	}
+	text := f.pending + delta.Content
+	f.pending = ""
+	if loc := partialCitation.FindStringIndex(text); loc != nil && len(text)-loc[0] <= maxPendingCitation {
+		f.pending = text[loc[0]:]
+		text = text[:loc[0]]
+	}
+	delta.Content = citationPattern.ReplaceAllString(text, "")
+	return delta, nil
+}
@@ -0,0 +1,62 @@
+package providers
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// sliceStream replays fixed deltas.
+type sliceStream struct{ deltas []Delta }
+
+func (s *sliceStream) Recv() (Delta, error) {
+	if len(s.deltas) == 0 {
+		return Delta{}, io.EOF
+	}
+	d := s.deltas[0]
+	s.deltas = s.deltas[1:]
+	return d, nil
+}
+
+func (s *sliceStream) RateLimit() openai.RateLimitHeaders { return openai.RateLimitHeaders{} }
+
+func (s *sliceStream) Close() error { return nil }
+
+func TestCitationFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		want   string
+	}{
+		{"perplexity markers", []string{"Oslo is cold[1]", "[2]. It ", "snows[3, 4]."}, "Oslo is cold. It snows."},
+		{"marker split across chunks", []string{"Rain[", "1", "] today"}, "Rain today"},
+		{"xai markdown links", []string{"Sunny [[1]](https://x.ai/", "news) now"}, "Sunny  now"},
+		{"plain brackets kept", []string{"arr[i] and [note]"}, "arr[i] and [note]"},
+		{"trailing bracket flushed", []string{"ends with ["}, "ends with ["},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deltas []Delta
+			for _, c := range tt.chunks {
+				deltas = append(deltas, Delta{Content: c})
+			}
+			filter := &citationFilter{DeltaStream: &sliceStream{deltas: deltas}}
+			got := ""
+			for {
+				d, err := filter.Recv()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got += d.Content
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+// Package providers streams chat completions from endpoints that speak the
+// OpenAI API, or the native APIs of Mistral, Cohere, Anthropic and Google
+// Gemini, normalizing every wire format to the same Delta stream. The
+// benchmark package times these streams.
+package providers
+
+import "net/http"
+
+// Provider is one chat completion endpoint and model.
+type Provider struct {
+	Name    string
+	BaseURL string
+	APIKey  string
+	Model   string
+	// API is the provider's wire protocol, one of APIs; empty means APIOpenAI.
+	API string
+	// StripCitations removes inline citation markers such as [1] or
+	// [[1]](https://...) from content before it is timed and counted, for
+	// search-grounded models like Perplexity Sonar and xAI Grok.
+	StripCitations bool
+	// HTTPClient sends the requests when set, e.g. to add headers or a proxy.
+	HTTPClient *http.Client
+	// NoStreamUsage leaves stream_options.include_usage out of OpenAI API
+	// requests, for servers that reject it. Tokens are then always counted
+	// locally.
+	NoStreamUsage bool
+}
@@ -0,0 +1,88 @@
+// Package report renders benchmark results as markdown in the layout of the
+// llm-api-speed CLI's REPORT.md. The CLI builds its tables from the same
+// helpers, so embedders and the CLI format cells identically.
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/lamim/llm-api-speed/pkg/benchmark"
+)
+
+// NotAvailable fills the cells of metrics that were not measured.
+const NotAvailable = "N/A"
+
+// Cell makes text safe for a markdown table cell: runs of whitespace,
+// newlines included, become one space and pipes are escaped.
+func Cell(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	return strings.ReplaceAll(text, "|", "\\|")
+}
+
+// Seconds formats a latency the way every report table does, e.g. "1.234s".
+func Seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3fs", d.Seconds())
+}
+
+// Throughput formats a token rate, e.g. "42.50 tok/s".
+func Throughput(tokensPerSecond float64) string {
+	return fmt.Sprintf("%.2f tok/s", tokensPerSecond)
+}
+
+// WriteHeader writes a table's header row and the separator below it.
+func WriteHeader(w io.Writer, columns ...string) {
+	var header, separator strings.Builder
+	header.WriteString("|")
+	separator.WriteString("|")
+	for _, c := range columns {
+		header.WriteString(" " + c + " |")
+		separator.WriteString(strings.Repeat("-", len(c)+2) + "|")
+	}
+	fmt.Fprintf(w, "%s\n%s\n", header.String(), separator.String())
+}
+
+// WriteRow writes one table row of already formatted cells.
+func WriteRow(w io.Writer, cells ...string) {
+	fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+}
+
+// WriteResults writes results as a Successful Tests table of the providers
+// with at least one successful request, then a Failed Tests table of those
+// where every request failed.
+func WriteResults(w io.Writer, results []benchmark.Result) error {
+	var report strings.Builder
+	var succeeded, failed []benchmark.Result
+	for _, r := range results {
+		if r.Successful == 0 {
+			failed = append(failed, r)
+		} else {
+			succeeded = append(succeeded, r)
+		}
+	}
+	if len(succeeded) > 0 {
+		report.WriteString("## Successful Tests\n\n")
+		WriteHeader(&report, "Provider", "Model", "Runs", "E2E Latency", "TTFT", "Throughput", "Tokens")
+		for _, r := range succeeded {
+			WriteRow(&report, r.Provider, r.Model, fmt.Sprintf("%d/%d", r.Successful, r.Runs),
+				Seconds(r.E2E), Seconds(r.TTFT), Throughput(r.Throughput), fmt.Sprintf("%d", r.Tokens))
+		}
+		report.WriteString("\n")
+	}
+	if len(failed) > 0 {
+		report.WriteString("## Failed Tests\n\n")
+		WriteHeader(&report, "Provider", "Model", "Error")
+		for _, r := range failed {
+			msg := "no requests sent"
+			if r.Err != nil {
+				msg = Cell(r.Err.Error())
+			}
+			WriteRow(&report, r.Provider, r.Model, msg)
+		}
+		report.WriteString("\n")
+	}
+	_, err := io.WriteString(w, report.String())
+	return err
+}
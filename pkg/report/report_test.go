@@ -0,0 +1,41 @@
+package report
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lamim/llm-api-speed/pkg/benchmark"
+)
+
+func TestWriteResults(t *testing.T) {
+	var out strings.Builder
+	err := WriteResults(&out, []benchmark.Result{
+		{Provider: "down", Model: "m", Runs: 3, Err: errors.New("status 503 | unavailable")},
+		{Provider: "nim", Model: "m", Runs: 3, Successful: 2, TTFT: 250 * time.Millisecond, E2E: 2 * time.Second, Throughput: 80, Tokens: 150},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"| Provider | Model | Runs | E2E Latency | TTFT | Throughput | Tokens |\n|----------|-------|------|-------------|------|------------|--------|\n",
+		"| nim | m | 2/3 | 2.000s | 0.250s | 80.00 tok/s | 150 |",
+		"## Failed Tests",
+		"| down | m | status 503 \\| unavailable |",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("report lacks %q:\n%s", want, got)
+		}
+	}
+	if strings.Index(got, "## Successful Tests") > strings.Index(got, "## Failed Tests") {
+		t.Errorf("expected successful providers first:\n%s", got)
+	}
+}
+
+func TestCell(t *testing.T) {
+	if got := Cell("line one\n  line | two"); got != "line one line \\| two" {
+		t.Errorf("Cell = %q", got)
+	}
+}
@@ -0,0 +1,451 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Section writes one part of a report, or nothing when it has nothing to show.
+type Section func(report *strings.Builder)
+
+// Entry is one provider's line in a session or diagnostic report.
+type Entry struct {
+	// Label names the provider, e.g. "nim [prod]".
+	Label string
+	Model string
+	Mode  string
+	// Success and Error are the outcome of a benchmark run.
+	Success bool
+	Error   string
+	// Requests, Successful, Failed and Errors count the requests of a
+	// diagnostic run; Errors maps each error message to its count.
+	Requests   int
+	Successful int
+	Failed     int
+	Errors     map[string]int
+	// The metrics average the successful requests.
+	E2E          time.Duration
+	TTFT         time.Duration
+	Throughput   float64
+	Tokens       int
+	ProjectedE2E time.Duration
+	// Cells are the entry's values of Document.Columns.
+	Cells []string
+}
+
+// Document is the frame of a report around its entries.
+type Document struct {
+	Title   string
+	Session string
+	// Notes follow the session line, before the summary.
+	Notes []Section
+	// TargetTokens, when positive, adds projected E2E latency for that many
+	// tokens to the results table and the leaderboards.
+	TargetTokens int
+	// Grade decorates a metric's cell, e.g. with a threshold marker; nil
+	// leaves the cells as they are.
+	Grade func(metric string, value float64, cell string) string
+	// Columns are extra columns of the results table, filled from each
+	// entry's Cells.
+	Columns []string
+	// Legend follows the results table.
+	Legend Section
+	// Sections follow the leaderboards.
+	Sections []Section
+	// Generated is the time in the footer; zero means now.
+	Generated time.Time
+}
+
+// grade decorates a metric's cell with doc.Grade.
+func (doc Document) grade(metric string, value float64, cell string) string {
+	if doc.Grade == nil {
+		return cell
+	}
+	return doc.Grade(metric, value, cell)
+}
+
+// writeFrontMatter writes the title, session line and notes.
+func (doc Document) writeFrontMatter(report *strings.Builder) {
+	fmt.Fprintf(report, "# %s\n\n", doc.Title)
+	fmt.Fprintf(report, "**Test Session:** %s\n\n", doc.Session)
+	for _, note := range doc.Notes {
+		note(report)
+	}
+	report.WriteString("---\n\n")
+}
+
+// writeFooter writes the closing rule and the generation time.
+func (doc Document) writeFooter(report *strings.Builder) {
+	report.WriteString("---\n\n")
+	generated := doc.Generated
+	if generated.IsZero() {
+		generated = time.Now()
+	}
+	fmt.Fprintf(report, "*Report generated at %s*\n", generated.Format("2006-01-02 15:04:05"))
+}
+
+// writeProjectedNote explains the projected E2E column.
+func (doc Document) writeProjectedNote(report *strings.Builder) {
+	fmt.Fprintf(report, "**Note:** Projected E2E calculated for %d tokens using formula: TTFT + (Target Tokens / Throughput)\n\n", doc.TargetTokens)
+}
+
+// WriteSession writes the report of a benchmark session: a summary, tables
+// of the successful and failed entries, leaderboards of the successful ones
+// and then doc's sections.
+func WriteSession(w io.Writer, doc Document, entries []Entry) error {
+	var report strings.Builder
+	doc.writeFrontMatter(&report)
+
+	successful := 0
+	failed := 0
+	for _, e := range entries {
+		if e.Success {
+			successful++
+		} else {
+			failed++
+		}
+	}
+
+	report.WriteString("## Summary\n\n")
+	fmt.Fprintf(&report, "- **Total Providers Tested:** %d\n", len(entries))
+	fmt.Fprintf(&report, "- **Successful:** %d\n", successful)
+	fmt.Fprintf(&report, "- **Failed:** %d\n\n", failed)
+
+	if successful > 0 {
+		report.WriteString("## Successful Tests\n\n")
+		columns := []string{"Provider", "Model", "Mode", "E2E Latency", "TTFT", "Throughput", "Tokens"}
+		if doc.TargetTokens > 0 {
+			doc.writeProjectedNote(&report)
+			columns = append(columns, "Projected E2E")
+		}
+		WriteHeader(&report, append(columns, doc.Columns...)...)
+		for _, e := range entries {
+			if e.Success {
+				doc.writeSessionRow(&report, e)
+			}
+		}
+		report.WriteString("\n")
+		if doc.Legend != nil {
+			doc.Legend(&report)
+		}
+	}
+
+	if failed > 0 {
+		report.WriteString("## Failed Tests\n\n")
+		WriteHeader(&report, "Provider", "Model", "Mode", "Error")
+		for _, e := range entries {
+			if !e.Success {
+				WriteRow(&report, e.Label, e.Model, e.Mode, e.Error)
+			}
+		}
+		report.WriteString("\n")
+	}
+
+	if successful > 0 {
+		doc.writeSessionLeaderboards(&report, entries)
+	}
+	for _, section := range doc.Sections {
+		section(&report)
+	}
+	doc.writeFooter(&report)
+
+	_, err := io.WriteString(w, report.String())
+	return err
+}
+
+// writeSessionRow writes a successful entry's row of the results table.
+func (doc Document) writeSessionRow(report *strings.Builder, e Entry) {
+	cells := []string{
+		e.Label, e.Model, e.Mode,
+		doc.grade("e2e", e.E2E.Seconds(), Seconds(e.E2E)),
+		doc.grade("ttft", e.TTFT.Seconds(), Seconds(e.TTFT)),
+		doc.grade("throughput", e.Throughput, Throughput(e.Throughput)),
+		doc.grade("tokens", float64(e.Tokens), fmt.Sprintf("%d", e.Tokens)),
+	}
+	if doc.TargetTokens > 0 && e.ProjectedE2E > 0 {
+		cells = append(cells, doc.grade("projected_e2e", e.ProjectedE2E.Seconds(), Seconds(e.ProjectedE2E)))
+	}
+	WriteRow(report, append(cells, e.Cells...)...)
+}
+
+// writeSessionLeaderboards ranks the successful entries by throughput, TTFT,
+// E2E latency and, with a target, projected E2E latency.
+func (doc Document) writeSessionLeaderboards(report *strings.Builder, entries []Entry) {
+	report.WriteString("## Performance Leaderboard\n\n")
+	report.WriteString("### By Throughput (Tokens/sec)\n\n")
+
+	// Sort by throughput
+	successfulEntries := make([]Entry, 0)
+	for _, e := range entries {
+		if e.Success {
+			successfulEntries = append(successfulEntries, e)
+		}
+	}
+
+	// Simple bubble sort by throughput descending
+	for i := 0; i < len(successfulEntries); i++ {
+		for j := i + 1; j < len(successfulEntries); j++ {
+			if successfulEntries[j].Throughput > successfulEntries[i].Throughput {
+				successfulEntries[i], successfulEntries[j] = successfulEntries[j], successfulEntries[i]
+			}
+		}
+	}
+
+	report.WriteString("| Rank | Provider | Throughput | TTFT | E2E Latency |\n")
+	report.WriteString("|------|----------|------------|------|-------------|\n")
+
+	for i, e := range successfulEntries {
+		fmt.Fprintf(report, "| %d | %s | %.2f tok/s | %s | %s |\n",
+			i+1, e.Label, e.Throughput, Seconds(e.TTFT), Seconds(e.E2E))
+	}
+	report.WriteString("\n")
+
+	// Sort by TTFT
+	report.WriteString("### By Time to First Token (TTFT)\n\n")
+
+	for i := 0; i < len(successfulEntries); i++ {
+		for j := i + 1; j < len(successfulEntries); j++ {
+			if successfulEntries[j].TTFT < successfulEntries[i].TTFT {
+				successfulEntries[i], successfulEntries[j] = successfulEntries[j], successfulEntries[i]
+			}
+		}
+	}
+
+	report.WriteString("| Rank | Provider | TTFT | Throughput | E2E Latency |\n")
+	report.WriteString("|------|----------|------|------------|-------------|\n")
+
+	for i, e := range successfulEntries {
+		fmt.Fprintf(report, "| %d | %s | %s | %.2f tok/s | %s |\n",
+			i+1, e.Label, Seconds(e.TTFT), e.Throughput, Seconds(e.E2E))
+	}
+	report.WriteString("\n")
+
+	// Sort by E2E Latency
+	report.WriteString("### By End-to-End Latency\n\n")
+
+	for i := 0; i < len(successfulEntries); i++ {
+		for j := i + 1; j < len(successfulEntries); j++ {
+			if successfulEntries[j].E2E < successfulEntries[i].E2E {
+				successfulEntries[i], successfulEntries[j] = successfulEntries[j], successfulEntries[i]
+			}
+		}
+	}
+
+	report.WriteString("| Rank | Provider | E2E Latency | TTFT | Throughput |\n")
+	report.WriteString("|------|----------|-------------|------|------------|\n")
+
+	for i, e := range successfulEntries {
+		fmt.Fprintf(report, "| %d | %s | %s | %s | %.2f tok/s |\n",
+			i+1, e.Label, Seconds(e.E2E), Seconds(e.TTFT), e.Throughput)
+	}
+	report.WriteString("\n")
+
+	// Sort by Projected E2E (if available)
+	if doc.TargetTokens > 0 {
+		fmt.Fprintf(report, "### By Projected E2E Latency (%d tokens)\n\n", doc.TargetTokens)
+		writeProjectedLeaderboard(report, successfulEntries, false)
+	}
+}
+
+// writeProjectedLeaderboard ranks entries by projected E2E latency, with
+// their success rate when diagnostic is set.
+func writeProjectedLeaderboard(report *strings.Builder, entries []Entry, diagnostic bool) {
+	// Sort by Projected E2E
+	for i := 0; i < len(entries); i++ {
+		for j := i + 1; j < len(entries); j++ {
+			if entries[j].ProjectedE2E > 0 && entries[i].ProjectedE2E > 0 &&
+				entries[j].ProjectedE2E < entries[i].ProjectedE2E {
+				entries[i], entries[j] = entries[j], entries[i]
+			}
+		}
+	}
+
+	if diagnostic {
+		report.WriteString("| Rank | Provider | Projected E2E | TTFT | Throughput | Success Rate |\n")
+		report.WriteString("|------|----------|---------------|------|------------|-------------|\n")
+	} else {
+		report.WriteString("| Rank | Provider | Projected E2E | TTFT | Throughput |\n")
+		report.WriteString("|------|----------|---------------|------|------------|\n")
+	}
+
+	for i, e := range entries {
+		if e.ProjectedE2E <= 0 {
+			continue
+		}
+		fmt.Fprintf(report, "| %d | %s | %s | %s | %.2f tok/s |", i+1, e.Label, Seconds(e.ProjectedE2E), Seconds(e.TTFT), e.Throughput)
+		if diagnostic {
+			fmt.Fprintf(report, " %s |", successRate(e))
+		}
+		report.WriteString("\n")
+	}
+	report.WriteString("\n")
+}
+
+// successRate formats the share of a diagnostic entry's requests that
+// succeeded, e.g. "95.0%".
+func successRate(e Entry) string {
+	return fmt.Sprintf("%.1f%%", 100.0*float64(e.Successful)/float64(e.Requests))
+}
+
+// WriteDiagnostic writes the report of a diagnostic session: a summary of
+// the requests, a table of every entry, leaderboards of those with a
+// successful request, doc's sections and then the errors each entry hit.
+func WriteDiagnostic(w io.Writer, doc Document, entries []Entry) error {
+	var report strings.Builder
+	doc.writeFrontMatter(&report)
+
+	var totalRequests, totalSuccessful, totalFailed int
+	for _, e := range entries {
+		totalRequests += e.Requests
+		totalSuccessful += e.Successful
+		totalFailed += e.Failed
+	}
+
+	report.WriteString("## Summary\n\n")
+	fmt.Fprintf(&report, "- **Providers Tested:** %d\n", len(entries))
+	fmt.Fprintf(&report, "- **Total Requests:** %d\n", totalRequests)
+	fmt.Fprintf(&report, "- **Successful:** %d (%.1f%%)\n",
+		totalSuccessful, 100.0*float64(totalSuccessful)/float64(totalRequests))
+	fmt.Fprintf(&report, "- **Failed:** %d (%.1f%%)\n\n",
+		totalFailed, 100.0*float64(totalFailed)/float64(totalRequests))
+
+	if len(entries) > 0 {
+		report.WriteString("## Detailed Results\n\n")
+		columns := []string{"Provider", "Model", "Mode", "Total Requests", "Success", "Failed", "Avg E2E", "Avg TTFT", "Avg Throughput"}
+		if doc.TargetTokens > 0 {
+			doc.writeProjectedNote(&report)
+			columns = append(columns, "Projected E2E")
+		}
+		WriteHeader(&report, append(columns, doc.Columns...)...)
+		for _, e := range entries {
+			doc.writeDiagnosticRow(&report, e)
+		}
+		report.WriteString("\n")
+		if doc.Legend != nil {
+			doc.Legend(&report)
+		}
+	}
+
+	successfulEntries := make([]Entry, 0)
+	for _, e := range entries {
+		if e.Successful > 0 {
+			successfulEntries = append(successfulEntries, e)
+		}
+	}
+	if len(successfulEntries) > 0 {
+		doc.writeDiagnosticLeaderboards(&report, successfulEntries)
+	}
+	for _, section := range doc.Sections {
+		section(&report)
+	}
+	writeErrorAnalysis(&report, entries)
+	doc.writeFooter(&report)
+
+	_, err := io.WriteString(w, report.String())
+	return err
+}
+
+// writeDiagnosticRow writes an entry's row of the diagnostic results table.
+func (doc Document) writeDiagnosticRow(report *strings.Builder, e Entry) {
+	avgE2E := NotAvailable
+	avgTTFT := NotAvailable
+	avgThroughput := NotAvailable
+	projectedE2E := NotAvailable
+
+	if e.Successful > 0 {
+		avgE2E = doc.grade("e2e", e.E2E.Seconds(), Seconds(e.E2E))
+		avgTTFT = doc.grade("ttft", e.TTFT.Seconds(), Seconds(e.TTFT))
+		avgThroughput = doc.grade("throughput", e.Throughput, Throughput(e.Throughput))
+		if e.ProjectedE2E > 0 {
+			projectedE2E = doc.grade("projected_e2e", e.ProjectedE2E.Seconds(), Seconds(e.ProjectedE2E))
+		}
+	}
+
+	cells := []string{e.Label, e.Model, e.Mode, fmt.Sprintf("%d", e.Requests),
+		fmt.Sprintf("%d/%d", e.Successful, e.Requests), fmt.Sprintf("%d", e.Failed), avgE2E, avgTTFT, avgThroughput}
+	if doc.TargetTokens > 0 {
+		cells = append(cells, projectedE2E)
+	}
+	WriteRow(report, append(cells, e.Cells...)...)
+}
+
+// writeDiagnosticLeaderboards ranks entries by throughput, TTFT and, with a
+// target, projected E2E latency, next to their success rate.
+func (doc Document) writeDiagnosticLeaderboards(report *strings.Builder, entries []Entry) {
+	report.WriteString("## Performance Leaderboard\n\n")
+	report.WriteString("### By Throughput (Tokens/sec)\n\n")
+
+	// Sort by throughput
+	for i := 0; i < len(entries); i++ {
+		for j := i + 1; j < len(entries); j++ {
+			if entries[j].Throughput > entries[i].Throughput {
+				entries[i], entries[j] = entries[j], entries[i]
+			}
+		}
+	}
+
+	report.WriteString("| Rank | Provider | Throughput | TTFT | E2E Latency | Success Rate |\n")
+	report.WriteString("|------|----------|------------|------|-------------|-------------|\n")
+
+	for i, e := range entries {
+		fmt.Fprintf(report, "| %d | %s | %.2f tok/s | %s | %s | %s |\n",
+			i+1, e.Label, e.Throughput, Seconds(e.TTFT), Seconds(e.E2E), successRate(e))
+	}
+	report.WriteString("\n")
+
+	// Sort by TTFT
+	report.WriteString("### By Time to First Token (TTFT)\n\n")
+
+	for i := 0; i < len(entries); i++ {
+		for j := i + 1; j < len(entries); j++ {
+			if entries[j].TTFT < entries[i].TTFT {
+				entries[i], entries[j] = entries[j], entries[i]
+			}
+		}
+	}
+
+	report.WriteString("| Rank | Provider | TTFT | Throughput | E2E Latency | Success Rate |\n")
+	report.WriteString("|------|----------|------|------------|-------------|-------------|\n")
+
+	for i, e := range entries {
+		fmt.Fprintf(report, "| %d | %s | %s | %.2f tok/s | %s | %s |\n",
+			i+1, e.Label, Seconds(e.TTFT), e.Throughput, Seconds(e.E2E), successRate(e))
+	}
+	report.WriteString("\n")
+
+	// Sort by Projected E2E (if available)
+	if doc.TargetTokens > 0 {
+		fmt.Fprintf(report, "### By Projected E2E Latency (%d tokens)\n\n", doc.TargetTokens)
+		writeProjectedLeaderboard(report, entries, true)
+	}
+}
+
+// writeErrorAnalysis lists the errors of every entry that hit any.
+func writeErrorAnalysis(report *strings.Builder, entries []Entry) {
+	hasErrors := false
+	for _, e := range entries {
+		if len(e.Errors) > 0 {
+			hasErrors = true
+			break
+		}
+	}
+	if !hasErrors {
+		return
+	}
+
+	report.WriteString("## Error Analysis\n\n")
+	for _, e := range entries {
+		if len(e.Errors) == 0 {
+			continue
+		}
+		fmt.Fprintf(report, "### %s Errors\n\n", e.Label)
+		report.WriteString("| Error | Count |\n")
+		report.WriteString("|-------|-------|\n")
+		for msg, count := range e.Errors {
+			fmt.Fprintf(report, "| %s | %d |\n", msg, count)
+		}
+		report.WriteString("\n")
+	}
+}
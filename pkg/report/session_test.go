@@ -0,0 +1,74 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteSession(t *testing.T) {
+	var out strings.Builder
+	doc := Document{
+		Title:        "Results",
+		Session:      "s1",
+		Notes:        []Section{func(r *strings.Builder) { r.WriteString("note\n\n") }},
+		TargetTokens: 1000,
+		Grade:        func(metric string, _ float64, cell string) string { return metric + ":" + cell },
+		Columns:      []string{"Region"},
+		Sections:     []Section{func(r *strings.Builder) { r.WriteString("## Extra\n\n") }},
+		Generated:    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	err := WriteSession(&out, doc, []Entry{
+		{Label: "slow", Model: "m", Mode: "streaming", Success: true, E2E: 4 * time.Second, TTFT: time.Second, Throughput: 20, Tokens: 60, ProjectedE2E: 51 * time.Second, Cells: []string{"eu"}},
+		{Label: "fast", Model: "m", Mode: "streaming", Success: true, E2E: 2 * time.Second, TTFT: 500 * time.Millisecond, Throughput: 80, Tokens: 120, ProjectedE2E: 13 * time.Second, Cells: []string{"us"}},
+		{Label: "down", Model: "m", Mode: "streaming", Error: "status 503"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"# Results\n\n**Test Session:** s1\n\nnote\n\n---\n\n",
+		"- **Total Providers Tested:** 3\n- **Successful:** 2\n- **Failed:** 1\n",
+		"| Provider | Model | Mode | E2E Latency | TTFT | Throughput | Tokens | Projected E2E | Region |",
+		"| fast | m | streaming | e2e:2.000s | ttft:0.500s | throughput:80.00 tok/s | tokens:120 | projected_e2e:13.000s | us |",
+		"| down | m | streaming | status 503 |",
+		"### By Throughput (Tokens/sec)\n\n| Rank | Provider | Throughput | TTFT | E2E Latency |\n|------|----------|------------|------|-------------|\n| 1 | fast |",
+		"### By Projected E2E Latency (1000 tokens)\n\n| Rank | Provider | Projected E2E | TTFT | Throughput |\n|------|----------|---------------|------|------------|\n| 1 | fast | 13.000s |",
+		"## Extra\n\n---\n\n*Report generated at 2026-01-02 03:04:05*\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("report lacks %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "| down | m | streaming | e2e:") {
+		t.Errorf("failed entries should stay out of the results table:\n%s", got)
+	}
+}
+
+func TestWriteDiagnostic(t *testing.T) {
+	var out strings.Builder
+	err := WriteDiagnostic(&out, Document{Title: "Diagnostic", Session: "s1"}, []Entry{
+		{Label: "nim", Model: "m", Mode: "mixed", Requests: 4, Successful: 3, Failed: 1, Errors: map[string]int{"timeout": 1},
+			E2E: 2 * time.Second, TTFT: 250 * time.Millisecond, Throughput: 80},
+		{Label: "down", Model: "m", Mode: "mixed", Requests: 4, Failed: 4, Errors: map[string]int{"status 503": 4}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"- **Providers Tested:** 2\n- **Total Requests:** 8\n- **Successful:** 3 (37.5%)\n- **Failed:** 5 (62.5%)\n",
+		"| nim | m | mixed | 4 | 3/4 | 1 | 2.000s | 0.250s | 80.00 tok/s |",
+		"| down | m | mixed | 4 | 0/4 | 4 | N/A | N/A | N/A |",
+		"| 1 | nim | 80.00 tok/s | 0.250s | 2.000s | 75.0% |",
+		"## Error Analysis\n\n### nim Errors\n\n| Error | Count |\n|-------|-------|\n| timeout | 1 |\n\n### down Errors\n\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("report lacks %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "| 1 | down |") || strings.Contains(got, "Projected E2E") {
+		t.Errorf("unexpected leaderboard entry or projection:\n%s", got)
+	}
+}
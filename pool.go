@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Pool sizes; set with --iterations, --sequential, --diagnostic-workers,
+// --diagnostic-autoscale, --diagnostic-max-workers and --max-parallel-providers.
+var (
+	// runIterations is the number of runs per mode in a standard benchmark.
+	runIterations = 3
+	// sequentialIterations runs them one at a time instead of all at once, so
+	// runs on a rate-limited key do not compete for its throughput.
+	sequentialIterations = false
+	// diagnosticWorkers is the number of concurrent workers per provider in diagnostic mode.
+	diagnosticWorkers = 10
+	// diagnosticAutoscale scales diagnostic workers between 1 and
+	// diagnosticMaxWorkers, starting from diagnosticWorkers.
+	diagnosticAutoscale  = false
+	diagnosticMaxWorkers = 100
+	// maxParallelProviders caps how many providers are benchmarked at once
+	// with --all and in diagnostic mode; 0 means no cap.
+	maxParallelProviders = 0
+)
+
+// maxPoolSize caps --iterations, --diagnostic-workers and
+// --diagnostic-max-workers.
+const maxPoolSize = 500
+
+// validatePoolSize checks a worker-count flag value.
+func validatePoolSize(flagName string, n int) error {
+	if n < 1 || n > maxPoolSize {
+		return fmt.Errorf("--%s must be between 1 and %d, got %d", flagName, maxPoolSize, n)
+	}
+	return nil
+}
+
+// iterationLimit is the concurrency of the n runs of a standard benchmark:
+// one at a time with sequentialIterations, otherwise all at once.
+func iterationLimit(n int) int {
+	if sequentialIterations {
+		return 1
+	}
+	return n
+}
+
+// providerLimit is the runPool limit for benchmarking providers: one at a time
+// unless concurrent is set, then at most maxParallelProviders.
+func providerLimit(concurrent bool) int {
+	if !concurrent {
+		return 1
+	}
+	return maxParallelProviders
+}
+
+// runPool calls fn for every index in [0, n) with at most limit calls in
+// flight, or all at once when limit <= 0. The first error cancels the context
+// passed to the calls still running and stops new ones from starting; it is
+// returned once every started call has finished. Calls that record a failure
+// as a result rather than returning it leave the others running.
+func runPool(ctx context.Context, limit, n int, fn func(ctx context.Context, i int) error) error {
+	g, ctx := errgroup.WithContext(ctx)
+	if limit > 0 {
+		g.SetLimit(limit)
+	}
+	for i := range n {
+		if ctx.Err() != nil {
+			break
+		}
+		g.Go(func() error { return fn(ctx, i) })
+	}
+	return g.Wait()
+}
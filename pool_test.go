@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunPoolRunsEveryIndexWithinLimit(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	seen := make([]bool, 20)
+	err := runPool(context.Background(), 3, len(seen), func(_ context.Context, i int) error {
+		n := inFlight.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		seen[i] = true
+		inFlight.Add(-1)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("runPool: %v", err)
+	}
+	for i, ok := range seen {
+		if !ok {
+			t.Errorf("index %d never ran", i)
+		}
+	}
+	if got := peak.Load(); got > 3 {
+		t.Errorf("peak concurrency = %d, want at most 3", got)
+	}
+}
+
+func TestRunPoolUnlimitedStartsAllAtOnce(t *testing.T) {
+	const n = 8
+	allStarted := make(chan struct{})
+	var count atomic.Int32
+	err := runPool(context.Background(), 0, n, func(_ context.Context, _ int) error {
+		if count.Add(1) == n {
+			close(allStarted)
+		}
+		select {
+		case <-allStarted:
+			return nil
+		case <-time.After(5 * time.Second):
+			return errors.New("not every call was in flight at once")
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunPoolErrorCancelsOthers(t *testing.T) {
+	boom := errors.New("boom")
+	var cancelled atomic.Int32
+	err := runPool(context.Background(), 0, 4, func(ctx context.Context, i int) error {
+		if i == 0 {
+			return boom
+		}
+		select {
+		case <-ctx.Done():
+			cancelled.Add(1)
+		case <-time.After(5 * time.Second):
+		}
+		return nil
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want %v", err, boom)
+	}
+	if got := cancelled.Load(); got != 3 {
+		t.Errorf("%d calls saw the cancellation, want 3", got)
+	}
+}
+
+func TestRunPoolStopsStartingAfterError(t *testing.T) {
+	var ran atomic.Int32
+	_ = runPool(context.Background(), 1, 10, func(_ context.Context, i int) error {
+		ran.Add(1)
+		if i == 1 {
+			return errors.New("stop")
+		}
+		return nil
+	})
+	if got := ran.Load(); got > 3 {
+		t.Errorf("%d calls ran after the error, want the pool to stop starting new ones", got)
+	}
+}
+
+func TestValidatePoolSize(t *testing.T) {
+	for _, n := range []int{1, 10, maxPoolSize} {
+		if err := validatePoolSize("iterations", n); err != nil {
+			t.Errorf("validatePoolSize(%d): %v", n, err)
+		}
+	}
+	for _, n := range []int{0, -1, maxPoolSize + 1} {
+		if err := validatePoolSize("iterations", n); err == nil {
+			t.Errorf("validatePoolSize(%d) accepted an invalid size", n)
+		}
+	}
+}
+
+func TestProviderLimit(t *testing.T) {
+	saved := maxParallelProviders
+	defer func() { maxParallelProviders = saved }()
+
+	maxParallelProviders = 0
+	if got := providerLimit(false); got != 1 {
+		t.Errorf("sequential limit = %d, want 1", got)
+	}
+	if got := providerLimit(true); got != 0 {
+		t.Errorf("uncapped concurrent limit = %d, want 0 (all at once)", got)
+	}
+	maxParallelProviders = 4
+	if got := providerLimit(true); got != 4 {
+		t.Errorf("capped concurrent limit = %d, want 4", got)
+	}
+	if got := providerLimit(false); got != 1 {
+		t.Errorf("sequential limit with a cap = %d, want 1", got)
+	}
+}
+
+func TestIterationLimit(t *testing.T) {
+	defer func() { sequentialIterations = false }()
+	if got := iterationLimit(6); got != 6 {
+		t.Errorf("concurrent limit = %d, want 6 (all at once)", got)
+	}
+	sequentialIterations = true
+	if got := iterationLimit(6); got != 1 {
+		t.Errorf("sequential limit = %d, want 1", got)
+	}
+}
+
+func TestLoadConfigFileTestParams(t *testing.T) {
+	cfg, err := loadConfigFile(writeConfig(t, "[test_params]\nconcurrent_iterations = false\n"))
+	if err != nil {
+		t.Fatalf("loadConfigFile failed: %v", err)
+	}
+	if !cfg.TestParams.sequential() {
+		t.Error("expected concurrent_iterations = false to run iterations sequentially")
+	}
+	cfg, err = loadConfigFile(writeConfig(t, "[test_params]\nconcurrent_iterations = true\n"))
+	if err != nil || cfg.TestParams.sequential() {
+		t.Errorf("expected concurrent iterations, got %+v, %v", cfg.TestParams, err)
+	}
+	if (fileConfig{}).TestParams.sequential() {
+		t.Error("expected concurrent iterations without a [test_params] table")
+	}
+}
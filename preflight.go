@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lamim/llm-api-speed/pkg/providers"
+	"github.com/pkoukk/tiktoken-go"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// preflightTimeout bounds the preflight request of one provider.
+const preflightTimeout = 30 * time.Second
+
+// preflightPrompt is the whole preflight request; one token of answer is enough.
+const preflightPrompt = "Reply with OK."
+
+// preflightResult is the outcome of one provider's preflight request.
+type preflightResult struct {
+	config  ProviderConfig
+	latency time.Duration
+	err     error
+	// fatal is set when the error will fail every run (bad key, unknown model)
+	// rather than possibly clear up (throttling, a server error, a timeout).
+	fatal bool
+}
+
+// preflightFatal reports whether err is a configuration error no retry fixes:
+// the endpoint refused the key, does not know the model, or rejected the
+// request outright.
+func preflightFatal(err error) bool {
+	status := 0
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	switch {
+	case errors.As(err, &apiErr):
+		status = apiErr.HTTPStatusCode
+	case errors.As(err, &reqErr):
+		status = reqErr.HTTPStatusCode
+	}
+	switch status {
+	case http.StatusBadRequest, http.StatusUnauthorized, http.StatusPaymentRequired, http.StatusForbidden,
+		http.StatusNotFound, http.StatusUnprocessableEntity:
+		return true
+	}
+	return false
+}
+
+// preflightProvider sends a one-token request and waits for the first delta or
+// the end of the stream, whichever comes first.
+func preflightProvider(config ProviderConfig, tke *tiktoken.Tiktoken) preflightResult {
+	result := preflightResult{config: config}
+	req := openai.ChatCompletionRequest{
+		Model:     config.Model,
+		Messages:  []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: preflightPrompt}},
+		MaxTokens: 1,
+		Stream:    true,
+	}
+	req = config.Quirks.apply(req)
+
+	ctx, cancel := context.WithTimeout(shutdownCtx, preflightTimeout)
+	defer cancel()
+	start := time.Now()
+	stream, err := providers.OpenStream(ctx, benchProvider(config), req)
+	if err == nil {
+		for {
+			delta, recvErr := stream.Recv()
+			if errors.Is(recvErr, io.EOF) {
+				break
+			}
+			if recvErr != nil {
+				err = recvErr
+				break
+			}
+			if !delta.Empty() {
+				break
+			}
+		}
+		_ = stream.Close()
+	}
+	result.latency = time.Since(start)
+	sessionBudget.record(config, countPromptTokens(tke, req.Messages), 1)
+	if err != nil {
+		result.err = err
+		result.fatal = preflightFatal(err)
+	}
+	return result
+}
+
+// runPreflight checks every provider concurrently and returns those that can
+// be benchmarked, with the ones that failed for good. Providers failing for
+// reasons that may pass (throttling, server errors) are kept with a warning.
+func runPreflight(providers []ProviderConfig, tke *tiktoken.Tiktoken) (passed []ProviderConfig, failed []preflightResult) {
+	log.Printf("=== PREFLIGHT: checking %d provider(s) ===", len(providers))
+	results := make([]preflightResult, len(providers))
+	var wg sync.WaitGroup
+	for i, p := range providers {
+		wg.Add(1)
+		go func(i int, p ProviderConfig) {
+			defer wg.Done()
+			results[i] = preflightProvider(p, tke)
+		}(i, p)
+	}
+	wg.Wait()
+
+	for _, r := range results {
+		label := providerLabel(r.config.Name, r.config.Env)
+		switch {
+		case r.err == nil:
+			log.Printf("[%s] Preflight OK (%s, model %s)", label, formatDuration(r.latency), r.config.Model)
+			passed = append(passed, r.config)
+		case r.fatal:
+			log.Printf("[%s] Preflight FAILED for model %s: %v", label, r.config.Model, r.err)
+			failed = append(failed, r)
+		default:
+			log.Printf("[%s] Preflight warning, testing anyway: %v", label, r.err)
+			passed = append(passed, r.config)
+		}
+	}
+	return passed, failed
+}
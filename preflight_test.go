@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// statusServer answers every request with status.
+func statusServer(t *testing.T, status int) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, `{"error":{"message":"nope"}}`, status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRunPreflight(t *testing.T) {
+	ok := mockSSEServer{chunks: []string{"OK"}}.start(t)
+	t.Cleanup(ok.Close)
+	providers := []ProviderConfig{
+		{Name: "ok", BaseURL: ok.URL, APIKey: "k", Model: "m"},
+		{Name: "badkey", BaseURL: statusServer(t, http.StatusUnauthorized).URL, APIKey: "k", Model: "m"},
+		{Name: "typo", BaseURL: statusServer(t, http.StatusNotFound).URL, APIKey: "k", Model: "mdoel"},
+		{Name: "busy", BaseURL: statusServer(t, http.StatusServiceUnavailable).URL, APIKey: "k", Model: "m"},
+	}
+
+	passed, failed := runPreflight(providers, testTokenizer(t))
+	if len(passed) != 2 || passed[0].Name != "ok" || passed[1].Name != "busy" {
+		t.Fatalf("expected ok and the transiently failing busy to pass, got %+v", passed)
+	}
+	if len(failed) != 2 || failed[0].config.Name != "badkey" || failed[1].config.Name != "typo" {
+		t.Fatalf("expected badkey and typo to fail, got %+v", failed)
+	}
+}
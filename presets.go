@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/lamim/llm-api-speed/pkg/providers"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// providerQuirks adapts requests to providers that deviate from the OpenAI API.
+type providerQuirks struct {
+	// MaxCompletionTokens sends the output limit as max_completion_tokens, for
+	// APIs that have deprecated max_tokens.
+	MaxCompletionTokens bool
+	// StripCitations drops inline citation markers from search-grounded output
+	// before it is timed and counted.
+	StripCitations bool
+}
+
+// apply returns req adjusted for the provider's quirks.
+func (q providerQuirks) apply(req openai.ChatCompletionRequest) openai.ChatCompletionRequest {
+	if q.MaxCompletionTokens && req.MaxTokens > 0 {
+		req.MaxCompletionTokens = req.MaxTokens
+		req.MaxTokens = 0
+	}
+	return req
+}
+
+// providerPreset is a built-in provider that works with just <PREFIX>_API_KEY
+// set; <PREFIX>_MODEL overrides its default model and <PREFIX>_BASE_URL its
+// base URL.
+type providerPreset struct {
+	name    string
+	label   string
+	baseURL string
+	model   string
+	quirks  providerQuirks
+	// api selects a native wire protocol instead of the OpenAI-compatible one.
+	api string
+	// proxy names the gateway the preset is, e.g. proxyLiteLLM.
+	proxy string
+}
+
+// providerPresets are the inference providers known out of the box. The
+// speed-focused ones default to the same open-weight model so they compare
+// directly.
+var providerPresets = []providerPreset{
+	{name: "groq", label: "Groq", baseURL: "https://api.groq.com/openai/v1", model: "openai/gpt-oss-120b",
+		quirks: providerQuirks{MaxCompletionTokens: true}},
+	{name: "cerebras", label: "Cerebras", baseURL: "https://api.cerebras.ai/v1", model: "gpt-oss-120b",
+		quirks: providerQuirks{MaxCompletionTokens: true}},
+	{name: "sambanova", label: "SambaNova", baseURL: "https://api.sambanova.ai/v1", model: "gpt-oss-120b"},
+	{name: "fireworks", label: "Fireworks AI", baseURL: "https://api.fireworks.ai/inference/v1",
+		model: "accounts/fireworks/models/gpt-oss-120b"},
+	{name: "together", label: "Together AI", baseURL: "https://api.together.xyz/v1", model: "openai/gpt-oss-120b"},
+	{name: "deepinfra", label: "DeepInfra", baseURL: "https://api.deepinfra.com/v1/openai", model: "openai/gpt-oss-120b"},
+	// Search-grounded models whose output carries citation markers
+	{name: "xai", label: "xAI", baseURL: "https://api.x.ai/v1", model: "grok-4-fast-non-reasoning",
+		quirks: providerQuirks{StripCitations: true}},
+	{name: "perplexity", label: "Perplexity", baseURL: "https://api.perplexity.ai", model: "sonar",
+		quirks: providerQuirks{StripCitations: true}},
+	// Native APIs, streamed without their OpenAI-compatible shims
+	{name: "mistral", label: "Mistral AI", baseURL: "https://api.mistral.ai/v1", model: "mistral-small-latest",
+		api: providers.APIMistral},
+	{name: "cohere", label: "Cohere", baseURL: "https://api.cohere.com/v2", model: "command-a-03-2025",
+		api: providers.APICohere},
+	{name: "anthropic", label: "Anthropic", baseURL: "https://api.anthropic.com/v1", model: "claude-sonnet-4-5",
+		api: providers.APIAnthropic},
+	{name: "gemini", label: "Google Gemini", baseURL: "https://generativelanguage.googleapis.com/v1beta",
+		model: "gemini-2.5-flash", api: providers.APIGemini},
+	// Self-hosted gateway in front of other providers; the key is a virtual key
+	// and the model a model alias from the proxy config
+	{name: "litellm", label: "LiteLLM proxy", baseURL: "http://localhost:4000/v1", model: "gpt-oss-120b",
+		proxy: proxyLiteLLM},
+}
+
+// config builds the provider config of a preset from the environment.
+func (p providerPreset) config() ProviderConfig {
+	prefix := strings.ToUpper(p.name)
+	model := os.Getenv(prefix + "_MODEL")
+	if model == "" {
+		model = p.model
+	}
+	baseURL := os.Getenv(prefix + "_BASE_URL")
+	if baseURL == "" {
+		baseURL = p.baseURL
+	}
+	return ProviderConfig{
+		Name:    p.name,
+		BaseURL: baseURL,
+		APIKey:  os.Getenv(prefix + "_API_KEY"),
+		Model:   model,
+		Env:     os.Getenv(prefix + "_ENV"),
+		Quirks:  p.quirks,
+		API:     p.api,
+		Proxy:   p.proxy,
+
+		InputPrice:  envFloat(prefix + "_INPUT_PRICE"),
+		OutputPrice: envFloat(prefix + "_OUTPUT_PRICE"),
+	}
+}
+
+// validateProviderType checks a provider type from <PREFIX>_TYPE or the type
+// key of a [provider.<name>] table: one of the wire protocols in providers.APIs.
+func validateProviderType(api string) error {
+	if slices.Contains(providers.APIs, api) {
+		return nil
+	}
+	return fmt.Errorf("unknown provider type %q (use %s)", api, strings.Join(providers.APIs, ", "))
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lamim/llm-api-speed/pkg/providers"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestPresetConfig(t *testing.T) {
+	t.Setenv("GROQ_API_KEY", "gsk-test")
+	t.Setenv("GROQ_MODEL", "")
+	t.Setenv("CEREBRAS_MODEL", "llama-3.3-70b")
+	t.Setenv("LITELLM_BASE_URL", "http://proxy.internal:4000")
+	t.Setenv("ANTHROPIC_BASE_URL", "")
+	t.Setenv("GEMINI_BASE_URL", "")
+
+	configs := make(map[string]ProviderConfig)
+	for _, preset := range providerPresets {
+		configs[preset.name] = preset.config()
+	}
+	groq := configs["groq"]
+	if groq.APIKey != "gsk-test" || groq.Model != "openai/gpt-oss-120b" || groq.BaseURL != "https://api.groq.com/openai/v1" {
+		t.Errorf("groq preset = %+v", groq)
+	}
+	if got := configs["cerebras"].Model; got != "llama-3.3-70b" {
+		t.Errorf("CEREBRAS_MODEL not applied, got %q", got)
+	}
+	if litellm := configs["litellm"]; litellm.BaseURL != "http://proxy.internal:4000" || litellm.Proxy != proxyLiteLLM {
+		t.Errorf("LITELLM_BASE_URL not applied or proxy unset: %+v", litellm)
+	}
+	if c := configs["anthropic"]; c.API != providers.APIAnthropic || c.BaseURL != "https://api.anthropic.com/v1" {
+		t.Errorf("anthropic preset = %+v", c)
+	}
+	if c := configs["gemini"]; c.API != providers.APIGemini || c.Model != "gemini-2.5-flash" {
+		t.Errorf("gemini preset = %+v", c)
+	}
+	for _, name := range []string{"sambanova", "fireworks", "together", "deepinfra"} {
+		if c := configs[name]; c.BaseURL == "" || c.Model == "" {
+			t.Errorf("preset %s incomplete: %+v", name, c)
+		}
+	}
+}
+
+func TestQuirksMaxCompletionTokens(t *testing.T) {
+	req := openai.ChatCompletionRequest{MaxTokens: 512}
+	if got := (providerQuirks{}).apply(req); got.MaxTokens != 512 || got.MaxCompletionTokens != 0 {
+		t.Errorf("no quirks changed request: %+v", got)
+	}
+	if got := (providerQuirks{MaxCompletionTokens: true}).apply(req); got.MaxTokens != 0 || got.MaxCompletionTokens != 512 {
+		t.Errorf("max_completion_tokens quirk not applied: %+v", got)
+	}
+}
+
+func TestValidateProviderType(t *testing.T) {
+	for _, api := range []string{"openai", "anthropic", "gemini", "mistral", "cohere"} {
+		if err := validateProviderType(api); err != nil {
+			t.Errorf("%s rejected: %v", api, err)
+		}
+	}
+	if err := validateProviderType("claude"); err == nil {
+		t.Error("unknown provider type accepted")
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Request profiles of --two-phase. Providers tune for one regime or the other:
+// small outputs reward a fast prefill and first token, long ones a high steady
+// decode rate.
+const (
+	profileLatency    = "latency"
+	profileThroughput = "throughput"
+)
+
+// requestProfile is the request shape of one --two-phase phase.
+type requestProfile struct {
+	name      string
+	maxTokens int
+	// long asks for a long story, so the output runs to maxTokens and the
+	// throughput is measured at steady state.
+	long bool
+}
+
+// requestProfiles are the phases of --two-phase, in the order they run.
+var requestProfiles = []requestProfile{
+	{name: profileLatency, maxTokens: 64},
+	{name: profileThroughput, maxTokens: 2048, long: true},
+}
+
+// lookupProfile returns the profile named name.
+func lookupProfile(name string) (requestProfile, bool) {
+	for _, p := range requestProfiles {
+		if p.name == name {
+			return p, true
+		}
+	}
+	return requestProfile{}, false
+}
+
+// profileEnvTag labels results of one profile, e.g. "prod-profile-latency".
+func profileEnvTag(env, profile string) string {
+	if env == "" {
+		return "profile-" + profile
+	}
+	return env + "-profile-" + profile
+}
+
+// applyProfiles tests every provider once per request profile, tagged e.g.
+// profile-latency and profile-throughput.
+func applyProfiles(providers []ProviderConfig) []ProviderConfig {
+	out := make([]ProviderConfig, 0, len(providers)*len(requestProfiles))
+	for _, p := range providers {
+		for _, profile := range requestProfiles {
+			shaped := p
+			shaped.Profile = profile.name
+			shaped.Env = profileEnvTag(p.Env, profile.name)
+			out = append(out, shaped)
+		}
+	}
+	return out
+}
+
+// streamingRequest builds the streaming request of one run: the streaming
+// prompt for requestKey with --max-tokens output tokens, or the shape of the
+// provider's request profile.
+func streamingRequest(config ProviderConfig, requestKey string) openai.ChatCompletionRequest {
+	req := openai.ChatCompletionRequest{
+		Model:     config.Model,
+		Messages:  []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: selectStreamingPrompt(config.Name, requestKey)}},
+		MaxTokens: requestMaxTokens(defaultMaxTokens),
+		Stream:    true,
+	}
+	if profile, ok := lookupProfile(config.Profile); ok {
+		req.MaxTokens = profile.maxTokens
+		if profile.long {
+			req.Messages = []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleSystem, Content: longStorySystemPrompt},
+				{Role: openai.ChatMessageRoleUser, Content: sessionPrompt(promptGroupLongStory, longStoryUserPrompt)},
+			}
+		}
+	}
+	return req
+}
+
+// profileMeasurement is one successful result run with a request profile.
+type profileMeasurement struct {
+	provider, env, mode, profile string
+	ttft, e2e                    time.Duration
+	throughput                   float64
+	tokens                       int
+}
+
+// writeProfileRows puts the latency and throughput profiles of each provider
+// side by side. Nothing is written unless some provider has both.
+func writeProfileRows(report *strings.Builder, measurements []profileMeasurement) {
+	type pair struct {
+		label, mode         string
+		latency, throughput *profileMeasurement
+	}
+	pairs := make(map[string]*pair)
+	for i := range measurements {
+		m := &measurements[i]
+		baseEnv := envWithoutTag(m.env, "profile-"+m.profile)
+		label := providerLabel(m.provider, baseEnv)
+		key := label + "\x00" + m.mode
+		p, ok := pairs[key]
+		if !ok {
+			p = &pair{label: label, mode: m.mode}
+			pairs[key] = p
+		}
+		switch m.profile {
+		case profileLatency:
+			p.latency = m
+		case profileThroughput:
+			p.throughput = m
+		}
+	}
+	keys := make([]string, 0, len(pairs))
+	for key, p := range pairs {
+		if p.latency != nil && p.throughput != nil {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+	sort.Strings(keys)
+
+	latency, _ := lookupProfile(profileLatency)
+	throughput, _ := lookupProfile(profileThroughput)
+	report.WriteString("## Latency vs Throughput Profiles\n\n")
+	fmt.Fprintf(report, "Each provider was run with two request shapes: a latency profile (the standard prompt, max_tokens %d) "+
+		"and a throughput profile (a long story, max_tokens %d). Providers tuned for interactive use shine in the first; "+
+		"those tuned for batch generation in the second.\n\n", latency.maxTokens, throughput.maxTokens)
+	report.WriteString("| Provider | Mode | Latency TTFT | Latency E2E | Throughput TTFT | Throughput | Throughput Tokens |\n")
+	report.WriteString("|----------|------|--------------|-------------|-----------------|------------|-------------------|\n")
+	for _, key := range keys {
+		p := pairs[key]
+		fmt.Fprintf(report, "| %s | %s | %s | %s | %s | %.2f tok/s | %d |\n", p.label, p.mode,
+			formatDuration(p.latency.ttft), formatDuration(p.latency.e2e),
+			formatDuration(p.throughput.ttft), p.throughput.throughput, p.throughput.tokens)
+	}
+	report.WriteString("\n")
+}
+
+// writeProfileSection compares successful results run with both request
+// profiles.
+func writeProfileSection(report *strings.Builder, results []TestResult) {
+	measurements := make([]profileMeasurement, 0, len(results))
+	for _, r := range results {
+		if r.Success && r.Profile != "" {
+			measurements = append(measurements, profileMeasurement{r.Provider, r.Env, r.Mode, r.Profile,
+				r.TTFT, r.E2ELatency, r.Throughput, r.CompletionTokens})
+		}
+	}
+	writeProfileRows(report, measurements)
+}
+
+// writeDiagnosticProfileSection is the diagnostic-report counterpart of
+// writeProfileSection.
+func writeDiagnosticProfileSection(report *strings.Builder, results []DiagnosticSummary) {
+	measurements := make([]profileMeasurement, 0, len(results))
+	for _, r := range results {
+		if r.Successful > 0 && r.Profile != "" {
+			measurements = append(measurements, profileMeasurement{r.Provider, r.Env, r.Mode, r.Profile,
+				r.AvgTTFT, r.AvgE2ELatency, r.AvgThroughput, r.AvgTokens})
+		}
+	}
+	writeProfileRows(report, measurements)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyProfiles(t *testing.T) {
+	got := applyProfiles([]ProviderConfig{{Name: "nim"}, {Name: "novita", Env: "prod"}})
+	if len(got) != 4 {
+		t.Fatalf("expected one copy per provider and profile, got %+v", got)
+	}
+	if got[0].Env != "profile-latency" || got[0].Profile != profileLatency ||
+		got[3].Env != "prod-profile-throughput" || got[3].Profile != profileThroughput {
+		t.Fatalf("unexpected tags %+v", got)
+	}
+}
+
+func TestProfileVariantsKeepSeparateStats(t *testing.T) {
+	variants := applyProfiles([]ProviderConfig{{Name: "nim", InputPrice: 1}})
+	keys := &keyTracker{providers: make(map[string]map[string]*KeyStat)}
+	budget := &usageBudget{}
+	for i, config := range variants {
+		for _, key := range []string{"sk-first-aaaa", "sk-second-bbbb"} {
+			config.APIKey = key
+			keys.record(config, i+1, nil)
+		}
+		budget.record(config, 1000*(i+1), 0)
+	}
+
+	for i, config := range variants {
+		label := providerLabel(config.Name, config.Env)
+		stats := keys.stats(label)
+		if len(stats) != 2 || stats[0].Requests != 1 || stats[0].OutputTokens != i+1 {
+			t.Errorf("%s: key stats %+v mix in other profiles", label, stats)
+		}
+		if usage := budget.usage(label); usage == nil || usage.PromptTokens != 1000*(i+1) {
+			t.Errorf("%s: usage %+v mixes in other profiles", label, usage)
+		}
+	}
+	if keys.stats("nim") != nil || budget.usage("nim") != nil {
+		t.Error("profile variants were recorded under the bare provider name")
+	}
+}
+
+func TestStreamingRequest(t *testing.T) {
+	standard := streamingRequest(ProviderConfig{Name: "nim", Model: "m"}, "run1")
+	if standard.MaxTokens != 512 || len(standard.Messages) != 1 || !standard.Stream {
+		t.Fatalf("unexpected standard request %+v", standard)
+	}
+
+	latency := streamingRequest(ProviderConfig{Name: "nim", Profile: profileLatency}, "run1")
+	if latency.MaxTokens != 64 || latency.Messages[0].Content != standard.Messages[0].Content {
+		t.Fatalf("expected the standard prompt with a small limit, got %+v", latency)
+	}
+
+	throughput := ProviderConfig{Name: "nim", Profile: profileThroughput}
+	req := streamingRequest(throughput, "run1")
+	if req.MaxTokens != 2048 || len(req.Messages) != 2 || req.Messages[1].Content != longStoryUserPrompt {
+		t.Fatalf("expected the long story with a large limit, got %+v", req)
+	}
+	if promptForRun(throughput, ModeStreaming, "run1") != longStoryUserPrompt {
+		t.Fatalf("expected the judge to see the long-story prompt")
+	}
+}
+
+func TestProfileSection(t *testing.T) {
+	var report strings.Builder
+	writeProfileSection(&report, []TestResult{
+		{Provider: "nim", Env: "profile-latency", Profile: profileLatency, Mode: "streaming", Success: true, TTFT: time.Second},
+	})
+	if report.Len() != 0 {
+		t.Fatalf("expected no section without both profiles, got %q", report.String())
+	}
+
+	writeProfileSection(&report, []TestResult{
+		{Provider: "nim", Env: "prod-profile-throughput", Profile: profileThroughput, Mode: "streaming", Success: true,
+			TTFT: 800 * time.Millisecond, Throughput: 250, CompletionTokens: 2048},
+		{Provider: "nim", Env: "prod-profile-latency", Profile: profileLatency, Mode: "streaming", Success: true,
+			TTFT: 200 * time.Millisecond, E2ELatency: 600 * time.Millisecond},
+	})
+	got := report.String()
+	if !strings.Contains(got, "## Latency vs Throughput Profiles") ||
+		!strings.Contains(got, "| nim [prod] | streaming | 0.200s | 0.600s | 0.800s | 250.00 tok/s | 2048 |") {
+		t.Fatalf("unexpected section:\n%s", got)
+	}
+}
+
+func TestProfileSectionWithPerIP(t *testing.T) {
+	orig := lookupIPAddrs
+	t.Cleanup(func() { lookupIPAddrs = orig })
+	lookupIPAddrs = func(context.Context, string) ([]net.IPAddr, error) {
+		return []net.IPAddr{{IP: net.ParseIP("2001:db8::1")}}, nil
+	}
+
+	// --per-ip expands the profile copies, so its tag follows theirs
+	providers := expandPerIP(context.Background(), applyProfiles([]ProviderConfig{{Name: "nim", BaseURL: "https://llm.test/v1"}}))
+	var results []TestResult
+	for _, p := range providers {
+		results = append(results, TestResult{Provider: p.Name, Env: p.Env, Profile: p.Profile, Mode: "streaming", Success: true,
+			TTFT: 200 * time.Millisecond, E2ELatency: 600 * time.Millisecond, Throughput: 250, CompletionTokens: 2048})
+	}
+	var report strings.Builder
+	writeProfileSection(&report, results)
+	if got := report.String(); !strings.Contains(got, "| nim [ip-2001-db8--1] | streaming |") {
+		t.Fatalf("expected both profiles paired under the per-IP label:\n%s", got)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var rotatePrompts bool
+var promptSeed uint64
+
+// promptLang selects the prompt pack used for streaming requests.
+var promptLang = defaultPromptLang
+
+const defaultPromptLang = "en"
+
+// promptPack is a set of streaming prompts written in one language, along with
+// the scripts a response in that language is expected to use.
+type promptPack struct {
+	name    string
+	scripts []*unicode.RangeTable
+	prompts []string
+}
+
+// promptPacks holds the built-in language packs keyed by --lang code. Every pack
+// asks for the same kind of short story so throughput stays comparable.
+var promptPacks = map[string]promptPack{
+	"en": {name: "English", scripts: []*unicode.RangeTable{unicode.Latin}, prompts: streamingPromptPool},
+	"zh": {name: "Chinese", scripts: []*unicode.RangeTable{unicode.Han}, prompts: []string{
+		"你是一个乐于助人的助手。请用中文写一个大约300字的短篇故事，讲述一个好奇的机器人在一颗被遗忘的星球上探索一座古老而荒芜的图书馆。",
+		"你是一个乐于助人的助手。请用中文写一个大约300字的短篇故事，讲述一位灯塔看守人收到了一封来自一百年前沉没的船只的信。",
+	}},
+	"ja": {name: "Japanese", scripts: []*unicode.RangeTable{unicode.Han, unicode.Hiragana, unicode.Katakana}, prompts: []string{
+		"あなたは親切なアシスタントです。忘れられた惑星にある、草木に覆われた古代の図書館を探検する好奇心旺盛なロボットについて、日本語で400字程度の短い物語を書いてください。",
+		"あなたは親切なアシスタントです。百年前に沈んだ船から手紙を受け取った灯台守について、日本語で400字程度の短い物語を書いてください。",
+	}},
+	"ko": {name: "Korean", scripts: []*unicode.RangeTable{unicode.Hangul, unicode.Han}, prompts: []string{
+		"당신은 도움이 되는 조수입니다. 잊혀진 행성에서 오래되고 덩굴로 뒤덮인 도서관을 탐험하는 호기심 많은 로봇에 대한 짧은 이야기를 한국어로 약 150단어로 써 주세요.",
+		"당신은 도움이 되는 조수입니다. 백 년 전에 침몰한 배로부터 편지를 받은 등대지기에 대한 짧은 이야기를 한국어로 약 150단어로 써 주세요.",
+	}},
+	"ar": {name: "Arabic", scripts: []*unicode.RangeTable{unicode.Arabic}, prompts: []string{
+		"أنت مساعد مفيد. اكتب باللغة العربية قصة قصيرة من حوالي 150 كلمة عن روبوت فضولي يستكشف مكتبة قديمة مغطاة بالنباتات على كوكب منسي.",
+		"أنت مساعد مفيد. اكتب باللغة العربية قصة قصيرة من حوالي 150 كلمة عن حارس منارة يتلقى رسالة من سفينة غرقت قبل قرن.",
+	}},
+	"ru": {name: "Russian", scripts: []*unicode.RangeTable{unicode.Cyrillic}, prompts: []string{
+		"Ты полезный ассистент. Напиши на русском языке короткий рассказ примерно из 150 слов о любопытном роботе, исследующем древнюю заросшую библиотеку на забытой планете.",
+		"Ты полезный ассистент. Напиши на русском языке короткий рассказ примерно из 150 слов о смотрителе маяка, который получает письмо с корабля, затонувшего сто лет назад.",
+	}},
+}
+
+// currentPromptPack returns the pack selected by --lang.
+func currentPromptPack() promptPack {
+	if pack, ok := promptPacks[promptLang]; ok {
+		return pack
+	}
+	return promptPacks[defaultPromptLang]
+}
+
+// validatePromptLang checks that a --lang code has a built-in pack.
+func validatePromptLang(lang string) error {
+	if _, ok := promptPacks[lang]; ok {
+		return nil
+	}
+	codes := make([]string, 0, len(promptPacks))
+	for code := range promptPacks {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return fmt.Errorf("unknown --lang %q (available: %s)", lang, strings.Join(codes, ", "))
+}
+
+// streamingPromptPool holds the prompts used when --rotate-prompts is enabled. The
+// first entry is the default streaming prompt so non-rotating runs stay comparable.
+var streamingPromptPool = []string{
+	streamingPrompt,
+	"You are a helpful assistant. Please write a short, 150-word story about a lighthouse keeper who " +
+		"receives a letter from a ship that sank a century ago.",
+	"You are a helpful assistant. Please write a short, 150-word story about two rival gardeners who " +
+		"discover their prize-winning plants are communicating at night.",
+	"You are a helpful assistant. Please write a short, 150-word story about a cartographer mapping a " +
+		"city that rearranges its streets every full moon.",
+	"You are a helpful assistant. Please write a short, 150-word story about an old clockmaker who " +
+		"builds a clock that runs backwards for one hour each day.",
+}
+
+// selectStreamingPrompt returns the streaming prompt for a given request from the
+// current language pack. With rotation enabled the choice is derived from the
+// session seed, provider name, and request key alone, so the same seed yields the
+// same prompt sequence regardless of how concurrent requests happen to be scheduled.
+// A custom streaming prompt replaces the pack and rotation.
+func selectStreamingPrompt(providerName, requestKey string) string {
+	if text, ok := customPrompts[promptGroupStreaming]; ok {
+		return text
+	}
+	pool := currentPromptPack().prompts
+	if !rotatePrompts {
+		return pool[0]
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(providerName))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(requestKey))
+	rng := rand.New(rand.NewPCG(promptSeed, h.Sum64())) // #nosec G404 -- reproducibility, not security
+	return pool[rng.IntN(len(pool))]
+}
+
+// writeLanguageTokenSection reports how many characters each token carried for
+// non-English runs, since tiktoken's cl100k_base splits non-Latin scripts into
+// far more tokens and tok/s alone is not comparable across languages.
+func writeLanguageTokenSection(report *strings.Builder, results []TestResult) {
+	rows := make([]TestResult, 0)
+	for _, r := range results {
+		if r.Success && r.Language != "" && r.Language != defaultPromptLang && r.CompletionTokens > 0 {
+			rows = append(rows, r)
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	report.WriteString("## Token Accounting by Language\n\n")
+	report.WriteString("| Provider | Language | Mode | Tokens | Characters | Chars/Token | Throughput | Chars/sec |\n")
+	report.WriteString("|----------|----------|------|--------|------------|-------------|------------|-----------|\n")
+	for _, r := range rows {
+		charsPerToken := float64(r.CompletionChars) / float64(r.CompletionTokens)
+		fmt.Fprintf(report, "| %s | %s | %s | %d | %d | %.2f | %.2f tok/s | %.2f |\n",
+			r.Provider, r.Language, r.Mode, r.CompletionTokens, r.CompletionChars,
+			charsPerToken, r.Throughput, r.Throughput*charsPerToken)
+	}
+	report.WriteString("\n")
+}
@@ -0,0 +1,54 @@
+package main
+
+import "testing"
+
+func TestSelectStreamingPromptSeeded(t *testing.T) {
+	defer func(r bool, s uint64) { rotatePrompts, promptSeed = r, s }(rotatePrompts, promptSeed)
+
+	rotatePrompts = false
+	if got := selectStreamingPrompt("nim", "run1"); got != streamingPrompt {
+		t.Fatalf("expected default prompt when rotation is disabled")
+	}
+
+	rotatePrompts = true
+	promptSeed = 42
+	first := make([]string, 0, 10)
+	for i := 0; i < 10; i++ {
+		first = append(first, selectStreamingPrompt("nim", string(rune('a'+i))))
+	}
+	for i := 0; i < 10; i++ {
+		if got := selectStreamingPrompt("nim", string(rune('a'+i))); got != first[i] {
+			t.Fatalf("expected seeded selection to be reproducible at index %d", i)
+		}
+	}
+
+	distinct := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		distinct[selectStreamingPrompt("nim", string(rune('a'+i)))] = true
+	}
+	if len(distinct) < 2 {
+		t.Fatalf("expected rotation to use more than one prompt, got %d", len(distinct))
+	}
+}
+
+func TestPromptPacks(t *testing.T) {
+	defer func(lang string) { promptLang = lang }(promptLang)
+
+	for code, pack := range promptPacks {
+		if len(pack.prompts) == 0 || len(pack.scripts) == 0 {
+			t.Fatalf("pack %q must have prompts and scripts", code)
+		}
+		// Each pack's own prompt must read as its expected script
+		if share := foreignScriptShare(pack.prompts[0], pack.scripts); share > unexpectedScriptRatio {
+			t.Fatalf("pack %q prompt has %.0f%% letters outside its scripts", code, 100*share)
+		}
+	}
+
+	if err := validatePromptLang("xx"); err == nil {
+		t.Fatalf("expected unknown language to be rejected")
+	}
+	promptLang = "ru"
+	if got := selectStreamingPrompt("nim", "run1"); got != promptPacks["ru"].prompts[0] {
+		t.Fatalf("expected Russian prompt, got %q", got)
+	}
+}
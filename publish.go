@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// publishSchema identifies the format of exported results bundles.
+const publishSchema = "llm-api-speed/published-results/v1"
+
+// PublishedBundle is a sanitized, shareable export of one session. It never
+// contains API keys, base URLs, error messages, response text, or host details.
+type PublishedBundle struct {
+	Schema      string            `json:"schema"`
+	ToolVersion string            `json:"toolVersion"`
+	Session     string            `json:"session"`
+	RecordedAt  time.Time         `json:"recordedAt"`
+	Region      string            `json:"region,omitempty"`
+	Mode        string            `json:"mode"`
+	Language    string            `json:"language"`
+	Results     []PublishedResult `json:"results"`
+}
+
+// PublishedResult is one provider's metrics. Durations are in milliseconds.
+type PublishedResult struct {
+	Provider         string  `json:"provider"`
+	Model            string  `json:"model"`
+	Env              string  `json:"env,omitempty"`
+	Mode             string  `json:"mode"`
+	Requests         int     `json:"requests"`
+	Successful       int     `json:"successful"`
+	TTFTMs           float64 `json:"ttftMs,omitempty"`
+	E2EMs            float64 `json:"e2eMs,omitempty"`
+	ThroughputTPS    float64 `json:"throughputTokensPerSec,omitempty"`
+	CompletionTokens int     `json:"completionTokens,omitempty"`
+	ProjectedE2EMs   float64 `json:"projectedE2eMs,omitempty"`
+	SecPer100Tokens  float64 `json:"secondsPer100Tokens,omitempty"`
+	NormalizedE2EMs  float64 `json:"normalizedE2eMs,omitempty"`
+	QualityScore     float64 `json:"qualityScore,omitempty"`
+}
+
+// durationMs converts a duration to fractional milliseconds.
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// buildPublishedBundle converts stored session results into a sanitized bundle.
+func buildPublishedBundle(manifest SessionManifest, results []TestResult, diagnostics []DiagnosticSummary, region string) PublishedBundle {
+	language := manifest.Flags["lang"]
+	if language == "" {
+		language = defaultPromptLang
+	}
+	bundle := PublishedBundle{
+		Schema:      publishSchema,
+		ToolVersion: manifest.ToolVersion,
+		Session:     manifest.Session,
+		RecordedAt:  manifest.CreatedAt,
+		Region:      region,
+		Mode:        manifest.Mode,
+		Language:    language,
+		Results:     make([]PublishedResult, 0, len(results)+len(diagnostics)),
+	}
+
+	for _, r := range results {
+		p := PublishedResult{Provider: r.Provider, Model: r.Model, Env: r.Env, Mode: r.Mode, Requests: 1}
+		if r.Success {
+			p.Successful = 1
+			p.TTFTMs = durationMs(r.TTFT)
+			p.E2EMs = durationMs(r.E2ELatency)
+			p.ThroughputTPS = r.Throughput
+			p.CompletionTokens = r.CompletionTokens
+			p.ProjectedE2EMs = durationMs(r.ProjectedE2E)
+			p.SecPer100Tokens = secondsPer100Tokens(r.E2ELatency, r.CompletionTokens)
+			p.NormalizedE2EMs = durationMs(normalizedE2E(r.TTFT, r.Throughput))
+			p.QualityScore = r.QualityScore
+		}
+		bundle.Results = append(bundle.Results, p)
+	}
+	for _, d := range diagnostics {
+		p := PublishedResult{
+			Provider:   d.Provider,
+			Model:      d.Model,
+			Env:        d.Env,
+			Mode:       "diagnostic-" + d.Mode,
+			Requests:   d.TotalRequests,
+			Successful: d.Successful,
+		}
+		if d.Successful > 0 {
+			p.TTFTMs = durationMs(d.AvgTTFT)
+			p.E2EMs = durationMs(d.AvgE2ELatency)
+			p.ThroughputTPS = d.AvgThroughput
+			p.CompletionTokens = d.AvgTokens
+			p.ProjectedE2EMs = durationMs(d.ProjectedE2E)
+			p.SecPer100Tokens = secondsPer100Tokens(d.AvgE2ELatency, d.AvgTokens)
+			p.NormalizedE2EMs = durationMs(normalizedE2E(d.AvgTTFT, d.AvgThroughput))
+		}
+		bundle.Results = append(bundle.Results, p)
+	}
+	return bundle
+}
+
+// runPublish implements the "publish" subcommand, which exports a session as a
+// sanitized results bundle suitable for sharing.
+func runPublish(args []string) {
+	fs := flag.NewFlagSet("publish", flag.ExitOnError)
+	region := fs.String("region", "", "Free-form region tag for where the benchmark ran (e.g. eu-west)")
+	out := fs.String("out", "", "Output file (default: <session>/published-results.json)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: llm-api-speed publish [--region tag] [--out file] <session>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		log.Fatalf("Error parsing flags: %v", err)
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	session := fs.Arg(0)
+	sessionDir := resolveSessionDir(session)
+	manifest, err := loadManifest(session)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	results, diagnostics, err := loadSessionResults(sessionDir)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if len(results)+len(diagnostics) == 0 {
+		log.Fatalf("Error: no results found in %s", sessionDir)
+	}
+
+	bundle := buildPublishedBundle(manifest, results, diagnostics, *region)
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		log.Fatalf("Error marshaling results bundle: %v", err)
+	}
+	filename := *out
+	if filename == "" {
+		filename = filepath.Join(sessionDir, "published-results.json")
+	}
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		log.Fatalf("Error writing results bundle: %v", err)
+	}
+	log.Printf("Published %d result(s) to %s (no keys, URLs, errors, or response text included)", len(bundle.Results), filename)
+}
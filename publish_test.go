@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPublishedBundleIsSanitized(t *testing.T) {
+	dir := t.TempDir()
+	providers := []ProviderConfig{
+		{Name: "nim", BaseURL: "https://private.example.internal/v1", APIKey: "secret-key", Model: "model-a"},
+	}
+	if err := writeManifest(dir, buildManifest("20250101-000000", nil, string(ModeStreaming), providers)); err != nil {
+		t.Fatalf("writeManifest failed: %v", err)
+	}
+	saveResult(dir, TestResult{
+		Provider: "nim", Model: "model-a", Mode: string(ModeStreaming), Success: true,
+		Timestamp: time.Unix(0, 0), TTFT: 250 * time.Millisecond, E2ELatency: 2 * time.Second, Throughput: 80,
+	})
+	saveResult(dir, TestResult{
+		Provider: "nim-failed", Model: "model-a", Mode: string(ModeStreaming),
+		Timestamp: time.Unix(60, 0), Error: "401 Unauthorized: key secret-key rejected",
+	})
+
+	manifest, err := loadManifest(dir)
+	if err != nil {
+		t.Fatalf("loadManifest failed: %v", err)
+	}
+	results, diagnostics, err := loadSessionResults(dir)
+	if err != nil {
+		t.Fatalf("loadSessionResults failed: %v", err)
+	}
+	if len(results) != 2 || len(diagnostics) != 0 {
+		t.Fatalf("expected 2 results and no diagnostics, got %d/%d", len(results), len(diagnostics))
+	}
+
+	bundle := buildPublishedBundle(manifest, results, diagnostics, "eu-west")
+	if bundle.Schema != publishSchema || bundle.Region != "eu-west" || bundle.Language != defaultPromptLang {
+		t.Fatalf("unexpected bundle header: %+v", bundle)
+	}
+	if r := bundle.Results[0]; r.TTFTMs != 250 || r.E2EMs != 2000 || r.Successful != 1 {
+		t.Fatalf("unexpected published result: %+v", r)
+	}
+
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	for _, secret := range []string{"secret-key", "private.example.internal", "Unauthorized"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("published bundle leaks %q", secret)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	// repetitionNGram is the word n-gram size used for the repetition ratio.
+	repetitionNGram = 3
+	// loopNGram is the word n-gram size used for loop detection.
+	loopNGram = 8
+	// loopMinRepeats is how often a loopNGram must recur to count as a loop.
+	loopMinRepeats = 3
+	// degenerateRepetitionRatio marks a response as degenerate when exceeded.
+	degenerateRepetitionRatio = 0.3
+	// unexpectedScriptRatio is the share of letters outside the expected script
+	// above which a response is flagged as being in a non-requested language.
+	unexpectedScriptRatio = 0.2
+)
+
+// OutputQuality holds cheap, local quality signals for one response.
+type OutputQuality struct {
+	RepetitionRatio  float64 `json:"repetitionRatio"`
+	LoopDetected     bool    `json:"loopDetected"`
+	Truncated        bool    `json:"truncated"`
+	UnexpectedScript bool    `json:"unexpectedScript"`
+}
+
+// Degenerate reports whether the response looks broken rather than merely short.
+func (q OutputQuality) Degenerate() bool {
+	return q.LoopDetected || q.UnexpectedScript || q.RepetitionRatio > degenerateRepetitionRatio
+}
+
+// analyzeOutput computes quality signals for a response that was expected to be
+// written in the given scripts.
+func analyzeOutput(text string, expectedScripts []*unicode.RangeTable) OutputQuality {
+	words := strings.Fields(strings.ToLower(text))
+	return OutputQuality{
+		RepetitionRatio:  repetitionRatio(words, repetitionNGram),
+		LoopDetected:     hasNGramLoop(words, loopNGram, loopMinRepeats),
+		Truncated:        endsMidSentence(text),
+		UnexpectedScript: foreignScriptShare(text, expectedScripts) > unexpectedScriptRatio,
+	}
+}
+
+// repetitionRatio returns the share of word n-grams that duplicate an earlier one.
+func repetitionRatio(words []string, n int) float64 {
+	total := len(words) - n + 1
+	if total <= 0 {
+		return 0
+	}
+	seen := make(map[string]bool, total)
+	repeated := 0
+	for i := 0; i < total; i++ {
+		key := strings.Join(words[i:i+n], " ")
+		if seen[key] {
+			repeated++
+		}
+		seen[key] = true
+	}
+	return float64(repeated) / float64(total)
+}
+
+// hasNGramLoop reports whether any word n-gram occurs at least minRepeats times,
+// which catches models stuck repeating the same passage.
+func hasNGramLoop(words []string, n, minRepeats int) bool {
+	counts := make(map[string]int)
+	for i := 0; i+n <= len(words); i++ {
+		key := strings.Join(words[i:i+n], " ")
+		counts[key]++
+		if counts[key] >= minRepeats {
+			return true
+		}
+	}
+	return false
+}
+
+// endsMidSentence reports whether text stops without terminal punctuation.
+func endsMidSentence(text string) bool {
+	trimmed := strings.TrimRightFunc(text, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune("\"'”’)]*_`", r)
+	})
+	if trimmed == "" {
+		return false
+	}
+	last := []rune(trimmed)[len([]rune(trimmed))-1]
+	return !strings.ContainsRune(".!?…。！？", last)
+}
+
+// foreignScriptShare returns the share of letters not in any expected script.
+func foreignScriptShare(text string, expected []*unicode.RangeTable) float64 {
+	letters, foreign := 0, 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if !unicode.IsOneOf(expected, r) {
+			foreign++
+		}
+	}
+	if letters == 0 {
+		return 0
+	}
+	return float64(foreign) / float64(letters)
+}
+
+// qualityTally aggregates OutputQuality over several responses.
+type qualityTally struct {
+	analyzed      int
+	repetitionSum float64
+	degenerate    int
+	loops         int
+	truncated     int
+	foreign       int
+}
+
+// add records the quality signals of one response.
+func (t *qualityTally) add(q OutputQuality) {
+	t.analyzed++
+	t.repetitionSum += q.RepetitionRatio
+	if q.Degenerate() {
+		t.degenerate++
+	}
+	if q.LoopDetected {
+		t.loops++
+	}
+	if q.Truncated {
+		t.truncated++
+	}
+	if q.UnexpectedScript {
+		t.foreign++
+	}
+}
+
+// avgRepetition returns the mean repetition ratio of analyzed responses.
+func (t *qualityTally) avgRepetition() float64 {
+	if t.analyzed == 0 {
+		return 0
+	}
+	return t.repetitionSum / float64(t.analyzed)
+}
+
+// flags returns human-readable labels for the problems seen.
+func (t *qualityTally) flags() []string {
+	var flags []string
+	if t.loops > 0 {
+		flags = append(flags, fmt.Sprintf("loop x%d", t.loops))
+	}
+	if t.avgRepetition() > degenerateRepetitionRatio {
+		flags = append(flags, fmt.Sprintf("repetitive (%.0f%%)", 100*t.avgRepetition()))
+	}
+	if t.foreign > 0 {
+		flags = append(flags, fmt.Sprintf("unexpected language x%d", t.foreign))
+	}
+	if t.truncated > 0 {
+		flags = append(flags, fmt.Sprintf("truncated x%d", t.truncated))
+	}
+	return flags
+}
+
+// writeOutputQualitySection lists results whose responses tripped a quality
+// heuristic, highlighting fast providers whose output is degenerate.
+func writeOutputQualitySection(report *strings.Builder, results []TestResult) {
+	flagged := make([]TestResult, 0)
+	for _, r := range results {
+		if r.Success && len(r.OutputFlags) > 0 {
+			flagged = append(flagged, r)
+		}
+	}
+	if len(flagged) == 0 {
+		return
+	}
+
+	report.WriteString("## Output Quality Flags\n\n")
+	report.WriteString("Heuristic checks on response text (no external calls). Degenerate runs had loops, " +
+		"heavy repetition, or text in an unexpected language; treat their throughput with suspicion.\n\n")
+	report.WriteString("| Provider | Mode | Throughput | Avg Repetition | Degenerate Runs | Flags |\n")
+	report.WriteString("|----------|------|------------|----------------|-----------------|-------|\n")
+	for _, r := range flagged {
+		fmt.Fprintf(report, "| %s | %s | %.2f tok/s | %.1f%% | %d | %s |\n",
+			providerLabel(r.Provider, r.Env), r.Mode, r.Throughput, 100*r.RepetitionRatio, r.DegenerateRuns,
+			strings.Join(r.OutputFlags, ", "))
+	}
+	report.WriteString("\n")
+}
+
+// writeDiagnosticOutputQualitySection is the diagnostic-report counterpart of
+// writeOutputQualitySection.
+func writeDiagnosticOutputQualitySection(report *strings.Builder, results []DiagnosticSummary) {
+	flagged := make([]DiagnosticSummary, 0)
+	for _, r := range results {
+		if len(r.OutputFlags) > 0 {
+			flagged = append(flagged, r)
+		}
+	}
+	if len(flagged) == 0 {
+		return
+	}
+
+	report.WriteString("## Output Quality Flags\n\n")
+	report.WriteString("| Provider | Mode | Avg Throughput | Degenerate Responses | Flags |\n")
+	report.WriteString("|----------|------|----------------|----------------------|-------|\n")
+	for _, r := range flagged {
+		fmt.Fprintf(report, "| %s | %s | %.2f tok/s | %d/%d | %s |\n",
+			providerLabel(r.Provider, r.Env), r.Mode, r.AvgThroughput, r.DegenerateResponses, r.Successful,
+			strings.Join(r.OutputFlags, ", "))
+	}
+	report.WriteString("\n")
+}
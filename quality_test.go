@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestAnalyzeOutput(t *testing.T) {
+	latin := []*unicode.RangeTable{unicode.Latin}
+
+	clean := analyzeOutput("The robot wandered through the dusty aisles. It found a book about stars, "+
+		"opened it carefully, and began to read by the light of its own eyes.", latin)
+	if clean.Degenerate() || clean.Truncated {
+		t.Fatalf("expected clean output to pass, got %+v", clean)
+	}
+
+	loop := analyzeOutput(strings.Repeat("and then the robot walked into the library again ", 5)+"The end.", latin)
+	if !loop.LoopDetected || !loop.Degenerate() {
+		t.Fatalf("expected loop to be detected, got %+v", loop)
+	}
+
+	truncated := analyzeOutput("The robot reached for the ancient tome and", latin)
+	if !truncated.Truncated {
+		t.Fatalf("expected truncation to be detected, got %+v", truncated)
+	}
+	quoted := analyzeOutput(`She whispered, "Goodbye."`, latin)
+	if quoted.Truncated {
+		t.Fatalf("expected closing quote after period not to count as truncation")
+	}
+
+	foreign := analyzeOutput("机器人在古老的图书馆里探索，发现了一本关于星星的书。", latin)
+	if !foreign.UnexpectedScript || !foreign.Degenerate() {
+		t.Fatalf("expected non-Latin output to be flagged, got %+v", foreign)
+	}
+}
+
+func TestQualityTallyFlags(t *testing.T) {
+	var tally qualityTally
+	tally.add(OutputQuality{LoopDetected: true, RepetitionRatio: 0.6})
+	tally.add(OutputQuality{Truncated: true})
+	if tally.degenerate != 1 {
+		t.Fatalf("expected 1 degenerate response, got %d", tally.degenerate)
+	}
+	flags := strings.Join(tally.flags(), ", ")
+	if flags != "loop x1, truncated x1" {
+		t.Fatalf("unexpected flags: %q", flags)
+	}
+}
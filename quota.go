@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// quotaWarnShare is the share of a key's rate-limit window above which the
+// session logs a warning and the report marks the key as near its limit.
+const quotaWarnShare = 0.8
+
+// quotaReported reports whether the provider sent rate-limit headers for this key.
+func (s KeyStat) quotaReported() bool {
+	return s.LimitRequests > 0 || s.LimitTokens > 0
+}
+
+// quotaShare returns the peak share of a limit in use, judged from the lowest
+// remaining value seen, or 0 when the limit is unknown.
+func quotaShare(limit, lowestRemaining int) float64 {
+	if limit <= 0 {
+		return 0
+	}
+	return float64(limit-lowestRemaining) / float64(limit)
+}
+
+// observe folds the rate-limit headers of one response into the counters of
+// config's current key, warning once when the key nears its limit.
+func (t *keyTracker) observe(config ProviderConfig, h openai.RateLimitHeaders) {
+	if h.LimitRequests <= 0 && h.LimitTokens <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stat := t.entry(config)
+	if h.LimitRequests > 0 {
+		if stat.LimitRequests == 0 || h.RemainingRequests < stat.LowestRemainingRequests {
+			stat.LowestRemainingRequests = h.RemainingRequests
+		}
+		stat.LimitRequests = h.LimitRequests
+	}
+	if h.LimitTokens > 0 {
+		if stat.LimitTokens == 0 || h.RemainingTokens < stat.LowestRemainingTokens {
+			stat.LowestRemainingTokens = h.RemainingTokens
+		}
+		stat.LimitTokens = h.LimitTokens
+	}
+
+	requests := quotaShare(stat.LimitRequests, stat.LowestRemainingRequests)
+	tokens := quotaShare(stat.LimitTokens, stat.LowestRemainingTokens)
+	if !stat.warned && max(requests, tokens) >= quotaWarnShare {
+		stat.warned = true
+		log.Printf("Warning: [%s] API key %s has used %.0f%% of its rate-limit window "+
+			"(%d/%d requests, %d/%d tokens remaining)", providerLabel(config.Name, config.Env), stat.Key, 100*max(requests, tokens),
+			stat.LowestRemainingRequests, stat.LimitRequests, stat.LowestRemainingTokens, stat.LimitTokens)
+	}
+}
+
+// formatQuota renders one quota dimension as "used/limit (share)".
+func formatQuota(limit, lowestRemaining int) string {
+	if limit <= 0 {
+		return "-"
+	}
+	share := quotaShare(limit, lowestRemaining)
+	cell := fmt.Sprintf("%d/%d (%.0f%%)", limit-lowestRemaining, limit, 100*share)
+	if share >= quotaWarnShare {
+		cell += " *near limit*"
+	}
+	return cell
+}
+
+// writeQuotaRows renders per-key quota consumption for keys whose provider sends
+// rate-limit headers.
+func writeQuotaRows(report *strings.Builder, rows []keyStatsRow) {
+	if len(rows) == 0 {
+		return
+	}
+	report.WriteString("## Quota Used\n\n")
+	fmt.Fprintf(report, "Peak use of each key's rate-limit window, from the provider's `x-ratelimit-*` headers "+
+		"(limit minus the lowest remaining value seen). Keys above %.0f%% are marked *near limit*; "+
+		"a shared key may also be drawn down by other clients.\n\n", 100*quotaWarnShare)
+	report.WriteString("| Provider | Key | Requests | Output Tokens | Request Window | Token Window |\n")
+	report.WriteString("|----------|-----|----------|---------------|----------------|--------------|\n")
+	for _, r := range rows {
+		for _, s := range r.stats {
+			if !s.quotaReported() {
+				continue
+			}
+			fmt.Fprintf(report, "| %s | `%s` | %d | %d | %s | %s |\n", r.provider, s.Key, s.Requests, s.OutputTokens,
+				formatQuota(s.LimitRequests, s.LowestRemainingRequests), formatQuota(s.LimitTokens, s.LowestRemainingTokens))
+		}
+	}
+	report.WriteString("\n")
+}
+
+// hasQuota reports whether any key in stats has quota headers.
+func hasQuota(stats []KeyStat) bool {
+	for _, s := range stats {
+		if s.quotaReported() {
+			return true
+		}
+	}
+	return false
+}
+
+// writeQuotaSection adds the quota table for results whose provider reported limits.
+func writeQuotaSection(report *strings.Builder, results []TestResult) {
+	rows := make([]keyStatsRow, 0, len(results))
+	for _, r := range results {
+		if hasQuota(r.KeyStats) {
+			rows = append(rows, keyStatsRow{providerLabel(r.Provider, r.Env), r.KeyStats})
+		}
+	}
+	writeQuotaRows(report, rows)
+}
+
+// writeDiagnosticQuotaSection is the diagnostic-report counterpart of
+// writeQuotaSection.
+func writeDiagnosticQuotaSection(report *strings.Builder, results []DiagnosticSummary) {
+	rows := make([]keyStatsRow, 0, len(results))
+	for _, r := range results {
+		if hasQuota(r.KeyStats) {
+			rows = append(rows, keyStatsRow{providerLabel(r.Provider, r.Env), r.KeyStats})
+		}
+	}
+	writeQuotaRows(report, rows)
+}
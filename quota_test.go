@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestQuotaTracksLowestRemaining(t *testing.T) {
+	tracker := &keyTracker{providers: make(map[string]map[string]*KeyStat)}
+	config := ProviderConfig{Name: "quota", APIKey: "sk-shared-abcd"}
+	for _, remaining := range []int{90, 15, 40} {
+		tracker.observe(config, openai.RateLimitHeaders{LimitRequests: 100, RemainingRequests: remaining})
+		tracker.record(config, 50, nil)
+	}
+
+	stats := tracker.stats("quota")
+	if len(stats) != 1 {
+		t.Fatalf("single key with quota headers should be reported, got %+v", stats)
+	}
+	if s := stats[0]; s.LimitRequests != 100 || s.LowestRemainingRequests != 15 || s.OutputTokens != 150 || s.LimitTokens != 0 {
+		t.Errorf("unexpected quota stats: %+v", s)
+	}
+
+	var report strings.Builder
+	writeQuotaSection(&report, []TestResult{{Provider: "quota", KeyStats: stats}})
+	out := report.String()
+	if !strings.Contains(out, "85/100 (85%) *near limit*") {
+		t.Errorf("quota section missing near-limit request window:\n%s", out)
+	}
+
+	report.Reset()
+	writeKeyStatsSection(&report, []TestResult{{Provider: "quota", KeyStats: stats}})
+	if report.Len() != 0 {
+		t.Errorf("key usage section shown for a single key:\n%s", report.String())
+	}
+}
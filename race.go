@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+const raceModeLabel = "race"
+
+// RaceIteration records which provider won one hedged request.
+type RaceIteration struct {
+	Iteration        int                      `json:"iteration"`
+	TTFTWinner       string                   `json:"ttftWinner,omitempty"`
+	CompletionWinner string                   `json:"completionWinner,omitempty"`
+	TTFT             map[string]time.Duration `json:"ttft"`
+	E2E              map[string]time.Duration `json:"e2e"`
+	Errors           map[string]string        `json:"errors,omitempty"`
+}
+
+// RaceStanding is one provider's tally across a race session.
+type RaceStanding struct {
+	Provider       string        `json:"provider"`
+	Model          string        `json:"model"`
+	TTFTWins       int           `json:"ttftWins"`
+	CompletionWins int           `json:"completionWins"`
+	Failures       int           `json:"failures"`
+	AvgTTFT        time.Duration `json:"avgTtft"`
+	AvgE2E         time.Duration `json:"avgE2e"`
+}
+
+// RaceSummary aggregates a race session.
+type RaceSummary struct {
+	Iterations []RaceIteration `json:"iterations"`
+	Standings  []RaceStanding  `json:"standings"`
+}
+
+// raceOnce sends the same request to every provider at the same moment and lets
+// all streams finish, recording who produced the first token and who completed first.
+func raceOnce(providers []ProviderConfig, tke *tiktoken.Tiktoken, iteration int) RaceIteration {
+	result := RaceIteration{
+		Iteration: iteration,
+		TTFT:      make(map[string]time.Duration),
+		E2E:       make(map[string]time.Duration),
+		Errors:    make(map[string]string),
+	}
+	requestKey := fmt.Sprintf("race%d", iteration)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	firstToken := make(chan string, len(providers))
+	outcomes := make([]streamOutcome, len(providers))
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i, p := range providers {
+		wg.Add(1)
+		go func(i int, p ProviderConfig) {
+			defer wg.Done()
+			outcomes[i] = raceStream(ctx, p, tke, requestKey, firstToken)
+		}(i, p)
+	}
+	wg.Wait()
+	close(firstToken)
+
+	// The channel is filled in the order first tokens arrived
+	result.TTFTWinner = <-firstToken
+
+	var bestEnd time.Time
+	for _, o := range outcomes {
+		if o.err != nil {
+			result.Errors[o.provider] = o.err.Error()
+			continue
+		}
+		result.TTFT[o.provider] = o.firstToken.Sub(start)
+		result.E2E[o.provider] = o.end.Sub(start)
+		if bestEnd.IsZero() || o.end.Before(bestEnd) {
+			bestEnd = o.end
+			result.CompletionWinner = o.provider
+		}
+	}
+	if len(result.Errors) == 0 {
+		result.Errors = nil
+	}
+	return result
+}
+
+// tallyRace builds per-provider standings from race iterations.
+func tallyRace(providers []ProviderConfig, iterations []RaceIteration) []RaceStanding {
+	standings := make([]RaceStanding, 0, len(providers))
+	for _, p := range providers {
+		s := RaceStanding{Provider: p.Name, Model: p.Model}
+		var ttftSum, e2eSum time.Duration
+		ok := 0
+		for _, it := range iterations {
+			if it.TTFTWinner == p.Name {
+				s.TTFTWins++
+			}
+			if it.CompletionWinner == p.Name {
+				s.CompletionWins++
+			}
+			if _, failed := it.Errors[p.Name]; failed {
+				s.Failures++
+				continue
+			}
+			if ttft, seen := it.TTFT[p.Name]; seen {
+				ttftSum += ttft
+				e2eSum += it.E2E[p.Name]
+				ok++
+			}
+		}
+		if ok > 0 {
+			s.AvgTTFT = ttftSum / time.Duration(ok)
+			s.AvgE2E = e2eSum / time.Duration(ok)
+		}
+		standings = append(standings, s)
+	}
+
+	// Sort by TTFT wins, then completion wins
+	for i := 0; i < len(standings); i++ {
+		for j := i + 1; j < len(standings); j++ {
+			if standings[j].TTFTWins > standings[i].TTFTWins ||
+				(standings[j].TTFTWins == standings[i].TTFTWins && standings[j].CompletionWins > standings[i].CompletionWins) {
+				standings[i], standings[j] = standings[j], standings[i]
+			}
+		}
+	}
+	return standings
+}
+
+// runRaceBenchmark races the providers against each other for the given number
+// of iterations and writes race-summary.json and RACE-REPORT.md.
+func runRaceBenchmark(providers []ProviderConfig, tke *tiktoken.Tiktoken, runs int, resultsDir, sessionTimestamp string) error {
+	if len(providers) < 2 {
+		return fmt.Errorf("race mode needs at least two providers, got %d", len(providers))
+	}
+	names := make([]string, 0, len(providers))
+	for _, p := range providers {
+		names = append(names, p.Name)
+	}
+	log.Printf("=== RACE MODE: %s, %d iterations ===", strings.Join(names, " vs "), runs)
+
+	var summary RaceSummary
+	for i := 1; i <= runs; i++ {
+		if err := canStartRun(); err != nil {
+			log.Printf("Race iteration %d skipped: %v", i, err)
+			break
+		}
+		it := raceOnce(providers, tke, i)
+		summary.Iterations = append(summary.Iterations, it)
+		log.Printf("Race %d/%d: first token=%s, first to complete=%s, failures=%d",
+			i, runs, it.TTFTWinner, it.CompletionWinner, len(it.Errors))
+	}
+	summary.Standings = tallyRace(providers, summary.Iterations)
+
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling race summary: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(resultsDir, "race-summary.json"), data, 0600); err != nil {
+		return fmt.Errorf("error writing race summary: %w", err)
+	}
+	return generateRaceReport(resultsDir, summary, sessionTimestamp)
+}
+
+// generateRaceReport writes RACE-REPORT.md with win rates per provider.
+func generateRaceReport(resultsDir string, summary RaceSummary, sessionTimestamp string) error {
+	filename := filepath.Join(resultsDir, "RACE-REPORT.md")
+	total := len(summary.Iterations)
+
+	var report strings.Builder
+	report.WriteString("# LLM API Race Results\n\n")
+	fmt.Fprintf(&report, "**Test Session:** %s\n\n", sessionTimestamp)
+	fmt.Fprintf(&report, "**Iterations:** %d (identical request sent to every provider at once)\n\n", total)
+	report.WriteString("---\n\n")
+
+	report.WriteString("## Win Rates\n\n")
+	report.WriteString("| Rank | Provider | Model | TTFT Wins | Completion Wins | Avg TTFT | Avg E2E | Failures |\n")
+	report.WriteString("|------|----------|-------|-----------|-----------------|----------|---------|----------|\n")
+	for i, s := range summary.Standings {
+		ttftRate, completionRate := 0.0, 0.0
+		if total > 0 {
+			ttftRate = 100.0 * float64(s.TTFTWins) / float64(total)
+			completionRate = 100.0 * float64(s.CompletionWins) / float64(total)
+		}
+		fmt.Fprintf(&report, "| %d | %s | %s | %d (%.1f%%) | %d (%.1f%%) | %s | %s | %d |\n",
+			i+1, s.Provider, s.Model, s.TTFTWins, ttftRate, s.CompletionWins, completionRate,
+			formatDuration(s.AvgTTFT), formatDuration(s.AvgE2E), s.Failures)
+	}
+	report.WriteString("\n")
+
+	report.WriteString("## Iterations\n\n")
+	report.WriteString("| # | First Token | First Complete | Failures |\n")
+	report.WriteString("|---|-------------|----------------|----------|\n")
+	for _, it := range summary.Iterations {
+		ttftWinner, completionWinner := it.TTFTWinner, it.CompletionWinner
+		if ttftWinner == "" {
+			ttftWinner = NotAvailable
+		}
+		if completionWinner == "" {
+			completionWinner = NotAvailable
+		}
+		fmt.Fprintf(&report, "| %d | %s | %s | %d |\n", it.Iteration, ttftWinner, completionWinner, len(it.Errors))
+	}
+	report.WriteString("\n")
+
+	report.WriteString("---\n\n")
+	fmt.Fprintf(&report, "*Report generated at %s*\n", time.Now().Format("2006-01-02 15:04:05"))
+
+	if err := os.WriteFile(filename, []byte(report.String()), 0600); err != nil {
+		return fmt.Errorf("error writing race report: %w", err)
+	}
+	log.Printf("Race report generated: %s", filename)
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRaceOnceAndTally(t *testing.T) {
+	tke := testTokenizer(t)
+
+	// quick starts first but streams slowly; steady starts later and finishes first
+	quick := mockSSEServer{chunks: []string{"a", "b", "c", "d"}, chunkDelay: 150 * time.Millisecond}.start(t)
+	defer quick.Close()
+	steady := mockSSEServer{firstTokenDelay: 100 * time.Millisecond, chunks: []string{"abcd."}}.start(t)
+	defer steady.Close()
+
+	providers := []ProviderConfig{
+		{Name: "quick", BaseURL: quick.URL, APIKey: "k", Model: "m"},
+		{Name: "steady", BaseURL: steady.URL, APIKey: "k", Model: "m"},
+	}
+
+	it := raceOnce(providers, tke, 1)
+	if it.TTFTWinner != "quick" {
+		t.Fatalf("expected quick to win on TTFT, got %+v", it)
+	}
+	if it.CompletionWinner != "steady" {
+		t.Fatalf("expected steady to win on completion, got %+v", it)
+	}
+
+	standings := tallyRace(providers, []RaceIteration{it, it})
+	if standings[0].Provider != "quick" || standings[0].TTFTWins != 2 || standings[1].CompletionWins != 2 {
+		t.Fatalf("unexpected standings: %+v", standings)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lamim/llm-api-speed/pkg/benchmark"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Error classes of a failed request, coarse enough to count in tail analyses.
+const (
+	errClassTimeout   = "timeout"
+	errClassRateLimit = "rate_limit"
+	errClassAuth      = "auth"
+	errClassClient    = "client_error"
+	errClassServer    = "server_error"
+	errClassNoTokens  = "no_tokens"
+	errClassNetwork   = "network"
+	errClassOther     = "other"
+)
+
+// errorClass classifies a request error; it is empty for nil.
+func errorClass(err error) string {
+	if err == nil {
+		return ""
+	}
+	status := 0
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &apiErr):
+		status = apiErr.HTTPStatusCode
+	case errors.As(err, &reqErr):
+		status = reqErr.HTTPStatusCode
+	}
+	switch {
+	case status == http.StatusTooManyRequests:
+		return errClassRateLimit
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return errClassAuth
+	case status >= 500:
+		return errClassServer
+	case status >= 400:
+		return errClassClient
+	case errors.Is(err, context.DeadlineExceeded) || strings.Contains(err.Error(), "timeout exceeded"):
+		return errClassTimeout
+	case errors.Is(err, benchmark.ErrNoTokens) || strings.Contains(err.Error(), "no content received"):
+		return errClassNoTokens
+	case errors.As(err, &netErr):
+		return errClassNetwork
+	}
+	return errClassOther
+}
+
+// DiagnosticRecord is one diagnostic request, kept so tail behavior can be
+// analyzed after the run rather than only the averages of DiagnosticSummary.
+type DiagnosticRecord struct {
+	Worker     int           `json:"worker"`
+	ReqNum     int           `json:"reqNum"`
+	Mode       TestMode      `json:"mode"`
+	Start      time.Time     `json:"start"`
+	End        time.Time     `json:"end"`
+	E2ELatency time.Duration `json:"e2eLatencyMs,omitempty"`
+	TTFT       time.Duration `json:"ttftMs,omitempty"`
+	Throughput float64       `json:"throughputTokensPerSec,omitempty"`
+	Tokens     int           `json:"completionTokens,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	ErrorClass string        `json:"errorClass,omitempty"`
+	Instance   string        `json:"instance,omitempty"`
+}
+
+// diagnosticRecordsFile names the records file saved next to a diagnostic
+// summary.
+func diagnosticRecordsFile(config ProviderConfig, timestamp string) string {
+	return fmt.Sprintf("%s-diagnostic-records-%s.jsonl", resultFilePrefix(config.Name, config.Env), timestamp)
+}
+
+// writeDiagnosticRecords saves records as JSON Lines, one request per line.
+func writeDiagnosticRecords(path string, records []DiagnosticRecord) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// loadDiagnosticRecords reads a records file written by writeDiagnosticRecords.
+func loadDiagnosticRecords(path string) ([]DiagnosticRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var records []DiagnosticRecord
+	dec := json.NewDecoder(f)
+	for dec.More() {
+		var r DiagnosticRecord
+		if err := dec.Decode(&r); err != nil {
+			return nil, fmt.Errorf("%s: record %d: %w", path, len(records)+1, err)
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lamim/llm-api-speed/pkg/benchmark"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestErrorClass(t *testing.T) {
+	cases := map[error]string{
+		nil:                                   "",
+		&openai.APIError{HTTPStatusCode: 429}: errClassRateLimit,
+		&openai.RequestError{HTTPStatusCode: 401}:                         errClassAuth,
+		&openai.RequestError{HTTPStatusCode: 503}:                         errClassServer,
+		&openai.APIError{HTTPStatusCode: 400}:                             errClassClient,
+		errors.New("timeout exceeded"):                                    errClassTimeout,
+		fmt.Errorf("x: %w", context.DeadlineExceeded):                     errClassTimeout,
+		fmt.Errorf("%w (content length: 0 bytes)", benchmark.ErrNoTokens): errClassNoTokens,
+		errors.New("boom"):                                                errClassOther,
+	}
+	for err, want := range cases {
+		if got := errorClass(err); got != want {
+			t.Errorf("errorClass(%v) = %q, want %q", err, got, want)
+		}
+	}
+}
+
+func TestDiagnosticRecordsRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	records := []DiagnosticRecord{
+		{Worker: 1, ReqNum: 1, Mode: ModeStreaming, Start: start, End: start.Add(2 * time.Second),
+			E2ELatency: 2 * time.Second, TTFT: 300 * time.Millisecond, Throughput: 80, Tokens: 136},
+		{Worker: 2, ReqNum: 1, Mode: ModeToolCalling, Start: start, End: start.Add(30 * time.Second),
+			Error: "timeout exceeded", ErrorClass: errClassTimeout},
+	}
+	config := ProviderConfig{Name: "nim", Env: "prod"}
+	name := diagnosticRecordsFile(config, "20250101-120000")
+	if name != "nim-prod-diagnostic-records-20250101-120000.jsonl" {
+		t.Errorf("records file = %q", name)
+	}
+	if err := writeDiagnosticRecords(filepath.Join(dir, name), records); err != nil {
+		t.Fatalf("writeDiagnosticRecords failed: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, name))
+	if lines := strings.Count(string(data), "\n"); lines != 2 {
+		t.Errorf("expected one line per request, got %d:\n%s", lines, data)
+	}
+
+	// Reports load the records of a saved summary, which keeps them out of its own JSON
+	summary, err := json.Marshal(DiagnosticSummary{Provider: "nim", Env: "prod", Mode: "streaming", RecordsFile: name, Records: records})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(summary), "reqNum") {
+		t.Errorf("records should not be inlined in the summary: %s", summary)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "nim-prod-diagnostic-summary-20250101-120000.json"), summary, 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, diagnostics, err := loadSessionResults(dir)
+	if err != nil || len(diagnostics) != 1 {
+		t.Fatalf("loadSessionResults = %v, %v", diagnostics, err)
+	}
+	got := diagnostics[0].Records
+	if len(got) != 2 || got[0].TTFT != 300*time.Millisecond || !got[1].End.Equal(start.Add(30*time.Second)) || got[1].ErrorClass != errClassTimeout {
+		t.Errorf("records not restored: %+v", got)
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// referenceRow is one externally published benchmark figure.
+type referenceRow struct {
+	provider   string
+	model      string
+	throughput float64
+	ttft       time.Duration
+}
+
+// referenceSource is the file name shown in reports, and referenceRows the rows
+// loaded from it via --reference.
+var (
+	referenceSource string
+	referenceRows   []referenceRow
+)
+
+// referenceColumns maps a logical column to header fragments that identify it,
+// covering ArtificialAnalysis-style exports and simple hand-written CSVs.
+var referenceColumns = map[string][]string{
+	"provider":   {"provider", "api provider", "host"},
+	"model":      {"model"},
+	"throughput": {"output speed", "tokens/s", "tokens per second", "throughput", "tok/s"},
+	"ttft":       {"first chunk", "first token", "ttft", "latency"},
+}
+
+// referenceColumnOrder is the order in which logical columns are tried for a header.
+var referenceColumnOrder = []string{"provider", "model", "throughput", "ttft"}
+
+// matchReferenceColumn returns the first logical column, not yet assigned, that a
+// lowercased header name identifies.
+func matchReferenceColumn(name string, assigned map[string]int) (string, bool) {
+	for _, key := range referenceColumnOrder {
+		if _, taken := assigned[key]; taken {
+			continue
+		}
+		for _, fragment := range referenceColumns[key] {
+			if strings.Contains(name, fragment) {
+				return key, true
+			}
+		}
+	}
+	return "", false
+}
+
+// loadReferenceCSV reads third-party benchmark figures from a CSV with a header row.
+// Columns are matched by name; throughput is tokens/s and TTFT is seconds unless
+// the header mentions "ms".
+func loadReferenceCSV(path string) ([]referenceRow, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("error opening reference file: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return parseReferenceCSV(f)
+}
+
+// parseReferenceCSV parses reference rows from CSV data.
+func parseReferenceCSV(r io.Reader) ([]referenceRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading reference header: %w", err)
+	}
+	columns := make(map[string]int)
+	ttftInMs := false
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if key, ok := matchReferenceColumn(name, columns); ok {
+			columns[key] = i
+			if key == "ttft" {
+				ttftInMs = strings.Contains(name, "ms")
+			}
+		}
+	}
+	if _, ok := columns["model"]; !ok {
+		return nil, errors.New("reference file has no model column")
+	}
+	_, hasThroughput := columns["throughput"]
+	_, hasTTFT := columns["ttft"]
+	if !hasThroughput && !hasTTFT {
+		return nil, errors.New("reference file has neither a throughput nor a TTFT column")
+	}
+
+	field := func(record []string, key string) string {
+		i, ok := columns[key]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []referenceRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading reference row: %w", err)
+		}
+		row := referenceRow{provider: field(record, "provider"), model: field(record, "model")}
+		if row.model == "" {
+			continue
+		}
+		if v, err := strconv.ParseFloat(field(record, "throughput"), 64); err == nil {
+			row.throughput = v
+		}
+		if v, err := strconv.ParseFloat(field(record, "ttft"), 64); err == nil {
+			if ttftInMs {
+				row.ttft = time.Duration(v * float64(time.Millisecond))
+			} else {
+				row.ttft = time.Duration(v * float64(time.Second))
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// normalizeName lowercases s and drops everything but letters and digits.
+func normalizeName(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// namesMatch reports whether two names refer to the same thing after normalizing,
+// allowing one to contain the other (e.g. "nim" and "NVIDIA NIM").
+func namesMatch(a, b string) bool {
+	a, b = normalizeName(a), normalizeName(b)
+	if a == "" || b == "" {
+		return false
+	}
+	return a == b || strings.Contains(a, b) || strings.Contains(b, a)
+}
+
+// findReference returns the reference row for a provider and model. The model is
+// compared without its organization prefix ("moonshotai/kimi-k2" -> "kimi-k2"). A
+// row without a provider matches any provider running that model.
+func findReference(rows []referenceRow, provider, model string) (referenceRow, bool) {
+	shortModel := model[strings.LastIndex(model, "/")+1:]
+	var fallback *referenceRow
+	for i, row := range rows {
+		if !namesMatch(row.model, shortModel) {
+			continue
+		}
+		if row.provider != "" && namesMatch(row.provider, provider) {
+			return row, true
+		}
+		if row.provider == "" && fallback == nil {
+			fallback = &rows[i]
+		}
+	}
+	if fallback != nil {
+		return *fallback, true
+	}
+	return referenceRow{}, false
+}
+
+// referenceMeasurement is a locally measured figure to compare with a reference.
+type referenceMeasurement struct {
+	provider   string
+	model      string
+	mode       string
+	ttft       time.Duration
+	throughput float64
+}
+
+// formatDelta renders the relative difference of measured against reference.
+func formatDelta(measured, reference float64) string {
+	if reference <= 0 || measured <= 0 {
+		return NotAvailable
+	}
+	return fmt.Sprintf("%+.0f%%", 100*(measured-reference)/reference)
+}
+
+// writeReferenceRows renders the external reference comparison table.
+func writeReferenceRows(report *strings.Builder, measurements []referenceMeasurement) {
+	if len(referenceRows) == 0 {
+		return
+	}
+	type pair struct {
+		m   referenceMeasurement
+		ref referenceRow
+	}
+	matched := make([]pair, 0)
+	for _, m := range measurements {
+		if ref, ok := findReference(referenceRows, m.provider, m.model); ok {
+			matched = append(matched, pair{m, ref})
+		}
+	}
+
+	report.WriteString("## External Reference Comparison\n\n")
+	fmt.Fprintf(report, "Reference figures from `%s`. Differences in prompt, region, and time of day make these indicative only.\n\n", referenceSource)
+	if len(matched) == 0 {
+		report.WriteString("*No tested provider/model matched a row in the reference file.*\n\n")
+		return
+	}
+	report.WriteString("| Provider | Model | Mode | Throughput | Reference Throughput | Δ | TTFT | Reference TTFT | Δ |\n")
+	report.WriteString("|----------|-------|------|------------|----------------------|---|------|----------------|---|\n")
+	for _, p := range matched {
+		refThroughput, refTTFT := NotAvailable, NotAvailable
+		if p.ref.throughput > 0 {
+			refThroughput = fmt.Sprintf("%.2f tok/s", p.ref.throughput)
+		}
+		if p.ref.ttft > 0 {
+			refTTFT = formatDuration(p.ref.ttft)
+		}
+		fmt.Fprintf(report, "| %s | %s | %s | %.2f tok/s | %s | %s | %s | %s | %s |\n",
+			p.m.provider, p.m.model, p.m.mode,
+			p.m.throughput, refThroughput, formatDelta(p.m.throughput, p.ref.throughput),
+			formatDuration(p.m.ttft), refTTFT, formatDelta(float64(p.m.ttft), float64(p.ref.ttft)))
+	}
+	report.WriteString("\n")
+}
+
+// writeReferenceSection compares successful results with the --reference file.
+func writeReferenceSection(report *strings.Builder, results []TestResult) {
+	measurements := make([]referenceMeasurement, 0, len(results))
+	for _, r := range results {
+		if r.Success {
+			measurements = append(measurements, referenceMeasurement{r.Provider, r.Model, r.Mode, r.TTFT, r.Throughput})
+		}
+	}
+	writeReferenceRows(report, measurements)
+}
+
+// writeDiagnosticReferenceSection is the diagnostic-report counterpart of
+// writeReferenceSection.
+func writeDiagnosticReferenceSection(report *strings.Builder, results []DiagnosticSummary) {
+	measurements := make([]referenceMeasurement, 0, len(results))
+	for _, r := range results {
+		if r.Successful > 0 {
+			measurements = append(measurements, referenceMeasurement{r.Provider, r.Model, r.Mode, r.AvgTTFT, r.AvgThroughput})
+		}
+	}
+	writeReferenceRows(report, measurements)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseReferenceCSV(t *testing.T) {
+	data := "API Provider,Model,Median Output Speed (tokens/s),Latency (First Chunk) (s)\n" +
+		"NVIDIA NIM,Kimi-K2-Instruct,45.5,0.8\n" +
+		"Novita,Kimi-K2-Instruct,60,1.25\n" +
+		",GLM-4.6,90,\n"
+	rows, err := parseReferenceCSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("parseReferenceCSV failed: %v", err)
+	}
+	if len(rows) != 3 || rows[1].throughput != 60 || rows[1].ttft != 1250*time.Millisecond || rows[2].ttft != 0 {
+		t.Fatalf("unexpected rows: %+v", rows)
+	}
+
+	ref, ok := findReference(rows, "nim", "moonshotai/Kimi-K2-Instruct")
+	if !ok || ref.throughput != 45.5 {
+		t.Fatalf("expected the NIM row for nim, got %+v (ok=%t)", ref, ok)
+	}
+	if ref, ok := findReference(rows, "nebius", "zai-org/GLM-4.6"); !ok || ref.throughput != 90 {
+		t.Fatalf("expected the provider-less row to match any provider, got %+v (ok=%t)", ref, ok)
+	}
+	if _, ok := findReference(rows, "nebius", "moonshotai/Kimi-K2-Instruct"); ok {
+		t.Fatalf("expected no match for an unlisted provider")
+	}
+
+	if _, err := parseReferenceCSV(strings.NewReader("Provider,Model\nnim,x\n")); err == nil {
+		t.Fatalf("expected an error when no metric columns are present")
+	}
+	msRows, err := parseReferenceCSV(strings.NewReader("model,ttft (ms)\nx,250\n"))
+	if err != nil || msRows[0].ttft != 250*time.Millisecond {
+		t.Fatalf("expected TTFT in milliseconds, got %+v (%v)", msRows, err)
+	}
+}
+
+func TestWriteReferenceSection(t *testing.T) {
+	savedRows, savedSource := referenceRows, referenceSource
+	defer func() { referenceRows, referenceSource = savedRows, savedSource }()
+	referenceRows = []referenceRow{{provider: "Novita", model: "kimi-k2", throughput: 50, ttft: time.Second}}
+	referenceSource = "aa.csv"
+
+	var report strings.Builder
+	writeReferenceSection(&report, []TestResult{
+		{Provider: "novita", Model: "moonshotai/kimi-k2", Mode: "streaming", Success: true, Throughput: 75, TTFT: 500 * time.Millisecond},
+	})
+	out := report.String()
+	if !strings.Contains(out, "## External Reference Comparison") || !strings.Contains(out, "| +50% |") || !strings.Contains(out, "| -50% |") {
+		t.Fatalf("unexpected reference section:\n%s", out)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Sources of a result's completion token counts.
+const (
+	tokenSourceProvider = "provider"
+	tokenSourceTiktoken = "tiktoken"
+	tokenSourceMixed    = "mixed"
+)
+
+// runTokens is a run's completion tokens counted locally and as reported by
+// the provider (0 when it reported none).
+type runTokens struct {
+	local, reported int
+}
+
+// tokenCountTracker records both token counts of each run, since the run
+// functions only return the count throughput was based on.
+type tokenCountTracker struct {
+	mu   sync.Mutex
+	runs map[RunContext]runTokens
+}
+
+// sessionTokenCounts is the token count tracker shared by every provider in
+// the session.
+var sessionTokenCounts = &tokenCountTracker{runs: make(map[RunContext]runTokens)}
+
+// record stores the counts of run, replacing any earlier attempt.
+func (t *tokenCountTracker) record(run RunContext, local, reported int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.runs[run] = runTokens{local, reported}
+}
+
+// counts returns the counts recorded for run.
+func (t *tokenCountTracker) counts(run RunContext) runTokens {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.runs[run]
+}
+
+// TokenCounts compares the completion tokens a provider reported with the
+// local tiktoken count, which is only exact for models using cl100k_base.
+type TokenCounts struct {
+	// Source is what the result's token counts and throughput are based on:
+	// "provider", "tiktoken", or "mixed" when only some runs reported usage.
+	Source string `json:"source"`
+	// Local averages the tiktoken count over the successful runs.
+	Local int `json:"localCompletionTokens"`
+	// Reported averages the provider's count over the runs that reported one.
+	Reported     int `json:"reportedCompletionTokens,omitempty"`
+	ReportedRuns int `json:"reportedRuns"`
+	// DeltaPercent is how far the provider's count is from tiktoken's over
+	// the runs that reported one; positive when the provider counts more.
+	DeltaPercent float64 `json:"deltaPercent,omitempty"`
+}
+
+// tokenCounts summarizes both counts of the successful runs, or returns nil
+// when none succeeded.
+func tokenCounts(runs []RunSample) *TokenCounts {
+	var c TokenCounts
+	successful, localSum, reportedSum, localOfReported := 0, 0, 0, 0
+	for _, r := range runs {
+		if !r.Success {
+			continue
+		}
+		successful++
+		localSum += r.LocalTokens
+		if r.ReportedTokens > 0 {
+			c.ReportedRuns++
+			reportedSum += r.ReportedTokens
+			localOfReported += r.LocalTokens
+		}
+	}
+	if successful == 0 {
+		return nil
+	}
+	c.Local = localSum / successful
+	switch c.ReportedRuns {
+	case 0:
+		c.Source = tokenSourceTiktoken
+		return &c
+	case successful:
+		c.Source = tokenSourceProvider
+	default:
+		c.Source = tokenSourceMixed
+	}
+	c.Reported = reportedSum / c.ReportedRuns
+	if localOfReported > 0 {
+		c.DeltaPercent = (float64(reportedSum)/float64(localOfReported) - 1) * 100
+	}
+	return &c
+}
+
+// writeTokenCountSection compares reported and local token counts for results
+// whose provider reported usage.
+func writeTokenCountSection(report *strings.Builder, results []TestResult) {
+	rows := make([]TestResult, 0, len(results))
+	for _, r := range results {
+		if r.Success && r.TokenCounts != nil && r.TokenCounts.ReportedRuns > 0 {
+			rows = append(rows, r)
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+	report.WriteString("## Reported vs Counted Tokens\n\n")
+	fmt.Fprintf(report, "Completion tokens as reported by the provider and as counted locally with %s. "+
+		"Token counts and throughput above use the provider's figure where there is one; "+
+		"a large delta means tiktoken is not the model's tokenizer.\n\n", primaryEncoding)
+	report.WriteString("| Provider | Model | Mode | Source | Runs Reported | Provider Tokens | tiktoken Tokens | Delta |\n")
+	report.WriteString("|----------|-------|------|--------|---------------|-----------------|-----------------|-------|\n")
+	for _, r := range rows {
+		c := r.TokenCounts
+		fmt.Fprintf(report, "| %s | %s | %s | %s | %d | %d | %d | %+.1f%% |\n",
+			providerLabel(r.Provider, r.Env), r.Model, r.Mode, c.Source, c.ReportedRuns, c.Reported, c.Local, c.DeltaPercent)
+	}
+	report.WriteString("\n")
+}
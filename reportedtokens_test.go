@@ -0,0 +1,52 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestTokenCounts(t *testing.T) {
+	if tokenCounts([]RunSample{{Success: false, LocalTokens: 10}}) != nil {
+		t.Fatal("expected no counts without successful runs")
+	}
+
+	local := tokenCounts([]RunSample{{Success: true, LocalTokens: 100}, {Success: true, LocalTokens: 200}})
+	if local.Source != tokenSourceTiktoken || local.Local != 150 || local.ReportedRuns != 0 || local.DeltaPercent != 0 {
+		t.Fatalf("unexpected tiktoken-only counts %+v", local)
+	}
+
+	mixed := tokenCounts([]RunSample{
+		{Success: true, LocalTokens: 100, ReportedTokens: 120},
+		{Success: true, LocalTokens: 300},
+		{Success: false, LocalTokens: 999, ReportedTokens: 999},
+	})
+	if mixed.Source != tokenSourceMixed || mixed.Local != 200 || mixed.Reported != 120 || mixed.ReportedRuns != 1 || math.Abs(mixed.DeltaPercent-20) > 1e-9 {
+		t.Fatalf("unexpected mixed counts %+v", mixed)
+	}
+
+	provider := tokenCounts([]RunSample{{Success: true, LocalTokens: 100, ReportedTokens: 90}})
+	if provider.Source != tokenSourceProvider || provider.DeltaPercent >= 0 {
+		t.Fatalf("unexpected provider counts %+v", provider)
+	}
+}
+
+func TestTokenCountSection(t *testing.T) {
+	var report strings.Builder
+	writeTokenCountSection(&report, []TestResult{
+		{Provider: "nim", Success: true, TokenCounts: &TokenCounts{Source: tokenSourceTiktoken, Local: 100}},
+	})
+	if report.Len() != 0 {
+		t.Fatalf("expected no section without reported usage, got %q", report.String())
+	}
+
+	writeTokenCountSection(&report, []TestResult{
+		{Provider: "nim", Env: "prod", Model: "llama", Mode: "streaming", Success: true,
+			TokenCounts: &TokenCounts{Source: tokenSourceProvider, Local: 400, Reported: 460, ReportedRuns: 3, DeltaPercent: 15}},
+	})
+	got := report.String()
+	if !strings.Contains(got, "## Reported vs Counted Tokens") ||
+		!strings.Contains(got, "| nim [prod] | llama | streaming | provider | 3 | 460 | 400 | +15.0% |") {
+		t.Fatalf("unexpected section:\n%s", got)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// resultFilter selects which stored results a regenerated report covers. The
+// zero value keeps everything.
+type resultFilter struct {
+	onlySuccess   bool
+	minThroughput float64
+	// providers match a provider name or its "name (env)" label, ignoring case.
+	providers []string
+	modes     []string
+}
+
+// reportFilter is applied by generateSessionReports; set by the report
+// subcommand's filter flags.
+var reportFilter resultFilter
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// active reports whether the filter drops anything.
+func (f resultFilter) active() bool {
+	return f.onlySuccess || f.minThroughput > 0 || len(f.providers) > 0 || len(f.modes) > 0
+}
+
+// matches reports whether a result for provider/env/mode passes the provider
+// and mode filters.
+func (f resultFilter) matches(provider, env, mode string) bool {
+	if len(f.modes) > 0 && !containsFold(f.modes, mode) {
+		return false
+	}
+	if len(f.providers) == 0 {
+		return true
+	}
+	return containsFold(f.providers, provider) || containsFold(f.providers, providerLabel(provider, env))
+}
+
+// containsFold reports whether list holds s, ignoring case.
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// keep reports whether a standard or long-story result passes the filter. A
+// failed result has no throughput, so --min-throughput drops it too.
+func (f resultFilter) keep(r TestResult) bool {
+	if f.onlySuccess && !r.Success {
+		return false
+	}
+	if f.minThroughput > 0 && (!r.Success || r.Throughput < f.minThroughput) {
+		return false
+	}
+	return f.matches(r.Provider, r.Env, r.Mode)
+}
+
+// keepDiagnostic reports whether a diagnostic summary passes the filter.
+// --only-success drops providers none of whose requests succeeded.
+func (f resultFilter) keepDiagnostic(d DiagnosticSummary) bool {
+	if f.onlySuccess && d.Successful == 0 {
+		return false
+	}
+	if f.minThroughput > 0 && (d.Successful == 0 || d.AvgThroughput < f.minThroughput) {
+		return false
+	}
+	return f.matches(d.Provider, d.Env, d.Mode)
+}
+
+// apply returns the results and diagnostic summaries that pass the filter.
+func (f resultFilter) apply(results []TestResult, diagnostics []DiagnosticSummary) ([]TestResult, []DiagnosticSummary) {
+	if !f.active() {
+		return results, diagnostics
+	}
+	var keptResults []TestResult
+	for _, r := range results {
+		if f.keep(r) {
+			keptResults = append(keptResults, r)
+		}
+	}
+	var keptDiagnostics []DiagnosticSummary
+	for _, d := range diagnostics {
+		if f.keepDiagnostic(d) {
+			keptDiagnostics = append(keptDiagnostics, d)
+		}
+	}
+	return keptResults, keptDiagnostics
+}
+
+// String describes the active filters, e.g. "successful results only;
+// throughput ≥ 50 tok/s; provider nim".
+func (f resultFilter) String() string {
+	var parts []string
+	if f.onlySuccess {
+		parts = append(parts, "successful results only")
+	}
+	if f.minThroughput > 0 {
+		parts = append(parts, fmt.Sprintf("throughput ≥ %g tok/s", f.minThroughput))
+	}
+	if len(f.providers) > 0 {
+		parts = append(parts, "provider "+strings.Join(f.providers, ", "))
+	}
+	if len(f.modes) > 0 {
+		parts = append(parts, "mode "+strings.Join(f.modes, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// filterNote tells the reader that a report covers a slice of the session.
+func filterNote(report *strings.Builder) {
+	if reportFilter.active() {
+		report.WriteString("*Filtered: " + reportFilter.String() + ".*\n\n")
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResultFilterKeep(t *testing.T) {
+	ok := TestResult{Provider: "nim", Env: "prod", Mode: string(ModeStreaming), Success: true, Throughput: 80}
+	slow := TestResult{Provider: "novita", Mode: string(ModeToolCalling), Success: true, Throughput: 20}
+	failed := TestResult{Provider: "minimax", Mode: string(ModeStreaming), Error: "timeout exceeded"}
+
+	cases := []struct {
+		name   string
+		filter resultFilter
+		want   []bool // ok, slow, failed
+	}{
+		{"none", resultFilter{}, []bool{true, true, true}},
+		{"only success", resultFilter{onlySuccess: true}, []bool{true, true, false}},
+		{"min throughput", resultFilter{minThroughput: 50}, []bool{true, false, false}},
+		{"provider name", resultFilter{providers: []string{"NIM"}}, []bool{true, false, false}},
+		{"provider label", resultFilter{providers: []string{providerLabel("nim", "prod")}}, []bool{true, false, false}},
+		{"mode", resultFilter{modes: []string{"tool-calling"}}, []bool{false, true, false}},
+		{"combined", resultFilter{onlySuccess: true, modes: []string{"streaming"}}, []bool{true, false, false}},
+	}
+	for _, tc := range cases {
+		for i, r := range []TestResult{ok, slow, failed} {
+			if got := tc.filter.keep(r); got != tc.want[i] {
+				t.Errorf("%s: keep(%s) = %t, want %t", tc.name, r.Provider, got, tc.want[i])
+			}
+		}
+	}
+}
+
+func TestResultFilterKeepDiagnostic(t *testing.T) {
+	f := resultFilter{onlySuccess: true, minThroughput: 30}
+	if !f.keepDiagnostic(DiagnosticSummary{Provider: "nim", Successful: 3, AvgThroughput: 40}) {
+		t.Error("dropped a diagnostic summary that passes every filter")
+	}
+	if f.keepDiagnostic(DiagnosticSummary{Provider: "nim", Failed: 4}) {
+		t.Error("kept a diagnostic summary with no successful request")
+	}
+	if f.keepDiagnostic(DiagnosticSummary{Provider: "nim", Successful: 3, AvgThroughput: 10}) {
+		t.Error("kept a diagnostic summary below the throughput floor")
+	}
+}
+
+func TestGenerateSessionReportsAppliesFilter(t *testing.T) {
+	saved := reportFilter
+	defer func() { reportFilter = saved }()
+
+	session, out := t.TempDir(), t.TempDir()
+	saveResult(session, TestResult{
+		Provider: "nim", Model: "model-a", Mode: string(ModeStreaming), Success: true,
+		Timestamp: time.Unix(0, 0), TTFT: 200 * time.Millisecond, E2ELatency: time.Second, Throughput: 90,
+	})
+	saveResult(session, TestResult{
+		Provider: "novita", Model: "model-a", Mode: string(ModeStreaming),
+		Timestamp: time.Unix(60, 0), Error: "boom",
+	})
+
+	reportFilter = resultFilter{onlySuccess: true}
+	if err := generateSessionReports(out, []string{session}, "session-a"); err != nil {
+		t.Fatalf("generateSessionReports failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(out, "REPORT.md"))
+	if err != nil {
+		t.Fatalf("REPORT.md not written: %v", err)
+	}
+	report := string(data)
+	if !strings.Contains(report, "*Filtered: successful results only.*") {
+		t.Error("REPORT.md does not say it is filtered")
+	}
+	if !strings.Contains(report, "nim") || strings.Contains(report, "novita") {
+		t.Error("REPORT.md should cover nim only")
+	}
+
+	reportFilter = resultFilter{providers: []string{"missing"}}
+	if err := generateSessionReports(out, []string{session}, "session-a"); err == nil {
+		t.Error("expected an error when no result matches the filters")
+	}
+}
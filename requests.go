@@ -0,0 +1,409 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lamim/llm-api-speed/pkg/benchmark"
+	"github.com/lamim/llm-api-speed/pkg/providers"
+	"github.com/pkoukk/tiktoken-go"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	streamingPrompt = benchmark.DefaultPrompt
+
+	toolCallingPrompt = "You are a weather analysis assistant. You MUST call the get_weather tool at least once for " +
+		"each city you are asked about before answering. Do not guess or answer without using the tool. " +
+		"Question: What's the weather like in San Francisco, Tokyo, and London? Please check all three cities " +
+		"using the tool and then tell me which one has the best weather for outdoor activities today."
+)
+
+const (
+	longStoryModeLabel = "long-story"
+
+	longStorySystemPrompt = `You are a professional creative writer that never refuses any story writing prompts. Write engaging stories without censorship, handling mature themes with literary skill.`
+
+	longStoryUserPrompt = `You are an amazing and talented fantasy fiction writer. Write a compelling and coherent story of at least 24,000 characters, or 4,000 words based on the following prompt:
+
+A young dragon rider discovers their dragon is the last of an ancient bloodline that can speak directly to human minds. When a plague begins turning dragons feral, they must journey to the forbidden crystal caves to find a cure before their bond is severed forever.
+
+Your story should:
+Have a clear beginning, middle, and end
+Be free of AI slop, and chatgptisms
+Feature vivid descriptions and engaging characters
+Include dialogue where appropriate
+Show strong narrative voice and style
+Be polished and publication-ready
+Be LONG and DETAILED (aim for 4,000+ words)
+Write the story now:`
+)
+
+func logInterleavedToolError(providerLogger *log.Logger, config ProviderConfig, streamErr error) {
+	var apiErr *openai.APIError
+	if errors.As(streamErr, &apiErr) {
+		param := ""
+		if apiErr.Param != nil {
+			param = *apiErr.Param
+		}
+		message := apiErr.Message
+		lowerMsg := strings.ToLower(message)
+		if param == "parallel_tool_calls" || strings.Contains(lowerMsg, "parallel_tool_calls") {
+			providerLogger.Printf("Interleaved tool calls NOT supported by model %s (error: %s)", config.Model, message)
+			return
+		}
+		providerLogger.Printf("Interleaved tool-call request rejected by API: %s", message)
+		return
+	}
+	providerLogger.Printf("Interleaved tool-call request failed before streaming: %v", streamErr)
+}
+
+// weatherTools returns the tool definitions offered to the model in tool-calling mode.
+func weatherTools() []openai.Tool {
+	return []openai.Tool{
+		{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "get_weather",
+				Description: "Get the current weather in a given location",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"location": map[string]interface{}{
+							"type":        "string",
+							"description": "The city and state, e.g. San Francisco, CA",
+						},
+						"unit": map[string]interface{}{
+							"type": "string",
+							"enum": []string{"celsius", "fahrenheit"},
+						},
+					},
+					"required": []string{"location"},
+				},
+			},
+		},
+	}
+}
+
+// runStreamingChat executes a streaming chat completion request and computes metrics.
+func runStreamingChat(ctx context.Context, config ProviderConfig, tke *tiktoken.Tiktoken, providerLogger *log.Logger, req openai.ChatCompletionRequest) (e2e, ttft time.Duration, throughput float64, tokens int, response string, err error) {
+	providerLogger = runLogger(ctx, providerLogger, config)
+	release, err := sessionScheduler.acquire(ctx, providerLabel(config.Name, config.Env))
+	if err != nil {
+		return 0, 0, 0, 0, "", fmt.Errorf("waiting for a request slot: %w", err)
+	}
+	// The wait for a slot can outlast a shutdown request or the budget
+	if err := canStartRun(); err != nil {
+		release()
+		return 0, 0, 0, 0, "", err
+	}
+	config = nextAPIKey(config)
+	req = bustCache(config.Quirks.apply(req))
+	sessionProgress.begin()
+	defer func() {
+		sessionProgress.record(providerLabel(config.Name, config.Env), ttft, throughput, err)
+		sessionKeys.record(config, tokens, err)
+		emitRunFinished(ctx, config, e2e, ttft, throughput, tokens, err)
+	}()
+
+	sample, err := streamWithContinuation(ctx, config, tke, providerLogger, req, release)
+	if err != nil {
+		return 0, 0, 0, 0, "", err
+	}
+	sessionTokenCounts.record(runContextFrom(ctx, config), sample.LocalTokens, sample.ReportedTokens)
+	return sample.E2E, sample.TTFT, sample.Throughput, sample.Tokens, sample.Response, nil
+}
+
+// singleTestRun performs one test run and returns metrics or error.
+// The requestKey identifies the run within the session for prompt rotation.
+func singleTestRun(ctx context.Context, config ProviderConfig, tke *tiktoken.Tiktoken, providerLogger *log.Logger, requestKey string) (e2e, ttft time.Duration, throughput float64, tokens int, response string, err error) {
+	return runStreamingChat(ctx, config, tke, providerLogger, streamingRequest(config, requestKey))
+}
+
+// longStoryRun performs a single long-form story generation run and returns metrics or error.
+func longStoryRun(ctx context.Context, config ProviderConfig, tke *tiktoken.Tiktoken, providerLogger *log.Logger) (e2e, ttft time.Duration, throughput float64, tokens int, response string, err error) {
+	messages := []openai.ChatCompletionMessage{
+		{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: longStorySystemPrompt,
+		},
+		{
+			Role:    openai.ChatMessageRoleUser,
+			Content: sessionPrompt(promptGroupLongStory, longStoryUserPrompt),
+		},
+	}
+	req := openai.ChatCompletionRequest{
+		Model:     config.Model,
+		Messages:  messages,
+		MaxTokens: requestMaxTokens(defaultLongStoryMaxTokens),
+		Stream:    true,
+	}
+
+	return runStreamingChat(ctx, config, tke, providerLogger, req)
+}
+
+// benchProvider describes config as the endpoint the providers package streams from.
+func benchProvider(config ProviderConfig) providers.Provider {
+	return providers.Provider{
+		Name:           config.Name,
+		BaseURL:        config.BaseURL,
+		APIKey:         config.APIKey,
+		Model:          config.Model,
+		API:            config.API,
+		StripCitations: config.Quirks.StripCitations,
+		HTTPClient:     providerHTTPClient(config),
+		NoStreamUsage:  config.NoStreamUsage,
+	}
+}
+
+// asSample packs the metrics a run returns into a benchmark.Sample, for the
+// Send hooks of the benchmark package's runners.
+func asSample(e2e, ttft time.Duration, throughput float64, tokens int, response string, err error) (benchmark.Sample, error) {
+	return benchmark.Sample{E2E: e2e, TTFT: ttft, Throughput: throughput, Tokens: tokens, Response: response}, err
+}
+
+// singleToolCallRun performs one tool-calling test run and returns metrics or error.
+// When toolReasoningCheck is true, additional logging is produced to validate that
+// tool calls occur alongside multi-step reasoning (before and after tool use).
+func singleToolCallRun(ctx context.Context, config ProviderConfig, tke *tiktoken.Tiktoken, providerLogger *log.Logger, toolReasoningCheck bool) (e2e, ttft time.Duration, throughput float64, tokens int, response string, err error) {
+	providerLogger = runLogger(ctx, providerLogger, config)
+	release, err := sessionScheduler.acquire(ctx, providerLabel(config.Name, config.Env))
+	if err != nil {
+		return 0, 0, 0, 0, "", fmt.Errorf("waiting for a request slot: %w", err)
+	}
+	defer release()
+	// The wait for a slot can outlast a shutdown request or the budget
+	if err := canStartRun(); err != nil {
+		return 0, 0, 0, 0, "", err
+	}
+	config = nextAPIKey(config)
+	sessionProgress.begin()
+	defer func() {
+		sessionProgress.record(providerLabel(config.Name, config.Env), ttft, throughput, err)
+		sessionKeys.record(config, tokens, err)
+		emitRunFinished(ctx, config, e2e, ttft, throughput, tokens, err)
+	}()
+
+	tools := weatherTools()
+
+	messages := []openai.ChatCompletionMessage{
+		{
+			Role:    openai.ChatMessageRoleUser,
+			Content: sessionPrompt(promptGroupToolCalling, toolCallingPrompt),
+		},
+	}
+
+	req := openai.ChatCompletionRequest{
+		Model:     config.Model,
+		Messages:  messages,
+		Tools:     tools,
+		MaxTokens: requestMaxTokens(defaultMaxTokens),
+		Stream:    true,
+	}
+	req.ToolChoice = toolChoiceParam(config.ToolChoice)
+	if toolReasoningCheck {
+		req.ParallelToolCalls = true
+	}
+	req = bustCache(config.Quirks.apply(req))
+	if err := checkContextWindow(config, tke, providerLogger, req); err != nil {
+		return 0, 0, 0, 0, "", err
+	}
+
+	// Execute the stream and measure metrics
+	startTime := time.Now()
+	var firstTokenTime time.Time
+	var fullResponseContent strings.Builder
+
+	stream, streamErr := providers.OpenStream(ctx, benchProvider(config), req)
+	if streamErr != nil {
+		if toolReasoningCheck {
+			logInterleavedToolError(providerLogger, config, streamErr)
+		}
+		return 0, 0, 0, 0, "", fmt.Errorf("error creating stream: %w", streamErr)
+	}
+	sessionKeys.observe(config, stream.RateLimit())
+	defer func() {
+		if closeErr := stream.Close(); closeErr != nil {
+			providerLogger.Printf("Warning: Failed to close stream: %v", closeErr)
+		}
+	}()
+
+	providerLogger.Println("... Tool calling request sent. Waiting for stream ...")
+
+	chunkCount := 0
+	nonEmptyChunks := 0
+	reasoningChunks := 0
+	toolCallChunks := 0
+	streamReportedToolCalls := false
+	streamInterleavedContent := false
+	streamInterleavedReasoning := false
+	chunkIndex := 0
+	reasoningBeforeTools := false
+	reasoningAfterTools := false
+	inToolPhase := false
+	toolPhaseCount := 0
+	reportedTokens := 0
+	var toolArgs toolArgsTimer
+
+	for {
+		delta, recvErr := stream.Recv()
+
+		// Check for end of stream
+		if errors.Is(recvErr, io.EOF) {
+			providerLogger.Printf(
+				"... Tool calling stream complete. Received %d chunks (%d content, %d reasoning, %d tool)",
+				chunkCount, nonEmptyChunks, reasoningChunks, toolCallChunks)
+			break
+		}
+
+		if recvErr != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				return 0, 0, 0, 0, "", fmt.Errorf("timeout exceeded")
+			}
+			return 0, 0, 0, 0, "", fmt.Errorf("stream error: %w", recvErr)
+		}
+
+		chunkCount++
+		chunkIndex++
+		if delta.CompletionTokens > 0 {
+			reportedTokens = delta.CompletionTokens
+		}
+
+		// Skip chunks without content (role-only chunks, keep-alives, ...)
+		if delta.Empty() {
+			// Log occasionally for debugging (every 100 chunks), not every single one
+			if chunkCount%100 == 0 {
+				providerLogger.Printf("... Chunk %d: Empty delta", chunkCount)
+			}
+			continue
+		}
+
+		// Check for first token (content, reasoning, or tool call)
+		hasContent := delta.Content != ""
+		hasReasoningContent := delta.Reasoning != ""
+		hasToolCall := len(delta.ToolCalls) > 0
+
+		if (hasContent || hasReasoningContent || hasToolCall) && firstTokenTime.IsZero() {
+			firstTokenTime = time.Now()
+			switch {
+			case hasReasoningContent:
+				providerLogger.Printf(
+					"... First token received (reasoning, tool-calling)! (chunk %d)", chunkCount)
+			case hasToolCall:
+				providerLogger.Printf("... First token received (tool-call)! (chunk %d)", chunkCount)
+			default:
+				providerLogger.Printf("... First token received (tool-calling)! (chunk %d)", chunkCount)
+			}
+		}
+
+		// Append content if present
+		if hasContent {
+			nonEmptyChunks++
+			fullResponseContent.WriteString(delta.Content)
+		}
+
+		// Append reasoning content if present
+		if hasReasoningContent {
+			reasoningChunks++
+			fullResponseContent.WriteString(delta.Reasoning)
+		}
+
+		// Append tool call information as text for token counting
+		if hasToolCall {
+			toolCallChunks++
+			streamReportedToolCalls = true
+			toolArgs.observe(delta.ToolCalls, time.Now())
+			if hasContent {
+				streamInterleavedContent = true
+			}
+			if hasReasoningContent {
+				streamInterleavedReasoning = true
+			}
+			for _, toolCall := range delta.ToolCalls {
+				if toolCall.Function.Name != "" {
+					fullResponseContent.WriteString(toolCall.Function.Name)
+				}
+				if toolCall.Function.Arguments != "" {
+					fullResponseContent.WriteString(toolCall.Function.Arguments)
+				}
+			}
+		}
+
+		// Track reasoning relative to tool-call phases for behavioral checks
+		if hasToolCall {
+			if !inToolPhase {
+				inToolPhase = true
+				toolPhaseCount++
+			}
+		} else {
+			inToolPhase = false
+		}
+
+		if hasReasoningContent {
+			if !streamReportedToolCalls {
+				reasoningBeforeTools = true
+			} else if !hasToolCall {
+				reasoningAfterTools = true
+			}
+		}
+	}
+
+	endTime := time.Now()
+
+	if toolReasoningCheck {
+		reasoningCheckPass := streamReportedToolCalls && reasoningBeforeTools && reasoningAfterTools
+		providerLogger.Printf("Tool-reasoning summary: toolCallsObserved=%t reasoningBeforeTools=%t reasoningAfterTools=%t toolPhases=%d pass=%t", streamReportedToolCalls, reasoningBeforeTools, reasoningAfterTools, toolPhaseCount, reasoningCheckPass)
+		providerLogger.Printf("Interleaved tool-call summary: interleavedContent=%t interleavedReasoning=%t", streamInterleavedContent, streamInterleavedReasoning)
+	}
+
+	if firstTokenTime.IsZero() {
+		return 0, 0, 0, 0, "", fmt.Errorf("no content received from API (received %d chunks)", chunkCount)
+	}
+
+	// Count tokens locally, preferring the provider's own count when it sent one
+	fullResponse := fullResponseContent.String()
+	localTokens := len(tke.Encode(fullResponse, nil, nil))
+	if toolCallChunks == 0 {
+		providerLogger.Println("Warning: no tool calls were observed in tool-calling mode (model returned only text/reasoning)")
+		return 0, 0, 0, 0, fullResponse, fmt.Errorf("no tool calls observed in tool-calling mode")
+	}
+
+	completionTokens := localTokens
+	if reportedTokens > 0 {
+		completionTokens = reportedTokens
+		providerLogger.Printf("... Total content length: %d bytes, %d tokens (provider reported %d)",
+			len(fullResponse), localTokens, reportedTokens)
+	} else {
+		providerLogger.Printf("... Total content length: %d bytes, %d tokens", len(fullResponse), localTokens)
+	}
+	sessionBudget.record(config, countPromptTokens(tke, req.Messages), completionTokens)
+
+	if localTokens == 0 {
+		return 0, 0, 0, 0, "", fmt.Errorf("received 0 tokens (content length: %d bytes)", len(fullResponse))
+	}
+	sessionTokenCounts.record(runContextFrom(ctx, config), localTokens, reportedTokens)
+	if stats := toolArgs.stats(); stats != nil {
+		providerLogger.Printf("... Tool arguments: %d call(s) parseable after %s on average (max %s), %d incomplete",
+			stats.Calls, formatDuration(stats.Avg), formatDuration(stats.Max), stats.Incomplete)
+		sessionStreams.addToolArgs(providerLabel(config.Name, config.Env), stats)
+	}
+
+	// Calculate metrics
+	e2eLatency := endTime.Sub(startTime)
+	ttftLatency := firstTokenTime.Sub(startTime)
+	generationTime := e2eLatency - ttftLatency
+
+	var throughputVal float64
+	if generationTime.Seconds() <= 0 {
+		throughputVal = 0.0
+	} else {
+		throughputVal = (float64(completionTokens) - 1.0) / generationTime.Seconds()
+	}
+
+	return e2eLatency, ttftLatency, throughputVal, completionTokens, fullResponse, nil
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// resultsRoot is the folder that holds session folders and the leaderboard;
+// set with --results-dir.
+var resultsRoot = "results"
+
+// loadSessionResults reads the per-provider result files saved in a session
+// folder. Standard and long-story runs are stored as TestResult files, diagnostic
+// runs as *-diagnostic-summary-*.json; other JSON files (manifest, mode
+// summaries) are ignored.
+func loadSessionResults(sessionDir string) ([]TestResult, []DiagnosticSummary, error) {
+	files, err := filepath.Glob(filepath.Join(sessionDir, "*.json"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listing session files: %w", err)
+	}
+	sort.Strings(files)
+
+	var results []TestResult
+	var diagnostics []DiagnosticSummary
+	for _, file := range files {
+		if filepath.Base(file) == manifestFileName {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Clean(file))
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading %s: %w", file, err)
+		}
+
+		if strings.Contains(filepath.Base(file), "-diagnostic-summary-") {
+			var summary DiagnosticSummary
+			if err := json.Unmarshal(data, &summary); err != nil {
+				return nil, nil, fmt.Errorf("error parsing %s: %w", file, err)
+			}
+			if summary.RecordsFile != "" {
+				records, err := loadDiagnosticRecords(filepath.Join(sessionDir, filepath.Base(summary.RecordsFile)))
+				if err != nil {
+					log.Printf("Warning: per-request records of %s not loaded: %v", filepath.Base(file), err)
+				}
+				summary.Records = records
+			}
+			diagnostics = append(diagnostics, summary)
+			continue
+		}
+
+		var result TestResult
+		if err := json.Unmarshal(data, &result); err != nil || result.Provider == "" || result.Mode == "" {
+			continue
+		}
+		results = append(results, result)
+	}
+	return results, diagnostics, nil
+}
+
+// generateSessionReports writes REPORT.md and/or DIAGNOSTIC-REPORT.md to outDir
+// from the result files saved in sessionDirs. Reports are always built from what
+// is on disk, so a finished session or a merged set of sessions can be reported
+// on again without rerunning any tests.
+func generateSessionReports(outDir string, sessionDirs []string, label string) error {
+	var results []TestResult
+	var diagnostics []DiagnosticSummary
+	for _, dir := range sessionDirs {
+		r, d, err := loadSessionResults(dir)
+		if err != nil {
+			return err
+		}
+		results = append(results, r...)
+		diagnostics = append(diagnostics, d...)
+	}
+	if len(results)+len(diagnostics) == 0 {
+		return fmt.Errorf("no results found in %s", strings.Join(sessionDirs, ", "))
+	}
+	results, diagnostics = reportFilter.apply(results, diagnostics)
+	if len(results)+len(diagnostics) == 0 {
+		return fmt.Errorf("no results in %s match the filters (%s)", strings.Join(sessionDirs, ", "), reportFilter)
+	}
+	if blindReports {
+		mapping := blindResults(results, diagnostics, rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())))
+		if err := writeBlindMapping(outDir, mapping); err != nil {
+			return err
+		}
+	}
+
+	if len(results) > 0 {
+		if err := generateMarkdownReport(outDir, results, label); err != nil {
+			return err
+		}
+	}
+	if len(diagnostics) > 0 {
+		if err := generateDiagnosticReport(outDir, diagnostics, label); err != nil {
+			return err
+		}
+	}
+	return printSessionSummary(outDir, results, diagnostics)
+}
+
+// sessionTargetTokens returns the --target-tokens value recorded in the first
+// session manifest that has one.
+func sessionTargetTokens(sessions []string) (int, bool) {
+	for _, session := range sessions {
+		manifest, err := loadManifest(session)
+		if err != nil {
+			continue
+		}
+		if n, err := strconv.Atoi(manifest.Flags["target-tokens"]); err == nil {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// runReport implements the "report" subcommand: it regenerates reports from one
+// or more saved sessions, merging their results when several are given.
+func runReport(args []string) {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	out := fs.String("out", "", "Output directory (default: the session itself, or <results-dir>/merged-<timestamp> for several)")
+	fs.StringVar(&resultsRoot, "results-dir", resultsRoot, "Folder bare session names are looked up in")
+	reference := fs.String("reference", "", "CSV of third-party benchmark figures to compare against")
+	target := fs.Int("target-tokens", 0, "Target token count for projected E2E (default: as recorded in the session manifest)")
+	blind := fs.Bool("blind", false, "Replace provider names with Provider A/B/C; the mapping is saved privately to "+blindMappingFileName)
+	onlySuccess := fs.Bool("only-success", false, "Leave out failed results (and diagnostic runs with no successful request)")
+	minThroughput := fs.Float64("min-throughput", 0, "Leave out results below this throughput in tokens/s")
+	provider := fs.String("provider", "", "Only report these providers (comma-separated names or \"name (env)\" labels)")
+	mode := fs.String("mode", "", "Only report these modes (comma-separated, e.g. streaming,tool-calling)")
+	badge := fs.Bool("badge", false, "Also write "+badgeFileName+" naming the highest-throughput provider")
+	configPath := fs.String("config", "", "TOML config file whose [[column]] entries add computed report columns and [[threshold]] entries grade cells")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: llm-api-speed report [--out dir] [--reference file] [--target-tokens n] [--blind] [--badge] [--config file] [filters] <session>...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		log.Fatalf("Error parsing flags: %v", err)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	sessions := fs.Args()
+	sessionDirs := make([]string, len(sessions))
+	names := make([]string, len(sessions))
+	for i, session := range sessions {
+		sessionDirs[i] = resolveSessionDir(session)
+		if info, err := os.Stat(sessionDirs[i]); err != nil || !info.IsDir() {
+			log.Fatalf("Error: session %s not found", session)
+		}
+		names[i] = filepath.Base(sessionDirs[i])
+	}
+
+	if *minThroughput < 0 {
+		log.Fatal("Error: --min-throughput must not be negative")
+	}
+	blindReports = *blind
+	writeBadge = *badge
+	sessionTags = manifestTags(sessions)
+	sessionSkipped = manifestSkipped(sessions)
+	sessionProviderNotes = manifestProviderNotes(sessions)
+	sessionModelMetadata = manifestModelMetadata(sessions)
+	if *configPath != "" {
+		cfg, err := loadConfigFile(*configPath)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		sessionColumns = cfg.Columns
+		sessionThresholds = cfg.Thresholds
+	}
+	reportFilter = resultFilter{
+		onlySuccess:   *onlySuccess,
+		minThroughput: *minThroughput,
+		providers:     splitList(*provider),
+		modes:         splitList(*mode),
+	}
+	targetTokens = *target
+	if targetTokens == 0 {
+		if n, ok := sessionTargetTokens(sessions); ok {
+			targetTokens = n
+		}
+	}
+
+	if *reference != "" {
+		rows, err := loadReferenceCSV(*reference)
+		if err != nil {
+			log.Fatalf("Error: --reference: %v", err)
+		}
+		referenceSource = filepath.Base(*reference)
+		referenceRows = rows
+	}
+
+	outDir := *out
+	switch {
+	case outDir != "":
+	case len(sessionDirs) == 1:
+		outDir = sessionDirs[0]
+	default:
+		outDir = filepath.Join(resultsRoot, "merged-"+time.Now().Format("20060102-150405"))
+	}
+	if err := os.MkdirAll(outDir, 0750); err != nil {
+		log.Fatalf("Error creating output directory: %v", err)
+	}
+
+	err := generateSessionReports(outDir, sessionDirs, strings.Join(names, ", "))
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	log.Printf("Reports for %d session(s) written to %s/", len(sessionDirs), outDir)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateSessionReportsMergesSessions(t *testing.T) {
+	first, second, out := t.TempDir(), t.TempDir(), t.TempDir()
+	saveResult(first, TestResult{
+		Provider: "nim", Model: "model-a", Mode: string(ModeStreaming), Success: true,
+		Timestamp: time.Unix(0, 0), TTFT: 200 * time.Millisecond, E2ELatency: time.Second, Throughput: 90,
+	})
+	saveResult(second, TestResult{
+		Provider: "novita", Model: "model-a", Mode: string(ModeStreaming), Success: true,
+		Timestamp: time.Unix(60, 0), TTFT: 400 * time.Millisecond, E2ELatency: 2 * time.Second, Throughput: 45,
+	})
+	data, err := json.Marshal(DiagnosticSummary{Provider: "nim", Model: "model-a", Mode: string(ModeStreaming), TotalRequests: 3, Successful: 3})
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(second, "nim-diagnostic-summary-20250101-000000.json"), data, 0600); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	if err := generateSessionReports(out, []string{first, second}, "session-a, session-b"); err != nil {
+		t.Fatalf("generateSessionReports failed: %v", err)
+	}
+	report, err := os.ReadFile(filepath.Join(out, "REPORT.md"))
+	if err != nil {
+		t.Fatalf("REPORT.md not written: %v", err)
+	}
+	for _, want := range []string{"session-a, session-b", "nim", "novita"} {
+		if !strings.Contains(string(report), want) {
+			t.Errorf("REPORT.md missing %q", want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(out, "DIAGNOSTIC-REPORT.md")); err != nil {
+		t.Errorf("DIAGNOSTIC-REPORT.md not written: %v", err)
+	}
+
+	if err := generateSessionReports(out, []string{t.TempDir()}, "empty"); err == nil {
+		t.Error("expected an error for a session without results")
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+const routedModeLabel = "routed"
+
+// routeMember is one real provider in a weighted routing pool.
+type routeMember struct {
+	name   string
+	weight float64
+}
+
+// parseRoutePool parses a pool spec such as "nim=3,novita=1". A member without
+// an explicit weight gets weight 1.
+func parseRoutePool(spec string) ([]routeMember, error) {
+	var members []routeMember
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, rawWeight, hasWeight := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		weight := 1.0
+		if hasWeight {
+			w, err := strconv.ParseFloat(strings.TrimSpace(rawWeight), 64)
+			if err != nil || w <= 0 {
+				return nil, fmt.Errorf("invalid weight %q for provider %q", rawWeight, name)
+			}
+			weight = w
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("provider %q listed twice in route pool", name)
+		}
+		seen[name] = true
+		members = append(members, routeMember{name: name, weight: weight})
+	}
+	if len(members) < 2 {
+		return nil, fmt.Errorf("route pool needs at least two providers, got %d", len(members))
+	}
+	return members, nil
+}
+
+// pickRouteMember samples a member in proportion to its weight.
+func pickRouteMember(rng *rand.Rand, members []routeMember) routeMember {
+	total := 0.0
+	for _, m := range members {
+		total += m.weight
+	}
+	target := rng.Float64() * total
+	for _, m := range members {
+		target -= m.weight
+		if target < 0 {
+			return m
+		}
+	}
+	return members[len(members)-1]
+}
+
+// RouteSample is one request sent through the emulated router.
+type RouteSample struct {
+	Iteration  int           `json:"iteration"`
+	Provider   string        `json:"provider"`
+	TTFT       time.Duration `json:"ttft"`
+	E2E        time.Duration `json:"e2e"`
+	Tokens     int           `json:"tokens"`
+	Throughput float64       `json:"throughput"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// LatencyDistribution summarizes latency percentiles over a set of requests.
+type LatencyDistribution struct {
+	Requests      int           `json:"requests"`
+	Failures      int           `json:"failures"`
+	P50TTFT       time.Duration `json:"p50Ttft"`
+	P90TTFT       time.Duration `json:"p90Ttft"`
+	P99TTFT       time.Duration `json:"p99Ttft"`
+	P50E2E        time.Duration `json:"p50E2e"`
+	P90E2E        time.Duration `json:"p90E2e"`
+	P99E2E        time.Duration `json:"p99E2e"`
+	AvgThroughput float64       `json:"avgThroughput"`
+}
+
+// RouteSummary compares the blended routed distribution with its constituents.
+type RouteSummary struct {
+	Pool         map[string]float64             `json:"pool"`
+	Samples      []RouteSample                  `json:"samples"`
+	Blended      LatencyDistribution            `json:"blended"`
+	Constituents map[string]LatencyDistribution `json:"constituents"`
+}
+
+// summarizeRouteSamples computes the latency distribution of the given samples.
+func summarizeRouteSamples(samples []RouteSample) LatencyDistribution {
+	dist := LatencyDistribution{Requests: len(samples)}
+	var ttfts, e2es []time.Duration
+	throughputSum := 0.0
+	for _, s := range samples {
+		if s.Error != "" {
+			dist.Failures++
+			continue
+		}
+		ttfts = append(ttfts, s.TTFT)
+		e2es = append(e2es, s.E2E)
+		throughputSum += s.Throughput
+	}
+	if len(ttfts) == 0 {
+		return dist
+	}
+	dist.P50TTFT = percentileDuration(ttfts, 50)
+	dist.P90TTFT = percentileDuration(ttfts, 90)
+	dist.P99TTFT = percentileDuration(ttfts, 99)
+	dist.P50E2E = percentileDuration(e2es, 50)
+	dist.P90E2E = percentileDuration(e2es, 90)
+	dist.P99E2E = percentileDuration(e2es, 99)
+	dist.AvgThroughput = throughputSum / float64(len(ttfts))
+	return dist
+}
+
+// runRoutedBenchmark sends requests through an emulated weighted router and
+// writes route-summary.json and ROUTE-REPORT.md.
+func runRoutedBenchmark(members []routeMember, configs map[string]ProviderConfig, tke *tiktoken.Tiktoken, runs int, resultsDir, sessionTimestamp string) error {
+	// Sampling uses the prompt seed so --seed also reproduces the routing sequence
+	rng := rand.New(rand.NewPCG(promptSeed, uint64(len(members)))) // #nosec G404 -- reproducibility, not security
+
+	summary := RouteSummary{
+		Pool:         make(map[string]float64),
+		Constituents: make(map[string]LatencyDistribution),
+	}
+	poolDesc := make([]string, 0, len(members))
+	for _, m := range members {
+		summary.Pool[m.name] = m.weight
+		poolDesc = append(poolDesc, fmt.Sprintf("%s=%g", m.name, m.weight))
+	}
+	log.Printf("=== ROUTED MODE: pool %s, %d requests ===", strings.Join(poolDesc, ", "), runs)
+
+	for i := 1; i <= runs; i++ {
+		if err := canStartRun(); err != nil {
+			log.Printf("Routed request %d skipped: %v", i, err)
+			break
+		}
+		member := pickRouteMember(rng, members)
+		config := configs[member.name]
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		o := raceStream(ctx, config, tke, fmt.Sprintf("routed%d", i), nil)
+		cancel()
+
+		sample := RouteSample{Iteration: i, Provider: member.name, Tokens: o.tokens}
+		if o.err != nil {
+			sample.Error = o.err.Error()
+			log.Printf("Routed request %d -> %s failed: %v", i, member.name, o.err)
+		} else {
+			sample.TTFT = o.firstToken.Sub(o.start)
+			sample.E2E = o.end.Sub(o.start)
+			if generation := o.end.Sub(o.firstToken).Seconds(); generation > 0 && o.tokens > 1 {
+				sample.Throughput = float64(o.tokens-1) / generation
+			}
+			log.Printf("Routed request %d -> %s: TTFT=%s E2E=%s Throughput=%.2f tok/s",
+				i, member.name, formatDuration(sample.TTFT), formatDuration(sample.E2E), sample.Throughput)
+		}
+		summary.Samples = append(summary.Samples, sample)
+	}
+
+	summary.Blended = summarizeRouteSamples(summary.Samples)
+	for _, m := range members {
+		var own []RouteSample
+		for _, s := range summary.Samples {
+			if s.Provider == m.name {
+				own = append(own, s)
+			}
+		}
+		summary.Constituents[m.name] = summarizeRouteSamples(own)
+	}
+
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling route summary: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(resultsDir, "route-summary.json"), data, 0600); err != nil {
+		return fmt.Errorf("error writing route summary: %w", err)
+	}
+	return generateRouteReport(resultsDir, members, summary, sessionTimestamp)
+}
+
+// generateRouteReport writes ROUTE-REPORT.md comparing the routed pool to its members.
+func generateRouteReport(resultsDir string, members []routeMember, summary RouteSummary, sessionTimestamp string) error {
+	filename := filepath.Join(resultsDir, "ROUTE-REPORT.md")
+
+	var report strings.Builder
+	report.WriteString("# LLM API Routed Provider Results\n\n")
+	fmt.Fprintf(&report, "**Test Session:** %s\n\n", sessionTimestamp)
+	report.WriteString("**Pool:** ")
+	totalWeight := 0.0
+	for _, m := range members {
+		totalWeight += m.weight
+	}
+	parts := make([]string, 0, len(members))
+	for _, m := range members {
+		parts = append(parts, fmt.Sprintf("%s (%.0f%%)", m.name, 100*m.weight/totalWeight))
+	}
+	report.WriteString(strings.Join(parts, ", ") + "\n\n")
+	report.WriteString("---\n\n")
+
+	report.WriteString("## Latency Distribution\n\n")
+	report.WriteString("| Provider | Requests | Failures | P50 TTFT | P90 TTFT | P99 TTFT | P50 E2E | P90 E2E | P99 E2E | Avg Throughput |\n")
+	report.WriteString("|----------|----------|----------|----------|----------|----------|---------|---------|---------|----------------|\n")
+	writeRow := func(name string, d LatencyDistribution) {
+		fmt.Fprintf(&report, "| %s | %d | %d | %s | %s | %s | %s | %s | %s | %.2f tok/s |\n",
+			name, d.Requests, d.Failures,
+			formatDuration(d.P50TTFT), formatDuration(d.P90TTFT), formatDuration(d.P99TTFT),
+			formatDuration(d.P50E2E), formatDuration(d.P90E2E), formatDuration(d.P99E2E), d.AvgThroughput)
+	}
+	writeRow("**"+routedModeLabel+"**", summary.Blended)
+	for _, m := range members {
+		writeRow(m.name, summary.Constituents[m.name])
+	}
+	report.WriteString("\n")
+
+	report.WriteString("---\n\n")
+	fmt.Fprintf(&report, "*Report generated at %s*\n", time.Now().Format("2006-01-02 15:04:05"))
+
+	if err := os.WriteFile(filename, []byte(report.String()), 0600); err != nil {
+		return fmt.Errorf("error writing route report: %w", err)
+	}
+	log.Printf("Route report generated: %s", filename)
+	return nil
+}
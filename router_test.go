@@ -0,0 +1,70 @@
+package main
+
+import (
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseRoutePool(t *testing.T) {
+	members, err := parseRoutePool("nim=3, novita")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(members) != 2 || members[0] != (routeMember{name: "nim", weight: 3}) || members[1] != (routeMember{name: "novita", weight: 1}) {
+		t.Fatalf("unexpected members: %+v", members)
+	}
+
+	for _, spec := range []string{"nim", "nim=3,nim=1", "nim=0,novita=1", "nim=x,novita"} {
+		if _, err := parseRoutePool(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestPickRouteMemberFollowsWeights(t *testing.T) {
+	members := []routeMember{{name: "a", weight: 3}, {name: "b", weight: 1}}
+	rng := rand.New(rand.NewPCG(1, 2))
+	counts := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		counts[pickRouteMember(rng, members).name]++
+	}
+	if share := float64(counts["a"]) / 4000; share < 0.7 || share > 0.8 {
+		t.Fatalf("expected about 75%% of picks for a, got %.2f", share)
+	}
+}
+
+func TestRunRoutedBenchmark(t *testing.T) {
+	tke := testTokenizer(t)
+	fast := mockSSEServer{chunks: []string{"Hello."}}.start(t)
+	defer fast.Close()
+	slow := mockSSEServer{firstTokenDelay: 30 * time.Millisecond, chunks: []string{"Hello."}}.start(t)
+	defer slow.Close()
+
+	members := []routeMember{{name: "fast", weight: 1}, {name: "slow", weight: 1}}
+	configs := map[string]ProviderConfig{
+		"fast": {Name: "fast", BaseURL: fast.URL, APIKey: "k", Model: "m"},
+		"slow": {Name: "slow", BaseURL: slow.URL, APIKey: "k", Model: "m"},
+	}
+	dir := t.TempDir()
+	if err := runRoutedBenchmark(members, configs, tke, 10, dir, "test"); err != nil {
+		t.Fatalf("runRoutedBenchmark failed: %v", err)
+	}
+	for _, name := range []string{"route-summary.json", "ROUTE-REPORT.md"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected %s: %v", name, err)
+		}
+	}
+
+	samples := []RouteSample{
+		{Provider: "fast", TTFT: 10, E2E: 20, Throughput: 100},
+		{Provider: "slow", TTFT: 30, E2E: 40, Throughput: 50},
+		{Provider: "slow", Error: "boom"},
+	}
+	dist := summarizeRouteSamples(samples)
+	if dist.Requests != 3 || dist.Failures != 1 || dist.P50TTFT != 10 || dist.P99E2E != 40 || dist.AvgThroughput != 75 {
+		t.Fatalf("unexpected distribution: %+v", dist)
+	}
+}
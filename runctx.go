@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// sessionID identifies the current session in logs and result records; it is
+// the session timestamp, which also names the results directory.
+var sessionID string
+
+// RunContext identifies one run within a session. It travels with the run's
+// context.Context so every function on the request path can label its log
+// lines and records without extra parameters.
+type RunContext struct {
+	SessionID string `json:"sessionId,omitempty"`
+	Provider  string `json:"provider"`
+	// Worker is the diagnostic worker or scenario virtual user, if any.
+	Worker int `json:"worker,omitempty"`
+	// Iteration numbers the run within its provider (or worker) from 1.
+	Iteration int      `json:"iteration,omitempty"`
+	Mode      TestMode `json:"mode,omitempty"`
+}
+
+// newRunContext starts a run context for config in the current session.
+func newRunContext(config ProviderConfig, mode TestMode, iteration int) RunContext {
+	return RunContext{
+		SessionID: sessionID,
+		Provider:  providerLabel(config.Name, config.Env),
+		Iteration: iteration,
+		Mode:      mode,
+	}
+}
+
+// String renders the fields that are set as key=value pairs, e.g.
+// "session=20251110-004642 provider=nim iter=2 mode=streaming", so log lines
+// can be filtered with grep.
+func (rc RunContext) String() string {
+	parts := make([]string, 0, 5)
+	if rc.SessionID != "" {
+		parts = append(parts, "session="+rc.SessionID)
+	}
+	parts = append(parts, "provider="+rc.Provider)
+	if rc.Worker > 0 {
+		parts = append(parts, fmt.Sprintf("worker=%d", rc.Worker))
+	}
+	if rc.Iteration > 0 {
+		parts = append(parts, fmt.Sprintf("iter=%d", rc.Iteration))
+	}
+	if rc.Mode != "" {
+		parts = append(parts, "mode="+string(rc.Mode))
+	}
+	return strings.Join(parts, " ")
+}
+
+// runContextKey is the context.Context key for a RunContext.
+type runContextKey struct{}
+
+// withRunContext returns a copy of ctx carrying rc.
+func withRunContext(ctx context.Context, rc RunContext) context.Context {
+	return context.WithValue(ctx, runContextKey{}, rc)
+}
+
+// runContextFrom returns the run context carried by ctx, falling back to one
+// naming only config's provider for requests made outside a tracked run.
+func runContextFrom(ctx context.Context, config ProviderConfig) RunContext {
+	if rc, ok := ctx.Value(runContextKey{}).(RunContext); ok {
+		return rc
+	}
+	return RunContext{SessionID: sessionID, Provider: providerLabel(config.Name, config.Env)}
+}
+
+// runLogger returns a logger writing to the same destination as base with the
+// run context of ctx in front of every message.
+func runLogger(ctx context.Context, base *log.Logger, config ProviderConfig) *log.Logger {
+	rc := runContextFrom(ctx, config)
+	return log.New(base.Writer(), base.Prefix()+"["+rc.String()+"] ", base.Flags()|log.Lmsgprefix)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestRunContextString(t *testing.T) {
+	rc := RunContext{SessionID: "20251110-004642", Provider: "nim (prod)", Worker: 3, Iteration: 2, Mode: ModeStreaming}
+	want := "session=20251110-004642 provider=nim (prod) worker=3 iter=2 mode=streaming"
+	if got := rc.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if got := (RunContext{Provider: "nim"}).String(); got != "provider=nim" {
+		t.Errorf("String() with only a provider = %q", got)
+	}
+}
+
+func TestRunContextFromFallsBackToProvider(t *testing.T) {
+	saved := sessionID
+	sessionID = "s1"
+	defer func() { sessionID = saved }()
+
+	config := ProviderConfig{Name: "nim", Env: "prod"}
+	got := runContextFrom(context.Background(), config)
+	if got != (RunContext{SessionID: "s1", Provider: providerLabel("nim", "prod")}) {
+		t.Errorf("fallback run context = %+v", got)
+	}
+
+	rc := newRunContext(config, ModeToolCalling, 4)
+	ctx, cancel := context.WithCancel(withRunContext(context.Background(), rc))
+	defer cancel()
+	if got := runContextFrom(ctx, config); got != rc {
+		t.Errorf("runContextFrom = %+v, want %+v", got, rc)
+	}
+}
+
+func TestRunLoggerPrefixesEveryLine(t *testing.T) {
+	var buf bytes.Buffer
+	base := log.New(&buf, "", log.LstdFlags)
+	rc := RunContext{SessionID: "s1", Provider: "nim", Iteration: 2, Mode: ModeStreaming}
+	logger := runLogger(withRunContext(context.Background(), rc), base, ProviderConfig{Name: "nim"})
+	logger.Printf("first %d", 1)
+	logger.Println("second")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %q", len(lines), buf.String())
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, "[session=s1 provider=nim iter=2 mode=streaming] ") {
+			t.Errorf("line %q lacks the run context", line)
+		}
+	}
+	// The timestamp stays first so logs still sort by time
+	if strings.HasPrefix(lines[0], "[") {
+		t.Errorf("run context precedes the timestamp: %q", lines[0])
+	}
+}
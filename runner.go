@@ -0,0 +1,433 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/lamim/llm-api-speed/pkg/benchmark"
+	"github.com/lamim/llm-api-speed/pkg/providers"
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// testProviderMetrics runs a full benchmark test against a single provider.
+// benchmark.RunProvider runs runIterations iterations per mode, concurrently
+// unless --sequential is set, with a 5-minute total timeout unless --timeout
+// is set; the averaged result is completed from the session's trackers and
+// saved.
+func testProviderMetrics(config ProviderConfig, tke *tiktoken.Tiktoken, logDir, resultsDir string, mode TestMode, toolReasoningCheck bool) {
+	// Create log file for this provider
+	timestamp := time.Now().Format("20060102-150405")
+	logFile, err := os.Create(filepath.Clean(filepath.Join(logDir, fmt.Sprintf("%s-%s.log", resultFilePrefix(config.Name, config.Env), timestamp))))
+	if err != nil {
+		log.Printf("Error creating log file for %s: %v", config.Name, err)
+		return
+	}
+	defer func() {
+		if closeErr := logFile.Close(); closeErr != nil {
+			log.Printf("Warning: Failed to close log file: %v", closeErr)
+		}
+	}()
+	defer saveHeaders(logDir, config, timestamp)
+
+	// Create a logger for this provider that writes to both stdout and file
+	providerLogger := log.New(io.MultiWriter(consoleOutput, logFile), "", log.LstdFlags)
+
+	modeStr := string(mode)
+	schedule := "concurrent"
+	if sequentialIterations {
+		schedule = "sequential"
+	}
+	providerLogger.Printf("--- Testing: %s (%s) - Mode: %s - Running %d %s iterations ---",
+		config.Name, config.Model, modeStr, runIterations, schedule)
+	emitProviderStarted(config, modeStr)
+	baseline := runNetworkBaseline(config, providerLogger)
+	warmUp(config, tke, providerLogger)
+	scraper := startServerScrape(config, providerLogger)
+
+	// Mixed mode runs both kinds of request
+	modes := []string{modeStr}
+	if mode == ModeMixed {
+		modes = []string{string(ModeStreaming), string(ModeToolCalling)}
+	}
+
+	// Each run records its failure rather than cancelling the others
+	totalRuns := len(modes) * runIterations
+	outcome, err := benchmark.RunProvider(context.Background(), benchmark.Config{
+		Runs:        runIterations,
+		Modes:       modes,
+		Concurrency: iterationLimit(totalRuns),
+		Timeout:     benchmarkTimeout(defaultRunTimeout),
+		Tokenizer:   tke,
+		Send: func(ctx context.Context, _ providers.Provider, id benchmark.RunID) (benchmark.Sample, error) {
+			run := newRunContext(config, TestMode(id.Mode), id.Iteration)
+			ctx = withRunContext(ctx, run)
+			runLog := runLogger(ctx, providerLogger, config)
+			if err := canStartRun(); err != nil {
+				runLog.Printf("Run %d/%d skipped: %v", id.Iteration, totalRuns, err)
+				return benchmark.Sample{}, err
+			}
+			runLog.Printf("Run %d/%d starting", id.Iteration, totalRuns)
+
+			var sample benchmark.Sample
+			var runErr error
+			useReasoningCheck := toolReasoningCheck && run.Mode == ModeToolCalling
+			runConfig := config.forWorker(id.Iteration)
+
+			// Execute the appropriate test based on mode
+			if run.Mode == ModeToolCalling {
+				sample, runErr = asSample(singleToolCallRun(ctx, runConfig, tke, providerLogger, useReasoningCheck))
+			} else {
+				sample, runErr = asSample(singleTestRun(ctx, runConfig, tke, providerLogger, fmt.Sprintf("run%d", id.Iteration)))
+			}
+
+			// Save response if flag is enabled
+			if saveResponses && runErr == nil && sample.Response != "" {
+				responseFile := filepath.Clean(filepath.Join(logDir,
+					fmt.Sprintf("%s-run%d-%s-response.txt", resultFilePrefix(config.Name, config.Env), id.Iteration, run.Mode)))
+				if err := os.WriteFile(responseFile, []byte(sample.Response), 0600); err != nil {
+					runLog.Printf("Warning: Failed to save response: %v", err)
+				}
+			}
+
+			if runErr != nil {
+				runLog.Printf("Run failed: %v", runErr)
+			} else {
+				runLog.Printf("Run complete: E2E=%s TTFT=%s Throughput=%.2f tok/s",
+					formatDuration(sample.E2E), formatDuration(sample.TTFT), sample.Throughput)
+			}
+			return sample, runErr
+		},
+	}, benchProvider(config))
+	if err != nil {
+		outcome.Err = err
+	}
+
+	// Complete the averages with what the runs left in the session's trackers
+	var judgedRuns []judgedRun
+	var quality qualityTally
+	var charsSum int
+	var costSum float64
+	var responses []string
+	runs := make([]RunSample, 0, len(outcome.Outcomes))
+
+	for _, o := range outcome.Outcomes {
+		run := newRunContext(config, TestMode(o.Mode), o.Iteration)
+		sample := RunSample{
+			Iteration:  o.Iteration,
+			Mode:       o.Mode,
+			Success:    o.Err == nil,
+			TTFT:       o.Sample.TTFT,
+			E2E:        o.Sample.E2E,
+			Throughput: o.Sample.Throughput,
+			Tokens:     o.Sample.Tokens,
+			Instance:   sessionInstances.state(run),
+		}
+		if o.Err != nil {
+			sample.Error = o.Err.Error()
+			runs = append(runs, sample)
+			continue
+		}
+		counts := sessionTokenCounts.counts(run)
+		sample.LocalTokens, sample.ReportedTokens = counts.local, counts.reported
+		runs = append(runs, sample)
+
+		if run.Mode != ModeToolCalling {
+			q := analyzeOutput(o.Sample.Response, currentPromptPack().scripts)
+			quality.add(q)
+			if q.Degenerate() {
+				providerLogger.Printf("[%s] Warning: degenerate output (repetition=%.0f%% loop=%t unexpectedLanguage=%t)",
+					run, 100*q.RepetitionRatio, q.LoopDetected, q.UnexpectedScript)
+			}
+		}
+		charsSum += utf8.RuneCountInString(o.Sample.Response)
+		responses = append(responses, o.Sample.Response)
+		prompt := promptForRun(config, run.Mode, fmt.Sprintf("run%d", o.Iteration))
+		costSum += estimateCost(config, len(tke.Encode(prompt, nil, nil)), o.Sample.Tokens)
+		judgedRuns = append(judgedRuns, judgedRun{
+			label:    fmt.Sprintf("run %d (%s)", o.Iteration, run.Mode),
+			prompt:   prompt,
+			response: o.Sample.Response,
+		})
+	}
+
+	serverMetrics := scraper.finish()
+
+	successfulRuns := outcome.Successful
+	if successfulRuns == 0 {
+		providerLogger.Printf("[%s] All runs failed", config.Name)
+		firstError := outcome.Err
+		if firstError == nil {
+			firstError = errors.New("no runs completed")
+		}
+		// Save error result
+		result := TestResult{
+			SessionID:       sessionID,
+			Provider:        config.Name,
+			Model:           config.Model,
+			Env:             config.Env,
+			IPVersion:       config.IPVersion,
+			HTTPVersion:     config.HTTPVersion,
+			ToolChoice:      config.ToolChoice,
+			Profile:         config.Profile,
+			Timestamp:       time.Now(),
+			Success:         false,
+			Error:           firstError.Error(),
+			Mode:            modeStr,
+			KeyStats:        sessionKeys.stats(providerLabel(config.Name, config.Env)),
+			Deadlines:       sessionDeadlines.stats(providerLabel(config.Name, config.Env)),
+			Usage:           sessionBudget.usage(providerLabel(config.Name, config.Env)),
+			LiteLLM:         sessionLiteLLM.stats(providerLabel(config.Name, config.Env)),
+			ServerMetrics:   serverMetrics,
+			NetworkBaseline: baseline,
+			Runs:            runs,
+		}
+		saveResult(resultsDir, result)
+		return
+	}
+
+	avgE2E, avgTTFT, avgThroughput, avgTokens := outcome.E2E, outcome.TTFT, outcome.Throughput, outcome.Tokens
+
+	// Print averaged results
+	providerLogger.Println("==============================================")
+	providerLogger.Printf("   LLM Metrics for: %s (averaged over %d run(s))", config.Name, successfulRuns)
+	providerLogger.Printf("   Model: %s", config.Model)
+	providerLogger.Printf("   Mode: %s", modeStr)
+	providerLogger.Printf("   Avg Output Tokens: %d", avgTokens)
+	providerLogger.Println("----------------------------------------------")
+	providerLogger.Printf("   End-to-End Latency: %s", formatDuration(avgE2E))
+	providerLogger.Printf("   Latency (TTFT):     %s", formatDuration(avgTTFT))
+	providerLogger.Printf("   Throughput (Tokens/sec): %.2f tokens/s", avgThroughput)
+	providerLogger.Println("==============================================")
+
+	// Calculate projected E2E if target tokens is set
+	var projectedE2E time.Duration
+	if targetTokens > 0 {
+		projectedE2E = calculateProjectedE2E(avgTTFT, avgThroughput, targetTokens)
+	}
+
+	qualityScores, qualityScore := scoreRuns(providerLogger, config, judgedRuns)
+	ttftSpread, e2eSpread, throughputSpread := runSpreads(runs)
+
+	// Save successful result
+	result := TestResult{
+		SessionID:        sessionID,
+		Provider:         config.Name,
+		Model:            config.Model,
+		Env:              config.Env,
+		IPVersion:        config.IPVersion,
+		HTTPVersion:      config.HTTPVersion,
+		ToolChoice:       config.ToolChoice,
+		Profile:          config.Profile,
+		Timestamp:        time.Now(),
+		E2ELatency:       avgE2E,
+		TTFT:             avgTTFT,
+		Throughput:       avgThroughput,
+		CompletionTokens: avgTokens,
+		ProjectedE2E:     projectedE2E,
+		SecPer100Tokens:  secondsPer100Tokens(avgE2E, avgTokens),
+		NormalizedE2E:    normalizedE2E(avgTTFT, avgThroughput),
+		TTFTSpread:       ttftSpread,
+		E2ESpread:        e2eSpread,
+		ThroughputSpread: throughputSpread,
+		ChunkStats:       sessionStreams.chunks(providerLabel(config.Name, config.Env)),
+		ThroughputCurve:  sessionStreams.curve(providerLabel(config.Name, config.Env)),
+		ITL:              sessionStreams.itl(providerLabel(config.Name, config.Env)),
+		ToolArgs:         sessionStreams.toolArgs(providerLabel(config.Name, config.Env)),
+		KeyStats:         sessionKeys.stats(providerLabel(config.Name, config.Env)),
+		Deadlines:        sessionDeadlines.stats(providerLabel(config.Name, config.Env)),
+		Usage:            sessionBudget.usage(providerLabel(config.Name, config.Env)),
+		LiteLLM:          sessionLiteLLM.stats(providerLabel(config.Name, config.Env)),
+		ServerMetrics:    serverMetrics,
+		NetworkBaseline:  baseline,
+		TokenCrossCheck:  crossCheckTokens(tke, responses, avgTokens, avgThroughput),
+		TokenCounts:      tokenCounts(runs),
+		Instances:        instanceStats(runs),
+		Success:          true,
+		Mode:             modeStr,
+		QualityScore:     qualityScore,
+		QualityScores:    qualityScores,
+		RepetitionRatio:  quality.avgRepetition(),
+		DegenerateRuns:   quality.degenerate,
+		OutputFlags:      quality.flags(),
+		Language:         promptLang,
+		CompletionChars:  charsSum / successfulRuns,
+		EstimatedCost:    costSum / float64(successfulRuns),
+		Runs:             runs,
+	}
+	saveResult(resultsDir, result)
+}
+
+// testProviderLongStory runs a single long-story benchmark against a provider.
+func testProviderLongStory(config ProviderConfig, tke *tiktoken.Tiktoken, logDir, resultsDir string) {
+	timestamp := time.Now().Format("20060102-150405")
+	logFile, err := os.Create(filepath.Clean(filepath.Join(logDir, fmt.Sprintf("%s-long-story-%s.log", resultFilePrefix(config.Name, config.Env), timestamp))))
+	if err != nil {
+		log.Printf("Error creating long-story log file for %s: %v", config.Name, err)
+		return
+	}
+	defer func() {
+		if closeErr := logFile.Close(); closeErr != nil {
+			log.Printf("Warning: Failed to close long-story log file: %v", closeErr)
+		}
+	}()
+	defer saveHeaders(logDir, config, timestamp)
+
+	providerLogger := log.New(io.MultiWriter(consoleOutput, logFile), "", log.LstdFlags)
+	providerLogger.Printf("--- Long-story test: %s (%s) ---", config.Name, config.Model)
+	emitProviderStarted(config, longStoryModeLabel)
+	baseline := runNetworkBaseline(config, providerLogger)
+	warmUp(config, tke, providerLogger)
+	scraper := startServerScrape(config, providerLogger)
+
+	run := newRunContext(config, TestMode(longStoryModeLabel), 1)
+	runLog := runLogger(withRunContext(context.Background(), run), providerLogger, config)
+
+	outcome, err := benchmark.RunProvider(context.Background(), benchmark.Config{
+		Runs:      1,
+		Modes:     []string{longStoryModeLabel},
+		Timeout:   benchmarkTimeout(defaultLongStoryTimeout),
+		Tokenizer: tke,
+		Send: func(ctx context.Context, _ providers.Provider, _ benchmark.RunID) (benchmark.Sample, error) {
+			if err := canStartRun(); err != nil {
+				return benchmark.Sample{}, err
+			}
+			runLog.Println("Long-story run starting")
+			return asSample(longStoryRun(withRunContext(ctx, run), config, tke, providerLogger))
+		},
+	}, benchProvider(config))
+	serverMetrics := scraper.finish()
+
+	var sample benchmark.Sample
+	runErr := err
+	if runErr == nil {
+		sample, runErr = outcome.Outcomes[0].Sample, outcome.Outcomes[0].Err
+	}
+	e2e, ttft, throughput, tokens, responseContent := sample.E2E, sample.TTFT, sample.Throughput, sample.Tokens, sample.Response
+
+	if saveResponses && runErr == nil && responseContent != "" {
+		responseFile := filepath.Clean(filepath.Join(logDir,
+			fmt.Sprintf("%s-long-story-response.txt", resultFilePrefix(config.Name, config.Env))))
+		if err := os.WriteFile(responseFile, []byte(responseContent), 0600); err != nil {
+			runLog.Printf("Warning: Failed to save long-story response: %v", err)
+		}
+	}
+
+	if runErr != nil {
+		runLog.Printf("Long-story run failed: %v", runErr)
+		result := TestResult{
+			SessionID:       sessionID,
+			Provider:        config.Name,
+			Model:           config.Model,
+			Env:             config.Env,
+			IPVersion:       config.IPVersion,
+			HTTPVersion:     config.HTTPVersion,
+			Timestamp:       time.Now(),
+			Success:         false,
+			Error:           runErr.Error(),
+			Mode:            longStoryModeLabel,
+			KeyStats:        sessionKeys.stats(providerLabel(config.Name, config.Env)),
+			Deadlines:       sessionDeadlines.stats(providerLabel(config.Name, config.Env)),
+			Usage:           sessionBudget.usage(providerLabel(config.Name, config.Env)),
+			LiteLLM:         sessionLiteLLM.stats(providerLabel(config.Name, config.Env)),
+			ServerMetrics:   serverMetrics,
+			NetworkBaseline: baseline,
+		}
+		saveResult(resultsDir, result)
+		return
+	}
+
+	providerLogger.Println("==============================================")
+	providerLogger.Printf("   Long-story LLM Metrics for: %s", config.Name)
+	providerLogger.Printf("   Model: %s", config.Model)
+	providerLogger.Printf("   Mode: %s", longStoryModeLabel)
+	providerLogger.Printf("   Output Tokens: %d", tokens)
+	providerLogger.Println("----------------------------------------------")
+	providerLogger.Printf("   End-to-End Latency: %s", formatDuration(e2e))
+	providerLogger.Printf("   Latency (TTFT):     %s", formatDuration(ttft))
+	providerLogger.Printf("   Throughput (Tokens/sec): %.2f tokens/s", throughput)
+	providerLogger.Println("==============================================")
+
+	var projectedE2E time.Duration
+	if targetTokens > 0 {
+		projectedE2E = calculateProjectedE2E(ttft, throughput, targetTokens)
+	}
+
+	var quality qualityTally
+	quality.add(analyzeOutput(responseContent, []*unicode.RangeTable{unicode.Latin}))
+
+	counts := sessionTokenCounts.counts(run)
+
+	qualityScores, qualityScore := scoreRuns(providerLogger, config, []judgedRun{{
+		label:    longStoryModeLabel,
+		prompt:   sessionPrompt(promptGroupLongStory, longStoryUserPrompt),
+		response: responseContent,
+	}})
+
+	result := TestResult{
+		SessionID:        sessionID,
+		Provider:         config.Name,
+		Model:            config.Model,
+		Env:              config.Env,
+		IPVersion:        config.IPVersion,
+		HTTPVersion:      config.HTTPVersion,
+		Timestamp:        time.Now(),
+		E2ELatency:       e2e,
+		TTFT:             ttft,
+		Throughput:       throughput,
+		CompletionTokens: tokens,
+		ProjectedE2E:     projectedE2E,
+		SecPer100Tokens:  secondsPer100Tokens(e2e, tokens),
+		NormalizedE2E:    normalizedE2E(ttft, throughput),
+		ChunkStats:       sessionStreams.chunks(providerLabel(config.Name, config.Env)),
+		ThroughputCurve:  sessionStreams.curve(providerLabel(config.Name, config.Env)),
+		ITL:              sessionStreams.itl(providerLabel(config.Name, config.Env)),
+		KeyStats:         sessionKeys.stats(providerLabel(config.Name, config.Env)),
+		Deadlines:        sessionDeadlines.stats(providerLabel(config.Name, config.Env)),
+		Usage:            sessionBudget.usage(providerLabel(config.Name, config.Env)),
+		LiteLLM:          sessionLiteLLM.stats(providerLabel(config.Name, config.Env)),
+		ServerMetrics:    serverMetrics,
+		NetworkBaseline:  baseline,
+		TokenCrossCheck:  crossCheckTokens(tke, []string{responseContent}, tokens, throughput),
+		TokenCounts:      tokenCounts([]RunSample{{Success: true, LocalTokens: counts.local, ReportedTokens: counts.reported}}),
+		Success:          true,
+		Mode:             longStoryModeLabel,
+		QualityScore:     qualityScore,
+		QualityScores:    qualityScores,
+		RepetitionRatio:  quality.avgRepetition(),
+		DegenerateRuns:   quality.degenerate,
+		OutputFlags:      quality.flags(),
+		Language:         defaultPromptLang,
+		CompletionChars:  utf8.RuneCountInString(responseContent),
+	}
+	saveResult(resultsDir, result)
+}
+
+// saveResult saves the test result to a JSON file. Every provider's final
+// result passes through here, so it also emits the provider_finished event.
+func saveResult(resultsDir string, result TestResult) {
+	emitProviderFinished(result)
+	timestamp := result.Timestamp.Format("20060102-150405")
+	filename := filepath.Join(resultsDir, fmt.Sprintf("%s-%s.json", resultFilePrefix(result.Provider, result.Env), timestamp))
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Printf("Error marshaling result for %s: %v", result.Provider, err)
+		return
+	}
+
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		log.Printf("Error writing result file for %s: %v", result.Provider, err)
+		return
+	}
+
+	log.Printf("Result saved: %s", filename)
+}
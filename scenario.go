@@ -0,0 +1,610 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+const (
+	scenarioModeLabel = "scenario"
+	// scenarioTick is how often the target concurrency is recomputed.
+	scenarioTick           = 250 * time.Millisecond
+	scenarioRequestTimeout = 2 * time.Minute
+	// scenarioLongFormTimeout matches the long-story mode's request timeout.
+	scenarioLongFormTimeout = 10 * time.Minute
+	// scenarioMaxTarget bounds a stage's concurrency per provider.
+	scenarioMaxTarget = 1000
+	// scenarioMaxTurns bounds conversation length, and with it context size.
+	scenarioMaxTurns = 50
+)
+
+// scenarioStage is one step of a load profile: over Duration, the number of
+// virtual users moves linearly from the previous stage's target to Target.
+type scenarioStage struct {
+	Duration configDuration `toml:"duration"`
+	Target   int            `toml:"target"`
+}
+
+// scenarioConfig is the [scenario] section of the config file, a k6-style list
+// of stages executed against every selected provider.
+type scenarioConfig struct {
+	Stages []scenarioStage `toml:"stages"`
+	// ThinkTime pauses each virtual user between requests, overriding
+	// --think-time; without either, users send requests back to back.
+	ThinkTime thinkTime `toml:"think_time"`
+	// Turns makes each virtual user hold a conversation of this many streaming
+	// turns, resending the growing history with every request, before starting
+	// a new one. 0 or 1 sends independent single-turn requests.
+	Turns int `toml:"turns"`
+	// Mix blends request classes by weight instead of following the test mode.
+	Mix trafficMix `toml:"mix"`
+}
+
+// validate checks that every stage has a duration and a sane target.
+func (s scenarioConfig) validate() error {
+	if len(s.Stages) == 0 {
+		return errors.New("scenario needs at least one [[scenario.stages]] entry")
+	}
+	if s.Turns < 0 || s.Turns > scenarioMaxTurns {
+		return fmt.Errorf("scenario turns must be between 0 and %d", scenarioMaxTurns)
+	}
+	if err := s.Mix.validate(); err != nil {
+		return fmt.Errorf("scenario mix: %w", err)
+	}
+	for i, st := range s.Stages {
+		if st.Duration.Duration <= 0 {
+			return fmt.Errorf("scenario stage %d: duration must be positive", i+1)
+		}
+		if st.Target < 0 || st.Target > scenarioMaxTarget {
+			return fmt.Errorf("scenario stage %d: target must be between 0 and %d", i+1, scenarioMaxTarget)
+		}
+	}
+	return nil
+}
+
+// total is the planned length of the scenario.
+func (s scenarioConfig) total() time.Duration {
+	var d time.Duration
+	for _, st := range s.Stages {
+		d += st.Duration.Duration
+	}
+	return d
+}
+
+// targetAt returns the stage running at elapsed and the concurrency it calls
+// for. ok is false once every stage has finished.
+func (s scenarioConfig) targetAt(elapsed time.Duration) (stage, target int, ok bool) {
+	from := 0
+	for i, st := range s.Stages {
+		if d := st.Duration.Duration; elapsed < d {
+			frac := float64(elapsed) / float64(d)
+			return i, from + int(math.Round(frac*float64(st.Target-from))), true
+		}
+		elapsed -= st.Duration.Duration
+		from = st.Target
+	}
+	return len(s.Stages) - 1, from, false
+}
+
+// describe labels stage i, e.g. "ramp 0→20 over 2m0s" or "hold 20 for 5m0s".
+func (s scenarioConfig) describe(i int) string {
+	from := 0
+	if i > 0 {
+		from = s.Stages[i-1].Target
+	}
+	st := s.Stages[i]
+	if st.Target == from {
+		return fmt.Sprintf("hold %d for %s", st.Target, st.Duration.Duration)
+	}
+	return fmt.Sprintf("ramp %d→%d over %s", from, st.Target, st.Duration.Duration)
+}
+
+// scenarioTally collects the requests of one stage or traffic class.
+type scenarioTally struct {
+	requests      int
+	failures      int
+	tokens        int
+	peakVUs       int
+	ttft          []time.Duration
+	e2e           []time.Duration
+	throughputSum float64
+}
+
+// add records one request outcome.
+func (t *scenarioTally) add(e2e, ttft time.Duration, throughput float64, tokens int, err error) {
+	t.requests++
+	if err != nil {
+		t.failures++
+		return
+	}
+	t.tokens += tokens
+	t.ttft = append(t.ttft, ttft)
+	t.e2e = append(t.e2e, e2e)
+	t.throughputSum += throughput
+}
+
+// stats summarizes the tally.
+func (t *scenarioTally) stats() ScenarioStats {
+	s := ScenarioStats{Requests: t.requests, Failures: t.failures, Tokens: t.tokens}
+	if t.requests > 0 {
+		s.SuccessRate = 100 * float64(t.requests-t.failures) / float64(t.requests)
+	}
+	if ok := len(t.ttft); ok > 0 {
+		var sum time.Duration
+		for _, d := range t.ttft {
+			sum += d
+		}
+		s.AvgTTFT = sum / time.Duration(ok)
+		s.P95TTFT = percentileDuration(t.ttft, 95)
+		s.P95E2E = percentileDuration(t.e2e, 95)
+		s.AvgThroughput = t.throughputSum / float64(ok)
+	}
+	return s
+}
+
+// scenarioTurnStats collects the conversation turns at one position.
+type scenarioTurnStats struct {
+	requests     int
+	failures     int
+	promptTokens int
+	ttft         []time.Duration
+	e2eSum       time.Duration
+}
+
+// scenarioRunner drives the virtual users of one provider.
+type scenarioRunner struct {
+	config             ProviderConfig
+	tke                *tiktoken.Tiktoken
+	mode               TestMode
+	toolReasoningCheck bool
+	thinkTime          thinkTime
+	turns              int
+	mix                trafficMix
+	logger             *log.Logger
+
+	// stage is the index of the running stage, read by virtual users.
+	stage   atomic.Int32
+	reqNum  atomic.Int64
+	stops   []chan struct{}
+	nextVU  int
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	results []scenarioTally
+	byClass map[string]*scenarioTally
+	byTurn  []scenarioTurnStats
+}
+
+// scale starts or stops virtual users until n are running. A stopped user
+// finishes its in-flight request first. Only the controller calls scale.
+func (r *scenarioRunner) scale(n int) {
+	for len(r.stops) < n {
+		stop := make(chan struct{})
+		r.stops = append(r.stops, stop)
+		r.nextVU++
+		r.wg.Add(1)
+		go r.virtualUser(r.nextVU, stop)
+	}
+	for len(r.stops) > n {
+		last := len(r.stops) - 1
+		close(r.stops[last])
+		r.stops = r.stops[:last]
+	}
+	r.mu.Lock()
+	stats := &r.results[r.stage.Load()]
+	stats.peakVUs = max(stats.peakVUs, n)
+	r.mu.Unlock()
+}
+
+// virtualUser sends requests until stopped, pausing for the think time after
+// each response.
+func (r *scenarioRunner) virtualUser(id int, stop <-chan struct{}) {
+	defer r.wg.Done()
+	config := r.config.forWorker(id)
+	rng := newWorkerRand(id)
+	var conv conversation
+	for reqs := 0; ; reqs++ {
+		if reqs > 0 && r.thinkTime.enabled() {
+			select {
+			case <-stop:
+				return
+			case <-time.After(r.thinkTime.sample(rng)):
+			}
+		}
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		if err := canStartRun(); err != nil {
+			return
+		}
+
+		stage := int(r.stage.Load())
+		reqNum := r.reqNum.Add(1)
+		class := r.nextClass(rng, reqNum)
+		timeout := scenarioRequestTimeout
+		if class == trafficLongForm {
+			timeout = scenarioLongFormTimeout
+		}
+		run := newRunContext(config, TestMode(class), int(reqNum))
+		run.Worker = id
+		// Requests are not tied to the scenario so an in-flight request finishes cleanly
+		reqCtx, cancel := context.WithTimeout(withRunContext(context.Background(), run), timeout)
+		runLog := runLogger(reqCtx, r.logger, config)
+		var e2e, ttft time.Duration
+		var throughput float64
+		var tokens int
+		var err error
+		switch {
+		case class == trafficToolCalling:
+			e2e, ttft, throughput, tokens, _, err = singleToolCallRun(reqCtx, config, r.tke, r.logger, r.toolReasoningCheck)
+		case class == trafficLongForm:
+			e2e, ttft, throughput, tokens, _, err = longStoryRun(reqCtx, config, r.tke, r.logger)
+		case r.turns > 1:
+			turn := conv.turn
+			messages := conv.next(config.Name, fmt.Sprintf("scenario%d", reqNum))
+			promptTokens := countPromptTokens(r.tke, messages)
+			var response string
+			e2e, ttft, throughput, tokens, response, err = conversationTurnRun(reqCtx, config, r.tke, r.logger, messages)
+			if err == nil {
+				conv.reply(response)
+			}
+			// A failed turn ends the conversation, as a user would start over
+			if err != nil || conv.turn >= r.turns {
+				conv.reset()
+			}
+			r.recordTurn(turn, promptTokens, e2e, ttft, err)
+		default:
+			e2e, ttft, throughput, tokens, _, err = singleTestRun(reqCtx, config, r.tke, r.logger, fmt.Sprintf("scenario%d", reqNum))
+		}
+		cancel()
+
+		if err != nil {
+			runLog.Printf("Request failed: %v", err)
+		} else {
+			runLog.Printf("Request: E2E=%s TTFT=%s Throughput=%.2f tok/s",
+				formatDuration(e2e), formatDuration(ttft), throughput)
+		}
+		r.record(stage, class, e2e, ttft, throughput, tokens, err)
+	}
+}
+
+// nextClass chooses the traffic class of a request: drawn from the mix when
+// one is configured, otherwise implied by the test mode.
+func (r *scenarioRunner) nextClass(rng *rand.Rand, reqNum int64) string {
+	if len(r.mix) > 0 {
+		return r.mix.pick(rng)
+	}
+	switch r.mode {
+	case ModeToolCalling:
+		return trafficToolCalling
+	case ModeMixed:
+		if reqNum%2 == 0 {
+			return trafficToolCalling
+		}
+	}
+	return trafficChat
+}
+
+// record adds one request outcome to the stage it started in and its class.
+func (r *scenarioRunner) record(stage int, class string, e2e, ttft time.Duration, throughput float64, tokens int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results[stage].add(e2e, ttft, throughput, tokens, err)
+	tally := r.byClass[class]
+	if tally == nil {
+		tally = &scenarioTally{}
+		r.byClass[class] = tally
+	}
+	tally.add(e2e, ttft, throughput, tokens, err)
+}
+
+// recordTurn adds one conversation turn outcome; turn is zero-based.
+func (r *scenarioRunner) recordTurn(turn, promptTokens int, e2e, ttft time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := &r.byTurn[turn]
+	stats.requests++
+	if err != nil {
+		stats.failures++
+		return
+	}
+	stats.promptTokens += promptTokens
+	stats.ttft = append(stats.ttft, ttft)
+	stats.e2eSum += e2e
+}
+
+// ScenarioTurnSummary is one provider's latency at one conversation turn.
+type ScenarioTurnSummary struct {
+	Turn            int           `json:"turn"`
+	Requests        int           `json:"requests"`
+	Failures        int           `json:"failures"`
+	AvgPromptTokens int           `json:"avgPromptTokens"`
+	AvgTTFT         time.Duration `json:"avgTtft"`
+	P95TTFT         time.Duration `json:"p95Ttft"`
+	AvgE2E          time.Duration `json:"avgE2e"`
+}
+
+// ScenarioStats are the request statistics of a stage or traffic class.
+type ScenarioStats struct {
+	Requests      int           `json:"requests"`
+	Failures      int           `json:"failures"`
+	SuccessRate   float64       `json:"successRate"`
+	Tokens        int           `json:"tokens"`
+	AvgTTFT       time.Duration `json:"avgTtft"`
+	P95TTFT       time.Duration `json:"p95Ttft"`
+	P95E2E        time.Duration `json:"p95E2e"`
+	AvgThroughput float64       `json:"avgThroughput"`
+}
+
+// ScenarioStageSummary is one provider's results for one stage.
+type ScenarioStageSummary struct {
+	Stage       int    `json:"stage"`
+	Description string `json:"description"`
+	Target      int    `json:"target"`
+	PeakVUs     int    `json:"peakVus"`
+	ScenarioStats
+}
+
+// ScenarioClassSummary is one provider's results for one traffic class.
+type ScenarioClassSummary struct {
+	Class string `json:"class"`
+	// Share is the configured percentage of the mix, 0 without a mix.
+	Share float64 `json:"share,omitempty"`
+	ScenarioStats
+}
+
+// ScenarioProviderSummary is one provider's results across the scenario.
+type ScenarioProviderSummary struct {
+	Provider string                 `json:"provider"`
+	Model    string                 `json:"model"`
+	Stages   []ScenarioStageSummary `json:"stages"`
+	Classes  []ScenarioClassSummary `json:"classes,omitempty"`
+	Turns    []ScenarioTurnSummary  `json:"turns,omitempty"`
+}
+
+// ScenarioSummary is written to scenario-summary.json.
+type ScenarioSummary struct {
+	Started   time.Time                 `json:"started"`
+	Planned   time.Duration             `json:"planned"`
+	Elapsed   time.Duration             `json:"elapsed"`
+	ThinkTime string                    `json:"thinkTime,omitempty"`
+	Mix       string                    `json:"mix,omitempty"`
+	Providers []ScenarioProviderSummary `json:"providers"`
+}
+
+// summary renders the runner's per-stage statistics.
+func (r *scenarioRunner) summary(sc scenarioConfig) ScenarioProviderSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := ScenarioProviderSummary{Provider: providerLabel(r.config.Name, r.config.Env), Model: r.config.Model}
+	for i := range r.results {
+		tally := &r.results[i]
+		s.Stages = append(s.Stages, ScenarioStageSummary{
+			Stage:         i + 1,
+			Description:   sc.describe(i),
+			Target:        sc.Stages[i].Target,
+			PeakVUs:       tally.peakVUs,
+			ScenarioStats: tally.stats(),
+		})
+	}
+	// Classes are only worth a table when more than one was sent
+	if len(r.mix) > 0 || len(r.byClass) > 1 {
+		for _, class := range trafficClasses {
+			if tally := r.byClass[class]; tally != nil {
+				s.Classes = append(s.Classes, ScenarioClassSummary{Class: class, Share: r.mix.share(class), ScenarioStats: tally.stats()})
+			}
+		}
+	}
+	for i, stats := range r.byTurn {
+		if stats.requests == 0 {
+			continue
+		}
+		turn := ScenarioTurnSummary{Turn: i + 1, Requests: stats.requests, Failures: stats.failures}
+		if ok := len(stats.ttft); ok > 0 {
+			var sum time.Duration
+			for _, d := range stats.ttft {
+				sum += d
+			}
+			turn.AvgPromptTokens = stats.promptTokens / ok
+			turn.AvgTTFT = sum / time.Duration(ok)
+			turn.P95TTFT = percentileDuration(stats.ttft, 95)
+			turn.AvgE2E = stats.e2eSum / time.Duration(ok)
+		}
+		s.Turns = append(s.Turns, turn)
+	}
+	return s
+}
+
+// runScenario executes the configured stages against every provider at once,
+// each provider with its own pool of virtual users, then writes
+// scenario-summary.json and SCENARIO-REPORT.md.
+func runScenario(providers []ProviderConfig, tke *tiktoken.Tiktoken, mode TestMode, toolReasoningCheck bool, sc scenarioConfig, logDir, resultsDir, sessionTimestamp string) error {
+	log.Printf("=== SCENARIO MODE: %d provider(s), %d stage(s) over %s ===", len(providers), len(sc.Stages), sc.total())
+	for i := range sc.Stages {
+		log.Printf("  Stage %d: %s", i+1, sc.describe(i))
+	}
+	log.Println("Press Ctrl+C (or q) to stop early; a report is still written.")
+
+	think := sc.ThinkTime
+	if !think.enabled() {
+		think = sessionThinkTime
+	}
+	if think.enabled() {
+		log.Printf("  Think time: %s", think)
+	}
+	if sc.Turns > 1 {
+		log.Printf("  Conversations: %d turns per virtual user", sc.Turns)
+	}
+	if len(sc.Mix) > 0 {
+		log.Printf("  Traffic mix: %s", sc.Mix)
+	}
+
+	ctx, stop := signal.NotifyContext(shutdownCtx, os.Interrupt)
+	defer stop()
+
+	runners := make([]*scenarioRunner, 0, len(providers))
+	for _, p := range providers {
+		logFile, err := os.Create(filepath.Join(logDir, fmt.Sprintf("%s-scenario.log", resultFilePrefix(p.Name, p.Env))))
+		if err != nil {
+			return fmt.Errorf("error creating log file: %w", err)
+		}
+		defer func() {
+			if closeErr := logFile.Close(); closeErr != nil {
+				log.Printf("Warning: Failed to close log file: %v", closeErr)
+			}
+		}()
+		runners = append(runners, &scenarioRunner{
+			config:             p,
+			tke:                tke,
+			mode:               mode,
+			toolReasoningCheck: toolReasoningCheck,
+			thinkTime:          think,
+			turns:              sc.Turns,
+			mix:                sc.Mix,
+			logger:             log.New(io.MultiWriter(consoleOutput, logFile), "", log.LstdFlags),
+			results:            make([]scenarioTally, len(sc.Stages)),
+			byClass:            make(map[string]*scenarioTally),
+			byTurn:             make([]scenarioTurnStats, sc.Turns),
+		})
+	}
+
+	started := time.Now()
+	ticker := time.NewTicker(scenarioTick)
+	defer ticker.Stop()
+	lastStage := -1
+loop:
+	for {
+		stage, target, ok := sc.targetAt(time.Since(started))
+		if !ok || canStartRun() != nil {
+			break
+		}
+		if stage != lastStage {
+			log.Printf("--- Scenario stage %d/%d: %s ---", stage+1, len(sc.Stages), sc.describe(stage))
+			lastStage = stage
+		}
+		for _, r := range runners {
+			r.stage.Store(int32(stage)) // #nosec G115 -- bounded by the number of stages
+			r.scale(target)
+		}
+		select {
+		case <-ctx.Done():
+			log.Println("Scenario interrupted, waiting for in-flight requests...")
+			break loop
+		case <-ticker.C:
+		}
+	}
+	for _, r := range runners {
+		r.scale(0)
+	}
+	for _, r := range runners {
+		r.wg.Wait()
+	}
+
+	summary := ScenarioSummary{Started: started, Planned: sc.total(), Elapsed: time.Since(started)}
+	if think.enabled() {
+		summary.ThinkTime = think.String()
+	}
+	if len(sc.Mix) > 0 {
+		summary.Mix = sc.Mix.String()
+	}
+	for _, r := range runners {
+		summary.Providers = append(summary.Providers, r.summary(sc))
+	}
+	return writeScenarioResults(resultsDir, summary, sessionTimestamp)
+}
+
+// writeScenarioResults writes scenario-summary.json and SCENARIO-REPORT.md.
+func writeScenarioResults(resultsDir string, summary ScenarioSummary, sessionTimestamp string) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling scenario summary: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(resultsDir, "scenario-summary.json"), data, 0600); err != nil {
+		return fmt.Errorf("error writing scenario summary: %w", err)
+	}
+	filename := filepath.Join(resultsDir, "SCENARIO-REPORT.md")
+	if err := os.WriteFile(filename, []byte(renderScenarioReport(summary, sessionTimestamp)), 0600); err != nil {
+		return fmt.Errorf("error writing scenario report: %w", err)
+	}
+	log.Printf("Scenario report generated: %s", filename)
+	return nil
+}
+
+// renderScenarioReport builds SCENARIO-REPORT.md, one stage table per provider.
+func renderScenarioReport(summary ScenarioSummary, sessionTimestamp string) string {
+	var report strings.Builder
+	report.WriteString("# LLM API Scenario Test Results\n\n")
+	fmt.Fprintf(&report, "**Test Session:** %s\n\n", sessionTimestamp)
+	fmt.Fprintf(&report, "**Elapsed:** %s of %s\n\n", summary.Elapsed.Round(time.Second), summary.Planned)
+	if summary.Mix != "" {
+		fmt.Fprintf(&report, "**Traffic Mix:** %s\n\n", summary.Mix)
+	}
+	if summary.ThinkTime != "" {
+		fmt.Fprintf(&report, "**Think Time:** %s\n\n", summary.ThinkTime)
+		report.WriteString("Virtual users pause for the think time after each response; each request counts toward the stage it started in.\n\n")
+	} else {
+		report.WriteString("Virtual users send requests back to back; each request counts toward the stage it started in.\n\n")
+	}
+	report.WriteString("---\n\n")
+
+	for _, p := range summary.Providers {
+		fmt.Fprintf(&report, "## %s (%s)\n\n", p.Provider, p.Model)
+		report.WriteString("| Stage | Profile | Peak VUs | Requests | Success | Avg TTFT | P95 TTFT | P95 E2E | Avg Throughput |\n")
+		report.WriteString("|-------|---------|----------|----------|---------|----------|----------|---------|----------------|\n")
+		for _, st := range p.Stages {
+			fmt.Fprintf(&report, "| %d | %s | %d | %d | %.1f%% | %s | %s | %s | %.2f tok/s |\n",
+				st.Stage, st.Description, st.PeakVUs, st.Requests, st.SuccessRate,
+				formatDuration(st.AvgTTFT), formatDuration(st.P95TTFT), formatDuration(st.P95E2E), st.AvgThroughput)
+		}
+		report.WriteString("\n")
+
+		if len(p.Classes) > 0 {
+			report.WriteString("### Traffic Classes\n\n")
+			report.WriteString("| Class | Mix Share | Requests | Success | Avg TTFT | P95 TTFT | P95 E2E | Avg Throughput |\n")
+			report.WriteString("|-------|-----------|----------|---------|----------|----------|---------|----------------|\n")
+			for _, c := range p.Classes {
+				share := "-"
+				if c.Share > 0 {
+					share = fmt.Sprintf("%.0f%%", c.Share)
+				}
+				fmt.Fprintf(&report, "| %s | %s | %d | %.1f%% | %s | %s | %s | %.2f tok/s |\n",
+					c.Class, share, c.Requests, c.SuccessRate,
+					formatDuration(c.AvgTTFT), formatDuration(c.P95TTFT), formatDuration(c.P95E2E), c.AvgThroughput)
+			}
+			report.WriteString("\n")
+		}
+
+		if len(p.Turns) > 0 {
+			report.WriteString("### Latency by Conversation Turn\n\n")
+			report.WriteString("Each turn resends the whole conversation so far, so prompt size grows with the turn number.\n\n")
+			report.WriteString("| Turn | Requests | Failures | Avg Prompt Tokens | Avg TTFT | P95 TTFT | Avg E2E |\n")
+			report.WriteString("|------|----------|----------|-------------------|----------|----------|---------|\n")
+			for _, turn := range p.Turns {
+				fmt.Fprintf(&report, "| %d | %d | %d | %d | %s | %s | %s |\n",
+					turn.Turn, turn.Requests, turn.Failures, turn.AvgPromptTokens,
+					formatDuration(turn.AvgTTFT), formatDuration(turn.P95TTFT), formatDuration(turn.AvgE2E))
+			}
+			report.WriteString("\n")
+		}
+	}
+
+	report.WriteString("---\n\n")
+	fmt.Fprintf(&report, "*Report generated at %s*\n", time.Now().Format("2006-01-02 15:04:05"))
+	return report.String()
+}
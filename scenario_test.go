@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(body), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigFileScenario(t *testing.T) {
+	path := writeConfig(t, `
+[[slo]]
+metric = "TTFT"
+threshold = "1.5s"
+objective = 99
+
+[scenario]
+think_time = "uniform:3s-8s"
+mix = { chat = 70, long_form = 20, tool_calling = 10 }
+
+[[scenario.stages]]
+duration = "2m"
+target = 20
+
+[[scenario.stages]]
+duration = "5m"
+target = 20
+
+[[scenario.stages]]
+duration = "1m"
+target = 0
+`)
+	cfg, err := loadConfigFile(path)
+	if err != nil {
+		t.Fatalf("loadConfigFile failed: %v", err)
+	}
+	sc := cfg.Scenario
+	if sc == nil || len(sc.Stages) != 3 || sc.total() != 8*time.Minute || sc.ThinkTime.Max != 8*time.Second ||
+		sc.Mix[trafficLongForm] != 20 || len(cfg.SLOs) != 1 || cfg.SLOs[0].Metric != sloTTFT {
+		t.Fatalf("unexpected scenario: %+v", sc)
+	}
+	for i, want := range []string{"ramp 0→20 over 2m0s", "hold 20 for 5m0s", "ramp 20→0 over 1m0s"} {
+		if got := sc.describe(i); got != want {
+			t.Errorf("describe(%d) = %q, want %q", i, got, want)
+		}
+	}
+
+	for _, bad := range []string{
+		"[scenario]\nstages = []\n",
+		"[[scenario.stages]]\nduration = \"0s\"\ntarget = 1\n",
+		"[[scenario.stages]]\nduration = \"1m\"\ntarget = -1\n",
+		"[[scenario.stages]]\nduration = \"soon\"\ntarget = 1\n",
+		"[[scenario.stages]]\nduration = \"1m\"\ntargte = 1\n",
+		"[scenario]\nthink_time = \"normal:5s\"\n[[scenario.stages]]\nduration = \"1m\"\ntarget = 1\n",
+		"[scenario]\nmix = { chat = 1, images = 1 }\n[[scenario.stages]]\nduration = \"1m\"\ntarget = 1\n",
+	} {
+		if _, err := loadConfigFile(writeConfig(t, bad)); err == nil {
+			t.Errorf("config %q accepted", bad)
+		}
+	}
+}
+
+func TestScenarioTargetAt(t *testing.T) {
+	sc := scenarioConfig{Stages: []scenarioStage{
+		{Duration: configDuration{10 * time.Second}, Target: 20},
+		{Duration: configDuration{10 * time.Second}, Target: 20},
+		{Duration: configDuration{10 * time.Second}, Target: 10},
+	}}
+	for _, tc := range []struct {
+		elapsed       time.Duration
+		stage, target int
+		ok            bool
+	}{
+		{0, 0, 0, true},
+		{5 * time.Second, 0, 10, true},
+		{15 * time.Second, 1, 20, true},
+		{25 * time.Second, 2, 15, true},
+		{30 * time.Second, 2, 10, false},
+	} {
+		stage, target, ok := sc.targetAt(tc.elapsed)
+		if stage != tc.stage || target != tc.target || ok != tc.ok {
+			t.Errorf("targetAt(%s) = %d, %d, %v; want %d, %d, %v",
+				tc.elapsed, stage, target, ok, tc.stage, tc.target, tc.ok)
+		}
+	}
+}
+
+func TestRunScenarioWritesStageResults(t *testing.T) {
+	tke := testTokenizer(t)
+	server := mockSSEServer{chunkDelay: 10 * time.Millisecond, chunks: []string{"Once ", "upon ", "a time."}}.start(t)
+	defer server.Close()
+
+	dir := t.TempDir()
+	providers := []ProviderConfig{{Name: "mock", BaseURL: server.URL, APIKey: "k", Model: "m"}}
+	sc := scenarioConfig{Stages: []scenarioStage{
+		{Duration: configDuration{400 * time.Millisecond}, Target: 3},
+		{Duration: configDuration{300 * time.Millisecond}, Target: 0},
+	}}
+	if err := runScenario(providers, tke, ModeStreaming, false, sc, dir, dir, "test"); err != nil {
+		t.Fatalf("runScenario failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "scenario-summary.json"))
+	if err != nil {
+		t.Fatalf("missing scenario summary: %v", err)
+	}
+	var summary ScenarioSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatalf("invalid scenario summary: %v", err)
+	}
+	if len(summary.Providers) != 1 || len(summary.Providers[0].Stages) != 2 {
+		t.Fatalf("unexpected scenario summary: %+v", summary)
+	}
+	ramp := summary.Providers[0].Stages[0]
+	if ramp.Requests == 0 || ramp.Failures != 0 || ramp.PeakVUs < 2 || ramp.PeakVUs > 3 {
+		t.Fatalf("unexpected ramp-up stage: %+v", ramp)
+	}
+	report, err := os.ReadFile(filepath.Join(dir, "SCENARIO-REPORT.md"))
+	if err != nil || !strings.Contains(string(report), "ramp 0→3 over 400ms") {
+		t.Fatalf("expected stage profiles in the scenario report, got %v", err)
+	}
+}
+
+func TestRunScenarioConversationTurns(t *testing.T) {
+	tke := testTokenizer(t)
+	server := mockSSEServer{chunkDelay: 5 * time.Millisecond, chunks: []string{"Once ", "upon ", "a time."}}.start(t)
+	defer server.Close()
+
+	dir := t.TempDir()
+	providers := []ProviderConfig{{Name: "mock", BaseURL: server.URL, APIKey: "k", Model: "m"}}
+	sc := scenarioConfig{Turns: 3, Stages: []scenarioStage{{Duration: configDuration{500 * time.Millisecond}, Target: 2}}}
+	if err := runScenario(providers, tke, ModeStreaming, false, sc, dir, dir, "test"); err != nil {
+		t.Fatalf("runScenario failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "scenario-summary.json"))
+	if err != nil {
+		t.Fatalf("missing scenario summary: %v", err)
+	}
+	var summary ScenarioSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatalf("invalid scenario summary: %v", err)
+	}
+	turns := summary.Providers[0].Turns
+	if len(turns) != 3 {
+		t.Fatalf("expected 3 turns, got %+v", turns)
+	}
+	for i := 1; i < len(turns); i++ {
+		if turns[i].AvgPromptTokens <= turns[i-1].AvgPromptTokens {
+			t.Errorf("prompt did not grow from turn %d to %d: %+v", i, i+1, turns)
+		}
+	}
+}
+
+func TestRunScenarioTrafficMix(t *testing.T) {
+	tke := testTokenizer(t)
+	server := mockSSEServer{chunkDelay: 5 * time.Millisecond, chunks: []string{"Once ", "upon ", "a time."}}.start(t)
+	defer server.Close()
+
+	dir := t.TempDir()
+	providers := []ProviderConfig{{Name: "mock", BaseURL: server.URL, APIKey: "k", Model: "m"}}
+	sc := scenarioConfig{
+		Mix:    trafficMix{trafficChat: 1, trafficLongForm: 1},
+		Stages: []scenarioStage{{Duration: configDuration{400 * time.Millisecond}, Target: 3}},
+	}
+	if err := runScenario(providers, tke, ModeStreaming, false, sc, dir, dir, "test"); err != nil {
+		t.Fatalf("runScenario failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "scenario-summary.json"))
+	if err != nil {
+		t.Fatalf("missing scenario summary: %v", err)
+	}
+	var summary ScenarioSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatalf("invalid scenario summary: %v", err)
+	}
+	classes := summary.Providers[0].Classes
+	if len(classes) != 2 || classes[0].Class != trafficChat || classes[1].Class != trafficLongForm || classes[0].Share != 50 {
+		t.Fatalf("unexpected classes: %+v", classes)
+	}
+	if total := classes[0].Requests + classes[1].Requests; total != summary.Providers[0].Stages[0].Requests {
+		t.Errorf("class requests %d do not add up to the stage's %d", total, summary.Providers[0].Stages[0].Requests)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// requestScheduler admits benchmark requests. Every standard, long-story,
+// diagnostic, scenario and soak request asks it for a slot before it is sent
+// and releases the slot when it is done, so limits hold across all the
+// providers and runs a session fans out at once.
+type requestScheduler interface {
+	// acquire blocks until provider may send a request or ctx ends. The
+	// returned release must be called once the request is done.
+	acquire(ctx context.Context, provider string) (release func(), err error)
+}
+
+// unlimitedScheduler admits every request at once; it is the default.
+type unlimitedScheduler struct{}
+
+func (unlimitedScheduler) acquire(context.Context, string) (func(), error) {
+	return func() {}, nil
+}
+
+// sessionScheduler admits the session's requests; set with --max-in-flight,
+// --provider-max-in-flight and --provider-rps.
+var sessionScheduler requestScheduler = unlimitedScheduler{}
+
+// newRequestScheduler returns the scheduler for the limits, where 0 means no
+// limit.
+func newRequestScheduler(maxInFlight, providerMaxInFlight int, providerRPS float64) (requestScheduler, error) {
+	if maxInFlight < 0 || providerMaxInFlight < 0 || providerRPS < 0 {
+		return nil, fmt.Errorf("scheduler limits cannot be negative")
+	}
+	if maxInFlight == 0 && providerMaxInFlight == 0 && providerRPS == 0 {
+		return unlimitedScheduler{}, nil
+	}
+	s := &fairScheduler{maxInFlight: maxInFlight, providerMaxInFlight: providerMaxInFlight, providers: make(map[string]*scheduledProvider)}
+	if providerRPS > 0 {
+		s.interval = time.Duration(float64(time.Second) / providerRPS)
+	}
+	return s, nil
+}
+
+// fairScheduler enforces a global and a per-provider in-flight limit and a
+// per-provider request rate. Waiting requests are admitted round-robin across
+// providers, so a provider with many queued runs cannot starve the others.
+type fairScheduler struct {
+	maxInFlight         int
+	providerMaxInFlight int
+	// interval is the least time between two request starts of a provider.
+	interval time.Duration
+
+	mu        sync.Mutex
+	inFlight  int
+	providers map[string]*scheduledProvider
+	// order lists providers as first seen; next is where the round-robin
+	// resumes.
+	order []string
+	next  int
+	// timer wakes dispatch at timerAt, when the next admission is only held
+	// back by a provider's rate limit.
+	timer   *time.Timer
+	timerAt time.Time
+}
+
+// scheduledProvider is the scheduling state of one provider.
+type scheduledProvider struct {
+	inFlight  int
+	lastStart time.Time
+	waiting   []*schedulerWaiter
+}
+
+// schedulerWaiter is one request waiting for a slot; ready is closed once it
+// is admitted.
+type schedulerWaiter struct {
+	ready    chan struct{}
+	admitted bool
+}
+
+func (s *fairScheduler) acquire(ctx context.Context, provider string) (func(), error) {
+	w := &schedulerWaiter{ready: make(chan struct{})}
+	s.mu.Lock()
+	p, ok := s.providers[provider]
+	if !ok {
+		p = &scheduledProvider{}
+		s.providers[provider] = p
+		s.order = append(s.order, provider)
+	}
+	p.waiting = append(p.waiting, w)
+	s.dispatch()
+	s.mu.Unlock()
+
+	release := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.inFlight--
+		p.inFlight--
+		s.dispatch()
+	}
+	select {
+	case <-w.ready:
+		return release, nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		admitted := w.admitted
+		if !admitted {
+			for i, other := range p.waiting {
+				if other == w {
+					p.waiting = append(p.waiting[:i], p.waiting[i+1:]...)
+					break
+				}
+			}
+		}
+		s.mu.Unlock()
+		if admitted {
+			release()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// dispatch admits waiting requests, one provider at a time in round-robin
+// order, until the limits stop it. It is called with s.mu held.
+func (s *fairScheduler) dispatch() {
+	var nextStart time.Time
+	for s.maxInFlight == 0 || s.inFlight < s.maxInFlight {
+		now := time.Now()
+		admitted := false
+		for k := range s.order {
+			i := (s.next + k) % len(s.order)
+			p := s.providers[s.order[i]]
+			if len(p.waiting) == 0 || (s.providerMaxInFlight > 0 && p.inFlight >= s.providerMaxInFlight) {
+				continue
+			}
+			if start := p.lastStart.Add(s.interval); s.interval > 0 && now.Before(start) {
+				if nextStart.IsZero() || start.Before(nextStart) {
+					nextStart = start
+				}
+				continue
+			}
+			w := p.waiting[0]
+			p.waiting = p.waiting[1:]
+			w.admitted = true
+			close(w.ready)
+			s.inFlight++
+			p.inFlight++
+			p.lastStart = now
+			s.next = i + 1
+			admitted = true
+			break
+		}
+		if !admitted {
+			break
+		}
+	}
+	// A provider whose rate limit ends before the pending wake-up moves it
+	// earlier, so its request is not held back by another provider's limit
+	if !nextStart.IsZero() && (s.timer == nil || nextStart.Before(s.timerAt)) {
+		if s.timer != nil {
+			s.timer.Stop()
+		}
+		var timer *time.Timer
+		timer = time.AfterFunc(time.Until(nextStart), func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if s.timer == timer {
+				s.timer = nil
+			}
+			s.dispatch()
+		})
+		s.timer, s.timerAt = timer, nextStart
+	}
+}
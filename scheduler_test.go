@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// queued returns how many requests wait on s.
+func queued(s *fairScheduler) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, p := range s.providers {
+		n += len(p.waiting)
+	}
+	return n
+}
+
+func TestNewRequestScheduler(t *testing.T) {
+	if s, err := newRequestScheduler(0, 0, 0); err != nil || s != (unlimitedScheduler{}) {
+		t.Fatalf("expected the unlimited scheduler without limits, got %T, %v", s, err)
+	}
+	if _, err := newRequestScheduler(-1, 0, 0); err == nil {
+		t.Fatal("expected negative limits to be rejected")
+	}
+	s, err := newRequestScheduler(4, 2, 10)
+	if fair, ok := s.(*fairScheduler); err != nil || !ok || fair.interval != 100*time.Millisecond {
+		t.Fatalf("unexpected scheduler %+v, %v", s, err)
+	}
+}
+
+func TestFairSchedulerRoundRobin(t *testing.T) {
+	scheduler, _ := newRequestScheduler(1, 0, 0)
+	s := scheduler.(*fairScheduler)
+	ctx := context.Background()
+
+	release, err := s.acquire(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	admitted := make(chan string, 4)
+	enqueue := func(provider string) {
+		want := queued(s) + 1
+		go func() {
+			release, err := s.acquire(ctx, provider)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			admitted <- provider
+			release()
+		}()
+		for queued(s) != want {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	enqueue("a")
+	enqueue("a")
+	enqueue("b")
+
+	// Each admitted request releases its slot at once, so the next one is
+	// picked by the round-robin alone
+	release()
+	var order string
+	for range 3 {
+		order += <-admitted
+	}
+	if order != "baa" {
+		t.Fatalf("expected b to be admitted before a's second queued request, got %q", order)
+	}
+}
+
+func TestFairSchedulerProviderLimits(t *testing.T) {
+	scheduler, _ := newRequestScheduler(0, 1, 0)
+	release, err := scheduler.acquire(context.Background(), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := scheduler.acquire(ctx, "a"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a second request of a to wait, got %v", err)
+	}
+	releaseB, err := scheduler.acquire(context.Background(), "b")
+	if err != nil {
+		t.Fatalf("expected b to be admitted while a is busy, got %v", err)
+	}
+	releaseB()
+	release()
+	if queued(scheduler.(*fairScheduler)) != 0 {
+		t.Fatal("expected the abandoned request to leave the queue")
+	}
+
+	scheduler, _ = newRequestScheduler(0, 0, 20)
+	start := time.Now()
+	for range 2 {
+		release, err := scheduler.acquire(context.Background(), "a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed < 45*time.Millisecond {
+		t.Fatalf("expected the rate limit to space requests 50ms apart, took %s", elapsed)
+	}
+}
+
+func TestFairSchedulerStaggeredRateLimits(t *testing.T) {
+	// 200ms between starts: b starts first, a 100ms later, so b's window ends
+	// 100ms before a's
+	scheduler, _ := newRequestScheduler(0, 0, 5)
+	start := time.Now()
+	for i, provider := range []string{"b", "a"} {
+		if i > 0 {
+			time.Sleep(100 * time.Millisecond)
+		}
+		release, err := scheduler.acquire(context.Background(), provider)
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+
+	// a queues first and arms a wake-up for its window at 300ms; b's window
+	// ends at 200ms and must not wait for it
+	go func() {
+		if release, err := scheduler.acquire(context.Background(), "a"); err == nil {
+			release()
+		}
+	}()
+	for queued(scheduler.(*fairScheduler)) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	release, err := scheduler.acquire(context.Background(), "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if elapsed := time.Since(start); elapsed > 260*time.Millisecond {
+		t.Fatalf("expected b admitted when its window ends at 200ms, took %s", elapsed)
+	}
+}
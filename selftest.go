@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+const (
+	// selfTestFileName is where the selftest result is kept for later sessions.
+	selfTestFileName = "selftest.json"
+	// selfTestFirstToken and selfTestChunkDelay shape the paced mock stream used
+	// to check timing accuracy; selfTestChunks chunks of one word each.
+	selfTestFirstToken = 100 * time.Millisecond
+	selfTestChunkDelay = 10 * time.Millisecond
+	selfTestChunks     = 100
+	// selfTestBurstChunks is the length of the unpaced stream used for raw speed.
+	selfTestBurstChunks = 2000
+	// selfTestMaxTTFTError and selfTestMaxThroughputError bound the measurement
+	// error a concurrency level may add before it is considered inaccurate.
+	selfTestMaxTTFTError       = 25 * time.Millisecond
+	selfTestMaxThroughputError = 0.05
+	// ceilingWarnShare is the share of a measured ceiling at which real sessions
+	// are warned that the client may be limiting results.
+	ceilingWarnShare = 0.7
+)
+
+// SelfTestLevel is the outcome at one concurrency level.
+type SelfTestLevel struct {
+	Concurrency        int           `json:"concurrency"`
+	AggregateTPS       float64       `json:"aggregateTokensPerSec"`
+	P90TTFTError       time.Duration `json:"p90TtftError"`
+	ThroughputErrorPct float64       `json:"throughputErrorPct"`
+	Accurate           bool          `json:"accurate"`
+	Failures           int           `json:"failures"`
+}
+
+// SelfTestResult records the measurement ceiling of the client host.
+type SelfTestResult struct {
+	CreatedAt              time.Time       `json:"createdAt"`
+	ToolVersion            string          `json:"toolVersion"`
+	OS                     string          `json:"os"`
+	Arch                   string          `json:"arch"`
+	NumCPU                 int             `json:"numCpu"`
+	MaxTokensPerSec        float64         `json:"maxTokensPerSec"`
+	MaxAccurateConcurrency int             `json:"maxAccurateConcurrency"`
+	Levels                 []SelfTestLevel `json:"levels"`
+}
+
+// clientCeiling is the selftest result found for this host, if any.
+var clientCeiling *SelfTestResult
+
+// startMockServer serves handler on a loopback port until the returned stop is called.
+func startMockServer(handler http.Handler) (url string, stop func(), err error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, fmt.Errorf("error starting mock server: %w", err)
+	}
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if serveErr := server.Serve(listener); serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
+			log.Printf("Warning: mock server stopped: %v", serveErr)
+		}
+	}()
+	return "http://" + listener.Addr().String(), func() { _ = server.Close() }, nil
+}
+
+// runConcurrentStreams runs n simultaneous streams against config.
+func runConcurrentStreams(config ProviderConfig, tke *tiktoken.Tiktoken, n int) (outcomes []streamOutcome, wall time.Duration) {
+	outcomes = make([]streamOutcome, n)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			defer cancel()
+			outcomes[i] = raceStream(ctx, config, tke, fmt.Sprintf("selftest%d", i), nil)
+		}(i)
+	}
+	wg.Wait()
+	return outcomes, time.Since(start)
+}
+
+// streamThroughput is the generation throughput of one outcome, as the normal
+// modes compute it.
+func streamThroughput(o streamOutcome) float64 {
+	generation := o.end.Sub(o.firstToken).Seconds()
+	if generation <= 0 || o.tokens < 2 {
+		return 0
+	}
+	return float64(o.tokens-1) / generation
+}
+
+// measureSelfTestLevel measures raw aggregate speed and timing accuracy at one
+// concurrency level. baselineTPS is the paced per-stream throughput measured at
+// concurrency 1 (0 when measuring the baseline itself).
+func measureSelfTestLevel(burst, paced ProviderConfig, tke *tiktoken.Tiktoken, n int, baselineTPS float64) (SelfTestLevel, float64) {
+	level := SelfTestLevel{Concurrency: n}
+
+	outcomes, wall := runConcurrentStreams(burst, tke, n)
+	tokens := 0
+	for _, o := range outcomes {
+		if o.err != nil {
+			level.Failures++
+			continue
+		}
+		tokens += o.tokens
+	}
+	if wall > 0 {
+		level.AggregateTPS = float64(tokens) / wall.Seconds()
+	}
+
+	outcomes, _ = runConcurrentStreams(paced, tke, n)
+	ttftErrors := make([]time.Duration, 0, n)
+	throughputSum, ok := 0.0, 0
+	for _, o := range outcomes {
+		if o.err != nil {
+			level.Failures++
+			continue
+		}
+		ttftErrors = append(ttftErrors, o.firstToken.Sub(o.start)-selfTestFirstToken)
+		throughputSum += streamThroughput(o)
+		ok++
+	}
+	if ok == 0 {
+		return level, 0
+	}
+	avgTPS := throughputSum / float64(ok)
+	level.P90TTFTError = percentileDuration(ttftErrors, 90)
+	if baselineTPS > 0 {
+		level.ThroughputErrorPct = 100 * (baselineTPS - avgTPS) / baselineTPS
+	}
+	level.Accurate = level.Failures == 0 && level.P90TTFTError <= selfTestMaxTTFTError &&
+		level.ThroughputErrorPct <= 100*selfTestMaxThroughputError
+	return level, avgTPS
+}
+
+// runSelfTestLevels measures each concurrency level in turn, stopping after two
+// consecutive inaccurate levels.
+func runSelfTestLevels(tke *tiktoken.Tiktoken, levels []int) (SelfTestResult, error) {
+	words := make([]string, selfTestBurstChunks)
+	for i := range words {
+		words[i] = " word"
+	}
+	burstURL, stopBurst, err := startMockServer(&mockSSEHandler{chunks: words})
+	if err != nil {
+		return SelfTestResult{}, err
+	}
+	defer stopBurst()
+	pacedURL, stopPaced, err := startMockServer(&mockSSEHandler{
+		firstTokenDelay: selfTestFirstToken,
+		chunkDelay:      selfTestChunkDelay,
+		chunks:          words[:selfTestChunks],
+	})
+	if err != nil {
+		return SelfTestResult{}, err
+	}
+	defer stopPaced()
+
+	burst := ProviderConfig{Name: "selftest-burst", BaseURL: burstURL, APIKey: "selftest", Model: "mock-model"}
+	paced := ProviderConfig{Name: "selftest-paced", BaseURL: pacedURL, APIKey: "selftest", Model: "mock-model"}
+
+	result := SelfTestResult{
+		CreatedAt:   time.Now(),
+		ToolVersion: version,
+		OS:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+		NumCPU:      runtime.NumCPU(),
+	}
+	baselineTPS := 0.0
+	misses := 0
+	for _, n := range levels {
+		level, avgTPS := measureSelfTestLevel(burst, paced, tke, n, baselineTPS)
+		if baselineTPS == 0 {
+			baselineTPS = avgTPS
+		}
+		result.Levels = append(result.Levels, level)
+		result.MaxTokensPerSec = max(result.MaxTokensPerSec, level.AggregateTPS)
+		log.Printf("Selftest concurrency %d: %.0f tok/s aggregate, p90 TTFT error %s, throughput error %.1f%%, accurate=%t",
+			n, level.AggregateTPS, formatDuration(level.P90TTFTError), level.ThroughputErrorPct, level.Accurate)
+		if level.Accurate {
+			result.MaxAccurateConcurrency = n
+			misses = 0
+		} else if misses++; misses >= 2 {
+			break
+		}
+	}
+	return result, nil
+}
+
+// selfTestPath returns where the selftest result for this host is stored.
+func selfTestPath() string {
+	return filepath.Join("results", selfTestFileName)
+}
+
+// loadSelfTest reads a previously saved selftest result.
+func loadSelfTest(path string) (*SelfTestResult, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	var result SelfTestResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	return &result, nil
+}
+
+// ceilingWarnings compares a session's peak load against the selftest ceiling.
+func ceilingWarnings(ceiling *SelfTestResult, peakInFlight int, avgThroughput float64) []string {
+	if ceiling == nil {
+		return nil
+	}
+	var warnings []string
+	if ceiling.MaxAccurateConcurrency > 0 && float64(peakInFlight) >= ceilingWarnShare*float64(ceiling.MaxAccurateConcurrency) {
+		warnings = append(warnings, fmt.Sprintf(
+			"peak of %d concurrent streams is close to this host's accurate limit of %d (selftest); consider fewer workers or a bigger client",
+			peakInFlight, ceiling.MaxAccurateConcurrency))
+	}
+	if load := float64(peakInFlight) * avgThroughput; ceiling.MaxTokensPerSec > 0 && load >= ceilingWarnShare*ceiling.MaxTokensPerSec {
+		warnings = append(warnings, fmt.Sprintf(
+			"estimated peak load of %.0f tok/s is close to this host's ceiling of %.0f tok/s (selftest)",
+			load, ceiling.MaxTokensPerSec))
+	}
+	return warnings
+}
+
+// runSelfTest implements the "selftest" subcommand.
+func runSelfTest(args []string) {
+	fs := flag.NewFlagSet("selftest", flag.ExitOnError)
+	maxConcurrency := fs.Int("max-concurrency", 256, "Highest concurrency level to try")
+	tokenizerDir := fs.String("tokenizer-dir", "", "Directory of bundled tokenizer files")
+	offline := fs.Bool("offline", false, "Never download tokenizer files")
+	if err := fs.Parse(args); err != nil {
+		log.Fatalf("Error parsing flags: %v", err)
+	}
+
+	tiktoken.SetBpeLoader(&bundleBpeLoader{dir: *tokenizerDir, offline: *offline})
+	tke, err := tiktoken.GetEncoding("cl100k_base")
+	if err != nil {
+		log.Fatalf("Error getting tokenizer: %v", err)
+	}
+
+	var levels []int
+	for n := 1; n <= *maxConcurrency; n *= 2 {
+		levels = append(levels, n)
+	}
+	log.Printf("=== SELFTEST: measuring this host against a local mock server (concurrency up to %d) ===", *maxConcurrency)
+	result, err := runSelfTestLevels(tke, levels)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	if err := os.MkdirAll("results", 0750); err != nil {
+		log.Fatalf("Error creating results directory: %v", err)
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatalf("Error marshaling selftest result: %v", err)
+	}
+	if err := os.WriteFile(selfTestPath(), data, 0600); err != nil {
+		log.Fatalf("Error writing selftest result: %v", err)
+	}
+
+	var guidance strings.Builder
+	fmt.Fprintf(&guidance, "Max aggregate throughput this host can parse: %.0f tok/s. ", result.MaxTokensPerSec)
+	if result.MaxAccurateConcurrency > 0 {
+		fmt.Fprintf(&guidance, "Timings stay accurate up to %d concurrent streams. ", result.MaxAccurateConcurrency)
+	} else {
+		guidance.WriteString("Timings were inaccurate even for a single stream; results from this host are unreliable. ")
+	}
+	fmt.Fprintf(&guidance, "Reports will warn when a session exceeds %.0f%% of these limits.", 100*ceilingWarnShare)
+	log.Println(guidance.String())
+	log.Printf("Selftest result saved: %s", selfTestPath())
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunSelfTestLevels(t *testing.T) {
+	tke := testTokenizer(t)
+	result, err := runSelfTestLevels(tke, []int{1, 4})
+	if err != nil {
+		t.Fatalf("runSelfTestLevels failed: %v", err)
+	}
+	if len(result.Levels) != 2 || result.MaxTokensPerSec <= 0 {
+		t.Fatalf("unexpected selftest result: %+v", result)
+	}
+	for _, level := range result.Levels {
+		if level.Failures != 0 || level.AggregateTPS <= 0 {
+			t.Errorf("unexpected level: %+v", level)
+		}
+	}
+}
+
+func TestCeilingWarnings(t *testing.T) {
+	ceiling := &SelfTestResult{MaxTokensPerSec: 10000, MaxAccurateConcurrency: 64}
+	if w := ceilingWarnings(nil, 500, 100); w != nil {
+		t.Fatalf("expected no warnings without a selftest, got %v", w)
+	}
+	if w := ceilingWarnings(ceiling, 10, 100); len(w) != 0 {
+		t.Fatalf("expected no warnings well below the ceiling, got %v", w)
+	}
+	w := ceilingWarnings(ceiling, 50, 200)
+	if len(w) != 2 || !strings.Contains(w[0], "50 concurrent streams") || !strings.Contains(w[1], "10000 tok/s") {
+		t.Fatalf("expected concurrency and throughput warnings, got %v", w)
+	}
+}
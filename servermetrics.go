@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// serverScrapeInterval is how often a provider's <PREFIX>_METRICS_URL is scraped
+// during a run; set with --server-metrics-interval.
+var serverScrapeInterval = 2 * time.Second
+
+// serverScrapeTimeout bounds one scrape so a stuck endpoint cannot stall a run.
+const serverScrapeTimeout = 5 * time.Second
+
+// Prometheus metric names exported by the supported inference servers. Newer
+// vLLM releases renamed the KV-cache gauge, so both names are accepted.
+var (
+	queueMetrics   = []string{"vllm:num_requests_waiting", "tgi_queue_size"}
+	batchMetrics   = []string{"vllm:num_requests_running", "tgi_batch_current_size"}
+	kvCacheMetrics = []string{"vllm:kv_cache_usage_perc", "vllm:gpu_cache_usage_perc"}
+)
+
+// LiteLLM proxy counters, compared between the first and last scrape of a run.
+// Latencies are histograms in seconds, averaged as sum over count.
+const (
+	liteLLMSpendMetric   = "litellm_spend_metric_total"
+	liteLLMLatencyMetric = "litellm_llm_api_latency_metric"
+	liteLLMTTFTMetric    = "litellm_llm_api_time_to_first_token_metric"
+)
+
+// liteLLMCounters are the series kept for the run deltas.
+var liteLLMCounters = []string{
+	liteLLMSpendMetric,
+	liteLLMLatencyMetric + "_sum", liteLLMLatencyMetric + "_count",
+	liteLLMTTFTMetric + "_sum", liteLLMTTFTMetric + "_count",
+}
+
+// MetricGauge summarizes a server-reported gauge over the scrapes of a run.
+type MetricGauge struct {
+	Avg float64 `json:"avg"`
+	Max float64 `json:"max"`
+}
+
+// ServerMetrics is what a self-hosted server reported about itself while it was
+// benchmarked.
+type ServerMetrics struct {
+	Server       string       `json:"server"`
+	Samples      int          `json:"samples"`
+	ScrapeErrors int          `json:"scrapeErrors,omitempty"`
+	QueueDepth   *MetricGauge `json:"queueDepth,omitempty"`
+	BatchSize    *MetricGauge `json:"batchSize,omitempty"`
+	// KVCacheUsage is in percent of the server's KV-cache capacity.
+	KVCacheUsage *MetricGauge `json:"kvCacheUsage,omitempty"`
+	// Spend, UpstreamLatency and UpstreamTTFT are what a LiteLLM proxy recorded
+	// during the run: USD spent and the mean latencies of its upstream calls.
+	Spend           float64       `json:"spend,omitempty"`
+	UpstreamLatency time.Duration `json:"upstreamLatencyMs,omitempty"`
+	UpstreamTTFT    time.Duration `json:"upstreamTtftMs,omitempty"`
+}
+
+// parsePrometheus reads the Prometheus text exposition format and returns each
+// metric's value summed over its label sets (e.g. across model replicas).
+func parsePrometheus(r io.Reader) (map[string]float64, error) {
+	values := make(map[string]float64)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, rest := line, ""
+		if i := strings.IndexByte(line, '{'); i >= 0 {
+			end := strings.LastIndexByte(line, '}')
+			if end < i {
+				continue
+			}
+			name, rest = line[:i], line[end+1:]
+		} else if i := strings.IndexAny(line, " \t"); i >= 0 {
+			name, rest = line[:i], line[i:]
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+		values[name] += value
+	}
+	return values, scanner.Err()
+}
+
+// gaugeTally accumulates one gauge across scrapes.
+type gaugeTally struct {
+	sum, max float64
+	n        int
+}
+
+func (g *gaugeTally) add(v float64) {
+	if g.n == 0 || v > g.max {
+		g.max = v
+	}
+	g.sum += v
+	g.n++
+}
+
+func (g *gaugeTally) gauge() *MetricGauge {
+	if g.n == 0 {
+		return nil
+	}
+	return &MetricGauge{Avg: g.sum / float64(g.n), Max: g.max}
+}
+
+// firstMetric returns the value of the first of names present in values.
+func firstMetric(values map[string]float64, names []string) (float64, bool) {
+	for _, name := range names {
+		if v, ok := values[name]; ok {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// detectServer names the inference server from its metric prefixes.
+func detectServer(values map[string]float64) string {
+	for name := range values {
+		switch {
+		case strings.HasPrefix(name, "vllm:"):
+			return "vllm"
+		case strings.HasPrefix(name, "tgi_"):
+			return "tgi"
+		case strings.HasPrefix(name, "litellm_"):
+			return proxyLiteLLM
+		}
+	}
+	return ""
+}
+
+// serverScraper polls a server's metrics endpoint in the background.
+type serverScraper struct {
+	url    string
+	logger *log.Logger
+	client *http.Client
+	stop   chan struct{}
+	done   chan struct{}
+
+	mu                sync.Mutex
+	metrics           ServerMetrics
+	queue, batch, kvs gaugeTally
+	// first and last hold the liteLLMCounters of the first and latest scrape.
+	first, last map[string]float64
+}
+
+// startServerScrape begins scraping config's metrics endpoint until finish is
+// called. It returns nil when the provider has no metrics URL.
+func startServerScrape(config ProviderConfig, logger *log.Logger) *serverScraper {
+	if config.MetricsURL == "" {
+		return nil
+	}
+	s := &serverScraper{
+		url:    config.MetricsURL,
+		logger: logger,
+		client: &http.Client{Timeout: serverScrapeTimeout},
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	logger.Printf("[%s] Scraping server metrics from %s every %s", config.Name, config.MetricsURL, serverScrapeInterval)
+	go s.run()
+	return s
+}
+
+func (s *serverScraper) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(serverScrapeInterval)
+	defer ticker.Stop()
+	for {
+		s.scrape()
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scrape takes one sample of the server's gauges.
+func (s *serverScraper) scrape() {
+	values, err := s.fetch()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		if s.metrics.ScrapeErrors == 0 {
+			s.logger.Printf("Warning: server metrics scrape failed: %v", err)
+		}
+		s.metrics.ScrapeErrors++
+		return
+	}
+	s.metrics.Samples++
+	if s.metrics.Server == "" {
+		s.metrics.Server = detectServer(values)
+	}
+	if v, ok := firstMetric(values, queueMetrics); ok {
+		s.queue.add(v)
+	}
+	if v, ok := firstMetric(values, batchMetrics); ok {
+		s.batch.add(v)
+	}
+	if v, ok := firstMetric(values, kvCacheMetrics); ok {
+		s.kvs.add(100 * v)
+	}
+	counters := make(map[string]float64)
+	for _, name := range liteLLMCounters {
+		if v, ok := values[name]; ok {
+			counters[name] = v
+		}
+	}
+	if s.first == nil {
+		s.first = counters
+	}
+	s.last = counters
+}
+
+// counterDelta is how much a counter grew between the first and last scrape.
+func (s *serverScraper) counterDelta(name string) float64 {
+	return s.last[name] - s.first[name]
+}
+
+// histogramMean averages a seconds histogram over the observations made
+// between the first and last scrape.
+func (s *serverScraper) histogramMean(name string) time.Duration {
+	count := s.counterDelta(name + "_count")
+	if count <= 0 {
+		return 0
+	}
+	return time.Duration(s.counterDelta(name+"_sum") / count * float64(time.Second))
+}
+
+func (s *serverScraper) fetch() (map[string]float64, error) {
+	resp, err := s.client.Get(s.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metrics endpoint returned %s", resp.Status)
+	}
+	return parsePrometheus(resp.Body)
+}
+
+// finish stops scraping and returns the summary, or nil if nothing was scraped.
+// It is safe to call on a nil scraper.
+func (s *serverScraper) finish() *ServerMetrics {
+	if s == nil {
+		return nil
+	}
+	close(s.stop)
+	<-s.done
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.metrics.Samples == 0 && s.metrics.ScrapeErrors == 0 {
+		return nil
+	}
+	metrics := s.metrics
+	metrics.QueueDepth = s.queue.gauge()
+	metrics.BatchSize = s.batch.gauge()
+	metrics.KVCacheUsage = s.kvs.gauge()
+	if metrics.Server == proxyLiteLLM {
+		metrics.Spend = s.counterDelta(liteLLMSpendMetric)
+		metrics.UpstreamLatency = s.histogramMean(liteLLMLatencyMetric)
+		metrics.UpstreamTTFT = s.histogramMean(liteLLMTTFTMetric)
+	}
+	return &metrics
+}
+
+// formatGauge renders a gauge as "avg / max".
+func formatGauge(g *MetricGauge, unit string) string {
+	if g == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%s / %.1f%s", g.Avg, unit, g.Max, unit)
+}
+
+// serverMetricsRow is one entry of the server-side metrics table.
+type serverMetricsRow struct {
+	provider string
+	mode     string
+	metrics  *ServerMetrics
+}
+
+// writeServerMetricsRows renders server-reported gauges next to the client view.
+func writeServerMetricsRows(report *strings.Builder, rows []serverMetricsRow) {
+	if len(rows) == 0 {
+		return
+	}
+	report.WriteString("## Server-Side Metrics\n\n")
+	report.WriteString("Gauges scraped from the server's Prometheus endpoint during the run (average / peak). " +
+		"A growing queue or a full KV cache points at server saturation rather than network latency.\n\n")
+	report.WriteString("| Provider | Mode | Server | Scrapes | Queue Depth | Batch Size | KV Cache |\n")
+	report.WriteString("|----------|------|--------|---------|-------------|------------|----------|\n")
+	for _, r := range rows {
+		m := r.metrics
+		server := m.Server
+		if server == "" {
+			server = "unknown"
+		}
+		scrapes := strconv.Itoa(m.Samples)
+		if m.ScrapeErrors > 0 {
+			scrapes += fmt.Sprintf(" (%d failed)", m.ScrapeErrors)
+		}
+		fmt.Fprintf(report, "| %s | %s | %s | %s | %s | %s | %s |\n", r.provider, r.mode, server, scrapes,
+			formatGauge(m.QueueDepth, ""), formatGauge(m.BatchSize, ""), formatGauge(m.KVCacheUsage, "%"))
+	}
+	report.WriteString("\n")
+}
+
+// writeServerMetricsSection adds server-side metrics for results that have them.
+func writeServerMetricsSection(report *strings.Builder, results []TestResult) {
+	rows := make([]serverMetricsRow, 0, len(results))
+	for _, r := range results {
+		if r.ServerMetrics != nil {
+			rows = append(rows, serverMetricsRow{providerLabel(r.Provider, r.Env), r.Mode, r.ServerMetrics})
+		}
+	}
+	writeServerMetricsRows(report, rows)
+}
+
+// writeDiagnosticServerMetricsSection is the diagnostic-report counterpart of
+// writeServerMetricsSection.
+func writeDiagnosticServerMetricsSection(report *strings.Builder, results []DiagnosticSummary) {
+	rows := make([]serverMetricsRow, 0, len(results))
+	for _, r := range results {
+		if r.ServerMetrics != nil {
+			rows = append(rows, serverMetricsRow{providerLabel(r.Provider, r.Env), r.Mode, r.ServerMetrics})
+		}
+	}
+	writeServerMetricsRows(report, rows)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParsePrometheus(t *testing.T) {
+	text := `# HELP vllm:num_requests_waiting Number of requests waiting.
+# TYPE vllm:num_requests_waiting gauge
+vllm:num_requests_waiting{engine="0",model_name="m"} 3.0
+vllm:num_requests_waiting{engine="1",model_name="m"} 2.0
+vllm:kv_cache_usage_perc{model_name="a b"} 0.5
+tgi_queue_size 7
+malformed_line
+`
+	values, err := parsePrometheus(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["vllm:num_requests_waiting"] != 5 || values["vllm:kv_cache_usage_perc"] != 0.5 || values["tgi_queue_size"] != 7 {
+		t.Errorf("unexpected values %v", values)
+	}
+	if _, ok := values["malformed_line"]; ok {
+		t.Error("line without a value parsed")
+	}
+}
+
+func TestServerScrape(t *testing.T) {
+	var scrapes atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := scrapes.Add(1)
+		fmt.Fprintf(w, "vllm:num_requests_waiting %d\nvllm:num_requests_running 8\nvllm:gpu_cache_usage_perc 0.%d\n", n, n)
+	}))
+	defer srv.Close()
+
+	defer func(old time.Duration) { serverScrapeInterval = old }(serverScrapeInterval)
+	serverScrapeInterval = 10 * time.Millisecond
+	if startServerScrape(ProviderConfig{Name: "none"}, log.New(io.Discard, "", 0)) != nil {
+		t.Fatal("scraper started without a metrics URL")
+	}
+	scraper := startServerScrape(ProviderConfig{Name: "vllm", MetricsURL: srv.URL}, log.New(io.Discard, "", 0))
+	time.Sleep(35 * time.Millisecond)
+	metrics := scraper.finish()
+
+	if metrics == nil || metrics.Server != "vllm" || metrics.Samples < 2 {
+		t.Fatalf("unexpected metrics %+v", metrics)
+	}
+	if metrics.BatchSize.Max != 8 || metrics.QueueDepth.Max != float64(metrics.Samples) || metrics.KVCacheUsage.Max <= metrics.KVCacheUsage.Avg {
+		t.Errorf("unexpected gauges: queue %+v batch %+v kv %+v", metrics.QueueDepth, metrics.BatchSize, metrics.KVCacheUsage)
+	}
+
+	var report strings.Builder
+	writeServerMetricsSection(&report, []TestResult{{Provider: "vllm", Mode: "streaming", ServerMetrics: metrics}})
+	if !strings.Contains(report.String(), "| vllm | streaming | vllm |") {
+		t.Errorf("unexpected section:\n%s", report.String())
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxSessionLabelLength bounds session names and tags, which end up in folder
+// names.
+const maxSessionLabelLength = 64
+
+// sessionTags label the session's purpose; set with --tags, or read back from
+// the manifests by the report subcommand.
+var sessionTags []string
+
+// validateSessionLabel checks that a session name or tag is safe to use in a
+// folder name: letters, digits, '-', '_' and '.'.
+func validateSessionLabel(kind, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s must not be empty", kind)
+	}
+	if len(value) > maxSessionLabelLength {
+		return fmt.Errorf("%s %q is longer than %d characters", kind, value, maxSessionLabelLength)
+	}
+	if strings.Trim(value, ".") == "" {
+		return fmt.Errorf("%s %q is not a usable name", kind, value)
+	}
+	for _, c := range value {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return fmt.Errorf("%s %q may only contain letters, digits, '-', '_' and '.'", kind, value)
+		}
+	}
+	return nil
+}
+
+// parseSessionTags parses a comma-separated --tags value, dropping duplicates.
+func parseSessionTags(value string) ([]string, error) {
+	var tags []string
+	for _, tag := range splitList(value) {
+		if err := validateSessionLabel("tag", tag); err != nil {
+			return nil, err
+		}
+		if !containsFold(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// sessionFolderID is the session's identifier and folder suffix: the timestamp,
+// followed by the session name when one is given, e.g.
+// "20251110-004615-pre-launch-check".
+func sessionFolderID(timestamp, name string) string {
+	if name == "" {
+		return timestamp
+	}
+	return timestamp + "-" + name
+}
+
+// manifestTags collects the tags recorded in the sessions' manifests, in
+// first-seen order. Sessions without a readable manifest contribute none.
+func manifestTags(sessions []string) []string {
+	var tags []string
+	for _, session := range sessions {
+		manifest, err := loadManifest(session)
+		if err != nil {
+			continue
+		}
+		for _, tag := range manifest.Tags {
+			if !containsFold(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// tagsNote lists the session tags under a report header.
+func tagsNote(report *strings.Builder) {
+	if len(sessionTags) > 0 {
+		fmt.Fprintf(report, "**Tags:** %s\n\n", strings.Join(sessionTags, ", "))
+	}
+}
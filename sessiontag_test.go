@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateSessionLabel(t *testing.T) {
+	for _, ok := range []string{"pre-launch-check", "v1.2_rc", "EU"} {
+		if err := validateSessionLabel("tag", ok); err != nil {
+			t.Errorf("validateSessionLabel(%q) = %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"", "..", "has space", "a/b", "ünïcode", strings.Repeat("x", maxSessionLabelLength+1)} {
+		if err := validateSessionLabel("tag", bad); err == nil {
+			t.Errorf("validateSessionLabel(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestParseSessionTags(t *testing.T) {
+	tags, err := parseSessionTags(" nightly, eu-west,,Nightly ")
+	if err != nil {
+		t.Fatalf("parseSessionTags failed: %v", err)
+	}
+	if strings.Join(tags, ",") != "nightly,eu-west" {
+		t.Errorf("tags = %v, want [nightly eu-west]", tags)
+	}
+	if _, err := parseSessionTags("ok,not ok"); err == nil {
+		t.Error("parseSessionTags accepted a tag with a space")
+	}
+}
+
+func TestSessionFolderID(t *testing.T) {
+	if got := sessionFolderID("20251110-004615", ""); got != "20251110-004615" {
+		t.Errorf("unnamed = %q", got)
+	}
+	if got := sessionFolderID("20251110-004615", "pre-launch-check"); got != "20251110-004615-pre-launch-check" {
+		t.Errorf("named = %q", got)
+	}
+}
+
+func TestSessionTagsInManifestAndReport(t *testing.T) {
+	root := t.TempDir()
+	sessions := map[string][]string{
+		"20251110-004615": {"nightly", "eu-west"},
+		"20251111-093000": {"EU-west", "canary"},
+	}
+	for _, timestamp := range []string{"20251110-004615", "20251111-093000"} {
+		dir := filepath.Join(root, "session-"+sessionFolderID(timestamp, "check"))
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			t.Fatal(err)
+		}
+		manifest := buildManifest(filepath.Base(dir), nil, string(ModeStreaming), nil)
+		manifest.Name = "check"
+		manifest.Tags = sessions[timestamp]
+		if err := writeManifest(dir, manifest); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dirs, _ := filepath.Glob(filepath.Join(root, "*"))
+	got := manifestTags(dirs)
+	if strings.Join(got, ",") != "nightly,eu-west,canary" {
+		t.Errorf("manifestTags = %v, want [nightly eu-west canary]", got)
+	}
+
+	saved := sessionTags
+	defer func() { sessionTags = saved }()
+	sessionTags = got
+	var report strings.Builder
+	tagsNote(&report)
+	if report.String() != "**Tags:** nightly, eu-west, canary\n\n" {
+		t.Errorf("tagsNote = %q", report.String())
+	}
+	sessionTags = nil
+	report.Reset()
+	tagsNote(&report)
+	if report.Len() != 0 {
+		t.Errorf("tagsNote without tags = %q", report.String())
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// signaturesFileName holds a session's signed file digests.
+const signaturesFileName = "SIGNATURES.json"
+
+// signaturePayloadHeader starts the signed text, so a signature cannot be
+// replayed for another purpose or format version.
+const signaturePayloadHeader = "llm-api-speed session signature v1\n"
+
+// sessionSigningKey signs each session's files when set with --sign-key.
+var sessionSigningKey ed25519.PrivateKey
+
+// SessionSignatures is SIGNATURES.json: the SHA-256 of every file in a session
+// folder and an Ed25519 signature over them.
+type SessionSignatures struct {
+	Algorithm string    `json:"algorithm"`
+	SignedAt  time.Time `json:"signedAt"`
+	// PublicKey is the signer's key, PEM-encoded. Verifying against it alone
+	// only proves the files match each other; pass the expected key to verify
+	// to prove who signed them.
+	PublicKey string `json:"publicKey"`
+	// Files maps slash-separated paths relative to the session folder to
+	// hex-encoded SHA-256 digests.
+	Files     map[string]string `json:"files"`
+	Signature string            `json:"signature"`
+}
+
+// signaturePayload is the text that is signed: the header, then one
+// "<digest>  <path>" line per file in path order, like sha256sum output.
+func signaturePayload(files map[string]string) []byte {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var b strings.Builder
+	b.WriteString(signaturePayloadHeader)
+	for _, path := range paths {
+		fmt.Fprintf(&b, "%s  %s\n", files[path], path)
+	}
+	return []byte(b.String())
+}
+
+// hashSessionFiles digests every file under dir except SIGNATURES.json.
+func hashSessionFiles(dir string) (map[string]string, error) {
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == signaturesFileName {
+			return nil
+		}
+		data, err := os.ReadFile(filepath.Clean(path))
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		files[rel] = hex.EncodeToString(sum[:])
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error hashing session files: %w", err)
+	}
+	return files, nil
+}
+
+// loadSigningKey reads an Ed25519 private key in PKCS#8 PEM form, as written
+// by "openssl genpkey -algorithm ed25519".
+func loadSigningKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing private key %s: %w", path, err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key %s is %T, not Ed25519", path, key)
+	}
+	return priv, nil
+}
+
+// loadVerifyKey reads an Ed25519 public key in PKIX PEM form, as written by
+// "openssl pkey -pubout".
+func loadVerifyKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	return parsePublicKeyPEM(block)
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("error reading key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", path)
+	}
+	return block, nil
+}
+
+func parsePublicKeyPEM(block *pem.Block) (ed25519.PublicKey, error) {
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing public key: %w", err)
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key is %T, not Ed25519", key)
+	}
+	return pub, nil
+}
+
+// signSession writes SIGNATURES.json into dir, covering every file there.
+func signSession(dir string, key ed25519.PrivateKey) error {
+	files, err := hashSessionFiles(dir)
+	if err != nil {
+		return err
+	}
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return fmt.Errorf("error encoding public key: %w", err)
+	}
+	sigs := SessionSignatures{
+		Algorithm: "ed25519",
+		SignedAt:  time.Now(),
+		PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+		Files:     files,
+		Signature: hex.EncodeToString(ed25519.Sign(key, signaturePayload(files))),
+	}
+	data, err := json.MarshalIndent(sigs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling signatures: %w", err)
+	}
+	filename := filepath.Join(dir, signaturesFileName)
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		return fmt.Errorf("error writing signatures: %w", err)
+	}
+	log.Printf("Session signed: %s (%d files)", filename, len(files))
+	return nil
+}
+
+// signSessionIfEnabled signs dir with --sign-key, logging rather than failing,
+// since the results themselves are already saved.
+func signSessionIfEnabled(dir string) {
+	if sessionSigningKey == nil {
+		return
+	}
+	if err := signSession(dir, sessionSigningKey); err != nil {
+		log.Printf("Warning: Failed to sign session: %v", err)
+	}
+}
+
+// verifyReport is the outcome of verifying a session folder.
+type verifyReport struct {
+	// Modified and Missing are signed files that changed or disappeared;
+	// either fails verification.
+	Modified, Missing []string
+	// Unsigned are files added after signing, e.g. published-results.json;
+	// they are reported but not covered by the signature.
+	Unsigned []string
+	// TrustedKey is set when the signature was checked against a key given by
+	// the verifier rather than the one embedded in the file.
+	TrustedKey bool
+}
+
+// verifySession checks dir's SIGNATURES.json: the signature over the recorded
+// digests, then every file against its digest. With a nil trusted key the
+// embedded public key is used, which detects accidental or careless edits but
+// not a forger who re-signs with their own key.
+func verifySession(dir string, trusted ed25519.PublicKey) (verifyReport, error) {
+	var report verifyReport
+	data, err := os.ReadFile(filepath.Clean(filepath.Join(dir, signaturesFileName)))
+	if err != nil {
+		return report, fmt.Errorf("error reading signatures: %w", err)
+	}
+	var sigs SessionSignatures
+	if err := json.Unmarshal(data, &sigs); err != nil {
+		return report, fmt.Errorf("error parsing %s: %w", signaturesFileName, err)
+	}
+	if sigs.Algorithm != "ed25519" {
+		return report, fmt.Errorf("unsupported signature algorithm %q", sigs.Algorithm)
+	}
+	key := trusted
+	if key == nil {
+		block, _ := pem.Decode([]byte(sigs.PublicKey))
+		if block == nil {
+			return report, errors.New("signatures carry no public key")
+		}
+		if key, err = parsePublicKeyPEM(block); err != nil {
+			return report, err
+		}
+	}
+	report.TrustedKey = trusted != nil
+	sig, err := hex.DecodeString(sigs.Signature)
+	if err != nil || !ed25519.Verify(key, signaturePayload(sigs.Files), sig) {
+		return report, errors.New("signature does not match the recorded digests or the key")
+	}
+
+	current, err := hashSessionFiles(dir)
+	if err != nil {
+		return report, err
+	}
+	for path, digest := range sigs.Files {
+		switch got, ok := current[path]; {
+		case !ok:
+			report.Missing = append(report.Missing, path)
+		case got != digest:
+			report.Modified = append(report.Modified, path)
+		}
+	}
+	for path := range current {
+		if _, ok := sigs.Files[path]; !ok {
+			report.Unsigned = append(report.Unsigned, path)
+		}
+	}
+	sort.Strings(report.Modified)
+	sort.Strings(report.Missing)
+	sort.Strings(report.Unsigned)
+	return report, nil
+}
+
+// ok reports whether every signed file is intact.
+func (r verifyReport) ok() bool {
+	return len(r.Modified) == 0 && len(r.Missing) == 0
+}
+
+// runVerify implements the "verify" subcommand.
+func runVerify(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	keyPath := fs.String("key", "", "Ed25519 public key (PEM) the session must be signed with; without it only the embedded key is checked")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: llm-api-speed verify [--key signer.pub] <session>...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		log.Fatalf("Error parsing flags: %v", err)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	var trusted ed25519.PublicKey
+	if *keyPath != "" {
+		key, err := loadVerifyKey(*keyPath)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		trusted = key
+	}
+
+	failed := false
+	for _, session := range fs.Args() {
+		dir := resolveSessionDir(session)
+		report, err := verifySession(dir, trusted)
+		switch {
+		case err != nil:
+			log.Printf("FAIL %s: %v", dir, err)
+			failed = true
+			continue
+		case !report.ok():
+			log.Printf("FAIL %s: modified %v, missing %v", dir, report.Modified, report.Missing)
+			failed = true
+		case report.TrustedKey:
+			log.Printf("OK   %s: signed with the given key, all files intact", dir)
+		default:
+			log.Printf("OK   %s: files match the embedded key's signature (pass --key to check who signed)", dir)
+		}
+		if len(report.Unsigned) > 0 {
+			log.Printf("     %s: added after signing, not covered: %s", dir, strings.Join(report.Unsigned, ", "))
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeSessionFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSignAndVerifySession(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	writeSessionFile(t, dir, "nim-20250101.json", `{"provider":"nim"}`)
+	writeSessionFile(t, dir, "logs/nim.log", "log line")
+	if err := signSession(dir, priv); err != nil {
+		t.Fatalf("signSession failed: %v", err)
+	}
+
+	report, err := verifySession(dir, pub)
+	if err != nil || !report.ok() || !report.TrustedKey || len(report.Unsigned) != 0 {
+		t.Fatalf("expected clean verification with trusted key, got %+v, %v", report, err)
+	}
+
+	writeSessionFile(t, dir, "nim-20250101.json", `{"provider":"nim","throughput":9999}`)
+	writeSessionFile(t, dir, "published-results.json", "{}")
+	if err := os.Remove(filepath.Join(dir, "logs", "nim.log")); err != nil {
+		t.Fatal(err)
+	}
+	report, err = verifySession(dir, nil)
+	if err != nil {
+		t.Fatalf("verifySession failed: %v", err)
+	}
+	if report.ok() || report.TrustedKey {
+		t.Fatalf("expected failed verification with embedded key, got %+v", report)
+	}
+	want := verifyReport{
+		Modified: []string{"nim-20250101.json"},
+		Missing:  []string{"logs/nim.log"},
+		Unsigned: []string{"published-results.json"},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Fatalf("got %+v, want %+v", report, want)
+	}
+}
+
+func TestVerifySessionRejectsOtherSigner(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(nil)
+	otherPub, _, _ := ed25519.GenerateKey(nil)
+	dir := t.TempDir()
+	writeSessionFile(t, dir, "result.json", "{}")
+	if err := signSession(dir, priv); err != nil {
+		t.Fatalf("signSession failed: %v", err)
+	}
+	if _, err := verifySession(dir, otherPub); err == nil {
+		t.Fatal("expected verification against another key to fail")
+	}
+}
+
+func TestLoadSigningKeyPEM(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	dir := t.TempDir()
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeSessionFile(t, dir, "signer.pem", string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})))
+	der, err = x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeSessionFile(t, dir, "signer.pub", string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})))
+
+	loaded, err := loadSigningKey(filepath.Join(dir, "signer.pem"))
+	if err != nil || !loaded.Equal(priv) {
+		t.Fatalf("loadSigningKey: %v", err)
+	}
+	loadedPub, err := loadVerifyKey(filepath.Join(dir, "signer.pub"))
+	if err != nil || !loadedPub.Equal(pub) {
+		t.Fatalf("loadVerifyKey: %v", err)
+	}
+	if _, err := loadSigningKey(filepath.Join(dir, "signer.pub")); err == nil {
+		t.Fatal("expected a public key to be rejected as a signing key")
+	}
+}
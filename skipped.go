@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// SkippedProvider is a configured provider the session did not benchmark.
+type SkippedProvider struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// sessionSkipped lists the providers skipped while selecting what to test, or
+// read back from the manifests by the report subcommand.
+var sessionSkipped []SkippedProvider
+
+// providerEnvPrefix is the prefix of a provider's environment variables, e.g.
+// NIM for nim and OAI for the generic provider.
+func providerEnvPrefix(name string) string {
+	if name == "generic" {
+		return "OAI"
+	}
+	return strings.ToUpper(name)
+}
+
+// partialConfigReason says why a provider that was only partly configured
+// cannot be tested, or returns "" for one fully configured or not configured
+// at all. A model alone counts only when set in the environment, since presets
+// come with a default one.
+func partialConfigReason(config ProviderConfig, getenv func(string) string) string {
+	prefix := providerEnvPrefix(config.Name)
+	switch {
+	case config.APIKey != "" && config.Model == "":
+		return fmt.Sprintf("%s_MODEL not set", prefix)
+	case config.APIKey == "" && getenv(prefix+"_MODEL") != "":
+		return fmt.Sprintf("%s_API_KEY not set", prefix)
+	}
+	return ""
+}
+
+// skipProvider records a provider left out of the session.
+func skipProvider(name, reason string) {
+	sessionSkipped = append(sessionSkipped, SkippedProvider{Name: name, Reason: reason})
+	sort.Slice(sessionSkipped, func(i, j int) bool { return sessionSkipped[i].Name < sessionSkipped[j].Name })
+}
+
+// manifestSkipped collects the skipped providers recorded in the sessions'
+// manifests, each once. Sessions without a readable manifest contribute none.
+func manifestSkipped(sessions []string) []SkippedProvider {
+	var skipped []SkippedProvider
+	for _, session := range sessions {
+		manifest, err := loadManifest(session)
+		if err != nil {
+			continue
+		}
+		for _, s := range manifest.Skipped {
+			if !slices.Contains(skipped, s) {
+				skipped = append(skipped, s)
+			}
+		}
+	}
+	return skipped
+}
+
+// writeSkippedProvidersSection lists the providers the session skipped, so a
+// report never silently covers fewer providers than were configured. Blind
+// reports leave it out, since it names providers.
+func writeSkippedProvidersSection(report *strings.Builder) {
+	if len(sessionSkipped) == 0 || blindReports {
+		return
+	}
+	report.WriteString("## Skipped Providers\n\n")
+	report.WriteString("Configured providers that were not benchmarked. Run with `--strict` to fail the session instead.\n\n")
+	report.WriteString("| Provider | Reason |\n")
+	report.WriteString("|----------|--------|\n")
+	for _, s := range sessionSkipped {
+		fmt.Fprintf(report, "| %s | %s |\n", s.Name, markdownCell(s.Reason))
+	}
+	report.WriteString("\n")
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPartialConfigReason(t *testing.T) {
+	env := map[string]string{"NOVITA_MODEL": "m"}
+	getenv := func(name string) string { return env[name] }
+	tests := []struct {
+		config ProviderConfig
+		want   string
+	}{
+		{ProviderConfig{Name: "nim", APIKey: "k", Model: "m"}, ""},
+		{ProviderConfig{Name: "nim", APIKey: "k"}, "NIM_MODEL not set"},
+		{ProviderConfig{Name: "generic", APIKey: "k"}, "OAI_MODEL not set"},
+		{ProviderConfig{Name: "novita", Model: "m"}, "NOVITA_API_KEY not set"},
+		// A preset's default model alone is not a configuration attempt
+		{ProviderConfig{Name: "groq", Model: "llama"}, ""},
+		{ProviderConfig{Name: "nim"}, ""},
+	}
+	for _, tt := range tests {
+		if got := partialConfigReason(tt.config, getenv); got != tt.want {
+			t.Errorf("partialConfigReason(%+v) = %q, want %q", tt.config, got, tt.want)
+		}
+	}
+}
+
+func TestSkippedProvidersSection(t *testing.T) {
+	saved := sessionSkipped
+	defer func() { sessionSkipped = saved }()
+
+	sessionSkipped = nil
+	var empty strings.Builder
+	writeSkippedProvidersSection(&empty)
+	if empty.Len() != 0 {
+		t.Fatalf("expected no section without skipped providers, got:\n%s", empty.String())
+	}
+
+	skipProvider("nim", "NIM_MODEL not set")
+	skipProvider("chutes", "preflight failed: error, status code: 401")
+	var report strings.Builder
+	writeSkippedProvidersSection(&report)
+	got := report.String()
+	if !strings.Contains(got, "## Skipped Providers") ||
+		strings.Index(got, "| chutes | preflight failed") > strings.Index(got, "| nim | NIM_MODEL not set |") {
+		t.Fatalf("expected both providers sorted by name, got:\n%s", got)
+	}
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SLO metrics.
+const (
+	sloTTFT    = "ttft"
+	sloE2E     = "e2e"
+	sloSuccess = "success"
+)
+
+// sloBucketWidth is the resolution of the rolling windows.
+const sloBucketWidth = time.Minute
+
+// sloWindows are the rolling windows burn rates are reported over; set with
+// --slo-windows. The short window reacts quickly, the long ones filter noise.
+var sloWindows = []time.Duration{5 * time.Minute, time.Hour, 6 * time.Hour}
+
+// sessionSLOs are the objectives from --slo and the config file's [[slo]] list.
+var sessionSLOs []sloObjective
+
+// sloObjective is a service level objective such as "99% of requests have
+// TTFT < 1.5s". A failed request misses every objective.
+type sloObjective struct {
+	Metric    string         `toml:"metric"`
+	Threshold configDuration `toml:"threshold"`
+	// Objective is the percentage of requests that must be good.
+	Objective float64 `toml:"objective"`
+}
+
+// parseSLO parses "ttft<1.5s@99", "e2e<10s@95" or "success@99.5".
+func parseSLO(spec string) (sloObjective, error) {
+	spec = strings.TrimSpace(spec)
+	cond, rawTarget, ok := strings.Cut(spec, "@")
+	if !ok {
+		return sloObjective{}, fmt.Errorf("SLO %q needs an objective, e.g. ttft<1.5s@99", spec)
+	}
+	objective, err := strconv.ParseFloat(rawTarget, 64)
+	if err != nil {
+		return sloObjective{}, fmt.Errorf("SLO %q: invalid objective %q", spec, rawTarget)
+	}
+	o := sloObjective{Objective: objective}
+	metric, rawThreshold, hasThreshold := strings.Cut(cond, "<")
+	o.Metric = strings.ToLower(strings.TrimSpace(metric))
+	if hasThreshold {
+		if o.Threshold.Duration, err = time.ParseDuration(strings.TrimSpace(rawThreshold)); err != nil {
+			return sloObjective{}, fmt.Errorf("SLO %q: %w", spec, err)
+		}
+	}
+	if err := o.validate(); err != nil {
+		return sloObjective{}, fmt.Errorf("SLO %q: %w", spec, err)
+	}
+	return o, nil
+}
+
+// parseSLOs parses a comma-separated list of SLO specs.
+func parseSLOs(spec string) ([]sloObjective, error) {
+	var objectives []sloObjective
+	for _, part := range strings.Split(spec, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		o, err := parseSLO(part)
+		if err != nil {
+			return nil, err
+		}
+		objectives = append(objectives, o)
+	}
+	return objectives, nil
+}
+
+// validate checks the metric, threshold and objective.
+func (o sloObjective) validate() error {
+	switch o.Metric {
+	case sloTTFT, sloE2E:
+		if o.Threshold.Duration <= 0 {
+			return fmt.Errorf("%s needs a positive threshold", o.Metric)
+		}
+	case sloSuccess:
+		if o.Threshold.Duration != 0 {
+			return fmt.Errorf("success takes no threshold")
+		}
+	default:
+		return fmt.Errorf("unknown metric %q (use %s, %s or %s)", o.Metric, sloTTFT, sloE2E, sloSuccess)
+	}
+	if o.Objective <= 0 || o.Objective >= 100 {
+		return fmt.Errorf("objective must be between 0 and 100 (exclusive), got %g", o.Objective)
+	}
+	return nil
+}
+
+// String describes the objective, e.g. "99% of requests have TTFT < 1.5s".
+func (o sloObjective) String() string {
+	target := strconv.FormatFloat(o.Objective, 'f', -1, 64)
+	if o.Metric == sloSuccess {
+		return fmt.Sprintf("%s%% of requests succeed", target)
+	}
+	return fmt.Sprintf("%s%% of requests have %s < %s", target, strings.ToUpper(o.Metric), o.Threshold.Duration)
+}
+
+// good reports whether a request met the objective.
+func (o sloObjective) good(e2e, ttft time.Duration, err error) bool {
+	if err != nil {
+		return false
+	}
+	switch o.Metric {
+	case sloTTFT:
+		return ttft < o.Threshold.Duration
+	case sloE2E:
+		return e2e < o.Threshold.Duration
+	}
+	return true
+}
+
+// sloBucket counts requests over one sloBucketWidth.
+type sloBucket struct {
+	start time.Time
+	total int
+	good  []int
+}
+
+// sloTracker evaluates objectives over rolling windows in constant memory:
+// requests are counted in per-minute buckets kept for the longest window.
+type sloTracker struct {
+	objectives []sloObjective
+	windows    []time.Duration
+	buckets    []sloBucket
+	total      int
+	good       []int
+}
+
+// newSLOTracker returns a tracker, or nil when there are no objectives.
+func newSLOTracker(objectives []sloObjective, windows []time.Duration) *sloTracker {
+	if len(objectives) == 0 {
+		return nil
+	}
+	windows = append([]time.Duration(nil), windows...)
+	sort.Slice(windows, func(a, b int) bool { return windows[a] < windows[b] })
+	return &sloTracker{objectives: objectives, windows: windows, good: make([]int, len(objectives))}
+}
+
+// record counts one request completed at at. It is a no-op on a nil tracker.
+func (t *sloTracker) record(at time.Time, e2e, ttft time.Duration, err error) {
+	if t == nil {
+		return
+	}
+	start := at.Truncate(sloBucketWidth)
+	if n := len(t.buckets); n == 0 || !t.buckets[n-1].start.Equal(start) {
+		t.buckets = append(t.buckets, sloBucket{start: start, good: make([]int, len(t.objectives))})
+		t.evict(at)
+	}
+	bucket := &t.buckets[len(t.buckets)-1]
+	bucket.total++
+	t.total++
+	for i, o := range t.objectives {
+		if o.good(e2e, ttft, err) {
+			bucket.good[i]++
+			t.good[i]++
+		}
+	}
+}
+
+// evict drops buckets older than the longest window.
+func (t *sloTracker) evict(now time.Time) {
+	longest := sloBucketWidth
+	for _, w := range t.windows {
+		longest = max(longest, w)
+	}
+	cutoff := now.Add(-longest - sloBucketWidth)
+	drop := 0
+	for drop < len(t.buckets) && t.buckets[drop].start.Before(cutoff) {
+		drop++
+	}
+	t.buckets = t.buckets[drop:]
+}
+
+// SLOWindow is an objective's compliance over one rolling window.
+type SLOWindow struct {
+	Window     time.Duration `json:"window"`
+	Requests   int           `json:"requests"`
+	Compliance float64       `json:"compliance"`
+	// BurnRate is how fast the error budget is being spent: 1 spends exactly
+	// the budget over the SLO period, 10 spends it ten times faster.
+	BurnRate float64 `json:"burnRate"`
+}
+
+// SLOStatus is an objective's compliance over the whole run and its windows.
+type SLOStatus struct {
+	Objective  string  `json:"objective"`
+	Metric     string  `json:"metric"`
+	Target     float64 `json:"target"`
+	Requests   int     `json:"requests"`
+	Compliance float64 `json:"compliance"`
+	Met        bool    `json:"met"`
+	// BudgetRemaining is the share of the run's error budget not yet spent, in
+	// percent; it goes negative once the objective is missed.
+	BudgetRemaining float64     `json:"budgetRemaining"`
+	Windows         []SLOWindow `json:"windows"`
+}
+
+// burnRate is the observed bad fraction relative to the allowed one.
+func burnRate(good, total int, objective float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	bad := float64(total-good) / float64(total)
+	return bad / (1 - objective/100)
+}
+
+// status evaluates every objective as of now, or returns nil on a nil tracker.
+func (t *sloTracker) status(now time.Time) []SLOStatus {
+	if t == nil {
+		return nil
+	}
+	statuses := make([]SLOStatus, 0, len(t.objectives))
+	for i, o := range t.objectives {
+		s := SLOStatus{Objective: o.String(), Metric: o.Metric, Target: o.Objective, Requests: t.total}
+		if t.total > 0 {
+			s.Compliance = 100 * float64(t.good[i]) / float64(t.total)
+			s.Met = s.Compliance >= o.Objective
+			s.BudgetRemaining = 100 * (1 - burnRate(t.good[i], t.total, o.Objective))
+		}
+		for _, w := range t.windows {
+			cutoff := now.Add(-w)
+			win := SLOWindow{Window: w}
+			good := 0
+			for _, b := range t.buckets {
+				if !b.start.Before(cutoff) {
+					win.Requests += b.total
+					good += b.good[i]
+				}
+			}
+			if win.Requests > 0 {
+				win.Compliance = 100 * float64(good) / float64(win.Requests)
+				win.BurnRate = burnRate(good, win.Requests, o.Objective)
+			}
+			s.Windows = append(s.Windows, win)
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses
+}
+
+// parseSLOWindows parses a comma-separated list of window durations.
+func parseSLOWindows(spec string) ([]time.Duration, error) {
+	var windows []time.Duration
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		w, err := time.ParseDuration(part)
+		if err != nil || w < sloBucketWidth {
+			return nil, fmt.Errorf("invalid SLO window %q (minimum %s)", part, sloBucketWidth)
+		}
+		windows = append(windows, w)
+	}
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("no SLO windows given")
+	}
+	return windows, nil
+}
+
+// formatSLOWindows renders windows as "5m0s, 1h0m0s".
+func formatSLOWindows(windows []time.Duration) string {
+	parts := make([]string, len(windows))
+	for i, w := range windows {
+		parts[i] = w.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// writeSLORows renders per-provider SLO compliance and burn rates.
+func writeSLORows(report *strings.Builder, provider string, statuses []SLOStatus) {
+	if len(statuses) == 0 {
+		return
+	}
+	fmt.Fprintf(report, "### %s\n\n", provider)
+	report.WriteString("| Objective | Requests | Compliance | Status | Budget Left | Burn Rate by Window |\n")
+	report.WriteString("|-----------|----------|------------|--------|-------------|---------------------|\n")
+	for _, s := range statuses {
+		status := "❌ Missed"
+		if s.Met {
+			status = "✅ Met"
+		}
+		burns := make([]string, 0, len(s.Windows))
+		for _, w := range s.Windows {
+			if w.Requests == 0 {
+				burns = append(burns, fmt.Sprintf("%s: -", w.Window))
+				continue
+			}
+			burns = append(burns, fmt.Sprintf("%s: %.2f×", w.Window, w.BurnRate))
+		}
+		fmt.Fprintf(report, "| %s | %d | %.2f%% | %s | %.1f%% | %s |\n",
+			s.Objective, s.Requests, s.Compliance, status, s.BudgetRemaining, strings.Join(burns, ", "))
+	}
+	report.WriteString("\n")
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSLO(t *testing.T) {
+	objectives, err := parseSLOs("ttft<1.5s@99, e2e<10s@95,success@99.5")
+	if err != nil {
+		t.Fatalf("parseSLOs failed: %v", err)
+	}
+	want := []string{
+		"99% of requests have TTFT < 1.5s",
+		"95% of requests have E2E < 10s",
+		"99.5% of requests succeed",
+	}
+	if len(objectives) != len(want) {
+		t.Fatalf("got %d objectives", len(objectives))
+	}
+	for i, o := range objectives {
+		if o.String() != want[i] {
+			t.Errorf("objective %d = %q, want %q", i, o, want[i])
+		}
+	}
+	for _, bad := range []string{"ttft<1.5s", "ttft@99", "ttft<1.5s@100", "tps<5s@99", "success<1s@99", "ttft<soon@99"} {
+		if _, err := parseSLO(bad); err == nil {
+			t.Errorf("parseSLO(%q) accepted", bad)
+		}
+	}
+}
+
+func TestSLOTrackerBurnRates(t *testing.T) {
+	o, _ := parseSLO("ttft<1s@90")
+	tracker := newSLOTracker([]sloObjective{o}, []time.Duration{time.Hour, 5 * time.Minute})
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// A good first hour, then a bad five minutes: half the requests are slow
+	for i := 0; i < 60; i++ {
+		tracker.record(start.Add(time.Duration(i)*time.Minute), 0, 500*time.Millisecond, nil)
+	}
+	now := start.Add(65 * time.Minute)
+	for i := 0; i < 10; i++ {
+		at := start.Add(61*time.Minute + time.Duration(i)*20*time.Second)
+		if i%2 == 0 {
+			tracker.record(at, 0, 2*time.Second, nil)
+		} else {
+			tracker.record(at, 0, 500*time.Millisecond, errors.New("boom"))
+		}
+	}
+
+	status := tracker.status(now)[0]
+	if status.Requests != 70 || status.Met || math.Abs(status.Compliance-60.0/70*100) > 0.01 {
+		t.Fatalf("unexpected overall status: %+v", status)
+	}
+	if len(status.Windows) != 2 || status.Windows[0].Window != 5*time.Minute {
+		t.Fatalf("windows not sorted: %+v", status.Windows)
+	}
+	short := status.Windows[0]
+	if short.Requests != 10 || math.Abs(short.BurnRate-10) > 0.01 {
+		t.Errorf("5m window = %+v, want 10 requests burning at 10x", short)
+	}
+	if long := status.Windows[1]; long.Requests < 60 || long.BurnRate >= short.BurnRate {
+		t.Errorf("1h window should dilute the burn: %+v", long)
+	}
+
+	// Old buckets are dropped once they fall out of the longest window
+	tracker.record(start.Add(5*time.Hour), 0, 0, nil)
+	if len(tracker.buckets) != 1 {
+		t.Errorf("expected old buckets to be evicted, have %d", len(tracker.buckets))
+	}
+}
+
+func TestSoakReportIncludesSLOs(t *testing.T) {
+	objective, _ := parseSLO("success@99")
+	sessionSLOs = []sloObjective{objective}
+	t.Cleanup(func() { sessionSLOs = nil })
+
+	agg := &soakAggregate{config: ProviderConfig{Name: "p"}, errors: make(map[string]int), slo: newSLOTracker(sessionSLOs, sloWindows)}
+	agg.record(time.Second, time.Second, 10, 10, nil)
+	agg.record(0, 0, 0, 0, errors.New("boom"))
+	summary := SoakSummary{Final: true, Providers: []SoakProviderSummary{agg.summary()}}
+	if slos := summary.Providers[0].SLOs; len(slos) != 1 || slos[0].Compliance != 50 || slos[0].Met {
+		t.Fatalf("unexpected SLO status: %+v", slos)
+	}
+	report := renderSoakReport(summary, "test")
+	if !strings.Contains(report, "## SLO Compliance") || !strings.Contains(report, "99% of requests succeed") {
+		t.Errorf("SLO section missing from soak report:\n%s", report)
+	}
+}
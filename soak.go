@@ -0,0 +1,572 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+const (
+	soakModeLabel = "soak"
+	// soakLogBackups is how many rotated log files are kept per provider.
+	soakLogBackups = 5
+	// soakMaxErrorKinds caps the distinct error messages tracked per provider so
+	// a flood of unique errors cannot grow memory without bound.
+	soakMaxErrorKinds  = 20
+	soakOtherErrors    = "(other errors)"
+	soakRequestTimeout = 2 * time.Minute
+)
+
+// soakOptions configures a soak session.
+type soakOptions struct {
+	duration    time.Duration
+	interval    time.Duration
+	checkpoint  time.Duration
+	logMaxBytes int64
+	// keepWarm, when set, sends a keepWarmTokens-token request whenever a
+	// provider has been idle this long between measured requests.
+	keepWarm       time.Duration
+	keepWarmTokens int
+}
+
+// rotatingLogWriter is an io.Writer that rolls its file over to path.1, path.2,
+// ... once it exceeds maxBytes, keeping at most backups old files.
+type rotatingLogWriter struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	backups  int
+	file     *os.File
+	size     int64
+}
+
+// newRotatingLogWriter opens (or creates) the log file at path.
+func newRotatingLogWriter(path string, maxBytes int64, backups int) (*rotatingLogWriter, error) {
+	w := &rotatingLogWriter{path: filepath.Clean(path), maxBytes: maxBytes, backups: backups}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *rotatingLogWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("error opening log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("error reading log file size: %w", err)
+	}
+	w.file, w.size = f, info.Size()
+	return nil
+}
+
+// Write appends p to the current file, rotating first if it would exceed maxBytes.
+func (w *rotatingLogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.maxBytes > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxBytes {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// rotate shifts path.N-1 -> path.N, ..., path -> path.1 and reopens path.
+func (w *rotatingLogWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("error closing log file: %w", err)
+	}
+	for i := w.backups - 1; i >= 1; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", w.path, i), fmt.Sprintf("%s.%d", w.path, i+1))
+	}
+	if w.backups > 0 {
+		if err := os.Rename(w.path, w.path+".1"); err != nil {
+			return fmt.Errorf("error rotating log file: %w", err)
+		}
+	} else if err := os.Remove(w.path); err != nil {
+		return fmt.Errorf("error truncating log file: %w", err)
+	}
+	return w.open()
+}
+
+// Close closes the current log file.
+func (w *rotatingLogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+// soakWindow accumulates the requests seen since the last checkpoint.
+type soakWindow struct {
+	requests      int
+	failures      int
+	ttftSum       time.Duration
+	throughputSum float64
+}
+
+// soakAggregate holds constant-memory statistics for one provider.
+type soakAggregate struct {
+	config     ProviderConfig
+	requests   int
+	failures   int
+	tokens     int
+	ttft       durationHistogram
+	e2e        durationHistogram
+	ttftStats  runningStats
+	throughput runningStats
+	errors     map[string]int
+	window     soakWindow
+	intervals  []SoakInterval
+	slo        *sloTracker
+	// Keep-warm requests are counted apart and never enter the statistics.
+	keepWarmSent, keepWarmFailed int
+}
+
+// record adds one request outcome.
+func (a *soakAggregate) record(e2e, ttft time.Duration, throughput float64, tokens int, err error) {
+	a.slo.record(time.Now(), e2e, ttft, err)
+	a.requests++
+	a.window.requests++
+	if err != nil {
+		a.failures++
+		a.window.failures++
+		key := err.Error()
+		if _, seen := a.errors[key]; !seen && len(a.errors) >= soakMaxErrorKinds {
+			key = soakOtherErrors
+		}
+		a.errors[key]++
+		return
+	}
+	a.tokens += tokens
+	a.ttft.add(ttft)
+	a.e2e.add(e2e)
+	a.ttftStats.add(float64(ttft))
+	a.throughput.add(throughput)
+	a.window.ttftSum += ttft
+	a.window.throughputSum += throughput
+}
+
+// closeWindow appends the current window as an interval and starts a new one.
+func (a *soakAggregate) closeWindow(at time.Time) {
+	w := a.window
+	interval := SoakInterval{At: at, Requests: w.requests, Failures: w.failures}
+	if ok := w.requests - w.failures; ok > 0 {
+		interval.AvgTTFT = w.ttftSum / time.Duration(ok)
+		interval.AvgThroughput = w.throughputSum / float64(ok)
+	}
+	a.intervals = append(a.intervals, interval)
+	a.window = soakWindow{}
+}
+
+// summary renders the aggregate as a JSON-friendly snapshot.
+func (a *soakAggregate) summary() SoakProviderSummary {
+	s := SoakProviderSummary{
+		Provider:         providerLabel(a.config.Name, a.config.Env),
+		Model:            a.config.Model,
+		Requests:         a.requests,
+		Failures:         a.failures,
+		Tokens:           a.tokens,
+		Intervals:        append([]SoakInterval(nil), a.intervals...),
+		SLOs:             a.slo.status(time.Now()),
+		KeepWarm:         a.keepWarmSent,
+		KeepWarmFailures: a.keepWarmFailed,
+	}
+	if a.requests > 0 {
+		s.SuccessRate = 100 * float64(a.requests-a.failures) / float64(a.requests)
+	}
+	if a.ttftStats.count > 0 {
+		s.AvgTTFT = time.Duration(a.ttftStats.mean)
+		s.StdDevTTFT = time.Duration(a.ttftStats.stddev())
+		s.P50TTFT = a.ttft.percentile(50)
+		s.P90TTFT = a.ttft.percentile(90)
+		s.P99TTFT = a.ttft.percentile(99)
+		s.P50E2E = a.e2e.percentile(50)
+		s.P90E2E = a.e2e.percentile(90)
+		s.P99E2E = a.e2e.percentile(99)
+		s.AvgThroughput = a.throughput.mean
+		s.MinThroughput = a.throughput.min
+		s.MaxThroughput = a.throughput.max
+	}
+	if len(a.errors) > 0 {
+		s.Errors = make(map[string]int, len(a.errors))
+		for k, v := range a.errors {
+			s.Errors[k] = v
+		}
+	}
+	return s
+}
+
+// SoakInterval summarizes the requests completed between two checkpoints.
+type SoakInterval struct {
+	At            time.Time     `json:"at"`
+	Requests      int           `json:"requests"`
+	Failures      int           `json:"failures"`
+	AvgTTFT       time.Duration `json:"avgTtft"`
+	AvgThroughput float64       `json:"avgThroughput"`
+}
+
+// SoakProviderSummary is one provider's soak statistics. Percentiles are
+// estimated from log-scaled histograms and are accurate to about 5%.
+type SoakProviderSummary struct {
+	Provider      string         `json:"provider"`
+	Model         string         `json:"model"`
+	Requests      int            `json:"requests"`
+	Failures      int            `json:"failures"`
+	SuccessRate   float64        `json:"successRate"`
+	Tokens        int            `json:"tokens"`
+	AvgTTFT       time.Duration  `json:"avgTtft"`
+	StdDevTTFT    time.Duration  `json:"stdDevTtft"`
+	P50TTFT       time.Duration  `json:"p50Ttft"`
+	P90TTFT       time.Duration  `json:"p90Ttft"`
+	P99TTFT       time.Duration  `json:"p99Ttft"`
+	P50E2E        time.Duration  `json:"p50E2e"`
+	P90E2E        time.Duration  `json:"p90E2e"`
+	P99E2E        time.Duration  `json:"p99E2e"`
+	AvgThroughput float64        `json:"avgThroughput"`
+	MinThroughput float64        `json:"minThroughput"`
+	MaxThroughput float64        `json:"maxThroughput"`
+	Errors        map[string]int `json:"errors,omitempty"`
+	Intervals     []SoakInterval `json:"intervals"`
+	SLOs          []SLOStatus    `json:"slos,omitempty"`
+	// KeepWarm counts the unmeasured keep-warm requests sent between
+	// measured ones.
+	KeepWarm         int `json:"keepWarmRequests,omitempty"`
+	KeepWarmFailures int `json:"keepWarmFailures,omitempty"`
+}
+
+// SoakSummary is the checkpoint written to soak-summary.json.
+type SoakSummary struct {
+	Started time.Time     `json:"started"`
+	Updated time.Time     `json:"updated"`
+	Planned time.Duration `json:"planned"`
+	Elapsed time.Duration `json:"elapsed"`
+	Final   bool          `json:"final"`
+	// KeepWarm is the idle time after which a keep-warm request is sent, and
+	// KeepWarmTokens its output limit; zero when disabled.
+	KeepWarm       time.Duration         `json:"keepWarm,omitempty"`
+	KeepWarmTokens int                   `json:"keepWarmTokens,omitempty"`
+	Providers      []SoakProviderSummary `json:"providers"`
+}
+
+// soakSession shares aggregates between provider workers and the checkpointer.
+type soakSession struct {
+	mu         sync.Mutex
+	started    time.Time
+	planned    time.Duration
+	keepWarm   time.Duration
+	warmTokens int
+	aggregates []*soakAggregate
+}
+
+// snapshot closes the current window of every provider and returns a summary.
+func (s *soakSession) snapshot(final bool) SoakSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	summary := SoakSummary{
+		Started: s.started,
+		Updated: now,
+		Planned: s.planned,
+		Elapsed: now.Sub(s.started),
+		Final:   final,
+	}
+	if s.keepWarm > 0 {
+		summary.KeepWarm = s.keepWarm
+		summary.KeepWarmTokens = s.warmTokens
+	}
+	for _, a := range s.aggregates {
+		a.closeWindow(now)
+		summary.Providers = append(summary.Providers, a.summary())
+	}
+	return summary
+}
+
+// runSoakTest sends requests to every provider at a fixed interval for the
+// configured duration (or until interrupted), flushing soak-summary.json and
+// regenerating SOAK-REPORT.md at every checkpoint.
+func runSoakTest(providers []ProviderConfig, tke *tiktoken.Tiktoken, mode TestMode, toolReasoningCheck bool, opts soakOptions, logDir, resultsDir, sessionTimestamp string) error {
+	log.Printf("=== SOAK MODE: %d provider(s) for %s, one request every %s, checkpoint every %s ===",
+		len(providers), opts.duration, opts.interval, opts.checkpoint)
+	if opts.keepWarm > 0 {
+		log.Printf("Keeping providers warm: a %d-token request after every %s idle between measured requests", opts.keepWarmTokens, opts.keepWarm)
+	}
+	log.Println("Press Ctrl+C (or q) to stop early; a final report is still written.")
+
+	soakCtx, cancel := context.WithTimeout(shutdownCtx, opts.duration)
+	defer cancel()
+	soakCtx, stop := signal.NotifyContext(soakCtx, os.Interrupt)
+	defer stop()
+
+	session := &soakSession{started: time.Now(), planned: opts.duration, keepWarm: opts.keepWarm, warmTokens: opts.keepWarmTokens}
+	for _, p := range providers {
+		session.aggregates = append(session.aggregates, &soakAggregate{
+			config: p,
+			errors: make(map[string]int),
+			slo:    newSLOTracker(sessionSLOs, sloWindows),
+		})
+	}
+
+	flush := func(final bool) {
+		summary := session.snapshot(final)
+		if err := writeSoakCheckpoint(resultsDir, summary, sessionTimestamp); err != nil {
+			log.Printf("Warning: Failed to write soak checkpoint: %v", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i, p := range providers {
+		wg.Add(1)
+		go func(agg *soakAggregate, config ProviderConfig) {
+			defer wg.Done()
+			if err := soakProvider(soakCtx, config, tke, mode, toolReasoningCheck, opts, logDir, session, agg); err != nil {
+				log.Printf("Warning: Soak worker for %s stopped: %v", config.Name, err)
+			}
+		}(session.aggregates[i], p)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	ticker := time.NewTicker(opts.checkpoint)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			flush(false)
+		case <-done:
+			flush(true)
+			return nil
+		}
+	}
+}
+
+// soakProvider is the request loop for one provider.
+func soakProvider(ctx context.Context, config ProviderConfig, tke *tiktoken.Tiktoken, mode TestMode, toolReasoningCheck bool, opts soakOptions, logDir string, session *soakSession, agg *soakAggregate) error {
+	logWriter, err := newRotatingLogWriter(filepath.Join(logDir, fmt.Sprintf("%s-soak.log", resultFilePrefix(config.Name, config.Env))), opts.logMaxBytes, soakLogBackups)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := logWriter.Close(); closeErr != nil {
+			log.Printf("Warning: Failed to close log file: %v", closeErr)
+		}
+	}()
+	providerLogger := log.New(io.MultiWriter(consoleOutput, logWriter), "", log.LstdFlags)
+	providerLogger.Printf("--- Soak testing: %s (%s) - Mode: %s ---", config.Name, config.Model, mode)
+
+	ticker := time.NewTicker(opts.interval)
+	defer ticker.Stop()
+	// Keep-warm requests fire while the provider is idle between measured
+	// requests; the timer restarts after each measured request
+	var keepWarmC <-chan time.Time
+	var keepWarm *time.Ticker
+	if opts.keepWarm > 0 {
+		keepWarm = time.NewTicker(opts.keepWarm)
+		defer keepWarm.Stop()
+		keepWarmC = keepWarm.C
+	}
+	for reqNum := 1; ; reqNum++ {
+		if err := canStartRun(); err != nil {
+			providerLogger.Printf("[%s] Stopping soak - %v", config.Name, err)
+			return nil
+		}
+
+		reqMode := mode
+		if mode == ModeMixed {
+			reqMode = ModeStreaming
+			if reqNum%2 == 0 {
+				reqMode = ModeToolCalling
+			}
+		}
+		// Requests are not tied to ctx so an in-flight request finishes cleanly
+		runCtx := withRunContext(context.Background(), newRunContext(config, reqMode, reqNum))
+		reqCtx, reqCancel := context.WithTimeout(runCtx, soakRequestTimeout)
+		runLog := runLogger(reqCtx, providerLogger, config)
+		var e2e, ttft time.Duration
+		var throughput float64
+		var tokens int
+		var reqErr error
+		if reqMode == ModeToolCalling {
+			e2e, ttft, throughput, tokens, _, reqErr = singleToolCallRun(reqCtx, config, tke, providerLogger, toolReasoningCheck)
+		} else {
+			e2e, ttft, throughput, tokens, _, reqErr = singleTestRun(reqCtx, config, tke, providerLogger, fmt.Sprintf("soak%d", reqNum))
+		}
+		reqCancel()
+
+		if reqErr != nil {
+			runLog.Printf("Soak request failed: %v", reqErr)
+		} else {
+			runLog.Printf("Soak request: E2E=%s TTFT=%s Throughput=%.2f tok/s",
+				formatDuration(e2e), formatDuration(ttft), throughput)
+		}
+		session.mu.Lock()
+		agg.record(e2e, ttft, throughput, tokens, reqErr)
+		session.mu.Unlock()
+
+		if keepWarm != nil {
+			keepWarm.Reset(opts.keepWarm)
+		}
+	idle:
+		for {
+			select {
+			case <-ctx.Done():
+				providerLogger.Printf("[%s] Soak finished after %d requests", config.Name, reqNum)
+				return nil
+			case <-ticker.C:
+				break idle
+			case <-keepWarmC:
+				warmErr := sendKeepWarm(context.Background(), config, tke, opts.keepWarmTokens)
+				if warmErr != nil {
+					providerLogger.Printf("[%s] Keep-warm request failed: %v", config.Name, warmErr)
+				}
+				session.mu.Lock()
+				agg.keepWarmSent++
+				if warmErr != nil {
+					agg.keepWarmFailed++
+				}
+				session.mu.Unlock()
+			}
+		}
+	}
+}
+
+// writeSoakCheckpoint writes soak-summary.json, the TTFT heatmap and
+// SOAK-REPORT.md, replacing files atomically so an interrupted write never leaves a truncated checkpoint.
+func writeSoakCheckpoint(resultsDir string, summary SoakSummary, sessionTimestamp string) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling soak summary: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(resultsDir, "soak-summary.json"), data); err != nil {
+		return fmt.Errorf("error writing soak summary: %w", err)
+	}
+	if svg, ok := renderSoakHeatmap(summary); ok {
+		if err := writeFileAtomic(filepath.Join(resultsDir, heatmapFileName), []byte(svg)); err != nil {
+			return fmt.Errorf("error writing TTFT heatmap: %w", err)
+		}
+	}
+	filename := filepath.Join(resultsDir, "SOAK-REPORT.md")
+	if err := writeFileAtomic(filename, []byte(renderSoakReport(summary, sessionTimestamp))); err != nil {
+		return fmt.Errorf("error writing soak report: %w", err)
+	}
+	state := "Interim"
+	if summary.Final {
+		state = "Final"
+	}
+	log.Printf("%s soak report generated: %s (elapsed %s)", state, filename, summary.Elapsed.Round(time.Second))
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it over path.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// renderSoakReport builds SOAK-REPORT.md from a checkpoint.
+func renderSoakReport(summary SoakSummary, sessionTimestamp string) string {
+	var report strings.Builder
+	report.WriteString("# LLM API Soak Test Results\n\n")
+	fmt.Fprintf(&report, "**Test Session:** %s\n\n", sessionTimestamp)
+	if summary.Final {
+		report.WriteString("**Status:** Final\n\n")
+	} else {
+		report.WriteString("**Status:** Interim (regenerated at every checkpoint)\n\n")
+	}
+	fmt.Fprintf(&report, "**Elapsed:** %s of %s\n\n", summary.Elapsed.Round(time.Second), summary.Planned)
+	if summary.KeepWarm > 0 {
+		fmt.Fprintf(&report, "**Kept warm:** a %d-token request after every %s idle; these requests are excluded from every statistic below, which therefore describe kept-warm latency\n\n",
+			summary.KeepWarmTokens, summary.KeepWarm)
+	}
+	report.WriteString("---\n\n")
+
+	report.WriteString("## Summary\n\n")
+	report.WriteString("| Provider | Model | Requests | Success | P50 TTFT | P90 TTFT | P99 TTFT | TTFT Std Dev | P50 E2E | P99 E2E | Avg Throughput | Min/Max Throughput |\n")
+	report.WriteString("|----------|-------|----------|---------|----------|----------|----------|--------------|---------|---------|----------------|--------------------|\n")
+	for _, p := range summary.Providers {
+		fmt.Fprintf(&report, "| %s | %s | %d | %.1f%% | %s | %s | %s | %s | %s | %s | %.2f tok/s | %.2f / %.2f |\n",
+			p.Provider, p.Model, p.Requests, p.SuccessRate,
+			formatDuration(p.P50TTFT), formatDuration(p.P90TTFT), formatDuration(p.P99TTFT), formatDuration(p.StdDevTTFT),
+			formatDuration(p.P50E2E), formatDuration(p.P99E2E), p.AvgThroughput, p.MinThroughput, p.MaxThroughput)
+	}
+	report.WriteString("\n*Percentiles are estimated from histograms (within ~5%).*\n\n")
+
+	if summary.KeepWarm > 0 {
+		report.WriteString("## Keep-Warm Requests\n\n")
+		report.WriteString("| Provider | Sent | Failed |\n")
+		report.WriteString("|----------|------|--------|\n")
+		for _, p := range summary.Providers {
+			fmt.Fprintf(&report, "| %s | %d | %d |\n", p.Provider, p.KeepWarm, p.KeepWarmFailures)
+		}
+		report.WriteString("\n")
+	}
+
+	if len(sessionSLOs) > 0 {
+		report.WriteString("## SLO Compliance\n\n")
+		report.WriteString("Compliance and error budget cover the whole run. The burn rate is the share of bad requests in each rolling window " +
+			"divided by the share the objective allows: 1× spends the budget exactly, above 1× spends it early " +
+			"(a sustained 14.4× over 1h would exhaust a 30-day budget in about two days).\n\n")
+		for _, p := range summary.Providers {
+			writeSLORows(&report, p.Provider, p.SLOs)
+		}
+	}
+
+	writeHeatmapSection(&report, slices.ContainsFunc(summary.Providers, func(p SoakProviderSummary) bool { return len(p.Intervals) > 0 }))
+
+	report.WriteString("## Stability Over Time\n\n")
+	for _, p := range summary.Providers {
+		fmt.Fprintf(&report, "### %s\n\n", p.Provider)
+		report.WriteString("| Checkpoint | Requests | Failures | Avg TTFT | Avg Throughput |\n")
+		report.WriteString("|------------|----------|----------|----------|----------------|\n")
+		for _, in := range p.Intervals {
+			fmt.Fprintf(&report, "| %s | %d | %d | %s | %.2f tok/s |\n",
+				in.At.Format("15:04:05"), in.Requests, in.Failures, formatDuration(in.AvgTTFT), in.AvgThroughput)
+		}
+		report.WriteString("\n")
+	}
+
+	var errorRows []string
+	for _, p := range summary.Providers {
+		for msg, count := range p.Errors {
+			errorRows = append(errorRows, fmt.Sprintf("| %s | %d | %s |\n", p.Provider, count, msg))
+		}
+	}
+	if len(errorRows) > 0 {
+		sort.Strings(errorRows)
+		report.WriteString("## Errors\n\n")
+		report.WriteString("| Provider | Count | Error |\n")
+		report.WriteString("|----------|-------|-------|\n")
+		for _, row := range errorRows {
+			report.WriteString(row)
+		}
+		report.WriteString("\n")
+	}
+
+	report.WriteString("---\n\n")
+	fmt.Fprintf(&report, "*Report generated at %s*\n", time.Now().Format("2006-01-02 15:04:05"))
+	return report.String()
+}
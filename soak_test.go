@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingLogWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "p-soak.log")
+	w, err := newRotatingLogWriter(path, 10, 2)
+	if err != nil {
+		t.Fatalf("newRotatingLogWriter failed: %v", err)
+	}
+	for _, line := range []string{"aaaaaaaa\n", "bbbbbbbb\n", "cccccccc\n", "dddddddd\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	for suffix, want := range map[string]string{"": "dddddddd\n", ".1": "cccccccc\n", ".2": "bbbbbbbb\n"} {
+		data, err := os.ReadFile(path + suffix)
+		if err != nil || string(data) != want {
+			t.Errorf("%s%s = %q (%v), want %q", path, suffix, data, err, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected only 2 backups to be kept")
+	}
+}
+
+func TestSoakAggregateCapsErrorsAndClosesWindows(t *testing.T) {
+	agg := &soakAggregate{config: ProviderConfig{Name: "p"}, errors: make(map[string]int)}
+	for i := 0; i < soakMaxErrorKinds+5; i++ {
+		agg.record(0, 0, 0, 0, fmt.Errorf("error %d", i))
+	}
+	if len(agg.errors) != soakMaxErrorKinds+1 || agg.errors[soakOtherErrors] != 5 {
+		t.Fatalf("expected error kinds to be capped, got %d kinds", len(agg.errors))
+	}
+
+	agg.closeWindow(time.Now())
+	agg.record(2*time.Second, time.Second, 50, 100, nil)
+	agg.record(4*time.Second, 3*time.Second, 100, 100, nil)
+	agg.record(0, 0, 0, 0, errors.New("boom"))
+	agg.closeWindow(time.Now())
+
+	s := agg.summary()
+	if len(s.Intervals) != 2 || s.Intervals[1].Requests != 3 || s.Intervals[1].Failures != 1 ||
+		s.Intervals[1].AvgTTFT != 2*time.Second || s.Intervals[1].AvgThroughput != 75 {
+		t.Fatalf("unexpected intervals: %+v", s.Intervals)
+	}
+	if s.Requests != 28 || s.Tokens != 200 || s.AvgTTFT != 2*time.Second || s.MaxThroughput != 100 {
+		t.Fatalf("unexpected summary: %+v", s)
+	}
+}
+
+func TestRunSoakTestWritesCheckpoints(t *testing.T) {
+	tke := testTokenizer(t)
+	server := mockSSEServer{chunks: []string{"Once ", "upon ", "a time."}}.start(t)
+	defer server.Close()
+
+	dir := t.TempDir()
+	providers := []ProviderConfig{{Name: "mock", BaseURL: server.URL, APIKey: "k", Model: "m"}}
+	opts := soakOptions{duration: 300 * time.Millisecond, interval: 50 * time.Millisecond, checkpoint: 100 * time.Millisecond}
+	if err := runSoakTest(providers, tke, ModeStreaming, false, opts, dir, dir, "test"); err != nil {
+		t.Fatalf("runSoakTest failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "soak-summary.json"))
+	if err != nil {
+		t.Fatalf("missing soak summary: %v", err)
+	}
+	var summary SoakSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatalf("invalid soak summary: %v", err)
+	}
+	if !summary.Final || len(summary.Providers) != 1 || summary.Providers[0].Requests < 2 || summary.Providers[0].Failures != 0 {
+		t.Fatalf("unexpected soak summary: %+v", summary)
+	}
+	if len(summary.Providers[0].Intervals) < 2 {
+		t.Fatalf("expected interim checkpoints before the final one, got %d", len(summary.Providers[0].Intervals))
+	}
+	report, err := os.ReadFile(filepath.Join(dir, "SOAK-REPORT.md"))
+	if err != nil || !strings.Contains(string(report), "**Status:** Final") {
+		t.Fatalf("expected a final soak report, got %v", err)
+	}
+}
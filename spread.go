@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// minSpreadRuns is the fewest successful runs a spread is computed from; with
+// a single run there is no variance to show.
+const minSpreadRuns = 2
+
+// DurationSpread describes how a latency varied across the successful runs
+// behind an average. Percentiles are nearest-rank.
+type DurationSpread struct {
+	Min    time.Duration `json:"min"`
+	Median time.Duration `json:"median"`
+	P90    time.Duration `json:"p90"`
+	P99    time.Duration `json:"p99"`
+	Max    time.Duration `json:"max"`
+	StdDev time.Duration `json:"stdDev"`
+}
+
+// RateSpread is DurationSpread for throughput, in tok/s.
+type RateSpread struct {
+	Min    float64 `json:"min"`
+	Median float64 `json:"median"`
+	P90    float64 `json:"p90"`
+	P99    float64 `json:"p99"`
+	Max    float64 `json:"max"`
+	StdDev float64 `json:"stdDev"`
+}
+
+// durationSpread summarizes values, or returns nil below minSpreadRuns.
+func durationSpread(values []time.Duration) *DurationSpread {
+	if len(values) < minSpreadRuns {
+		return nil
+	}
+	var stats runningStats
+	for _, v := range values {
+		stats.add(float64(v))
+	}
+	return &DurationSpread{
+		Min:    time.Duration(stats.min),
+		Median: percentileDuration(values, 50),
+		P90:    percentileDuration(values, 90),
+		P99:    percentileDuration(values, 99),
+		Max:    time.Duration(stats.max),
+		StdDev: time.Duration(stats.stddev()),
+	}
+}
+
+// rateSpread summarizes values, or returns nil below minSpreadRuns.
+func rateSpread(values []float64) *RateSpread {
+	if len(values) < minSpreadRuns {
+		return nil
+	}
+	var stats runningStats
+	for _, v := range values {
+		stats.add(v)
+	}
+	return &RateSpread{
+		Min:    stats.min,
+		Median: percentileFloat(values, 50),
+		P90:    percentileFloat(values, 90),
+		P99:    percentileFloat(values, 99),
+		Max:    stats.max,
+		StdDev: stats.stddev(),
+	}
+}
+
+// runSpreads computes the TTFT, E2E and throughput spreads of the successful
+// runs.
+func runSpreads(runs []RunSample) (ttft, e2e *DurationSpread, throughput *RateSpread) {
+	var ttfts, e2es []time.Duration
+	var rates []float64
+	for _, r := range runs {
+		if !r.Success {
+			continue
+		}
+		ttfts = append(ttfts, r.TTFT)
+		e2es = append(e2es, r.E2E)
+		rates = append(rates, r.Throughput)
+	}
+	return durationSpread(ttfts), durationSpread(e2es), rateSpread(rates)
+}
+
+// recordSpreads is runSpreads for diagnostic requests.
+func recordSpreads(records []DiagnosticRecord) (ttft, e2e *DurationSpread, throughput *RateSpread) {
+	runs := make([]RunSample, 0, len(records))
+	for _, r := range records {
+		runs = append(runs, RunSample{Success: r.Error == "", TTFT: r.TTFT, E2E: r.E2ELatency, Throughput: r.Throughput})
+	}
+	return runSpreads(runs)
+}
+
+// spreadRow is one provider's entry in the spread table.
+type spreadRow struct {
+	provider   string
+	mode       string
+	ttft, e2e  *DurationSpread
+	throughput *RateSpread
+}
+
+// writeDurationSpread renders one latency row of the spread table.
+func writeDurationSpread(report *strings.Builder, provider, mode, metric string, s *DurationSpread) {
+	fmt.Fprintf(report, "| %s | %s | %s | %s | %s | %s | %s | %s | %s |\n", provider, mode, metric,
+		formatDuration(s.Min), formatDuration(s.Median), formatDuration(s.P90), formatDuration(s.P99),
+		formatDuration(s.Max), formatDuration(s.StdDev))
+}
+
+// writeSpreadRows renders the spread table.
+func writeSpreadRows(report *strings.Builder, rows []spreadRow) {
+	if len(rows) == 0 {
+		return
+	}
+	report.WriteString("## Run Spread\n\n")
+	report.WriteString("How much each metric varied across the successful runs behind the averages above. " +
+		"A small standard deviation and a P99 close to the median mark a consistent provider; " +
+		"a wide range means the average owes something to luck.\n\n")
+	report.WriteString("| Provider | Mode | Metric | Min | Median | P90 | P99 | Max | Std Dev |\n")
+	report.WriteString("|----------|------|--------|-----|--------|-----|-----|-----|---------|\n")
+	for _, r := range rows {
+		if r.ttft != nil {
+			writeDurationSpread(report, r.provider, r.mode, "TTFT", r.ttft)
+		}
+		if r.e2e != nil {
+			writeDurationSpread(report, r.provider, r.mode, "E2E", r.e2e)
+		}
+		if s := r.throughput; s != nil {
+			fmt.Fprintf(report, "| %s | %s | Throughput | %.2f tok/s | %.2f tok/s | %.2f tok/s | %.2f tok/s | %.2f tok/s | %.2f tok/s |\n",
+				r.provider, r.mode, s.Min, s.Median, s.P90, s.P99, s.Max, s.StdDev)
+		}
+	}
+	report.WriteString("\n")
+}
+
+// writeSpreadSection adds the spread table for results with enough runs.
+func writeSpreadSection(report *strings.Builder, results []TestResult) {
+	rows := make([]spreadRow, 0, len(results))
+	for _, r := range results {
+		if r.TTFTSpread != nil || r.E2ESpread != nil || r.ThroughputSpread != nil {
+			rows = append(rows, spreadRow{providerLabel(r.Provider, r.Env), r.Mode, r.TTFTSpread, r.E2ESpread, r.ThroughputSpread})
+		}
+	}
+	writeSpreadRows(report, rows)
+}
+
+// writeDiagnosticSpreadSection is the diagnostic-report counterpart of
+// writeSpreadSection.
+func writeDiagnosticSpreadSection(report *strings.Builder, results []DiagnosticSummary) {
+	rows := make([]spreadRow, 0, len(results))
+	for _, r := range results {
+		if r.TTFTSpread != nil || r.E2ESpread != nil || r.ThroughputSpread != nil {
+			rows = append(rows, spreadRow{providerLabel(r.Provider, r.Env), r.Mode, r.TTFTSpread, r.E2ESpread, r.ThroughputSpread})
+		}
+	}
+	writeSpreadRows(report, rows)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunSpreads(t *testing.T) {
+	runs := []RunSample{
+		{Success: true, TTFT: 200 * time.Millisecond, E2E: 2 * time.Second, Throughput: 100},
+		{Success: true, TTFT: 400 * time.Millisecond, E2E: 3 * time.Second, Throughput: 80},
+		{Success: true, TTFT: 600 * time.Millisecond, E2E: 4 * time.Second, Throughput: 60},
+		{Success: false, Error: "timeout exceeded"},
+	}
+	ttft, e2e, throughput := runSpreads(runs)
+	if ttft == nil || ttft.Min != 200*time.Millisecond || ttft.Median != 400*time.Millisecond ||
+		ttft.P90 != 600*time.Millisecond || ttft.Max != 600*time.Millisecond || ttft.StdDev != 200*time.Millisecond {
+		t.Errorf("unexpected TTFT spread: %+v", ttft)
+	}
+	if e2e == nil || e2e.StdDev != time.Second {
+		t.Errorf("unexpected E2E spread: %+v", e2e)
+	}
+	if throughput == nil || throughput.Min != 60 || throughput.Median != 80 || throughput.P99 != 100 || throughput.StdDev != 20 {
+		t.Errorf("unexpected throughput spread: %+v", throughput)
+	}
+	if ttft, _, _ := runSpreads(runs[:1]); ttft != nil {
+		t.Error("a single successful run has no spread")
+	}
+
+	records := []DiagnosticRecord{
+		{TTFT: time.Second, E2ELatency: 2 * time.Second, Throughput: 50},
+		{TTFT: 3 * time.Second, E2ELatency: 4 * time.Second, Throughput: 30},
+		{Error: "boom"},
+	}
+	if ttft, _, _ := recordSpreads(records); ttft == nil || ttft.Median != time.Second || ttft.Max != 3*time.Second {
+		t.Errorf("unexpected diagnostic spread: %+v", ttft)
+	}
+}
+
+func TestSpreadSection(t *testing.T) {
+	ttft, e2e, throughput := runSpreads([]RunSample{
+		{Success: true, TTFT: 200 * time.Millisecond, E2E: 2 * time.Second, Throughput: 100},
+		{Success: true, TTFT: 400 * time.Millisecond, E2E: 3 * time.Second, Throughput: 80},
+	})
+	var report strings.Builder
+	writeSpreadSection(&report, []TestResult{
+		{Provider: "nim", Env: "prod", Mode: "streaming", TTFTSpread: ttft, E2ESpread: e2e, ThroughputSpread: throughput},
+		{Provider: "single-run", Mode: "streaming"},
+	})
+	out := report.String()
+	for _, want := range []string{
+		"| nim [prod] | streaming | TTFT | 0.200s | 0.200s | 0.400s | 0.400s | 0.400s | 0.141s |",
+		"| nim [prod] | streaming | Throughput | 80.00 tok/s | 80.00 tok/s | 100.00 tok/s |",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "single-run") {
+		t.Error("results without a spread must be skipped")
+	}
+
+	report.Reset()
+	writeDiagnosticSpreadSection(&report, []DiagnosticSummary{{Provider: "nim", Mode: "mixed", TTFTSpread: ttft}})
+	if !strings.Contains(report.String(), "| nim | mixed | TTFT |") || strings.Contains(report.String(), "| E2E |") {
+		t.Errorf("unexpected diagnostic section:\n%s", report.String())
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// percentileDuration returns the p-th percentile (0-100) of the given durations
+// using nearest-rank on a sorted copy. It returns 0 for an empty slice.
+func percentileDuration(values []time.Duration, p float64) time.Duration {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, len(values))
+	copy(sorted, values)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	rank = max(1, min(len(sorted), rank))
+	return sorted[rank-1]
+}
+
+// percentileFloat is percentileDuration for plain numbers such as throughput.
+func percentileFloat(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	rank = max(1, min(len(sorted), rank))
+	return sorted[rank-1]
+}
+
+// runningStats accumulates count, mean, variance, min and max in constant memory
+// using Welford's online algorithm.
+type runningStats struct {
+	count int64
+	mean  float64
+	m2    float64
+	min   float64
+	max   float64
+}
+
+// add records one observation.
+func (s *runningStats) add(x float64) {
+	s.count++
+	if s.count == 1 {
+		s.min, s.max = x, x
+	} else {
+		s.min = math.Min(s.min, x)
+		s.max = math.Max(s.max, x)
+	}
+	delta := x - s.mean
+	s.mean += delta / float64(s.count)
+	s.m2 += delta * (x - s.mean)
+}
+
+// stddev returns the sample standard deviation.
+func (s *runningStats) stddev() float64 {
+	if s.count < 2 {
+		return 0
+	}
+	return math.Sqrt(s.m2 / float64(s.count-1))
+}
+
+const (
+	// histogramBase is the smallest bucket bound of a durationHistogram.
+	histogramBase = time.Millisecond
+	// histogramGrowth is the ratio between consecutive bucket bounds (~5% error).
+	histogramGrowth = 1.05
+	// histogramBuckets covers 1ms up to roughly 40 minutes.
+	histogramBuckets = 300
+)
+
+// durationHistogram counts durations in fixed log-scaled buckets so percentiles
+// can be estimated over arbitrarily long runs without keeping every sample.
+type durationHistogram struct {
+	counts [histogramBuckets + 1]int64
+	total  int64
+}
+
+// bucketBound returns the upper bound of bucket i.
+func bucketBound(i int) time.Duration {
+	return time.Duration(float64(histogramBase) * math.Pow(histogramGrowth, float64(i)))
+}
+
+// add records one duration; values past the last bound land in an overflow bucket.
+func (h *durationHistogram) add(d time.Duration) {
+	i := 0
+	if d > histogramBase {
+		i = int(math.Ceil(math.Log(float64(d)/float64(histogramBase)) / math.Log(histogramGrowth)))
+	}
+	h.counts[min(i, histogramBuckets)]++
+	h.total++
+}
+
+// percentile estimates the p-th percentile (0-100) as the upper bound of the
+// bucket containing the nearest-rank sample.
+func (h *durationHistogram) percentile(p float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(p / 100 * float64(h.total)))
+	rank = max(1, min(h.total, rank))
+	var seen int64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			return bucketBound(i)
+		}
+	}
+	return bucketBound(histogramBuckets)
+}
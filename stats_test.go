@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPercentileDuration(t *testing.T) {
+	values := []time.Duration{5, 1, 4, 2, 3, 10, 9, 8, 7, 6}
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{p: 0, want: 1},
+		{p: 50, want: 5},
+		{p: 90, want: 9},
+		{p: 99, want: 10},
+		{p: 100, want: 10},
+	}
+	for _, tt := range tests {
+		if got := percentileDuration(values, tt.p); got != tt.want {
+			t.Errorf("percentileDuration(p%.0f) = %d, want %d", tt.p, got, tt.want)
+		}
+	}
+	if percentileDuration(nil, 50) != 0 {
+		t.Errorf("expected 0 for empty input")
+	}
+	if values[0] != 5 {
+		t.Errorf("expected input slice to be left unsorted")
+	}
+}
+
+func TestRunningStats(t *testing.T) {
+	var s runningStats
+	for _, x := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+		s.add(x)
+	}
+	if s.count != 8 || s.mean != 5 || s.min != 2 || s.max != 9 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+	if sd := s.stddev(); sd < 2.13 || sd > 2.14 {
+		t.Fatalf("stddev = %.4f, want ~2.138", sd)
+	}
+}
+
+func TestDurationHistogramPercentile(t *testing.T) {
+	var h durationHistogram
+	for i := 1; i <= 100; i++ {
+		h.add(time.Duration(i) * 10 * time.Millisecond)
+	}
+	for _, tt := range []struct {
+		p    float64
+		want time.Duration
+	}{{50, 500 * time.Millisecond}, {90, 900 * time.Millisecond}, {99, 990 * time.Millisecond}} {
+		got := h.percentile(tt.p)
+		if got < tt.want || float64(got) > float64(tt.want)*histogramGrowth {
+			t.Errorf("p%.0f = %s, want within 5%% above %s", tt.p, got, tt.want)
+		}
+	}
+	var empty durationHistogram
+	if empty.percentile(50) != 0 {
+		t.Errorf("expected 0 for empty histogram")
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/lamim/llm-api-speed/pkg/benchmark"
+)
+
+// streamStats is what the tracker keeps for one provider.
+type streamStats struct {
+	chunks   benchmark.ChunkStats
+	curves   benchmark.CurveSum
+	toolArgs ToolArgsStats
+	itl      itlTracker
+}
+
+// streamTracker accumulates per-stream details of successful streams (chunk
+// sizes, throughput curves, inter-token gaps) per provider, so results can
+// report them without threading them through every run function.
+type streamTracker struct {
+	mu        sync.Mutex
+	providers map[string]*streamStats
+}
+
+// newStreamTracker returns an empty tracker.
+func newStreamTracker() *streamTracker {
+	return &streamTracker{providers: make(map[string]*streamStats)}
+}
+
+// sessionStreams is the stream tracker shared by every provider in the session.
+var sessionStreams = newStreamTracker()
+
+// statsFor returns provider's stats, creating them on first use. The caller
+// holds t.mu.
+func (t *streamTracker) statsFor(provider string) *streamStats {
+	stats, ok := t.providers[provider]
+	if !ok {
+		stats = &streamStats{}
+		t.providers[provider] = stats
+	}
+	return stats
+}
+
+// add records one successful stream.
+func (t *streamTracker) add(provider string, s benchmark.Sample) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats := t.statsFor(provider)
+	stats.chunks.Merge(s.Chunks)
+	stats.curves.Add(s.Curve)
+	stats.itl.add(s.ITL)
+}
+
+// addToolArgs records the tool-argument timings of one successful stream.
+func (t *streamTracker) addToolArgs(provider string, s *ToolArgsStats) {
+	if s == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.statsFor(provider).toolArgs.merge(s)
+}
+
+// chunks returns a copy of the chunk statistics for provider, or nil if none
+// were recorded.
+func (t *streamTracker) chunks(provider string) *benchmark.ChunkStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats, ok := t.providers[provider]
+	if !ok || stats.chunks.Chunks == 0 {
+		return nil
+	}
+	c := stats.chunks
+	return &c
+}
+
+// curve returns the mean throughput curve for provider.
+func (t *streamTracker) curve(provider string) []float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats, ok := t.providers[provider]
+	if !ok {
+		return nil
+	}
+	return stats.curves.Mean()
+}
+
+// itl returns the inter-token latency of provider's streams, or nil if none
+// were recorded.
+func (t *streamTracker) itl(provider string) *ITLStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats, ok := t.providers[provider]
+	if !ok {
+		return nil
+	}
+	return stats.itl.stats()
+}
+
+// toolArgs returns a copy of the tool-argument timings for provider, or nil if
+// no tool calls were recorded.
+func (t *streamTracker) toolArgs(provider string) *ToolArgsStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats, ok := t.providers[provider]
+	if !ok || stats.toolArgs.Calls+stats.toolArgs.Incomplete == 0 {
+		return nil
+	}
+	a := stats.toolArgs
+	return &a
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lamim/llm-api-speed/pkg/providers"
+	"github.com/pkoukk/tiktoken-go"
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+const structuredModeLabel = "structured-output"
+
+// structuredTimeout bounds one structured-output request.
+const structuredTimeout = 2 * time.Minute
+
+// structuredPrompt asks for a response that fills structuredSchema.
+const structuredPrompt = "Give a plausible current weather report for Oslo with a three-day forecast. " +
+	"Respond only with JSON that matches the provided schema."
+
+// structuredSchema is the json_schema every structured-output run requests.
+// Strict mode requires every property to be required and no others allowed.
+var structuredSchema = jsonschema.Definition{
+	Type: jsonschema.Object,
+	Properties: map[string]jsonschema.Definition{
+		"city":          {Type: jsonschema.String},
+		"temperature_c": {Type: jsonschema.Number},
+		"conditions":    {Type: jsonschema.String, Enum: []string{"sunny", "cloudy", "rain", "snow", "fog", "storm"}},
+		"forecast": {
+			Type: jsonschema.Array,
+			Items: &jsonschema.Definition{
+				Type: jsonschema.Object,
+				Properties: map[string]jsonschema.Definition{
+					"day":    {Type: jsonschema.String},
+					"high_c": {Type: jsonschema.Number},
+					"low_c":  {Type: jsonschema.Number},
+				},
+				Required:             []string{"day", "high_c", "low_c"},
+				AdditionalProperties: false,
+			},
+		},
+	},
+	Required:             []string{"city", "temperature_c", "conditions", "forecast"},
+	AdditionalProperties: false,
+}
+
+// structuredOptions configures a structured-output session.
+type structuredOptions struct {
+	runs int
+}
+
+// StructuredRun is one structured-output request.
+type StructuredRun struct {
+	Run      int           `json:"run"`
+	Strict   bool          `json:"strict"`
+	TTFT     time.Duration `json:"ttft,omitempty"`
+	E2E      time.Duration `json:"e2e,omitempty"`
+	Tokens   int           `json:"tokens,omitempty"`
+	Conforms bool          `json:"conforms"`
+	// Problem says why a response did not conform.
+	Problem string `json:"problem,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// StructuredStats aggregates the strict or non-strict runs of one provider.
+type StructuredStats struct {
+	Runs       int `json:"runs"`
+	Succeeded  int `json:"succeeded"`
+	Conforming int `json:"conforming"`
+	// ConformanceRate is the share of successful responses that matched the
+	// schema.
+	ConformanceRate float64       `json:"conformanceRate"`
+	AvgTTFT         time.Duration `json:"avgTtft,omitempty"`
+	AvgE2E          time.Duration `json:"avgE2e,omitempty"`
+}
+
+// StructuredSummary compares a provider's strict and non-strict runs.
+type StructuredSummary struct {
+	Provider  string          `json:"provider"`
+	Model     string          `json:"model"`
+	Strict    StructuredStats `json:"strict"`
+	NonStrict StructuredStats `json:"nonStrict"`
+	Runs      []StructuredRun `json:"runs"`
+}
+
+// unexpectedProperty returns the path of the first property data has that
+// schema does not allow, which jsonschema.Validate does not check.
+func unexpectedProperty(schema jsonschema.Definition, data any, path string) string {
+	switch schema.Type {
+	case jsonschema.Object:
+		obj, _ := data.(map[string]any)
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			prop, ok := schema.Properties[key]
+			if !ok {
+				if schema.AdditionalProperties == false {
+					return path + key
+				}
+				continue
+			}
+			if bad := unexpectedProperty(prop, obj[key], path+key+"."); bad != "" {
+				return bad
+			}
+		}
+	case jsonschema.Array:
+		items, _ := data.([]any)
+		for i, item := range items {
+			if bad := unexpectedProperty(*schema.Items, item, fmt.Sprintf("%s%d.", path, i)); bad != "" {
+				return bad
+			}
+		}
+	}
+	return ""
+}
+
+// checkStructured validates a response against structuredSchema and returns
+// why it does not conform, or "" if it does. The response must be the JSON
+// document alone; markdown fences or prose around it do not conform.
+func checkStructured(response string) string {
+	response = strings.TrimSpace(response)
+	if response == "" {
+		return "empty response"
+	}
+	var data any
+	if err := json.Unmarshal([]byte(response), &data); err != nil {
+		return "not valid JSON"
+	}
+	if !jsonschema.Validate(structuredSchema, data) {
+		return "does not match the schema"
+	}
+	if bad := unexpectedProperty(structuredSchema, data, ""); bad != "" {
+		return fmt.Sprintf("unexpected property %s", bad)
+	}
+	return ""
+}
+
+// structuredRun streams one structured-output request and validates the
+// response.
+func structuredRun(config ProviderConfig, tke *tiktoken.Tiktoken, strict bool, run int) StructuredRun {
+	result := StructuredRun{Run: run, Strict: strict}
+	req := openai.ChatCompletionRequest{
+		Model:     config.Model,
+		Messages:  []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: structuredPrompt}},
+		MaxTokens: 512,
+		Stream:    true,
+		ResponseFormat: &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:   "weather_report",
+				Schema: &structuredSchema,
+				Strict: strict,
+			},
+		},
+	}
+	req = bustCache(config.Quirks.apply(req))
+
+	ctx, cancel := context.WithTimeout(shutdownCtx, structuredTimeout)
+	defer cancel()
+	start := time.Now()
+	stream, err := providers.OpenStream(ctx, benchProvider(config), req)
+	if err != nil {
+		result.Error = fmt.Sprintf("error creating stream: %v", err)
+		return result
+	}
+	defer func() {
+		_ = stream.Close()
+	}()
+
+	// Only the content is validated; reasoning streamed alongside it is not
+	// part of the structured response
+	var content, output strings.Builder
+	for {
+		delta, recvErr := stream.Recv()
+		if errors.Is(recvErr, io.EOF) {
+			break
+		}
+		if recvErr != nil {
+			result.Error = fmt.Sprintf("stream error: %v", recvErr)
+			break
+		}
+		if result.TTFT == 0 && !delta.Empty() {
+			result.TTFT = time.Since(start)
+		}
+		content.WriteString(delta.Content)
+		output.WriteString(delta.Content)
+		output.WriteString(delta.Reasoning)
+	}
+	result.E2E = time.Since(start)
+	result.Tokens = len(tke.Encode(output.String(), nil, nil))
+	sessionBudget.record(config, countPromptTokens(tke, req.Messages), result.Tokens)
+	if result.Error == "" {
+		result.Problem = checkStructured(content.String())
+		result.Conforms = result.Problem == ""
+	}
+	return result
+}
+
+// summarizeStructured aggregates runs with the given strictness.
+func summarizeStructured(runs []StructuredRun, strict bool) StructuredStats {
+	var stats StructuredStats
+	var ttftSum, e2eSum time.Duration
+	for _, r := range runs {
+		if r.Strict != strict {
+			continue
+		}
+		stats.Runs++
+		if r.Error != "" {
+			continue
+		}
+		stats.Succeeded++
+		ttftSum += r.TTFT
+		e2eSum += r.E2E
+		if r.Conforms {
+			stats.Conforming++
+		}
+	}
+	if stats.Succeeded > 0 {
+		stats.ConformanceRate = float64(stats.Conforming) / float64(stats.Succeeded)
+		stats.AvgTTFT = ttftSum / time.Duration(stats.Succeeded)
+		stats.AvgE2E = e2eSum / time.Duration(stats.Succeeded)
+	}
+	return stats
+}
+
+// benchmarkStructured alternates strict and non-strict runs for one provider,
+// so drift in the provider's load affects both alike.
+func benchmarkStructured(config ProviderConfig, tke *tiktoken.Tiktoken, opts structuredOptions) StructuredSummary {
+	summary := StructuredSummary{Provider: config.Name, Model: config.Model}
+	for run := 1; run <= opts.runs; run++ {
+		for _, strict := range []bool{true, false} {
+			if err := canStartRun(); err != nil {
+				log.Printf("[%s] Structured-output run %d skipped: %v", config.Name, run, err)
+				break
+			}
+			r := structuredRun(config, tke, strict, run)
+			switch {
+			case r.Error != "":
+				log.Printf("[%s] Structured-output run %d (strict=%t) failed: %s", config.Name, run, strict, r.Error)
+			case !r.Conforms:
+				log.Printf("[%s] Structured-output run %d (strict=%t): %s", config.Name, run, strict, r.Problem)
+			}
+			summary.Runs = append(summary.Runs, r)
+		}
+	}
+	summary.Strict = summarizeStructured(summary.Runs, true)
+	summary.NonStrict = summarizeStructured(summary.Runs, false)
+	return summary
+}
+
+// runStructuredBenchmark benchmarks every provider concurrently and writes
+// structured-output-summary.json and STRUCTURED-OUTPUT-REPORT.md.
+func runStructuredBenchmark(providers []ProviderConfig, tke *tiktoken.Tiktoken, opts structuredOptions, resultsDir, sessionTimestamp string) error {
+	log.Printf("=== STRUCTURED OUTPUT: %d provider(s), %d strict and %d non-strict run(s) each ===",
+		len(providers), opts.runs, opts.runs)
+
+	summaries := make([]StructuredSummary, len(providers))
+	var wg sync.WaitGroup
+	for i, p := range providers {
+		wg.Add(1)
+		go func(i int, p ProviderConfig) {
+			defer wg.Done()
+			summaries[i] = benchmarkStructured(p, tke, opts)
+		}(i, p)
+	}
+	wg.Wait()
+
+	data, err := json.MarshalIndent(summaries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling structured output summary: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(resultsDir, "structured-output-summary.json"), data, 0600); err != nil {
+		return fmt.Errorf("error writing structured output summary: %w", err)
+	}
+	return generateStructuredReport(resultsDir, summaries, sessionTimestamp)
+}
+
+// conformanceCell renders a conformance rate with its counts.
+func conformanceCell(s StructuredStats) string {
+	if s.Succeeded == 0 {
+		return NotAvailable
+	}
+	return fmt.Sprintf("%.0f%% (%d/%d)", 100*s.ConformanceRate, s.Conforming, s.Succeeded)
+}
+
+// generateStructuredReport writes STRUCTURED-OUTPUT-REPORT.md.
+func generateStructuredReport(resultsDir string, summaries []StructuredSummary, sessionTimestamp string) error {
+	filename := filepath.Join(resultsDir, "STRUCTURED-OUTPUT-REPORT.md")
+
+	var report strings.Builder
+	report.WriteString("# LLM API Structured Output Results\n\n")
+	fmt.Fprintf(&report, "**Test Session:** %s\n\n", sessionTimestamp)
+	report.WriteString("Every run requests the same json_schema response format, alternating strict: true and strict: false. " +
+		"Each streamed response is validated against the schema: it must be the JSON document alone, with every required property, " +
+		"the right types and enum values, and no extra properties. A positive Δ means strict mode is slower.\n\n")
+	report.WriteString("---\n\n")
+
+	report.WriteString("## Summary\n\n")
+	report.WriteString("| Provider | Model | Strict Conformance | Non-Strict Conformance | Strict TTFT | Non-Strict TTFT | Δ TTFT | Strict E2E | Non-Strict E2E | Δ E2E |\n")
+	report.WriteString("|----------|-------|--------------------|------------------------|-------------|-----------------|--------|------------|----------------|-------|\n")
+	for _, s := range summaries {
+		deltaTTFT, deltaE2E := NotAvailable, NotAvailable
+		if s.Strict.Succeeded > 0 && s.NonStrict.Succeeded > 0 {
+			deltaTTFT = formatSignedDuration(s.Strict.AvgTTFT - s.NonStrict.AvgTTFT)
+			deltaE2E = formatSignedDuration(s.Strict.AvgE2E - s.NonStrict.AvgE2E)
+		}
+		fmt.Fprintf(&report, "| %s | %s | %s | %s | %s | %s | %s | %s | %s | %s |\n",
+			s.Provider, s.Model, conformanceCell(s.Strict), conformanceCell(s.NonStrict),
+			formatDurationOrNA(s.Strict.AvgTTFT), formatDurationOrNA(s.NonStrict.AvgTTFT), deltaTTFT,
+			formatDurationOrNA(s.Strict.AvgE2E), formatDurationOrNA(s.NonStrict.AvgE2E), deltaE2E)
+	}
+	report.WriteString("\n")
+
+	report.WriteString("## Runs\n\n")
+	report.WriteString("| Provider | Run | Strict | TTFT | E2E | Tokens | Conforms | Problem |\n")
+	report.WriteString("|----------|-----|--------|------|-----|--------|----------|---------|\n")
+	for _, s := range summaries {
+		for _, r := range s.Runs {
+			conforms, problem := "no", r.Problem
+			switch {
+			case r.Error != "":
+				conforms, problem = NotAvailable, r.Error
+			case r.Conforms:
+				conforms = "yes"
+			}
+			fmt.Fprintf(&report, "| %s | %d | %t | %s | %s | %d | %s | %s |\n",
+				s.Provider, r.Run, r.Strict, formatDurationOrNA(r.TTFT), formatDurationOrNA(r.E2E), r.Tokens, conforms, problem)
+		}
+	}
+	report.WriteString("\n")
+
+	report.WriteString("---\n\n")
+	fmt.Fprintf(&report, "*Report generated at %s*\n", time.Now().Format("2006-01-02 15:04:05"))
+
+	if err := os.WriteFile(filename, []byte(report.String()), 0600); err != nil {
+		return fmt.Errorf("error writing structured output report: %w", err)
+	}
+	log.Printf("Structured output report generated: %s", filename)
+	return nil
+}
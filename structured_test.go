@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const conformingWeather = `{"city":"Oslo","temperature_c":4.5,"conditions":"rain",` +
+	`"forecast":[{"day":"Mon","high_c":6,"low_c":1}]}`
+
+func TestCheckStructured(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string
+	}{
+		{"conforming", conformingWeather, ""},
+		{"surrounding whitespace", "\n " + conformingWeather + "\n", ""},
+		{"empty", "  ", "empty response"},
+		{"fenced", "```json\n" + conformingWeather + "\n```", "not valid JSON"},
+		{"missing required", `{"city":"Oslo","temperature_c":4,"conditions":"rain"}`, "does not match the schema"},
+		{"wrong enum", strings.Replace(conformingWeather, `"rain"`, `"drizzle"`, 1), "does not match the schema"},
+		{"extra top-level property", strings.Replace(conformingWeather, `{"city"`, `{"humidity":80,"city"`, 1), "unexpected property humidity"},
+		{"extra nested property", strings.Replace(conformingWeather, `"low_c":1`, `"low_c":1,"wind":3`, 1), "unexpected property forecast.0.wind"},
+	}
+	for _, tt := range tests {
+		if got := checkStructured(tt.response); got != tt.want {
+			t.Errorf("%s: checkStructured = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// structuredServer streams a conforming document for strict requests and the
+// same document in a markdown fence otherwise.
+func structuredServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ResponseFormat struct {
+				Type       string `json:"type"`
+				JSONSchema struct {
+					Strict bool `json:"strict"`
+				} `json:"json_schema"`
+			} `json:"response_format"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ResponseFormat.Type != "json_schema" {
+			http.Error(w, "expected a json_schema response format", http.StatusBadRequest)
+			return
+		}
+		body := conformingWeather
+		if !req.ResponseFormat.JSONSchema.Strict {
+			body = "```json\n" + body + "\n```"
+		}
+		content, _ := json.Marshal(body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%s}}]}\n\n", content)
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestBenchmarkStructured(t *testing.T) {
+	config := ProviderConfig{Name: "mock", BaseURL: structuredServer(t).URL, APIKey: "k", Model: "m"}
+	summary := benchmarkStructured(config, testTokenizer(t), structuredOptions{runs: 2})
+	if len(summary.Runs) != 4 {
+		t.Fatalf("expected 2 strict and 2 non-strict runs, got %+v", summary.Runs)
+	}
+	if summary.Strict.Conforming != 2 || summary.Strict.ConformanceRate != 1 {
+		t.Fatalf("expected strict runs to conform, got %+v", summary.Strict)
+	}
+	if summary.NonStrict.Succeeded != 2 || summary.NonStrict.Conforming != 0 {
+		t.Fatalf("expected fenced non-strict runs not to conform, got %+v", summary.NonStrict)
+	}
+
+	dir := t.TempDir()
+	if err := generateStructuredReport(dir, []StructuredSummary{summary}, "20250101-000000"); err != nil {
+		t.Fatalf("generateStructuredReport failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "STRUCTURED-OUTPUT-REPORT.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(data)
+	if !strings.Contains(report, "| mock | m | 100% (2/2) | 0% (0/2) |") || !strings.Contains(report, "not valid JSON") {
+		t.Fatalf("unexpected report:\n%s", report)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// badgeFileName is the SVG badge written with --badge.
+const badgeFileName = "badge.svg"
+
+// writeBadge enables the SVG badge; set with --badge.
+var writeBadge bool
+
+// providerStanding is one provider's best figures across a session's results.
+type providerStanding struct {
+	label      string
+	ttft       float64 // seconds, 0 when nothing succeeded
+	throughput float64
+	failed     int
+	total      int
+}
+
+// failureRate is the share of requests that failed.
+func (p providerStanding) failureRate() float64 {
+	if p.total == 0 {
+		return 0
+	}
+	return float64(p.failed) / float64(p.total)
+}
+
+// sessionStandings folds results and diagnostic summaries into one standing per
+// provider label, keeping each provider's best TTFT and throughput. A standard
+// result counts as one request; a diagnostic summary as all of its requests.
+func sessionStandings(results []TestResult, diagnostics []DiagnosticSummary) []providerStanding {
+	byLabel := make(map[string]*providerStanding)
+	get := func(provider, env string) *providerStanding {
+		label := providerLabel(provider, env)
+		p, ok := byLabel[label]
+		if !ok {
+			p = &providerStanding{label: label}
+			byLabel[label] = p
+		}
+		return p
+	}
+	best := func(p *providerStanding, ttft, throughput float64) {
+		if ttft > 0 && (p.ttft == 0 || ttft < p.ttft) {
+			p.ttft = ttft
+		}
+		p.throughput = max(p.throughput, throughput)
+	}
+	for _, r := range results {
+		p := get(r.Provider, r.Env)
+		p.total++
+		if !r.Success {
+			p.failed++
+			continue
+		}
+		best(p, r.TTFT.Seconds(), r.Throughput)
+	}
+	for _, d := range diagnostics {
+		p := get(d.Provider, d.Env)
+		p.total += d.TotalRequests
+		p.failed += d.Failed
+		if d.Successful > 0 {
+			best(p, d.AvgTTFT.Seconds(), d.AvgThroughput)
+		}
+	}
+
+	standings := make([]providerStanding, 0, len(byLabel))
+	for _, p := range byLabel {
+		standings = append(standings, *p)
+	}
+	sort.Slice(standings, func(a, b int) bool { return standings[a].label < standings[b].label })
+	return standings
+}
+
+// sessionHeadline picks the fastest TTFT, highest throughput and worst failure
+// rate. Each pick is nil when no provider qualifies.
+func sessionHeadline(standings []providerStanding) (fastest, highest, worst *providerStanding) {
+	for i := range standings {
+		p := &standings[i]
+		if p.ttft > 0 && (fastest == nil || p.ttft < fastest.ttft) {
+			fastest = p
+		}
+		if p.throughput > 0 && (highest == nil || p.throughput > highest.throughput) {
+			highest = p
+		}
+		if p.failed > 0 && (worst == nil || p.failureRate() > worst.failureRate()) {
+			worst = p
+		}
+	}
+	return fastest, highest, worst
+}
+
+// summaryLine renders the headline as one terminal line, e.g. "fastest TTFT:
+// groq 0.180s | highest throughput: groq 812.3 tok/s | worst failure rate:
+// minimax 25.0% (1/4)".
+func summaryLine(standings []providerStanding) string {
+	fastest, highest, worst := sessionHeadline(standings)
+	parts := make([]string, 0, 3)
+	if fastest != nil {
+		parts = append(parts, fmt.Sprintf("fastest TTFT: %s %.3fs", fastest.label, fastest.ttft))
+	}
+	if highest != nil {
+		parts = append(parts, fmt.Sprintf("highest throughput: %s %.1f tok/s", highest.label, highest.throughput))
+	}
+	if worst != nil {
+		parts = append(parts, fmt.Sprintf("worst failure rate: %s %.1f%% (%d/%d)",
+			worst.label, 100*worst.failureRate(), worst.failed, worst.total))
+	} else if len(standings) > 0 {
+		parts = append(parts, "no failures")
+	}
+	return strings.Join(parts, " | ")
+}
+
+// chartWidth is the length of the longest bar of a terminal chart.
+const chartWidth = 30
+
+// chartBar is one provider's bar; value is 0 when nothing succeeded.
+type chartBar struct {
+	label string
+	value float64
+}
+
+// renderBarChart draws bars as plain ASCII, so it survives any terminal and CI
+// log, scaled to the largest value and followed by the figure itself.
+func renderBarChart(title string, bars []chartBar, format func(float64) string) string {
+	labelWidth, maxValue := 0, 0.0
+	for _, bar := range bars {
+		labelWidth = max(labelWidth, utf8.RuneCountInString(bar.label))
+		maxValue = max(maxValue, bar.value)
+	}
+	var b strings.Builder
+	b.WriteString(title + ":\n")
+	for _, bar := range bars {
+		padding := strings.Repeat(" ", labelWidth-utf8.RuneCountInString(bar.label))
+		if bar.value <= 0 {
+			fmt.Fprintf(&b, "  %s%s  (no successful runs)\n", bar.label, padding)
+			continue
+		}
+		n := max(1, int(math.Round(chartWidth*bar.value/maxValue)))
+		fmt.Fprintf(&b, "  %s%s  %-*s %s\n", bar.label, padding, chartWidth, strings.Repeat("#", n), format(bar.value))
+	}
+	return b.String()
+}
+
+// summaryCharts renders throughput and TTFT charts of the standings, best
+// first, so providers can be compared at a glance without opening a report.
+func summaryCharts(standings []providerStanding) string {
+	if len(standings) < 2 {
+		return ""
+	}
+	throughput := make([]chartBar, len(standings))
+	ttft := make([]chartBar, len(standings))
+	for i, p := range standings {
+		throughput[i] = chartBar{label: p.label, value: p.throughput}
+		ttft[i] = chartBar{label: p.label, value: p.ttft}
+	}
+	// Providers without a figure go last
+	sort.SliceStable(throughput, func(a, b int) bool { return throughput[a].value > throughput[b].value })
+	sort.SliceStable(ttft, func(a, b int) bool {
+		if (ttft[a].value > 0) != (ttft[b].value > 0) {
+			return ttft[a].value > 0
+		}
+		return ttft[a].value < ttft[b].value
+	})
+	return renderBarChart("Throughput (tok/s, higher is better)", throughput, func(v float64) string { return fmt.Sprintf("%.1f", v) }) +
+		renderBarChart("TTFT (s, lower is better)", ttft, func(v float64) string { return fmt.Sprintf("%.3f", v) })
+}
+
+// badgeCharWidth approximates the width of one character of 11px Verdana, the
+// font badges conventionally use.
+const badgeCharWidth = 7
+
+// renderBadge draws a two-part badge in the common flat style, with label on
+// grey and message on colour.
+func renderBadge(label, message, color string) string {
+	labelWidth := 10 + badgeCharWidth*utf8.RuneCountInString(label)
+	messageWidth := 10 + badgeCharWidth*utf8.RuneCountInString(message)
+	width := labelWidth + messageWidth
+	label, message = html.EscapeString(label), html.EscapeString(message)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`+"\n", width, label, message)
+	fmt.Fprintf(&b, `  <title>%s: %s</title>`+"\n", label, message)
+	fmt.Fprintf(&b, `  <clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>`+"\n", width)
+	b.WriteString(`  <g clip-path="url(#r)">` + "\n")
+	fmt.Fprintf(&b, `    <rect width="%d" height="20" fill="#555"/>`+"\n", labelWidth)
+	fmt.Fprintf(&b, `    <rect x="%d" width="%d" height="20" fill="%s"/>`+"\n", labelWidth, messageWidth, color)
+	b.WriteString("  </g>\n")
+	b.WriteString(`  <g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">` + "\n")
+	fmt.Fprintf(&b, `    <text x="%d" y="14">%s</text>`+"\n", labelWidth/2, label)
+	fmt.Fprintf(&b, `    <text x="%d" y="14">%s</text>`+"\n", labelWidth+messageWidth/2, message)
+	b.WriteString("  </g>\n</svg>\n")
+	return b.String()
+}
+
+// sessionBadge is the badge for a session: its highest-throughput provider, or
+// a red "no successful runs" when nothing succeeded.
+func sessionBadge(standings []providerStanding) string {
+	_, highest, _ := sessionHeadline(standings)
+	if highest == nil {
+		return renderBadge("fastest", "no successful runs", "#e05d44")
+	}
+	return renderBadge("fastest", fmt.Sprintf("%s %.0f tok/s", highest.label, highest.throughput), "#4c1")
+}
+
+// printSessionSummary logs the one-line summary, the session's usage and,
+// when several providers were tested, bar charts of them; with --badge it also
+// writes the SVG badge to outDir.
+func printSessionSummary(outDir string, results []TestResult, diagnostics []DiagnosticSummary) error {
+	standings := sessionStandings(results, diagnostics)
+	if line := summaryLine(standings); line != "" {
+		log.Printf("Summary: %s", line)
+	}
+	if usage := sessionUsage(results, diagnostics); usage.tokens() > 0 {
+		log.Printf("Session usage: %s", usage)
+	}
+	if charts := summaryCharts(standings); charts != "" {
+		log.Printf("Best per provider:\n%s", charts)
+	}
+	if !writeBadge {
+		return nil
+	}
+	filename := filepath.Join(outDir, badgeFileName)
+	if err := os.WriteFile(filename, []byte(sessionBadge(standings)), 0600); err != nil {
+		return fmt.Errorf("error writing badge: %w", err)
+	}
+	log.Printf("Badge saved: %s", filename)
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummaryLine(t *testing.T) {
+	results := []TestResult{
+		{Provider: "groq", Mode: string(ModeStreaming), Success: true, TTFT: 300 * time.Millisecond, Throughput: 812.3},
+		{Provider: "nim", Mode: string(ModeStreaming), Success: true, TTFT: 180 * time.Millisecond, Throughput: 90},
+		{Provider: "nim", Mode: string(ModeToolCalling), Success: true, TTFT: 250 * time.Millisecond, Throughput: 120},
+		{Provider: "minimax", Mode: string(ModeStreaming), Error: "timeout exceeded"},
+	}
+	diagnostics := []DiagnosticSummary{
+		{Provider: "novita", Mode: string(ModeStreaming), TotalRequests: 4, Successful: 3, Failed: 1,
+			AvgTTFT: 400 * time.Millisecond, AvgThroughput: 60},
+	}
+	got := summaryLine(sessionStandings(results, diagnostics))
+	want := "fastest TTFT: nim 0.180s | highest throughput: groq 812.3 tok/s | worst failure rate: minimax 100.0% (1/1)"
+	if got != want {
+		t.Errorf("summaryLine =\n %q\nwant\n %q", got, want)
+	}
+
+	clean := []TestResult{{Provider: "nim", Success: true, TTFT: time.Second, Throughput: 10}}
+	if got := summaryLine(sessionStandings(clean, nil)); !strings.HasSuffix(got, "| no failures") {
+		t.Errorf("summaryLine without failures = %q", got)
+	}
+	if got := summaryLine(nil); got != "" {
+		t.Errorf("summaryLine of nothing = %q, want empty", got)
+	}
+}
+
+func TestRenderBadgeIsValidSVG(t *testing.T) {
+	svg := renderBadge("fastest", `a&b <"c"> 812 tok/s`, "#4c1")
+	if err := xml.Unmarshal([]byte(svg), new(struct{})); err != nil {
+		t.Fatalf("badge is not well-formed XML: %v\n%s", err, svg)
+	}
+	if !strings.Contains(svg, "a&amp;b &lt;&#34;c&#34;&gt; 812 tok/s") {
+		t.Errorf("badge message not escaped:\n%s", svg)
+	}
+}
+
+func TestSummaryCharts(t *testing.T) {
+	results := []TestResult{
+		{Provider: "groq", Success: true, TTFT: 300 * time.Millisecond, Throughput: 800},
+		{Provider: "nim", Env: "prod", Success: true, TTFT: 150 * time.Millisecond, Throughput: 200},
+		{Provider: "minimax", Error: "timeout exceeded"},
+	}
+	charts := summaryCharts(sessionStandings(results, nil))
+	want := `Throughput (tok/s, higher is better):
+  groq        ############################## 800.0
+  nim [prod]  ########                       200.0
+  minimax     (no successful runs)
+TTFT (s, lower is better):
+  nim [prod]  ###############                0.150
+  groq        ############################## 0.300
+  minimax     (no successful runs)
+`
+	if charts != want {
+		t.Errorf("summaryCharts =\n%s\nwant\n%s", charts, want)
+	}
+	if got := summaryCharts(sessionStandings(results[:1], nil)); got != "" {
+		t.Errorf("a single provider needs no chart, got:\n%s", got)
+	}
+}
+
+func TestPrintSessionSummaryWritesBadge(t *testing.T) {
+	saved := writeBadge
+	defer func() { writeBadge = saved }()
+	dir := t.TempDir()
+	results := []TestResult{{Provider: "groq", Success: true, TTFT: time.Second, Throughput: 812.4}}
+
+	writeBadge = false
+	if err := printSessionSummary(dir, results, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, badgeFileName)); err == nil {
+		t.Error("badge written without --badge")
+	}
+
+	writeBadge = true
+	if err := printSessionSummary(dir, results, nil); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, badgeFileName))
+	if err != nil {
+		t.Fatalf("badge not written: %v", err)
+	}
+	if !strings.Contains(string(data), "fastest: groq 812 tok/s") {
+		t.Errorf("badge does not name the fastest provider:\n%s", data)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// Think-time distributions.
+const (
+	thinkFixed       = "fixed"
+	thinkUniform     = "uniform"
+	thinkExponential = "exponential"
+	// thinkExponentialCap bounds exponential pauses at this many times the mean
+	// so one unlucky draw cannot idle a worker for the rest of a run.
+	thinkExponentialCap = 10
+)
+
+// thinkTime is the pause a worker takes between receiving a response and
+// sending its next request, like a user reading a reply before typing. It is
+// written as "fixed:15s", "uniform:5s-20s" or "exponential:10s" (the mean).
+type thinkTime struct {
+	Kind string
+	// Min is the fixed pause, the uniform lower bound, or the exponential mean.
+	Min time.Duration
+	// Max is the uniform upper bound.
+	Max time.Duration
+}
+
+// sessionThinkTime is set by --think-time; the zero value keeps each mode's
+// default pacing.
+var sessionThinkTime thinkTime
+
+// parseThinkTime parses a think-time spec.
+func parseThinkTime(spec string) (thinkTime, error) {
+	kind, value, found := strings.Cut(strings.TrimSpace(spec), ":")
+	if !found {
+		return thinkTime{}, fmt.Errorf("think time %q must look like fixed:15s, uniform:5s-20s or exponential:10s", spec)
+	}
+	t := thinkTime{Kind: strings.ToLower(kind)}
+	var err error
+	switch t.Kind {
+	case thinkFixed, thinkExponential, "exp":
+		if t.Kind == "exp" {
+			t.Kind = thinkExponential
+		}
+		if t.Min, err = time.ParseDuration(value); err != nil {
+			return thinkTime{}, fmt.Errorf("think time %q: %w", spec, err)
+		}
+		if t.Kind == thinkExponential && t.Min <= 0 {
+			return thinkTime{}, fmt.Errorf("think time %q: the mean must be positive", spec)
+		}
+	case thinkUniform:
+		lo, hi, ok := strings.Cut(value, "-")
+		if !ok {
+			return thinkTime{}, fmt.Errorf("think time %q: uniform needs a range such as 5s-20s", spec)
+		}
+		if t.Min, err = time.ParseDuration(lo); err != nil {
+			return thinkTime{}, fmt.Errorf("think time %q: %w", spec, err)
+		}
+		if t.Max, err = time.ParseDuration(hi); err != nil {
+			return thinkTime{}, fmt.Errorf("think time %q: %w", spec, err)
+		}
+		if t.Max < t.Min {
+			return thinkTime{}, fmt.Errorf("think time %q: the range is reversed", spec)
+		}
+	default:
+		return thinkTime{}, fmt.Errorf("think time %q: unknown distribution %q (use %s, %s or %s)",
+			spec, kind, thinkFixed, thinkUniform, thinkExponential)
+	}
+	if t.Min < 0 {
+		return thinkTime{}, fmt.Errorf("think time %q must not be negative", spec)
+	}
+	return t, nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler for the config file.
+func (t *thinkTime) UnmarshalText(text []byte) error {
+	parsed, err := parseThinkTime(string(text))
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// enabled reports whether a distribution was configured.
+func (t thinkTime) enabled() bool {
+	return t.Kind != ""
+}
+
+// String renders the spec in the form parseThinkTime accepts.
+func (t thinkTime) String() string {
+	switch t.Kind {
+	case "":
+		return "none"
+	case thinkUniform:
+		return fmt.Sprintf("%s:%s-%s", t.Kind, t.Min, t.Max)
+	default:
+		return fmt.Sprintf("%s:%s", t.Kind, t.Min)
+	}
+}
+
+// sample draws one pause. Each worker passes its own rng so workers think
+// independently of each other.
+func (t thinkTime) sample(rng *rand.Rand) time.Duration {
+	switch t.Kind {
+	case thinkUniform:
+		return t.Min + time.Duration(rng.Int64N(int64(t.Max-t.Min)+1))
+	case thinkExponential:
+		return min(time.Duration(rng.ExpFloat64()*float64(t.Min)), thinkExponentialCap*t.Min)
+	default:
+		return t.Min
+	}
+}
+
+// newWorkerRand returns an independent random source for one worker.
+func newWorkerRand(worker int) *rand.Rand {
+	return rand.New(rand.NewPCG(rand.Uint64(), uint64(worker))) // #nosec G404,G115 -- load shaping, not security
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func TestParseThinkTime(t *testing.T) {
+	for spec, want := range map[string]thinkTime{
+		"fixed:15s":       {Kind: thinkFixed, Min: 15 * time.Second},
+		"uniform:5s-20s":  {Kind: thinkUniform, Min: 5 * time.Second, Max: 20 * time.Second},
+		"exponential:10s": {Kind: thinkExponential, Min: 10 * time.Second},
+		"exp:500ms":       {Kind: thinkExponential, Min: 500 * time.Millisecond},
+	} {
+		got, err := parseThinkTime(spec)
+		if err != nil || got != want {
+			t.Errorf("parseThinkTime(%q) = %+v, %v; want %+v", spec, got, err, want)
+		}
+		if again, err := parseThinkTime(got.String()); err != nil || again != got {
+			t.Errorf("%q does not round-trip through String: %q", spec, got.String())
+		}
+	}
+	for _, bad := range []string{"15s", "fixed:soon", "uniform:20s-5s", "uniform:5s", "exponential:0s", "normal:5s", "fixed:-1s"} {
+		if _, err := parseThinkTime(bad); err == nil {
+			t.Errorf("parseThinkTime(%q) accepted", bad)
+		}
+	}
+}
+
+func TestThinkTimeSample(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	if got := (thinkTime{Kind: thinkFixed, Min: time.Second}).sample(rng); got != time.Second {
+		t.Errorf("fixed sample = %s", got)
+	}
+
+	uniform := thinkTime{Kind: thinkUniform, Min: time.Second, Max: 2 * time.Second}
+	exponential := thinkTime{Kind: thinkExponential, Min: time.Second}
+	var expSum time.Duration
+	const n = 5000
+	for range n {
+		if d := uniform.sample(rng); d < time.Second || d > 2*time.Second {
+			t.Fatalf("uniform sample %s out of range", d)
+		}
+		d := exponential.sample(rng)
+		if d < 0 || d > thinkExponentialCap*time.Second {
+			t.Fatalf("exponential sample %s out of range", d)
+		}
+		expSum += d
+	}
+	if mean := expSum / n; mean < 900*time.Millisecond || mean > 1100*time.Millisecond {
+		t.Errorf("exponential mean = %s, want about 1s", mean)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Markers put in front of a graded table cell.
+const (
+	gradeGood = "🟢"
+	gradeWarn = "🟡"
+	gradeBad  = "🔴"
+)
+
+// sessionThresholds are the config file's [[threshold]] entries, at most one
+// per metric; set from --config.
+var sessionThresholds []metricThreshold
+
+// thresholdMetrics are the built-in table metrics a threshold can grade, in
+// the units of columnFields (durations in seconds). Custom column names can be
+// graded too.
+var thresholdMetrics = []string{"e2e", "projected_e2e", "throughput", "tokens", "ttft"}
+
+// metricThreshold grades a metric as good, warn or bad, e.g. TTFT good up to
+// 0.5s and bad from 2s. When good is below bad lower values are better, as for
+// latencies; otherwise higher values are, as for throughput. Values between
+// the two are a warning.
+type metricThreshold struct {
+	Metric string  `toml:"metric"`
+	Good   float64 `toml:"good"`
+	Bad    float64 `toml:"bad"`
+}
+
+// validate checks the threshold against the built-in metrics and the custom
+// columns of the same config.
+func (t metricThreshold) validate(columns []customColumn) error {
+	known := false
+	for _, m := range thresholdMetrics {
+		known = known || m == t.Metric
+	}
+	for _, c := range columns {
+		known = known || c.Name == t.Metric
+	}
+	if !known {
+		return fmt.Errorf("unknown metric %q (use %s, or a [[column]] name)", t.Metric, strings.Join(thresholdMetrics, ", "))
+	}
+	if t.Good == t.Bad {
+		return fmt.Errorf("metric %s: good and bad must differ", t.Metric)
+	}
+	return nil
+}
+
+// grade returns the marker for v.
+func (t metricThreshold) grade(v float64) string {
+	lowerIsBetter := t.Good < t.Bad
+	switch {
+	case lowerIsBetter && v <= t.Good, !lowerIsBetter && v >= t.Good:
+		return gradeGood
+	case lowerIsBetter && v >= t.Bad, !lowerIsBetter && v <= t.Bad:
+		return gradeBad
+	}
+	return gradeWarn
+}
+
+// gradeCell prefixes a table cell showing v with its grade, when the metric has
+// a threshold and v is defined.
+func gradeCell(metric string, v float64, text string) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return text
+	}
+	for _, t := range sessionThresholds {
+		if t.Metric == metric {
+			return t.grade(v) + " " + text
+		}
+	}
+	return text
+}
+
+// writeThresholdLegend explains the markers below a graded table.
+func writeThresholdLegend(report *strings.Builder) {
+	if len(sessionThresholds) == 0 {
+		return
+	}
+	targets := make([]string, len(sessionThresholds))
+	for i, t := range sessionThresholds {
+		goodOp, badOp := "≤", "≥"
+		if t.Good > t.Bad {
+			goodOp, badOp = "≥", "≤"
+		}
+		targets[i] = fmt.Sprintf("%s %s %g / %s %g", t.Metric, goodOp, t.Good, badOp, t.Bad)
+	}
+	fmt.Fprintf(report, "%s meets target, %s warning, %s misses target (good / bad, durations in seconds: %s)\n\n",
+		gradeGood, gradeWarn, gradeBad, strings.Join(targets, "; "))
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	mdreport "github.com/lamim/llm-api-speed/pkg/report"
+)
+
+func TestMetricThresholdGrade(t *testing.T) {
+	ttft := metricThreshold{Metric: "ttft", Good: 0.5, Bad: 2}
+	throughput := metricThreshold{Metric: "throughput", Good: 100, Bad: 30}
+	cases := []struct {
+		threshold metricThreshold
+		value     float64
+		want      string
+	}{
+		{ttft, 0.2, gradeGood},
+		{ttft, 0.5, gradeGood},
+		{ttft, 1, gradeWarn},
+		{ttft, 2, gradeBad},
+		{throughput, 150, gradeGood},
+		{throughput, 50, gradeWarn},
+		{throughput, 10, gradeBad},
+	}
+	for _, tc := range cases {
+		if got := tc.threshold.grade(tc.value); got != tc.want {
+			t.Errorf("%s %v = %s, want %s", tc.threshold.Metric, tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestLoadConfigFileThresholds(t *testing.T) {
+	path := writeConfig(t, `
+[[column]]
+name = "tokens_per_dollar"
+expr = "tokens / cost"
+
+[[threshold]]
+metric = "ttft"
+good = 0.5
+bad = 2
+
+[[threshold]]
+metric = "tokens_per_dollar"
+good = 100000
+bad = 10000
+`)
+	cfg, err := loadConfigFile(path)
+	if err != nil {
+		t.Fatalf("loadConfigFile failed: %v", err)
+	}
+	if len(cfg.Thresholds) != 2 || cfg.Thresholds[1].Metric != "tokens_per_dollar" {
+		t.Fatalf("unexpected thresholds: %+v", cfg.Thresholds)
+	}
+
+	for body, want := range map[string]string{
+		"[[threshold]]\nmetric = \"cost\"\ngood = 1\nbad = 2\n":                                                      "unknown metric",
+		"[[threshold]]\nmetric = \"ttft\"\ngood = 1\nbad = 1\n":                                                      "must differ",
+		"[[threshold]]\nmetric = \"ttft\"\ngood = 1\nbad = 2\n[[threshold]]\nmetric = \"ttft\"\ngood = 1\nbad = 3\n": "more than one",
+	} {
+		if _, err := loadConfigFile(writeConfig(t, body)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("config %q: error %v, want %q", body, err, want)
+		}
+	}
+}
+
+func TestGradedReportCells(t *testing.T) {
+	saved := sessionThresholds
+	defer func() { sessionThresholds = saved }()
+	sessionThresholds = []metricThreshold{
+		{Metric: "ttft", Good: 0.5, Bad: 2},
+		{Metric: "throughput", Good: 100, Bad: 30},
+	}
+
+	var report strings.Builder
+	if err := mdreport.WriteSession(&report, mdreport.Document{Grade: gradeCell}, []mdreport.Entry{testResultEntry(TestResult{
+		Provider: "nim", Model: "m", Mode: "streaming", Success: true,
+		TTFT: 3 * time.Second, E2ELatency: 5 * time.Second, Throughput: 120, CompletionTokens: 10,
+	})}); err != nil {
+		t.Fatal(err)
+	}
+	// The results table row is the first one naming the provider
+	_, row, _ := strings.Cut(report.String(), "| nim |")
+	row, _, _ = strings.Cut(row, "\n")
+	if !strings.Contains(row, gradeBad+" 3.000s") || !strings.Contains(row, gradeGood+" 120.00 tok/s") {
+		t.Errorf("expected graded TTFT and throughput cells, got %q", row)
+	}
+	if strings.Contains(row, gradeGood+" 5.000s") || strings.Count(row, "🟢")+strings.Count(row, "🔴") != 2 {
+		t.Errorf("ungraded metrics should be left alone, got %q", row)
+	}
+
+	report.Reset()
+	writeThresholdLegend(&report)
+	if !strings.Contains(report.String(), "ttft ≤ 0.5 / ≥ 2; throughput ≥ 100 / ≤ 30") {
+		t.Errorf("unexpected legend: %q", report.String())
+	}
+
+	sessionThresholds = nil
+	report.Reset()
+	writeThresholdLegend(&report)
+	if report.Len() != 0 {
+		t.Errorf("no thresholds should give no legend, got %q", report.String())
+	}
+}
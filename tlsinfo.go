@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tlsProbeTimeout bounds the handshake made to record a provider's TLS setup.
+const tlsProbeTimeout = 10 * time.Second
+
+// TLSInfo is what a provider's endpoint negotiated in a fresh TLS handshake.
+type TLSInfo struct {
+	Version     string `json:"version,omitempty"`
+	CipherSuite string `json:"cipherSuite,omitempty"`
+	// ALPN is the application protocol agreed on, e.g. "h2" or "http/1.1".
+	ALPN string `json:"alpn,omitempty"`
+	// CertChainLength counts the certificates the server sent, leaf included;
+	// long chains add bytes and verification work to every new connection.
+	CertChainLength int           `json:"certChainLength,omitempty"`
+	Issuer          string        `json:"issuer,omitempty"`
+	NotAfter        time.Time     `json:"notAfter,omitzero"`
+	Handshake       time.Duration `json:"handshakeMs,omitempty"`
+	// HTTP3 is the endpoint's Alt-Svc advertisement of HTTP/3, e.g.
+	// `h3=":443"; ma=86400`, empty when it offers none.
+	HTTP3 string `json:"http3,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// probeTLS connects to config's endpoint the way requests do (honoring
+// --per-ip, --ip-version and --http3) and records the negotiated parameters.
+// It returns nil for plain-HTTP endpoints. roots overrides the system CA pool
+// when set.
+func probeTLS(ctx context.Context, config ProviderConfig, roots *x509.CertPool) *TLSInfo {
+	u, err := url.Parse(config.BaseURL)
+	if err != nil || u.Scheme != "https" {
+		return nil
+	}
+	host, port := u.Hostname(), u.Port()
+	if port == "" {
+		port = "443"
+	}
+	ctx, cancel := context.WithTimeout(ctx, tlsProbeTimeout)
+	defer cancel()
+	if config.HTTPVersion == httpVersion3 {
+		return probeQUIC(ctx, config, host, port, roots)
+	}
+	addr := host
+	if config.DialIP != "" {
+		addr = config.DialIP
+	}
+	tlsConfig := &tls.Config{
+		ServerName: host,
+		NextProtos: []string{"h2", "http/1.1"},
+		RootCAs:    roots,
+		MinVersion: tls.VersionTLS12,
+	}
+	dialer := &tls.Dialer{Config: tlsConfig}
+	network, target := ipVersionNetwork(config.IPVersion), net.JoinHostPort(addr, port)
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, network, target)
+	if err != nil {
+		return &TLSInfo{Error: err.Error()}
+	}
+	defer conn.Close()
+	info := &TLSInfo{Handshake: time.Since(start)}
+	info.describe(conn.(*tls.Conn).ConnectionState())
+	info.HTTP3 = probeHTTP3(ctx, config, network, target, tlsConfig)
+	return info
+}
+
+// describe records what a completed handshake negotiated.
+func (info *TLSInfo) describe(state tls.ConnectionState) {
+	info.Version = tls.VersionName(state.Version)
+	info.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+	info.ALPN = state.NegotiatedProtocol
+	info.CertChainLength = len(state.PeerCertificates)
+	if len(state.PeerCertificates) > 0 {
+		leaf := state.PeerCertificates[0]
+		info.Issuer = leaf.Issuer.CommonName
+		info.NotAfter = leaf.NotAfter
+	}
+}
+
+// probeHTTP3 sends a HEAD request to config's base URL over TCP and returns
+// the HTTP/3 entries of its Alt-Svc header, showing which endpoints offer it;
+// requests only use HTTP/3 under --http3.
+func probeHTTP3(ctx context.Context, config ProviderConfig, network, target string, tlsConfig *tls.Config) string {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, target)
+		},
+		TLSClientConfig:   tlsConfig.Clone(),
+		ForceAttemptHTTP2: true,
+	}
+	defer transport.CloseIdleConnections()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, config.BaseURL, http.NoBody)
+	if err != nil {
+		return ""
+	}
+	if userAgent := config.userAgent(); userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return ""
+	}
+	_ = resp.Body.Close()
+	return altSvcHTTP3(resp.Header.Values("Alt-Svc"))
+}
+
+// altSvcHTTP3 keeps the HTTP/3 alternatives ("h3" and drafts such as
+// "h3-29") of Alt-Svc header values.
+func altSvcHTTP3(values []string) string {
+	var h3 []string
+	for _, value := range values {
+		for _, entry := range strings.Split(value, ",") {
+			entry = strings.TrimSpace(entry)
+			protocol, _, _ := strings.Cut(entry, "=")
+			if protocol == "h3" || strings.HasPrefix(protocol, "h3-") {
+				h3 = append(h3, entry)
+			}
+		}
+	}
+	return strings.Join(h3, ", ")
+}
+
+// recordTLS probes every provider concurrently and stores the results in the
+// manifest, whose providers are in the same order.
+func recordTLS(manifest *SessionManifest, providers []ProviderConfig) {
+	var wg sync.WaitGroup
+	for i, p := range providers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			info := probeTLS(context.Background(), p, nil)
+			if info == nil {
+				return
+			}
+			manifest.Providers[i].TLS = info
+			if info.Error != "" {
+				log.Printf("Warning: TLS probe of %s failed: %s", providerLabel(p.Name, p.Env), info.Error)
+				return
+			}
+			sessionHandshakes.Store(providerLabel(p.Name, p.Env), info.Handshake)
+			log.Printf("%s TLS: %s, %s, ALPN %q, %d certificate(s), handshake %s", providerLabel(p.Name, p.Env),
+				info.Version, info.CipherSuite, info.ALPN, info.CertChainLength, formatDuration(info.Handshake))
+			if info.HTTP3 != "" {
+				log.Printf("%s advertises HTTP/3: %s", providerLabel(p.Name, p.Env), info.HTTP3)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProbeTLS(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Alt-Svc", `h3=":443"; ma=86400, h2=":443"`)
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+
+	// The test certificate is issued for example.com and 127.0.0.1
+	config := ProviderConfig{Name: "tls", BaseURL: strings.Replace(srv.URL, "127.0.0.1", "example.com", 1), DialIP: "127.0.0.1"}
+	info := probeTLS(context.Background(), config, roots)
+	if info == nil || info.Error != "" {
+		t.Fatalf("probe failed: %+v", info)
+	}
+	if info.Version != "TLS 1.3" || info.ALPN != "h2" || info.CertChainLength != 1 || info.CipherSuite == "" || info.NotAfter.IsZero() ||
+		info.HTTP3 != `h3=":443"; ma=86400` {
+		t.Errorf("unexpected TLS info: %+v", info)
+	}
+
+	// Untrusted certificates are reported rather than ignored
+	if info := probeTLS(context.Background(), config, nil); info == nil || info.Error == "" {
+		t.Errorf("expected a verification error, got %+v", info)
+	}
+	if info := probeTLS(context.Background(), ProviderConfig{BaseURL: "http://localhost:8000/v1"}, nil); info != nil {
+		t.Errorf("plain HTTP endpoint probed: %+v", info)
+	}
+}
+
+func TestAltSvcHTTP3(t *testing.T) {
+	got := altSvcHTTP3([]string{`h2=":443"; ma=60`, `h3-29=":8443", h3=":443"; ma=86400`})
+	if want := `h3-29=":8443", h3=":443"; ma=86400`; got != want {
+		t.Errorf("altSvcHTTP3 = %q, want %q", got, want)
+	}
+	if got := altSvcHTTP3([]string{"clear"}); got != "" {
+		t.Errorf("expected no HTTP/3 in %q", got)
+	}
+}
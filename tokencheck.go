@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+const (
+	// primaryEncoding is the tokenizer every throughput figure is counted with.
+	primaryEncoding = "cl100k_base"
+	// defaultCrossCheckThreshold is the throughput change, in percent, above
+	// which a model is flagged as sensitive to the tokenizer choice.
+	defaultCrossCheckThreshold = 10.0
+)
+
+// Token counting cross-check, enabled with --tokenizer-cross-check. The same
+// responses are re-counted with a second encoding; models whose throughput
+// moves by more than crossCheckThreshold percent depend on the tokenizer
+// choice, so their tok/s is only comparable when counted with the right one.
+var (
+	crossCheckEncoding  string
+	crossCheckEncoder   *tiktoken.Tiktoken
+	crossCheckThreshold = defaultCrossCheckThreshold
+)
+
+// TokenCrossCheck is a result's completion recounted with a second encoding.
+type TokenCrossCheck struct {
+	Encoding         string  `json:"encoding"`
+	CompletionTokens int     `json:"completionTokens"`
+	Throughput       float64 `json:"throughputTokensPerSec"`
+	// DeltaPercent is how far the second encoding moves throughput relative
+	// to the primary one; positive when it counts more tokens.
+	DeltaPercent float64 `json:"deltaPercent"`
+}
+
+// setupTokenCrossCheck loads the cross-check encoding, if one was requested.
+func setupTokenCrossCheck(encoding string, threshold float64) error {
+	if encoding == "" {
+		return nil
+	}
+	if encoding == primaryEncoding {
+		return fmt.Errorf("%s is already the primary encoding", encoding)
+	}
+	if threshold <= 0 {
+		return fmt.Errorf("threshold must be positive, got %g", threshold)
+	}
+	tke, err := tiktoken.GetEncoding(encoding)
+	if err != nil {
+		return fmt.Errorf("error loading encoding %s: %w", encoding, err)
+	}
+	crossCheckEncoding = encoding
+	crossCheckEncoder = tke
+	crossCheckThreshold = threshold
+	return nil
+}
+
+// crossCheckTokens recounts responses with the cross-check encoding and scales
+// the averaged token count and throughput by the ratio between the two
+// encodings, since both describe the same text over the same time. It returns
+// nil when the cross-check is off or there is nothing to count.
+func crossCheckTokens(tke *tiktoken.Tiktoken, responses []string, avgTokens int, avgThroughput float64) *TokenCrossCheck {
+	if crossCheckEncoder == nil {
+		return nil
+	}
+	var primary, secondary int
+	for _, response := range responses {
+		primary += len(tke.Encode(response, nil, nil))
+		secondary += len(crossCheckEncoder.Encode(response, nil, nil))
+	}
+	if primary == 0 {
+		return nil
+	}
+	ratio := float64(secondary) / float64(primary)
+	return &TokenCrossCheck{
+		Encoding:         crossCheckEncoding,
+		CompletionTokens: int(math.Round(float64(avgTokens) * ratio)),
+		Throughput:       avgThroughput * ratio,
+		DeltaPercent:     (ratio - 1) * 100,
+	}
+}
+
+// tokenizerSensitive reports whether the encoding choice moves c's throughput
+// by more than the threshold.
+func (c *TokenCrossCheck) tokenizerSensitive(threshold float64) bool {
+	return math.Abs(c.DeltaPercent) > threshold
+}
+
+// writeTokenCrossCheckSection compares throughput under both encodings.
+func writeTokenCrossCheckSection(report *strings.Builder, results []TestResult) {
+	rows := make([]TestResult, 0)
+	for _, r := range results {
+		if r.Success && r.TokenCrossCheck != nil {
+			rows = append(rows, r)
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	report.WriteString("## Tokenizer Cross-Check\n\n")
+	fmt.Fprintf(report, "Completions recounted with a second encoding. Models flagged ⚠ change throughput by more than %.0f%% "+
+		"depending on the tokenizer, so their tok/s only compares fairly once counted with the encoding the model actually uses.\n\n", crossCheckThreshold)
+	report.WriteString("| Provider | Model | Mode | Encoding | Tokens | Throughput | Delta |\n")
+	report.WriteString("|----------|-------|------|----------|--------|------------|-------|\n")
+	for _, r := range rows {
+		c := r.TokenCrossCheck
+		fmt.Fprintf(report, "| %s | %s | %s | %s | %d | %.2f tok/s | |\n",
+			providerLabel(r.Provider, r.Env), r.Model, r.Mode, primaryEncoding, r.CompletionTokens, r.Throughput)
+		flag := ""
+		if c.tokenizerSensitive(crossCheckThreshold) {
+			flag = " ⚠"
+		}
+		fmt.Fprintf(report, "| | | | %s | %d | %.2f tok/s | %+.1f%%%s |\n",
+			c.Encoding, c.CompletionTokens, c.Throughput, c.DeltaPercent, flag)
+	}
+	report.WriteString("\n")
+}
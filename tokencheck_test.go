@@ -0,0 +1,61 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestCrossCheckTokensScalesByEncodingRatio(t *testing.T) {
+	tke := testTokenizer(t)
+	if got := crossCheckTokens(tke, []string{"hello"}, 5, 50); got != nil {
+		t.Fatalf("expected nil with the cross-check off, got %+v", got)
+	}
+
+	t.Cleanup(func() {
+		crossCheckEncoding, crossCheckEncoder, crossCheckThreshold = "", nil, defaultCrossCheckThreshold
+	})
+	if err := setupTokenCrossCheck(primaryEncoding, 10); err == nil {
+		t.Fatal("expected the primary encoding to be rejected")
+	}
+	if err := setupTokenCrossCheck("o200k_base", 0); err == nil {
+		t.Fatal("expected a non-positive threshold to be rejected")
+	}
+	// The test loader counts one token per byte under every encoding.
+	if err := setupTokenCrossCheck("o200k_base", 5); err != nil {
+		t.Fatalf("setupTokenCrossCheck failed: %v", err)
+	}
+	got := crossCheckTokens(tke, []string{"hello", "world"}, 5, 50)
+	if got == nil || got.Encoding != "o200k_base" || got.CompletionTokens != 5 || got.Throughput != 50 || got.DeltaPercent != 0 {
+		t.Fatalf("expected identical counts under byte-level encodings, got %+v", got)
+	}
+	if got := crossCheckTokens(tke, []string{""}, 0, 0); got != nil {
+		t.Fatalf("expected nil for empty responses, got %+v", got)
+	}
+}
+
+func TestTokenCrossCheckSectionFlagsSensitiveModels(t *testing.T) {
+	var report strings.Builder
+	writeTokenCrossCheckSection(&report, []TestResult{{Provider: "nim", Success: true}})
+	if report.Len() != 0 {
+		t.Fatalf("expected no section without cross-checks, got %q", report.String())
+	}
+
+	results := []TestResult{
+		{Provider: "nim", Model: "a", Mode: "streaming", Success: true, CompletionTokens: 100, Throughput: 80,
+			TokenCrossCheck: &TokenCrossCheck{Encoding: "o200k_base", CompletionTokens: 97, Throughput: 77.6, DeltaPercent: -3}},
+		{Provider: "novita", Model: "b", Mode: "streaming", Success: true, CompletionTokens: 100, Throughput: 60,
+			TokenCrossCheck: &TokenCrossCheck{Encoding: "o200k_base", CompletionTokens: 80, Throughput: 48, DeltaPercent: -20}},
+	}
+	writeTokenCrossCheckSection(&report, results)
+	out := report.String()
+	if !strings.Contains(out, "## Tokenizer Cross-Check") || !strings.Contains(out, "| -20.0% ⚠ |") {
+		t.Fatalf("expected the sensitive model to be flagged, got:\n%s", out)
+	}
+	if !strings.Contains(out, "| -3.0% |") {
+		t.Fatalf("expected the small delta to be unflagged, got:\n%s", out)
+	}
+	if c := results[1].TokenCrossCheck; !c.tokenizerSensitive(19.5) || c.tokenizerSensitive(math.Abs(c.DeltaPercent)) {
+		t.Fatal("expected the threshold to be exclusive")
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1" // #nosec G505 -- matches tiktoken-go's cache key scheme, not used for security
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// tokenizerEncodingURLs lists the encoding files bundled by "tokenizer-bundle".
+var tokenizerEncodingURLs = []string{
+	"https://openaipublic.blob.core.windows.net/encodings/cl100k_base.tiktoken",
+	"https://openaipublic.blob.core.windows.net/encodings/o200k_base.tiktoken",
+}
+
+// bundleBpeLoader loads tiktoken encodings from a local bundle directory first,
+// then tiktoken-go's own cache, and only downloads them when not running offline.
+type bundleBpeLoader struct {
+	dir     string
+	offline bool
+}
+
+// LoadTiktokenBpe implements tiktoken.BpeLoader.
+func (l *bundleBpeLoader) LoadTiktokenBpe(tiktokenBpeFile string) (map[string]int, error) {
+	name := path.Base(tiktokenBpeFile)
+	if l.dir != "" {
+		bundled := filepath.Join(l.dir, name)
+		if _, err := os.Stat(bundled); err == nil {
+			return parseTiktokenBpeFile(bundled)
+		}
+	}
+
+	cached := tiktokenCachePath(tiktokenBpeFile)
+	if _, err := os.Stat(cached); err == nil {
+		return parseTiktokenBpeFile(cached)
+	}
+
+	if l.offline {
+		return nil, fmt.Errorf("offline mode: encoding %s not found in tokenizer dir %q or cache %q "+
+			"(run 'llm-api-speed tokenizer-bundle <dir>' on a connected machine and pass --tokenizer-dir)",
+			name, l.dir, filepath.Dir(cached))
+	}
+
+	ranks, err := tiktoken.NewDefaultBpeLoader().LoadTiktokenBpe(tiktokenBpeFile)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading encoding %s: %w", name, err)
+	}
+	return ranks, nil
+}
+
+// tiktokenCachePath mirrors the cache location tiktoken-go uses for downloaded files.
+func tiktokenCachePath(blobpath string) string {
+	cacheDir := strings.TrimSpace(os.Getenv("TIKTOKEN_CACHE_DIR"))
+	if cacheDir == "" {
+		cacheDir = strings.TrimSpace(os.Getenv("DATA_GYM_CACHE_DIR"))
+	}
+	if cacheDir == "" {
+		cacheDir = filepath.Join(os.TempDir(), "data-gym-cache")
+	}
+	return filepath.Join(cacheDir, fmt.Sprintf("%x", sha1.Sum([]byte(blobpath)))) // #nosec G401
+}
+
+// parseTiktokenBpeFile parses a .tiktoken file of "base64-token rank" lines.
+func parseTiktokenBpeFile(filename string) (map[string]int, error) {
+	contents, err := os.ReadFile(filepath.Clean(filename))
+	if err != nil {
+		return nil, fmt.Errorf("error reading encoding file: %w", err)
+	}
+	ranks := make(map[string]int)
+	for _, line := range strings.Split(string(contents), "\n") {
+		if line == "" {
+			continue
+		}
+		parts := strings.Split(line, " ")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("malformed line in %s: %q", filename, line)
+		}
+		token, err := base64.StdEncoding.DecodeString(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("malformed token in %s: %w", filename, err)
+		}
+		rank, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("malformed rank in %s: %w", filename, err)
+		}
+		ranks[string(token)] = rank
+	}
+	return ranks, nil
+}
+
+// runTokenizerBundle implements "tokenizer-bundle <dir>": it downloads the encoding
+// files into dir so they can be copied to hosts that run with --offline.
+func runTokenizerBundle(args []string) {
+	if len(args) < 1 {
+		log.Fatal("Usage: llm-api-speed tokenizer-bundle <dir>")
+	}
+	dir := args[0]
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		log.Fatalf("Error creating tokenizer bundle directory: %v", err)
+	}
+
+	for _, url := range tokenizerEncodingURLs {
+		filename := filepath.Join(dir, path.Base(url))
+		if err := downloadFile(url, filename); err != nil {
+			log.Fatalf("Error downloading %s: %v", url, err)
+		}
+		log.Printf("Saved %s", filename)
+	}
+	log.Printf("Tokenizer bundle ready. Use it with: --offline --tokenizer-dir %s", dir)
+}
+
+// downloadFile fetches url and writes the body to filename.
+func downloadFile(url, filename string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error fetching file: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			log.Printf("Warning: Failed to close response body: %v", closeErr)
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		return fmt.Errorf("error writing file: %w", err)
+	}
+	return nil
+}
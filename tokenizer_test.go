@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBundleBpeLoaderOffline(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TIKTOKEN_CACHE_DIR", t.TempDir())
+
+	loader := &bundleBpeLoader{dir: dir, offline: true}
+	url := "https://openaipublic.blob.core.windows.net/encodings/cl100k_base.tiktoken"
+
+	if _, err := loader.LoadTiktokenBpe(url); err == nil || !strings.Contains(err.Error(), "offline mode") {
+		t.Fatalf("expected offline mode error for missing bundle, got %v", err)
+	}
+
+	// "aGk=" is base64 for "hi", "eW8=" for "yo"
+	if err := os.WriteFile(filepath.Join(dir, "cl100k_base.tiktoken"), []byte("aGk= 0\neW8= 1\n"), 0600); err != nil {
+		t.Fatalf("failed to write bundle file: %v", err)
+	}
+	ranks, err := loader.LoadTiktokenBpe(url)
+	if err != nil {
+		t.Fatalf("expected bundled encoding to load, got %v", err)
+	}
+	if ranks["hi"] != 0 || ranks["yo"] != 1 || len(ranks) != 2 {
+		t.Fatalf("unexpected ranks: %v", ranks)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// ToolArgsStats is how long tool-call arguments took to stream: from a call's
+// first delta until its arguments parsed as JSON. Agent frameworks cannot act
+// on a call before then, whatever the TTFT.
+type ToolArgsStats struct {
+	Calls int           `json:"calls"`
+	Avg   time.Duration `json:"avgMs"`
+	Max   time.Duration `json:"maxMs"`
+	// Incomplete counts calls whose arguments never parsed by the end of the
+	// stream.
+	Incomplete int `json:"incomplete,omitempty"`
+
+	sum time.Duration
+}
+
+// add records the latency of one call whose arguments completed.
+func (s *ToolArgsStats) add(d time.Duration) {
+	s.Calls++
+	s.sum += d
+	s.Avg = s.sum / time.Duration(s.Calls)
+	s.Max = max(s.Max, d)
+}
+
+// toolCallProgress is one tool call being assembled from stream deltas.
+type toolCallProgress struct {
+	first     time.Time
+	args      strings.Builder
+	completed time.Duration
+	done      bool
+}
+
+// toolArgsTimer follows the tool calls of one stream.
+type toolArgsTimer struct {
+	calls map[string]*toolCallProgress
+	order []string
+}
+
+// toolCallKey identifies the call a delta belongs to: by index when the API
+// sends one, else by ID, else it continues the latest call (Cohere sends the
+// ID only on a call's first delta).
+func (t *toolArgsTimer) toolCallKey(call openai.ToolCall) string {
+	switch {
+	case call.Index != nil:
+		return "index:" + strconv.Itoa(*call.Index)
+	case call.ID != "":
+		return "id:" + call.ID
+	case len(t.order) > 0:
+		return t.order[len(t.order)-1]
+	}
+	return "index:0"
+}
+
+// observe records tool-call deltas that arrived at now.
+func (t *toolArgsTimer) observe(calls []openai.ToolCall, now time.Time) {
+	if t.calls == nil {
+		t.calls = make(map[string]*toolCallProgress)
+	}
+	for _, call := range calls {
+		key := t.toolCallKey(call)
+		p, ok := t.calls[key]
+		if !ok {
+			p = &toolCallProgress{first: now}
+			t.calls[key] = p
+			t.order = append(t.order, key)
+		}
+		p.args.WriteString(call.Function.Arguments)
+		if !p.done && json.Valid([]byte(p.args.String())) {
+			p.done = true
+			p.completed = now.Sub(p.first)
+		}
+	}
+}
+
+// stats summarizes the stream's calls, or returns nil if there were none.
+func (t *toolArgsTimer) stats() *ToolArgsStats {
+	if len(t.order) == 0 {
+		return nil
+	}
+	var s ToolArgsStats
+	for _, key := range t.order {
+		if p := t.calls[key]; p.done {
+			s.add(p.completed)
+		} else {
+			s.Incomplete++
+		}
+	}
+	return &s
+}
+
+// merge adds o's calls to s.
+func (s *ToolArgsStats) merge(o *ToolArgsStats) {
+	if o == nil {
+		return
+	}
+	s.Calls += o.Calls
+	s.sum += o.sum
+	s.Max = max(s.Max, o.Max)
+	s.Incomplete += o.Incomplete
+	if s.Calls > 0 {
+		s.Avg = s.sum / time.Duration(s.Calls)
+	}
+}
+
+// toolArgsRow is one entry of the tool-argument streaming table.
+type toolArgsRow struct {
+	provider string
+	mode     string
+	ttft     time.Duration
+	stats    *ToolArgsStats
+}
+
+// writeToolArgsRows renders tool-argument streaming latency next to TTFT.
+func writeToolArgsRows(report *strings.Builder, rows []toolArgsRow) {
+	if len(rows) == 0 {
+		return
+	}
+	report.WriteString("## Tool Argument Streaming\n\n")
+	report.WriteString("Time from a tool call's first delta until its arguments parse as JSON, the earliest an agent can act on the call. " +
+		"Incomplete calls never produced parseable arguments.\n\n")
+	report.WriteString("| Provider | Mode | TTFT | Tool Calls | Avg Args Time | Max Args Time | Incomplete |\n")
+	report.WriteString("|----------|------|------|------------|---------------|---------------|------------|\n")
+	for _, r := range rows {
+		fmt.Fprintf(report, "| %s | %s | %s | %d | %s | %s | %d |\n",
+			r.provider, r.mode, formatDuration(r.ttft), r.stats.Calls,
+			formatDuration(r.stats.Avg), formatDuration(r.stats.Max), r.stats.Incomplete)
+	}
+	report.WriteString("\n")
+}
+
+// writeToolArgsSection adds the tool-argument table for results that made
+// tool calls.
+func writeToolArgsSection(report *strings.Builder, results []TestResult) {
+	rows := make([]toolArgsRow, 0, len(results))
+	for _, r := range results {
+		if r.ToolArgs != nil {
+			rows = append(rows, toolArgsRow{providerLabel(r.Provider, r.Env), r.Mode, r.TTFT, r.ToolArgs})
+		}
+	}
+	writeToolArgsRows(report, rows)
+}
+
+// writeDiagnosticToolArgsSection is the diagnostic-report counterpart of
+// writeToolArgsSection.
+func writeDiagnosticToolArgsSection(report *strings.Builder, results []DiagnosticSummary) {
+	rows := make([]toolArgsRow, 0, len(results))
+	for _, r := range results {
+		if r.ToolArgs != nil {
+			rows = append(rows, toolArgsRow{providerLabel(r.Provider, r.Env), r.Mode, r.AvgTTFT, r.ToolArgs})
+		}
+	}
+	writeToolArgsRows(report, rows)
+}
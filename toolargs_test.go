@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func toolCallDelta(index *int, id, name, args string) []openai.ToolCall {
+	call := openai.ToolCall{Index: index, ID: id}
+	call.Function.Name = name
+	call.Function.Arguments = args
+	return []openai.ToolCall{call}
+}
+
+func TestToolArgsTimer(t *testing.T) {
+	zero, one := 0, 1
+	start := time.Unix(0, 0)
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+
+	// OpenAI style: indexed deltas, two calls interleaved, the second never closes
+	var timer toolArgsTimer
+	timer.observe(toolCallDelta(&zero, "a", "get_weather", ""), at(0))
+	timer.observe(toolCallDelta(&zero, "", "", `{"city":`), at(10))
+	timer.observe(toolCallDelta(&one, "b", "get_weather", `{"ci`), at(20))
+	timer.observe(toolCallDelta(&zero, "", "", `"Oslo"}`), at(40))
+	stats := timer.stats()
+	if stats == nil || stats.Calls != 1 || stats.Avg != 40*time.Millisecond || stats.Incomplete != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	// Cohere style: the ID only on the first delta of each call
+	var cohere toolArgsTimer
+	cohere.observe(toolCallDelta(nil, "c1", "get_weather", ""), at(0))
+	cohere.observe(toolCallDelta(nil, "", "", `{"city":"Oslo"}`), at(30))
+	cohere.observe(toolCallDelta(nil, "c2", "get_weather", `{}`), at(50))
+	stats = cohere.stats()
+	if stats == nil || stats.Calls != 2 || stats.Max != 30*time.Millisecond || stats.Avg != 15*time.Millisecond {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	var none toolArgsTimer
+	if none.stats() != nil {
+		t.Fatal("expected nil stats without tool calls")
+	}
+}
+
+func TestStreamTrackerMergesToolArgs(t *testing.T) {
+	tracker := newStreamTracker()
+	if tracker.toolArgs("nim") != nil {
+		t.Fatal("expected nil before any tool calls")
+	}
+	var a, b ToolArgsStats
+	a.add(100 * time.Millisecond)
+	b.add(300 * time.Millisecond)
+	b.Incomplete = 1
+	tracker.addToolArgs("nim", &a)
+	tracker.addToolArgs("nim", &b)
+	got := tracker.toolArgs("nim")
+	if got.Calls != 2 || got.Avg != 200*time.Millisecond || got.Max != 300*time.Millisecond || got.Incomplete != 1 {
+		t.Fatalf("unexpected merged stats %+v", got)
+	}
+
+	var report strings.Builder
+	writeToolArgsSection(&report, []TestResult{{Provider: "nim", Mode: "tool-calling", TTFT: time.Second, ToolArgs: got}})
+	if !strings.Contains(report.String(), "## Tool Argument Streaming") || !strings.Contains(report.String(), "| nim | tool-calling |") {
+		t.Fatalf("unexpected report:\n%s", report.String())
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// --tool-choice values besides the name of a benchmark tool, which forces that
+// function.
+const (
+	toolChoiceAuto     = "auto"
+	toolChoiceRequired = "required"
+)
+
+// parseToolChoices splits a comma-separated --tool-choice list. Each entry is
+// auto, required or the name of one of the benchmark's tools.
+func parseToolChoices(list string) ([]string, error) {
+	if list == "" {
+		return nil, nil
+	}
+	tools := make(map[string]bool)
+	for _, tool := range weatherTools() {
+		tools[tool.Function.Name] = true
+	}
+	var choices []string
+	seen := make(map[string]bool)
+	for _, choice := range strings.Split(list, ",") {
+		choice = strings.TrimSpace(choice)
+		if choice != toolChoiceAuto && choice != toolChoiceRequired && !tools[choice] {
+			return nil, fmt.Errorf("unknown tool choice %q (want %s, %s or a tool name such as get_weather)",
+				choice, toolChoiceAuto, toolChoiceRequired)
+		}
+		if seen[choice] {
+			return nil, fmt.Errorf("tool choice %q listed twice", choice)
+		}
+		seen[choice] = true
+		choices = append(choices, choice)
+	}
+	return choices, nil
+}
+
+// toolChoiceEnvTag labels results run with one tool choice, e.g. "prod-tool-auto".
+func toolChoiceEnvTag(env, choice string) string {
+	if env == "" {
+		return "tool-" + choice
+	}
+	return env + "-tool-" + choice
+}
+
+// applyToolChoices sets every provider's tool choice, or for several choices
+// tests each provider once per choice, tagged e.g. tool-auto and tool-required.
+func applyToolChoices(providers []ProviderConfig, choices []string) []ProviderConfig {
+	if len(choices) == 0 {
+		return providers
+	}
+	out := make([]ProviderConfig, 0, len(providers)*len(choices))
+	for _, p := range providers {
+		for _, choice := range choices {
+			forced := p
+			forced.ToolChoice = choice
+			if len(choices) > 1 {
+				forced.Env = toolChoiceEnvTag(p.Env, choice)
+			}
+			out = append(out, forced)
+		}
+	}
+	return out
+}
+
+// toolChoiceParam is the request's tool_choice for choice; empty keeps the
+// benchmark's default of required.
+func toolChoiceParam(choice string) any {
+	switch choice {
+	case "", toolChoiceRequired:
+		return toolChoiceRequired
+	case toolChoiceAuto:
+		return toolChoiceAuto
+	}
+	return openai.ToolChoice{Type: openai.ToolTypeFunction, Function: openai.ToolFunction{Name: choice}}
+}
+
+// toolChoiceMeasurement is one result run with an explicit tool choice.
+type toolChoiceMeasurement struct {
+	provider, env, mode, choice string
+	ttft, e2e                   time.Duration
+	toolArgs                    *ToolArgsStats
+}
+
+// writeToolChoiceRows compares forced tool choices with auto for each
+// provider. Nothing is written unless some provider ran with auto and at least
+// one other choice.
+func writeToolChoiceRows(report *strings.Builder, measurements []toolChoiceMeasurement) {
+	type group struct {
+		label, mode string
+		byChoice    map[string]*toolChoiceMeasurement
+	}
+	groups := make(map[string]*group)
+	for i := range measurements {
+		m := &measurements[i]
+		baseEnv := envWithoutTag(m.env, "tool-"+m.choice)
+		label := providerLabel(m.provider, baseEnv)
+		key := label + "\x00" + m.mode
+		g, ok := groups[key]
+		if !ok {
+			g = &group{label: label, mode: m.mode, byChoice: make(map[string]*toolChoiceMeasurement)}
+			groups[key] = g
+		}
+		g.byChoice[m.choice] = m
+	}
+	keys := make([]string, 0, len(groups))
+	for key, g := range groups {
+		if g.byChoice[toolChoiceAuto] != nil && len(g.byChoice) > 1 {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+	sort.Strings(keys)
+
+	report.WriteString("## Tool Choice: Forced vs Auto\n\n")
+	report.WriteString("The same provider with tool_choice left to the model (auto) and forced (required, or one named function). " +
+		"A positive Δ means forcing the choice is slower.\n\n")
+	report.WriteString("| Provider | Mode | Tool Choice | TTFT | Δ TTFT | E2E | Δ E2E | Avg Args Time |\n")
+	report.WriteString("|----------|------|-------------|------|--------|-----|-------|---------------|\n")
+	for _, key := range keys {
+		g := groups[key]
+		auto := g.byChoice[toolChoiceAuto]
+		choices := make([]string, 0, len(g.byChoice))
+		for choice := range g.byChoice {
+			choices = append(choices, choice)
+		}
+		sort.Slice(choices, func(i, j int) bool {
+			// auto first, as the baseline
+			if (choices[i] == toolChoiceAuto) != (choices[j] == toolChoiceAuto) {
+				return choices[i] == toolChoiceAuto
+			}
+			return choices[i] < choices[j]
+		})
+		for _, choice := range choices {
+			m := g.byChoice[choice]
+			deltaTTFT, deltaE2E := "—", "—"
+			if choice != toolChoiceAuto {
+				deltaTTFT = formatSignedDuration(m.ttft - auto.ttft)
+				deltaE2E = formatSignedDuration(m.e2e - auto.e2e)
+			}
+			args := NotAvailable
+			if m.toolArgs != nil && m.toolArgs.Calls > 0 {
+				args = formatDuration(m.toolArgs.Avg)
+			}
+			fmt.Fprintf(report, "| %s | %s | %s | %s | %s | %s | %s | %s |\n", g.label, g.mode, choice,
+				formatDuration(m.ttft), deltaTTFT, formatDuration(m.e2e), deltaE2E, args)
+		}
+	}
+	report.WriteString("\n")
+}
+
+// writeToolChoiceSection compares successful results run with several tool
+// choices.
+func writeToolChoiceSection(report *strings.Builder, results []TestResult) {
+	measurements := make([]toolChoiceMeasurement, 0, len(results))
+	for _, r := range results {
+		if r.Success && r.ToolChoice != "" {
+			measurements = append(measurements, toolChoiceMeasurement{r.Provider, r.Env, r.Mode, r.ToolChoice, r.TTFT, r.E2ELatency, r.ToolArgs})
+		}
+	}
+	writeToolChoiceRows(report, measurements)
+}
+
+// writeDiagnosticToolChoiceSection is the diagnostic-report counterpart of
+// writeToolChoiceSection.
+func writeDiagnosticToolChoiceSection(report *strings.Builder, results []DiagnosticSummary) {
+	measurements := make([]toolChoiceMeasurement, 0, len(results))
+	for _, r := range results {
+		if r.Successful > 0 && r.ToolChoice != "" {
+			measurements = append(measurements, toolChoiceMeasurement{r.Provider, r.Env, r.Mode, r.ToolChoice, r.AvgTTFT, r.AvgE2ELatency, r.ToolArgs})
+		}
+	}
+	writeToolChoiceRows(report, measurements)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestParseToolChoices(t *testing.T) {
+	choices, err := parseToolChoices("auto, required,get_weather")
+	if err != nil || strings.Join(choices, ",") != "auto,required,get_weather" {
+		t.Fatalf("got %v, %v", choices, err)
+	}
+	for _, bad := range []string{"any", "auto,auto", "get_time"} {
+		if _, err := parseToolChoices(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestApplyToolChoices(t *testing.T) {
+	providers := []ProviderConfig{{Name: "nim", Env: "prod"}}
+	single := applyToolChoices(providers, []string{"auto"})
+	if len(single) != 1 || single[0].ToolChoice != "auto" || single[0].Env != "prod" {
+		t.Fatalf("expected one untagged provider, got %+v", single)
+	}
+	both := applyToolChoices(providers, []string{"auto", "required"})
+	if len(both) != 2 || both[0].Env != "prod-tool-auto" || both[1].Env != "prod-tool-required" || both[1].ToolChoice != "required" {
+		t.Fatalf("expected one tagged copy per choice, got %+v", both)
+	}
+}
+
+func TestToolChoiceParam(t *testing.T) {
+	if toolChoiceParam("") != "required" || toolChoiceParam("auto") != "auto" {
+		t.Fatal("unexpected string tool choices")
+	}
+	named, ok := toolChoiceParam("get_weather").(openai.ToolChoice)
+	if !ok || named.Function.Name != "get_weather" || named.Type != openai.ToolTypeFunction {
+		t.Fatalf("expected a named function choice, got %#v", toolChoiceParam("get_weather"))
+	}
+}
+
+func TestToolChoiceSection(t *testing.T) {
+	var report strings.Builder
+	writeToolChoiceSection(&report, []TestResult{
+		{Provider: "nim", Env: "tool-required", ToolChoice: "required", Mode: "tool-calling", Success: true, TTFT: time.Second},
+	})
+	if report.Len() != 0 {
+		t.Fatalf("expected no section without an auto baseline, got %q", report.String())
+	}
+
+	results := []TestResult{
+		{Provider: "nim", Env: "tool-required", ToolChoice: "required", Mode: "tool-calling", Success: true,
+			TTFT: 1500 * time.Millisecond, E2ELatency: 3 * time.Second},
+		{Provider: "nim", Env: "tool-auto", ToolChoice: "auto", Mode: "tool-calling", Success: true,
+			TTFT: time.Second, E2ELatency: 2 * time.Second},
+	}
+	writeToolChoiceSection(&report, results)
+	out := report.String()
+	autoRow := strings.Index(out, "| nim | tool-calling | auto |")
+	requiredRow := strings.Index(out, "| nim | tool-calling | required |")
+	if autoRow < 0 || requiredRow < autoRow {
+		t.Fatalf("expected auto first, then required, under the base provider label:\n%s", out)
+	}
+	if !strings.Contains(out[requiredRow:], "+0.500s") {
+		t.Fatalf("expected the forced TTFT delta, got:\n%s", out)
+	}
+}
+
+func TestToolChoiceSectionWithPerIP(t *testing.T) {
+	orig := lookupIPAddrs
+	t.Cleanup(func() { lookupIPAddrs = orig })
+	lookupIPAddrs = func(context.Context, string) ([]net.IPAddr, error) {
+		return []net.IPAddr{{IP: net.ParseIP("10.0.0.1")}}, nil
+	}
+
+	// --per-ip expands the tool-choice copies, so its tag follows theirs
+	providers := expandPerIP(context.Background(),
+		applyToolChoices([]ProviderConfig{{Name: "nim", BaseURL: "https://llm.test/v1"}}, []string{"auto", "required"}))
+	var results []TestResult
+	for i, p := range providers {
+		results = append(results, TestResult{Provider: p.Name, Env: p.Env, ToolChoice: p.ToolChoice, Mode: "tool-calling",
+			Success: true, TTFT: time.Duration(i+1) * time.Second})
+	}
+	var report strings.Builder
+	writeToolChoiceSection(&report, results)
+	if out := report.String(); !strings.Contains(out, "| nim [ip-10.0.0.1] | tool-calling | required | 2.000s | +1.000s |") {
+		t.Fatalf("expected the choices paired under the per-IP label:\n%s", out)
+	}
+}
+
+func TestToolChoiceCopiesKeepSeparateToolArgs(t *testing.T) {
+	// Streams one get_weather call whose arguments arrive in two pieces
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i, args := range []string{`{\"location\":`, `\"Paris\"}`} {
+			call := `{"index":0,"function":{"arguments":"` + args + `"}}`
+			if i == 0 {
+				call = `{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"` + args + `"}}`
+			}
+			fmt.Fprintf(w, "data: {\"id\":\"c\",\"object\":\"chat.completion.chunk\",\"choices\":[{\"index\":0,\"delta\":{\"tool_calls\":[%s]}}]}\n\n", call)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+	tke := testTokenizer(t)
+	logger := log.New(io.Discard, "", 0)
+
+	copies := applyToolChoices([]ProviderConfig{{Name: "tool-args-choices", BaseURL: server.URL, APIKey: "k", Model: "m"}},
+		[]string{"auto", "required"})
+	for _, config := range []ProviderConfig{copies[0], copies[0], copies[1]} {
+		if _, _, _, _, _, err := singleToolCallRun(context.Background(), config, tke, logger, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	auto := sessionStreams.toolArgs(providerLabel(copies[0].Name, copies[0].Env))
+	required := sessionStreams.toolArgs(providerLabel(copies[1].Name, copies[1].Env))
+	if auto == nil || required == nil || auto.Calls != 2 || required.Calls != 1 {
+		t.Errorf("tool-argument stats per choice = %+v and %+v, want 2 and 1 calls", auto, required)
+	}
+	if sessionStreams.toolArgs(copies[0].Name) != nil {
+		t.Error("tool-argument stats were recorded under the bare provider name")
+	}
+}
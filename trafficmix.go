@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+)
+
+// Traffic classes a load test can blend.
+const (
+	trafficChat        = "chat"
+	trafficLongForm    = "long_form"
+	trafficToolCalling = "tool_calling"
+)
+
+// trafficClasses lists the classes in report order.
+var trafficClasses = []string{trafficChat, trafficLongForm, trafficToolCalling}
+
+// trafficMix weights request classes, e.g. {chat = 70, long_form = 20,
+// tool_calling = 10}. Weights are relative and need not add up to 100.
+type trafficMix map[string]float64
+
+// validate checks that every class is known and at least one has weight.
+func (m trafficMix) validate() error {
+	total := 0.0
+	for class, weight := range m {
+		if !slices.Contains(trafficClasses, class) {
+			return fmt.Errorf("unknown traffic class %q (use %s)", class, strings.Join(trafficClasses, ", "))
+		}
+		if weight < 0 {
+			return fmt.Errorf("traffic class %q has a negative weight", class)
+		}
+		total += weight
+	}
+	if len(m) > 0 && total == 0 {
+		return fmt.Errorf("traffic mix needs at least one class with a positive weight")
+	}
+	return nil
+}
+
+// share returns class's percentage of the mix.
+func (m trafficMix) share(class string) float64 {
+	total := 0.0
+	for _, weight := range m {
+		total += weight
+	}
+	if total == 0 {
+		return 0
+	}
+	return 100 * m[class] / total
+}
+
+// pick samples a class in proportion to its weight.
+func (m trafficMix) pick(rng *rand.Rand) string {
+	total := 0.0
+	for _, weight := range m {
+		total += weight
+	}
+	target := rng.Float64() * total
+	last := ""
+	for _, class := range trafficClasses {
+		if m[class] <= 0 {
+			continue
+		}
+		target -= m[class]
+		if target < 0 {
+			return class
+		}
+		last = class
+	}
+	return last
+}
+
+// String renders the mix as percentages, e.g. "chat 70%, tool_calling 30%".
+func (m trafficMix) String() string {
+	parts := make([]string, 0, len(m))
+	for _, class := range trafficClasses {
+		if m[class] > 0 {
+			parts = append(parts, fmt.Sprintf("%s %.0f%%", class, m.share(class)))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestTrafficMixValidate(t *testing.T) {
+	if err := (trafficMix{trafficChat: 70, trafficLongForm: 20, trafficToolCalling: 10}).validate(); err != nil {
+		t.Errorf("valid mix rejected: %v", err)
+	}
+	for _, bad := range []trafficMix{
+		{"embeddings": 1},
+		{trafficChat: -1, trafficLongForm: 2},
+		{trafficChat: 0},
+	} {
+		if err := bad.validate(); err == nil {
+			t.Errorf("mix %v accepted", bad)
+		}
+	}
+}
+
+func TestTrafficMixPick(t *testing.T) {
+	mix := trafficMix{trafficChat: 7, trafficToolCalling: 3}
+	if got := mix.String(); got != "chat 70%, tool_calling 30%" {
+		t.Errorf("String() = %q", got)
+	}
+	rng := rand.New(rand.NewPCG(3, 4))
+	counts := make(map[string]int)
+	const n = 10000
+	for range n {
+		counts[mix.pick(rng)]++
+	}
+	if counts[trafficLongForm] != 0 {
+		t.Errorf("unweighted class picked %d times", counts[trafficLongForm])
+	}
+	if share := float64(counts[trafficChat]) / n; share < 0.67 || share > 0.73 {
+		t.Errorf("chat share = %.3f, want about 0.70", share)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// trendWindow is how far back trend charts reach.
+const trendWindow = 30 * 24 * time.Hour
+
+// trendDirName holds the per-provider trend charts under the results root.
+const trendDirName = "trends"
+
+// trendPoint is one measurement of a provider at a point in time.
+type trendPoint struct {
+	at         time.Time
+	ttft       time.Duration
+	throughput float64
+}
+
+// trendSeries is one provider's measurements, split by mode.
+type trendSeries struct {
+	provider, env string
+	byMode        map[string][]trendPoint
+}
+
+// collectTrends gathers every successful result measured since since, one
+// series per provider label. Diagnostic runs contribute their averages.
+func collectTrends(sessions []storedSession, since time.Time) []*trendSeries {
+	series := make(map[string]*trendSeries)
+	add := func(provider, env, mode string, p trendPoint) {
+		if p.at.Before(since) {
+			return
+		}
+		label := providerLabel(provider, env)
+		s, ok := series[label]
+		if !ok {
+			s = &trendSeries{provider: provider, env: env, byMode: make(map[string][]trendPoint)}
+			series[label] = s
+		}
+		s.byMode[mode] = append(s.byMode[mode], p)
+	}
+	for _, stored := range sessions {
+		for _, r := range stored.results {
+			if r.Success {
+				add(r.Provider, r.Env, r.Mode, trendPoint{r.Timestamp, r.TTFT, r.Throughput})
+			}
+		}
+		for _, d := range stored.diagnostics {
+			if d.Successful > 0 {
+				add(d.Provider, d.Env, d.Mode, trendPoint{d.Timestamp, d.AvgTTFT, d.AvgThroughput})
+			}
+		}
+	}
+
+	list := make([]*trendSeries, 0, len(series))
+	for _, s := range series {
+		for _, points := range s.byMode {
+			sort.Slice(points, func(a, b int) bool { return points[a].at.Before(points[b].at) })
+		}
+		list = append(list, s)
+	}
+	sort.Slice(list, func(a, b int) bool {
+		return providerLabel(list[a].provider, list[a].env) < providerLabel(list[b].provider, list[b].env)
+	})
+	return list
+}
+
+// trendColors tells modes apart in a chart.
+var trendColors = []string{"#4c78a8", "#f58518", "#54a24b", "#e45756", "#72b7b2", "#b279a2"}
+
+// Trend chart geometry, in pixels.
+const (
+	trendWidth       = 640
+	trendPanelHeight = 150
+	trendMarginLeft  = 60
+	trendMarginRight = 20
+	trendPanelGap    = 45
+	trendTop         = 40
+)
+
+// renderTrendChart draws a provider's throughput and TTFT over [since, until]
+// as two stacked panels, one line per mode.
+func renderTrendChart(s *trendSeries, since, until time.Time) string {
+	modes := make([]string, 0, len(s.byMode))
+	for mode := range s.byMode {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+
+	height := trendTop + 2*trendPanelHeight + 2*trendPanelGap
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`+"\n", trendWidth, height)
+	fmt.Fprintf(&b, `  <rect width="%d" height="%d" fill="#fff"/>`+"\n", trendWidth, height)
+	fmt.Fprintf(&b, `  <text x="%d" y="18" font-size="14" font-weight="bold">%s — last %d days</text>`+"\n",
+		trendMarginLeft, html.EscapeString(providerLabel(s.provider, s.env)), int(trendWindow.Hours()/24))
+	legendX := trendMarginLeft
+	for i, mode := range modes {
+		color := trendColors[i%len(trendColors)]
+		fmt.Fprintf(&b, `  <rect x="%d" y="26" width="10" height="10" fill="%s"/><text x="%d" y="35">%s</text>`+"\n",
+			legendX, color, legendX+14, html.EscapeString(mode))
+		legendX += 24 + 7*len(mode)
+	}
+
+	top := trendTop + trendPanelGap/2
+	writeTrendPanel(&b, "Throughput (tok/s)", top, modes, s, since, until, func(p trendPoint) float64 { return p.throughput })
+	top += trendPanelHeight + trendPanelGap
+	writeTrendPanel(&b, "TTFT (s)", top, modes, s, since, until, func(p trendPoint) float64 { return p.ttft.Seconds() })
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+// writeTrendPanel draws one metric with a zero-based y axis and dated x axis.
+func writeTrendPanel(b *strings.Builder, title string, top int, modes []string, s *trendSeries, since, until time.Time, value func(trendPoint) float64) {
+	left, right := trendMarginLeft, trendWidth-trendMarginRight
+	bottom := top + trendPanelHeight
+	maxValue := 0.0
+	for _, points := range s.byMode {
+		for _, p := range points {
+			maxValue = max(maxValue, value(p))
+		}
+	}
+	if maxValue == 0 {
+		maxValue = 1
+	}
+	maxValue *= 1.1
+	span := until.Sub(since).Seconds()
+	x := func(t time.Time) float64 {
+		return float64(left) + float64(right-left)*t.Sub(since).Seconds()/span
+	}
+	y := func(v float64) float64 {
+		return float64(bottom) - float64(trendPanelHeight)*v/maxValue
+	}
+
+	fmt.Fprintf(b, `  <text x="%d" y="%d" font-weight="bold">%s</text>`+"\n", left, top-6, title)
+	for i := 0; i <= 2; i++ {
+		v := maxValue * float64(i) / 2
+		fmt.Fprintf(b, `  <line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#ddd"/>`+"\n", left, y(v), right, y(v))
+		fmt.Fprintf(b, `  <text x="%d" y="%.1f" text-anchor="end">%.3g</text>`+"\n", left-6, y(v)+4, v)
+	}
+	for i := 0; i <= 2; i++ {
+		t := since.Add(time.Duration(float64(until.Sub(since)) * float64(i) / 2))
+		anchor := []string{"start", "middle", "end"}[i]
+		fmt.Fprintf(b, `  <text x="%.1f" y="%d" text-anchor="%s" fill="#555">%s</text>`+"\n", x(t), bottom+15, anchor, t.Format("Jan 2"))
+	}
+	fmt.Fprintf(b, `  <line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#999"/>`+"\n", left, bottom, right, bottom)
+
+	for i, mode := range modes {
+		color := trendColors[i%len(trendColors)]
+		points := s.byMode[mode]
+		coords := make([]string, len(points))
+		for j, p := range points {
+			coords[j] = fmt.Sprintf("%.1f,%.1f", x(p.at), y(value(p)))
+		}
+		if len(points) > 1 {
+			fmt.Fprintf(b, `  <polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`+"\n", color, strings.Join(coords, " "))
+		}
+		for _, p := range points {
+			fmt.Fprintf(b, `  <circle cx="%.1f" cy="%.1f" r="3" fill="%s"/>`+"\n", x(p.at), y(value(p)), color)
+		}
+	}
+}
+
+// trendChart is a written chart and the provider it shows.
+type trendChart struct {
+	label string
+	// path is relative to the results root.
+	path string
+}
+
+// writeTrendCharts renders a chart per provider measured within trendWindow of
+// now into resultsRoot/trends, replacing the previous set.
+func writeTrendCharts(resultsRoot string, sessions []storedSession, now time.Time) ([]trendChart, error) {
+	since := now.Add(-trendWindow)
+	series := collectTrends(sessions, since)
+	dir := filepath.Join(resultsRoot, trendDirName)
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("error clearing trend charts: %w", err)
+	}
+	if len(series) == 0 {
+		return nil, nil
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("error creating trend directory: %w", err)
+	}
+	charts := make([]trendChart, 0, len(series))
+	for _, s := range series {
+		name := resultFilePrefix(s.provider, s.env) + ".svg"
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(renderTrendChart(s, since, now)), 0600); err != nil {
+			return nil, fmt.Errorf("error writing trend chart: %w", err)
+		}
+		charts = append(charts, trendChart{label: providerLabel(s.provider, s.env), path: trendDirName + "/" + name})
+	}
+	return charts, nil
+}
+
+// writeTrendSection embeds the trend charts in the leaderboard.
+func writeTrendSection(report *strings.Builder, charts []trendChart) {
+	if len(charts) == 0 {
+		return
+	}
+	fmt.Fprintf(report, "## Trends (last %d days)\n\n", int(trendWindow.Hours()/24))
+	report.WriteString("Throughput and TTFT of every successful result, so gradual degradation stands out.\n\n")
+	for _, chart := range charts {
+		fmt.Fprintf(report, "### %s\n\n![%s trend](%s)\n\n", chart.label, chart.label, chart.path)
+	}
+}
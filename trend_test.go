@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollectTrendsKeepsWindowAndSortsPoints(t *testing.T) {
+	now := time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)
+	sessions := []storedSession{
+		{name: "session-b", results: []TestResult{
+			{Provider: "nim", Mode: string(ModeStreaming), Success: true, Timestamp: now.Add(-24 * time.Hour), Throughput: 70},
+			{Provider: "nim", Mode: string(ModeStreaming), Timestamp: now.Add(-12 * time.Hour), Error: "boom"},
+		}},
+		{name: "session-a", results: []TestResult{
+			{Provider: "nim", Mode: string(ModeStreaming), Success: true, Timestamp: now.Add(-48 * time.Hour), Throughput: 90},
+			{Provider: "nim", Mode: string(ModeStreaming), Success: true, Timestamp: now.Add(-40 * 24 * time.Hour), Throughput: 120},
+		}},
+	}
+	series := collectTrends(sessions, now.Add(-trendWindow))
+	if len(series) != 1 {
+		t.Fatalf("got %d series, want 1", len(series))
+	}
+	points := series[0].byMode[string(ModeStreaming)]
+	if len(points) != 2 {
+		t.Fatalf("got %d points, want the 2 successful ones inside the window", len(points))
+	}
+	if points[0].throughput != 90 || points[1].throughput != 70 {
+		t.Errorf("points not in time order: %+v", points)
+	}
+}
+
+func TestUpdateLeaderboardWritesTrendCharts(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "session-20250101-000000")
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for i, mode := range []TestMode{ModeStreaming, ModeToolCalling} {
+		saveResult(dir, TestResult{
+			Provider: "nim", Env: "prod", Model: "model-a", Mode: string(mode), Success: true,
+			Timestamp: now.Add(-time.Duration(i+1) * time.Hour), TTFT: 200 * time.Millisecond, Throughput: 80,
+		})
+	}
+	// A stale chart from an earlier run must not survive
+	if err := os.MkdirAll(filepath.Join(root, trendDirName), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, trendDirName, "gone.svg"), []byte("<svg/>"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := updateLeaderboard(root); err != nil {
+		t.Fatalf("updateLeaderboard: %v", err)
+	}
+	chart := filepath.Join(root, trendDirName, resultFilePrefix("nim", "prod")+".svg")
+	data, err := os.ReadFile(chart)
+	if err != nil {
+		t.Fatalf("trend chart not written: %v", err)
+	}
+	if err := xml.Unmarshal(data, new(struct{})); err != nil {
+		t.Fatalf("trend chart is not well-formed XML: %v", err)
+	}
+	for _, want := range []string{"Throughput (tok/s)", "TTFT (s)", "streaming", "tool-calling"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("trend chart missing %q", want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, trendDirName, "gone.svg")); err == nil {
+		t.Error("stale trend chart was kept")
+	}
+
+	board, err := os.ReadFile(filepath.Join(root, leaderboardFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(board), "## Trends (last 30 days)") ||
+		!strings.Contains(string(board), "(trends/"+resultFilePrefix("nim", "prod")+".svg)") {
+		t.Errorf("leaderboard does not embed the trend chart:\n%s", board)
+	}
+}
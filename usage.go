@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UsageTotals is the token usage and estimated cost of a provider's requests
+// in a session: benchmark runs, but also preflight, keep-warm and judge
+// calls.
+type UsageTotals struct {
+	PromptTokens     int     `json:"promptTokens"`
+	CompletionTokens int     `json:"completionTokens"`
+	Cost             float64 `json:"estimatedCostUsd,omitempty"`
+}
+
+// add adds o to u.
+func (u *UsageTotals) add(o UsageTotals) {
+	u.PromptTokens += o.PromptTokens
+	u.CompletionTokens += o.CompletionTokens
+	u.Cost += o.Cost
+}
+
+// tokens returns the prompt and completion tokens together.
+func (u UsageTotals) tokens() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// String describes the usage for reports and logs.
+func (u UsageTotals) String() string {
+	cost := fmt.Sprintf("estimated cost $%.4f", u.Cost)
+	if u.Cost == 0 {
+		cost = "cost not estimated (no prices set)"
+	}
+	return fmt.Sprintf("%d prompt + %d completion tokens (%d total), %s",
+		u.PromptTokens, u.CompletionTokens, u.tokens(), cost)
+}
+
+// sessionUsage totals the usage of results and diagnostic summaries. Usage is
+// cumulative per provider within a session, so each provider of each session
+// counts once, with its latest (largest) snapshot.
+func sessionUsage(results []TestResult, diagnostics []DiagnosticSummary) UsageTotals {
+	latest := make(map[string]UsageTotals)
+	keep := func(session, provider, env string, usage *UsageTotals) {
+		if usage == nil {
+			return
+		}
+		key := session + "\x00" + providerLabel(provider, env)
+		if usage.tokens() >= latest[key].tokens() {
+			latest[key] = *usage
+		}
+	}
+	for _, r := range results {
+		keep(r.SessionID, r.Provider, r.Env, r.Usage)
+	}
+	for _, d := range diagnostics {
+		keep(d.SessionID, d.Provider, d.Env, d.Usage)
+	}
+	var total UsageTotals
+	for _, usage := range latest {
+		total.add(usage)
+	}
+	return total
+}
+
+// usageNote adds the session's usage to a report header, if any was recorded.
+func usageNote(report *strings.Builder, usage UsageTotals) {
+	if usage.tokens() > 0 {
+		fmt.Fprintf(report, "**Session Usage:** %s\n\n", usage)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUsageBudgetPerProvider(t *testing.T) {
+	a := ProviderConfig{Name: "a", InputPrice: 1.0, OutputPrice: 2.0}
+	judge := ProviderConfig{Name: "judge", InputPrice: 10.0}
+
+	b := &usageBudget{}
+	b.record(a, 1000, 500)
+	b.recordFor("a", judge, 100, 0)
+	if b.usage("b") != nil {
+		t.Fatalf("expected no usage for an unused provider")
+	}
+	got := b.usage("a")
+	if got == nil || got.PromptTokens != 1100 || got.CompletionTokens != 500 {
+		t.Fatalf("unexpected usage %+v", got)
+	}
+	if want := 0.002 + 0.001; got.Cost < want-1e-12 || got.Cost > want+1e-12 {
+		t.Fatalf("expected $%.4f including the judge, got $%.4f", want, got.Cost)
+	}
+	if tokens, _ := b.totals(); tokens != 1600 {
+		t.Fatalf("expected 1600 tokens in the session total, got %d", tokens)
+	}
+}
+
+func TestSessionUsage(t *testing.T) {
+	early := &UsageTotals{PromptTokens: 100, CompletionTokens: 200, Cost: 0.01}
+	late := &UsageTotals{PromptTokens: 300, CompletionTokens: 600, Cost: 0.03}
+	other := &UsageTotals{PromptTokens: 10, CompletionTokens: 20}
+	results := []TestResult{
+		{SessionID: "s1", Provider: "a", Usage: late},
+		{SessionID: "s1", Provider: "a", Usage: early},
+		{SessionID: "s2", Provider: "a", Usage: early},
+		{SessionID: "s1", Provider: "b"},
+	}
+	diagnostics := []DiagnosticSummary{{SessionID: "s1", Provider: "c", Usage: other}}
+
+	got := sessionUsage(results, diagnostics)
+	want := UsageTotals{PromptTokens: 410, CompletionTokens: 820, Cost: 0.04}
+	if got.PromptTokens != want.PromptTokens || got.CompletionTokens != want.CompletionTokens ||
+		got.Cost < want.Cost-1e-12 || got.Cost > want.Cost+1e-12 {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestUsageNote(t *testing.T) {
+	var report strings.Builder
+	usageNote(&report, UsageTotals{})
+	if report.Len() != 0 {
+		t.Fatalf("expected no note without usage, got %q", report.String())
+	}
+
+	usageNote(&report, UsageTotals{PromptTokens: 1200, CompletionTokens: 3400, Cost: 0.0125})
+	want := "**Session Usage:** 1200 prompt + 3400 completion tokens (4600 total), estimated cost $0.0125\n\n"
+	if report.String() != want {
+		t.Fatalf("expected %q, got %q", want, report.String())
+	}
+
+	report.Reset()
+	usageNote(&report, UsageTotals{PromptTokens: 1, CompletionTokens: 2})
+	if !strings.Contains(report.String(), "cost not estimated") {
+		t.Fatalf("expected unpriced usage to say so, got %q", report.String())
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import "net/http"
+
+// sessionUserAgent is the User-Agent sent to providers that set no
+// <PREFIX>_USER_AGENT of their own (--user-agent). Empty leaves Go's default.
+var sessionUserAgent = defaultUserAgent()
+
+// defaultUserAgent identifies the tool and its version, e.g. "llm-api-speed/1.4.0".
+func defaultUserAgent() string {
+	return "llm-api-speed/" + version
+}
+
+// userAgent is the User-Agent of config's requests: its own override, else
+// the session's.
+func (c ProviderConfig) userAgent() string {
+	if c.UserAgent != "" {
+		return c.UserAgent
+	}
+	return sessionUserAgent
+}
+
+// userAgentTransport sets the User-Agent of every request. Some gateways route
+// or throttle by it, so it is pinned rather than left to the HTTP library.
+type userAgentTransport struct {
+	base      http.RoundTripper
+	userAgent string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return t.base.RoundTrip(req)
+}
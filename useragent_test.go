@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProviderUserAgent(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("User-Agent")
+	}))
+	defer server.Close()
+
+	saved := sessionUserAgent
+	defer func() { sessionUserAgent = saved }()
+
+	tests := []struct {
+		session, override, want string
+	}{
+		{defaultUserAgent(), "", "llm-api-speed/" + version},
+		{"pinned/1.0", "", "pinned/1.0"},
+		{"pinned/1.0", "gateway-probe/2", "gateway-probe/2"},
+		{"", "", "Go-http-client/1.1"},
+	}
+	for _, tt := range tests {
+		sessionUserAgent = tt.session
+		resp, err := providerHTTPClient(ProviderConfig{BaseURL: server.URL, UserAgent: tt.override}).Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if got != tt.want {
+			t.Errorf("session %q, override %q: User-Agent %q, want %q", tt.session, tt.override, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// warmupRequests is the number of unmeasured requests sent to each provider
+// before its measured runs; set with --warmup or test_params.warmup_requests.
+var warmupRequests = 0
+
+// warmupTimeout bounds each warm-up request. It is generous because waking a
+// serverless model is exactly what the warm-up is for.
+const warmupTimeout = 2 * time.Minute
+
+// validateWarmup checks a warm-up request count.
+func validateWarmup(n int) error {
+	if n < 0 || n > maxPoolSize {
+		return fmt.Errorf("warm-up requests must be between 0 and %d, got %d", maxPoolSize, n)
+	}
+	return nil
+}
+
+// warmUp sends warmupRequests streaming requests to config one at a time and
+// discards their timings, so the TLS handshake, connection setup and any model
+// loading are paid before the first measured run. Failures are logged and do
+// not stop the benchmark; a provider that cannot answer will fail its runs
+// anyway. The requests count against the session budget.
+func warmUp(config ProviderConfig, tke *tiktoken.Tiktoken, providerLogger *log.Logger) {
+	if warmupRequests == 0 {
+		return
+	}
+	providerLogger.Printf("[%s] Sending %d warm-up request(s), excluded from the results", config.Name, warmupRequests)
+	for i := 1; i <= warmupRequests; i++ {
+		if err := canStartRun(); err != nil {
+			providerLogger.Printf("[%s] Warm-up stopped: %v", config.Name, err)
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), warmupTimeout)
+		o := raceStream(ctx, config, tke, fmt.Sprintf("warmup%d", i), nil)
+		cancel()
+		if o.err != nil {
+			providerLogger.Printf("[%s] Warm-up request %d failed: %v", config.Name, i, o.err)
+			continue
+		}
+		providerLogger.Printf("[%s] Warm-up request %d: TTFT=%s E2E=%s", config.Name, i,
+			formatDuration(o.firstToken.Sub(o.start)), formatDuration(o.end.Sub(o.start)))
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWarmUp(t *testing.T) {
+	tke := testTokenizer(t)
+	handler := &mockSSEHandler{chunks: []string{"Hello."}, unavailable: 1}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	config := ProviderConfig{Name: "warmup-test", BaseURL: server.URL, APIKey: "k", Model: "m"}
+	logger := log.New(io.Discard, "", 0)
+
+	warmUp(config, tke, logger)
+	if got := handler.requests.Load(); got != 0 {
+		t.Fatalf("expected no requests without warm-up, got %d", got)
+	}
+
+	warmupRequests = 3
+	t.Cleanup(func() { warmupRequests = 0 })
+	warmUp(config, tke, logger)
+	if got := handler.requests.Load(); got != 3 {
+		t.Fatalf("expected a failed warm-up not to stop the others, got %d requests", got)
+	}
+	for _, line := range sessionProgress.summary() {
+		if strings.HasPrefix(line, config.Name+":") {
+			t.Fatalf("expected warm-ups to stay out of the measured progress, got %q", line)
+		}
+	}
+}
+
+func TestLoadConfigFileWarmup(t *testing.T) {
+	cfg, err := loadConfigFile(writeConfig(t, "[test_params]\nwarmup_requests = 2\n"))
+	if err != nil || cfg.TestParams.warmup() != 2 {
+		t.Fatalf("expected two warm-up requests, got %+v, %v", cfg.TestParams, err)
+	}
+	if _, err := loadConfigFile(writeConfig(t, "[test_params]\nwarmup_requests = -1\n")); err == nil {
+		t.Fatal("expected a negative warm-up count to be rejected")
+	}
+	if (fileConfig{}).TestParams.warmup() != 0 {
+		t.Fatal("expected no warm-up without a [test_params] table")
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RunSample is one iteration of a standard benchmark, kept alongside the
+// averages so providers can be compared run by run.
+type RunSample struct {
+	Iteration  int           `json:"iteration"`
+	Mode       string        `json:"mode"`
+	Success    bool          `json:"success"`
+	TTFT       time.Duration `json:"ttftMs,omitempty"`
+	E2E        time.Duration `json:"e2eLatencyMs,omitempty"`
+	Throughput float64       `json:"throughputTokensPerSec,omitempty"`
+	Tokens     int           `json:"completionTokens,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	// LocalTokens and ReportedTokens are the run's completion tokens counted
+	// with tiktoken and as reported by the provider; Tokens is the latter when
+	// there is one.
+	LocalTokens    int `json:"localCompletionTokens,omitempty"`
+	ReportedTokens int `json:"reportedCompletionTokens,omitempty"`
+	// Instance is "warm" or "cold" when the provider reported which kind of
+	// instance served the run (see instanceState).
+	Instance string `json:"instance,omitempty"`
+}
+
+// runKey identifies an iteration that several providers ran: the same session,
+// mode and iteration number.
+type runKey struct {
+	session, mode string
+	iteration     int
+}
+
+// winRate counts how often one provider beat another over shared iterations.
+type winRate struct {
+	overlap int
+	// wins count ties as half a win, so A's rate over B and B's over A add up
+	// to 100%.
+	ttftWins, throughputWins float64
+}
+
+// pairwiseWinRates compares every pair of providers over the iterations both
+// ran successfully. It returns the provider labels, sorted, and the rates of
+// each row provider over each column provider.
+func pairwiseWinRates(results []TestResult) ([]string, map[[2]string]winRate) {
+	samples := make(map[string]map[runKey]RunSample)
+	for _, r := range results {
+		label := providerLabel(r.Provider, r.Env)
+		for _, s := range r.Runs {
+			if !s.Success {
+				continue
+			}
+			if samples[label] == nil {
+				samples[label] = make(map[runKey]RunSample)
+			}
+			samples[label][runKey{r.SessionID, s.Mode, s.Iteration}] = s
+		}
+	}
+	labels := make([]string, 0, len(samples))
+	for label := range samples {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	rates := make(map[[2]string]winRate)
+	for _, a := range labels {
+		for _, b := range labels {
+			if a == b {
+				continue
+			}
+			var w winRate
+			for key, sa := range samples[a] {
+				sb, ok := samples[b][key]
+				if !ok {
+					continue
+				}
+				w.overlap++
+				w.ttftWins += beats(float64(sb.TTFT), float64(sa.TTFT))
+				w.throughputWins += beats(sa.Throughput, sb.Throughput)
+			}
+			if w.overlap > 0 {
+				rates[[2]string{a, b}] = w
+			}
+		}
+	}
+	return labels, rates
+}
+
+// beats scores x against y where higher is better: 1 for a win, 0.5 for a tie.
+func beats(x, y float64) float64 {
+	switch {
+	case x > y:
+		return 1
+	case x == y:
+		return 0.5
+	}
+	return 0
+}
+
+// writeWinRateMatrix renders one metric's matrix.
+func writeWinRateMatrix(report *strings.Builder, title string, labels []string, rates map[[2]string]winRate, wins func(winRate) float64) {
+	fmt.Fprintf(report, "### %s\n\n", title)
+	report.WriteString("| Provider |")
+	for _, b := range labels {
+		fmt.Fprintf(report, " vs %s |", b)
+	}
+	report.WriteString("\n|----------|" + strings.Repeat("------|", len(labels)) + "\n")
+	for _, a := range labels {
+		fmt.Fprintf(report, "| %s |", a)
+		for _, b := range labels {
+			w, ok := rates[[2]string{a, b}]
+			switch {
+			case a == b:
+				report.WriteString(" — |")
+			case !ok:
+				report.WriteString(" - |")
+			default:
+				fmt.Fprintf(report, " %.0f%% (n=%d) |", 100*wins(w)/float64(w.overlap), w.overlap)
+			}
+		}
+		report.WriteString("\n")
+	}
+	report.WriteString("\n")
+}
+
+// writeWinRateSection shows, for every pair of providers, how often the row
+// provider beat the column provider over the iterations both completed. Nothing
+// is written unless two providers share at least one iteration.
+func writeWinRateSection(report *strings.Builder, results []TestResult) {
+	labels, rates := pairwiseWinRates(results)
+	if len(rates) == 0 {
+		return
+	}
+	report.WriteString("## Pairwise Win Rates\n\n")
+	report.WriteString("Share of shared iterations (same session, mode and run number, both successful) in which the row provider beat the column provider. ")
+	report.WriteString("Unlike a comparison of averages, one outlier run moves a rate by at most 1/n. Ties count as half a win.\n\n")
+	writeWinRateMatrix(report, "TTFT (lower wins)", labels, rates, func(w winRate) float64 { return w.ttftWins })
+	writeWinRateMatrix(report, "Throughput (higher wins)", labels, rates, func(w winRate) float64 { return w.throughputWins })
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func winRateResult(provider string, ttfts []time.Duration, throughputs []float64) TestResult {
+	r := TestResult{SessionID: "s1", Provider: provider, Mode: string(ModeStreaming), Success: true}
+	for i := range ttfts {
+		r.Runs = append(r.Runs, RunSample{
+			Iteration: i + 1, Mode: string(ModeStreaming), Success: throughputs[i] > 0,
+			TTFT: ttfts[i], Throughput: throughputs[i],
+		})
+	}
+	return r
+}
+
+func TestPairwiseWinRates(t *testing.T) {
+	ms := time.Millisecond
+	results := []TestResult{
+		winRateResult("nim", []time.Duration{100 * ms, 300 * ms, 200 * ms, 100 * ms}, []float64{90, 50, 60, 70}),
+		// Iteration 4 failed, so only three iterations overlap
+		winRateResult("groq", []time.Duration{200 * ms, 200 * ms, 200 * ms, 50 * ms}, []float64{80, 80, 60, 0}),
+	}
+	labels, rates := pairwiseWinRates(results)
+	if strings.Join(labels, ",") != "groq,nim" {
+		t.Fatalf("labels = %v", labels)
+	}
+	nim := rates[[2]string{"nim", "groq"}]
+	if nim.overlap != 3 {
+		t.Fatalf("overlap = %d, want 3", nim.overlap)
+	}
+	// TTFT: nim wins run 1, loses run 2, ties run 3
+	if nim.ttftWins != 1.5 {
+		t.Errorf("nim TTFT wins = %g, want 1.5", nim.ttftWins)
+	}
+	// Throughput: nim wins run 1, loses run 2, ties run 3
+	if nim.throughputWins != 1.5 {
+		t.Errorf("nim throughput wins = %g, want 1.5", nim.throughputWins)
+	}
+	groq := rates[[2]string{"groq", "nim"}]
+	if groq.ttftWins+nim.ttftWins != float64(nim.overlap) {
+		t.Errorf("win rates do not add up to 100%%: %g + %g over %d", groq.ttftWins, nim.ttftWins, nim.overlap)
+	}
+}
+
+func TestPairwiseWinRatesNeedsSameSession(t *testing.T) {
+	a := winRateResult("nim", []time.Duration{time.Second}, []float64{10})
+	b := winRateResult("groq", []time.Duration{time.Second}, []float64{10})
+	b.SessionID = "s2"
+	if _, rates := pairwiseWinRates([]TestResult{a, b}); len(rates) != 0 {
+		t.Errorf("iterations from different sessions were compared: %+v", rates)
+	}
+}
+
+func TestWriteWinRateSection(t *testing.T) {
+	ms := time.Millisecond
+	var report strings.Builder
+	writeWinRateSection(&report, []TestResult{
+		winRateResult("nim", []time.Duration{100 * ms, 100 * ms}, []float64{90, 90}),
+		winRateResult("groq", []time.Duration{200 * ms, 200 * ms}, []float64{80, 80}),
+	})
+	out := report.String()
+	for _, want := range []string{
+		"## Pairwise Win Rates",
+		"### TTFT (lower wins)",
+		"| Provider | vs groq | vs nim |",
+		"| nim | 100% (n=2) | — |",
+		"| groq | — | 0% (n=2) |",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("section missing %q:\n%s", want, out)
+		}
+	}
+
+	report.Reset()
+	writeWinRateSection(&report, []TestResult{winRateResult("nim", []time.Duration{ms}, []float64{1})})
+	if report.Len() != 0 {
+		t.Errorf("section written for a single provider:\n%s", report.String())
+	}
+}